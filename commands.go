@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"pelican-gallery/internal/api"
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/generation"
+	"pelican-gallery/internal/logging"
+	"pelican-gallery/internal/metrics"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+
+	"github.com/joho/godotenv"
+)
+
+// Exit codes of the commands. Scripts can tell a run that did part of its
+// work from one that did none.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command failed
+	exitUsage   = 2 // the arguments are invalid
+	exitPartial = 3 // some of the work failed
+)
+
+// usage describes the commands
+const usage = `Usage: pelican-gallery [command] [flags]
+
+Commands:
+  serve         run the web server (the default)
+  generate      generate artworks without the web server:
+                  --group ID       only the artworks of this group
+                  --model M        only the artworks made with model M
+                  --all-missing    only the artworks without an SVG
+                  --auto-continue  continue output cut off at max_tokens
+  export        write every group to an export document:
+                  --out FILE       the file to write (standard output when empty)
+                  --category C     only the groups of this category
+  import        import an export document, plain or gzip-compressed:
+                  --in FILE        the file to read (standard input when empty)
+                  --overwrite      replace groups whose title exists instead of skipping them
+  migrate       apply pending schema migrations to the database
+  prune-empty   delete artworks that have no SVG:
+                  --min-age D      only artworks at least this old (default 1h)
+                  --permanent      delete them for good instead of moving them to the recycle bin
+                  --dry-run        only list them
+
+Commands other than serve work on the database at DB_PATH, read config/prompts
+and use OPENROUTER_API_KEY like the server; with DRY_RUN=true generate saves
+placeholder SVGs without calling OpenRouter. They exit with 0 on success, 1 on
+failure, 2 on invalid arguments and 3 when only some of the work succeeded.
+`
+
+// run runs the command named by the first argument and returns its exit
+// code. Without a command the web server runs.
+func run(args []string) int {
+	// .env is loaded first as it may set LOG_FORMAT
+	envErr := godotenv.Load()
+	logging.Setup(config.LogFormat(), os.Stderr)
+
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		if len(args) > 0 {
+			fmt.Fprint(os.Stderr, usage)
+			return exitUsage
+		}
+		serve(envErr)
+		return exitOK
+	case "generate":
+		return generateCommand(args)
+	case "export":
+		return exportCommand(args)
+	case "import":
+		return importCommand(args)
+	case "migrate":
+		return migrateCommand(args)
+	case "prune-empty":
+		return pruneEmptyCommand(args)
+	case "help":
+		fmt.Print(usage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		return exitUsage
+	}
+}
+
+// databasePath returns the database file named by DB_PATH, artworks.db by
+// default
+func databasePath() string {
+	if path := os.Getenv("DB_PATH"); path != "" {
+		return path
+	}
+	return "artworks.db"
+}
+
+// openDatabase opens the database at path, migrating it when writable and
+// opening it read-only otherwise. A read-only database is never written to:
+// with migrations pending, as after deploying a new release, it fails with
+// ErrSchemaOutdated until the migrate command has upgraded it.
+func openDatabase(path string, writable bool) (*database.DB, error) {
+	if writable {
+		return database.New(path)
+	}
+
+	db, err := database.New("file:" + path + "?mode=ro")
+	if errors.Is(err, database.ErrSchemaOutdated) {
+		return nil, fmt.Errorf("%w; run \"pelican-gallery migrate\" to upgrade it", err)
+	}
+	return db, err
+}
+
+// loadPrompts loads the prompt styles from config/prompts, along with the
+// per-model generation parameters of config/models.yaml
+func loadPrompts() (*config.PromptStore, error) {
+	count, err := config.InitModelOverrides(config.ModelOverridesFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", config.ModelOverridesFile, err)
+	}
+	if count > 0 {
+		log.Printf("Loaded %d model override(s) from %s", count, config.ModelOverridesFile)
+	}
+	return config.NewPromptStore("config/prompts")
+}
+
+// parseFlags parses the flags of a command, which takes no other arguments
+func parseFlags(fs *flag.FlagSet, args []string) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "%s takes no arguments, got %q\n", fs.Name(), fs.Args())
+		return false
+	}
+	return true
+}
+
+// fail reports an error of a command and returns exitFailure
+func fail(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	return exitFailure
+}
+
+// generateCommand generates the selected artworks directly against the
+// database and OpenRouter, a group at a time with the batch worker pool
+func generateCommand(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	groupID := fs.Int("group", 0, "only the artworks of this group")
+	model := fs.String("model", "", "only the artworks made with this model")
+	allMissing := fs.Bool("all-missing", false, "only the artworks without an SVG")
+	autoContinue := fs.Bool("auto-continue", false, "continue output cut off at max_tokens")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+	if *groupID == 0 && *model == "" && !*allMissing {
+		fmt.Fprintln(os.Stderr, "generate needs at least one of --group, --model and --all-missing")
+		return exitUsage
+	}
+
+	client := openrouter.NewClient()
+	if client.APIKey == "" && !config.DryRun() {
+		return fail("%v", openrouter.ErrMissingAPIKey)
+	}
+
+	db, err := openDatabase(databasePath(), true)
+	if err != nil {
+		return fail("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	prompts, err := loadPrompts()
+	if err != nil {
+		return fail("failed to load prompt configs: %v", err)
+	}
+
+	var groups []models.ArtworkGroup
+	if *groupID != 0 {
+		group, err := db.GetGroup(*groupID)
+		if err != nil {
+			return fail("group %d: %v", *groupID, err)
+		}
+		groups = []models.ArtworkGroup{*group}
+	} else if groups, err = db.ListGroups(); err != nil {
+		return fail("%v", err)
+	}
+
+	// Ctrl+C aborts the OpenRouter calls in flight instead of paying for them
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	service := generation.NewService(prompts, db, client, metrics.NewAppMetrics())
+	total, failed := 0, 0
+	for i := range groups {
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "Interrupted; the remaining groups were not generated")
+			break
+		}
+
+		group := &groups[i]
+		artworks, err := db.ListArtworksByGroup(group.ID)
+		if err != nil {
+			return fail("%v", err)
+		}
+
+		var selected []models.Artwork
+		for _, artwork := range artworks {
+			if (*model == "" || artwork.Model == *model) && (!*allMissing || artwork.SVG == "") {
+				selected = append(selected, artwork)
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+
+		log.Printf("Generating %d artwork(s) of group %d (%s)", len(selected), group.ID, group.Title)
+		for _, result := range service.GenerateBatch(ctx, group, selected, *autoContinue, nil) {
+			total++
+			if !result.Success {
+				failed++
+				fmt.Printf("failed   artwork %d (group %d, %s): %s\n", result.ArtworkID, group.ID, result.Model, result.Error)
+				continue
+			}
+			fmt.Printf("ok       artwork %d (group %d, %s): %d characters\n", result.ArtworkID, group.ID, result.Model, result.SVGLength)
+		}
+	}
+
+	fmt.Printf("Generated %d of %d artwork(s), %d failed\n", total-failed, total, failed)
+	switch {
+	case failed == 0:
+		return exitOK
+	case failed == total:
+		return exitFailure
+	default:
+		return exitPartial
+	}
+}
+
+// exportCommand writes the export document of GET /api/export to a file
+func exportCommand(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", "the file to write (standard output when empty)")
+	category := fs.String("category", "", "only the groups of this category")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	db, err := openDatabase(databasePath(), false)
+	if err != nil {
+		return fail("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		// Written next to the destination first, so a failed export never
+		// replaces an earlier one
+		file, err := os.CreateTemp(dirOf(*out), ".export-*.json")
+		if err != nil {
+			return fail("%v", err)
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		w = file
+	}
+
+	buffered := bufio.NewWriter(w)
+	exported, err := newCommandHandler(nil, db).Export(buffered, *category)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		return fail("export failed after %d group(s): %v", exported, err)
+	}
+
+	if file, ok := w.(*os.File); ok && file != os.Stdout {
+		if err := file.Close(); err != nil {
+			return fail("%v", err)
+		}
+		if err := os.Rename(file.Name(), *out); err != nil {
+			return fail("%v", err)
+		}
+	}
+	log.Printf("Exported %d group(s)", exported)
+	return exitOK
+}
+
+// importCommand imports an export document the way POST /api/import does
+func importCommand(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", "the file to read (standard input when empty)")
+	overwrite := fs.Bool("overwrite", false, "replace groups whose title exists instead of skipping them")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return fail("%v", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	// Compressed exports (?gzip=1) are recognized by their magic number
+	body := bufio.NewReader(r)
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fail("invalid gzip data: %v", err)
+		}
+		r = gz
+	} else {
+		r = body
+	}
+
+	db, err := openDatabase(databasePath(), true)
+	if err != nil {
+		return fail("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	prompts, err := loadPrompts()
+	if err != nil {
+		return fail("failed to load prompt configs: %v", err)
+	}
+
+	result, err := newCommandHandler(prompts, db).Import(r, *overwrite)
+	var rejected *api.ImportRejectedError
+	if errors.As(err, &rejected) {
+		fmt.Fprintln(os.Stderr, "Import rejected: some records are invalid")
+		for _, problem := range rejected.Problems {
+			fmt.Fprintln(os.Stderr, "  "+problem)
+		}
+		return exitFailure
+	}
+	if err != nil {
+		return fail("import failed; nothing was imported: %v", err)
+	}
+
+	fmt.Printf("Imported %d group(s) (%d overwritten, %d skipped) and %d artwork(s) (%d skipped)\n",
+		result.GroupsImported, result.GroupsOverwritten, result.GroupsSkipped, result.ArtworksImported, result.ArtworksSkipped)
+	return exitOK
+}
+
+// migrateCommand applies the pending schema migrations, so that a database
+// served read-only can be upgraded after deploying a new release
+func migrateCommand(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	db, err := openDatabase(databasePath(), true)
+	if err != nil {
+		return fail("failed to migrate database: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return fail("%v", err)
+	}
+	fmt.Printf("Database schema is at version %d\n", version)
+	return exitOK
+}
+
+// pruneEmptyCommand deletes the artworks that have no SVG, such as those
+// left behind by failed generations
+func pruneEmptyCommand(args []string) int {
+	fs := flag.NewFlagSet("prune-empty", flag.ContinueOnError)
+	minAge := fs.Duration("min-age", time.Hour, "only artworks at least this old, so generations in progress are left alone")
+	permanent := fs.Bool("permanent", false, "delete them for good instead of moving them to the recycle bin")
+	dryRun := fs.Bool("dry-run", false, "only list them")
+	if !parseFlags(fs, args) {
+		return exitUsage
+	}
+
+	db, err := openDatabase(databasePath(), !*dryRun)
+	if err != nil {
+		return fail("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	groups, err := db.ListGroups()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	cutoff := time.Now().Add(-*minAge)
+	total, failed := 0, 0
+	for _, group := range groups {
+		artworks, err := db.ListArtworksByGroup(group.ID)
+		if err != nil {
+			return fail("%v", err)
+		}
+		for _, artwork := range artworks {
+			if artwork.SVG != "" || artwork.CreatedAt.After(cutoff) {
+				continue
+			}
+			total++
+			if *dryRun {
+				fmt.Printf("empty    artwork %d (group %d, %s)\n", artwork.ID, group.ID, artwork.Model)
+				continue
+			}
+
+			if *permanent {
+				err = db.PurgeArtwork(artwork.ID)
+			} else {
+				err = db.DeleteArtwork(artwork.ID)
+			}
+			if err != nil {
+				failed++
+				fmt.Printf("failed   artwork %d (group %d, %s): %v\n", artwork.ID, group.ID, artwork.Model, err)
+				continue
+			}
+			fmt.Printf("deleted  artwork %d (group %d, %s)\n", artwork.ID, group.ID, artwork.Model)
+		}
+	}
+
+	switch {
+	case *dryRun:
+		fmt.Printf("%d empty artwork(s) would be deleted\n", total)
+	case *permanent:
+		fmt.Printf("Deleted %d of %d empty artwork(s) for good\n", total-failed, total)
+	default:
+		fmt.Printf("Moved %d of %d empty artwork(s) to the recycle bin\n", total-failed, total)
+	}
+	switch {
+	case failed == 0:
+		return exitOK
+	case failed == total:
+		return exitFailure
+	default:
+		return exitPartial
+	}
+}
+
+// newCommandHandler returns the API handler whose export and import the
+// commands share; it serves no requests
+func newCommandHandler(prompts *config.PromptStore, db *database.DB) *api.Handler {
+	return api.NewHandler(prompts, db, nil, metrics.NewAppMetrics(), nil)
+}
+
+// dirOf returns the directory of path, "." for a bare file name
+func dirOf(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[:i+1]
+	}
+	return "."
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/models"
+)
+
+// outdatedDatabase creates a database at the latest schema version and then
+// forgets its newest migration, as if a release adding it had just been
+// deployed, and returns its path
+func outdatedDatabase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	db.Close()
+
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	if _, err := raw.Exec(`DELETE FROM schema_migrations WHERE version = ?`, database.LatestSchemaVersion); err != nil {
+		t.Fatalf("forget latest migration: %v", err)
+	}
+	return path
+}
+
+// appliedVersion reads the schema version of the database at path without
+// going through database.New
+func appliedVersion(t *testing.T, path string) int {
+	t.Helper()
+	raw, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var version int
+	if err := raw.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatalf("read schema version: %v", err)
+	}
+	return version
+}
+
+func TestOpenDatabaseReadOnlyDoesNotMigrate(t *testing.T) {
+	path := outdatedDatabase(t)
+
+	db, err := openDatabase(path, false)
+	if err == nil {
+		db.Close()
+		t.Fatal("openDatabase read-only succeeded with a migration pending")
+	}
+	if !errors.Is(err, database.ErrSchemaOutdated) {
+		t.Errorf("error = %v, want ErrSchemaOutdated", err)
+	}
+	if got := appliedVersion(t, path); got != database.LatestSchemaVersion-1 {
+		t.Errorf("schema version after read-only open = %d, want it left at %d", got, database.LatestSchemaVersion-1)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	path := outdatedDatabase(t)
+	t.Setenv("DB_PATH", path)
+
+	if code := migrateCommand(nil); code != exitOK {
+		t.Fatalf("migrate exited with %d", code)
+	}
+	if got := appliedVersion(t, path); got != database.LatestSchemaVersion {
+		t.Errorf("schema version after migrate = %d, want %d", got, database.LatestSchemaVersion)
+	}
+
+	db, err := openDatabase(path, false)
+	if err != nil {
+		t.Fatalf("openDatabase read-only after migrate: %v", err)
+	}
+	db.Close()
+
+	if code := migrateCommand([]string{"extra"}); code != exitUsage {
+		t.Errorf("migrate with an argument exited with %d, want %d", code, exitUsage)
+	}
+}
+
+// commandDatabase points DB_PATH at a new database, seeded by seed, for the
+// commands to open; seed gets the database open for writing
+func commandDatabase(t *testing.T, seed func(db *database.DB)) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	seed(db)
+	db.Close()
+	t.Setenv("DB_PATH", path)
+	return path
+}
+
+// withDatabase opens the database at path for the checks that follow a
+// command
+func withDatabase(t *testing.T, path string, check func(db *database.DB)) {
+	t.Helper()
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	defer db.Close()
+	check(db)
+}
+
+// createEmptyArtwork creates an artwork of model without an SVG, created long
+// enough ago for prune-empty
+func createEmptyArtwork(t *testing.T, db *database.DB, groupID int, model string) int {
+	t.Helper()
+	created := time.Now().Add(-2 * time.Hour)
+	id, err := db.CreateArtwork(models.Artwork{GroupID: groupID, Model: model, MaxTokens: 1000, CreatedAt: created, UpdatedAt: created})
+	if err != nil {
+		t.Fatalf("CreateArtwork: %v", err)
+	}
+	return id
+}
+
+func TestGenerateCommand(t *testing.T) {
+	openRouter := newFakeOpenRouter(t)
+	t.Setenv("OPENROUTER_BASE_URL", openRouter.URL)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("DRY_RUN", "false")
+	gpt, gemini := fakeModels[0], fakeModels[1]
+
+	var pelican, heron int
+	var missing, drawn, failing []int
+	path := commandDatabase(t, func(db *database.DB) {
+		var err error
+		if pelican, err = db.CreateGroup(models.ArtworkGroup{Title: "Pelican", Prompt: "A pelican"}); err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+		missing = []int{createEmptyArtwork(t, db, pelican, gpt), createEmptyArtwork(t, db, pelican, gemini)}
+		id, err := db.CreateArtwork(models.Artwork{GroupID: pelican, Model: fakeModels[2], SVG: testSVG})
+		if err != nil {
+			t.Fatalf("CreateArtwork: %v", err)
+		}
+		drawn = []int{id}
+		if heron, err = db.CreateGroup(models.ArtworkGroup{Title: "Heron", Prompt: "A heron"}); err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+		failing = []int{createEmptyArtwork(t, db, heron, gpt), createEmptyArtwork(t, db, heron, gemini)}
+	})
+
+	// Every missing artwork of the Pelican group is generated
+	if code := generateCommand([]string{"--group", strconv.Itoa(pelican), "--all-missing"}); code != exitOK {
+		t.Fatalf("generate exited with %d, want %d", code, exitOK)
+	}
+	withDatabase(t, path, func(db *database.DB) {
+		for _, id := range missing {
+			artwork, err := db.GetArtwork(id)
+			if err != nil || !strings.Contains(artwork.SVG, "<title>"+artwork.Model+"</title>") {
+				t.Errorf("artwork %d after generate = %+v, %v", id, artwork, err)
+			}
+		}
+		if artwork, err := db.GetArtwork(drawn[0]); err != nil || artwork.SVG != testSVG {
+			t.Errorf("generate --all-missing redrew artwork %d: %+v, %v", drawn[0], artwork, err)
+		}
+	})
+	if calls := openRouter.calls(); len(calls) != 2 {
+		t.Errorf("OpenRouter got %d requests, want 2: %v", len(calls), calls)
+	}
+
+	// Part of the Heron group fails, then all that is asked for
+	openRouter.fail(gemini)
+	if code := generateCommand([]string{"--group", strconv.Itoa(heron)}); code != exitPartial {
+		t.Errorf("generate with one failure exited with %d, want %d", code, exitPartial)
+	}
+	if code := generateCommand([]string{"--model", gemini, "--all-missing"}); code != exitFailure {
+		t.Errorf("generate with only failures exited with %d, want %d", code, exitFailure)
+	}
+	withDatabase(t, path, func(db *database.DB) {
+		if artwork, err := db.GetArtwork(failing[0]); err != nil || artwork.SVG == "" {
+			t.Errorf("artwork generated next to a failure = %+v, %v", artwork, err)
+		}
+		if artwork, err := db.GetArtwork(failing[1]); err != nil || artwork.SVG != "" {
+			t.Errorf("failed artwork = %+v, %v", artwork, err)
+		}
+	})
+
+	for _, args := range [][]string{nil, {"--group", "abc"}, {"--all-missing", "extra"}} {
+		if code := generateCommand(args); code != exitUsage {
+			t.Errorf("generate %q exited with %d, want %d", args, code, exitUsage)
+		}
+	}
+	if code := generateCommand([]string{"--group", "9999"}); code != exitFailure {
+		t.Errorf("generate of a missing group exited with %d, want %d", code, exitFailure)
+	}
+
+	t.Setenv("OPENROUTER_API_KEY", "")
+	if code := generateCommand([]string{"--all-missing"}); code != exitFailure {
+		t.Errorf("generate without an API key exited with %d, want %d", code, exitFailure)
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	var groupID int
+	commandDatabase(t, func(db *database.DB) {
+		var err error
+		if groupID, err = db.CreateGroup(models.ArtworkGroup{Title: "Pelican", Prompt: "A pelican", Category: "Birds"}); err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+		if _, err := db.CreateArtwork(models.Artwork{GroupID: groupID, Model: fakeModels[0], MaxTokens: 1000, SVG: testSVG}); err != nil {
+			t.Fatalf("CreateArtwork: %v", err)
+		}
+	})
+	out := filepath.Join(t.TempDir(), "export.json")
+	if code := exportCommand([]string{"--out", out}); code != exitOK {
+		t.Fatalf("export exited with %d", code)
+	}
+
+	// Importing into an empty database restores the group
+	target := commandDatabase(t, func(*database.DB) {})
+	if code := importCommand([]string{"--in", out}); code != exitOK {
+		t.Fatalf("import exited with %d", code)
+	}
+	withDatabase(t, target, func(db *database.DB) {
+		groups, err := db.ListGroups()
+		if err != nil || len(groups) != 1 || groups[0].Title != "Pelican" || groups[0].Category != "Birds" {
+			t.Fatalf("groups after import = %+v, %v", groups, err)
+		}
+		artworks, err := db.ListArtworksByGroup(groups[0].ID)
+		if err != nil || len(artworks) != 1 || artworks[0].SVG != testSVG || artworks[0].Model != fakeModels[0] {
+			t.Errorf("artworks after import = %+v, %v", artworks, err)
+		}
+	})
+
+	// Importing again skips the group whose title exists
+	if code := importCommand([]string{"--in", out}); code != exitOK {
+		t.Fatalf("second import exited with %d", code)
+	}
+	withDatabase(t, target, func(db *database.DB) {
+		if groups, err := db.ListGroups(); err != nil || len(groups) != 1 {
+			t.Errorf("groups after importing twice = %d, %v", len(groups), err)
+		}
+	})
+
+	if code := importCommand([]string{"--in", filepath.Join(t.TempDir(), "missing.json")}); code != exitFailure {
+		t.Errorf("import of a missing file exited with %d, want %d", code, exitFailure)
+	}
+}
+
+func TestPruneEmptyCommand(t *testing.T) {
+	var groupID, drawn, recent int
+	var empty []int
+	path := commandDatabase(t, func(db *database.DB) {
+		var err error
+		if groupID, err = db.CreateGroup(models.ArtworkGroup{Title: "Pelican", Prompt: "A pelican"}); err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+		empty = []int{createEmptyArtwork(t, db, groupID, fakeModels[0]), createEmptyArtwork(t, db, groupID, fakeModels[1])}
+		if drawn, err = db.CreateArtwork(models.Artwork{GroupID: groupID, Model: fakeModels[2], SVG: testSVG, CreatedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+			t.Fatalf("CreateArtwork: %v", err)
+		}
+		// A generation that may still be running
+		if recent, err = db.CreateArtwork(models.Artwork{GroupID: groupID, Model: fakeModels[2], Revision: 2, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("CreateArtwork: %v", err)
+		}
+	})
+
+	// remaining returns the IDs of the artworks of the group, in order
+	remaining := func() []int {
+		var ids []int
+		withDatabase(t, path, func(db *database.DB) {
+			artworks, err := db.ListArtworksByGroup(groupID)
+			if err != nil {
+				t.Fatalf("ListArtworksByGroup: %v", err)
+			}
+			for _, artwork := range artworks {
+				ids = append(ids, artwork.ID)
+			}
+		})
+		sort.Ints(ids)
+		return ids
+	}
+
+	if code := pruneEmptyCommand([]string{"--dry-run"}); code != exitOK {
+		t.Fatalf("prune-empty --dry-run exited with %d", code)
+	}
+	if got := remaining(); len(got) != 4 {
+		t.Errorf("prune-empty --dry-run deleted artworks, %v remain", got)
+	}
+
+	if code := pruneEmptyCommand(nil); code != exitOK {
+		t.Fatalf("prune-empty exited with %d", code)
+	}
+	if got, want := remaining(), []int{drawn, recent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("artworks after prune-empty = %v, want %v", got, want)
+	}
+	withDatabase(t, path, func(db *database.DB) {
+		bin, err := db.ListRecycleBin()
+		if err != nil {
+			t.Fatalf("ListRecycleBin: %v", err)
+		}
+		if len(bin.Artworks) != len(empty) {
+			t.Errorf("recycle bin holds %d artworks, want the %d pruned", len(bin.Artworks), len(empty))
+		}
+	})
+
+	if code := pruneEmptyCommand([]string{"--min-age", "soon"}); code != exitUsage {
+		t.Errorf("prune-empty with an invalid --min-age exited with %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+)
+
+// maxRestoreBytes bounds the size of an uploaded database snapshot
+const maxRestoreBytes = 1 << 30
+
+// BackupHandler handles GET /api/admin/backup. It takes a consistent
+// snapshot of the live database into a temporary file and sends it as a
+// download named after the time it was taken.
+func (h *Handler) BackupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Admin pages are disabled")
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "pelican-backup-")
+	if err != nil {
+		log.Printf("Error creating a backup directory: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to back up the database")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	takenAt := time.Now()
+	path := filepath.Join(dir, database.BackupFileName(takenAt))
+	if err := h.db.Backup(r.Context(), path); err != nil {
+		log.Printf("Error backing up the database: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to back up the database")
+		return
+	}
+
+	snapshot, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error opening the backup: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to back up the database")
+		return
+	}
+	defer snapshot.Close()
+	info, err := snapshot.Stat()
+	if err != nil {
+		log.Printf("Error reading the backup: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to back up the database")
+		return
+	}
+
+	log.Printf("Sending a %d byte database backup", info.Size())
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filepath.Base(path)))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, snapshot); err != nil {
+		log.Printf("Error sending the backup: %v", err)
+	}
+}
+
+// RestoreHandler handles POST /api/admin/restore?confirm=replace-database.
+// The body is a snapshot as sent by BackupHandler. It is checked to open and
+// pass PRAGMA integrity_check before it replaces the whole database; the
+// confirm parameter guards against replacing it by accident.
+func (h *Handler) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Admin pages are disabled")
+		return
+	}
+
+	if r.URL.Query().Get("confirm") != config.RestoreConfirmation {
+		writeJSONError(w, http.StatusBadRequest,
+			fmt.Sprintf("Restoring replaces the whole database; confirm with ?confirm=%s", config.RestoreConfirmation))
+		return
+	}
+
+	upload, err := os.CreateTemp("", "pelican-restore-*.db")
+	if err != nil {
+		log.Printf("Error creating a file for the snapshot: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to restore the database")
+		return
+	}
+	defer os.Remove(upload.Name())
+
+	_, err = io.Copy(upload, http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+	if closeErr := upload.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Snapshot is larger than %d bytes", maxRestoreBytes))
+			return
+		}
+		log.Printf("Error receiving the snapshot: %v", err)
+		writeJSONError(w, http.StatusBadRequest, "Failed to read the snapshot")
+		return
+	}
+
+	if err := h.db.Restore(r.Context(), upload.Name()); err != nil {
+		log.Printf("Error restoring the database: %v", err)
+		if errors.Is(err, database.ErrInvalidSnapshot) {
+			writeJSONError(w, http.StatusBadRequest, "The snapshot is not a valid gallery database", err.Error())
+			return
+		}
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to restore the database")
+		return
+	}
+
+	// Every artwork may have changed
+	h.pngCache.reset()
+
+	stats, err := h.db.GetStats("")
+	if err != nil {
+		log.Printf("Error counting the restored database: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Restored the database but failed to count it")
+		return
+	}
+	version, err := h.db.SchemaVersion()
+	if err != nil {
+		log.Printf("Error reading the restored schema version: %v", err)
+	}
+	log.Printf("Restored the database from a snapshot: %d group(s), %d artwork(s)", stats.TotalGroups, stats.TotalArtworks)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Database restored",
+		"schema_version": version,
+		"total_groups":   stats.TotalGroups,
+		"total_artworks": stats.TotalArtworks,
+	})
+}
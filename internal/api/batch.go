@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pelican-gallery/internal/generation"
+	"pelican-gallery/internal/openrouter"
+)
+
+// GenerateGroupHandler handles POST /api/groups/{id}/generate-all. With
+// ?async=true it returns a job ID right away and the batch runs in the
+// background, reporting progress on /api/jobs/{id}/events. With
+// ?auto_continue=true output cut off at max_tokens is continued, and with
+// ?dry_run=true placeholder SVGs are saved without calling OpenRouter.
+func (h *Handler) GenerateGroupHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork creation is currently disabled")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	group, err := h.db.GetGroup(groupID)
+	if err != nil {
+		log.Printf("Error getting group %d: %v", groupID, err)
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	artworks, err := h.db.ListArtworksByGroup(groupID)
+	if err != nil {
+		log.Printf("Error listing artworks for group %d: %v", groupID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list artworks")
+		return
+	}
+
+	autoContinue := r.URL.Query().Get("auto_continue") == "true"
+	ctx := r.Context()
+	if r.URL.Query().Get("dry_run") == "true" {
+		ctx = openrouter.WithDryRun(ctx)
+	}
+
+	log.Printf("Batch generation for group %d: %d artwork(s), %d worker(s)", groupID, len(artworks), generation.Workers)
+
+	if r.URL.Query().Get("async") == "true" {
+		states := make([]JobEvent, len(artworks))
+		for i, artwork := range artworks {
+			states[i] = JobEvent{ArtworkID: artwork.ID, Model: artwork.Model, Status: jobStatusQueued}
+		}
+		job := h.jobs.start(groupID, states)
+
+		// The job outlives the request that started it, so it keeps the
+		// request ID but not the cancellation
+		go func() {
+			h.gen.GenerateBatch(context.WithoutCancel(ctx), group, artworks, autoContinue, job.update)
+			job.finish()
+			summary := job.summary()
+			log.Printf("Batch job %d for group %d finished: %d succeeded, %d failed", job.id, groupID, summary.Succeeded, summary.Failed)
+			time.AfterFunc(jobRetention, func() { h.jobs.forget(job.id) })
+		}()
+
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"job_id":     job.id,
+			"group_id":   groupID,
+			"total":      len(artworks),
+			"events_url": fmt.Sprintf("/api/jobs/%d/events", job.id),
+		})
+		return
+	}
+
+	results := h.gen.GenerateBatch(ctx, group, artworks, autoContinue, nil)
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	log.Printf("Batch generation for group %d finished: %d succeeded, %d failed", groupID, succeeded, len(results)-succeeded)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"group_id":  groupID,
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
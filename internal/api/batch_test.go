@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"pelican-gallery/internal/generation"
+)
+
+func TestGenerateGroupHandlerGeneratesEveryModel(t *testing.T) {
+	h, db, generator := newTestHandler(t)
+
+	groupID := seedGroup(t, db, "Pelican", "Animals")
+	models := []string{"openai/gpt-4o", "anthropic/claude-3.5-sonnet", "google/gemini-pro"}
+	ids := make(map[string]int)
+	for _, model := range models {
+		ids[model] = seedArtwork(t, db, groupID, model, "")
+	}
+
+	rec := httptest.NewRecorder()
+	h.GenerateGroupHandler(rec, newRequest(http.MethodPost, "/api/groups/1/generate-all", ""), strconv.Itoa(groupID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var response struct {
+		Total     int                      `json:"total"`
+		Succeeded int                      `json:"succeeded"`
+		Failed    int                      `json:"failed"`
+		Results   []generation.BatchResult `json:"results"`
+	}
+	decodeJSON(t, rec, &response)
+	if response.Total != 3 || response.Succeeded != 3 || response.Failed != 0 {
+		t.Errorf("response = %+v, want 3 succeeded", response)
+	}
+	for i, result := range response.Results {
+		if result.Model != models[i] || result.ArtworkID != ids[models[i]] {
+			t.Errorf("result %d = %+v, want artwork %d of %s", i, result, ids[models[i]], models[i])
+		}
+	}
+
+	if calls := generator.calls(); len(calls) != 3 {
+		t.Errorf("generator called %d times, want 3", len(calls))
+	}
+	for _, model := range models {
+		artwork, err := db.GetArtwork(ids[model])
+		if err != nil {
+			t.Fatalf("GetArtwork: %v", err)
+		}
+		if artwork.SVG != modelSVG(model) {
+			t.Errorf("artwork of %s has SVG %q, want its own", model, artwork.SVG)
+		}
+	}
+}
+
+func TestGenerateGroupHandlerRequiresEditing(t *testing.T) {
+	h, db, generator := newTestHandler(t)
+	t.Setenv("ENABLE_EDITING", "false")
+
+	groupID := seedGroup(t, db, "Pelican", "")
+	seedArtwork(t, db, groupID, "openai/gpt-4o", "")
+
+	rec := httptest.NewRecorder()
+	h.GenerateGroupHandler(rec, newRequest(http.MethodPost, "/api/groups/1/generate-all", ""), strconv.Itoa(groupID))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if calls := generator.calls(); len(calls) != 0 {
+		t.Errorf("generator called %d times with editing disabled", len(calls))
+	}
+}
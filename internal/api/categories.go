@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// renameCategoryRequest is the body of PUT /api/categories/{name}
+type renameCategoryRequest struct {
+	Name string `json:"name"`
+}
+
+// mergeCategoriesRequest is the body of POST /api/categories/merge
+type mergeCategoriesRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+// RenameCategoryHandler handles PUT /api/categories/{name}, renaming a
+// category on every group filed under it. Renaming onto a category that is
+// already in use is refused; merge the two instead.
+func (h *Handler) RenameCategoryHandler(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPut {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	var req renameCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	newName := strings.TrimSpace(req.Name)
+	if name == "" || newName == "" {
+		writeJSONError(w, http.StatusBadRequest, "Both the current and the new category name are required")
+		return
+	}
+
+	if newName != name {
+		inUse, err := h.db.CountGroupsInCategory(newName)
+		if err != nil {
+			log.Printf("Error checking category %q: %v", newName, err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to rename category")
+			return
+		}
+		if inUse > 0 {
+			writeJSONError(w, http.StatusConflict, "Category already exists, merge the categories instead", map[string]interface{}{
+				"category": newName,
+				"groups":   inUse,
+			})
+			return
+		}
+	}
+
+	h.moveCategory(w, name, newName)
+}
+
+// MergeCategoriesHandler handles POST /api/categories/merge, moving every
+// group of one category into another
+func (h *Handler) MergeCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	var req mergeCategoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	from, into := strings.TrimSpace(req.From), strings.TrimSpace(req.Into)
+	if from == "" || into == "" {
+		writeJSONError(w, http.StatusBadRequest, "from and into are required")
+		return
+	}
+
+	h.moveCategory(w, from, into)
+}
+
+// moveCategory refiles the groups of one category under another and reports
+// how many groups moved; moving a category onto itself moves nothing
+func (h *Handler) moveCategory(w http.ResponseWriter, from, to string) {
+	affected, err := h.db.RenameCategory(from, to)
+	if err != nil {
+		log.Printf("Error moving category %q to %q: %v", from, to, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to update category")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"from":     from,
+		"to":       to,
+		"affected": affected,
+	})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"pelican-gallery/internal/database"
+)
+
+// categoryCounts returns the number of groups per category of db
+func categoryCounts(t *testing.T, db *database.DB) map[string]int {
+	t.Helper()
+	groups, err := db.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, g := range groups {
+		counts[g.Category]++
+	}
+	return counts
+}
+
+// categoryResult is the body of a successful rename or merge
+type categoryResult struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Affected int64  `json:"affected"`
+}
+
+func TestRenameCategoryHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	seedGroup(t, db, "Pelican", "nature")
+	seedGroup(t, db, "Heron", "nature")
+	seedGroup(t, db, "Bicycle", "vehicles")
+
+	rename := func(name, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.RenameCategoryHandler(rec, newRequest(http.MethodPut, "/api/categories/"+name, body), name)
+		return rec
+	}
+
+	rec := rename("nature", `{"name": "Nature"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename = %d, body %s", rec.Code, rec.Body)
+	}
+	var result categoryResult
+	decodeJSON(t, rec, &result)
+	if want := (categoryResult{"nature", "Nature", 2}); result != want {
+		t.Errorf("rename = %+v, want %+v", result, want)
+	}
+	if got, want := categoryCounts(t, db), map[string]int{"Nature": 2, "vehicles": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("categories after rename = %v, want %v", got, want)
+	}
+
+	// Renaming onto itself changes nothing
+	rec = rename("Nature", `{"name": " Nature "}`)
+	result = categoryResult{}
+	decodeJSON(t, rec, &result)
+	if rec.Code != http.StatusOK || result.Affected != 0 {
+		t.Errorf("no-op rename = %d %+v, want 0 affected", rec.Code, result)
+	}
+
+	// Renaming onto a category in use is a merge, which rename refuses
+	rec = rename("Nature", `{"name": "vehicles"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("rename onto vehicles = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	tests := []struct {
+		name, method, category, body string
+		want                         int
+	}{
+		{"no new name", http.MethodPut, "Nature", `{"name": "  "}`, http.StatusBadRequest},
+		{"bad body", http.MethodPut, "Nature", `{`, http.StatusBadRequest},
+		{"wrong method", http.MethodPost, "Nature", `{"name": "Wild"}`, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.RenameCategoryHandler(rec, newRequest(tt.method, "/api/categories/"+tt.category, tt.body), tt.category)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := rename("Nature", `{"name": "Wild"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("rename without editing = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestMergeCategoriesHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	seedGroup(t, db, "Pelican", "birds")
+	seedGroup(t, db, "Heron", "Birds")
+	seedGroup(t, db, "Owl", "Birds")
+	seedGroup(t, db, "Bicycle", "vehicles")
+
+	merge := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.MergeCategoriesHandler(rec, newRequest(http.MethodPost, "/api/categories/merge", body))
+		return rec
+	}
+
+	rec := merge(`{"from": "Birds", "into": "birds"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("merge = %d, body %s", rec.Code, rec.Body)
+	}
+	var result categoryResult
+	decodeJSON(t, rec, &result)
+	if want := (categoryResult{"Birds", "birds", 2}); result != want {
+		t.Errorf("merge = %+v, want %+v", result, want)
+	}
+	if got, want := categoryCounts(t, db), map[string]int{"birds": 3, "vehicles": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("categories after merge = %v, want %v", got, want)
+	}
+
+	// The merged category is gone from the category list
+	categories, err := db.GetDistinctCategories()
+	if err != nil {
+		t.Fatalf("GetDistinctCategories: %v", err)
+	}
+	var names []string
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"birds", "vehicles"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("categories = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name, body string
+		want       int
+		affected   int64
+	}{
+		{"into itself", `{"from": "birds", "into": "birds"}`, http.StatusOK, 0},
+		{"unknown source", `{"from": "fish", "into": "birds"}`, http.StatusOK, 0},
+		{"missing into", `{"from": "birds"}`, http.StatusBadRequest, 0},
+		{"bad body", `[`, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := merge(tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+			continue
+		}
+		if rec.Code == http.StatusOK {
+			result := categoryResult{}
+			decodeJSON(t, rec, &result)
+			if result.Affected != tt.affected {
+				t.Errorf("%s: affected = %d, want %d", tt.name, result.Affected, tt.affected)
+			}
+		}
+	}
+	if got, want := categoryCounts(t, db), map[string]int{"birds": 3, "vehicles": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("categories after no-op merges = %v, want %v", got, want)
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := merge(`{"from": "vehicles", "into": "birds"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("merge without editing = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// GroupComparisonHandler handles GET /api/groups/{id}/comparison. It returns
+// the group with its artworks grouped by model, for showing every model's
+// rendition of the prompt side by side. ?models=a,b keeps only the listed
+// models, in that order; otherwise models come in the FEATURED_MODEL_ORDER
+// the homepage uses. Within a model the featured artwork comes first, then
+// the newest revision.
+func (h *Handler) GroupComparisonHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	group, err := h.db.GetGroup(groupID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	artworks, err := h.db.ListArtworksByGroup(groupID)
+	if err != nil {
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to list artworks")
+		return
+	}
+	artworks = models.FilterVisible(artworks, viewScope(r))
+	config.AnnotateDeprecated(artworks)
+
+	writeJSON(w, http.StatusOK, models.GroupComparison{
+		Group:  *group,
+		Models: compareModels(artworks, parseModelList(r.URL.Query().Get("models"))),
+	})
+}
+
+// parseModelList splits a comma-separated list of model IDs, dropping blanks
+// and repeats
+func parseModelList(raw string) []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(raw, ",") {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
+// compareModels groups artworks by model. With only set, just those models
+// are kept, in its order; otherwise models are ordered like the homepage's
+// featured artworks. Models without artworks are left out.
+func compareModels(artworks []models.Artwork, only []string) []models.ModelRenditions {
+	byModel := make(map[string][]models.Artwork)
+	var representatives []models.Artwork
+	for _, artwork := range artworks {
+		if _, seen := byModel[artwork.Model]; !seen {
+			representatives = append(representatives, artwork)
+		}
+		byModel[artwork.Model] = append(byModel[artwork.Model], artwork)
+	}
+
+	order := only
+	if len(order) == 0 {
+		config.SortFeaturedArtworks(representatives)
+		for _, artwork := range representatives {
+			order = append(order, artwork.Model)
+		}
+	}
+
+	infos := config.CachedModelInfos()
+	comparison := []models.ModelRenditions{}
+	for _, model := range order {
+		renditions, ok := byModel[model]
+		if !ok {
+			continue
+		}
+		sort.SliceStable(renditions, func(i, j int) bool {
+			a, b := renditions[i], renditions[j]
+			if a.Featured != b.Featured {
+				return a.Featured
+			}
+			if a.Revision != b.Revision {
+				return a.Revision > b.Revision
+			}
+			return a.ID > b.ID
+		})
+
+		info := infos[model]
+		comparison = append(comparison, models.ModelRenditions{
+			Model:          model,
+			Name:           info.Name,
+			Provider:       config.ModelProvider(model),
+			Cost:           info.Cost,
+			Deprecated:     renditions[0].Deprecated,
+			SuggestedModel: renditions[0].SuggestedModel,
+			Artworks:       renditions,
+		})
+	}
+	return comparison
+}
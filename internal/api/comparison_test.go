@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestGroupComparisonHandler(t *testing.T) {
+	t.Setenv("FEATURED_MODEL_ORDER", "google/gemini-2.5-pro,openai/gpt-4o")
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "birds")
+	seedArtwork(t, db, groupID, "anthropic/claude-sonnet-4", testSVG)
+	first := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	seedArtwork(t, db, groupID, "google/gemini-2.5-pro", testSVG)
+	private := seedArtwork(t, db, groupID, "x-ai/grok-4", testSVG)
+	if err := db.SetArtworkVisibility(private, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	// Two more generations of GPT-4o, with the first one featured
+	artwork, err := db.GetArtwork(first)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	var revisions []int
+	for i := 0; i < 2; i++ {
+		revision, err := db.AddArtworkRevision(*artwork)
+		if err != nil {
+			t.Fatalf("AddArtworkRevision: %v", err)
+		}
+		if err := db.SaveArtworkSVG(revision.ID, modelSVG("revision "+strconv.Itoa(revision.Revision))); err != nil {
+			t.Fatalf("SaveArtworkSVG: %v", err)
+		}
+		revisions = append(revisions, revision.ID)
+	}
+	if err := db.SetFeaturedArtwork(first, false); err != nil {
+		t.Fatalf("SetFeaturedArtwork: %v", err)
+	}
+
+	group := strconv.Itoa(groupID)
+	compare := func(query string) models.GroupComparison {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GroupComparisonHandler(rec, newRequest(http.MethodGet, "/api/groups/"+group+"/comparison"+query, ""), group)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET comparison%s = %d, body %s", query, rec.Code, rec.Body)
+		}
+		var comparison models.GroupComparison
+		decodeJSON(t, rec, &comparison)
+		if comparison.Group.ID != groupID {
+			t.Errorf("comparison of group %d, want %d", comparison.Group.ID, groupID)
+		}
+		return comparison
+	}
+	modelIDs := func(comparison models.GroupComparison) []string {
+		var ids []string
+		for _, renditions := range comparison.Models {
+			ids = append(ids, renditions.Model)
+			for _, artwork := range renditions.Artworks {
+				if artwork.Model != renditions.Model || artwork.SVG == "" {
+					t.Errorf("%s lists artwork %d of %s with %d bytes of SVG", renditions.Model, artwork.ID, artwork.Model, len(artwork.SVG))
+				}
+			}
+		}
+		return ids
+	}
+
+	// Models follow FEATURED_MODEL_ORDER, then name; private ones are left out
+	comparison := compare("")
+	if got, want := modelIDs(comparison), []string{"google/gemini-2.5-pro", "openai/gpt-4o", "anthropic/claude-sonnet-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("models = %v, want %v", got, want)
+	}
+	gpt := comparison.Models[1]
+	var ids []int
+	for _, artwork := range gpt.Artworks {
+		ids = append(ids, artwork.ID)
+	}
+	// The featured artwork first, then the newest revision
+	if want := []int{first, revisions[1], revisions[0]}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GPT-4o artworks = %v, want %v", ids, want)
+	}
+	if gpt.Provider != "openai" {
+		t.Errorf("GPT-4o provider = %q, want openai", gpt.Provider)
+	}
+
+	// ?models= keeps the listed models in its order, skipping unknown ones
+	comparison = compare("?models=anthropic/claude-sonnet-4,+missing/model,openai/gpt-4o,anthropic/claude-sonnet-4,x-ai/grok-4")
+	if got, want := modelIDs(comparison), []string{"anthropic/claude-sonnet-4", "openai/gpt-4o"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered models = %v, want %v", got, want)
+	}
+
+	errorTests := []struct {
+		name, method, id string
+		want             int
+	}{
+		{"unknown group", http.MethodGet, "999", http.StatusNotFound},
+		{"bad group ID", http.MethodGet, "x", http.StatusBadRequest},
+		{"POST", http.MethodPost, group, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range errorTests {
+		rec := httptest.NewRecorder()
+		h.GroupComparisonHandler(rec, newRequest(tt.method, "/api/groups/"+tt.id+"/comparison", ""), tt.id)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	tests := map[string][]string{
+		"":                   nil,
+		"a/b":                {"a/b"},
+		" a/b , c/d ,, a/b ": {"a/b", "c/d"},
+	}
+	for raw, want := range tests {
+		if got := parseModelList(raw); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseModelList(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/render"
+)
+
+// dailyDateLayout is the format of the date of a daily matchup
+const dailyDateLayout = "2006-01-02"
+
+// dailyArtwork is one side of the daily matchup. The model stays hidden, as
+// on the vote page, so the matchup can be voted on.
+type dailyArtwork struct {
+	ID       int    `json:"id"`
+	SVG      string `json:"svg"`
+	ImageURL string `json:"image_url"`
+}
+
+// dailyMatchup is the response of GET /api/daily
+type dailyMatchup struct {
+	Date     string               `json:"date"`
+	Group    *models.ArtworkGroup `json:"group"`
+	GroupURL string               `json:"group_url,omitempty"`
+	Artworks []dailyArtwork       `json:"artworks"`
+}
+
+// DailyMatchupHandler handles GET /api/daily, the "pelican of the day": a
+// group and two of its artworks by different models, picked at random but
+// seeded with the UTC date so the matchup holds for the whole day. ?date=
+// asks for another day. When no group has artworks by two models, group is
+// null and artworks is empty.
+func (h *Handler) DailyMatchupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	now := time.Now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	if value := r.URL.Query().Get("date"); value != "" {
+		parsed, err := time.Parse(dailyDateLayout, value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid date %q: use YYYY-MM-DD", value))
+			return
+		}
+		day = parsed
+	}
+	date := day.Format(dailyDateLayout)
+
+	group, pair, err := h.db.GetSeededArtworkPair(dailySeed(date))
+	if err != nil {
+		log.Printf("Error picking the matchup of %s: %v", date, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to pick the daily matchup")
+		return
+	}
+
+	matchup := dailyMatchup{Date: date, Artworks: []dailyArtwork{}}
+	if group != nil {
+		base := config.RequestBaseURL(r)
+		matchup.Group = group
+		matchup.GroupURL = fmt.Sprintf("%s/group/%d", base, group.ID)
+		for _, artwork := range pair {
+			matchup.Artworks = append(matchup.Artworks, dailyArtwork{
+				ID:       artwork.ID,
+				SVG:      artwork.SVG,
+				ImageURL: fmt.Sprintf("%s/artworks/%d.png?v=%s", base, artwork.ID, render.Version(artwork.SVG)),
+			})
+		}
+	}
+
+	// Today's matchup is cached until midnight UTC, other days for a day; an
+	// empty matchup is retried soon in case artworks are added
+	maxAge := 24 * time.Hour
+	if day.Equal(now.Truncate(24 * time.Hour)) {
+		maxAge = day.Add(24 * time.Hour).Sub(now)
+	}
+	if group == nil {
+		maxAge = 5 * time.Minute
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	writeJSON(w, http.StatusOK, matchup)
+}
+
+// dailySeed derives the random seed of a day's matchup from its date
+func dailySeed(date string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dailyMatchupOf serves the daily matchup for query and decodes it
+func dailyMatchupOf(t *testing.T, h *Handler, query string) (dailyMatchup, *httptest.ResponseRecorder) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.DailyMatchupHandler(rec, newRequest(http.MethodGet, "/api/daily"+query, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/daily%s = %d: %s", query, rec.Code, rec.Body)
+	}
+	var matchup dailyMatchup
+	decodeJSON(t, rec, &matchup)
+	return matchup, rec
+}
+
+func TestDailyMatchupHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupOf := make(map[int]int) // artwork ID -> group ID
+	for i := 0; i < 10; i++ {
+		groupID := seedGroup(t, db, "Group "+strconv.Itoa(i), "")
+		for _, model := range []string{"openai/gpt-4o", "anthropic/claude-sonnet-4", "google/gemini-2.5-pro"} {
+			groupOf[seedArtwork(t, db, groupID, model, modelSVG(model))] = groupID
+		}
+	}
+
+	first, rec := dailyMatchupOf(t, h, "?date=2025-06-01")
+	if first.Date != "2025-06-01" || first.Group == nil || len(first.Artworks) != 2 {
+		t.Fatalf("matchup = %+v", first)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=86400" {
+		t.Errorf("Cache-Control of a past day = %q, want a day", cc)
+	}
+	if first.GroupURL != "http://example.com/group/"+strconv.Itoa(first.Group.ID) {
+		t.Errorf("group_url = %q", first.GroupURL)
+	}
+	for _, artwork := range first.Artworks {
+		if groupOf[artwork.ID] != first.Group.ID {
+			t.Errorf("artwork %d is not of group %d", artwork.ID, first.Group.ID)
+		}
+		if !strings.HasPrefix(artwork.ImageURL, "http://example.com/artworks/"+strconv.Itoa(artwork.ID)+".png?v=") {
+			t.Errorf("image_url = %q", artwork.ImageURL)
+		}
+	}
+	if first.Artworks[0].SVG == first.Artworks[1].SVG {
+		t.Errorf("both artworks are by the same model: %s", first.Artworks[0].SVG)
+	}
+
+	// The same date picks the same matchup every time
+	for i := 0; i < 5; i++ {
+		again, _ := dailyMatchupOf(t, h, "?date=2025-06-01")
+		if again.Group.ID != first.Group.ID || again.Artworks[0].ID != first.Artworks[0].ID || again.Artworks[1].ID != first.Artworks[1].ID {
+			t.Fatalf("2025-06-01 picked group %d (%d/%d), then group %d (%d/%d)",
+				first.Group.ID, first.Artworks[0].ID, first.Artworks[1].ID,
+				again.Group.ID, again.Artworks[0].ID, again.Artworks[1].ID)
+		}
+	}
+
+	// Other dates pick other groups
+	groups := make(map[int]bool)
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		matchup, _ := dailyMatchupOf(t, h, "?date="+day.AddDate(0, 0, i).Format(dailyDateLayout))
+		groups[matchup.Group.ID] = true
+	}
+	if len(groups) < 2 {
+		t.Errorf("30 days all picked the same group")
+	}
+
+	// Without a date, today's matchup is cached until midnight UTC
+	today, rec := dailyMatchupOf(t, h, "")
+	if want := time.Now().UTC().Format(dailyDateLayout); today.Date != want {
+		t.Errorf("date = %q, want today, %s", today.Date, want)
+	}
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(rec.Header().Get("Cache-Control"), "public, max-age="))
+	if err != nil || maxAge < 0 || maxAge > 24*60*60 {
+		t.Errorf("Cache-Control of today = %q, want up to midnight", rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestDailyMatchupHandlerWithoutMatchup(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	// A single model cannot be compared
+	groupID := seedGroup(t, db, "Pelican", "")
+	seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+
+	matchup, rec := dailyMatchupOf(t, h, "?date=2025-06-01")
+	if matchup.Group != nil || matchup.Artworks == nil || len(matchup.Artworks) != 0 {
+		t.Errorf("matchup without qualifying groups = %+v, want no group and no artworks", matchup)
+	}
+	if !strings.Contains(rec.Body.String(), `"artworks":[]`) {
+		t.Errorf("body = %s, want an empty artworks array", rec.Body)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=300" {
+		t.Errorf("Cache-Control = %q, want a short max-age", cc)
+	}
+
+	tests := []struct {
+		method, query string
+		want          int
+	}{
+		{http.MethodGet, "?date=yesterday", http.StatusBadRequest},
+		{http.MethodGet, "?date=2025-13-01", http.StatusBadRequest},
+		{http.MethodPost, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.DailyMatchupHandler(rec, newRequest(tt.method, "/api/daily"+tt.query, ""))
+		if rec.Code != tt.want {
+			t.Errorf("%s /api/daily%s = %d, want %d", tt.method, tt.query, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// exportVersion identifies the layout of the export document
+const exportVersion = 1
+
+// exportGroup is a group in the export document with everything needed to
+// recreate it
+type exportGroup struct {
+	models.ArtworkGroup
+	OriginalArtwork *exportOriginalArtwork `json:"original_artwork,omitempty"`
+	Artworks        []models.Artwork       `json:"artworks"`
+}
+
+// exportOriginalArtwork carries the reference image; Data is base64 in JSON
+type exportOriginalArtwork struct {
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"data"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// ExportHandler handles GET /api/export. It streams every group, archived ones
+// included, with its artworks and reference image as one JSON document, one
+// group at a time so memory stays flat for large galleries. ?category= limits
+// the export to one category; see startDownload for compression. Private
+// artworks are only exported to requests with the admin API key.
+func (h *Handler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Export is only available while editing is enabled")
+		return
+	}
+
+	category := r.URL.Query().Get("category")
+
+	groups, err := h.db.ListGroups()
+	if err != nil {
+		log.Printf("Error listing groups for export: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list groups")
+		return
+	}
+
+	out := startDownload(w, r, "pelican-gallery-export.json", "application/json")
+	defer out.Close()
+
+	// Once streaming has started the status can no longer change, so a failure
+	// midway is only logged; the client sees a truncated, unparseable document
+	exported, err := h.writeExport(out, groups, category, viewScope(r))
+	if err != nil {
+		log.Printf("Export aborted after %d group(s): %v", exported, err)
+		return
+	}
+
+	log.Printf("Exported %d group(s) (category=%q)", exported, category)
+}
+
+// startDownload sets the headers of a file download and returns the writer for
+// its body, which must be closed. The body is gzip-compressed as it streams
+// when the client accepts gzip, served with Content-Encoding so browsers
+// unpack it transparently. ?gzip=1 forces compression for CLI downloads and
+// serves the file itself as a .gz, so `curl -O` saves a valid archive.
+func startDownload(w http.ResponseWriter, r *http.Request, filename, contentType string) io.WriteCloser {
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if forced, _ := strconv.ParseBool(r.URL.Query().Get("gzip")); forced {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".gz"))
+		return gzip.NewWriter(w)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		return gzip.NewWriter(w)
+	}
+	return nopWriteCloser{w}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip, honoring
+// q=0 refusals; an explicit gzip entry takes precedence over "*"
+func acceptsGzip(r *http.Request) bool {
+	wildcard := false
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+
+		accepted := true
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				accepted = false
+			}
+		}
+
+		switch {
+		case strings.EqualFold(coding, "gzip"):
+			return accepted
+		case coding == "*":
+			wildcard = accepted
+		}
+	}
+	return wildcard
+}
+
+// nopWriteCloser adds a no-op Close to an uncompressed response body
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Export writes the document GET /api/export serves, for the groups in
+// category (all when empty), private artworks included, and returns how
+// many groups were written. It is for callers outside HTTP such as the
+// export command.
+func (h *Handler) Export(out io.Writer, category string) (int, error) {
+	groups, err := h.db.ListGroups()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list groups: %w", err)
+	}
+	return h.writeExport(out, groups, category, models.ScopeEditing)
+}
+
+// writeExport writes the export document for the groups in category (all
+// when empty), with the artworks visible in scope, and returns how many
+// groups were written
+func (h *Handler) writeExport(out io.Writer, groups []models.ArtworkGroup, category string, scope models.ViewScope) (int, error) {
+	header, err := json.Marshal(struct {
+		Version    int       `json:"version"`
+		ExportedAt time.Time `json:"exported_at"`
+		Category   string    `json:"category,omitempty"`
+	}{exportVersion, time.Now().UTC(), category})
+	if err != nil {
+		return 0, err
+	}
+
+	// Splice the groups array into the header object
+	if _, err := fmt.Fprintf(out, "%s,\"groups\":[\n", header[:len(header)-1]); err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(out)
+	exported := 0
+	for _, group := range groups {
+		if category != "" && group.Category != category {
+			continue
+		}
+
+		entry, err := h.exportEntry(group, scope)
+		if err != nil {
+			return exported, err
+		}
+
+		if exported > 0 {
+			if _, err := io.WriteString(out, ","); err != nil {
+				return exported, err
+			}
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+
+	_, err = io.WriteString(out, "]}\n")
+	return exported, err
+}
+
+// exportEntry collects a group's artworks visible in scope and its reference
+// image for the export document
+func (h *Handler) exportEntry(group models.ArtworkGroup, scope models.ViewScope) (exportGroup, error) {
+	entry := exportGroup{ArtworkGroup: group, Artworks: []models.Artwork{}}
+
+	artworks, err := h.db.ListArtworksByGroup(group.ID)
+	if err != nil {
+		return entry, fmt.Errorf("failed to list artworks for group %d: %w", group.ID, err)
+	}
+	if visible := models.FilterVisible(artworks, scope); visible != nil {
+		entry.Artworks = visible
+	}
+
+	if group.HasOriginalArtwork {
+		original, err := h.db.GetOriginalArtwork(group.ID)
+		if err != nil {
+			return entry, fmt.Errorf("failed to get original artwork for group %d: %w", group.ID, err)
+		}
+		entry.OriginalArtwork = &exportOriginalArtwork{
+			ContentType: original.ContentType,
+			Data:        original.Data,
+			UploadedAt:  original.UploadedAt,
+		}
+		if entry.OriginalArtwork.ContentType == "" {
+			entry.OriginalArtwork.ContentType = http.DetectContentType(original.Data)
+		}
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/models"
+)
+
+// exportDocument is the decoded body of GET /api/export
+type exportDocument struct {
+	Version  int           `json:"version"`
+	Category string        `json:"category"`
+	Groups   []exportGroup `json:"groups"`
+}
+
+// seedExportGallery fills db with groups in two categories, one archived and
+// one with a reference image, and returns their IDs
+func seedExportGallery(t *testing.T, db *database.DB) []int {
+	t.Helper()
+	birds := seedGroup(t, db, "Pelican", "birds")
+	seedArtwork(t, db, birds, "openai/gpt-4o", testSVG)
+	seedArtwork(t, db, birds, "anthropic/claude-sonnet-4", "")
+	if err := db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: birds, ContentType: "image/png", Data: testPNG(t, 4, 4), UploadedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+
+	archived := seedGroup(t, db, "Heron", "birds")
+	seedArtwork(t, db, archived, "google/gemini-2.5-pro", testSVG)
+	if err := db.SetGroupArchived(archived, true); err != nil {
+		t.Fatalf("SetGroupArchived: %v", err)
+	}
+
+	bicycle := seedGroup(t, db, "Bicycle", "vehicles")
+	seedArtwork(t, db, bicycle, "openai/gpt-4o", testSVG)
+	return []int{birds, archived, bicycle}
+}
+
+// export requests target and decodes the export document
+func export(t *testing.T, h *Handler, target string) exportDocument {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ExportHandler(rec, newRequest(http.MethodGet, target, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, body %s", target, rec.Code, rec.Body)
+	}
+	var doc exportDocument
+	decodeJSON(t, rec, &doc)
+	return doc
+}
+
+// sameJSON reports whether a and b encode to the same JSON
+func sameJSON(t *testing.T, a, b interface{}) bool {
+	t.Helper()
+	ja, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Equal(ja, jb)
+}
+
+func TestExportHandlerRoundTrips(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	ids := seedExportGallery(t, db)
+
+	doc := export(t, h, "/api/export")
+	if doc.Version != exportVersion {
+		t.Errorf("version = %d, want %d", doc.Version, exportVersion)
+	}
+	if len(doc.Groups) != len(ids) {
+		t.Fatalf("exported %d groups, want %d", len(doc.Groups), len(ids))
+	}
+
+	for i, id := range ids {
+		exported := doc.Groups[i]
+		group, err := db.GetGroup(id)
+		if err != nil {
+			t.Fatalf("GetGroup: %v", err)
+		}
+		if !sameJSON(t, exported.ArtworkGroup, group) {
+			t.Errorf("group %d exported as %+v, want %+v", id, exported.ArtworkGroup, group)
+		}
+
+		artworks, err := db.ListArtworksByGroup(id)
+		if err != nil {
+			t.Fatalf("ListArtworksByGroup: %v", err)
+		}
+		if len(exported.Artworks) != len(artworks) || !sameJSON(t, exported.Artworks, artworks) {
+			t.Errorf("artworks of group %d exported as %+v, want %+v", id, exported.Artworks, artworks)
+		}
+	}
+
+	original := doc.Groups[0].OriginalArtwork
+	if original == nil || original.ContentType != "image/png" || !bytes.Equal(original.Data, testPNG(t, 4, 4)) {
+		t.Errorf("original artwork exported as %+v", original)
+	}
+	if doc.Groups[1].OriginalArtwork != nil || !doc.Groups[1].Archived {
+		t.Errorf("archived group exported as %+v", doc.Groups[1])
+	}
+}
+
+func TestExportHandlerLeavesOutPrivateArtworks(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "birds")
+	public := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	private := seedArtwork(t, db, groupID, "anthropic/claude-sonnet-4", testSVG)
+	if err := db.SetArtworkVisibility(private, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	exportedIDs := func(doc exportDocument) []int {
+		var ids []int
+		for _, artwork := range doc.Groups[0].Artworks {
+			ids = append(ids, artwork.ID)
+		}
+		return ids
+	}
+
+	if got := exportedIDs(export(t, h, "/api/export")); !reflect.DeepEqual(got, []int{public}) {
+		t.Errorf("anonymous export has artworks %v, want only the public %d", got, public)
+	}
+
+	rec := httptest.NewRecorder()
+	r := newRequest(http.MethodGet, "/api/export", "")
+	h.ExportHandler(rec, r.WithContext(config.WithAdmin(r.Context())))
+	var doc exportDocument
+	decodeJSON(t, rec, &doc)
+	if got := exportedIDs(doc); !reflect.DeepEqual(got, []int{public, private}) {
+		t.Errorf("admin export has artworks %v, want %d and %d", got, public, private)
+	}
+
+	// The export command is run by the operator and keeps everything
+	var buf bytes.Buffer
+	if _, err := h.Export(&buf, ""); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if got := exportedIDs(doc); !reflect.DeepEqual(got, []int{public, private}) {
+		t.Errorf("Export has artworks %v, want %d and %d", got, public, private)
+	}
+}
+
+func TestExportHandlerCategory(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	ids := seedExportGallery(t, db)
+
+	doc := export(t, h, "/api/export?category=vehicles")
+	if doc.Category != "vehicles" || len(doc.Groups) != 1 || doc.Groups[0].ID != ids[2] {
+		t.Errorf("vehicles export = %+v", doc)
+	}
+
+	doc = export(t, h, "/api/export?category=none")
+	if len(doc.Groups) != 0 {
+		t.Errorf("export of an empty category has %d groups", len(doc.Groups))
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	rec := httptest.NewRecorder()
+	h.ExportHandler(rec, newRequest(http.MethodGet, "/api/export", ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("export without editing = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestExportHandlerGzip(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	seedExportGallery(t, db)
+	plain := export(t, h, "/api/export")
+
+	tests := []struct {
+		name, query, acceptEncoding string
+		wantGzip                    bool
+		wantEncoding, wantType      string
+	}{
+		{"accepted", "", "gzip, deflate", true, "gzip", "application/json"},
+		{"forced", "?gzip=1", "", true, "", "application/gzip"},
+		{"refused", "", "gzip;q=0, *", false, "", "application/json"},
+		{"wildcard", "", "*", true, "gzip", "application/json"},
+		{"none", "", "", false, "", "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest(http.MethodGet, "/api/export"+tt.query, "")
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ExportHandler(rec, r)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+
+			var body io.Reader = rec.Body
+			if tt.wantGzip {
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("body is not gzip: %v", err)
+				}
+				body = zr
+			}
+			var doc exportDocument
+			if err := json.NewDecoder(body).Decode(&doc); err != nil {
+				t.Fatalf("decode export: %v", err)
+			}
+			if !sameJSON(t, doc.Groups, plain.Groups) {
+				t.Errorf("export decoded to %+v, want %+v", doc.Groups, plain.Groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// groupExportArtwork is an artwork of the HTML group export with its SVG
+// ready to inline
+type groupExportArtwork struct {
+	models.Artwork
+	SVGContent template.HTML
+}
+
+// groupExportPage is the data of the group-export.html template
+type groupExportPage struct {
+	Group           models.ArtworkGroup
+	Artworks        []groupExportArtwork
+	OriginalArtwork template.URL // data: URI of the reference image, if any
+	GroupURL        string
+	ExportedAt      time.Time
+}
+
+// GroupExportHandler handles GET /api/groups/{id}/export. ?format=html, the
+// default, downloads the group as one standalone HTML page with its SVGs,
+// reference image and styles inline, to share a comparison offline.
+// ?format=json downloads the group as an export document, which /api/import
+// reads back. Only the artworks visible on the group page are included.
+func (h *Handler) GroupExportHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "json" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown format %q: use html or json", format))
+		return
+	}
+
+	group, err := h.db.GetGroup(groupID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	entry, err := h.exportEntry(*group, viewScope(r))
+	if err != nil {
+		log.Printf("Error exporting group %d: %v", groupID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to export group")
+		return
+	}
+
+	exportedAt := time.Now().UTC()
+	filename := fmt.Sprintf("pelican-gallery-group-%d.%s", groupID, format)
+
+	// The document is assembled before anything is sent, so a failure can
+	// still be answered with an error
+	var body bytes.Buffer
+	var contentType string
+	switch format {
+	case "html":
+		contentType = "text/html; charset=utf-8"
+		err = h.tmpl.ExecuteTemplate(&body, "group-export.html", groupExportData(entry, config.RequestBaseURL(r), exportedAt))
+	case "json":
+		contentType = "application/json"
+		err = json.NewEncoder(&body).Encode(struct {
+			Version    int           `json:"version"`
+			ExportedAt time.Time     `json:"exported_at"`
+			Groups     []exportGroup `json:"groups"`
+		}{exportVersion, exportedAt, []exportGroup{entry}})
+	}
+	if err != nil {
+		log.Printf("Error rendering the %s export of group %d: %v", format, groupID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to export group")
+		return
+	}
+
+	out := startDownload(w, r, filename, contentType)
+	defer out.Close()
+	if _, err := body.WriteTo(out); err != nil {
+		log.Printf("Error sending the export of group %d: %v", groupID, err)
+		return
+	}
+
+	log.Printf("Exported group %d as %s (%d artwork(s))", groupID, format, len(entry.Artworks))
+}
+
+// groupExportData prepares an exported group for the group-export.html
+// template
+func groupExportData(entry exportGroup, baseURL string, exportedAt time.Time) groupExportPage {
+	page := groupExportPage{
+		Group:      entry.ArtworkGroup,
+		Artworks:   make([]groupExportArtwork, len(entry.Artworks)),
+		GroupURL:   fmt.Sprintf("%s/group/%d", baseURL, entry.ID),
+		ExportedAt: exportedAt,
+	}
+	for i, artwork := range entry.Artworks {
+		// SVGs are sanitized when they are saved, as on the group page
+		page.Artworks[i] = groupExportArtwork{Artwork: artwork, SVGContent: template.HTML(artwork.SVG)}
+	}
+	if original := entry.OriginalArtwork; original != nil {
+		page.OriginalArtwork = template.URL("data:" + original.ContentType + ";base64," + base64.StdEncoding.EncodeToString(original.Data))
+	}
+	return page
+}
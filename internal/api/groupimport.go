@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// maxGroupImportBytes bounds the size of a bulk group import
+const maxGroupImportBytes = 10 << 20
+
+// groupImportColumns are the CSV columns a bulk group import understands;
+// title and prompt are required, the others optional
+var groupImportColumns = []string{"title", "prompt", "category", "original_url", "artist_name", "prompt_style"}
+
+// groupImportRow is one group of a bulk group import
+type groupImportRow struct {
+	Title       string `json:"title"`
+	Prompt      string `json:"prompt"`
+	Category    string `json:"category"`
+	OriginalURL string `json:"original_url"`
+	ArtistName  string `json:"artist_name"`
+	PromptStyle string `json:"prompt_style"`
+}
+
+// ImportGroupsHandler handles POST /api/groups/import. The body is a CSV file
+// with a header row (Content-Type: text/csv) or a JSON array of groups. Valid
+// rows are created in one transaction; rows whose title already exists are
+// skipped and invalid rows are reported without stopping the others.
+func (h *Handler) ImportGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork creation is currently disabled")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxGroupImportBytes)
+
+	var rows []groupImportRow
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		rows, err = readGroupImportCSV(body)
+	case "", "application/json":
+		err = json.NewDecoder(body).Decode(&rows)
+	default:
+		writeJSONError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Type %q: send text/csv or application/json", mediaType))
+		return
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import is larger than %d bytes", maxGroupImportBytes))
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "Invalid import body", err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "The import has no rows")
+		return
+	}
+
+	results := make([]models.GroupRowResult, len(rows))
+	var groups []models.ArtworkGroup
+	var groupRows []int
+	now := time.Now()
+	for i, row := range rows {
+		results[i] = models.GroupRowResult{Row: i + 1, Title: strings.TrimSpace(row.Title)}
+		group, err := h.validateGroupImportRow(row)
+		if err != nil {
+			results[i].Status = models.GroupRowError
+			results[i].Error = err.Error()
+			continue
+		}
+		group.CreatedAt = now
+		group.UpdatedAt = now
+		groups = append(groups, group)
+		groupRows = append(groupRows, i)
+	}
+
+	if len(groups) > 0 {
+		ids, err := h.db.CreateGroups(groups)
+		if err != nil {
+			log.Printf("Bulk group import failed and was rolled back: %v", err)
+			writeDBError(w, err, http.StatusInternalServerError, "Import failed; nothing was imported", err.Error())
+			return
+		}
+		for j, id := range ids {
+			result := &results[groupRows[j]]
+			if id == 0 {
+				result.Status = models.GroupRowSkipped
+				result.Error = "a group with this title already exists"
+				continue
+			}
+			result.Status = models.GroupRowCreated
+			result.ID = id
+		}
+	}
+
+	counts := map[string]int{models.GroupRowCreated: 0, models.GroupRowSkipped: 0, models.GroupRowError: 0}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	log.Printf("Bulk imported %d group(s) (%d skipped, %d invalid)",
+		counts[models.GroupRowCreated], counts[models.GroupRowSkipped], counts[models.GroupRowError])
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"created": counts[models.GroupRowCreated],
+		"skipped": counts[models.GroupRowSkipped],
+		"errors":  counts[models.GroupRowError],
+		"results": results,
+	})
+}
+
+// readGroupImportCSV reads the rows of a CSV import. The header row names the
+// columns, in any order; unknown columns are an error so typos are caught.
+func readGroupImportCSV(body io.Reader) ([]groupImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(groupImportColumns, name) {
+			return nil, fmt.Errorf("unknown column %q: use %s", name, strings.Join(groupImportColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "prompt"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	var rows []groupImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return record[i]
+			}
+			return ""
+		}
+		rows = append(rows, groupImportRow{
+			Title:       field("title"),
+			Prompt:      field("prompt"),
+			Category:    field("category"),
+			OriginalURL: field("original_url"),
+			ArtistName:  field("artist_name"),
+			PromptStyle: field("prompt_style"),
+		})
+	}
+}
+
+// validateGroupImportRow checks a row the way CreateGroupHandler checks a
+// single group and converts it for the database
+func (h *Handler) validateGroupImportRow(row groupImportRow) (models.ArtworkGroup, error) {
+	group := models.ArtworkGroup{
+		Title:       strings.TrimSpace(row.Title),
+		Prompt:      strings.TrimSpace(row.Prompt),
+		Category:    strings.TrimSpace(row.Category),
+		OriginalURL: strings.TrimSpace(row.OriginalURL),
+		ArtistName:  strings.TrimSpace(row.ArtistName),
+		PromptStyle: strings.TrimSpace(row.PromptStyle),
+	}
+
+	if group.Title == "" || group.Prompt == "" {
+		return group, errors.New("title and prompt are required")
+	}
+	if !h.prompts.Load().Has(group.PromptStyle) {
+		return group, fmt.Errorf("unknown prompt style %q", group.PromptStyle)
+	}
+	if group.OriginalURL != "" {
+		u, err := url.Parse(group.OriginalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return group, fmt.Errorf("original_url %q is not an http(s) URL", group.OriginalURL)
+		}
+	}
+	return group, nil
+}
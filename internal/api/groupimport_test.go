@@ -0,0 +1,183 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// groupImportResponse is the body ImportGroupsHandler answers with
+type groupImportResponse struct {
+	Created int                     `json:"created"`
+	Skipped int                     `json:"skipped"`
+	Errors  int                     `json:"errors"`
+	Results []models.GroupRowResult `json:"results"`
+}
+
+// importGroups posts body with contentType to the bulk group import of h
+func importGroups(h *Handler, contentType, body string) *httptest.ResponseRecorder {
+	r := newRequest(http.MethodPost, "/api/groups/import", body)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ImportGroupsHandler(rec, r)
+	return rec
+}
+
+func TestImportGroupsHandlerCSV(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	existing := seedGroup(t, db, "Albatross", "Birds")
+
+	csv := strings.Join([]string{
+		"\ufefftitle, Prompt,category,original_url,artist_name,prompt_style",
+		"Pelican,A pelican riding a bicycle,Birds,https://example.com/pelican.png,Jane,",
+		`"Heron, grey","A heron, standing on one leg",Birds,,,`,
+		",A bird without a title,Birds,,,",
+		"Stork,A stork,Birds,ftp://example.com/stork.png,,",
+		"Crane,A crane,Birds,,,Watercolor",
+		"Albatross,Another albatross,Birds,,,",
+		"Pelican,The same title again,Birds,,,",
+		"Swan,A swan,Birds,,,Minimalist Line Art",
+	}, "\n") + "\n"
+
+	rec := importGroups(h, "text/csv; charset=utf-8", csv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp groupImportResponse
+	decodeJSON(t, rec, &resp)
+	if resp.Created != 3 || resp.Skipped != 2 || resp.Errors != 3 {
+		t.Errorf("counts = %d created, %d skipped, %d errors; want 3, 2 and 3", resp.Created, resp.Skipped, resp.Errors)
+	}
+
+	want := []struct {
+		title, status, error string
+	}{
+		{"Pelican", models.GroupRowCreated, ""},
+		{"Heron, grey", models.GroupRowCreated, ""},
+		{"", models.GroupRowError, "title and prompt are required"},
+		{"Stork", models.GroupRowError, "not an http(s) URL"},
+		{"Crane", models.GroupRowError, "unknown prompt style"},
+		{"Albatross", models.GroupRowSkipped, "already exists"},
+		{"Pelican", models.GroupRowSkipped, "already exists"},
+		{"Swan", models.GroupRowCreated, ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("%d results, want %d: %+v", len(resp.Results), len(want), resp.Results)
+	}
+	created := make(map[string]int)
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Row != i+1 || got.Title != w.title || got.Status != w.status || !strings.Contains(got.Error, w.error) {
+			t.Errorf("result %d = %+v, want row %d %q %s with error %q", i, got, i+1, w.title, w.status, w.error)
+		}
+		if w.status == models.GroupRowCreated {
+			if got.ID == 0 {
+				t.Errorf("row %d was created without an ID", got.Row)
+			}
+			created[got.Title] = got.ID
+		} else if got.ID != 0 {
+			t.Errorf("row %d was %s but has ID %d", got.Row, got.Status, got.ID)
+		}
+	}
+
+	groups, err := db.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+	if len(groups) != 4 {
+		t.Errorf("%d groups after the import, want the existing one and 3 imported", len(groups))
+	}
+	pelican, err := db.GetGroup(created["Pelican"])
+	if err != nil {
+		t.Fatalf("GetGroup: %v", err)
+	}
+	if pelican.Prompt != "A pelican riding a bicycle" || pelican.Category != "Birds" ||
+		pelican.OriginalURL != "https://example.com/pelican.png" || pelican.ArtistName != "Jane" {
+		t.Errorf("imported Pelican = %+v", pelican)
+	}
+	if swan, err := db.GetGroup(created["Swan"]); err != nil || swan.PromptStyle != "Minimalist Line Art" {
+		t.Errorf("imported Swan = %+v, %v; want the line art style", swan, err)
+	}
+	if albatross, err := db.GetGroup(existing); err != nil || albatross.Prompt != "Generate an SVG of Albatross" {
+		t.Errorf("skipped Albatross changed the existing group: %+v, %v", albatross, err)
+	}
+}
+
+func TestImportGroupsHandlerJSON(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+
+	rec := importGroups(h, "", `[
+		{"title": " Pelican ", "prompt": "A pelican", "category": "Birds"},
+		{"title": "Heron", "prompt": ""}
+	]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp groupImportResponse
+	decodeJSON(t, rec, &resp)
+	var statuses []string
+	for _, result := range resp.Results {
+		statuses = append(statuses, result.Title+" "+result.Status)
+	}
+	if want := []string{"Pelican created", "Heron error"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("results = %v, want %v", statuses, want)
+	}
+	if group, err := db.GetGroup(resp.Results[0].ID); err != nil || group.Title != "Pelican" {
+		t.Errorf("imported group = %+v, %v; want the trimmed title", group, err)
+	}
+
+	// Rows with only errors leave the database alone
+	rec = importGroups(h, "application/json", `[{"title": "Stork"}]`)
+	decodeJSON(t, rec, &resp)
+	if rec.Code != http.StatusOK || resp.Created != 0 || resp.Errors != 1 {
+		t.Errorf("invalid rows only = %d, %+v", rec.Code, resp)
+	}
+	if groups, err := db.ListGroups(); err != nil || len(groups) != 1 {
+		t.Errorf("%d groups after importing invalid rows, want 1 (%v)", len(groups), err)
+	}
+}
+
+func TestImportGroupsHandlerRejectsImports(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+
+	tests := []struct {
+		name, contentType, body string
+		want                    int
+	}{
+		{"unknown column", "text/csv", "title,prompt,colour\nPelican,A pelican,white\n", http.StatusBadRequest},
+		{"missing column", "text/csv", "title,category\nPelican,Birds\n", http.StatusBadRequest},
+		{"ragged row", "text/csv", "title,prompt\nPelican,A pelican,Birds\n", http.StatusBadRequest},
+		{"empty CSV", "text/csv", "title,prompt\n", http.StatusBadRequest},
+		{"empty JSON", "application/json", "[]", http.StatusBadRequest},
+		{"malformed JSON", "application/json", `[{"title": `, http.StatusBadRequest},
+		{"XML", "application/xml", "<groups/>", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := importGroups(h, tt.contentType, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.ImportGroupsHandler(rec, newRequest(http.MethodGet, "/api/groups/import", ""))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := importGroups(h, "text/csv", "title,prompt\nPelican,A pelican\n"); rec.Code != http.StatusForbidden {
+		t.Errorf("with editing disabled, status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	if groups, err := db.ListGroups(); err != nil || len(groups) != 0 {
+		t.Errorf("rejected imports created %d groups (%v)", len(groups), err)
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// zipMetadataName is the archive entry describing the group and its artworks
+const zipMetadataName = "metadata.json"
+
+// zipMetadata is the metadata.json of a group ZIP export
+type zipMetadata struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Group      models.ArtworkGroup `json:"group"`
+	Artworks   []zipArtwork        `json:"artworks"`
+}
+
+// zipArtwork describes an artwork of the archive and names its SVG entry
+type zipArtwork struct {
+	File            string    `json:"file"`
+	ID              int       `json:"id"`
+	Model           string    `json:"model"`
+	Temperature     float64   `json:"temperature"`
+	MaxTokens       int       `json:"max_tokens"`
+	ReasoningEffort string    `json:"reasoning_effort,omitempty"`
+	Featured        bool      `json:"featured"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// GroupZipExportHandler handles GET /api/groups/{id}/export.zip. It streams
+// a ZIP archive with one SVG file per artwork, named after its model, and a
+// metadata.json with the group and the parameters of each artwork. Only the
+// generated artworks visible on the group page are included.
+func (h *Handler) GroupZipExportHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	group, err := h.db.GetGroup(groupID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	artworks, err := h.db.ListArtworksByGroup(groupID)
+	if err != nil {
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to list artworks")
+		return
+	}
+
+	metadata := zipMetadata{
+		Version:    exportVersion,
+		ExportedAt: time.Now().UTC(),
+		Group:      *group,
+		Artworks:   []zipArtwork{},
+	}
+	files := zipFileNames(artworks)
+
+	// ZIP entries are already deflated, so the archive is never gzipped again
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("pelican-gallery-group-%d.zip", groupID)))
+
+	// Once streaming has started the status can no longer change, so a failure
+	// midway is only logged; the client sees a truncated archive
+	archive := zip.NewWriter(w)
+	for _, artwork := range models.FilterVisible(artworks, viewScope(r)) {
+		if artwork.SVG == "" {
+			continue
+		}
+
+		file := files[artwork.ID]
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Deflate, Modified: artwork.UpdatedAt})
+		if err == nil {
+			_, err = entry.Write([]byte(artwork.SVG))
+		}
+		if err != nil {
+			log.Printf("ZIP export of group %d aborted at artwork %d: %v", groupID, artwork.ID, err)
+			return
+		}
+
+		metadata.Artworks = append(metadata.Artworks, zipArtwork{
+			File:            file,
+			ID:              artwork.ID,
+			Model:           artwork.Model,
+			Temperature:     artwork.Temperature,
+			MaxTokens:       artwork.MaxTokens,
+			ReasoningEffort: artwork.ReasoningEffort,
+			Featured:        artwork.Featured,
+			CreatedAt:       artwork.CreatedAt,
+			UpdatedAt:       artwork.UpdatedAt,
+		})
+	}
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: zipMetadataName, Method: zip.Deflate, Modified: metadata.ExportedAt})
+	if err == nil {
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(metadata)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Printf("ZIP export of group %d aborted: %v", groupID, err)
+		return
+	}
+
+	log.Printf("Exported group %d as ZIP (%d SVG(s))", groupID, len(metadata.Artworks))
+}
+
+// zipFileNames names the SVG entry of each artwork after its model, made
+// safe for any filesystem. A model used more than once in the group gets the
+// artwork ID appended so the names stay unique.
+func zipFileNames(artworks []models.Artwork) map[int]string {
+	counts := make(map[string]int)
+	for _, artwork := range artworks {
+		counts[safeFileName(artwork.Model)]++
+	}
+
+	names := make(map[int]string, len(artworks))
+	for _, artwork := range artworks {
+		base := safeFileName(artwork.Model)
+		if counts[base] > 1 {
+			base = fmt.Sprintf("%s-%d", base, artwork.ID)
+		}
+		names[artwork.ID] = base + ".svg"
+	}
+	return names
+}
+
+// safeFileName turns s into a file name of letters, digits, dots, dashes and
+// underscores. Other characters, such as the slash of "openai/gpt-4o",
+// become dashes.
+func safeFileName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+	name = strings.Trim(name, ".-")
+	if name == "" {
+		return "artwork"
+	}
+	return name
+}
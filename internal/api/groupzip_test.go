@@ -0,0 +1,128 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// readZip opens the archive in rec and returns the content of each entry
+func readZip(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	body := rec.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("body is not a ZIP archive: %v", err)
+	}
+	entries := make(map[string]string)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", file.Name, err)
+		}
+		entries[file.Name] = string(content)
+	}
+	return entries
+}
+
+func TestGroupZipExportHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "birds")
+	first := seedArtwork(t, db, groupID, "openai/gpt-4o", modelSVG("first"))
+	second := seedArtwork(t, db, groupID, "openai/gpt-4o", modelSVG("second"))
+	seedArtwork(t, db, groupID, "anthropic/claude-sonnet-4", modelSVG("claude"))
+	seedArtwork(t, db, groupID, "google/gemini-2.5-pro", "")
+	hidden := seedArtwork(t, db, groupID, "x-ai/grok-4", modelSVG("grok"))
+	if err := db.SetArtworkVisibility(hidden, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	target := "/api/groups/" + strconv.Itoa(groupID) + "/export.zip"
+	rec := httptest.NewRecorder()
+	h.GroupZipExportHandler(rec, newRequest(http.MethodGet, target, ""), strconv.Itoa(groupID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, body %s", target, rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Content-Type = %q, want application/zip", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "pelican-gallery-group-"+strconv.Itoa(groupID)+".zip") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	entries := readZip(t, rec)
+	wantSVGs := map[string]string{
+		"openai-gpt-4o-" + strconv.Itoa(first) + ".svg":  modelSVG("first"),
+		"openai-gpt-4o-" + strconv.Itoa(second) + ".svg": modelSVG("second"),
+		"anthropic-claude-sonnet-4.svg":                  modelSVG("claude"),
+	}
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(entries) != len(wantSVGs)+1 {
+		t.Fatalf("entries = %v, want the %d SVGs and %s", names, len(wantSVGs), zipMetadataName)
+	}
+	for name, svg := range wantSVGs {
+		if entries[name] != svg {
+			t.Errorf("%s = %q, want %q", name, entries[name], svg)
+		}
+	}
+
+	var metadata zipMetadata
+	if err := json.Unmarshal([]byte(entries[zipMetadataName]), &metadata); err != nil {
+		t.Fatalf("%s: %v", zipMetadataName, err)
+	}
+	if metadata.Version != exportVersion || metadata.Group.ID != groupID || metadata.Group.Title != "Pelican" {
+		t.Errorf("metadata = version %d, group %d %q", metadata.Version, metadata.Group.ID, metadata.Group.Title)
+	}
+	if len(metadata.Artworks) != len(wantSVGs) {
+		t.Fatalf("metadata lists %d artworks, want %d", len(metadata.Artworks), len(wantSVGs))
+	}
+	for _, artwork := range metadata.Artworks {
+		if _, ok := wantSVGs[artwork.File]; !ok {
+			t.Errorf("metadata names %s, which is not an SVG of the archive", artwork.File)
+		}
+		if artwork.Temperature != 0.7 || artwork.MaxTokens != 4096 {
+			t.Errorf("%s has temperature %v and max tokens %d, want 0.7 and 4096", artwork.File, artwork.Temperature, artwork.MaxTokens)
+		}
+	}
+}
+
+func TestGroupZipExportHandlerErrors(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := strconv.Itoa(seedGroup(t, db, "Pelican", "birds"))
+
+	tests := []struct {
+		name, method, id string
+		want             int
+	}{
+		{"unknown group", http.MethodGet, "999", http.StatusNotFound},
+		{"invalid ID", http.MethodGet, "abc", http.StatusBadRequest},
+		{"POST", http.MethodPost, groupID, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.GroupZipExportHandler(rec, newRequest(tt.method, "/api/groups/"+tt.id+"/export.zip", ""), tt.id)
+			if rec.Code != tt.want {
+				t.Errorf("%s = %d, want %d", tt.method, rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -2,45 +2,84 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"log/slog"
+	"math"
 	"net/http"
-	"os"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
 
 	"pelican-gallery/internal/config"
 	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/generation"
+	"pelican-gallery/internal/images"
+	"pelican-gallery/internal/metrics"
 	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+	"pelican-gallery/internal/sanitize"
 )
 
 // Handler contains the API handlers
 type Handler struct {
-	promptConfig *models.PromptConfig
-	db           *database.DB
-	tmpl         *template.Template
+	prompts   *config.PromptStore
+	db        *database.DB
+	tmpl      *template.Template
+	generator openrouter.Generator
+	gen       *generation.Service
+
+	manifestCache manifestCache
+	pngCache      pngCache
+	jobs          jobRegistry
+
+	// trustedProxies are the proxies whose X-Forwarded-For is believed when
+	// telling voters apart
+	trustedProxies []netip.Prefix
 }
 
 // NewHandler creates a new API handler
-func NewHandler(promptConfig *models.PromptConfig, db *database.DB, tmpl *template.Template) *Handler {
-	return &Handler{
-		promptConfig: promptConfig,
-		db:           db,
-		tmpl:         tmpl,
-	}
+func NewHandler(prompts *config.PromptStore, db *database.DB, tmpl *template.Template, appMetrics *metrics.AppMetrics, generator openrouter.Generator) *Handler {
+	h := &Handler{
+		prompts:   prompts,
+		db:        db,
+		tmpl:      tmpl,
+		generator: generator,
+		gen:       generation.NewService(prompts, db, generator, appMetrics),
+	}
+	db.OnSVGSaved(h.pngCache.invalidate)
+	return h
+}
+
+// SetTrustedProxies sets the proxies whose X-Forwarded-For header is believed
+// when identifying a client
+func (h *Handler) SetTrustedProxies(trustedProxies []netip.Prefix) {
+	h.trustedProxies = trustedProxies
 }
 
-// jsonError is a simple structured error returned to clients
+// jsonError is a simple structured error returned to clients. RequestID
+// repeats the X-Request-ID response header so a reported error can be found
+// in the log.
 type jsonError struct {
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
+// writeJSON writes v as the JSON response. When the rate limiter has warned
+// the client through a header, JSON objects repeat it in a "warning" field.
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	if warning := w.Header().Get(config.RateLimitWarningHeader); warning != "" && v != nil {
+		v = withWarning(v, warning)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if v != nil {
@@ -48,12 +87,41 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	}
 }
 
+// withWarning adds a "warning" field to v when it encodes as a JSON object
+// without one; anything else is returned unchanged
+func withWarning(v interface{}, warning string) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil || len(data) == 0 || data[0] != '{' {
+		return v
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return v
+	}
+	if _, exists := fields["warning"]; exists {
+		return v
+	}
+	fields["warning"], _ = json.Marshal(warning)
+	return fields
+}
+
 func writeJSONError(w http.ResponseWriter, status int, message string, details ...interface{}) {
 	var det interface{}
 	if len(details) > 0 {
 		det = details[0]
 	}
-	writeJSON(w, status, jsonError{Message: message, Details: det})
+	writeJSON(w, status, jsonError{Message: message, Details: det, RequestID: w.Header().Get(config.RequestIDHeader)})
+}
+
+// writeDBError responds to a failed database write: 403 when the database is
+// read-only, otherwise status with message
+func writeDBError(w http.ResponseWriter, err error, status int, message string, details ...interface{}) {
+	if errors.Is(err, database.ErrReadOnly) {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+	writeJSONError(w, status, message, details...)
 }
 
 // isEditingEnabled checks if artwork editing/creating is enabled
@@ -61,6 +129,15 @@ func isEditingEnabled() bool {
 	return config.IsEditingEnabled()
 }
 
+// viewScope returns the scope for artworks requested by ID; private artworks
+// are only included while editing is enabled and r carries the admin API key
+func viewScope(r *http.Request) models.ViewScope {
+	if isEditingEnabled() && config.IsAdmin(r.Context()) {
+		return models.ScopeEditing
+	}
+	return models.ScopeDirectLink
+}
+
 // GenerateHandler handles SVG generation requests
 func (h *Handler) GenerateHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -91,26 +168,51 @@ func (h *Handler) GenerateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Temperature < 0 || req.Temperature > 1 {
-		writeJSONError(w, http.StatusBadRequest, "Temperature must be between 0 and 1")
+	if err := models.ValidateGenerationParams(req.Temperature, req.MaxTokens); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := models.ValidateReasoningEffort(req.ReasoningEffort); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if req.MaxTokens <= 0 {
-		writeJSONError(w, http.StatusBadRequest, "MaxTokens must be positive")
+	prompts := h.prompts.Load()
+	if !prompts.Has(req.PromptStyle) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown prompt style %q", req.PromptStyle))
 		return
 	}
 
-	log.Printf("Generate SVG request: model=%s, prompt length=%d", req.Model, len(req.Prompt))
+	if !checkModel(w, r, req.Model, req.AllowUnknownModel) {
+		return
+	}
 
-	svg, err := h.generateSVG(req.Prompt, req.Model, req.Temperature, req.MaxTokens)
+	slog.InfoContext(r.Context(), fmt.Sprintf("Generate SVG request: model=%s, prompt length=%d", req.Model, len(req.Prompt)),
+		"model", req.Model, "prompt_length", len(req.Prompt))
+
+	ctx := r.Context()
+	if req.DryRun {
+		ctx = openrouter.WithDryRun(ctx)
+	}
+	svg, err := h.gen.GenerateSVG(ctx, openrouter.GenerationRequest{
+		PromptConfig:    prompts.Get(req.PromptStyle),
+		Prompt:          req.Prompt,
+		Title:           req.Title,
+		Category:        req.Category,
+		Model:           req.Model,
+		Temperature:     req.Temperature,
+		MaxTokens:       req.MaxTokens,
+		ReasoningEffort: req.ReasoningEffort,
+	}, req.AutoContinue)
 	if err != nil {
-		log.Printf("Error generating SVG: %v", err)
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		slog.ErrorContext(r.Context(), fmt.Sprintf("Error generating SVG: %v", err), "model", req.Model, "error", err)
+		writeGenerationError(w, err)
 		return
 	}
 
-	log.Printf("Successfully generated SVG with length: %d characters", len(svg))
+	slog.InfoContext(r.Context(), fmt.Sprintf("Successfully generated SVG with length: %d characters", len(svg)),
+		"model", req.Model, "svg_length", len(svg))
 
 	resp := models.GenerateResponse{
 		SVG: svg,
@@ -119,108 +221,87 @@ func (h *Handler) GenerateHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// generateSVG calls the OpenRouter API to generate SVG
-func (h *Handler) generateSVG(prompt, model string, temperature float64, maxTokens int) (string, error) {
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("OPENROUTER_API_KEY environment variable is not set")
-	}
-
-	log.Printf("Calling OpenRouter API with model: %s", model)
+// writeGenerationError maps a generation failure to a response, with a
+// machine-readable code where the UI can suggest a fix
+func writeGenerationError(w http.ResponseWriter, err error) {
+	var deprecatedErr *generation.ModelDeprecatedError
+	var truncatedErr *generation.SVGTruncatedError
 
-	var messages []models.Message
-
-	for _, sysPrompt := range h.promptConfig.SystemPrompts {
-		messages = append(messages, models.Message(sysPrompt))
-	}
-
-	userPrompt := config.FormatUserPrompt(h.promptConfig.UserPromptTemplate, prompt)
-	messages = append(messages, models.Message{
-		Role:    "user",
-		Content: userPrompt,
-	})
-
-	log.Printf("Sending %d messages to OpenRouter", len(messages))
-
-	openRouterReq := models.OpenRouterRequest{
-		Model:       model,
-		Messages:    messages,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-		Reasoning: &models.Reasoning{
-			Effort:  "medium",
-			Enabled: true,
-			Exclude: true,
-		},
-	}
-
-	// Note: reasoning is enabled for supported models at medium effort.
-	// We exclude reasoning from the response (exclude=true) and do not log reasoning content.
-	log.Printf("Request will use reasoning: effort=%s, exclude=%t", "medium", true)
-
-	jsonData, err := json.Marshal(openRouterReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	switch {
+	case errors.Is(err, database.ErrReadOnly):
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+	case errors.Is(err, generation.ErrSaveSVG):
+		writeJSONError(w, http.StatusInternalServerError, "Failed to save SVG")
+	case errors.Is(err, generation.ErrBusy):
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error(), map[string]string{
+			"code": "generation_busy",
+		})
+	case errors.As(err, &deprecatedErr):
+		writeJSONError(w, http.StatusGone, err.Error(), map[string]string{
+			"code":            "model_deprecated",
+			"model":           deprecatedErr.Model,
+			"suggested_model": deprecatedErr.SuggestedModel,
+		})
+	case errors.As(err, &truncatedErr):
+		writeJSONError(w, http.StatusBadGateway, err.Error(), map[string]interface{}{
+			"code":          "svg_truncated",
+			"finish_reason": openrouter.FinishReasonLength,
+			"max_tokens":    truncatedErr.MaxTokens,
+		})
+	case errors.Is(err, generation.ErrSVGIncomplete):
+		writeJSONError(w, http.StatusBadGateway, err.Error(), map[string]string{
+			"code": "svg_incomplete",
+		})
+	default:
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
 	}
+}
 
-	req, err := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonData))
-
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+// DeleteArtworkHandler handles artwork deletion requests. Artworks go to the
+// recycle bin unless ?permanent=true is given.
+func (h *Handler) DeleteArtworkHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("X-Title", "Pelican Art Gallery")
-
-	client := &http.Client{
-		Timeout: 300 * time.Second, // 5 minutes
-	}
-	log.Printf("Making request to OpenRouter API...")
-	resp, err := client.Do(req)
+	artworkID, err := strconv.Atoi(artworkIDStr)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		writeJSONError(w, http.StatusBadRequest, "Invalid artwork ID")
+		return
 	}
-	defer resp.Body.Close()
 
-	log.Printf("OpenRouter API responded with status: %d", resp.StatusCode)
+	permanent := r.URL.Query().Get("permanent") == "true"
+	log.Printf("Delete artwork request: ID=%d, permanent=%t", artworkID, permanent)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	deleteArtwork, message := h.db.DeleteArtwork, "Artwork moved to the recycle bin"
+	if permanent {
+		deleteArtwork, message = h.db.PurgeArtwork, "Artwork deleted permanently"
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("OpenRouter API error (status %d): %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("OpenRouter API returned status %d: %s", resp.StatusCode, string(body))
+	if err := deleteArtwork(artworkID); err != nil {
+		log.Printf("Error deleting artwork (id=%d): %v", artworkID, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to delete artwork")
+		return
 	}
 
-	var openRouterResp models.OpenRouterResponse
-	if err := json.Unmarshal(body, &openRouterResp); err != nil {
-		log.Printf("Failed to parse OpenRouter response")
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
+	log.Printf("Successfully deleted artwork with ID: %d", artworkID)
 
-	if openRouterResp.Error != nil {
-		log.Printf("OpenRouter API error: %s", openRouterResp.Error.Message)
-		return "", fmt.Errorf("OpenRouter API error: %s", openRouterResp.Error.Message)
+	response := map[string]interface{}{
+		"success":   true,
+		"permanent": permanent,
+		"message":   message,
 	}
+	writeJSON(w, http.StatusOK, response)
+}
 
-	if len(openRouterResp.Choices) == 0 {
-		log.Printf("No choices in OpenRouter response")
-		return "", fmt.Errorf("no response from OpenRouter API")
+// RestoreArtworkHandler handles POST /api/artworks/{id}/restore
+func (h *Handler) RestoreArtworkHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	log.Printf("Received %d choices from OpenRouter", len(openRouterResp.Choices))
-
-	svgContent := strings.TrimSpace(openRouterResp.Choices[0].Message.Content)
-	log.Printf("Raw OpenRouter response content length: %d", len(svgContent))
-
-	return svgContent, nil
-}
-
-// DeleteArtworkHandler handles artwork deletion requests
-func (h *Handler) DeleteArtworkHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
 	if !isEditingEnabled() {
 		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
 		return
@@ -232,26 +313,29 @@ func (h *Handler) DeleteArtworkHandler(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 
-	log.Printf("Delete artwork request: ID=%d", artworkID)
-
-	if err := h.db.DeleteArtwork(artworkID); err != nil {
-		log.Printf("Error deleting artwork (id=%d): %v", artworkID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to delete artwork")
+	if err := h.db.RestoreArtwork(artworkID); err != nil {
+		log.Printf("Error restoring artwork (id=%d): %v", artworkID, err)
+		writeDBError(w, err, http.StatusNotFound, "Artwork not found in the recycle bin")
 		return
 	}
 
-	log.Printf("Successfully deleted artwork with ID: %d", artworkID)
-
-	response := map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Artwork deleted successfully",
-	}
-	writeJSON(w, http.StatusOK, response)
+		"message": "Artwork restored successfully",
+	})
 }
 
-// ListGroupsHandler handles GET /api/groups
+// ListGroupsHandler handles GET /api/groups. Each group carries the number of
+// its visible artworks and how many are generated; ?category= limits the
+// list to one category and ?sort= (newest, oldest or title) orders it.
 func (h *Handler) ListGroupsHandler(w http.ResponseWriter, r *http.Request) {
-	groups, err := h.db.ListGroups()
+	sortBy, err := models.ParseGroupSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groups, err := h.db.ListGroupsWithCounts(r.URL.Query().Get("category"), viewScope(r), sortBy)
 	if err != nil {
 		log.Printf("Error listing groups: %v", err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to list groups")
@@ -260,7 +344,9 @@ func (h *Handler) ListGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, groups)
 }
 
-// CreateGroupHandler handles POST /api/groups
+// CreateGroupHandler handles POST /api/groups. A prompt that a group of the
+// same category already has, ignoring case and spacing, gets 409 with that
+// group's ID unless allow_duplicate is set.
 func (h *Handler) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
 	if !isEditingEnabled() {
 		writeJSONError(w, http.StatusForbidden, "Artwork creation is currently disabled")
@@ -273,6 +359,14 @@ func (h *Handler) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		Category    string `json:"category"`
 		OriginalURL string `json:"original_url"`
 		ArtistName  string `json:"artist_name"`
+		PromptStyle string `json:"prompt_style"`
+		// AutoTitle derives a missing title: "prompt" or "model"
+		AutoTitle string `json:"auto_title"`
+		// AllowDuplicate creates the group even if the category already has
+		// a group with the same prompt
+		AllowDuplicate bool `json:"allow_duplicate"`
+		// Force skips every duplicate check
+		Force bool `json:"force"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -281,25 +375,154 @@ func (h *Handler) CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A retry with the Idempotency-Key of a created group gets that group
+	// back, before any duplicate check could reject it
+	key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if len(key) > maxIdempotencyKeyLength {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
+	if key != "" && h.replayIdempotentCreation(w, key) {
+		return
+	}
+
+	switch req.AutoTitle {
+	case "", autoTitlePrompt, autoTitleModel:
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown auto_title mode %q: use %q or %q", req.AutoTitle, autoTitlePrompt, autoTitleModel))
+		return
+	}
+
+	if strings.TrimSpace(req.Title) == "" && req.AutoTitle != "" && strings.TrimSpace(req.Prompt) != "" {
+		req.Title = h.autoTitle(r.Context(), req.Prompt, req.AutoTitle)
+		log.Printf("Auto-titled new group (%s): %q", req.AutoTitle, req.Title)
+	}
+
 	if req.Title == "" || req.Prompt == "" {
 		writeJSONError(w, http.StatusBadRequest, "Title and prompt are required")
 		return
 	}
 
+	if !h.prompts.Load().Has(req.PromptStyle) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown prompt style %q", req.PromptStyle))
+		return
+	}
+
+	if !req.Force {
+		existing, err := h.db.FindGroupByTitlePrompt(req.Title, req.Prompt)
+		if err != nil {
+			log.Printf("Error checking for duplicate groups: %v", err)
+			writeDBError(w, err, http.StatusInternalServerError, "Failed to check for duplicate groups")
+			return
+		}
+		// A concurrent request with the same key may have created the
+		// duplicate since the key was looked up
+		if existing != nil && key != "" && h.replayIdempotentCreation(w, key) {
+			return
+		}
+		if existing != nil {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("Group %d already has this title and prompt; set force to create another", existing.ID), map[string]interface{}{
+				"code":  "duplicate_group",
+				"group": existing,
+			})
+			return
+		}
+	}
+
+	if !req.AllowDuplicate && !req.Force {
+		existing, err := h.db.FindGroupByPrompt(req.Prompt, req.Category)
+		if err != nil {
+			log.Printf("Error checking for duplicate groups: %v", err)
+			writeDBError(w, err, http.StatusInternalServerError, "Failed to check for duplicate groups")
+			return
+		}
+		if existing != nil && key != "" && h.replayIdempotentCreation(w, key) {
+			return
+		}
+		if existing != nil {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("Group %d (%q) already has this prompt; set allow_duplicate to create another", existing.ID, existing.Title), map[string]interface{}{
+				"code":     "duplicate_prompt",
+				"group_id": existing.ID,
+				"title":    existing.Title,
+			})
+			return
+		}
+	}
+
 	group := models.ArtworkGroup{
 		Title:       req.Title,
 		Prompt:      req.Prompt,
 		Category:    req.Category,
 		OriginalURL: req.OriginalURL,
 		ArtistName:  req.ArtistName,
+		PromptStyle: req.PromptStyle,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	if key != "" {
+		h.createGroupWithKey(w, group, key)
+		return
+	}
+
 	id, err := h.db.CreateGroup(group)
 	if err != nil {
 		log.Printf("Error creating group: %v", err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to create group")
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to create group")
+		return
+	}
+
+	group.ID = id
+	writeJSON(w, http.StatusCreated, group)
+}
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header of group creation
+const maxIdempotencyKeyLength = 255
+
+// replayIdempotentCreation answers a group creation whose Idempotency-Key
+// already created a group and reports whether it did. A failed lookup is
+// answered too.
+func (h *Handler) replayIdempotentCreation(w http.ResponseWriter, key string) bool {
+	id, err := h.db.GroupIDByIdempotencyKey(key)
+	if err != nil {
+		log.Printf("Error looking up idempotency key: %v", err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to create group")
+		return true
+	}
+	if id == 0 {
+		return false
+	}
+	h.replayGroupCreation(w, id, key)
+	return true
+}
+
+// replayGroupCreation answers a retried group creation with the group the
+// first request created: 200 with the group, or 409 once it is deleted
+func (h *Handler) replayGroupCreation(w http.ResponseWriter, id int, key string) {
+	existing, err := h.db.GetGroup(id)
+	if err != nil {
+		// GetGroup leaves out the recycle bin
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("Idempotency key %q was used by group %d, which has been deleted", key, id), map[string]interface{}{
+			"code":     "idempotency_key_used",
+			"group_id": id,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, existing)
+}
+
+// createGroupWithKey creates a group under an Idempotency-Key, answering
+// with the earlier group if the key was already used
+func (h *Handler) createGroupWithKey(w http.ResponseWriter, group models.ArtworkGroup, key string) {
+	id, created, err := h.db.CreateGroupWithKey(group, key)
+	if err != nil {
+		log.Printf("Error creating group: %v", err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to create group")
+		return
+	}
+	if !created {
+		log.Printf("Idempotency key %q replayed group %d", key, id)
+		h.replayGroupCreation(w, id, key)
 		return
 	}
 
@@ -326,6 +549,7 @@ func (h *Handler) UpdateGroupHandler(w http.ResponseWriter, r *http.Request, gro
 		Category    string `json:"category"`
 		OriginalURL string `json:"original_url"`
 		ArtistName  string `json:"artist_name"`
+		PromptStyle string `json:"prompt_style"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -339,6 +563,11 @@ func (h *Handler) UpdateGroupHandler(w http.ResponseWriter, r *http.Request, gro
 		return
 	}
 
+	if !h.prompts.Load().Has(req.PromptStyle) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown prompt style %q", req.PromptStyle))
+		return
+	}
+
 	group := models.ArtworkGroup{
 		ID:          groupID,
 		Title:       req.Title,
@@ -346,19 +575,21 @@ func (h *Handler) UpdateGroupHandler(w http.ResponseWriter, r *http.Request, gro
 		Category:    req.Category,
 		OriginalURL: req.OriginalURL,
 		ArtistName:  req.ArtistName,
+		PromptStyle: req.PromptStyle,
 		UpdatedAt:   time.Now(),
 	}
 
 	if err := h.db.UpdateGroup(group); err != nil {
 		log.Printf("Error updating group (id=%d): %v", groupID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to update group")
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to update group")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, group)
 }
 
-// DeleteGroupHandler handles DELETE /api/groups/{id}
+// DeleteGroupHandler handles DELETE /api/groups/{id}. The group and its
+// artworks go to the recycle bin unless ?permanent=true is given.
 func (h *Handler) DeleteGroupHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
 	if !isEditingEnabled() {
 		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
@@ -371,23 +602,131 @@ func (h *Handler) DeleteGroupHandler(w http.ResponseWriter, r *http.Request, gro
 		return
 	}
 
-	log.Printf("Delete group request: ID=%d", groupID)
+	if r.URL.Query().Get("permanent") != "true" {
+		log.Printf("Delete group request: ID=%d", groupID)
+
+		if err := h.db.DeleteGroup(groupID); err != nil {
+			log.Printf("Error deleting group (id=%d): %v", groupID, err)
+			writeDBError(w, err, http.StatusInternalServerError, "Failed to delete group")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":   true,
+			"permanent": false,
+			"message":   "Group and its artworks moved to the recycle bin",
+		})
+		return
+	}
 
-	if err := h.db.DeleteGroup(groupID); err != nil {
+	log.Printf("Permanent delete group request: ID=%d", groupID)
+
+	deleted, err := h.db.DeleteGroupDeep(groupID)
+	if err != nil {
 		log.Printf("Error deleting group (id=%d): %v", groupID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to delete group")
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to delete group")
 		return
 	}
 
-	log.Printf("Successfully deleted group with ID: %d (cascaded to all artworks)", groupID)
+	log.Printf("Successfully deleted group with ID: %d (rows per table: %v)", groupID, deleted)
 
 	response := map[string]interface{}{
-		"success": true,
-		"message": "Group and all associated artworks deleted successfully",
+		"success":   true,
+		"permanent": true,
+		"message":   "Group and all associated artworks deleted permanently",
+		"deleted":   deleted,
 	}
 	writeJSON(w, http.StatusOK, response)
 }
 
+// RestoreGroupHandler handles POST /api/groups/{id}/restore
+func (h *Handler) RestoreGroupHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	if err := h.db.RestoreGroup(groupID); err != nil {
+		log.Printf("Error restoring group (id=%d): %v", groupID, err)
+		writeDBError(w, err, http.StatusNotFound, "Group not found in the recycle bin")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Group restored successfully",
+	})
+}
+
+// RecycleBinHandler handles GET /api/recycle-bin
+func (h *Handler) RecycleBinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	bin, err := h.db.ListRecycleBin()
+	if err != nil {
+		log.Printf("Error listing recycle bin: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list recycle bin")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bin)
+}
+
+// ArchiveGroupHandler handles POST /api/groups/{id}/archive and /unarchive
+func (h *Handler) ArchiveGroupHandler(w http.ResponseWriter, r *http.Request, groupIDStr string, archived bool) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	if err := h.db.SetGroupArchived(groupID, archived); err != nil {
+		log.Printf("Error setting archived=%t on group %d: %v", archived, groupID, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to update group")
+		return
+	}
+
+	message := "Group archived successfully"
+	if !archived {
+		message = "Group unarchived successfully"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"archived": archived,
+		"message":  message,
+	})
+}
+
 // GetGroupHandler handles GET /api/groups/{id}
 func (h *Handler) GetGroupHandler(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/groups/")
@@ -412,6 +751,8 @@ func (h *Handler) GetGroupHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusInternalServerError, "Failed to list artworks")
 		return
 	}
+	artworks = models.FilterVisible(artworks, viewScope(r))
+	config.AnnotateDeprecated(artworks)
 
 	response := struct {
 		Group    *models.ArtworkGroup `json:"group"`
@@ -424,7 +765,8 @@ func (h *Handler) GetGroupHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, response)
 }
 
-// CreateArtworkHandler handles POST /api/artworks
+// CreateArtworkHandler handles POST /api/artworks. An optional svg field
+// (raw markup or a data:image/svg+xml URI) imports a finished artwork.
 func (h *Handler) CreateArtworkHandler(w http.ResponseWriter, r *http.Request) {
 	if !isEditingEnabled() {
 		writeJSONError(w, http.StatusForbidden, "Artwork creation is currently disabled")
@@ -432,10 +774,14 @@ func (h *Handler) CreateArtworkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		GroupID     int     `json:"group_id"`
-		Model       string  `json:"model"`
-		Temperature float64 `json:"temperature"`
-		MaxTokens   int     `json:"max_tokens"`
+		GroupID         int      `json:"group_id"`
+		Model           string   `json:"model"`
+		Temperature     *float64 `json:"temperature"`
+		MaxTokens       int      `json:"max_tokens"`
+		ReasoningEffort string   `json:"reasoning_effort"`
+		SVG             string   `json:"svg"`
+		// AllowUnknownModel accepts a model OpenRouter does not list
+		AllowUnknownModel bool `json:"allow_unknown_model"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -449,19 +795,75 @@ func (h *Handler) CreateArtworkHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Artworks to generate take the parameters the request leaves out from
+	// the model's override; imported ones keep what they were made with
+	if override, ok := config.ModelOverride(req.Model); ok && req.SVG == "" {
+		if req.Temperature == nil {
+			req.Temperature = override.Temperature
+		}
+		if req.MaxTokens == 0 {
+			req.MaxTokens = override.MaxTokens
+		}
+		if req.ReasoningEffort == "" {
+			req.ReasoningEffort = override.ReasoningEffort
+		}
+	}
+	var temperature float64
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
+
+	if err := models.ValidateTemperature(temperature); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// An imported SVG was not generated here, so it may leave max_tokens unset
+	if req.SVG == "" || req.MaxTokens != 0 {
+		if err := models.ValidateMaxTokens(req.MaxTokens); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := models.ValidateReasoningEffort(req.ReasoningEffort); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Imported SVGs often come from models OpenRouter retired long ago; only
+	// artworks that will be generated need a model it offers
+	if req.SVG == "" && !checkModel(w, r, req.Model, req.AllowUnknownModel) {
+		return
+	}
+
 	artwork := models.Artwork{
-		GroupID:     req.GroupID,
-		Model:       req.Model,
-		Temperature: req.Temperature,
-		MaxTokens:   req.MaxTokens,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		GroupID:         req.GroupID,
+		Model:           req.Model,
+		Temperature:     temperature,
+		MaxTokens:       req.MaxTokens,
+		Visibility:      models.VisibilityPublic,
+		ReasoningEffort: req.ReasoningEffort,
+		Source:          models.SourceGenerated,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	if req.SVG != "" {
+		markup, err := decodeImportedSVG(req.SVG)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid SVG", err.Error())
+			return
+		}
+		artwork.SVG = sanitize.SVG(markup)
+		artwork.Source = models.SourceImported
+		log.Printf("Importing SVG for group %d (model=%s): length=%d characters", req.GroupID, req.Model, len(artwork.SVG))
 	}
 
 	id, err := h.db.CreateArtwork(artwork)
 	if err != nil {
 		log.Printf("Error creating artwork (group_id=%d, model=%s): %v", req.GroupID, req.Model, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to create artwork")
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to create artwork")
 		return
 	}
 
@@ -483,8 +885,9 @@ func (h *Handler) UpdateArtworkHandler(w http.ResponseWriter, r *http.Request, a
 	}
 
 	var req struct {
-		Temperature float64 `json:"temperature"`
-		MaxTokens   int     `json:"max_tokens"`
+		Temperature     float64 `json:"temperature"`
+		MaxTokens       int     `json:"max_tokens"`
+		ReasoningEffort string  `json:"reasoning_effort"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -493,9 +896,19 @@ func (h *Handler) UpdateArtworkHandler(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 
-	if err := h.db.UpdateArtwork(artworkID, req.Temperature, req.MaxTokens); err != nil {
+	if err := models.ValidateGenerationParams(req.Temperature, req.MaxTokens); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := models.ValidateReasoningEffort(req.ReasoningEffort); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.db.UpdateArtwork(artworkID, req.Temperature, req.MaxTokens, req.ReasoningEffort); err != nil {
 		log.Printf("Error updating artwork (id=%d): %v", artworkID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to update artwork")
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to update artwork")
 		return
 	}
 
@@ -505,11 +918,161 @@ func (h *Handler) UpdateArtworkHandler(w http.ResponseWriter, r *http.Request, a
 		writeJSONError(w, http.StatusInternalServerError, "Failed to get updated artwork")
 		return
 	}
+	artwork.SuggestedModel, artwork.Deprecated = config.DeprecatedModel(artwork.Model)
 
 	writeJSON(w, http.StatusOK, artwork)
 }
 
-// GenerateArtworkHandler handles POST /api/generate
+// SetArtworkModelHandler handles PATCH /api/artworks/{id}/model. It moves an
+// artwork to another available model that the group does not have yet, and
+// with "regenerate": true draws it again with that model. The model change
+// is kept even when the regeneration fails.
+func (h *Handler) SetArtworkModelHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
+	if r.Method != http.MethodPatch {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	artworkID, err := strconv.Atoi(artworkIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid artwork ID")
+		return
+	}
+
+	var req struct {
+		Model        string `json:"model"`
+		Regenerate   bool   `json:"regenerate"`
+		AutoContinue bool   `json:"auto_continue"`
+		DryRun       bool   `json:"dry_run"`
+		// AllowUnknownModel accepts a model OpenRouter does not list
+		AllowUnknownModel bool `json:"allow_unknown_model"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("SetArtworkModel invalid body: %v", err)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Model = strings.TrimSpace(req.Model)
+	if req.Model == "" {
+		writeJSONError(w, http.StatusBadRequest, "Model is required")
+		return
+	}
+
+	artwork, err := h.db.GetArtwork(artworkID)
+	if err != nil {
+		log.Printf("Error getting artwork %d: %v", artworkID, err)
+		writeJSONError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	if req.Model != artwork.Model {
+		if !checkModel(w, r, req.Model, req.AllowUnknownModel) {
+			return
+		}
+
+		siblings, err := h.db.ListArtworksByGroup(artwork.GroupID)
+		if err != nil {
+			log.Printf("Error listing artworks of group %d: %v", artwork.GroupID, err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to check the group's artworks")
+			return
+		}
+		for _, sibling := range siblings {
+			if sibling.ID != artwork.ID && sibling.Model == req.Model {
+				writeJSONError(w, http.StatusConflict, fmt.Sprintf("The group already has an artwork by %s", req.Model), map[string]int{"artwork_id": sibling.ID})
+				return
+			}
+		}
+
+		if err := h.db.SetArtworkModel(artworkID, req.Model); err != nil {
+			log.Printf("Error changing model of artwork %d: %v", artworkID, err)
+			writeDBError(w, err, http.StatusInternalServerError, "Failed to update artwork model")
+			return
+		}
+		slog.InfoContext(r.Context(), fmt.Sprintf("Changed model of artwork %d from %s to %s", artworkID, artwork.Model, req.Model))
+		artwork.Model = req.Model
+	}
+
+	if req.Regenerate {
+		group, err := h.db.GetGroup(artwork.GroupID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), fmt.Sprintf("Error getting group (id=%d for artwork=%d): %v", artwork.GroupID, artworkID, err))
+			writeJSONError(w, http.StatusInternalServerError, "Failed to get group")
+			return
+		}
+		ctx := r.Context()
+		if req.DryRun {
+			ctx = openrouter.WithDryRun(ctx)
+		}
+		if _, err := h.gen.GenerateAndSave(ctx, artwork, group, req.AutoContinue); err != nil {
+			slog.ErrorContext(r.Context(), fmt.Sprintf("Error regenerating artwork %d with %s: %v", artworkID, artwork.Model, err))
+			writeGenerationError(w, err)
+			return
+		}
+	}
+
+	updated, err := h.db.GetArtwork(artworkID)
+	if err != nil {
+		log.Printf("Error getting updated artwork (id=%d): %v", artworkID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get updated artwork")
+		return
+	}
+	updated.SuggestedModel, updated.Deprecated = config.DeprecatedModel(updated.Model)
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// maxModelSuggestions bounds the similar models suggested for an unknown one
+const maxModelSuggestions = 5
+
+// checkModel reports whether artworks may use model, writing a 422 with the
+// closest matches when they may not. A model must be offered by OpenRouter
+// unless allowUnknown is set, for models OpenRouter has not listed yet.
+// Without a model list, e.g. when OpenRouter is unreachable, nothing can be
+// checked and the model is accepted with a warning.
+func checkModel(w http.ResponseWriter, r *http.Request, model string, allowUnknown bool) bool {
+	available := config.GetAvailableModels()
+	if containsModel(available, model) {
+		return true
+	}
+	if len(available) == 0 {
+		slog.WarnContext(r.Context(), fmt.Sprintf("Cannot check model %s: the OpenRouter model list is unavailable", model), "model", model)
+		return true
+	}
+	if allowUnknown {
+		slog.InfoContext(r.Context(), fmt.Sprintf("Accepting model %s, which OpenRouter does not list (allow_unknown_model)", model), "model", model)
+		return true
+	}
+
+	writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Model %q is not available on OpenRouter", model), map[string]interface{}{
+		"code":        "unknown_model",
+		"model":       model,
+		"suggestions": config.SimilarModels(model, available, maxModelSuggestions),
+	})
+	return false
+}
+
+// containsModel reports whether list includes the model id
+func containsModel(list []models.ModelInfo, id string) bool {
+	for _, model := range list {
+		if model.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateArtworkHandler handles POST /api/generate. The new SVG replaces the
+// artwork's, which is kept as an artwork revision. With keep_history the
+// artwork is left alone and the SVG goes to a new artwork with the same
+// model and parameters, numbered with the next revision, so both can be
+// compared side by side.
 func (h *Handler) GenerateArtworkHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -522,11 +1085,14 @@ func (h *Handler) GenerateArtworkHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var req struct {
-		ArtworkID int `json:"artwork_id"`
+		ArtworkID    int  `json:"artwork_id"`
+		AutoContinue bool `json:"auto_continue"`
+		DryRun       bool `json:"dry_run"`
+		KeepHistory  bool `json:"keep_history"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("GenerateArtwork invalid body: %v", err)
+		slog.ErrorContext(r.Context(), fmt.Sprintf("GenerateArtwork invalid body: %v", err))
 		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -538,51 +1104,242 @@ func (h *Handler) GenerateArtworkHandler(w http.ResponseWriter, r *http.Request)
 
 	artwork, err := h.db.GetArtwork(req.ArtworkID)
 	if err != nil {
-		log.Printf("Error getting artwork (id=%d): %v", req.ArtworkID, err)
+		slog.ErrorContext(r.Context(), fmt.Sprintf("Error getting artwork (id=%d): %v", req.ArtworkID, err))
 		writeJSONError(w, http.StatusInternalServerError, "Failed to get artwork")
 		return
 	}
 
 	group, err := h.db.GetGroup(artwork.GroupID)
 	if err != nil {
-		log.Printf("Error getting group (id=%d for artwork=%d): %v", artwork.GroupID, req.ArtworkID, err)
+		slog.ErrorContext(r.Context(), fmt.Sprintf("Error getting group (id=%d for artwork=%d): %v", artwork.GroupID, req.ArtworkID, err))
 		writeJSONError(w, http.StatusInternalServerError, "Failed to get group")
 		return
 	}
 
-	svg, err := h.generateSVG(group.Prompt, artwork.Model, artwork.Temperature, artwork.MaxTokens)
-	if err != nil {
-		log.Printf("Error generating SVG for artwork %d: %v", req.ArtworkID, err)
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
-		return
+	// A failed generation leaves the new revision without an SVG, like any
+	// artwork still to generate, so it can be retried by its ID
+	if req.KeepHistory {
+		artwork, err = h.db.AddArtworkRevision(*artwork)
+		if err != nil {
+			slog.ErrorContext(r.Context(), fmt.Sprintf("Error adding a revision of artwork %d: %v", req.ArtworkID, err))
+			writeDBError(w, err, http.StatusInternalServerError, "Failed to create artwork revision")
+			return
+		}
+		slog.InfoContext(r.Context(), fmt.Sprintf("Generating revision %d of %s as artwork %d, keeping artwork %d", artwork.Revision, artwork.Model, artwork.ID, req.ArtworkID),
+			"artwork_id", artwork.ID, "model", artwork.Model, "revision", artwork.Revision)
 	}
 
-	log.Printf("Generated SVG for artwork %d: length=%d characters", req.ArtworkID, len(svg))
-
-	if err := h.db.SaveArtworkSVG(req.ArtworkID, svg); err != nil {
-		log.Printf("Error saving SVG (artwork=%d): %v", req.ArtworkID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to save SVG")
+	ctx := r.Context()
+	if req.DryRun {
+		ctx = openrouter.WithDryRun(ctx)
+	}
+	svg, err := h.gen.GenerateAndSave(ctx, artwork, group, req.AutoContinue)
+	if err != nil {
+		slog.ErrorContext(r.Context(), fmt.Sprintf("Error generating SVG for artwork %d: %v", artwork.ID, err))
+		writeGenerationError(w, err)
 		return
 	}
 
-	log.Printf("Successfully saved SVG for artwork %d to database", req.ArtworkID)
+	slog.InfoContext(r.Context(), fmt.Sprintf("Successfully saved SVG for artwork %d to database", artwork.ID))
 
 	response := struct {
-		ID  int    `json:"id"`
-		SVG string `json:"svg"`
+		ID       int    `json:"id"`
+		SVG      string `json:"svg"`
+		Revision int    `json:"revision"`
 	}{
-		ID:  req.ArtworkID,
-		SVG: svg,
+		ID:       artwork.ID,
+		SVG:      svg,
+		Revision: artwork.Revision,
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
 
-// ListModelsHandler handles GET /api/models
+// ListModelsHandler handles GET /api/models. Each model carries the number
+// of stored artworks made with it and when it was last used. The list can be
+// narrowed with ?provider= (openai, anthropic, google or other), ?used=true
+// for models with artworks and ?q= for a case-insensitive search of ID and
+// name; total counts the models that match. With ?grouped=true the models
+// are returned partitioned by provider instead of as a flat list, and
+// ?refresh=true fetches the list from OpenRouter instead of the cache.
+// fetched_at and cache_age_seconds tell when the list was fetched, and stale
+// whether it has outlived MODELS_CACHE_TTL and is being fetched again.
 func (h *Handler) ListModelsHandler(w http.ResponseWriter, r *http.Request) {
-	models := config.GetAvailableModels()
+	query := r.URL.Query()
+
+	provider := strings.ToLower(query.Get("provider"))
+	switch provider {
+	case "", config.ProviderOpenAI, config.ProviderAnthropic, config.ProviderGoogle, config.ProviderOther:
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown provider %q: use %s, %s, %s or %s",
+			provider, config.ProviderOpenAI, config.ProviderAnthropic, config.ProviderGoogle, config.ProviderOther))
+		return
+	}
+	usedOnly := query.Get("used") == "true"
+	search := strings.ToLower(strings.TrimSpace(query.Get("q")))
+
+	usage, err := h.db.ListModelUsage()
+	if err != nil {
+		log.Printf("Error listing model usage: %v", err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to list model usage")
+		return
+	}
+	usageByModel := make(map[string]models.ModelUsage, len(usage))
+	for _, u := range usage {
+		usageByModel[u.Model] = u
+	}
+
+	if query.Get("refresh") == "true" {
+		if err := config.RefreshModels(r.Context()); err != nil {
+			slog.WarnContext(r.Context(), fmt.Sprintf("Refreshing the OpenRouter model list failed, serving the cached one: %v", err), "error", err)
+		}
+	}
+
+	matching := []models.ModelInfo{}
+	for _, model := range config.GetAvailableModels() {
+		if u, ok := usageByModel[model.ID]; ok {
+			model.ArtworkCount = u.ArtworkCount
+			model.LastUsedAt = &u.LastUsedAt
+		}
+
+		switch {
+		case provider != "" && config.ProviderOf(model.ID) != provider:
+		case usedOnly && model.ArtworkCount == 0:
+		case search != "" && !strings.Contains(strings.ToLower(model.ID), search) && !strings.Contains(strings.ToLower(model.Name), search):
+		default:
+			matching = append(matching, model)
+		}
+	}
+
+	response := map[string]interface{}{
+		"total":             len(matching),
+		"fetched_at":        nil,
+		"cache_age_seconds": nil,
+		"stale":             true,
+	}
+	if fetchedAt, stale := config.ModelListFetchedAt(); !fetchedAt.IsZero() {
+		response["fetched_at"] = fetchedAt.UTC()
+		response["cache_age_seconds"] = int(time.Since(fetchedAt).Seconds())
+		response["stale"] = stale
+	}
+
+	if query.Get("grouped") == "true" {
+		response["groups"] = config.GroupModelsByProvider(matching)
+	} else {
+		response["models"] = matching
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ReloadConfigHandler handles POST /api/admin/reload-config. It re-reads the
+// prompt configs and reports what changed; invalid configs leave the current
+// ones in place.
+func (h *Handler) ReloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	changes, err := h.prompts.Reload()
+	if err != nil {
+		log.Printf("Prompt config reload failed, keeping previous config: %v", err)
+		writeJSONError(w, http.StatusBadRequest, "Invalid prompt config", err.Error())
+		return
+	}
+
+	log.Printf("Prompt config reloaded: added=%v removed=%v changed=%v", changes.Added, changes.Removed, changes.Changed)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"models": models,
+		"success": true,
+		"changes": changes,
+	})
+}
+
+// StatsHandler handles GET /api/stats
+func (h *Handler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if err := models.ValidateSource(source); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := h.db.GetStats(source)
+	if err != nil {
+		log.Printf("Error getting stats: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get stats")
+		return
+	}
+	stats.EstimatedCost = estimateGenerationCost(stats.ArtworksByModel, config.CachedModelInfos())
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// svgBytesPerToken approximates how many bytes of SVG markup a model emits
+// per output token
+const svgBytesPerToken = 3
+
+// estimateGenerationCost estimates in dollars, rounded to cents, what the
+// stored generated SVGs cost in output tokens at current OpenRouter prices.
+// Prompts, reasoning and failed attempts are not counted, nor are models
+// without a known price.
+func estimateGenerationCost(counts []models.ModelCount, infos map[string]models.ModelInfo) float64 {
+	var cost float64
+	for _, count := range counts {
+		tokens := float64(count.GeneratedBytes) / svgBytesPerToken
+		cost += tokens * infos[count.Model].Cost / 1e6
+	}
+	return math.Round(cost*100) / 100
+}
+
+// ListPromptStylesHandler handles GET /api/prompt-styles
+func (h *Handler) ListPromptStylesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	prompts := h.prompts.Load()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"styles":  prompts.Styles(),
+		"default": prompts.Default,
+	})
+}
+
+// ListUsedModelsHandler handles GET /api/models/used, listing the models stored
+// artworks reference and flagging those OpenRouter no longer offers
+func (h *Handler) ListUsedModelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	usage, err := h.db.ListModelUsage()
+	if err != nil {
+		log.Printf("Error listing model usage: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list used models")
+		return
+	}
+
+	deprecated := []models.ModelUsage{}
+	for i := range usage {
+		usage[i].SuggestedModel, usage[i].Deprecated = config.DeprecatedModel(usage[i].Model)
+		if usage[i].Deprecated {
+			deprecated = append(deprecated, usage[i])
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"models":     usage,
+		"deprecated": deprecated,
 	})
 }
 
@@ -614,44 +1371,59 @@ func (h *Handler) UploadOriginalArtworkHandler(w http.ResponseWriter, r *http.Re
 	}
 	defer file.Close()
 
-	// Validate file type (accept common image formats)
-	contentType := header.Header.Get("Content-Type")
+	// Read file content
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("Error reading file: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	// Validate file type from the content itself (accept common image formats);
+	// the declared Content-Type header is not trusted
+	contentType := http.DetectContentType(fileBytes)
 	validTypes := map[string]bool{
 		"image/jpeg": true,
-		"image/jpg":  true,
 		"image/png":  true,
 		"image/gif":  true,
 		"image/webp": true,
 	}
 
 	if !validTypes[contentType] {
+		log.Printf("Rejected original artwork upload %q: detected %s", header.Filename, contentType)
 		writeJSONError(w, http.StatusBadRequest, "Invalid file type. Only images (jpeg, png, gif, webp) are allowed")
 		return
 	}
 
-	// Read file content
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		log.Printf("Error reading file: %v", err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to read file")
+	if _, err := h.db.GetGroup(groupID); err != nil {
+		log.Printf("Error getting group %d: %v", groupID, err)
+		writeJSONError(w, http.StatusNotFound, "Group not found")
 		return
 	}
 
-	// Get the group to update
-	group, err := h.db.GetGroup(groupID)
+	// Only the re-encoded image is stored: scaled down, without its metadata
+	processed, err := images.Process(fileBytes)
 	if err != nil {
-		log.Printf("Error getting group %d: %v", groupID, err)
-		writeJSONError(w, http.StatusNotFound, "Group not found")
+		log.Printf("Rejected original artwork upload %q: %v", header.Filename, err)
+		if errors.Is(err, images.ErrTooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Image is too large: at most %d megapixels are allowed", images.MaxPixels/1_000_000))
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "The image could not be read", err.Error())
 		return
 	}
 
-	// Update the group with the original artwork
-	group.OriginalArtwork = fileBytes
-	group.UpdatedAt = time.Now()
+	original := models.OriginalArtwork{
+		GroupID:     groupID,
+		ContentType: processed.ContentType,
+		Data:        processed.Full,
+		Thumbnail:   processed.Thumb,
+		UploadedAt:  time.Now(),
+	}
 
-	if err := h.db.UpdateGroup(*group); err != nil {
-		log.Printf("Error updating group %d with original artwork: %v", groupID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to save original artwork")
+	if err := h.db.SaveOriginalArtwork(original); err != nil {
+		log.Printf("Error saving original artwork for group %d: %v", groupID, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to save original artwork")
 		return
 	}
 
@@ -660,7 +1432,9 @@ func (h *Handler) UploadOriginalArtworkHandler(w http.ResponseWriter, r *http.Re
 	})
 }
 
-// GetOriginalArtworkHandler handles GET /api/groups/{id}/original-artwork
+// GetOriginalArtworkHandler handles GET /api/groups/{id}/original-artwork.
+// ?size=thumb serves the thumbnail instead of the full image; images stored
+// before thumbnails existed fall back to the full image.
 func (h *Handler) GetOriginalArtworkHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
 	groupID, err := strconv.Atoi(groupIDStr)
 	if err != nil {
@@ -668,28 +1442,101 @@ func (h *Handler) GetOriginalArtworkHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	group, err := h.db.GetGroup(groupID)
-	if err != nil {
-		log.Printf("Error getting group %d: %v", groupID, err)
-		writeJSONError(w, http.StatusNotFound, "Group not found")
+	size := r.URL.Query().Get("size")
+	if size != "" && size != "full" && size != "thumb" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid size %q: use full or thumb", size))
 		return
 	}
 
-	if len(group.OriginalArtwork) == 0 {
+	original, err := h.db.GetOriginalArtwork(groupID)
+	if err != nil {
+		log.Printf("Error getting original artwork for group %d: %v", groupID, err)
 		writeJSONError(w, http.StatusNotFound, "No original artwork found for this group")
 		return
 	}
 
-	// Detect content type from the first few bytes
-	contentType := http.DetectContentType(group.OriginalArtwork)
+	data := original.Data
+	if size == "thumb" && len(original.Thumbnail) > 0 {
+		data = original.Thumbnail
+	}
+
+	// Images migrated from the group row have no stored content type
+	contentType := original.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
 
+	// Uploads can be replaced under the same URL, so cache briefly and let
+	// clients revalidate cheaply with the ETag or upload time
+	sum := sha256.Sum256(data)
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(group.OriginalArtwork)))
-	w.WriteHeader(http.StatusOK)
-	w.Write(group.OriginalArtwork)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	// ServeContent answers If-None-Match and If-Modified-Since with 304
+	http.ServeContent(w, r, "", original.UploadedAt, bytes.NewReader(data))
+}
+
+// SetArtworkVisibilityHandler handles PATCH /api/artworks/{id}/visibility
+func (h *Handler) SetArtworkVisibilityHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
+	if r.Method != http.MethodPatch {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	artworkID, err := strconv.Atoi(artworkIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid artwork ID")
+		return
+	}
+
+	var req struct {
+		Visibility string `json:"visibility"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("SetArtworkVisibility invalid body: %v", err)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	visibility, err := models.ParseVisibility(req.Visibility)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := h.db.GetArtwork(artworkID); err != nil {
+		log.Printf("Error getting artwork %d: %v", artworkID, err)
+		writeJSONError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+
+	if err := h.db.SetArtworkVisibility(artworkID, visibility); err != nil {
+		log.Printf("Error setting visibility of artwork %d: %v", artworkID, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to update artwork visibility")
+		return
+	}
+
+	artwork, err := h.db.GetArtwork(artworkID)
+	if err != nil {
+		log.Printf("Error getting updated artwork (id=%d): %v", artworkID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get updated artwork")
+		return
+	}
+	artwork.SuggestedModel, artwork.Deprecated = config.DeprecatedModel(artwork.Model)
+
+	writeJSON(w, http.StatusOK, artwork)
 }
 
-// SetFeaturedArtworkHandler handles POST /api/artworks/{id}/featured
+// SetFeaturedArtworkHandler handles POST /api/artworks/{id}/featured. The
+// artwork replaces the featured artwork of its group; with ?global=true it
+// becomes the only featured artwork of the gallery.
 func (h *Handler) SetFeaturedArtworkHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
 	if !isEditingEnabled() {
 		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
@@ -702,13 +1549,79 @@ func (h *Handler) SetFeaturedArtworkHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := h.db.SetFeaturedArtwork(artworkID); err != nil {
+	global := r.URL.Query().Get("global") == "true"
+	if err := h.db.SetFeaturedArtwork(artworkID, global); err != nil {
 		log.Printf("Error setting featured artwork %d: %v", artworkID, err)
-		writeJSONError(w, http.StatusInternalServerError, "Failed to set featured artwork")
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to set featured artwork")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "Artwork set as featured",
+		"global":  global,
+	})
+}
+
+// SchemaCheckHandler handles GET /api/admin/schema-check. It reports how the
+// database differs from the schema the code expects, without changing it.
+func (h *Handler) SchemaCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Admin pages are disabled")
+		return
+	}
+
+	report, err := h.db.CheckSchema()
+	if err != nil {
+		log.Printf("Error checking database schema: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to check database schema")
+		return
+	}
+
+	if !report.OK {
+		log.Printf("Schema check found %d issue(s) and %d foreign key violation(s)", len(report.Issues), len(report.ForeignKeyViolations))
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// ListGenerationErrorsHandler handles GET /api/admin/errors
+func (h *Handler) ListGenerationErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Admin pages are disabled")
+		return
+	}
+
+	filter, err := models.ParseGenerationErrorFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summaries, err := h.db.ListGenerationErrorSummaries(filter)
+	if err != nil {
+		log.Printf("Error listing generation errors: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list generation errors")
+		return
+	}
+
+	total := 0
+	for _, summary := range summaries {
+		total += summary.Count
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"filter": filter,
+		"total":  total,
+		"errors": summaries,
 	})
 }
@@ -0,0 +1,1113 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/logging"
+	"pelican-gallery/internal/metrics"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+)
+
+// testSVG is a small valid SVG for artworks that need one
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4"/></svg>`
+
+// fakeGenerator stands in for OpenRouter. It answers with generate when
+// set and otherwise with an SVG naming the requested model, and records
+// every request it gets.
+type fakeGenerator struct {
+	generate func(req openrouter.GenerationRequest) (openrouter.GenerationResult, error)
+
+	mu       sync.Mutex
+	requests []openrouter.GenerationRequest
+}
+
+func (g *fakeGenerator) GenerateSVG(ctx context.Context, req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+	g.mu.Lock()
+	g.requests = append(g.requests, req)
+	g.mu.Unlock()
+
+	if g.generate != nil {
+		return g.generate(req)
+	}
+	svg := modelSVG(req.Model)
+	return openrouter.GenerationResult{SVG: svg, Content: svg, FinishReason: "stop", Model: req.Model}, nil
+}
+
+// calls returns the requests the generator got so far
+func (g *fakeGenerator) calls() []openrouter.GenerationRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]openrouter.GenerationRequest(nil), g.requests...)
+}
+
+// modelSVG is the SVG fakeGenerator draws for model
+func modelSVG(model string) string {
+	return `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><title>` + model + `</title></svg>`
+}
+
+// newTestHandler returns a handler on a fresh database and a fake generator,
+// with editing enabled
+func newTestHandler(t *testing.T) (*Handler, *database.DB, *fakeGenerator) {
+	t.Helper()
+	t.Setenv("ENABLE_EDITING", "true")
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	prompts, err := config.NewPromptStore("../../config/prompts")
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+
+	generator := &fakeGenerator{}
+	return NewHandler(prompts, db, nil, metrics.NewAppMetrics(), generator), db, generator
+}
+
+// seedGroup creates a group with title in category and returns its ID
+func seedGroup(t *testing.T, db *database.DB, title, category string) int {
+	t.Helper()
+	now := time.Now()
+	id, err := db.CreateGroup(models.ArtworkGroup{
+		Title:     title,
+		Prompt:    "Generate an SVG of " + title,
+		Category:  category,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	return id
+}
+
+// seedArtwork creates an artwork of model in a group, with svg unless it is
+// empty, and returns its ID
+func seedArtwork(t *testing.T, db *database.DB, groupID int, model, svg string) int {
+	t.Helper()
+	now := time.Now()
+	id, err := db.CreateArtwork(models.Artwork{
+		GroupID:     groupID,
+		Model:       model,
+		Temperature: 0.7,
+		MaxTokens:   4096,
+		SVG:         svg,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		t.Fatalf("CreateArtwork: %v", err)
+	}
+	return id
+}
+
+// testPNG encodes a blank PNG of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newRequest builds a request with a JSON body, or none when body is ""
+func newRequest(method, target, body string) *http.Request {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return r
+}
+
+// decodeJSON decodes the body of a recorded response into v
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestArchiveGroupHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	idStr := strconv.Itoa(groupID)
+
+	rec := httptest.NewRecorder()
+	h.ArchiveGroupHandler(rec, newRequest(http.MethodPost, "/api/groups/"+idStr+"/archive", ""), idStr, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("archive status = %d, body %s", rec.Code, rec.Body)
+	}
+	if group, err := db.GetGroup(groupID); err != nil || !group.Archived {
+		t.Fatalf("group after archiving = %+v, %v", group, err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ArchiveGroupHandler(rec, newRequest(http.MethodPost, "/api/groups/"+idStr+"/unarchive", ""), idStr, false)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unarchive status = %d, body %s", rec.Code, rec.Body)
+	}
+	if group, err := db.GetGroup(groupID); err != nil || group.Archived {
+		t.Fatalf("group after unarchiving = %+v, %v", group, err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ArchiveGroupHandler(rec, newRequest(http.MethodGet, "/api/groups/"+idStr+"/archive", ""), idStr, true)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestListGroupsHandlerCounts(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	mixed := seedGroup(t, db, "Mixed", "birds")
+	seedArtwork(t, db, mixed, "openai/gpt-4o", testSVG)
+	seedArtwork(t, db, mixed, "google/gemini-2.5-pro", testSVG)
+	seedArtwork(t, db, mixed, "anthropic/claude-sonnet-4", "")
+	deleted := seedArtwork(t, db, mixed, "x-ai/grok-4", testSVG)
+	if err := db.DeleteArtwork(deleted); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	pending := seedGroup(t, db, "Pending", "birds")
+	seedArtwork(t, db, pending, "openai/gpt-4o", "")
+	seedGroup(t, db, "Empty", "vehicles")
+
+	list := func(query string) map[string][2]int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ListGroupsHandler(rec, newRequest(http.MethodGet, "/api/groups"+query, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /api/groups%s = %d, body %s", query, rec.Code, rec.Body)
+		}
+		var groups []models.GroupSummary
+		decodeJSON(t, rec, &groups)
+		counts := make(map[string][2]int)
+		for _, g := range groups {
+			counts[g.Title] = [2]int{g.ArtworkCount, g.GeneratedCount}
+		}
+		return counts
+	}
+
+	tests := []struct {
+		query string
+		want  map[string][2]int
+	}{
+		{"", map[string][2]int{"Mixed": {3, 2}, "Pending": {1, 0}, "Empty": {0, 0}}},
+		{"?category=birds", map[string][2]int{"Mixed": {3, 2}, "Pending": {1, 0}}},
+		{"?category=vehicles", map[string][2]int{"Empty": {0, 0}}},
+		{"?category=none", map[string][2]int{}},
+	}
+	for _, tt := range tests {
+		if got := list(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: counts = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// withoutModelList points the OpenRouter model list at a server that has no
+// models, so model checks accept any model without reaching the network
+func withoutModelList(t *testing.T) {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("OPENROUTER_BASE_URL", upstream.URL)
+}
+
+// withModelList has OpenRouter list the models ids for the rest of the test
+// and empties the cached list again afterwards, for the tests that rely on
+// withoutModelList
+func withModelList(t *testing.T, ids ...string) {
+	t.Helper()
+	var mu sync.Mutex
+	listed := ids
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		data := []map[string]string{}
+		for _, id := range listed {
+			data = append(data, map[string]string{"id": id, "name": id})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Setenv("OPENROUTER_BASE_URL", upstream.URL)
+	if err := config.RefreshModels(context.Background()); err != nil {
+		t.Fatalf("RefreshModels: %v", err)
+	}
+	t.Cleanup(func() {
+		mu.Lock()
+		listed = nil
+		mu.Unlock()
+		if err := config.RefreshModels(context.Background()); err != nil {
+			t.Errorf("RefreshModels: %v", err)
+		}
+		upstream.Close()
+	})
+}
+
+func TestSetArtworkModelHandler(t *testing.T) {
+	withoutModelList(t)
+	h, db, gen := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	artworkID := seedArtwork(t, db, groupID, "a/model", testSVG)
+	taken := seedArtwork(t, db, groupID, "c/model", testSVG)
+	idStr := strconv.Itoa(artworkID)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.SetArtworkModelHandler(rec, newRequest(http.MethodPatch, "/api/artworks/"+idStr+"/model", body), idStr)
+		return rec
+	}
+
+	// Reassigning keeps the SVG and does not generate
+	rec := patch(`{"model": "b/model"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("change status = %d, body %s", rec.Code, rec.Body)
+	}
+	var artwork models.Artwork
+	decodeJSON(t, rec, &artwork)
+	if artwork.Model != "b/model" || artwork.SVG != testSVG {
+		t.Errorf("changed artwork = %s with %q, want b/model with the old SVG", artwork.Model, artwork.SVG)
+	}
+	if stored, err := db.GetArtwork(artworkID); err != nil || stored.Model != "b/model" {
+		t.Errorf("stored artwork = %+v, %v", stored, err)
+	}
+	if calls := gen.calls(); len(calls) != 0 {
+		t.Errorf("changing the model generated %d times", len(calls))
+	}
+
+	// A model the group already has is refused
+	rec = patch(`{"model": "c/model", "regenerate": true}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	var conflict struct {
+		Details struct {
+			ArtworkID int `json:"artwork_id"`
+		} `json:"details"`
+	}
+	decodeJSON(t, rec, &conflict)
+	if conflict.Details.ArtworkID != taken {
+		t.Errorf("conflict names artwork %d, want %d", conflict.Details.ArtworkID, taken)
+	}
+	if stored, _ := db.GetArtwork(artworkID); stored.Model != "b/model" {
+		t.Errorf("refused change stored model %s", stored.Model)
+	}
+
+	// Regenerating draws the artwork with the new model
+	rec = patch(`{"model": "d/model", "regenerate": true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("regenerate status = %d, body %s", rec.Code, rec.Body)
+	}
+	artwork = models.Artwork{}
+	decodeJSON(t, rec, &artwork)
+	if calls := gen.calls(); len(calls) != 1 || calls[0].Model != "d/model" {
+		t.Errorf("regenerating sent %+v, want one request for d/model", calls)
+	}
+	if artwork.Model != "d/model" || !strings.Contains(artwork.SVG, "d/model") {
+		t.Errorf("regenerated artwork = %s with %q", artwork.Model, artwork.SVG)
+	}
+
+	tests := []struct {
+		name, method, id, body string
+		want                   int
+	}{
+		{"no model", http.MethodPatch, idStr, `{"model": " "}`, http.StatusBadRequest},
+		{"bad body", http.MethodPatch, idStr, `{`, http.StatusBadRequest},
+		{"bad ID", http.MethodPatch, "x", `{"model": "e/model"}`, http.StatusBadRequest},
+		{"unknown artwork", http.MethodPatch, "9999", `{"model": "e/model"}`, http.StatusNotFound},
+		{"wrong method", http.MethodPost, idStr, `{"model": "e/model"}`, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.SetArtworkModelHandler(rec, newRequest(tt.method, "/api/artworks/"+tt.id+"/model", tt.body), tt.id)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestWritesToReadOnlyDatabase(t *testing.T) {
+	t.Setenv("ENABLE_EDITING", "true")
+	withoutModelList(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.New(path)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	groupID := seedGroup(t, db, "Pelican", "birds")
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	db.Close()
+
+	readOnly, err := database.New("file:" + path + "?mode=ro")
+	if err != nil {
+		t.Fatalf("open read-only: %v", err)
+	}
+	t.Cleanup(func() { readOnly.Close() })
+	if !readOnly.IsReadOnly() {
+		t.Fatal("IsReadOnly = false for a database opened with mode=ro")
+	}
+	prompts, err := config.NewPromptStore("../../config/prompts")
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+	h := NewHandler(prompts, readOnly, nil, metrics.NewAppMetrics(), &fakeGenerator{})
+
+	group, artwork := strconv.Itoa(groupID), strconv.Itoa(artworkID)
+	tests := []struct {
+		name  string
+		serve func(w http.ResponseWriter)
+	}{
+		{"create group", func(w http.ResponseWriter) {
+			h.CreateGroupHandler(w, newRequest(http.MethodPost, "/api/groups", `{"title": "Heron", "prompt": "A heron"}`))
+		}},
+		{"update group", func(w http.ResponseWriter) {
+			h.UpdateGroupHandler(w, newRequest(http.MethodPut, "/api/groups/"+group, `{"title": "Heron", "prompt": "A heron"}`), group)
+		}},
+		{"delete group", func(w http.ResponseWriter) {
+			h.DeleteGroupHandler(w, newRequest(http.MethodDelete, "/api/groups/"+group, ""), group)
+		}},
+		{"create artwork", func(w http.ResponseWriter) {
+			h.CreateArtworkHandler(w, newRequest(http.MethodPost, "/api/artworks", `{"group_id": `+group+`, "model": "google/gemini-2.5-pro", "max_tokens": 1000}`))
+		}},
+		{"feature artwork", func(w http.ResponseWriter) {
+			h.SetFeaturedArtworkHandler(w, newRequest(http.MethodPost, "/api/artworks/"+artwork+"/featured", ""), artwork)
+		}},
+		{"delete artwork", func(w http.ResponseWriter) {
+			h.DeleteArtworkHandler(w, newRequest(http.MethodDelete, "/api/delete-artwork/"+artwork, ""), artwork)
+		}},
+		{"rename category", func(w http.ResponseWriter) {
+			h.RenameCategoryHandler(w, newRequest(http.MethodPut, "/api/categories/birds", `{"name": "Birds"}`), "birds")
+		}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.serve(rec)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, http.StatusForbidden, rec.Body)
+		}
+	}
+
+	// Reads still work and nothing changed
+	if g, err := readOnly.GetGroup(groupID); err != nil || g.Title != "Pelican" || g.Category != "birds" {
+		t.Errorf("group after refused writes = %+v, %v", g, err)
+	}
+	if a, err := readOnly.GetArtwork(artworkID); err != nil || a.Featured {
+		t.Errorf("artwork after refused writes = %+v, %v", a, err)
+	}
+}
+
+func TestArtworkParameterValidation(t *testing.T) {
+	withoutModelList(t)
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	artworkID := strconv.Itoa(seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG))
+
+	tests := []struct {
+		name                   string
+		temperature, maxTokens string
+		wantCode               int
+		wantMessage            string
+	}{
+		{"valid", "0.7", "4096", http.StatusOK, ""},
+		{"highest bounds", "2", "100000", http.StatusOK, ""},
+		{"negative temperature", "-0.5", "4096", http.StatusBadRequest, "temperature must be between 0 and 2, got -0.5"},
+		{"temperature over 2", "2.5", "4096", http.StatusBadRequest, "temperature must be between 0 and 2, got 2.5"},
+		{"negative max_tokens", "0.7", "-1", http.StatusBadRequest, "max_tokens must be between 1 and 100000, got -1"},
+		{"max_tokens over the limit", "0.7", "200000", http.StatusBadRequest, "max_tokens must be between 1 and 100000, got 200000"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each created artwork needs a model of its own in the group
+			model := "test/model-" + strconv.Itoa(i)
+			rec := httptest.NewRecorder()
+			h.CreateArtworkHandler(rec, newRequest(http.MethodPost, "/api/artworks",
+				`{"group_id": `+strconv.Itoa(groupID)+`, "model": "`+model+`", "temperature": `+tt.temperature+`, "max_tokens": `+tt.maxTokens+`}`))
+			checkValidation(t, "create", rec, tt.wantCode, tt.wantMessage)
+
+			rec = httptest.NewRecorder()
+			h.UpdateArtworkHandler(rec, newRequest(http.MethodPatch, "/api/artworks/"+artworkID,
+				`{"temperature": `+tt.temperature+`, "max_tokens": `+tt.maxTokens+`}`), artworkID)
+			checkValidation(t, "update", rec, tt.wantCode, tt.wantMessage)
+
+			// GenerateHandler, which is not routed, shares the bounds
+			rec = httptest.NewRecorder()
+			h.GenerateHandler(rec, newRequest(http.MethodPost, "/",
+				`{"prompt": "A pelican", "model": "`+model+`", "temperature": `+tt.temperature+`, "max_tokens": `+tt.maxTokens+`}`))
+			checkValidation(t, "generate", rec, tt.wantCode, tt.wantMessage)
+		})
+	}
+}
+
+// checkValidation checks the status of a validated request and, when it
+// fails, the message naming the bad field
+func checkValidation(t *testing.T, action string, rec *httptest.ResponseRecorder, wantCode int, wantMessage string) {
+	t.Helper()
+	if rec.Code != wantCode && !(wantCode == http.StatusOK && rec.Code == http.StatusCreated) {
+		t.Fatalf("%s = %d, want %d: %s", action, rec.Code, wantCode, rec.Body)
+	}
+	if wantMessage == "" {
+		return
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	decodeJSON(t, rec, &body)
+	if body.Message != wantMessage {
+		t.Errorf("%s message = %q, want %q", action, body.Message, wantMessage)
+	}
+}
+
+func TestUnknownModels(t *testing.T) {
+	withModelList(t, "anthropic/claude-sonnet-4", "openai/gpt-4o", "openai/gpt-4o-mini")
+	h, db, _ := newTestHandler(t)
+	groupID := strconv.Itoa(seedGroup(t, db, "Pelican", ""))
+
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreateArtworkHandler(rec, newRequest(http.MethodPost, "/api/artworks", `{"group_id": `+groupID+`, "max_tokens": 1000, `+body+`}`))
+		return rec
+	}
+
+	tests := []struct {
+		name            string
+		body            string
+		wantCode        int
+		wantSuggestions []string
+	}{
+		{"listed", `"model": "openai/gpt-4o"`, http.StatusCreated, nil},
+		{"typo", `"model": "anthropic/claude-sonet-4"`, http.StatusUnprocessableEntity, []string{"anthropic/claude-sonnet-4"}},
+		{"prefix", `"model": "openai/gpt-4"`, http.StatusUnprocessableEntity, []string{"openai/gpt-4o", "openai/gpt-4o-mini"}},
+		{"unknown", `"model": "acme/painter"`, http.StatusUnprocessableEntity, []string{}},
+		{"unknown with override", `"model": "acme/painter", "allow_unknown_model": true`, http.StatusCreated, nil},
+		{"imported SVG", `"model": "retired/model", "svg": "` + strings.ReplaceAll(testSVG, `"`, `\"`) + `"`, http.StatusCreated, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := create(tt.body)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantSuggestions == nil {
+				return
+			}
+			var body struct {
+				Details struct {
+					Code        string   `json:"code"`
+					Suggestions []string `json:"suggestions"`
+				} `json:"details"`
+			}
+			decodeJSON(t, rec, &body)
+			if body.Details.Code != "unknown_model" || !reflect.DeepEqual(body.Details.Suggestions, tt.wantSuggestions) {
+				t.Errorf("details = %+v, want code unknown_model and suggestions %q", body.Details, tt.wantSuggestions)
+			}
+		})
+	}
+
+	// Changing the model and the ad-hoc generate path check it the same way
+	artworkID := strconv.Itoa(seedArtwork(t, db, seedGroup(t, db, "Heron", ""), "openai/gpt-4o", testSVG))
+	rec := httptest.NewRecorder()
+	h.SetArtworkModelHandler(rec, newRequest(http.MethodPatch, "/api/artworks/"+artworkID+"/model", `{"model": "anthropic/claude-sonet-4"}`), artworkID)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("model change status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	h.GenerateHandler(rec, newRequest(http.MethodPost, "/", `{"prompt": "A pelican", "model": "anthropic/claude-sonet-4", "max_tokens": 1000}`))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("generate status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+}
+
+func TestUnknownModelsWithoutModelList(t *testing.T) {
+	withoutModelList(t)
+	h, db, _ := newTestHandler(t)
+	groupID := strconv.Itoa(seedGroup(t, db, "Pelican", ""))
+
+	// Nothing can be checked, so the model is accepted with a warning
+	rec := httptest.NewRecorder()
+	h.CreateArtworkHandler(rec, newRequest(http.MethodPost, "/api/artworks", `{"group_id": `+groupID+`, "model": "anthropic/claude-sonet-4", "max_tokens": 1000}`))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+}
+
+func TestGenerateHandlerBusy(t *testing.T) {
+	withoutModelList(t)
+	t.Setenv("MAX_CONCURRENT_GENERATIONS", "1")
+	t.Setenv("GENERATION_QUEUE_TIMEOUT", "50ms")
+	h, _, gen := newTestHandler(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	gen.generate = func(req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+		if req.Model == "slow/model" {
+			close(started)
+			<-release
+		}
+		svg := modelSVG(req.Model)
+		return openrouter.GenerationResult{SVG: svg, Content: svg, FinishReason: "stop", Model: req.Model}, nil
+	}
+
+	generate := func(model string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.GenerateHandler(rec, newRequest(http.MethodPost, "/", `{"prompt": "A pelican", "model": "`+model+`", "max_tokens": 1000}`))
+		return rec
+	}
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- generate("slow/model") }()
+	<-started
+
+	rec := generate("fast/model")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status with the only slot taken = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body)
+	}
+	var body struct {
+		Details struct {
+			Code string `json:"code"`
+		} `json:"details"`
+	}
+	decodeJSON(t, rec, &body)
+	if body.Details.Code != "generation_busy" {
+		t.Errorf("code = %q, want generation_busy", body.Details.Code)
+	}
+
+	close(release)
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Errorf("slow generation = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := generate("fast/model"); rec.Code != http.StatusOK {
+		t.Errorf("generation once the slot is free = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateArtworkTakesModelOverrideDefaults(t *testing.T) {
+	withoutModelList(t)
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte("model_overrides:\n  - match: \"openai/o*\"\n    temperature: 1\n    max_tokens: 16000\n    reasoning_effort: high\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.InitModelOverrides(path); err != nil {
+		t.Fatalf("InitModelOverrides: %v", err)
+	}
+	t.Cleanup(func() { config.InitModelOverrides(filepath.Join(t.TempDir(), "none.yaml")) })
+	h, db, _ := newTestHandler(t)
+	groupID := strconv.Itoa(seedGroup(t, db, "Pelican", ""))
+
+	tests := []struct {
+		name, body    string
+		wantTemp      float64
+		wantMaxTokens int
+		wantReasoning string
+	}{
+		{"defaults", `"model": "openai/o3"`, 1, 16000, "high"},
+		{"explicit values", `"model": "openai/o4-mini", "temperature": 0, "max_tokens": 2000, "reasoning_effort": "low"`, 0, 2000, "low"},
+		{"no override", `"model": "openai/gpt-4o", "max_tokens": 1000`, 0, 1000, ""},
+		{"imported SVG", `"model": "openai/o1", "svg": "` + strings.ReplaceAll(testSVG, `"`, `\"`) + `"`, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.CreateArtworkHandler(rec, newRequest(http.MethodPost, "/api/artworks", `{"group_id": `+groupID+`, `+tt.body+`}`))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			var artwork models.Artwork
+			decodeJSON(t, rec, &artwork)
+			if artwork.Temperature != tt.wantTemp || artwork.MaxTokens != tt.wantMaxTokens || artwork.ReasoningEffort != tt.wantReasoning {
+				t.Errorf("artwork has temperature %v, max_tokens %d, effort %q, want %v, %d, %q",
+					artwork.Temperature, artwork.MaxTokens, artwork.ReasoningEffort, tt.wantTemp, tt.wantMaxTokens, tt.wantReasoning)
+			}
+		})
+	}
+}
+
+func TestCreateGroupIdempotency(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+
+	create := func(key, body string) *httptest.ResponseRecorder {
+		r := newRequest(http.MethodPost, "/api/groups", body)
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.CreateGroupHandler(rec, r)
+		return rec
+	}
+	groupID := func(rec *httptest.ResponseRecorder) int {
+		t.Helper()
+		var group models.ArtworkGroup
+		decodeJSON(t, rec, &group)
+		return group.ID
+	}
+	groupCount := func() int {
+		t.Helper()
+		groups, err := db.ListGroups()
+		if err != nil {
+			t.Fatalf("ListGroups: %v", err)
+		}
+		return len(groups)
+	}
+
+	const dawn = `{"title": "Pelican at dawn", "prompt": "A pelican at dawn", "category": "Birds"}`
+	rec := create("form-1", dawn)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("first create = %d, body %s", rec.Code, rec.Body)
+	}
+	first := groupID(rec)
+
+	// A retry with the key gets the same group back, whatever its body says
+	for _, body := range []string{dawn, `{"title": "Heron", "prompt": "A heron"}`} {
+		rec := create("form-1", body)
+		if rec.Code != http.StatusOK || groupID(rec) != first {
+			t.Errorf("retry = %d, body %s, want 200 with group %d", rec.Code, rec.Body, first)
+		}
+	}
+	if n := groupCount(); n != 1 {
+		t.Fatalf("%d groups after retries, want 1", n)
+	}
+
+	// Concurrent requests with one key create one group
+	const retries = 5
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, retries)
+	for i := range recs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recs[i] = create("import-7", `{"title": "Heron", "prompt": "A heron", "category": "Birds"}`)
+		}(i)
+	}
+	wg.Wait()
+	ids := make(map[int]bool)
+	for _, rec := range recs {
+		if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+			t.Fatalf("concurrent create = %d, body %s", rec.Code, rec.Body)
+		}
+		ids[groupID(rec)] = true
+	}
+	if len(ids) != 1 || groupCount() != 2 {
+		t.Errorf("concurrent creates answered with groups %v and left %d groups, want one new group", ids, groupCount())
+	}
+
+	// Without a key the same title and prompt conflict, unless forced
+	rec = create("", `{"title": " pelican AT dawn", "prompt": "a pelican  at dawn ", "category": "Sea"}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate create = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	var conflict struct {
+		Details struct {
+			Code  string              `json:"code"`
+			Group models.ArtworkGroup `json:"group"`
+		} `json:"details"`
+	}
+	decodeJSON(t, rec, &conflict)
+	if conflict.Details.Code != "duplicate_group" || conflict.Details.Group.ID != first {
+		t.Errorf("conflict details = %+v, want duplicate_group of group %d", conflict.Details, first)
+	}
+	rec = create("", `{"title": "Pelican at dawn", "prompt": "A pelican at dawn", "category": "Sea", "force": true}`)
+	if rec.Code != http.StatusCreated || groupID(rec) == first {
+		t.Errorf("forced create = %d, body %s, want a new group", rec.Code, rec.Body)
+	}
+
+	// The key of a deleted group is not reused
+	if err := db.DeleteGroup(first); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if rec := create("form-1", dawn); rec.Code != http.StatusConflict {
+		t.Errorf("retry after deleting the group = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	if rec := create(strings.Repeat("k", maxIdempotencyKeyLength+1), dawn); rec.Code != http.StatusBadRequest {
+		t.Errorf("overlong key = %d, want 400", rec.Code)
+	}
+}
+
+func TestGetOriginalArtworkConditionalGet(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	idStr := strconv.Itoa(groupID)
+	uploadedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: groupID, ContentType: "image/png", Data: []byte("png bytes"), UploadedAt: uploadedAt}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		r := newRequest(http.MethodGet, "/api/groups/"+idStr+"/original-artwork", "")
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		h.GetOriginalArtworkHandler(rec, r, idStr)
+		return rec
+	}
+
+	rec := get("", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "png bytes" {
+		t.Fatalf("GET = %d %q, want 200 with the image", rec.Code, rec.Body)
+	}
+	etag := rec.Header().Get("ETag")
+	sum := sha256.Sum256([]byte("png bytes"))
+	if want := `"` + hex.EncodeToString(sum[:]) + `"`; etag != want {
+		t.Errorf("ETag = %s, want %s", etag, want)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != uploadedAt.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q, want %q", got, uploadedAt.Format(http.TimeFormat))
+	}
+	if rec.Header().Get("Cache-Control") == "" || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+
+	tests := []struct {
+		header, value string
+		want          int
+	}{
+		{"If-None-Match", etag, http.StatusNotModified},
+		{"If-None-Match", `"stale"`, http.StatusOK},
+		{"If-Modified-Since", uploadedAt.Format(http.TimeFormat), http.StatusNotModified},
+		{"If-Modified-Since", uploadedAt.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := get(tt.header, tt.value)
+		if rec.Code != tt.want {
+			t.Errorf("%s: %s = %d, want %d", tt.header, tt.value, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("%s: %s sent a body with the 304", tt.header, tt.value)
+		}
+	}
+
+	// A new upload changes the ETag, so the old one no longer matches
+	if err := db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: groupID, ContentType: "image/png", Data: []byte("new bytes"), UploadedAt: uploadedAt.Add(time.Hour)}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusOK || rec.Body.String() != "new bytes" {
+		t.Errorf("GET with the old ETag = %d %q, want 200 with the new image", rec.Code, rec.Body)
+	}
+}
+
+// uploadOriginal posts data as the original artwork of group idStr
+func uploadOriginal(t *testing.T, h *Handler, idStr string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("artwork", "reference.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/groups/"+idStr+"/original-artwork", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.UploadOriginalArtworkHandler(rec, r, idStr)
+	return rec
+}
+
+func TestUploadOriginalArtworkHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	idStr := strconv.Itoa(groupID)
+
+	if rec := uploadOriginal(t, h, idStr, testPNG(t, 1200, 600)); rec.Code != http.StatusOK {
+		t.Fatalf("upload = %d: %s", rec.Code, rec.Body)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.GetOriginalArtworkHandler(rec, newRequest(http.MethodGet, "/api/groups/"+idStr+"/original-artwork"+query, ""), idStr)
+		return rec
+	}
+	sizes := map[string]image.Point{"": image.Pt(1200, 600), "?size=full": image.Pt(1200, 600), "?size=thumb": image.Pt(400, 200)}
+	etags := make(map[string]string)
+	for query, want := range sizes {
+		rec := get(query)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("GET %q = %d %s", query, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		img, err := png.Decode(rec.Body)
+		if err != nil {
+			t.Fatalf("GET %q: %v", query, err)
+		}
+		if size := img.Bounds().Size(); size != want {
+			t.Errorf("GET %q size = %v, want %v", query, size, want)
+		}
+		etags[query] = rec.Header().Get("ETag")
+	}
+	if etags[""] != etags["?size=full"] || etags["?size=thumb"] == etags["?size=full"] {
+		t.Errorf("ETags = %v, want one for the full image and another for the thumbnail", etags)
+	}
+	if rec := get("?size=huge"); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET ?size=huge = %d, want 400", rec.Code)
+	}
+
+	// Images stored before thumbnails existed serve the full image
+	if err := db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: groupID, ContentType: "image/png", Data: []byte("png bytes"), UploadedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+	if rec := get("?size=thumb"); rec.Code != http.StatusOK || rec.Body.String() != "png bytes" {
+		t.Errorf("thumb without a thumbnail = %d %q, want the full image", rec.Code, rec.Body)
+	}
+
+	// A PNG whose header claims 10000x5000 pixels is rejected before decoding
+	huge := testPNG(t, 1, 1)
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 5000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	tests := []struct {
+		name, id string
+		data     []byte
+		want     int
+	}{
+		{"too large", idStr, huge, http.StatusRequestEntityTooLarge},
+		{"not an image", idStr, []byte(testSVG), http.StatusBadRequest},
+		{"truncated", idStr, testPNG(t, 4, 4)[:40], http.StatusBadRequest},
+		{"unknown group", "999", testPNG(t, 4, 4), http.StatusNotFound},
+		{"invalid group", "abc", testPNG(t, 4, 4), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := uploadOriginal(t, h, tt.id, tt.data); rec.Code != tt.want {
+			t.Errorf("%s: upload = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if rec := get(""); rec.Body.String() != "png bytes" {
+		t.Error("a rejected upload replaced the stored image")
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := uploadOriginal(t, h, idStr, testPNG(t, 4, 4)); rec.Code != http.StatusForbidden {
+		t.Errorf("with editing disabled, upload = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestReloadConfigHandler(t *testing.T) {
+	withoutModelList(t)
+	h, _, gen := newTestHandler(t)
+	dir := t.TempDir()
+	writeConfig := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "default.yaml"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("name: Plain\ndefault: true\nsystem_prompts:\n  - role: system\n    content: Draw well.\nuser_prompt_template: Draw {{.Description}}\n")
+	prompts, err := config.NewPromptStore(dir)
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+	h.prompts = prompts
+
+	// generate runs a generation and returns the system prompt it was sent
+	generate := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GenerateHandler(rec, newRequest(http.MethodPost, "/api/generate", `{"prompt": "A pelican", "model": "openai/gpt-4o", "max_tokens": 1000}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("generate status = %d: %s", rec.Code, rec.Body)
+		}
+		calls := gen.calls()
+		sent := calls[len(calls)-1].PromptConfig
+		if sent == nil || len(sent.SystemPrompts) != 1 {
+			t.Fatalf("generation was sent prompt config %+v", sent)
+		}
+		return sent.SystemPrompts[0].Content
+	}
+	reload := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ReloadConfigHandler(rec, newRequest(http.MethodPost, "/api/admin/reload-config", ""))
+		return rec
+	}
+
+	if got := generate(); got != "Draw well." {
+		t.Fatalf("system prompt = %q, want Draw well.", got)
+	}
+
+	// Editing the file alone changes nothing until the reload
+	writeConfig("name: Plain\ndefault: true\nsystem_prompts:\n  - role: system\n    content: Draw better.\nuser_prompt_template: Draw {{.Description}}\n")
+	if got := generate(); got != "Draw well." {
+		t.Errorf("system prompt before the reload = %q, want Draw well.", got)
+	}
+	rec := reload()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reload status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Changes config.PromptChanges `json:"changes"`
+	}
+	decodeJSON(t, rec, &body)
+	if fields := body.Changes.Changed["Plain"]; !reflect.DeepEqual(fields, []string{"system_prompts"}) {
+		t.Errorf("changes = %+v, want the system prompts of Plain", body.Changes)
+	}
+	if got := generate(); got != "Draw better." {
+		t.Errorf("system prompt after the reload = %q, want Draw better.", got)
+	}
+
+	// Invalid YAML is rejected and the running config kept
+	writeConfig("name: [Plain\n")
+	if rec := reload(); rec.Code != http.StatusBadRequest {
+		t.Errorf("reload of invalid YAML status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	if got := generate(); got != "Draw better." {
+		t.Errorf("system prompt after a rejected reload = %q, want Draw better.", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ReloadConfigHandler(rec, newRequest(http.MethodGet, "/api/admin/reload-config", ""))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := reload(); rec.Code != http.StatusForbidden {
+		t.Errorf("reload with editing disabled status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestSetFeaturedArtworkHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	pelican := seedArtwork(t, db, seedGroup(t, db, "Pelican", ""), "openai/gpt-4o", testSVG)
+	heronGroup := seedGroup(t, db, "Heron", "")
+	heron := seedArtwork(t, db, heronGroup, "openai/gpt-4o", testSVG)
+	heronGemini := seedArtwork(t, db, heronGroup, "google/gemini-2.5-pro", testSVG)
+
+	feature := func(id int, query string) *httptest.ResponseRecorder {
+		idStr := strconv.Itoa(id)
+		rec := httptest.NewRecorder()
+		h.SetFeaturedArtworkHandler(rec, newRequest(http.MethodPost, "/api/artworks/"+idStr+"/featured"+query, ""), idStr)
+		return rec
+	}
+	featured := func() []int {
+		t.Helper()
+		var ids []int
+		for _, id := range []int{pelican, heron, heronGemini} {
+			artwork, err := db.GetArtwork(id)
+			if err != nil {
+				t.Fatalf("GetArtwork: %v", err)
+			}
+			if artwork.Featured {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	steps := []struct {
+		id    int
+		query string
+		want  []int
+	}{
+		{pelican, "", []int{pelican}},
+		{heron, "", []int{pelican, heron}},
+		{heronGemini, "", []int{pelican, heronGemini}},
+		{heron, "?global=true", []int{heron}},
+	}
+	for _, step := range steps {
+		rec := feature(step.id, step.query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("feature %d%s = %d: %s", step.id, step.query, rec.Code, rec.Body)
+		}
+		var body struct {
+			Global bool `json:"global"`
+		}
+		decodeJSON(t, rec, &body)
+		if body.Global != (step.query != "") {
+			t.Errorf("feature %d%s: global = %v", step.id, step.query, body.Global)
+		}
+		if got := featured(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("after featuring %d%s, featured = %v, want %v", step.id, step.query, got, step.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.SetFeaturedArtworkHandler(rec, newRequest(http.MethodPost, "/api/artworks/abc/featured", ""), "abc")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := feature(pelican, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("with editing disabled = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if got := featured(); !reflect.DeepEqual(got, []int{heron}) {
+		t.Errorf("featured after rejected requests = %v, want [%d]", got, heron)
+	}
+}
+
+func TestGenerateHandlerLogsJSON(t *testing.T) {
+	withModelList(t, "openai/gpt-4o")
+	h, _, gen := newTestHandler(t)
+	var buf bytes.Buffer
+	logging.Setup("json", &buf)
+	t.Cleanup(func() { logging.Setup("", os.Stderr) })
+
+	generate := func() {
+		t.Helper()
+		r := newRequest(http.MethodPost, "/api/generate", `{"prompt": "A pelican", "model": "openai/gpt-4o", "max_tokens": 1000}`)
+		rec := httptest.NewRecorder()
+		h.GenerateHandler(rec, r.WithContext(logging.WithRequestID(r.Context(), "req-1")))
+	}
+	generate()
+	gen.generate = func(req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+		return openrouter.GenerationResult{}, errors.New("upstream down")
+	}
+	generate()
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %v\n%s", err, line)
+		}
+		if record["model"] != nil {
+			records = append(records, record)
+		}
+	}
+	want := []map[string]interface{}{
+		{"level": "INFO", "prompt_length": float64(len("A pelican"))},
+		{"level": "INFO", "svg_length": float64(len(modelSVG("openai/gpt-4o")))},
+		{"level": "INFO", "prompt_length": float64(len("A pelican"))},
+		{"level": "ERROR", "error": "upstream down"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("%d records with a model, want %d:\n%s", len(records), len(want), buf.String())
+	}
+	for i, record := range records {
+		want[i]["model"] = "openai/gpt-4o"
+		want[i]["request_id"] = "req-1"
+		for key, value := range want[i] {
+			if record[key] != value {
+				t.Errorf("record %d: %s = %v, want %v", i, key, record[key], value)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"pelican-gallery/internal/config"
+)
+
+// healthTimeout bounds the database check of the readiness probe
+const healthTimeout = 2 * time.Second
+
+// componentCheck is the outcome of one readiness sub-check
+type componentCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LiveHandler handles GET /health/live, also served at /health. It only
+// shows that the process serves requests and never touches the database, so
+// a slow database does not get the process restarted.
+func (h *Handler) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyHandler handles GET /health/ready, also served at /readyz. It checks
+// that the database responds, that the templates are parsed and, while
+// editing is enabled outside DRY_RUN, that an OpenRouter API key is
+// configured. It answers
+// 200 when every check passes and 503 otherwise, with the status of each
+// component and the schema version.
+func (h *Handler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	var version int
+	dbErr := h.db.Ping(ctx)
+	if dbErr == nil {
+		version, dbErr = h.db.SchemaVersion()
+	}
+	checks := map[string]error{
+		"database":  dbErr,
+		"templates": h.checkTemplates(),
+	}
+	// Dry runs generate placeholders without a key
+	if isEditingEnabled() && !config.DryRun() {
+		checks["openrouter"] = checkOpenRouterKey()
+	}
+
+	status, code := "ok", http.StatusOK
+	components := make(map[string]componentCheck, len(checks))
+	for name, err := range checks {
+		if err != nil {
+			log.Printf("Readiness check %s failed: %v", name, err)
+			status, code = "unhealthy", http.StatusServiceUnavailable
+			components[name] = componentCheck{Status: "unhealthy", Error: err.Error()}
+			continue
+		}
+		components[name] = componentCheck{Status: "ok"}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	report := map[string]interface{}{
+		"status":     status,
+		"components": components,
+	}
+	if version > 0 {
+		report["schema_version"] = version
+	}
+	writeJSON(w, code, report)
+}
+
+// checkTemplates reports whether the page templates have been parsed
+func (h *Handler) checkTemplates() error {
+	if h.tmpl == nil || len(h.tmpl.Templates()) == 0 {
+		return errors.New("templates are not parsed")
+	}
+	return nil
+}
+
+// checkOpenRouterKey reports whether generation has an API key to use
+func checkOpenRouterKey() error {
+	if os.Getenv("OPENROUTER_API_KEY") == "" {
+		return errors.New("OPENROUTER_API_KEY is not set")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pelican-gallery/internal/metrics"
+)
+
+func TestLiveHandler(t *testing.T) {
+	// A handler without a database: liveness must not touch it
+	h := &Handler{}
+	rec := httptest.NewRecorder()
+	h.LiveHandler(rec, newRequest(http.MethodGet, "/health/live", ""))
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("liveness = %d %v, want 200 and no-store", rec.Code, rec.Header())
+	}
+	var body map[string]string
+	decodeJSON(t, rec, &body)
+	if body["status"] != "ok" {
+		t.Errorf("body = %v, want status ok", body)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	parsed := template.Must(template.New("page.html").Parse(`ok`))
+
+	tests := []struct {
+		name       string
+		templates  *template.Template
+		editing    string
+		dryRun     string
+		apiKey     string
+		closeDB    bool
+		want       int
+		components map[string]string
+	}{
+		{"ready", parsed, "true", "", "key", false, http.StatusOK,
+			map[string]string{"database": "ok", "templates": "ok", "openrouter": "ok"}},
+		{"closed database", parsed, "true", "", "key", true, http.StatusServiceUnavailable,
+			map[string]string{"database": "unhealthy", "templates": "ok", "openrouter": "ok"}},
+		{"missing templates", nil, "true", "", "key", false, http.StatusServiceUnavailable,
+			map[string]string{"database": "ok", "templates": "unhealthy", "openrouter": "ok"}},
+		{"missing key while editing", parsed, "true", "", "", false, http.StatusServiceUnavailable,
+			map[string]string{"database": "ok", "templates": "ok", "openrouter": "unhealthy"}},
+		// Without editing nothing is generated, so the key is not checked
+		{"missing key without editing", parsed, "false", "", "", false, http.StatusOK,
+			map[string]string{"database": "ok", "templates": "ok"}},
+		// Dry runs draw placeholders, so they need no key either
+		{"missing key in a dry run", parsed, "true", "true", "", false, http.StatusOK,
+			map[string]string{"database": "ok", "templates": "ok"}},
+		{"closed database in a dry run", parsed, "true", "true", "", true, http.StatusServiceUnavailable,
+			map[string]string{"database": "unhealthy", "templates": "ok"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, db, gen := newTestHandler(t)
+			t.Setenv("ENABLE_EDITING", tt.editing)
+			t.Setenv("DRY_RUN", tt.dryRun)
+			t.Setenv("OPENROUTER_API_KEY", tt.apiKey)
+			h := NewHandler(nil, db, tt.templates, metrics.NewAppMetrics(), gen)
+			if tt.closeDB {
+				db.Close()
+			}
+
+			rec := httptest.NewRecorder()
+			h.ReadyHandler(rec, newRequest(http.MethodGet, "/health/ready", ""))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", cc)
+			}
+			var body struct {
+				Status        string                    `json:"status"`
+				Components    map[string]componentCheck `json:"components"`
+				SchemaVersion int                       `json:"schema_version"`
+			}
+			decodeJSON(t, rec, &body)
+			if (body.SchemaVersion > 0) == tt.closeDB {
+				t.Errorf("schema_version = %d with the database closed = %v", body.SchemaVersion, tt.closeDB)
+			}
+
+			wantStatus := "ok"
+			if tt.want != http.StatusOK {
+				wantStatus = "unhealthy"
+			}
+			if body.Status != wantStatus {
+				t.Errorf("status = %q, want %q", body.Status, wantStatus)
+			}
+			if len(body.Components) != len(tt.components) {
+				t.Errorf("components = %v, want %v", body.Components, tt.components)
+			}
+			for name, want := range tt.components {
+				got := body.Components[name]
+				if got.Status != want || (want == "unhealthy") != (got.Error != "") {
+					t.Errorf("component %s = %+v, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
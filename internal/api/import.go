@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"pelican-gallery/internal/images"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/sanitize"
+)
+
+// maxImportBytes bounds the (decompressed) size of an import document
+const maxImportBytes = 256 << 20
+
+// Conflict policies for groups whose title already exists
+const (
+	importOnConflictSkip      = "skip"
+	importOnConflictOverwrite = "overwrite"
+)
+
+// importDocument is the export document as read back by the import
+type importDocument struct {
+	Version int           `json:"version"`
+	Groups  []exportGroup `json:"groups"`
+}
+
+// ImportHandler handles POST /api/import. It accepts the document written by
+// /api/export, plain or gzip-compressed, and recreates its groups, artworks
+// and reference images in one transaction. Groups whose title already exists
+// are skipped, or replaced with ?on_conflict=overwrite. Every record is
+// validated first; nothing is written unless all of them pass.
+func (h *Handler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Import is only available while editing is enabled")
+		return
+	}
+
+	onConflict := r.URL.Query().Get("on_conflict")
+	switch onConflict {
+	case "":
+		onConflict = importOnConflictSkip
+	case importOnConflictSkip, importOnConflictOverwrite:
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Unknown on_conflict %q: use %q or %q", onConflict, importOnConflictSkip, importOnConflictOverwrite))
+		return
+	}
+
+	body, err := importBody(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid import body", err.Error())
+		return
+	}
+
+	result, err := h.Import(body, onConflict == importOnConflictOverwrite)
+	var maxBytesErr *http.MaxBytesError
+	var rejected *ImportRejectedError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import is larger than %d bytes", maxImportBytes))
+		return
+	case errors.Is(err, ErrInvalidImport):
+		writeJSONError(w, http.StatusBadRequest, "Invalid import document", err.Error())
+		return
+	case errors.As(err, &rejected):
+		writeJSONError(w, http.StatusBadRequest, "Import rejected: some records are invalid", rejected.Problems)
+		return
+	case err != nil:
+		log.Printf("Import failed and was rolled back: %v", err)
+		writeDBError(w, err, http.StatusInternalServerError, "Import failed; nothing was imported", err.Error())
+		return
+	}
+
+	log.Printf("Imported %d group(s) (%d overwritten, %d skipped) and %d artwork(s) (%d skipped)",
+		result.GroupsImported, result.GroupsOverwritten, result.GroupsSkipped, result.ArtworksImported, result.ArtworksSkipped)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ErrInvalidImport is returned by Import for a document that is not valid
+// JSON
+var ErrInvalidImport = errors.New("invalid import document")
+
+// ImportRejectedError is returned by Import for a document with invalid
+// records; it lists every problem found
+type ImportRejectedError struct {
+	Problems []string
+}
+
+func (e *ImportRejectedError) Error() string {
+	return "import rejected: " + strings.Join(e.Problems, "; ")
+}
+
+// Import imports the export document read from doc the way POST /api/import
+// does, for callers outside HTTP such as the import command. Every record
+// is validated first; nothing is written unless all of them pass.
+func (h *Handler) Import(doc io.Reader, overwrite bool) (*models.ImportResult, error) {
+	var document importDocument
+	if err := json.NewDecoder(doc).Decode(&document); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+
+	groups, problems := h.validateImport(document)
+	if len(problems) > 0 {
+		return nil, &ImportRejectedError{Problems: problems}
+	}
+
+	return h.db.ImportGroups(groups, overwrite)
+}
+
+// importBody returns the size-limited request body, decompressing it when it
+// is sent with Content-Encoding: gzip or is a gzip file such as a ?gzip=1
+// export
+func importBody(w http.ResponseWriter, r *http.Request) (io.Reader, error) {
+	body := bufio.NewReader(r.Body)
+
+	magic, _ := body.Peek(2)
+	compressed := strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") ||
+		(len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b)
+	if !compressed {
+		return http.MaxBytesReader(w, io.NopCloser(body), maxImportBytes), nil
+	}
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data: %w", err)
+	}
+	return http.MaxBytesReader(w, gz, maxImportBytes), nil
+}
+
+// validateImport checks every record of the document and converts it for
+// the database. It returns one message per problem found, so a rejected
+// import can be fixed in one go.
+func (h *Handler) validateImport(doc importDocument) ([]models.ImportGroup, []string) {
+	var problems []string
+	if doc.Version != exportVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d: expected %d", doc.Version, exportVersion))
+	}
+	if doc.Groups == nil {
+		problems = append(problems, "missing groups")
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	prompts := h.prompts.Load()
+	now := time.Now()
+
+	groups := make([]models.ImportGroup, 0, len(doc.Groups))
+	for i, entry := range doc.Groups {
+		where := fmt.Sprintf("groups[%d]", i)
+		fail := func(format string, args ...interface{}) {
+			problems = append(problems, where+": "+fmt.Sprintf(format, args...))
+		}
+
+		group := entry.ArtworkGroup
+		group.ID = 0
+		group.DeletedAt = nil
+		group.Title = strings.TrimSpace(group.Title)
+		if group.Title == "" || strings.TrimSpace(group.Prompt) == "" {
+			fail("title and prompt are required")
+		}
+		if !prompts.Has(group.PromptStyle) {
+			fail("unknown prompt style %q", group.PromptStyle)
+		}
+		if group.CreatedAt.IsZero() {
+			group.CreatedAt = now
+		}
+		if group.UpdatedAt.IsZero() {
+			group.UpdatedAt = group.CreatedAt
+		}
+
+		imported := models.ImportGroup{Group: group}
+
+		if original := entry.OriginalArtwork; original != nil {
+			contentType := original.ContentType
+			if contentType == "" {
+				contentType = http.DetectContentType(original.Data)
+			}
+			uploadedAt := original.UploadedAt
+			if uploadedAt.IsZero() {
+				uploadedAt = now
+			}
+			switch {
+			case len(original.Data) == 0:
+				fail("original_artwork has no data")
+			case !strings.HasPrefix(contentType, "image/"):
+				fail("original_artwork must be an image, got %s", contentType)
+			default:
+				// Stored like an upload: scaled down, without metadata
+				if processed, err := images.Process(original.Data); err != nil {
+					fail("original_artwork: %v", err)
+				} else {
+					imported.OriginalArtwork = &models.OriginalArtwork{
+						ContentType: processed.ContentType,
+						Data:        processed.Full,
+						Thumbnail:   processed.Thumb,
+						UploadedAt:  uploadedAt,
+					}
+				}
+			}
+		}
+
+		for j, artwork := range entry.Artworks {
+			where := fmt.Sprintf("groups[%d].artworks[%d]", i, j)
+			fail := func(format string, args ...interface{}) {
+				problems = append(problems, where+": "+fmt.Sprintf(format, args...))
+			}
+
+			if strings.TrimSpace(artwork.Model) == "" {
+				fail("model is required")
+			}
+			if err := models.ValidateTemperature(artwork.Temperature); err != nil {
+				fail("%v", err)
+			}
+			// Imported artworks may carry no max_tokens
+			if artwork.MaxTokens != 0 {
+				if err := models.ValidateMaxTokens(artwork.MaxTokens); err != nil {
+					fail("%v", err)
+				}
+			}
+			if err := models.ValidateReasoningEffort(artwork.ReasoningEffort); err != nil {
+				fail("%v", err)
+			}
+			if err := models.ValidateSource(artwork.Source); err != nil {
+				fail("%v", err)
+			}
+			if artwork.Visibility != "" {
+				visibility, err := models.ParseVisibility(string(artwork.Visibility))
+				if err != nil {
+					fail("%v", err)
+				}
+				artwork.Visibility = visibility
+			}
+
+			artwork.ID = 0
+			artwork.DeletedAt = nil
+			if artwork.SVG != "" {
+				artwork.SVG = sanitize.SVG(artwork.SVG)
+			}
+			if artwork.CreatedAt.IsZero() {
+				artwork.CreatedAt = now
+			}
+			if artwork.UpdatedAt.IsZero() {
+				artwork.UpdatedAt = artwork.CreatedAt
+			}
+			imported.Artworks = append(imported.Artworks, artwork)
+		}
+
+		groups = append(groups, imported)
+	}
+
+	return groups, problems
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// exportBody returns the export document of h
+func exportBody(t *testing.T, h *Handler) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := h.Export(&buf, ""); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// importRequest posts body to the import endpoint of h
+func importRequest(h *Handler, query, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ImportHandler(rec, newRequest(http.MethodPost, "/api/import"+query, body))
+	return rec
+}
+
+// gallerySummary describes the groups of h with their artworks by title,
+// independently of IDs and timestamps
+func gallerySummary(t *testing.T, h *Handler) map[string]string {
+	t.Helper()
+	var doc exportDocument
+	if err := json.Unmarshal(exportBody(t, h), &doc); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	summary := make(map[string]string)
+	for _, g := range doc.Groups {
+		var b strings.Builder
+		b.WriteString(g.Category + "|" + g.Prompt)
+		if g.Archived {
+			b.WriteString("|archived")
+		}
+		if g.OriginalArtwork != nil {
+			b.WriteString("|original " + g.OriginalArtwork.ContentType)
+		}
+		for _, a := range g.Artworks {
+			b.WriteString("|" + a.Model + " " + a.Source + " " + a.SVG)
+		}
+		summary[g.Title] = b.String()
+	}
+	return summary
+}
+
+func TestImportHandlerClean(t *testing.T) {
+	source, sourceDB, _ := newTestHandler(t)
+	seedExportGallery(t, sourceDB)
+	body := exportBody(t, source)
+
+	target, _, _ := newTestHandler(t)
+	rec := importRequest(target, "", string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import = %d, body %s", rec.Code, rec.Body)
+	}
+	var result models.ImportResult
+	decodeJSON(t, rec, &result)
+	if want := (models.ImportResult{GroupsImported: 3, ArtworksImported: 4}); result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	want, got := gallerySummary(t, source), gallerySummary(t, target)
+	if len(got) != len(want) {
+		t.Fatalf("imported gallery = %v, want %v", got, want)
+	}
+	for title, summary := range want {
+		if got[title] != summary {
+			t.Errorf("imported %q = %q, want %q", title, got[title], summary)
+		}
+	}
+}
+
+func TestImportHandlerConflicts(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	seedExportGallery(t, db)
+	body := exportBody(t, h)
+	before := gallerySummary(t, h)
+
+	// Change an SVG in the document so overwriting is visible
+	const newSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10"/></svg>`
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	bicycle := doc["groups"].([]interface{})[2].(map[string]interface{})
+	bicycle["artworks"].([]interface{})[0].(map[string]interface{})["svg"] = newSVG
+	changed, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := importRequest(h, "?on_conflict=skip", string(changed))
+	var result models.ImportResult
+	decodeJSON(t, rec, &result)
+	if want := (models.ImportResult{GroupsSkipped: 3, ArtworksSkipped: 4}); rec.Code != http.StatusOK || result != want {
+		t.Errorf("skip import = %d %+v, want %+v", rec.Code, result, want)
+	}
+	if after := gallerySummary(t, h); after["Bicycle"] != before["Bicycle"] || len(after) != len(before) {
+		t.Errorf("skip import changed the gallery: %v", after)
+	}
+
+	rec = importRequest(h, "?on_conflict=overwrite", string(changed))
+	result = models.ImportResult{}
+	decodeJSON(t, rec, &result)
+	if want := (models.ImportResult{GroupsImported: 3, GroupsOverwritten: 3, ArtworksImported: 4}); rec.Code != http.StatusOK || result != want {
+		t.Errorf("overwrite import = %d %+v, want %+v", rec.Code, result, want)
+	}
+	after := gallerySummary(t, h)
+	if len(after) != len(before) || after["Pelican"] != before["Pelican"] {
+		t.Errorf("overwrite import = %v, want the same groups", after)
+	}
+	if !strings.HasSuffix(after["Bicycle"], newSVG) {
+		t.Errorf("overwritten Bicycle = %q, want the new SVG", after["Bicycle"])
+	}
+	groups, err := db.ListGroups()
+	if err != nil || len(groups) != 3 {
+		t.Errorf("%d groups after overwriting, want 3 (%v)", len(groups), err)
+	}
+}
+
+func TestImportHandlerRejectsMalformedInput(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	seedGroup(t, db, "Existing", "")
+
+	tests := []struct {
+		name, query, body string
+		wantDetail        string
+	}{
+		{"not JSON", "", `{"version": 1, "groups": [`, ""},
+		{"wrong version", "", `{"version": 99, "groups": []}`, "unsupported version 99"},
+		{"no groups", "", `{"version": 1}`, "missing groups"},
+		{"missing title", "", `{"version": 1, "groups": [{"title": "Fine", "prompt": "p", "artworks": []}, {"title": " ", "prompt": "p"}]}`, "groups[1]: title and prompt are required"},
+		{"invalid artwork", "", `{"version": 1, "groups": [{"title": "T", "prompt": "p", "artworks": [{"model": "", "temperature": 9}]}]}`, "groups[0].artworks[0]: model is required"},
+		{"unknown prompt style", "", `{"version": 1, "groups": [{"title": "T", "prompt": "p", "prompt_style": "nope"}]}`, "groups[0]: unknown prompt style"},
+		{"unknown conflict policy", "?on_conflict=merge", `{"version": 1, "groups": []}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := importRequest(h, tt.query, tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantDetail) {
+				t.Errorf("body = %s, want it to mention %q", rec.Body, tt.wantDetail)
+			}
+		})
+	}
+
+	// A document with one bad record imports nothing
+	groups, err := db.ListGroups()
+	if err != nil || len(groups) != 1 {
+		t.Errorf("%d groups after rejected imports, want 1 (%v)", len(groups), err)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Bounds for the ?limit= of the divergent groups
+const (
+	defaultDivergentLimit = 10
+	maxDivergentLimit     = 50
+)
+
+// DivergentGroupsHandler handles GET /api/insights/divergent. It lists the
+// groups whose models drew the least alike, most divergent first, with the
+// public artwork compared for each model and the agreement score of the
+// group: the mean perceptual similarity of each pair of models, from 0 to 1.
+// Groups need two models with rendered artworks. ?limit= bounds the list.
+func (h *Handler) DivergentGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := defaultDivergentLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDivergentLimit {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxDivergentLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	groups, err := h.db.ListDivergentGroups(limit)
+	if err != nil {
+		log.Printf("Error ranking divergent groups: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to rank groups")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"groups": groups,
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestDivergentGroupsHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	const (
+		leftHalf  = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect width="50" height="100"/></svg>`
+		rightHalf = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect x="50" width="50" height="100"/></svg>`
+	)
+	alike := seedGroup(t, db, "Alike", "")
+	seedArtwork(t, db, alike, "openai/gpt-4o", leftHalf)
+	seedArtwork(t, db, alike, "google/gemini-2.5-pro", leftHalf)
+	opposite := seedGroup(t, db, "Opposite", "")
+	seedArtwork(t, db, opposite, "openai/gpt-4o", leftHalf)
+	seedArtwork(t, db, opposite, "google/gemini-2.5-pro", rightHalf)
+	single := seedGroup(t, db, "Single", "")
+	seedArtwork(t, db, single, "openai/gpt-4o", rightHalf)
+
+	divergent := func(query string) []models.DivergentGroup {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.DivergentGroupsHandler(rec, newRequest(http.MethodGet, "/api/insights/divergent"+query, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var body struct {
+			Groups []models.DivergentGroup `json:"groups"`
+		}
+		decodeJSON(t, rec, &body)
+		return body.Groups
+	}
+
+	groups := divergent("")
+	if len(groups) != 2 || groups[0].Group.ID != opposite || groups[1].Group.ID != alike {
+		t.Fatalf("divergent groups = %+v, want Opposite then Alike", groups)
+	}
+	if groups[1].Agreement != 1 || groups[0].Agreement >= 1 || len(groups[0].Artworks) != 2 {
+		t.Errorf("divergent groups = %+v", groups)
+	}
+	if groups := divergent("?limit=1"); len(groups) != 1 || groups[0].Group.ID != opposite {
+		t.Errorf("limit=1 gave %+v", groups)
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=51", "?limit=many"} {
+		rec := httptest.NewRecorder()
+		h.DivergentGroupsHandler(rec, newRequest(http.MethodGet, "/api/insights/divergent"+query, ""))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.DivergentGroupsHandler(rec, newRequest(http.MethodPost, "/api/insights/divergent", ""))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/generation"
+)
+
+// jobRetention is how long a finished job stays available for late or
+// reconnecting event subscribers
+const jobRetention = 10 * time.Minute
+
+// Artwork states reported by batch job events
+const (
+	jobStatusQueued    = "queued"
+	jobStatusStarted   = generation.StatusStarted
+	jobStatusCompleted = generation.StatusCompleted
+	jobStatusFailed    = generation.StatusFailed
+)
+
+// JobEvent reports the state of one artwork in a batch job
+type JobEvent struct {
+	JobID     int    `json:"job_id"`
+	ArtworkID int    `json:"artwork_id"`
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	SVGLength int    `json:"svg_length,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// JobSummary is sent as the final event once a batch job has finished
+type JobSummary struct {
+	JobID     int `json:"job_id"`
+	GroupID   int `json:"group_id"`
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// batchJob tracks the per-artwork state of a batch generation running in the
+// background and fans its events out to subscribers
+type batchJob struct {
+	id      int
+	groupID int
+
+	mu          sync.Mutex
+	states      []JobEvent
+	done        bool
+	subscribers map[chan JobEvent]struct{}
+}
+
+// jobRegistry holds the batch jobs of this process
+type jobRegistry struct {
+	mu     sync.Mutex
+	jobs   map[int]*batchJob
+	nextID int
+}
+
+// start registers a job for the given artworks, all initially queued
+func (reg *jobRegistry) start(groupID int, states []JobEvent) *batchJob {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if reg.jobs == nil {
+		reg.jobs = make(map[int]*batchJob)
+	}
+	reg.nextID++
+
+	job := &batchJob{
+		id:          reg.nextID,
+		groupID:     groupID,
+		states:      states,
+		subscribers: make(map[chan JobEvent]struct{}),
+	}
+	for i := range job.states {
+		job.states[i].JobID = job.id
+	}
+	reg.jobs[job.id] = job
+	return job
+}
+
+// get returns the job with the given ID, if it is still retained
+func (reg *jobRegistry) get(id int) (*batchJob, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	job, ok := reg.jobs[id]
+	return job, ok
+}
+
+// forget drops a job once its retention period is over
+func (reg *jobRegistry) forget(id int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.jobs, id)
+}
+
+// update records the new state of the artwork at idx and sends it to every
+// subscriber
+func (job *batchJob) update(idx int, status string, result generation.BatchResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	event := job.states[idx]
+	event.Status = status
+	event.SVGLength = result.SVGLength
+	event.Error = result.Error
+	job.states[idx] = event
+
+	for ch := range job.subscribers {
+		ch <- event
+	}
+}
+
+// finish marks the job as done and closes every subscriber channel
+func (job *batchJob) finish() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.done = true
+	for ch := range job.subscribers {
+		close(ch)
+		delete(job.subscribers, ch)
+	}
+}
+
+// subscribe returns the current state of every artwork and, unless the job
+// is done, a channel receiving later events. The channel is closed when the
+// job finishes.
+func (job *batchJob) subscribe() ([]JobEvent, chan JobEvent) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	snapshot := append([]JobEvent(nil), job.states...)
+	if job.done {
+		return snapshot, nil
+	}
+
+	// Each artwork emits at most two more events (started, then completed or
+	// failed), so update never blocks on a subscriber
+	ch := make(chan JobEvent, 2*len(job.states))
+	job.subscribers[ch] = struct{}{}
+	return snapshot, ch
+}
+
+// unsubscribe stops event delivery to ch, e.g. after the client went away
+func (job *batchJob) unsubscribe(ch chan JobEvent) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	delete(job.subscribers, ch)
+}
+
+// summary counts the finished artworks of the job
+func (job *batchJob) summary() JobSummary {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	summary := JobSummary{JobID: job.id, GroupID: job.groupID, Total: len(job.states)}
+	for _, state := range job.states {
+		switch state.Status {
+		case jobStatusCompleted:
+			summary.Succeeded++
+		case jobStatusFailed:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// JobEventsHandler handles GET /api/jobs/{id}/events. It streams the job's
+// per-artwork progress as server-sent events, starting with the current state
+// of every artwork so reconnecting clients catch up, and ends with a "done"
+// event once the batch has finished.
+func (h *Handler) JobEventsHandler(w http.ResponseWriter, r *http.Request, jobIDStr string) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	jobID, err := strconv.Atoi(jobIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, ok := h.jobs.get(jobID)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	send := func(event string, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	snapshot, events := job.subscribe()
+	if events != nil {
+		defer job.unsubscribe(events)
+	}
+
+	for _, state := range snapshot {
+		if err := send("progress", state); err != nil {
+			return
+		}
+	}
+
+	for events != nil {
+		select {
+		case <-r.Context().Done():
+			log.Printf("Job %d: event subscriber disconnected", job.id)
+			return
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				break
+			}
+			if err := send("progress", event); err != nil {
+				return
+			}
+		}
+	}
+
+	send("done", job.summary())
+}
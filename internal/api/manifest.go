@@ -0,0 +1,120 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// manifestTTL is how long the assembled manifest is reused
+const manifestTTL = time.Minute
+
+// Manifest is the bootstrap document served by GET /api/manifest. Field names
+// are a public contract for third-party clients: add fields, never rename.
+type Manifest struct {
+	Name         string                 `json:"name"`
+	BaseURL      string                 `json:"base_url"`
+	Version      string                 `json:"version"`
+	APIVersions  []string               `json:"api_versions"`
+	Capabilities ManifestCapabilities   `json:"capabilities"`
+	Categories   []models.CategoryCount `json:"categories"`
+	RateLimits   []ManifestRateLimit    `json:"rate_limits"`
+	PromptStyles []models.PromptStyle   `json:"prompt_styles"`
+	GeneratedAt  time.Time              `json:"generated_at"`
+}
+
+// ManifestCapabilities lists the features enabled on this instance
+type ManifestCapabilities struct {
+	Editing          bool `json:"editing"`
+	Generation       bool `json:"generation"`
+	Voting           bool `json:"voting"`
+	AdminKeyRequired bool `json:"admin_key_required"`
+}
+
+// ManifestRateLimit describes a rate limit tier
+type ManifestRateLimit struct {
+	Scope         string `json:"scope"`
+	Requests      int    `json:"requests"`
+	WindowSeconds int    `json:"window_seconds"`
+}
+
+// manifestCache holds the last assembled manifest
+type manifestCache struct {
+	mu       sync.Mutex
+	manifest *Manifest
+	expires  time.Time
+}
+
+// ManifestHandler handles GET /api/manifest
+func (h *Handler) ManifestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	manifest, err := h.manifest()
+	if err != nil {
+		log.Printf("Error building manifest: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to build manifest")
+		return
+	}
+
+	// The base URL depends on the request when BASE_URL is not configured
+	response := *manifest
+	if response.BaseURL == "" {
+		response.BaseURL = config.RequestBaseURL(r)
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, response)
+}
+
+// manifest returns the cached manifest, rebuilding it once it has expired
+func (h *Handler) manifest() (*Manifest, error) {
+	h.manifestCache.mu.Lock()
+	defer h.manifestCache.mu.Unlock()
+
+	now := time.Now()
+	if h.manifestCache.manifest != nil && now.Before(h.manifestCache.expires) {
+		return h.manifestCache.manifest, nil
+	}
+
+	categories, err := h.db.GetCategoryCounts()
+	if err != nil {
+		return nil, err
+	}
+	if categories == nil {
+		categories = []models.CategoryCount{}
+	}
+
+	editing := isEditingEnabled()
+	manifest := &Manifest{
+		Name:        "Pelican Art Gallery",
+		BaseURL:     config.BaseURL(),
+		Version:     config.Version,
+		APIVersions: []string{"1"},
+		Capabilities: ManifestCapabilities{
+			Editing:          editing,
+			Generation:       editing && os.Getenv("OPENROUTER_API_KEY") != "",
+			Voting:           false,
+			AdminKeyRequired: config.AdminAPIKey() != "",
+		},
+		Categories: categories,
+		RateLimits: []ManifestRateLimit{{
+			Scope:         "api",
+			Requests:      config.RateLimitRequests,
+			WindowSeconds: int(config.RateLimitWindow / time.Second),
+		}},
+		PromptStyles: h.prompts.Load().Styles(),
+		GeneratedAt:  now.UTC(),
+	}
+
+	h.manifestCache.manifest = manifest
+	h.manifestCache.expires = now.Add(manifestTTL)
+	return manifest, nil
+}
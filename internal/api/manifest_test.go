@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// manifestSchema is the contract of GET /api/manifest. Objects must have
+// exactly the listed keys, arrays hold elements of their one schema and
+// strings name the JSON type of a value.
+var manifestSchema = map[string]interface{}{
+	"name":         "string",
+	"base_url":     "string",
+	"version":      "string",
+	"api_versions": []interface{}{"string"},
+	"capabilities": map[string]interface{}{
+		"editing":            "boolean",
+		"generation":         "boolean",
+		"voting":             "boolean",
+		"admin_key_required": "boolean",
+	},
+	"categories": []interface{}{map[string]interface{}{
+		"category": "string",
+		"groups":   "number",
+	}},
+	"rate_limits": []interface{}{map[string]interface{}{
+		"scope":          "string",
+		"requests":       "number",
+		"window_seconds": "number",
+	}},
+	"prompt_styles": []interface{}{map[string]interface{}{
+		"name":        "string",
+		"description": "string",
+		"default":     "boolean",
+	}},
+	"generated_at": "string",
+}
+
+// checkSchema reports every place where value, decoded from JSON, does not
+// follow schema
+func checkSchema(t *testing.T, path string, value, schema interface{}) {
+	t.Helper()
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			t.Errorf("%s = %v, want an object", path, value)
+			return
+		}
+		for key, field := range schema {
+			v, ok := object[key]
+			if !ok {
+				t.Errorf("%s.%s is missing", path, key)
+				continue
+			}
+			checkSchema(t, path+"."+key, v, field)
+		}
+		var extra []string
+		for key := range object {
+			if _, ok := schema[key]; !ok {
+				extra = append(extra, key)
+			}
+		}
+		sort.Strings(extra)
+		if len(extra) > 0 {
+			t.Errorf("%s has fields outside the schema: %v", path, extra)
+		}
+	case []interface{}:
+		array, ok := value.([]interface{})
+		if !ok {
+			t.Errorf("%s = %v, want an array", path, value)
+			return
+		}
+		for i, v := range array {
+			checkSchema(t, path+"["+strconv.Itoa(i)+"]", v, schema[0])
+		}
+	case string:
+		var ok bool
+		switch schema {
+		case "string":
+			_, ok = value.(string)
+		case "number":
+			_, ok = value.(float64)
+		case "boolean":
+			_, ok = value.(bool)
+		}
+		if !ok {
+			t.Errorf("%s = %v, want a %s", path, value, schema)
+		}
+	}
+}
+
+func TestManifestHandlerFollowsSchema(t *testing.T) {
+	t.Setenv("BASE_URL", "")
+	h, db, _ := newTestHandler(t)
+	seedGroup(t, db, "Pelican", "birds")
+	seedGroup(t, db, "Heron", "birds")
+	seedGroup(t, db, "Bicycle", "")
+
+	rec := httptest.NewRecorder()
+	h.ManifestHandler(rec, httptest.NewRequest(http.MethodGet, "http://gallery.test/api/manifest", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var document interface{}
+	decodeJSON(t, rec, &document)
+	checkSchema(t, "manifest", document, manifestSchema)
+
+	var manifest Manifest
+	decodeJSON(t, rec, &manifest)
+	if manifest.BaseURL != "http://gallery.test" {
+		t.Errorf("base_url = %q, want the request's base URL", manifest.BaseURL)
+	}
+	if len(manifest.Categories) != 1 || manifest.Categories[0].Category != "birds" || manifest.Categories[0].Groups != 2 {
+		t.Errorf("categories = %+v, want birds with 2 groups", manifest.Categories)
+	}
+	if !manifest.Capabilities.Editing {
+		t.Error("capabilities.editing = false with editing enabled")
+	}
+	if len(manifest.PromptStyles) == 0 {
+		t.Error("prompt_styles is empty")
+	}
+
+	// The manifest is cached, so a new category only shows up after a minute
+	seedGroup(t, db, "Owl", "night")
+	rec = httptest.NewRecorder()
+	h.ManifestHandler(rec, httptest.NewRequest(http.MethodGet, "/api/manifest", nil))
+	var cached Manifest
+	decodeJSON(t, rec, &cached)
+	if !cached.GeneratedAt.Equal(manifest.GeneratedAt) || len(cached.Categories) != 1 {
+		t.Errorf("second manifest = %+v, want the cached one", cached)
+	}
+}
+
+func TestManifestHandlerEmptyDatabase(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.ManifestHandler(rec, httptest.NewRequest(http.MethodGet, "/api/manifest", nil))
+	var document interface{}
+	decodeJSON(t, rec, &document)
+	checkSchema(t, "manifest", document, manifestSchema)
+
+	rec = httptest.NewRecorder()
+	h.ManifestHandler(rec, httptest.NewRequest(http.MethodPost, "/api/manifest", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"pelican-gallery/internal/models"
+)
+
+// Bounds for the ?limit= of the model history
+const (
+	defaultModelHistoryLimit = 50
+	maxModelHistoryLimit     = 500
+)
+
+// ModelHistoryHandler handles GET /api/models/history. It lists, newest
+// first, the models that appeared on or disappeared from OpenRouter at each
+// recorded change of the model list. ?limit= bounds the number of changes
+// and ?model= keeps only the changes that involve one model, e.g. to find
+// out when an old group's model stopped being offered.
+func (h *Handler) ModelHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := defaultModelHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxModelHistoryLimit {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxModelHistoryLimit))
+			return
+		}
+		limit = parsed
+	}
+	model := r.URL.Query().Get("model")
+
+	// One extra snapshot gives the oldest listed change its predecessor
+	snapshots, err := h.db.ListModelSnapshots(limit + 1)
+	if err != nil {
+		log.Printf("Error listing model snapshots: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load model history")
+		return
+	}
+
+	changes := []models.ModelListChange{}
+	for i := 0; i < len(snapshots) && i < limit; i++ {
+		var previous []string
+		if i+1 < len(snapshots) {
+			previous = snapshots[i+1].ModelIDs
+		}
+		change := diffModelSnapshots(previous, snapshots[i])
+		change.Initial = i+1 == len(snapshots)
+
+		if model != "" {
+			change.Added = filterModelIDs(change.Added, model)
+			change.Removed = filterModelIDs(change.Removed, model)
+			if len(change.Added) == 0 && len(change.Removed) == 0 {
+				continue
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"changes": changes,
+	})
+}
+
+// diffModelSnapshots reports the models added and removed in snapshot
+// compared to the previous model IDs. Without a predecessor nothing is
+// reported as added; the snapshot is the baseline.
+func diffModelSnapshots(previous []string, snapshot models.ModelSnapshot) models.ModelListChange {
+	change := models.ModelListChange{
+		FetchedAt: snapshot.FetchedAt,
+		Total:     len(snapshot.ModelIDs),
+		Added:     []string{},
+		Removed:   []string{},
+	}
+	if previous == nil {
+		return change
+	}
+
+	before := make(map[string]bool, len(previous))
+	for _, id := range previous {
+		before[id] = true
+	}
+	after := make(map[string]bool, len(snapshot.ModelIDs))
+	for _, id := range snapshot.ModelIDs {
+		after[id] = true
+		if !before[id] {
+			change.Added = append(change.Added, id)
+		}
+	}
+	for _, id := range previous {
+		if !after[id] {
+			change.Removed = append(change.Removed, id)
+		}
+	}
+	return change
+}
+
+// filterModelIDs keeps only the given model
+func filterModelIDs(ids []string, model string) []string {
+	kept := []string{}
+	for _, id := range ids {
+		if id == model {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
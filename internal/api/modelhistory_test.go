@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestModelHistoryHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snapshots := [][]string{
+		{"openai/gpt-4o", "anthropic/claude-3.5-sonnet", "google/gemini-pro"},
+		// Unchanged lists are not recorded again
+		{"google/gemini-pro", "openai/gpt-4o", "anthropic/claude-3.5-sonnet"},
+		{"openai/gpt-4o", "anthropic/claude-sonnet-4", "google/gemini-pro", "x-ai/grok-4"},
+	}
+	for i, ids := range snapshots {
+		if err := db.RecordModelSnapshot(ids, first.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("RecordModelSnapshot: %v", err)
+		}
+	}
+
+	history := func(query string) []models.ModelListChange {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ModelHistoryHandler(rec, newRequest(http.MethodGet, "/api/models/history"+query, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d, body %s", query, rec.Code, rec.Body)
+		}
+		var response struct {
+			Changes []models.ModelListChange `json:"changes"`
+		}
+		decodeJSON(t, rec, &response)
+		return response.Changes
+	}
+
+	changes := history("")
+	if len(changes) != 2 {
+		t.Fatalf("%d changes, want 2: %+v", len(changes), changes)
+	}
+	latest, initial := changes[0], changes[1]
+	if !latest.FetchedAt.Equal(first.Add(2*time.Hour)) || latest.Total != 4 || latest.Initial {
+		t.Errorf("latest change = %+v", latest)
+	}
+	if want := []string{"anthropic/claude-sonnet-4", "x-ai/grok-4"}; !reflect.DeepEqual(latest.Added, want) {
+		t.Errorf("added = %v, want %v", latest.Added, want)
+	}
+	if want := []string{"anthropic/claude-3.5-sonnet"}; !reflect.DeepEqual(latest.Removed, want) {
+		t.Errorf("removed = %v, want %v", latest.Removed, want)
+	}
+	if !initial.Initial || len(initial.Added) != 0 || len(initial.Removed) != 0 || initial.Total != 3 {
+		t.Errorf("initial snapshot = %+v, want a baseline without changes", initial)
+	}
+
+	// ?model= keeps the changes involving one model
+	if changes := history("?model=anthropic/claude-3.5-sonnet"); len(changes) != 1 || !reflect.DeepEqual(changes[0].Removed, []string{"anthropic/claude-3.5-sonnet"}) {
+		t.Errorf("history of a removed model = %+v", changes)
+	}
+	if changes := history("?model=openai/gpt-4o"); len(changes) != 0 {
+		t.Errorf("history of an unchanged model = %+v, want none", changes)
+	}
+
+	// ?limit=1 still diffs the newest change against its predecessor
+	if changes := history("?limit=1"); len(changes) != 1 || changes[0].Initial || len(changes[0].Added) != 2 {
+		t.Errorf("limited history = %+v", changes)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ModelHistoryHandler(rec, newRequest(http.MethodGet, "/api/models/history?limit=0", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
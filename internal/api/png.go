@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/render"
+)
+
+// Bounds for the ?width= of rendered PNGs; widths outside are clamped
+const (
+	defaultPNGWidth = 512
+	minPNGWidth     = 64
+	maxPNGWidth     = 2048
+)
+
+// Cache lifetimes of rendered PNGs. A request whose ?v= matches the current
+// SVG version can be cached for good, since a new SVG gets a new URL.
+const (
+	pngMaxAge          = 5 * time.Minute
+	versionedPNGMaxAge = 365 * 24 * time.Hour
+)
+
+// maxCachedPNGs bounds the number of rendered PNGs kept in memory
+const maxCachedPNGs = 256
+
+// pngKey identifies one rendering of an artwork
+type pngKey struct {
+	artworkID int
+	width     int
+	version   string
+}
+
+// renderedPNG is a cached rendering
+type renderedPNG struct {
+	data       []byte
+	renderedAt time.Time
+}
+
+// pngCache holds rendered PNGs, evicting the oldest once full. Entries are
+// dropped whenever the artwork's SVG is saved.
+type pngCache struct {
+	mu      sync.Mutex
+	entries map[pngKey]renderedPNG
+	order   []pngKey
+}
+
+// get returns the cached rendering for key, if any
+func (c *pngCache) get(key pngKey) (renderedPNG, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// put stores a rendering, evicting the oldest entry when the cache is full
+func (c *pngCache) put(key pngKey, entry renderedPNG) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[pngKey]renderedPNG)
+	}
+	if _, exists := c.entries[key]; !exists {
+		for len(c.order) >= maxCachedPNGs {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = entry
+}
+
+// invalidate drops every rendering of an artwork
+func (c *pngCache) invalidate(artworkID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := c.order[:0]
+	for _, key := range c.order {
+		if key.artworkID == artworkID {
+			delete(c.entries, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	c.order = kept
+}
+
+// reset drops every rendering
+func (c *pngCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+	c.order = nil
+}
+
+// ArtworkPNGHandler handles GET /api/artworks/{id}/png?width=N and its public
+// alias GET /artworks/{id}.png. It rasterizes the artwork's SVG for clients
+// that cannot display SVG, such as social previews and feed readers.
+func (h *Handler) ArtworkPNGHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	artworkID, err := strconv.Atoi(artworkIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid artwork ID")
+		return
+	}
+
+	width := defaultPNGWidth
+	if raw := r.URL.Query().Get("width"); raw != "" {
+		width, err = strconv.Atoi(raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("width must be a number between %d and %d", minPNGWidth, maxPNGWidth))
+			return
+		}
+		width = min(max(width, minPNGWidth), maxPNGWidth)
+	}
+
+	artwork, err := h.db.GetArtwork(artworkID)
+	if err != nil || !artwork.Visibility.VisibleIn(viewScope(r)) {
+		writeJSONError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	if artwork.SVG == "" {
+		writeJSONError(w, http.StatusNotFound, "Artwork has no SVG yet")
+		return
+	}
+
+	version := render.Version(artwork.SVG)
+	key := pngKey{artworkID: artworkID, width: width, version: version}
+	entry, ok := h.pngCache.get(key)
+	if !ok {
+		data, err := render.PNG(artwork.SVG, width)
+		if err != nil {
+			log.Printf("Error rendering artwork %d as PNG: %v", artworkID, err)
+			writeJSONError(w, http.StatusUnprocessableEntity, "Artwork SVG could not be rendered", err.Error())
+			return
+		}
+		entry = renderedPNG{data: data, renderedAt: time.Now()}
+		h.pngCache.put(key, entry)
+	}
+
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(pngMaxAge.Seconds()))
+	if r.URL.Query().Get("v") == version {
+		cacheControl = fmt.Sprintf("public, max-age=%d, immutable", int(versionedPNGMaxAge.Seconds()))
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, version, width))
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, "", entry.renderedAt, bytes.NewReader(entry.data))
+}
+
+// ArtworkThumbnailHandler handles GET /api/artworks/{id}/thumbnail?width=N.
+// It serves the PNG thumbnail stored with the artwork, render.ThumbnailWidth
+// pixels wide unless ?width= asks otherwise. A missing thumbnail, or one of
+// another width, is rendered and stored in its place.
+func (h *Handler) ArtworkThumbnailHandler(w http.ResponseWriter, r *http.Request, artworkIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	artworkID, err := strconv.Atoi(artworkIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid artwork ID")
+		return
+	}
+
+	width := render.ThumbnailWidth
+	if raw := r.URL.Query().Get("width"); raw != "" {
+		width, err = strconv.Atoi(raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("width must be a number between %d and %d", minPNGWidth, maxPNGWidth))
+			return
+		}
+		width = min(max(width, minPNGWidth), maxPNGWidth)
+	}
+
+	artwork, err := h.db.GetArtwork(artworkID)
+	if err != nil || !artwork.Visibility.VisibleIn(viewScope(r)) {
+		writeJSONError(w, http.StatusNotFound, "Artwork not found")
+		return
+	}
+	if artwork.SVG == "" {
+		writeJSONError(w, http.StatusNotFound, "Artwork has no SVG yet")
+		return
+	}
+
+	thumbnail, storedWidth, err := h.db.GetArtworkThumbnail(artworkID)
+	if err != nil {
+		log.Printf("Error getting the thumbnail of artwork %d: %v", artworkID, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to get thumbnail")
+		return
+	}
+	if thumbnail == nil || storedWidth != width {
+		thumbnail, err = render.PNG(artwork.SVG, width)
+		if err != nil {
+			log.Printf("Error rendering the thumbnail of artwork %d: %v", artworkID, err)
+			writeJSONError(w, http.StatusUnprocessableEntity, "Artwork SVG could not be rendered", err.Error())
+			return
+		}
+		// The thumbnail is served even when it cannot be stored
+		if err := h.db.SaveArtworkThumbnail(artworkID, artwork.SVG, thumbnail, width); err != nil {
+			log.Printf("Error storing the thumbnail of artwork %d: %v", artworkID, err)
+		}
+	}
+
+	version := render.Version(artwork.SVG)
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(pngMaxAge.Seconds()))
+	if r.URL.Query().Get("v") == version {
+		cacheControl = fmt.Sprintf("public, max-age=%d, immutable", int(versionedPNGMaxAge.Seconds()))
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, version, width))
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, "", artwork.UpdatedAt, bytes.NewReader(thumbnail))
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"pelican-gallery/internal/render"
+)
+
+func TestArtworkPNGHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100"><rect width="200" height="100" fill="red"/></svg>`)
+	pending := seedArtwork(t, db, groupID, "google/gemini-2.5-pro", "")
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ArtworkPNGHandler(rec, newRequest(http.MethodGet, "/api/artworks/"+id+"/png"+query, ""), id)
+		return rec
+	}
+	id := strconv.Itoa(artworkID)
+
+	tests := []struct {
+		query                 string
+		wantWidth, wantHeight int
+	}{
+		{"", defaultPNGWidth, defaultPNGWidth / 2},
+		{"?width=300", 300, 150},
+		{"?width=1", minPNGWidth, minPNGWidth / 2},
+		{"?width=100000", maxPNGWidth, maxPNGWidth / 2},
+	}
+	for _, tt := range tests {
+		rec := get(id, tt.query)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("GET %s = %d %s, body %s", tt.query, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("GET %s is not a PNG: %v", tt.query, err)
+		}
+		if config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+			t.Errorf("GET %s is %dx%d, want %dx%d", tt.query, config.Width, config.Height, tt.wantWidth, tt.wantHeight)
+		}
+	}
+
+	// A new SVG is rendered instead of the cached PNG of the old one
+	if err := db.SaveArtworkSVG(artworkID, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect width="100" height="100"/></svg>`); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	rec := get(id, "?width=300")
+	if config, err := png.DecodeConfig(bytes.NewReader(rec.Body.Bytes())); err != nil || config.Height != 300 {
+		t.Errorf("PNG after saving a square SVG = %+v, %v, want 300x300", config, err)
+	}
+
+	errorTests := []struct {
+		id, query string
+		want      int
+	}{
+		{"abc", "", http.StatusBadRequest},
+		{id, "?width=wide", http.StatusBadRequest},
+		{"99999", "", http.StatusNotFound},
+		{strconv.Itoa(pending), "", http.StatusNotFound},
+	}
+	for _, tt := range errorTests {
+		if rec := get(tt.id, tt.query); rec.Code != tt.want {
+			t.Errorf("GET /api/artworks/%s/png%s = %d, want %d", tt.id, tt.query, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestArtworkThumbnailHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	const wide = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100"><rect width="200" height="100" fill="red"/></svg>`
+	// Artworks created with an SVG, like imported ones, start without a thumbnail
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", wide)
+	pending := seedArtwork(t, db, groupID, "google/gemini-2.5-pro", "")
+	broken := seedArtwork(t, db, groupID, "x-ai/grok-4", `<svg xmlns="http://www.w3.org/2000/svg"><rect/></svg>`)
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ArtworkThumbnailHandler(rec, newRequest(http.MethodGet, "/api/artworks/"+id+"/thumbnail"+query, ""), id)
+		return rec
+	}
+	id := strconv.Itoa(artworkID)
+
+	tests := []struct {
+		query     string
+		wantWidth int
+	}{
+		{"", render.ThumbnailWidth},
+		{"", render.ThumbnailWidth},
+		{"?width=100", 100},
+		{"?width=1", minPNGWidth},
+	}
+	for _, tt := range tests {
+		rec := get(id, tt.query)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("GET %s = %d %s, body %s", tt.query, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("GET %s is not a PNG: %v", tt.query, err)
+		}
+		if config.Width != tt.wantWidth || config.Height != tt.wantWidth/2 {
+			t.Errorf("GET %s is %dx%d, want %dx%d", tt.query, config.Width, config.Height, tt.wantWidth, tt.wantWidth/2)
+		}
+
+		// The thumbnail served is the one stored for next time
+		stored, width, err := db.GetArtworkThumbnail(artworkID)
+		if err != nil || width != tt.wantWidth || !bytes.Equal(stored, rec.Body.Bytes()) {
+			t.Errorf("after GET %s the stored thumbnail is %d wide (%d bytes), %v", tt.query, width, len(stored), err)
+		}
+	}
+
+	errorTests := []struct {
+		id, query string
+		want      int
+	}{
+		{"abc", "", http.StatusBadRequest},
+		{id, "?width=wide", http.StatusBadRequest},
+		{"99999", "", http.StatusNotFound},
+		{strconv.Itoa(pending), "", http.StatusNotFound},
+		{strconv.Itoa(broken), "", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range errorTests {
+		if rec := get(tt.id, tt.query); rec.Code != tt.want {
+			t.Errorf("GET /api/artworks/%s/thumbnail%s = %d, want %d", tt.id, tt.query, rec.Code, tt.want)
+		}
+	}
+	if stored, _, err := db.GetArtworkThumbnail(broken); err != nil || stored != nil {
+		t.Errorf("an SVG that cannot be rendered got a thumbnail: %d bytes, %v", len(stored), err)
+	}
+}
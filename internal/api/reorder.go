@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"pelican-gallery/internal/database"
+)
+
+// reorderRequest is the body of the reorder endpoints: every ID being
+// ordered, first to last
+type reorderRequest struct {
+	IDs []int `json:"ids"`
+}
+
+// decodeReorder reads a reorder body, answering 405, 403 or 400 when the
+// request cannot reorder anything
+func decodeReorder(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	if r.Method != http.MethodPut {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return nil, false
+	}
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return nil, false
+	}
+
+	var req reorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IDs == nil {
+		writeJSONError(w, http.StatusBadRequest, "Body must be {\"ids\": [...]} listing every ID in the new order")
+		return nil, false
+	}
+	return req.IDs, true
+}
+
+// writeReorderError answers a failed reorder: 400 with the offending IDs when
+// the order does not list the expected set, 500 otherwise
+func writeReorderError(w http.ResponseWriter, err error, what string) {
+	var mismatch *database.ReorderError
+	if errors.As(err, &mismatch) {
+		writeJSONError(w, http.StatusBadRequest, "ids must list every "+what+" exactly once", mismatch)
+		return
+	}
+	log.Printf("Error reordering %ss: %v", what, err)
+	writeDBError(w, err, http.StatusInternalServerError, "Failed to reorder "+what+"s")
+}
+
+// ReorderGroupsHandler handles PUT /api/groups/reorder. The body lists the
+// IDs of all groups outside the recycle bin, archived ones included, in the
+// order the gallery shows them under the manual sort.
+func (h *Handler) ReorderGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	ids, ok := decodeReorder(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.ReorderGroups(ids); err != nil {
+		writeReorderError(w, err, "group")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"ids":     ids,
+	})
+}
+
+// ReorderArtworksHandler handles PUT /api/groups/{id}/artworks/reorder. The
+// body lists the IDs of all the group's artworks outside the recycle bin, of
+// every visibility, in the order the group shows them.
+func (h *Handler) ReorderArtworksHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	ids, ok := decodeReorder(w, r)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+	if _, err := h.db.GetGroup(groupID); err != nil {
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	if err := h.db.ReorderArtworks(groupID, ids); err != nil {
+		writeReorderError(w, err, "artwork")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"group_id": groupID,
+		"ids":      ids,
+	})
+}
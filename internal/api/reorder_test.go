@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"pelican-gallery/internal/database"
+)
+
+func TestReorderGroupsHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	a := seedGroup(t, db, "A", "")
+	b := seedGroup(t, db, "B", "")
+
+	reorder := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ReorderGroupsHandler(rec, newRequest(method, "/api/groups/reorder", body))
+		return rec
+	}
+
+	if rec := reorder(http.MethodPut, fmt.Sprintf(`{"ids": [%d, %d]}`, b, a)); rec.Code != http.StatusOK {
+		t.Fatalf("reorder = %d, body %s", rec.Code, rec.Body)
+	}
+	groups, err := db.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+	if len(groups) != 2 || groups[0].ID != b || groups[1].ID != a {
+		t.Errorf("groups after reordering = %+v, want B then A", groups)
+	}
+
+	// A partial list is refused with the IDs left out
+	rec := reorder(http.MethodPut, fmt.Sprintf(`{"ids": [%d, 999]}`, b))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("partial reorder = %d, want 400: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Details database.ReorderError `json:"details"`
+	}
+	decodeJSON(t, rec, &body)
+	if want := (database.ReorderError{Missing: []int{a}, Unknown: []int{999}}); !reflect.DeepEqual(body.Details, want) {
+		t.Errorf("details = %+v, want %+v", body.Details, want)
+	}
+
+	tests := []struct {
+		name, method, body string
+		want               int
+	}{
+		{"GET", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"no ids", http.MethodPut, `{}`, http.StatusBadRequest},
+		{"not JSON", http.MethodPut, `ids`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := reorder(tt.method, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	if rec := reorder(http.MethodPut, fmt.Sprintf(`{"ids": [%d, %d]}`, a, b)); rec.Code != http.StatusForbidden {
+		t.Errorf("reorder with editing disabled = %d, want 403", rec.Code)
+	}
+}
+
+func TestReorderArtworksHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	x := seedArtwork(t, db, groupID, "x/model", testSVG)
+	y := seedArtwork(t, db, groupID, "y/model", testSVG)
+	foreign := seedArtwork(t, db, seedGroup(t, db, "Heron", ""), "x/model", testSVG)
+	group := strconv.Itoa(groupID)
+
+	reorder := func(groupIDStr, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ReorderArtworksHandler(rec, newRequest(http.MethodPut, "/api/groups/"+groupIDStr+"/artworks/reorder", body), groupIDStr)
+		return rec
+	}
+
+	if rec := reorder(group, fmt.Sprintf(`{"ids": [%d, %d]}`, y, x)); rec.Code != http.StatusOK {
+		t.Fatalf("reorder = %d, body %s", rec.Code, rec.Body)
+	}
+	artworks, err := db.ListArtworksByGroup(groupID)
+	if err != nil {
+		t.Fatalf("ListArtworksByGroup: %v", err)
+	}
+	if len(artworks) != 2 || artworks[0].ID != y || artworks[1].ID != x {
+		t.Errorf("artworks after reordering = %d, %d, want %d, %d", artworks[0].ID, artworks[1].ID, y, x)
+	}
+
+	tests := []struct {
+		name, group, body string
+		want              int
+	}{
+		{"foreign artwork", group, fmt.Sprintf(`{"ids": [%d, %d, %d]}`, y, x, foreign), http.StatusBadRequest},
+		{"duplicate", group, fmt.Sprintf(`{"ids": [%d, %d, %d]}`, y, x, x), http.StatusBadRequest},
+		{"unknown group", "999", `{"ids": []}`, http.StatusNotFound},
+		{"bad group ID", "x", `{"ids": []}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := reorder(tt.group, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if artworks, _ := db.ListArtworksByGroup(groupID); artworks[0].ID != y {
+		t.Errorf("a refused reorder changed the order")
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/models"
+)
+
+// ArtworkRevisionsHandler dispatches /api/artworks/{id}/revisions/...:
+//
+//	GET  /api/artworks/{id}/revisions                    the revisions, newest first
+//	GET  /api/artworks/{id}/revisions/{rev}              one revision with its SVG
+//	POST /api/artworks/{id}/revisions/{rev}/restore      put the revision back
+func (h *Handler) ArtworkRevisionsHandler(w http.ResponseWriter, r *http.Request, artworkIDStr, revisionIDStr, action string) {
+	artworkID, err := strconv.Atoi(artworkIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid artwork ID")
+		return
+	}
+
+	switch {
+	case revisionIDStr == "" && action == "":
+		h.listArtworkRevisions(w, r, artworkID)
+	case action == "":
+		if revisionID, ok := parseRevisionID(w, revisionIDStr); ok {
+			h.getArtworkRevision(w, r, artworkID, revisionID)
+		}
+	case action == "restore":
+		if revisionID, ok := parseRevisionID(w, revisionIDStr); ok {
+			h.restoreArtworkRevision(w, r, artworkID, revisionID)
+		}
+	default:
+		writeJSONError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// parseRevisionID parses a revision ID from the path, answering 400 when it
+// is not a number
+func parseRevisionID(w http.ResponseWriter, revisionIDStr string) (int, bool) {
+	revisionID, err := strconv.Atoi(revisionIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid revision ID")
+		return 0, false
+	}
+	return revisionID, true
+}
+
+// revisableArtwork looks up an artwork whose revisions may be shown, answering
+// 404 when it does not exist or is not visible
+func (h *Handler) revisableArtwork(w http.ResponseWriter, r *http.Request, artworkID int) (*models.Artwork, bool) {
+	artwork, err := h.db.GetArtwork(artworkID)
+	if err != nil || !artwork.Visibility.VisibleIn(viewScope(r)) {
+		writeJSONError(w, http.StatusNotFound, "Artwork not found")
+		return nil, false
+	}
+	return artwork, true
+}
+
+func (h *Handler) listArtworkRevisions(w http.ResponseWriter, r *http.Request, artworkID int) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if _, ok := h.revisableArtwork(w, r, artworkID); !ok {
+		return
+	}
+
+	revisions, err := h.db.ListArtworkRevisions(artworkID)
+	if err != nil {
+		log.Printf("Error listing revisions of artwork %d: %v", artworkID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to list revisions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+func (h *Handler) getArtworkRevision(w http.ResponseWriter, r *http.Request, artworkID, revisionID int) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if _, ok := h.revisableArtwork(w, r, artworkID); !ok {
+		return
+	}
+
+	revision, err := h.db.GetArtworkRevision(artworkID, revisionID)
+	if errors.Is(err, database.ErrRevisionNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Revision not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching revision %d of artwork %d: %v", revisionID, artworkID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch revision")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revision)
+}
+
+func (h *Handler) restoreArtworkRevision(w http.ResponseWriter, r *http.Request, artworkID, revisionID int) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	if err := h.db.RestoreArtworkRevision(artworkID, revisionID); err != nil {
+		log.Printf("Error restoring revision %d of artwork %d: %v", revisionID, artworkID, err)
+		if errors.Is(err, database.ErrRevisionNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Revision not found")
+			return
+		}
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to restore revision")
+		return
+	}
+
+	artwork, err := h.db.GetArtwork(artworkID)
+	if err != nil {
+		log.Printf("Error fetching restored artwork %d: %v", artworkID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to fetch restored artwork")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, artwork)
+}
+
+// ModelRevisionsHandler handles GET /api/groups/{id}/revisions?model=...: the
+// visible artworks of a model in the group, by revision, to compare the
+// generations kept with keep_history
+func (h *Handler) ModelRevisionsHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		writeJSONError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	if _, err := h.db.GetGroup(groupID); err != nil {
+		writeJSONError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	artworks, err := h.db.ListModelRevisions(groupID, model)
+	if err != nil {
+		log.Printf("Error listing revisions of %s in group %d: %v", model, groupID, err)
+		writeDBError(w, err, http.StatusInternalServerError, "Failed to list revisions")
+		return
+	}
+
+	artworks = models.FilterVisible(artworks, viewScope(r))
+	if artworks == nil {
+		artworks = []models.Artwork{}
+	}
+	writeJSON(w, http.StatusOK, artworks)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+)
+
+func TestArtworkRevisionsHandler(t *testing.T) {
+	h, db, gen := newTestHandler(t)
+	db.KeepRevisions(3)
+	drawing := func(n int) string {
+		return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><title>drawing %d</title></svg>`, n)
+	}
+	drawings := 0
+	gen.generate = func(req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+		drawings++
+		svg := drawing(drawings)
+		return openrouter.GenerationResult{SVG: svg, Content: svg, FinishReason: "stop", Model: req.Model}, nil
+	}
+
+	groupID := seedGroup(t, db, "Pelican", "")
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", drawing(0))
+	idStr := strconv.Itoa(artworkID)
+	revisions := func(revisionIDStr, action, method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ArtworkRevisionsHandler(rec, newRequest(method, "/api/artworks/"+idStr+"/revisions", ""), idStr, revisionIDStr, action)
+		return rec
+	}
+	// listSVGs lists the revisions newest first and fetches the SVG of each
+	listSVGs := func() ([]models.ArtworkRevision, []string) {
+		t.Helper()
+		rec := revisions("", "", http.MethodGet)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list status = %d, body %s", rec.Code, rec.Body)
+		}
+		var list []models.ArtworkRevision
+		decodeJSON(t, rec, &list)
+		var svgs []string
+		for i, revision := range list {
+			if i > 0 && revision.ID >= list[i-1].ID {
+				t.Errorf("revisions are not listed newest first: %d after %d", revision.ID, list[i-1].ID)
+			}
+			rec := revisions(strconv.Itoa(revision.ID), "", http.MethodGet)
+			var full models.ArtworkRevision
+			decodeJSON(t, rec, &full)
+			if full.Size != len(full.SVG) || revision.Size != full.Size {
+				t.Errorf("revision %d has size %d listed and %d fetched for %d bytes", revision.ID, revision.Size, full.Size, len(full.SVG))
+			}
+			svgs = append(svgs, full.SVG)
+		}
+		return list, svgs
+	}
+
+	// Generate three times; each replaced SVG becomes a revision
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		h.GenerateArtworkHandler(rec, newRequest(http.MethodPost, "/api/generate", `{"artwork_id": `+idStr+`}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("generate %d status = %d, body %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	list, svgs := listSVGs()
+	if want := []string{drawing(2), drawing(1), drawing(0)}; fmt.Sprint(svgs) != fmt.Sprint(want) {
+		t.Fatalf("revisions after three generations = %v, want %v", svgs, want)
+	}
+
+	// Restoring the first keeps the current SVG as a revision and prunes the
+	// oldest beyond the limit of three
+	first := list[len(list)-1]
+	rec := revisions(strconv.Itoa(first.ID), "restore", http.MethodPost)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore status = %d, body %s", rec.Code, rec.Body)
+	}
+	var artwork models.Artwork
+	decodeJSON(t, rec, &artwork)
+	if artwork.SVG != drawing(0) {
+		t.Errorf("restored artwork has %q, want the first SVG", artwork.SVG)
+	}
+	if _, svgs = listSVGs(); fmt.Sprint(svgs) != fmt.Sprint([]string{drawing(3), drawing(2), drawing(1)}) {
+		t.Errorf("revisions after restoring = %v, want drawings 3, 2 and 1", svgs)
+	}
+
+	tests := []struct {
+		name, revision, action, method string
+		want                           int
+	}{
+		{"pruned revision", strconv.Itoa(first.ID), "", http.MethodGet, http.StatusNotFound},
+		{"restore pruned revision", strconv.Itoa(first.ID), "restore", http.MethodPost, http.StatusNotFound},
+		{"bad revision ID", "x", "", http.MethodGet, http.StatusBadRequest},
+		{"unknown action", strconv.Itoa(first.ID), "undo", http.MethodPost, http.StatusNotFound},
+		{"restore with GET", strconv.Itoa(list[0].ID), "restore", http.MethodGet, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if rec := revisions(tt.revision, tt.action, tt.method); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestGenerateArtworkKeepHistory(t *testing.T) {
+	h, db, gen := newTestHandler(t)
+	drawings := 0
+	gen.generate = func(req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+		drawings++
+		svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><title>drawing %d</title></svg>`, drawings)
+		return openrouter.GenerationResult{SVG: svg, Content: svg, FinishReason: "stop", Model: req.Model}, nil
+	}
+
+	groupID := seedGroup(t, db, "Pelican", "")
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	generate := func(keepHistory bool) (id, revision int, svg string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GenerateArtworkHandler(rec, newRequest(http.MethodPost, "/api/generate", fmt.Sprintf(`{"artwork_id": %d, "keep_history": %t}`, artworkID, keepHistory)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("generate status = %d, body %s", rec.Code, rec.Body)
+		}
+		var body struct {
+			ID       int    `json:"id"`
+			Revision int    `json:"revision"`
+			SVG      string `json:"svg"`
+		}
+		decodeJSON(t, rec, &body)
+		return body.ID, body.Revision, body.SVG
+	}
+
+	// By default the SVG is replaced in place
+	id, revision, svg := generate(false)
+	if id != artworkID || revision != 1 {
+		t.Errorf("plain generate = artwork %d revision %d, want %d revision 1", id, revision, artworkID)
+	}
+	if artwork, err := db.GetArtwork(artworkID); err != nil || artwork.SVG != svg {
+		t.Fatalf("artwork after a plain generate = %+v, %v", artwork, err)
+	}
+
+	// keep_history generates into new artworks and leaves the first alone
+	var kept []int
+	for want := 2; want <= 3; want++ {
+		id, revision, newSVG := generate(true)
+		if id == artworkID || revision != want {
+			t.Errorf("keep_history generate = artwork %d revision %d, want a new artwork with revision %d", id, revision, want)
+		}
+		if artwork, err := db.GetArtwork(id); err != nil || artwork.SVG != newSVG || artwork.Model != "openai/gpt-4o" {
+			t.Errorf("new revision = %+v, %v", artwork, err)
+		}
+		kept = append(kept, id)
+	}
+	if artwork, err := db.GetArtwork(artworkID); err != nil || artwork.SVG != svg {
+		t.Errorf("first artwork after keep_history = %+v, %v, want its SVG unchanged", artwork, err)
+	}
+
+	group := strconv.Itoa(groupID)
+	list := func(target, groupIDStr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ModelRevisionsHandler(rec, newRequest(http.MethodGet, target, ""), groupIDStr)
+		return rec
+	}
+	rec := list("/api/groups/"+group+"/revisions?model=openai/gpt-4o", group)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revisions status = %d, body %s", rec.Code, rec.Body)
+	}
+	var artworks []models.Artwork
+	decodeJSON(t, rec, &artworks)
+	var got []int
+	for _, artwork := range artworks {
+		got = append(got, artwork.ID)
+	}
+	if want := append([]int{artworkID}, kept...); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("revisions = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name, target, group string
+		want                int
+	}{
+		{"missing model", "/api/groups/" + group + "/revisions", group, http.StatusBadRequest},
+		{"unknown group", "/api/groups/999/revisions?model=openai/gpt-4o", "999", http.StatusNotFound},
+		{"bad group ID", "/api/groups/x/revisions?model=openai/gpt-4o", "x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := list(tt.target, tt.group); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"pelican-gallery/internal/sanitize"
+)
+
+// resanitizeBatchSize is how many artworks are loaded per batch
+const resanitizeBatchSize = 100
+
+// ResanitizeAllHandler handles POST /api/svg/resanitize-all. It walks every
+// stored artwork in batches, re-applies the current SVG sanitizer and saves
+// the artworks whose markup changed.
+func (h *Handler) ResanitizeAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !isEditingEnabled() {
+		writeJSONError(w, http.StatusForbidden, "Artwork editing is currently disabled")
+		return
+	}
+
+	scanned, batches := 0, 0
+	modified := []int{}
+	lastID := 0
+
+	for {
+		artworks, err := h.db.ListArtworksAfter(lastID, resanitizeBatchSize)
+		if err != nil {
+			log.Printf("Error loading artworks after id %d: %v", lastID, err)
+			writeJSONError(w, http.StatusInternalServerError, "Failed to load artworks")
+			return
+		}
+		if len(artworks) == 0 {
+			break
+		}
+		batches++
+
+		for _, artwork := range artworks {
+			lastID = artwork.ID
+			scanned++
+
+			if artwork.SVG == "" {
+				continue
+			}
+
+			cleaned := sanitize.SVG(artwork.SVG)
+			if cleaned == artwork.SVG {
+				continue
+			}
+
+			if err := h.db.SaveArtworkSVG(artwork.ID, cleaned); err != nil {
+				log.Printf("Error saving re-sanitized SVG (artwork=%d): %v", artwork.ID, err)
+				writeDBError(w, err, http.StatusInternalServerError, "Failed to save sanitized SVG")
+				return
+			}
+			modified = append(modified, artwork.ID)
+		}
+	}
+
+	log.Printf("Re-sanitized %d artwork(s) in %d batch(es): %d modified", scanned, batches, len(modified))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scanned":      scanned,
+		"modified":     len(modified),
+		"modified_ids": modified,
+		"batches":      batches,
+	})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResanitizeAllHandler(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+
+	malicious := `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><rect onclick="alert(2)" width="5"/></svg>`
+	maliciousID := seedArtwork(t, db, groupID, "openai/gpt-4o", malicious)
+	cleanID := seedArtwork(t, db, groupID, "anthropic/claude-3.5-sonnet", testSVG)
+	seedArtwork(t, db, groupID, "google/gemini-pro", "")
+
+	before, err := db.GetArtwork(cleanID)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ResanitizeAllHandler(rec, newRequest(http.MethodPost, "/api/svg/resanitize-all", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var response struct {
+		Scanned     int   `json:"scanned"`
+		Modified    int   `json:"modified"`
+		ModifiedIDs []int `json:"modified_ids"`
+	}
+	decodeJSON(t, rec, &response)
+	if response.Scanned != 3 || response.Modified != 1 || len(response.ModifiedIDs) != 1 || response.ModifiedIDs[0] != maliciousID {
+		t.Errorf("response = %+v, want 3 scanned and artwork %d modified", response, maliciousID)
+	}
+
+	cleaned, err := db.GetArtwork(maliciousID)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	if want := `<svg xmlns="http://www.w3.org/2000/svg"><rect width="5"/></svg>`; cleaned.SVG != want {
+		t.Errorf("malicious SVG is now %q, want %q", cleaned.SVG, want)
+	}
+
+	after, err := db.GetArtwork(cleanID)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	if after.SVG != testSVG || !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("clean artwork changed: %q updated %v, was %v", after.SVG, after.UpdatedAt, before.UpdatedAt)
+	}
+}
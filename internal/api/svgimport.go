@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// maxImportedSVGBytes bounds the decoded size of an imported SVG
+const maxImportedSVGBytes = 2 << 20
+
+// decodeImportedSVG accepts raw SVG markup or a data:image/svg+xml URI (base64
+// or percent-encoded) and returns the markup once it parses as an SVG document
+func decodeImportedSVG(input string) (string, error) {
+	markup := strings.TrimSpace(input)
+
+	if rest, ok := strings.CutPrefix(markup, "data:"); ok {
+		meta, data, found := strings.Cut(rest, ",")
+		if !found {
+			return "", errors.New("malformed data URI")
+		}
+
+		params := strings.Split(meta, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), "image/svg+xml") {
+			return "", fmt.Errorf("unsupported data URI type %q: must be image/svg+xml", params[0])
+		}
+
+		isBase64 := strings.EqualFold(params[len(params)-1], "base64")
+		if isBase64 {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+			if err != nil {
+				return "", fmt.Errorf("invalid base64 in data URI: %w", err)
+			}
+			markup = string(decoded)
+		} else {
+			decoded, err := url.PathUnescape(data)
+			if err != nil {
+				return "", fmt.Errorf("invalid percent-encoding in data URI: %w", err)
+			}
+			markup = decoded
+		}
+		markup = strings.TrimSpace(markup)
+	}
+
+	if len(markup) > maxImportedSVGBytes {
+		return "", fmt.Errorf("SVG is larger than %d bytes", maxImportedSVGBytes)
+	}
+
+	if err := validateSVGDocument(markup); err != nil {
+		return "", err
+	}
+
+	return markup, nil
+}
+
+// validateSVGDocument checks that markup is well-formed XML with an <svg> root
+func validateSVGDocument(markup string) error {
+	decoder := xml.NewDecoder(strings.NewReader(markup))
+
+	root := ""
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("SVG is not well-formed: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && root == "" {
+			root = start.Name.Local
+		}
+	}
+
+	if root != "svg" {
+		return errors.New("SVG markup must have an <svg> root element")
+	}
+	return nil
+}
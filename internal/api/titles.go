@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+)
+
+// Auto-title modes accepted by CreateGroupHandler
+const (
+	autoTitlePrompt = "prompt" // first sentence of the prompt
+	autoTitleModel  = "model"  // summarized by a model, falling back to the prompt
+)
+
+const (
+	// maxTitleLength bounds generated titles, in characters
+	maxTitleLength = 60
+	// defaultTitleModel summarizes prompts when TITLE_MODEL is not set
+	defaultTitleModel = "openai/gpt-4o-mini"
+	// titleTimeout bounds how long group creation waits for a model title
+	titleTimeout = 20 * time.Second
+)
+
+// titlePromptConfig asks a model to summarize an artwork prompt into a title
+var titlePromptConfig = &models.PromptConfig{
+	Name: "Title",
+	SystemPrompts: []models.SystemPrompt{{
+		Role:    "system",
+		Content: "You write short titles for artworks. Reply with the title only: at most six words, no quotes, no trailing punctuation.",
+	}},
+	UserPromptTemplate: "Write a title for this artwork description: {art_work_description}",
+}
+
+// autoTitle derives a group title from its prompt. Mode "model" asks a model
+// for a summary and falls back to the prompt-derived title on any failure.
+// Dry runs never ask the model, as it would only answer with a placeholder.
+func (h *Handler) autoTitle(ctx context.Context, prompt, mode string) string {
+	if mode == autoTitleModel && !openrouter.IsDryRun(ctx) {
+		if title, err := h.summarizeTitle(ctx, prompt); err != nil {
+			log.Printf("Auto-title: model summary failed, using prompt: %v", err)
+		} else if title = cleanTitle(title); validTitle(title) {
+			return title
+		} else {
+			log.Printf("Auto-title: discarding model title %q", title)
+		}
+	}
+	return titleFromPrompt(prompt)
+}
+
+// summarizeTitle asks the title model for a title, giving up after titleTimeout
+// or when ctx is cancelled
+func (h *Handler) summarizeTitle(ctx context.Context, prompt string) (string, error) {
+	model := os.Getenv("TITLE_MODEL")
+	if model == "" {
+		model = defaultTitleModel
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+
+	result, err := h.generator.GenerateSVG(ctx, openrouter.GenerationRequest{
+		PromptConfig:    titlePromptConfig,
+		Prompt:          prompt,
+		Model:           model,
+		Temperature:     0.3,
+		MaxTokens:       50,
+		ReasoningEffort: models.ReasoningEffortOff,
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", errTitleTimeout
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.SVG, nil
+}
+
+// errTitleTimeout is returned when the title model does not answer in time
+var errTitleTimeout = errors.New("title model timed out")
+
+// titleFromPrompt returns the first sentence of prompt, cleaned and truncated
+// to maxTitleLength at a word boundary
+func titleFromPrompt(prompt string) string {
+	title := strings.TrimSpace(prompt)
+	if i := strings.IndexAny(title, ".!?\n"); i > 0 {
+		title = title[:i]
+	}
+	title = cleanTitle(title)
+
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength])
+		if i := strings.LastIndex(title, " "); i > maxTitleLength/2 {
+			title = title[:i]
+		}
+		title = strings.TrimRightFunc(title, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsPunct(r)
+		}) + "…"
+	}
+
+	if title == "" {
+		return "Untitled"
+	}
+	return title
+}
+
+// cleanTitle collapses whitespace and strips surrounding quotes and punctuation
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	title = strings.Trim(title, "\"'`*“”‘’ ")
+	title = strings.TrimRight(title, ".,;:")
+	if title != "" {
+		runes := []rune(title)
+		runes[0] = unicode.ToUpper(runes[0])
+		title = string(runes)
+	}
+	return title
+}
+
+// validTitle reports whether a generated title is non-empty, single-line and
+// within maxTitleLength
+func validTitle(title string) bool {
+	n := len([]rune(title))
+	return n > 0 && n <= maxTitleLength && !strings.ContainsAny(title, "\r\n<>")
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// voteRequest is the body of POST /api/votes
+type voteRequest struct {
+	WinnerArtworkID int `json:"winner_artwork_id"`
+	LoserArtworkID  int `json:"loser_artwork_id"`
+}
+
+// voteResult reveals the models behind a vote once it is cast
+type voteResult struct {
+	Recorded    bool   `json:"recorded"`
+	WinnerModel string `json:"winner_model"`
+	LoserModel  string `json:"loser_model"`
+}
+
+// voterFingerprint identifies a voter by a hash of their IP and user agent,
+// so votes can be deduplicated without storing either. X-Forwarded-For is
+// only believed from trusted proxies, so changing it does not make a new
+// voter.
+func (h *Handler) voterFingerprint(r *http.Request) string {
+	ip := r.RemoteAddr
+	if addr, ok := config.TrustedClientIP(r, h.trustedProxies); ok {
+		ip = addr.String()
+	}
+	sum := sha256.Sum256([]byte(ip + "\x00" + r.UserAgent()))
+	return hex.EncodeToString(sum[:])
+}
+
+// votable reports whether an artwork may take part in a vote: public and
+// with an SVG to show
+func votable(artwork *models.Artwork) bool {
+	return artwork.Visibility == models.VisibilityPublic && artwork.SVG != ""
+}
+
+// VoteHandler handles POST /api/votes, recording which of two renditions of
+// the same group a visitor preferred. The response reveals both models. A
+// second vote by the same visitor on the same pair is rejected with 409, and
+// still reveals the models.
+func (h *Handler) VoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.db.IsReadOnly() {
+		writeJSONError(w, http.StatusServiceUnavailable, "Voting is closed while the gallery is read-only")
+		return
+	}
+
+	var req voteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.WinnerArtworkID <= 0 || req.LoserArtworkID <= 0 || req.WinnerArtworkID == req.LoserArtworkID {
+		writeJSONError(w, http.StatusBadRequest, "winner_artwork_id and loser_artwork_id must be two different artworks")
+		return
+	}
+
+	winner, err := h.db.GetArtwork(req.WinnerArtworkID)
+	if err != nil || !votable(winner) {
+		writeJSONError(w, http.StatusNotFound, "Winning artwork not found")
+		return
+	}
+	loser, err := h.db.GetArtwork(req.LoserArtworkID)
+	if err != nil || !votable(loser) {
+		writeJSONError(w, http.StatusNotFound, "Losing artwork not found")
+		return
+	}
+	if winner.GroupID != loser.GroupID {
+		writeJSONError(w, http.StatusBadRequest, "Both artworks must belong to the same group")
+		return
+	}
+	if winner.Model == loser.Model {
+		writeJSONError(w, http.StatusBadRequest, "Both artworks are by the same model")
+		return
+	}
+
+	recorded, err := h.db.RecordVote(models.Vote{
+		GroupID:         winner.GroupID,
+		WinnerArtworkID: winner.ID,
+		LoserArtworkID:  loser.ID,
+		WinnerModel:     winner.Model,
+		LoserModel:      loser.Model,
+		Fingerprint:     h.voterFingerprint(r),
+	})
+	if err != nil {
+		log.Printf("Error recording vote %d > %d: %v", winner.ID, loser.ID, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to record vote")
+		return
+	}
+
+	status := http.StatusCreated
+	if !recorded {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, voteResult{
+		Recorded:    recorded,
+		WinnerModel: winner.Model,
+		LoserModel:  loser.Model,
+	})
+}
+
+// LeaderboardHandler handles GET /api/leaderboard, the ELO rating of every
+// model that has been voted on, computed from the full vote history.
+// ?category= limits the votes to groups in that category.
+func (h *Handler) LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	category := strings.TrimSpace(r.URL.Query().Get("category"))
+
+	var votes []models.Vote
+	var err error
+	if category != "" {
+		votes, err = h.db.ListCategoryVotes(category)
+	} else {
+		votes, err = h.db.ListVotes()
+	}
+	if err != nil {
+		log.Printf("Error listing votes: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load votes")
+		return
+	}
+
+	response := map[string]interface{}{
+		"ratings":        models.EloRatings(votes),
+		"votes":          len(votes),
+		"initial_rating": models.EloInitialRating,
+		"k":              models.EloK,
+	}
+	if category != "" {
+		response["category"] = category
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/models"
+)
+
+// seedVotes records votes, each by a different voter, on a new group in
+// category where winner beat loser
+func seedVotes(t *testing.T, db *database.DB, category, winner, loser string, votes int) {
+	t.Helper()
+	groupID := seedGroup(t, db, category+" group", category)
+	winnerID := seedArtwork(t, db, groupID, winner, testSVG)
+	loserID := seedArtwork(t, db, groupID, loser, testSVG)
+	for i := 0; i < votes; i++ {
+		recorded, err := db.RecordVote(models.Vote{
+			GroupID:         groupID,
+			WinnerArtworkID: winnerID,
+			LoserArtworkID:  loserID,
+			WinnerModel:     winner,
+			LoserModel:      loser,
+			Fingerprint:     category + strconv.Itoa(i),
+		})
+		if err != nil || !recorded {
+			t.Fatalf("RecordVote = %v, %v", recorded, err)
+		}
+	}
+}
+
+func TestLeaderboardHandlerByCategory(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	seedVotes(t, db, "portraits", "a/model", "b/model", 3)
+	seedVotes(t, db, "landscapes", "b/model", "a/model", 2)
+	seedVotes(t, db, "landscapes", "c/model", "a/model", 1)
+
+	leaderboard := func(query string) (ratings []models.ModelRating, votes int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.LeaderboardHandler(rec, newRequest(http.MethodGet, "/api/leaderboard"+query, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d, body %s", query, rec.Code, rec.Body)
+		}
+		var response struct {
+			Ratings []models.ModelRating `json:"ratings"`
+			Votes   int                  `json:"votes"`
+		}
+		decodeJSON(t, rec, &response)
+		return response.Ratings, response.Votes
+	}
+	ranking := func(ratings []models.ModelRating) []string {
+		ranked := []string{}
+		for _, r := range ratings {
+			ranked = append(ranked, r.Model+" "+strconv.Itoa(r.Wins)+"-"+strconv.Itoa(r.Losses))
+		}
+		return ranked
+	}
+
+	tests := []struct {
+		query     string
+		wantVotes int
+		want      []string
+	}{
+		{"?category=portraits", 3, []string{"a/model 3-0", "b/model 0-3"}},
+		{"?category=landscapes", 3, []string{"b/model 2-0", "c/model 1-0", "a/model 0-3"}},
+		{"?category=unknown", 0, []string{}},
+	}
+	for _, tt := range tests {
+		ratings, votes := leaderboard(tt.query)
+		if got := ranking(ratings); votes != tt.wantVotes || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %d votes ranked %v, want %d ranked %v", tt.query, votes, got, tt.wantVotes, tt.want)
+		}
+	}
+
+	// Without a category every vote counts
+	ratings, votes := leaderboard("")
+	if votes != 6 || len(ratings) != 3 {
+		t.Errorf("overall: %d votes ranked %v, want 6 votes on 3 models", votes, ranking(ratings))
+	}
+}
+
+func TestVoteHandlerIgnoresSpoofedForwardedFor(t *testing.T) {
+	h, db, _ := newTestHandler(t)
+	h.SetTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	groupID := seedGroup(t, db, "Pelican", "")
+	winner := seedArtwork(t, db, groupID, "a/model", testSVG)
+	loser := seedArtwork(t, db, groupID, "b/model", testSVG)
+
+	vote := func(remoteAddr, forwardedFor string) int {
+		t.Helper()
+		r := newRequest(http.MethodPost, "/api/votes", `{"winner_artwork_id": `+strconv.Itoa(winner)+`, "loser_artwork_id": `+strconv.Itoa(loser)+`}`)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		h.VoteHandler(rec, r)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name, remoteAddr, forwardedFor string
+		want                           int
+	}{
+		{"direct vote", "203.0.113.5:1234", "198.51.100.1", http.StatusCreated},
+		{"same client, spoofed header", "203.0.113.5:1234", "198.51.100.2", http.StatusConflict},
+		{"same client, no header", "203.0.113.5:4321", "", http.StatusConflict},
+		{"client behind the proxy", "10.0.0.1:80", "198.51.100.1", http.StatusCreated},
+		{"another client behind the proxy", "10.0.0.1:80", "198.51.100.2", http.StatusCreated},
+		{"client behind the proxy, spoofed entry", "10.0.0.1:80", "192.0.2.9, 198.51.100.1", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if got := vote(tt.remoteAddr, tt.forwardedFor); got != tt.want {
+			t.Errorf("%s: vote = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
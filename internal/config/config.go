@@ -1,15 +1,18 @@
 package config
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"pelican-gallery/internal/models"
@@ -17,22 +20,84 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-var (
-	modelsCache []models.ModelInfo
-	cacheExpiry time.Time
-	modelsMu    sync.RWMutex
+// Version identifies the running build. Release builds set it with
+// -ldflags "-X pelican-gallery/internal/config.Version=...".
+var Version = "dev"
+
+// Per-client rate limit applied to API routes
+const (
+	RateLimitWindow   = time.Minute
+	RateLimitRequests = 100
+)
+
+// RateLimitWarningHeader is set on responses to clients that have used most
+// of their rate limit allowance
+const RateLimitWarningHeader = "X-RateLimit-Warning"
+
+// RequestIDHeader carries the ID of a request: taken from the client when it
+// sends one, and set on every response
+const RequestIDHeader = "X-Request-ID"
+
+// defaultRateLimitWarnFraction is the share of the allowance after which
+// clients are warned, unless RATE_LIMIT_WARN_FRACTION says otherwise
+const defaultRateLimitWarnFraction = 0.8
+
+// Number of groups in the Atom feed, unless FEED_SIZE or ?limit= says
+// otherwise; neither can go beyond MaxFeedSize
+const (
+	defaultFeedSize = 20
+	MaxFeedSize     = 100
 )
 
+// defaultArtworkRevisions is the number of replaced SVGs kept per artwork,
+// unless ARTWORK_REVISIONS says otherwise
+const defaultArtworkRevisions = 20
+
+// defaultGalleryBatchSize is the number of gallery groups a worker assembles
+// at a time, unless GALLERY_BATCH_SIZE says otherwise
+const defaultGalleryBatchSize = 100
+
+// defaultGalleryCacheTTL is how long a rendered gallery page is reused,
+// unless GALLERY_CACHE_TTL says otherwise
+const defaultGalleryCacheTTL = time.Minute
+
+// defaultMaxConcurrentGenerations is the number of OpenRouter generations
+// run at once, unless MAX_CONCURRENT_GENERATIONS says otherwise
+const defaultMaxConcurrentGenerations = 8
+
+// defaultGenerationQueueTimeout is how long a generation waits for a free
+// slot, unless GENERATION_QUEUE_TIMEOUT says otherwise
+const defaultGenerationQueueTimeout = 30 * time.Second
+
+// defaultBackupKeep is the number of scheduled snapshots kept, unless
+// BACKUP_KEEP says otherwise
+const defaultBackupKeep = 7
+
+// RestoreConfirmation is the value the confirm parameter of a database
+// restore must have, so the database is never replaced by accident
+const RestoreConfirmation = "replace-database"
+
 type openRouterResponse struct {
 	Data []openRouterModel `json:"data"`
 }
 
 type openRouterModel struct {
-	ID      string                 `json:"id"`
-	Name    string                 `json:"name"`
-	Pricing map[string]interface{} `json:"pricing"`
+	ID            string                 `json:"id"`
+	Name          string                 `json:"name"`
+	ContextLength int                    `json:"context_length"`
+	Pricing       map[string]interface{} `json:"pricing"`
+	Architecture  struct {
+		InputModalities []string `json:"input_modalities"`
+	} `json:"architecture"`
 }
 
+// DefaultOpenRouterBaseURL is the public OpenRouter API root
+const DefaultOpenRouterBaseURL = "https://openrouter.ai/api/v1"
+
+// defaultModelsCacheTTL is how long the OpenRouter model list is reused
+// unless MODELS_CACHE_TTL says otherwise
+const defaultModelsCacheTTL = 5 * time.Minute
+
 // LoadPromptConfig loads the prompt configuration from the YAML file
 func LoadPromptConfig(filename string) (*models.PromptConfig, error) {
 	data, err := os.ReadFile(filename)
@@ -48,9 +113,63 @@ func LoadPromptConfig(filename string) (*models.PromptConfig, error) {
 	return &config, nil
 }
 
-// FormatUserPrompt formats the user prompt template with the provided description
-func FormatUserPrompt(template, description string) string {
-	return strings.ReplaceAll(template, "{art_work_description}", description)
+// LoadPromptConfigs loads every .yaml file in dir into a prompt library keyed
+// by name. Loading fails on unnamed configs, duplicate names, a missing or
+// broken user_prompt_template, or when the default cannot be determined:
+// exactly one config must set `default: true` unless only one config exists.
+func LoadPromptConfigs(dir string) (*models.PromptLibrary, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt configs: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no prompt configs found in %s", dir)
+	}
+
+	library := &models.PromptLibrary{Configs: make(map[string]*models.PromptConfig)}
+	sources := make(map[string]string)
+	var defaults []string
+
+	for _, file := range files {
+		promptConfig, err := LoadPromptConfig(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if promptConfig.Name == "" {
+			return nil, fmt.Errorf("%s: prompt config has no name", file)
+		}
+		if strings.TrimSpace(promptConfig.UserPromptTemplate) == "" {
+			return nil, fmt.Errorf("%s: prompt config %q has no user_prompt_template", file, promptConfig.Name)
+		}
+		// Parsed once here, so generations only execute the template
+		if promptConfig.UserPrompt, err = ParseUserPrompt(promptConfig.UserPromptTemplate); err != nil {
+			return nil, fmt.Errorf("%s: prompt config %q: %w", file, promptConfig.Name, err)
+		}
+		if other, ok := sources[promptConfig.Name]; ok {
+			return nil, fmt.Errorf("%s: prompt config name %q is already used by %s", file, promptConfig.Name, other)
+		}
+
+		sources[promptConfig.Name] = file
+		library.Configs[promptConfig.Name] = promptConfig
+		if promptConfig.Default {
+			defaults = append(defaults, promptConfig.Name)
+		}
+	}
+
+	switch {
+	case len(defaults) == 1:
+		library.Default = defaults[0]
+	case len(defaults) > 1:
+		return nil, fmt.Errorf("multiple default prompt configs: %s", strings.Join(defaults, ", "))
+	case len(library.Configs) == 1:
+		for name := range library.Configs {
+			library.Default = name
+		}
+	default:
+		return nil, fmt.Errorf("no default prompt config in %s: set `default: true` in one of them", dir)
+	}
+
+	return library, nil
 }
 
 // GetAvailableModels returns a list of available models for the dropdown
@@ -61,13 +180,7 @@ func GetAvailableModels() []models.ModelInfo {
 		defaultSet[id] = true
 	}
 
-	// Try to fetch live models from OpenRouter when an API key is present.
-	var allModels []models.ModelInfo
-	if openModels, err := fetchOpenRouterModels(); err == nil && len(openModels) > 0 {
-		allModels = openModels
-	} else {
-		allModels = getAllModels()
-	}
+	allModels := getAllModels()
 
 	// Sort models by cost (cheapest first)
 	sort.Slice(allModels, func(i, j int) bool {
@@ -90,23 +203,71 @@ func GetAvailableModels() []models.ModelInfo {
 	return filteredModels
 }
 
-// fetchOpenRouterModels fetches models from the OpenRouter API
-func fetchOpenRouterModels() ([]models.ModelInfo, error) {
-	// Return cached value if valid
-	modelsMu.RLock()
-	if time.Now().Before(cacheExpiry) && len(modelsCache) > 0 {
-		models := make([]models.ModelInfo, len(modelsCache))
-		copy(models, modelsCache)
-		modelsMu.RUnlock()
-		return models, nil
+// ModelProvider returns the provider part of a model ID ("openai" for
+// "openai/gpt-5"), or "other" when the ID has no provider prefix
+func ModelProvider(id string) string {
+	provider, _, found := strings.Cut(id, "/")
+	if !found || provider == "" {
+		return "other"
+	}
+	return provider
+}
+
+// Provider families that models are filtered by, on the group page and in
+// GET /api/models
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGoogle    = "google"
+	ProviderOther     = "other"
+)
+
+// ProviderOf returns the provider family of a model ID: ProviderOpenAI,
+// ProviderAnthropic, ProviderGoogle, or ProviderOther for everyone else
+func ProviderOf(modelID string) string {
+	switch provider := strings.ToLower(ModelProvider(modelID)); provider {
+	case ProviderOpenAI, ProviderAnthropic, ProviderGoogle:
+		return provider
+	default:
+		return ProviderOther
 	}
-	modelsMu.RUnlock()
+}
+
+// GroupModelsByProvider partitions models by provider. Groups are sorted by
+// provider name and keep the order of the input within each group.
+func GroupModelsByProvider(list []models.ModelInfo) []models.ModelGroup {
+	index := make(map[string]int)
+	var groups []models.ModelGroup
+	for _, model := range list {
+		provider := ModelProvider(model.ID)
+		i, ok := index[provider]
+		if !ok {
+			i = len(groups)
+			index[provider] = i
+			groups = append(groups, models.ModelGroup{Provider: provider})
+		}
+		groups[i].Models = append(groups[i].Models, model)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Provider < groups[j].Provider
+	})
+
+	return groups
+}
 
-	// Fetch from API
-	modelsMu.Lock()
-	defer modelsMu.Unlock()
+// fetchOpenRouterModels fetches the model list from the OpenRouter API. The
+// API key is sent when OPENROUTER_API_KEY is set.
+func fetchOpenRouterModels(ctx context.Context) ([]models.ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, OpenRouterBaseURL()+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if key := os.Getenv("OPENROUTER_API_KEY"); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
 
-	resp, err := http.Get("https://openrouter.ai/api/v1/models")
+	resp, err := modelsHTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -123,29 +284,195 @@ func fetchOpenRouterModels() ([]models.ModelInfo, error) {
 
 	var modelInfos []models.ModelInfo
 	for _, model := range apiResp.Data {
-		cost := 0.0
-		if completion, ok := model.Pricing["completion"].(string); ok {
-			if f, err := parseFloat(completion); err == nil {
-				// Convert from per-token to per-million-tokens cost
-				cost = f * 1000000
+		supportsVision := false
+		for _, modality := range model.Architecture.InputModalities {
+			if modality == "image" {
+				supportsVision = true
+				break
 			}
 		}
 		modelInfos = append(modelInfos, models.ModelInfo{
-			ID:   model.ID,
-			Name: model.Name,
-			Cost: cost,
+			ID:             model.ID,
+			Name:           model.Name,
+			Cost:           perMillionTokens(model.Pricing["completion"]),
+			PromptCost:     perMillionTokens(model.Pricing["prompt"]),
+			ContextLength:  model.ContextLength,
+			SupportsVision: supportsVision,
 		})
 	}
 
-	// Update cache
-	modelsCache = make([]models.ModelInfo, len(modelInfos))
-	copy(modelsCache, modelInfos)
-	cacheExpiry = time.Now().Add(5 * time.Minute)
-
 	log.Printf("Fetched %d models from OpenRouter", len(modelInfos))
+
 	return modelInfos, nil
 }
 
+// perMillionTokens converts an OpenRouter per-token price, sent as a string,
+// to dollars per million tokens. Missing or malformed prices count as 0.
+func perMillionTokens(price interface{}) float64 {
+	s, ok := price.(string)
+	if !ok {
+		return 0
+	}
+	f, err := parseFloat(s)
+	if err != nil {
+		return 0
+	}
+	return f * 1000000
+}
+
+// modelsCacheTTL returns how long a fetched model list stays cached, read
+// from MODELS_CACHE_TTL as a Go duration such as "10m" or "1h"
+func modelsCacheTTL() time.Duration {
+	return durationEnv("MODELS_CACHE_TTL", defaultModelsCacheTTL)
+}
+
+// SlowQueryThreshold returns the duration from SLOW_QUERY_THRESHOLD (e.g.
+// "200ms") at or above which database statements are logged. It is 0, which
+// disables the log, when unset.
+func SlowQueryThreshold() time.Duration {
+	return durationEnv("SLOW_QUERY_THRESHOLD", 0)
+}
+
+// RateLimitWarnFraction returns the share of the rate limit allowance, read
+// from RATE_LIMIT_WARN_FRACTION, after which clients are warned. 0 turns the
+// warning off.
+func RateLimitWarnFraction() float64 {
+	value := strings.TrimSpace(os.Getenv("RATE_LIMIT_WARN_FRACTION"))
+	if value == "" {
+		return defaultRateLimitWarnFraction
+	}
+	fraction, err := strconv.ParseFloat(value, 64)
+	if err != nil || fraction < 0 || fraction > 1 {
+		log.Printf("Ignoring invalid RATE_LIMIT_WARN_FRACTION %q, using %g", value, defaultRateLimitWarnFraction)
+		return defaultRateLimitWarnFraction
+	}
+	return fraction
+}
+
+// FeedSize returns the number of groups in the Atom feed, read from
+// FEED_SIZE and capped at MaxFeedSize
+func FeedSize() int {
+	return min(positiveIntEnv("FEED_SIZE", defaultFeedSize), MaxFeedSize)
+}
+
+// GalleryWorkers returns how many goroutines assemble the gallery page,
+// read from GALLERY_WORKERS and defaulting to GOMAXPROCS. 1 assembles it
+// serially.
+func GalleryWorkers() int {
+	return positiveIntEnv("GALLERY_WORKERS", runtime.GOMAXPROCS(0))
+}
+
+// GalleryBatchSize returns the number of groups a gallery worker assembles
+// at a time, read from GALLERY_BATCH_SIZE. Galleries of at most one batch
+// are assembled serially.
+func GalleryBatchSize() int {
+	return positiveIntEnv("GALLERY_BATCH_SIZE", defaultGalleryBatchSize)
+}
+
+// GalleryCacheTTL returns how long a rendered gallery page is served from
+// memory in production, read from GALLERY_CACHE_TTL (e.g. "1m"). 0 turns
+// the cache off.
+func GalleryCacheTTL() time.Duration {
+	return durationEnv("GALLERY_CACHE_TTL", defaultGalleryCacheTTL)
+}
+
+// ArtworkRevisions returns the number of replaced SVGs kept per artwork,
+// read from ARTWORK_REVISIONS
+func ArtworkRevisions() int {
+	return positiveIntEnv("ARTWORK_REVISIONS", defaultArtworkRevisions)
+}
+
+// MaxConcurrentGenerations returns the number of generations that may call
+// OpenRouter at once, read from MAX_CONCURRENT_GENERATIONS
+func MaxConcurrentGenerations() int {
+	return positiveIntEnv("MAX_CONCURRENT_GENERATIONS", defaultMaxConcurrentGenerations)
+}
+
+// GenerationQueueTimeout returns how long a generation waits for one of the
+// MaxConcurrentGenerations slots before it is turned away, read from
+// GENERATION_QUEUE_TIMEOUT (e.g. "30s")
+func GenerationQueueTimeout() time.Duration {
+	return durationEnv("GENERATION_QUEUE_TIMEOUT", defaultGenerationQueueTimeout)
+}
+
+// BackupInterval returns how often a snapshot of the database is written to
+// BackupDir, read from BACKUP_INTERVAL (e.g. "6h"). It is 0, which disables
+// scheduled backups, when unset.
+func BackupInterval() time.Duration {
+	return durationEnv("BACKUP_INTERVAL", 0)
+}
+
+// BackupDir returns the directory scheduled snapshots are written to, read
+// from BACKUP_DIR, or "" when unset
+func BackupDir() string {
+	return strings.TrimSpace(os.Getenv("BACKUP_DIR"))
+}
+
+// BackupKeep returns the number of scheduled snapshots kept in BackupDir,
+// read from BACKUP_KEEP; older ones are deleted
+func BackupKeep() int {
+	return positiveIntEnv("BACKUP_KEEP", defaultBackupKeep)
+}
+
+// LogFormat returns the format of the application log, read from
+// LOG_FORMAT: "json" for structured logs, anything else for plain text
+func LogFormat() string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT")))
+}
+
+// positiveIntEnv reads a positive integer from the environment variable
+// name, returning fallback when it is unset or invalid
+func positiveIntEnv(name string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Printf("Ignoring invalid %s %q, using %d", name, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// durationEnv reads a Go duration from the environment variable name,
+// returning fallback when it is unset, malformed or negative
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// RefreshModels fetches the OpenRouter model list again, or waits for the
+// fetch already running. On failure the previous list stays cached.
+func RefreshModels(ctx context.Context) error {
+	_, err := modelList.refresh(ctx)
+	return err
+}
+
+// ModelListFetchedAt returns when the cached OpenRouter model list was
+// fetched, the zero time before the first successful fetch, and whether it
+// has outlived MODELS_CACHE_TTL
+func ModelListFetchedAt() (fetchedAt time.Time, stale bool) {
+	return modelList.status()
+}
+
+// OpenRouterBaseURL returns the OpenRouter API root, replaced by
+// OPENROUTER_BASE_URL when set, e.g. to go through a proxy or a stub
+func OpenRouterBaseURL() string {
+	if base := strings.TrimSuffix(strings.TrimSpace(os.Getenv("OPENROUTER_BASE_URL")), "/"); base != "" {
+		return base
+	}
+	return DefaultOpenRouterBaseURL
+}
+
 // parseFloat parses a string to float64
 func parseFloat(s string) (float64, error) {
 	if s == "" {
@@ -164,6 +491,68 @@ func IsEditingEnabled() bool {
 	return enableEditing == "true" || enableEditing == "1"
 }
 
+// DryRun reports whether DRY_RUN is set, making generation return a
+// placeholder SVG instead of calling OpenRouter
+func DryRun() bool {
+	dryRun := os.Getenv("DRY_RUN")
+	return dryRun == "true" || dryRun == "1"
+}
+
+// BaseURL returns the public base URL configured with BASE_URL, without a
+// trailing slash, or "" when it is not set
+func BaseURL() string {
+	return strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+}
+
+// RequestBaseURL returns BaseURL, or when it is not configured reconstructs
+// the public base URL from the request, honoring X-Forwarded-Proto from a
+// TLS-terminating proxy
+func RequestBaseURL(r *http.Request) string {
+	if base := BaseURL(); base != "" {
+		return base
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// AdminAPIKey returns the bearer token required by write endpoints, or "" when
+// write endpoints are not protected by a key
+func AdminAPIKey() string {
+	return os.Getenv("ADMIN_API_KEY")
+}
+
+// HasAdminKey reports whether r carries an "Authorization: Bearer" header
+// matching ADMIN_API_KEY. It is false when no key is configured.
+func HasAdminKey(r *http.Request) bool {
+	key := AdminAPIKey()
+	if key == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(key)) == 1
+}
+
+type adminKey struct{}
+
+// WithAdmin returns a copy of ctx marked as belonging to a request that
+// presented a valid admin API key
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether ctx was marked by WithAdmin
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
 // GetDefaultModels returns the default model IDs
 func GetDefaultModels() []string {
 	// Get all available models and filter for free ones or those under $0.40/1M tokens
@@ -181,9 +570,9 @@ func GetDefaultModels() []string {
 
 // getAllModels returns the raw model data (helper function)
 func getAllModels() []models.ModelInfo {
-	// Return live models from OpenRouter only. If the API call fails return an
-	// empty list.
-	if live, err := fetchOpenRouterModels(); err == nil && len(live) > 0 {
+	// Return live models from OpenRouter only. If there is no model list,
+	// e.g. because the API call failed, return an empty list.
+	if live, err := modelList.get(context.Background()); err == nil && len(live) > 0 {
 		return live
 	}
 
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestGroupModelsByProvider(t *testing.T) {
+	list := []models.ModelInfo{
+		{ID: "openai/gpt-4o"},
+		{ID: "anthropic/claude-3.5-sonnet"},
+		{ID: "openai/gpt-4o-mini"},
+		{ID: "local-model"},
+		{ID: "anthropic/claude-3-haiku"},
+	}
+
+	var got []string
+	for _, group := range GroupModelsByProvider(list) {
+		for _, model := range group.Models {
+			if ModelProvider(model.ID) != group.Provider {
+				t.Errorf("model %s is grouped under %s", model.ID, group.Provider)
+			}
+			got = append(got, group.Provider+":"+model.ID)
+		}
+	}
+
+	want := []string{
+		"anthropic:anthropic/claude-3.5-sonnet",
+		"anthropic:anthropic/claude-3-haiku",
+		"openai:openai/gpt-4o",
+		"openai:openai/gpt-4o-mini",
+		"other:local-model",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("grouped models = %v, want %v", got, want)
+	}
+}
+
+func TestModelProvider(t *testing.T) {
+	tests := map[string]string{
+		"openai/gpt-4o": "openai",
+		"local-model":   "other",
+		"/no-provider":  "other",
+		"x-ai/grok-2":   "x-ai",
+	}
+	for id, want := range tests {
+		if got := ModelProvider(id); got != want {
+			t.Errorf("ModelProvider(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+// writePromptConfigs writes each YAML body to a file of its own in a fresh
+// directory and returns the directory
+func writePromptConfigs(t *testing.T, configs ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, body := range configs {
+		if err := os.WriteFile(filepath.Join(dir, string(rune('a'+i))+".yaml"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadPromptConfigs(t *testing.T) {
+	dir := writePromptConfigs(t,
+		"name: Plain\ndefault: true\nuser_prompt_template: Draw {{.Description}}\n",
+		"name: Line Art\nsystem_prompts:\n  - role: system\n    content: Use lines.\nuser_prompt_template: Draw {art_work_description} in lines\n",
+	)
+
+	library, err := LoadPromptConfigs(dir)
+	if err != nil {
+		t.Fatalf("LoadPromptConfigs: %v", err)
+	}
+	if library.Default != "Plain" || len(library.Configs) != 2 {
+		t.Fatalf("library = %+v", library)
+	}
+	if got := library.Get("Line Art"); got.SystemPrompts[0].Content != "Use lines." {
+		t.Errorf("Get(Line Art) = %+v", got)
+	}
+	if got := library.Get("Gone"); got.Name != "Plain" {
+		t.Errorf("Get of an unknown style = %q, want the default", got.Name)
+	}
+}
+
+func TestLoadPromptConfigsFailsFast(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []string
+		want    string
+	}{
+		{
+			name: "duplicate names",
+			configs: []string{
+				"name: Plain\ndefault: true\nuser_prompt_template: Draw {{.Description}}\n",
+				"name: Plain\nuser_prompt_template: Draw {{.Description}}\n",
+			},
+			want: "already used",
+		},
+		{
+			name:    "missing user_prompt_template",
+			configs: []string{"name: Plain\ndefault: true\n"},
+			want:    "has no user_prompt_template",
+		},
+		{
+			name:    "broken user_prompt_template",
+			configs: []string{"name: Plain\nuser_prompt_template: Draw {{.Nope}}\n"},
+			want:    "Plain",
+		},
+		{
+			name: "no default",
+			configs: []string{
+				"name: Plain\nuser_prompt_template: Draw {{.Description}}\n",
+				"name: Other\nuser_prompt_template: Draw {{.Description}}\n",
+			},
+			want: "no default prompt config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPromptConfigs(writePromptConfigs(t, tt.configs...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadPromptConfigs error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
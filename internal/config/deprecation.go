@@ -0,0 +1,158 @@
+package config
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"pelican-gallery/internal/models"
+)
+
+var (
+	usedModelsSource func() ([]string, error)
+	deprecatedModels = map[string]string{} // model ID -> suggested replacement
+	deprecatedMu     sync.RWMutex
+)
+
+// SetUsedModelsSource registers the function that lists the model IDs stored
+// artworks reference. Each model cache refresh diffs them against the live list.
+func SetUsedModelsSource(source func() ([]string, error)) {
+	deprecatedMu.Lock()
+	usedModelsSource = source
+	deprecatedMu.Unlock()
+}
+
+// DeprecatedModel reports whether a model is no longer offered by OpenRouter,
+// along with the closest current model from the same provider (may be empty)
+func DeprecatedModel(id string) (suggestion string, deprecated bool) {
+	deprecatedMu.RLock()
+	defer deprecatedMu.RUnlock()
+	suggestion, deprecated = deprecatedModels[id]
+	return suggestion, deprecated
+}
+
+// AnnotateDeprecated sets the deprecation fields on each artwork
+func AnnotateDeprecated(artworks []models.Artwork) {
+	for i := range artworks {
+		artworks[i].SuggestedModel, artworks[i].Deprecated = DeprecatedModel(artworks[i].Model)
+	}
+}
+
+// updateDeprecatedModels marks used models missing from the live list
+func updateDeprecatedModels(live []models.ModelInfo) {
+	deprecatedMu.RLock()
+	source := usedModelsSource
+	deprecatedMu.RUnlock()
+	if source == nil || len(live) == 0 {
+		return
+	}
+
+	used, err := source()
+	if err != nil {
+		log.Printf("Failed to list used models for deprecation check: %v", err)
+		return
+	}
+
+	liveSet := make(map[string]bool, len(live))
+	for _, model := range live {
+		liveSet[model.ID] = true
+	}
+
+	deprecated := make(map[string]string)
+	for _, id := range used {
+		if !liveSet[id] {
+			deprecated[id] = closestModel(id, live)
+		}
+	}
+
+	deprecatedMu.Lock()
+	deprecatedModels = deprecated
+	deprecatedMu.Unlock()
+
+	if len(deprecated) > 0 {
+		log.Printf("%d stored model(s) are no longer offered by OpenRouter", len(deprecated))
+	}
+}
+
+// closestModel returns the live model from the same provider family whose ID
+// is nearest to id, or "" when the provider has no live models
+func closestModel(id string, live []models.ModelInfo) string {
+	provider, name, _ := strings.Cut(id, "/")
+
+	best, bestDistance := "", -1
+	for _, model := range live {
+		candidateProvider, candidateName, _ := strings.Cut(model.ID, "/")
+		if candidateProvider != provider || model.ID == "openrouter/auto" {
+			continue
+		}
+		distance := editDistance(name, candidateName)
+		if bestDistance < 0 || distance < bestDistance || (distance == bestDistance && model.ID < best) {
+			best, bestDistance = model.ID, distance
+		}
+	}
+	return best
+}
+
+// SimilarModels returns up to n live model IDs that id may have been meant to
+// be, closest first: models whose ID starts with id, then those within a few
+// edits of it. A typo like "anthropic/claude-sonet-4" finds
+// "anthropic/claude-sonnet-4".
+func SimilarModels(id string, live []models.ModelInfo, n int) []string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return []string{}
+	}
+	// Beyond this many edits a suggestion is more likely noise than a fix
+	maxDistance := max(3, len(id)/4)
+
+	type candidate struct {
+		id       string
+		distance int
+	}
+	var candidates []candidate
+	for _, model := range live {
+		candidateID := strings.ToLower(model.ID)
+		distance := editDistance(id, candidateID)
+		if strings.HasPrefix(candidateID, id) {
+			distance = 0
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{model.ID, distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+
+	suggestions := []string{}
+	for _, c := range candidates[:min(n, len(candidates))] {
+		suggestions = append(suggestions, c.id)
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestSimilarModels(t *testing.T) {
+	live := []models.ModelInfo{
+		{ID: "anthropic/claude-sonnet-4"},
+		{ID: "anthropic/claude-opus-4"},
+		{ID: "openai/gpt-4o-mini"},
+		{ID: "openai/gpt-4o"},
+		{ID: "google/gemini-2.5-pro"},
+	}
+
+	tests := []struct {
+		id   string
+		n    int
+		want []string
+	}{
+		{"anthropic/claude-sonet-4", 5, []string{"anthropic/claude-sonnet-4", "anthropic/claude-opus-4"}},
+		{"anthropic/claude-sonet-4", 1, []string{"anthropic/claude-sonnet-4"}},
+		{"Anthropic/Claude-Sonnet-4", 1, []string{"anthropic/claude-sonnet-4"}},
+		{"openai/gpt-4", 5, []string{"openai/gpt-4o", "openai/gpt-4o-mini"}},
+		{"openai/gpt-4", 1, []string{"openai/gpt-4o"}},
+		{"google/gemini-2.5-flash", 5, []string{"google/gemini-2.5-pro"}},
+		{"google/gemini", 5, []string{"google/gemini-2.5-pro"}},
+		{"acme/painter", 5, []string{}},
+		{"  ", 5, []string{}},
+	}
+	for _, tt := range tests {
+		if got := SimilarModels(tt.id, live, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SimilarModels(%q, %d) = %q, want %q", tt.id, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"pelican-gallery/internal/models"
+)
+
+// featuredOrderByCost is the FEATURED_MODEL_ORDER value that orders featured
+// artworks by model cost, cheapest first
+const featuredOrderByCost = "cost"
+
+// featuredModelOrder returns the model IDs listed in FEATURED_MODEL_ORDER, or
+// nil when it is unset or set to "cost"
+func featuredModelOrder() []string {
+	var order []string
+	for _, id := range strings.Split(os.Getenv("FEATURED_MODEL_ORDER"), ",") {
+		if id = strings.TrimSpace(id); id != "" && id != featuredOrderByCost {
+			order = append(order, id)
+		}
+	}
+	return order
+}
+
+// SortFeaturedArtworks puts featured artworks in the order configured with
+// FEATURED_MODEL_ORDER, so the homepage always compares models in the same
+// visual order. The setting is either a comma-separated list of model IDs,
+// with unlisted models after the listed ones, or "cost" to order by output
+// token price. Remaining ties, and everything when unconfigured, fall back
+// to model name order.
+func SortFeaturedArtworks(artworks []models.Artwork) {
+	var less func(a, b string) bool
+
+	if strings.TrimSpace(os.Getenv("FEATURED_MODEL_ORDER")) == featuredOrderByCost {
+		costs := cachedModelCosts()
+		less = func(a, b string) bool {
+			costA, knownA := costs[a]
+			costB, knownB := costs[b]
+			if knownA != knownB {
+				return knownA
+			}
+			return costA < costB
+		}
+	} else {
+		rank := make(map[string]int)
+		for i, id := range featuredModelOrder() {
+			if _, seen := rank[id]; !seen {
+				rank[id] = i
+			}
+		}
+		less = func(a, b string) bool {
+			rankA, listedA := rank[a]
+			rankB, listedB := rank[b]
+			if listedA != listedB {
+				return listedA
+			}
+			return rankA < rankB
+		}
+	}
+
+	sort.SliceStable(artworks, func(i, j int) bool {
+		a, b := artworks[i].Model, artworks[j].Model
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a < b
+	})
+}
+
+// cachedModelCosts returns the cost of each model from the last OpenRouter
+// model list, even when it has expired. Page rendering never waits on a fetch;
+// models are simply unpriced until the list has been loaded once.
+func cachedModelCosts() map[string]float64 {
+	return modelList.costs()
+}
+
+// CachedModelInfos indexes the last OpenRouter model list by model ID, even
+// when it has expired, without waiting on a fetch. It is empty until the
+// list has been loaded once.
+func CachedModelInfos() map[string]models.ModelInfo {
+	return modelList.infos()
+}
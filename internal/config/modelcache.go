@@ -0,0 +1,157 @@
+package config
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// modelsFetchTimeout bounds a fetch of the OpenRouter model list
+const modelsFetchTimeout = 10 * time.Second
+
+// modelsRetryDelay is how long a failed fetch of the model list is not
+// retried; the previous list, if any, is served meanwhile
+const modelsRetryDelay = time.Minute
+
+// modelsHTTPClient fetches the OpenRouter model list
+var modelsHTTPClient = &http.Client{Timeout: modelsFetchTimeout}
+
+// modelList caches the OpenRouter model list for the whole process
+var modelList = &modelCache{fetch: fetchOpenRouterModels}
+
+// modelCache holds the OpenRouter model list. Only one fetch runs at a time:
+// callers that find the list expired get the stale copy right away while it
+// is fetched again in the background, and only callers without any list
+// wait for the fetch. A failed fetch keeps the previous list, so an
+// OpenRouter outage never empties the model dropdown.
+type modelCache struct {
+	fetch func(ctx context.Context) ([]models.ModelInfo, error)
+
+	mu        sync.Mutex
+	list      []models.ModelInfo
+	fetchedAt time.Time
+	expiry    time.Time
+	lastErr   error
+	inflight  chan struct{} // closed when the running fetch ends, nil when idle
+}
+
+// get returns a copy of the model list. An expired list is returned as is
+// and fetched again in the background; without a list, get waits for the
+// fetch until ctx is done.
+func (c *modelCache) get(ctx context.Context) ([]models.ModelInfo, error) {
+	c.mu.Lock()
+	if time.Now().Before(c.expiry) {
+		defer c.mu.Unlock()
+		return slices.Clone(c.list), nil
+	}
+
+	done := c.startFetch()
+	if len(c.list) > 0 {
+		defer c.mu.Unlock()
+		return slices.Clone(c.list), nil
+	}
+	c.mu.Unlock()
+
+	return c.wait(ctx, done)
+}
+
+// refresh fetches the model list again, or joins the fetch already running,
+// and waits for it until ctx is done
+func (c *modelCache) refresh(ctx context.Context) ([]models.ModelInfo, error) {
+	c.mu.Lock()
+	done := c.startFetch()
+	c.mu.Unlock()
+
+	return c.wait(ctx, done)
+}
+
+// wait returns the model list and the error of the fetch once done is
+// closed, or the error of ctx if it ends first
+func (c *modelCache) wait(ctx context.Context, done <-chan struct{}) ([]models.ModelInfo, error) {
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.list), c.lastErr
+}
+
+// startFetch starts fetching the model list unless a fetch is running and
+// returns the channel closed when it ends. c.mu must be held.
+func (c *modelCache) startFetch() <-chan struct{} {
+	if c.inflight != nil {
+		return c.inflight
+	}
+	done := make(chan struct{})
+	c.inflight = done
+
+	// The fetch is shared by every caller waiting for it, so it is not tied
+	// to the context of any of them; modelsHTTPClient bounds it
+	go func() {
+		list, err := c.fetch(context.Background())
+		fetchedAt := time.Now()
+		if err == nil {
+			updateDeprecatedModels(list)
+			recordModelList(list, fetchedAt)
+		}
+
+		c.mu.Lock()
+		if err != nil {
+			log.Printf("Failed to fetch models from OpenRouter, keeping %d cached model(s): %v", len(c.list), err)
+			c.expiry = fetchedAt.Add(modelsRetryDelay)
+		} else {
+			c.list = list
+			c.fetchedAt = fetchedAt
+			c.expiry = fetchedAt.Add(modelsCacheTTL())
+		}
+		c.lastErr = err
+		c.inflight = nil
+		c.mu.Unlock()
+		close(done)
+	}()
+
+	return done
+}
+
+// status returns when the model list was last fetched and whether it has
+// outlived its TTL
+func (c *modelCache) status() (fetchedAt time.Time, stale bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetchedAt.IsZero() {
+		return time.Time{}, true
+	}
+	return c.fetchedAt, time.Since(c.fetchedAt) >= modelsCacheTTL()
+}
+
+// costs returns the cost of each model of the list, even when it has expired
+func (c *modelCache) costs() map[string]float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	costs := make(map[string]float64, len(c.list))
+	for _, model := range c.list {
+		costs[model.ID] = model.Cost
+	}
+	return costs
+}
+
+// infos indexes the models of the list by ID, even when it has expired
+func (c *modelCache) infos() map[string]models.ModelInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	infos := make(map[string]models.ModelInfo, len(c.list))
+	for _, model := range c.list {
+		infos[model.ID] = model
+	}
+	return infos
+}
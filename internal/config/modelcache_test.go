@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// fakeOpenRouterModels is a model list as OpenRouter's /models returns it
+const fakeOpenRouterModels = `{"data": [
+	{
+		"id": "openai/gpt-4o",
+		"name": "OpenAI: GPT-4o",
+		"context_length": 128000,
+		"pricing": {"prompt": "0.0000025", "completion": "0.00001"},
+		"architecture": {"input_modalities": ["text", "image"]}
+	},
+	{
+		"id": "meta/llama-3",
+		"name": "Meta: Llama 3",
+		"context_length": 8192,
+		"pricing": {"prompt": "0", "completion": "free"},
+		"architecture": {"input_modalities": ["text"]}
+	}
+]}`
+
+// fakeOpenRouter serves fakeOpenRouterModels as OPENROUTER_BASE_URL for the
+// rest of the test and returns the number of requests it has served
+func fakeOpenRouter(t *testing.T) *atomic.Int32 {
+	t.Helper()
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fakeOpenRouterModels))
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("OPENROUTER_BASE_URL", upstream.URL+"/")
+	return &requests
+}
+
+func TestFetchOpenRouterModels(t *testing.T) {
+	fakeOpenRouter(t)
+
+	list, err := fetchOpenRouterModels(context.Background())
+	if err != nil {
+		t.Fatalf("fetchOpenRouterModels: %v", err)
+	}
+	want := []models.ModelInfo{
+		{ID: "openai/gpt-4o", Name: "OpenAI: GPT-4o", Cost: 10, PromptCost: 2.5, ContextLength: 128000, SupportsVision: true},
+		{ID: "meta/llama-3", Name: "Meta: Llama 3", ContextLength: 8192},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("parsed %d models, want %d", len(list), len(want))
+	}
+	for i := range want {
+		got := list[i]
+		// Prices are parsed from decimal strings; compare them loosely
+		if got.ID != want[i].ID || got.Name != want[i].Name || got.ContextLength != want[i].ContextLength ||
+			got.SupportsVision != want[i].SupportsVision || !closeTo(got.Cost, want[i].Cost) || !closeTo(got.PromptCost, want[i].PromptCost) {
+			t.Errorf("model %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
+
+func TestModelsCacheTTL(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":      defaultModelsCacheTTL,
+		"30s":   30 * time.Second,
+		"1h":    time.Hour,
+		"0":     0,
+		"-5m":   defaultModelsCacheTTL,
+		"later": defaultModelsCacheTTL,
+	}
+	for value, want := range tests {
+		t.Setenv("MODELS_CACHE_TTL", value)
+		if got := modelsCacheTTL(); got != want {
+			t.Errorf("MODELS_CACHE_TTL=%q: TTL = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestModelCacheReusesList(t *testing.T) {
+	requests := fakeOpenRouter(t)
+	t.Setenv("MODELS_CACHE_TTL", "1h")
+	cache := &modelCache{fetch: fetchOpenRouterModels}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		list, err := cache.get(ctx)
+		if err != nil || len(list) != 2 {
+			t.Fatalf("get = %d models, %v", len(list), err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d fetches for three gets within the TTL, want 1", n)
+	}
+	if fetchedAt, stale := cache.status(); fetchedAt.IsZero() || stale {
+		t.Errorf("status = %v, stale %v, want a fresh list", fetchedAt, stale)
+	}
+
+	// Refreshing busts the cache
+	if _, err := cache.refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d fetches after a refresh, want 2", n)
+	}
+}
+
+func TestModelCacheExpires(t *testing.T) {
+	requests := fakeOpenRouter(t)
+	t.Setenv("MODELS_CACHE_TTL", "0")
+	cache := &modelCache{fetch: fetchOpenRouterModels}
+	ctx := context.Background()
+
+	if _, err := cache.get(ctx); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, stale := cache.status(); !stale {
+		t.Error("list is not stale with a TTL of 0")
+	}
+
+	// An expired list is served right away and fetched in the background
+	list, err := cache.get(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expired get = %d models, %v", len(list), err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired list was not fetched again")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
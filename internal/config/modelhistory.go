@@ -0,0 +1,41 @@
+package config
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+var (
+	modelListRecorder func(ids []string, fetchedAt time.Time) error
+	recorderMu        sync.RWMutex
+)
+
+// SetModelListRecorder registers the function that persists the model IDs of
+// every successful fetch from OpenRouter, for the model availability history
+func SetModelListRecorder(record func(ids []string, fetchedAt time.Time) error) {
+	recorderMu.Lock()
+	modelListRecorder = record
+	recorderMu.Unlock()
+}
+
+// recordModelList hands a fetched model list to the registered recorder
+func recordModelList(live []models.ModelInfo, fetchedAt time.Time) {
+	recorderMu.RLock()
+	record := modelListRecorder
+	recorderMu.RUnlock()
+	if record == nil || len(live) == 0 {
+		return
+	}
+
+	ids := make([]string, len(live))
+	for i, model := range live {
+		ids[i] = model.ID
+	}
+
+	if err := record(ids, fetchedAt); err != nil {
+		log.Printf("Failed to record model list snapshot: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"pelican-gallery/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelOverridesFile holds the per-model generation parameters
+const ModelOverridesFile = "config/models.yaml"
+
+// modelOverrides are the overrides in use; nil until loaded
+var modelOverrides atomic.Pointer[models.ModelOverrides]
+
+// LoadModelOverrides reads the per-model generation parameters from the YAML
+// file. Loading fails on an empty or repeated pattern, or on a parameter
+// outside the bounds OpenRouter accepts.
+func LoadModelOverrides(filename string) (*models.ModelOverrides, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model overrides: %w", err)
+	}
+
+	var overrides models.ModelOverrides
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse model overrides: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, override := range overrides.Overrides {
+		override.Match = strings.TrimSpace(override.Match)
+		if override.Match == "" {
+			return nil, fmt.Errorf("model override %d has no match pattern", i+1)
+		}
+		if seen[override.Match] {
+			return nil, fmt.Errorf("model override %q is listed twice", override.Match)
+		}
+		seen[override.Match] = true
+
+		if err := validateModelOverride(override); err != nil {
+			return nil, fmt.Errorf("model override %q: %w", override.Match, err)
+		}
+		overrides.Overrides[i] = override
+	}
+
+	return &overrides, nil
+}
+
+// validateModelOverride checks the parameters of an override against the
+// bounds enforced on artworks
+func validateModelOverride(override models.ModelOverride) error {
+	if override.Temperature != nil {
+		if err := models.ValidateTemperature(*override.Temperature); err != nil {
+			return err
+		}
+	}
+	if override.MaxTokens != 0 {
+		if err := models.ValidateMaxTokens(override.MaxTokens); err != nil {
+			return err
+		}
+	}
+	if override.MaxTokensLimit != 0 {
+		if err := models.ValidateMaxTokens(override.MaxTokensLimit); err != nil {
+			return fmt.Errorf("max_tokens_limit: %w", err)
+		}
+		if override.MaxTokens > override.MaxTokensLimit {
+			return fmt.Errorf("max_tokens %d exceeds max_tokens_limit %d", override.MaxTokens, override.MaxTokensLimit)
+		}
+	}
+	return models.ValidateReasoningEffort(override.ReasoningEffort)
+}
+
+// InitModelOverrides loads the overrides in filename for ModelOverride to
+// use. A missing file means no overrides. It returns the number loaded.
+func InitModelOverrides(filename string) (int, error) {
+	overrides, err := LoadModelOverrides(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		overrides, err = &models.ModelOverrides{}, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	modelOverrides.Store(overrides)
+	return len(overrides.Overrides), nil
+}
+
+// ModelOverride returns the override that applies to model, if any
+func ModelOverride(model string) (models.ModelOverride, bool) {
+	override := modelOverrides.Load().Match(model)
+	if override == nil {
+		return models.ModelOverride{}, false
+	}
+	return *override, true
+}
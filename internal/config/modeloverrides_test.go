@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModelOverrides writes body as a model overrides file and returns its
+// path
+func writeModelOverrides(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadModelOverrides(t *testing.T) {
+	overrides, err := LoadModelOverrides(writeModelOverrides(t, `model_overrides:
+  - match: " openai/o* "
+    omit_temperature: true
+    max_tokens: 16000
+    reasoning_effort: medium
+  - match: "anthropic/claude-sonnet-4"
+    temperature: 0.3
+    max_tokens_limit: 8000
+    system_prompt: Keep it simple.
+`))
+	if err != nil {
+		t.Fatalf("LoadModelOverrides: %v", err)
+	}
+	if len(overrides.Overrides) != 2 {
+		t.Fatalf("loaded %d overrides, want 2", len(overrides.Overrides))
+	}
+	o := overrides.Overrides[0]
+	if o.Match != "openai/o*" || !o.OmitTemperature || o.MaxTokens != 16000 || o.ReasoningEffort != "medium" {
+		t.Errorf("first override = %+v", o)
+	}
+	o = overrides.Overrides[1]
+	if o.Temperature == nil || *o.Temperature != 0.3 || o.MaxTokensLimit != 8000 || o.SystemPrompt != "Keep it simple." {
+		t.Errorf("second override = %+v", o)
+	}
+}
+
+func TestLoadModelOverridesErrors(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"empty pattern", "model_overrides:\n  - match: \" \"\n", "no match pattern"},
+		{"repeated pattern", "model_overrides:\n  - match: openai/o*\n  - match: \"openai/o* \"\n", "listed twice"},
+		{"temperature", "model_overrides:\n  - match: openai/o*\n    temperature: 3\n", "temperature"},
+		{"max_tokens", "model_overrides:\n  - match: openai/o*\n    max_tokens: 200000\n", "max_tokens"},
+		{"max_tokens_limit", "model_overrides:\n  - match: openai/o*\n    max_tokens_limit: -1\n", "max_tokens_limit"},
+		{"max_tokens above the limit", "model_overrides:\n  - match: openai/o*\n    max_tokens: 9000\n    max_tokens_limit: 8000\n", "exceeds max_tokens_limit"},
+		{"reasoning effort", "model_overrides:\n  - match: openai/o*\n    reasoning_effort: extreme\n", "extreme"},
+		{"not YAML", "model_overrides: [", "parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadModelOverrides(writeModelOverrides(t, tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadModelOverrides error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitModelOverrides(t *testing.T) {
+	t.Cleanup(func() { modelOverrides.Store(nil) })
+
+	// The shipped file loads
+	n, err := InitModelOverrides(filepath.Join("..", "..", ModelOverridesFile))
+	if err != nil || n == 0 {
+		t.Fatalf("InitModelOverrides(%s) = %d, %v", ModelOverridesFile, n, err)
+	}
+	if override, ok := ModelOverride("openai/o3"); !ok || !override.OmitTemperature {
+		t.Errorf("ModelOverride(openai/o3) = %+v, %v, want one omitting temperature", override, ok)
+	}
+
+	// A missing file means no overrides
+	n, err = InitModelOverrides(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || n != 0 {
+		t.Fatalf("InitModelOverrides of a missing file = %d, %v", n, err)
+	}
+	if override, ok := ModelOverride("openai/o3"); ok {
+		t.Errorf("ModelOverride(openai/o3) without overrides = %+v", override)
+	}
+}
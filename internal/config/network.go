@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// RateLimitExempt returns the clients listed in RATE_LIMIT_EXEMPT, a comma
+// separated list of IPs and CIDRs, that bypass the rate limiter
+func RateLimitExempt() ([]netip.Prefix, error) {
+	return prefixesEnv("RATE_LIMIT_EXEMPT")
+}
+
+// TrustedProxies returns the proxies listed in TRUSTED_PROXIES, a comma
+// separated list of IPs and CIDRs, whose X-Forwarded-For header is believed
+// when deciding who a client really is
+func TrustedProxies() ([]netip.Prefix, error) {
+	return prefixesEnv("TRUSTED_PROXIES")
+}
+
+// prefixesEnv parses a comma separated list of IPs and CIDRs from the
+// environment variable name. A bare IP covers that address only.
+func prefixesEnv(name string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(os.Getenv(name), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q in %s: %w", entry, name, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP %q in %s: %w", entry, name, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ClientIP returns the address a request claims to come from: the first
+// X-Forwarded-For entry, X-Real-IP, or the peer address. Both headers can be
+// set by the client, so use it to tell clients apart, not to trust them.
+func ClientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		// Take the first IP in case of multiple
+		if idx := strings.Index(xff, ","); idx > 0 {
+			return strings.TrimSpace(xff[:idx])
+		}
+		return strings.TrimSpace(xff)
+	}
+
+	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+		return strings.TrimSpace(xri)
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// TrustedClientIP returns the client address of r without trusting headers
+// a client can set: the peer address, or, when the peer is a trusted proxy,
+// the nearest X-Forwarded-For entry that is not itself a trusted proxy
+func TrustedClientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr := peer.Addr().Unmap()
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0 && PrefixesContain(trustedProxies, addr); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+	return addr, true
+}
+
+// PrefixesContain reports whether addr is in any of prefixes
+func PrefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestTrustedClientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.1/32")}
+
+	tests := []struct {
+		name, remoteAddr, forwardedFor string
+		want                           string
+	}{
+		{"direct", "203.0.113.5:1234", "", "203.0.113.5"},
+		{"spoofed header from a client", "203.0.113.5:1234", "198.51.100.1", "203.0.113.5"},
+		{"through a proxy", "10.0.0.1:80", "198.51.100.1", "198.51.100.1"},
+		{"spoofed entry before the proxy", "10.0.0.1:80", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"through two proxies", "10.0.0.1:80", "198.51.100.1, 192.168.1.1", "198.51.100.1"},
+		{"garbage after the proxy", "10.0.0.1:80", "not-an-ip", "10.0.0.1"},
+		{"mapped IPv4", "[::ffff:203.0.113.5]:1234", "", "203.0.113.5"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		addr, ok := TrustedClientIP(r, proxies)
+		if !ok || addr.String() != tt.want {
+			t.Errorf("%s: TrustedClientIP = %v, %v; want %s", tt.name, addr, ok, tt.want)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "pipe"
+	if _, ok := TrustedClientIP(r, proxies); ok {
+		t.Error("TrustedClientIP of an unparseable peer is ok")
+	}
+}
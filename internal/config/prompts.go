@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// PromptStore holds the prompt library loaded from a directory and swaps it
+// atomically on reload, so in-flight generations keep the library they
+// started with
+type PromptStore struct {
+	dir     string
+	current atomic.Pointer[models.PromptLibrary]
+	mu      sync.Mutex // serializes reloads
+	loaded  string     // fingerprint of the directory when first loaded
+}
+
+// PromptChanges reports how a reload changed the prompt library
+type PromptChanges struct {
+	Added   []string            `json:"added"`
+	Removed []string            `json:"removed"`
+	Changed map[string][]string `json:"changed"` // style name -> changed fields
+	Default string              `json:"default,omitempty"`
+}
+
+// NewPromptStore loads the prompt configs in dir
+func NewPromptStore(dir string) (*PromptStore, error) {
+	store := &PromptStore{dir: dir}
+	// Taken first, so a file changed while loading is reloaded by Watch
+	store.loaded = store.fingerprint()
+	library, err := LoadPromptConfigs(dir)
+	if err != nil {
+		return nil, err
+	}
+	store.current.Store(library)
+	return store, nil
+}
+
+// Load returns the current prompt library
+func (s *PromptStore) Load() *models.PromptLibrary {
+	return s.current.Load()
+}
+
+// Reload re-reads the prompt directory and swaps in the new library. On error
+// the previous library stays in place.
+func (s *PromptStore) Reload() (PromptChanges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	library, err := LoadPromptConfigs(s.dir)
+	if err != nil {
+		return PromptChanges{}, err
+	}
+
+	changes := diffPromptLibraries(s.current.Load(), library)
+	s.current.Store(library)
+	return changes, nil
+}
+
+// Watch polls the prompt directory and reloads whenever a file is added,
+// removed or modified since the store was created, until stop is closed.
+// Failed reloads are logged and keep the previous library.
+func (s *PromptStore) Watch(interval time.Duration, stop <-chan struct{}) {
+	last := s.loaded
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		current := s.fingerprint()
+		if current == last {
+			continue
+		}
+		last = current
+
+		changes, err := s.Reload()
+		if err != nil {
+			log.Printf("Prompt config reload failed, keeping previous config: %v", err)
+			continue
+		}
+		log.Printf("Prompt config reloaded: added=%v removed=%v changed=%v", changes.Added, changes.Removed, changes.Changed)
+	}
+}
+
+// fingerprint summarizes the names, sizes and modification times of the YAML
+// files in the prompt directory
+func (s *PromptStore) fingerprint() string {
+	files, _ := filepath.Glob(filepath.Join(s.dir, "*.yaml"))
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// diffPromptLibraries lists the styles added, removed and changed between two
+// libraries, naming the YAML fields that differ for changed styles
+func diffPromptLibraries(old, updated *models.PromptLibrary) PromptChanges {
+	changes := PromptChanges{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: map[string][]string{},
+	}
+
+	for name, config := range updated.Configs {
+		previous, ok := old.Configs[name]
+		if !ok {
+			changes.Added = append(changes.Added, name)
+			continue
+		}
+
+		var fields []string
+		if previous.Description != config.Description {
+			fields = append(fields, "description")
+		}
+		if previous.Default != config.Default {
+			fields = append(fields, "default")
+		}
+		if !reflect.DeepEqual(previous.SystemPrompts, config.SystemPrompts) {
+			fields = append(fields, "system_prompts")
+		}
+		if previous.UserPromptTemplate != config.UserPromptTemplate {
+			fields = append(fields, "user_prompt_template")
+		}
+		if len(fields) > 0 {
+			changes.Changed[name] = fields
+		}
+	}
+
+	for name := range old.Configs {
+		if _, ok := updated.Configs[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+
+	if old.Default != updated.Default {
+		changes.Default = updated.Default
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	return changes
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// promptConfig is a YAML prompt config named name with one system prompt
+func promptConfig(name, systemPrompt string) string {
+	return "name: " + name + "\ndefault: true\nsystem_prompts:\n  - role: system\n    content: " + systemPrompt + "\nuser_prompt_template: Draw {{.Description}}\n"
+}
+
+// systemPrompt returns the system prompt of the default config in store
+func systemPrompt(t *testing.T, store *PromptStore) string {
+	t.Helper()
+	library := store.Load()
+	prompts := library.Get(library.Default).SystemPrompts
+	if len(prompts) != 1 {
+		t.Fatalf("default config has %d system prompts, want 1", len(prompts))
+	}
+	return prompts[0].Content
+}
+
+func TestPromptStoreReload(t *testing.T) {
+	dir := writePromptConfigs(t, promptConfig("Plain", "Draw well."))
+	store, err := NewPromptStore(dir)
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(promptConfig("Plain", "Draw better.")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("name: Line Art\nuser_prompt_template: Draw {{.Description}} in lines\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := store.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	want := PromptChanges{
+		Added:   []string{"Line Art"},
+		Removed: []string{},
+		Changed: map[string][]string{"Plain": {"system_prompts"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	if got := systemPrompt(t, store); got != "Draw better." {
+		t.Errorf("system prompt after Reload = %q, want Draw better.", got)
+	}
+
+	// An invalid config leaves the loaded library in place
+	before := store.Load()
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("name: [Plain\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reload(); err == nil || !strings.Contains(err.Error(), "a.yaml") {
+		t.Errorf("Reload of invalid YAML error = %v, want one naming a.yaml", err)
+	}
+	if store.Load() != before {
+		t.Error("a failed Reload replaced the library")
+	}
+}
+
+func TestPromptStoreWatch(t *testing.T) {
+	dir := writePromptConfigs(t, promptConfig("Plain", "Draw well."))
+	store, err := NewPromptStore(dir)
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go store.Watch(10*time.Millisecond, stop)
+
+	// waitFor polls until the default config has systemPrompt
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for systemPrompt(t, store) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("system prompt = %q after waiting, want %q", systemPrompt(t, store), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// The content's size differs, so the change shows even when the
+	// modification time does not
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(promptConfig("Plain", "Draw much better.")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("Draw much better.")
+
+	// A broken file is skipped, and fixing it is picked up again
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("name: [Plain\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := systemPrompt(t, store); got != "Draw much better." {
+		t.Errorf("system prompt after a broken edit = %q, want the previous one", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(promptConfig("Plain", "Draw the best.")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("Draw the best.")
+}
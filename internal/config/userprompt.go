@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"pelican-gallery/internal/models"
+)
+
+// legacyDescriptionPlaceholder is the placeholder user prompt templates used
+// before they were Go templates; it is rewritten to {{.Description}}
+const legacyDescriptionPlaceholder = "{art_work_description}"
+
+// maxUserPromptLength caps the output of a user prompt template, so a
+// runaway template in a prompt config cannot flood a generation request.
+// text/template bounds the depth of recursive templates itself, so with the
+// output capped execution always ends.
+const maxUserPromptLength = 64 * 1024
+
+// errUserPromptTooLong stops a template whose output exceeds maxUserPromptLength
+var errUserPromptTooLong = errors.New("user prompt is too long")
+
+// UserPromptData is what a user_prompt_template can refer to
+type UserPromptData struct {
+	// Description is the artwork description, the group's prompt
+	Description string
+	Title       string
+	Category    string
+}
+
+// ParseUserPrompt parses a user_prompt_template as a text/template, after
+// rewriting the legacy {art_work_description} placeholder. A trial run with
+// empty data catches references to unknown fields before a generation does.
+func ParseUserPrompt(text string) (*template.Template, error) {
+	text = strings.ReplaceAll(text, legacyDescriptionPlaceholder, "{{.Description}}")
+	tmpl, err := template.New("user_prompt_template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user_prompt_template: %w", err)
+	}
+	if _, err := executeUserPrompt(tmpl, UserPromptData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// FormatUserPrompt fills in the user_prompt_template of promptConfig.
+// Templates use Go text/template syntax with the fields of UserPromptData,
+// e.g. {{.Description}}; the legacy {art_work_description} still works. The
+// template parsed when the library was loaded is used; configs built in code
+// are parsed here. Output past maxUserPromptLength bytes is an error.
+func FormatUserPrompt(promptConfig *models.PromptConfig, data UserPromptData) (string, error) {
+	tmpl := promptConfig.UserPrompt
+	if tmpl == nil {
+		var err error
+		if tmpl, err = ParseUserPrompt(promptConfig.UserPromptTemplate); err != nil {
+			return "", err
+		}
+	}
+	return executeUserPrompt(tmpl, data)
+}
+
+// executeUserPrompt runs tmpl with data into a size-limited buffer
+func executeUserPrompt(tmpl *template.Template, data UserPromptData) (string, error) {
+	out := &limitedBuilder{max: maxUserPromptLength}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", fmt.Errorf("failed to execute user_prompt_template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// limitedBuilder is a strings.Builder that fails writes past max bytes
+type limitedBuilder struct {
+	strings.Builder
+	max int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, errUserPromptTooLong
+	}
+	return b.Builder.Write(p)
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestFormatUserPrompt(t *testing.T) {
+	data := UserPromptData{Description: "a pelican riding a bicycle", Title: "Pelican", Category: "Birds"}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"several fields", "Draw {{.Title}} ({{.Category}}): {{.Description}}", "Draw Pelican (Birds): a pelican riding a bicycle"},
+		{"legacy placeholder", "Create an SVG of {art_work_description}.", "Create an SVG of a pelican riding a bicycle."},
+		{"legacy placeholder twice", "{art_work_description} / {art_work_description}", "a pelican riding a bicycle / a pelican riding a bicycle"},
+		{"legacy and template", "{{.Title}}: {art_work_description}", "Pelican: a pelican riding a bicycle"},
+		{"conditional", "Draw {{.Description}}{{if .Category}} for the {{.Category}} category{{end}}", "Draw a pelican riding a bicycle for the Birds category"},
+		{"functions", `{{printf "%q" .Title}} {{len .Category}}`, `"Pelican" 5`},
+		{"no placeholders", "Draw anything", "Draw anything"},
+	}
+	for _, tt := range tests {
+		got, err := FormatUserPrompt(&models.PromptConfig{UserPromptTemplate: tt.template}, data)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: FormatUserPrompt = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	// Without a category the conditional part is left out
+	got, err := FormatUserPrompt(&models.PromptConfig{UserPromptTemplate: "Draw {{.Description}}{{if .Category}} for {{.Category}}{{end}}"}, UserPromptData{Description: "a heron"})
+	if err != nil || got != "Draw a heron" {
+		t.Errorf("FormatUserPrompt without category = %q, %v", got, err)
+	}
+}
+
+func TestFormatUserPromptErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		data     UserPromptData
+		want     string
+	}{
+		{"unclosed action", "Draw {{.Description", UserPromptData{}, "invalid user_prompt_template"},
+		{"unknown field", "Draw {{.Colour}}", UserPromptData{}, "failed to execute user_prompt_template"},
+		{"endless recursion", `{{define "loop"}}{{template "loop" .}}{{end}}{{template "loop" .}}`, UserPromptData{}, "exceeded maximum template depth"},
+		{"too long", "{{.Description}}{{.Description}}", UserPromptData{Description: strings.Repeat("x", maxUserPromptLength/2+1)}, "too long"},
+	}
+	for _, tt := range tests {
+		got, err := FormatUserPrompt(&models.PromptConfig{UserPromptTemplate: tt.template}, tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: FormatUserPrompt = %q, %v, want an error containing %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	_, err := FormatUserPrompt(&models.PromptConfig{UserPromptTemplate: "{{.Description}}{{.Description}}"}, UserPromptData{Description: strings.Repeat("x", maxUserPromptLength/2+1)})
+	if !errors.Is(err, errUserPromptTooLong) {
+		t.Errorf("oversized prompt error = %v, want errUserPromptTooLong", err)
+	}
+}
+
+func TestLoadPromptConfigsParsesUserPrompts(t *testing.T) {
+	dir := writePromptConfigs(t, "name: Plain\nuser_prompt_template: Draw {{.Title}}, {art_work_description}\n")
+	library, err := LoadPromptConfigs(dir)
+	if err != nil {
+		t.Fatalf("LoadPromptConfigs: %v", err)
+	}
+	promptConfig := library.Get("Plain")
+	if promptConfig.UserPrompt == nil {
+		t.Fatal("the user prompt template was not parsed on load")
+	}
+
+	// Generations execute the template parsed on load
+	promptConfig.UserPromptTemplate = "{{.Unparsed"
+	got, err := FormatUserPrompt(promptConfig, UserPromptData{Title: "Pelican", Description: "a pelican"})
+	if err != nil || got != "Draw Pelican, a pelican" {
+		t.Errorf("FormatUserPrompt = %q, %v; want the loaded template filled in", got, err)
+	}
+
+	// Templates that fail on any data are rejected when loading
+	dir = writePromptConfigs(t, "name: Plain\nuser_prompt_template: Draw {{.Colour}}\n")
+	if _, err := LoadPromptConfigs(dir); err == nil || !strings.Contains(err.Error(), "Colour") {
+		t.Errorf("LoadPromptConfigs with an unknown field error = %v", err)
+	}
+}
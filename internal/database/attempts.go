@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+
+	"pelican-gallery/internal/models"
+)
+
+// RecordGenerationAttempt stores the outcome of a generation call
+func (db *DB) RecordGenerationAttempt(attempt models.GenerationAttempt) error {
+	query := `
+	INSERT INTO generation_attempts (artwork_id, group_id, model, success, error_class, error, duration_ms, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := db.writer.Exec(query, attempt.ArtworkID, attempt.GroupID, attempt.Model, attempt.Success, attempt.ErrorClass, attempt.Error, attempt.DurationMS, attempt.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record generation attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ListGenerationErrorSummaries aggregates failed generation attempts by model and
+// error classification, returning the count and most recent example of each
+func (db *DB) ListGenerationErrorSummaries(filter models.GenerationErrorFilter) ([]models.GenerationErrorSummary, error) {
+	defer db.timeRead("ListGenerationErrorSummaries")()
+
+	where := `success = 0 AND created_at >= ? AND created_at <= ?`
+	args := []interface{}{filter.From.UTC(), filter.To.UTC()}
+
+	if filter.GroupID != 0 {
+		where += ` AND group_id = ?`
+		args = append(args, filter.GroupID)
+	}
+
+	if filter.Query != "" {
+		where += ` AND (error LIKE ? OR model LIKE ?)`
+		args = append(args, "%"+filter.Query+"%", "%"+filter.Query+"%")
+	}
+
+	query := fmt.Sprintf(`
+	WITH ranked AS (
+		SELECT model, error_class, error, artwork_id, group_id, created_at,
+			ROW_NUMBER() OVER (PARTITION BY model, error_class ORDER BY created_at DESC, id DESC) AS rn,
+			COUNT(*) OVER (PARTITION BY model, error_class) AS failures
+		FROM generation_attempts
+		WHERE %s
+	)
+	SELECT model, error_class, failures, error, artwork_id, group_id, created_at
+	FROM ranked
+	WHERE rn = 1
+	ORDER BY failures DESC, created_at DESC
+	`, where)
+
+	rows, err := db.reader.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query generation errors: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.GenerationErrorSummary
+	for rows.Next() {
+		var summary models.GenerationErrorSummary
+		err := rows.Scan(
+			&summary.Model,
+			&summary.ErrorClass,
+			&summary.Count,
+			&summary.LastError,
+			&summary.LastArtworkID,
+			&summary.LastGroupID,
+			&summary.LastSeenAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan generation error: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating generation error rows: %w", err)
+	}
+
+	return summaries, nil
+}
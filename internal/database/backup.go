@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// ErrInvalidSnapshot is returned when a file offered for restore is not a
+// healthy gallery database
+var ErrInvalidSnapshot = errors.New("invalid database snapshot")
+
+// An online backup copies backupStepPages pages at a time and pauses
+// between steps. The source is only locked while a step runs, so writes go
+// through in between; a write restarts the copy so the snapshot stays
+// consistent. Under a steady stream of writes the copy would never finish,
+// so after twice the steps a clean copy needs, the rest is copied in one
+// step, holding off writes for that long.
+const (
+	backupStepPages = 256
+	backupStepPause = 5 * time.Millisecond
+)
+
+// SQLite result codes a backup step retries on
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// BackupFilePrefix and BackupFileExt frame the timestamp in the name of a
+// snapshot, e.g. pelican-gallery-20240102-150405.db
+const (
+	BackupFilePrefix = "pelican-gallery-"
+	BackupFileExt    = ".db"
+)
+
+// backupTimeLayout is the timestamp in snapshot file names; it sorts by time
+const backupTimeLayout = "20060102-150405"
+
+// BackupFileName returns the file name of a snapshot taken at t
+func BackupFileName(t time.Time) string {
+	return BackupFilePrefix + t.UTC().Format(backupTimeLayout) + BackupFileExt
+}
+
+// sqliteConn is the part of the SQLite driver connection used to copy a
+// database page by page
+type sqliteConn interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Backup writes a consistent snapshot of the database to path, which must
+// not exist or be empty. It uses SQLite's online backup API on a read
+// connection, so it works on a read-only database, and writes usually wait
+// for one step of the copy at most.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	conn, err := db.reader.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	var pages int32
+	if err := conn.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return fmt.Errorf("failed to read the database size: %w", err)
+	}
+
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(sqliteConn)
+		if !ok {
+			return errors.New("the SQLite driver does not support online backups")
+		}
+		backup, err := c.NewBackup(path)
+		if err != nil {
+			return fmt.Errorf("failed to start backup: %w", err)
+		}
+		if err := copyPages(ctx, backup, backupStepPages, 2*(pages/backupStepPages+1)); err != nil {
+			backup.Finish()
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+		if err := backup.Finish(); err != nil {
+			return fmt.Errorf("failed to finish backup: %w", err)
+		}
+		return nil
+	})
+}
+
+// copyPages steps a backup to the end, pages at a time (-1 for all at
+// once), pausing between steps and retrying steps that find the database
+// locked. After maxSteps steps, the rest is copied in one step.
+func copyPages(ctx context.Context, backup *sqlite.Backup, pages int32, maxSteps int32) error {
+	for step := int32(1); ; step++ {
+		if step > maxSteps {
+			pages = -1
+		}
+		more, err := backup.Step(pages)
+		if err != nil && !isBusyError(err) {
+			return err
+		}
+		if err == nil && !more {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backupStepPause):
+		}
+	}
+}
+
+// isBusyError reports whether err is SQLite finding the database locked by
+// another connection (SQLITE_BUSY or SQLITE_LOCKED)
+func isBusyError(err error) bool {
+	var sqliteErr interface{ Code() int }
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == sqliteBusy || code == sqliteLocked
+}
+
+// BackupToDir writes a snapshot named after the current time into dir and
+// then deletes the oldest snapshots there beyond keep. The snapshot is
+// written under a temporary name first, so dir never holds a partial one.
+// It returns the path of the new snapshot.
+func (db *DB) BackupToDir(ctx context.Context, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	path := filepath.Join(dir, BackupFileName(time.Now()))
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := db.Backup(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to move snapshot into place: %w", err)
+	}
+
+	if err := pruneBackups(dir, keep); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// pruneBackups deletes the oldest snapshots in dir beyond keep. Only files
+// named like BackupFileName are considered.
+func pruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, BackupFilePrefix) && strings.HasSuffix(name, BackupFileExt) {
+			snapshots = append(snapshots, name)
+		}
+	}
+	sort.Strings(snapshots)
+
+	for len(snapshots) > max(keep, 1) {
+		if err := os.Remove(filepath.Join(dir, snapshots[0])); err != nil {
+			return fmt.Errorf("failed to delete old backup: %w", err)
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// ScheduleBackups calls BackupToDir every interval until ctx is done,
+// logging the outcome of each snapshot through logf
+func (db *DB) ScheduleBackups(ctx context.Context, interval time.Duration, dir string, keep int, logf func(format string, args ...interface{})) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		started := time.Now()
+		path, err := db.BackupToDir(ctx, dir, keep)
+		if err != nil {
+			logf("Scheduled backup failed: %v", err)
+			continue
+		}
+		logf("Backed up the database to %s in %s", path, time.Since(started).Round(time.Millisecond))
+	}
+}
+
+// CheckSnapshot opens the database at path read-only and checks that it
+// passes PRAGMA integrity_check and is a gallery database this build can
+// migrate. Problems with the file are reported as ErrInvalidSnapshot.
+func CheckSnapshot(path string) error {
+	snapshot, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	var result string
+	if err := snapshot.QueryRow(`PRAGMA integrity_check(1)`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidSnapshot, result)
+	}
+
+	var tables int
+	if err := snapshot.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('artwork_groups', 'artworks')`).Scan(&tables); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if tables != 2 {
+		return fmt.Errorf("%w: it has no artwork_groups and artworks tables", ErrInvalidSnapshot)
+	}
+
+	version, err := schemaVersion(snapshot)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if version > LatestSchemaVersion {
+		return fmt.Errorf("%w: its schema version %d is newer than this build's %d", ErrInvalidSnapshot, version, LatestSchemaVersion)
+	}
+
+	return nil
+}
+
+// Restore replaces the whole database with the snapshot at path, after
+// checking it with CheckSnapshot, then migrates it if it is older than this
+// build. The copy runs in one step on the writer, so it waits for the write
+// in progress and holds every other write until it is done.
+func (db *DB) Restore(ctx context.Context, path string) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if err := CheckSnapshot(path); err != nil {
+		return err
+	}
+
+	conn, err := db.writer.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the write connection: %w", err)
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(sqliteConn)
+		if !ok {
+			return errors.New("the SQLite driver does not support online backups")
+		}
+		restore, err := c.NewRestore("file:" + path + "?mode=ro")
+		if err != nil {
+			return fmt.Errorf("failed to start restore: %w", err)
+		}
+		if err := copyPages(ctx, restore, -1, 0); err != nil {
+			restore.Finish()
+			return fmt.Errorf("failed to restore database: %w", err)
+		}
+		if err := restore.Finish(); err != nil {
+			return fmt.Errorf("failed to finish restore: %w", err)
+		}
+		return nil
+	})
+	conn.Close()
+	if err != nil {
+		return err
+	}
+
+	return db.Migrate()
+}
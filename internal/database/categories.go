@@ -0,0 +1,209 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"pelican-gallery/internal/models"
+)
+
+// ErrCategoryNotFound is returned for a slug or name no category has had
+var ErrCategoryNotFound = errors.New("category not found")
+
+// defaultCategorySlug is the slug of a category whose name has no letters
+// or digits at all
+const defaultCategorySlug = "category"
+
+// slugFolds spells out letters that do not decompose into an ASCII letter
+// and a mark
+var slugFolds = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+}
+
+// slugify turns a category name into a URL path segment: lowercase letters
+// and digits separated by single hyphens. Accents on Latin letters are
+// dropped, so "Café" and "Cafe" share a base slug and the second gets a
+// suffix. Letters of other scripts are kept with their marks, so "日本" and
+// "Ελλάδα" get slugs of their own rather than all falling back to the
+// default.
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	ascii := false
+	for _, r := range strings.ToLower(norm.NFKD.String(name)) {
+		switch {
+		// Checked first, as the folded letters are letters too
+		case slugFolds[r] != "":
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			ascii = true
+			b.WriteString(slugFolds[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			ascii = r <= unicode.MaxASCII
+			b.WriteRune(r)
+		case unicode.IsMark(r):
+			// The accent of a decomposed Latin letter is dropped; marks
+			// other scripts need to spell a word are kept
+			if !ascii && b.Len() > 0 && !hyphen {
+				b.WriteRune(r)
+			}
+		default:
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return defaultCategorySlug
+	}
+	return norm.NFC.String(b.String())
+}
+
+// uniqueSlug returns base, or base with the lowest free suffix from 2, that
+// no other category uses or has used. Slugs the category with ownID had
+// before are free for it to take back.
+func uniqueSlug(q execQueryer, base string, ownID int) (string, error) {
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = base + "-" + strconv.Itoa(n)
+		}
+
+		var taken bool
+		err := q.QueryRow(`SELECT
+			EXISTS (SELECT 1 FROM categories WHERE slug = ? AND id != ?) OR
+			EXISTS (SELECT 1 FROM category_slug_history WHERE slug = ? AND category_id != ?)`,
+			slug, ownID, slug, ownID).Scan(&taken)
+		if err != nil {
+			return "", fmt.Errorf("failed to check category slug: %w", err)
+		}
+		if !taken {
+			return slug, nil
+		}
+	}
+}
+
+// ensureCategory gives the category name a slug unless it already has one,
+// and returns the category's ID. Groups without a category have none.
+func ensureCategory(q execQueryer, name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	var id int
+	err := q.QueryRow(`SELECT id FROM categories WHERE name = ?`, name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to look up category: %w", err)
+	}
+
+	slug, err := uniqueSlug(q, slugify(name), 0)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.Exec(`INSERT INTO categories (name, slug) VALUES (?, ?)`, name, slug)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create category: %w", err)
+	}
+	insertedID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return int(insertedID), nil
+}
+
+// moveCategorySlugs follows a rename of category from to to. A new name
+// takes over the category and gets a slug of its own; moving into an
+// existing category merges the two. Either way every slug from had keeps
+// leading to the groups, through category_slug_history.
+func moveCategorySlugs(q execQueryer, from, to string) error {
+	var fromID int
+	var fromSlug string
+	err := q.QueryRow(`SELECT id, slug FROM categories WHERE name = ?`, from).Scan(&fromID, &fromSlug)
+	if err == sql.ErrNoRows {
+		_, err = ensureCategory(q, to)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up category: %w", err)
+	}
+
+	var toID int
+	err = q.QueryRow(`SELECT id FROM categories WHERE name = ?`, to).Scan(&toID)
+	switch {
+	case err == sql.ErrNoRows:
+		return renameCategorySlug(q, fromID, fromSlug, to)
+	case err != nil:
+		return fmt.Errorf("failed to look up category: %w", err)
+	}
+
+	if _, err := q.Exec(`UPDATE category_slug_history SET category_id = ? WHERE category_id = ?`, toID, fromID); err != nil {
+		return fmt.Errorf("failed to move category slug history: %w", err)
+	}
+	if _, err := q.Exec(`INSERT INTO category_slug_history (slug, category_id) VALUES (?, ?)`, fromSlug, toID); err != nil {
+		return fmt.Errorf("failed to keep category slug: %w", err)
+	}
+	if _, err := q.Exec(`DELETE FROM categories WHERE id = ?`, fromID); err != nil {
+		return fmt.Errorf("failed to delete merged category: %w", err)
+	}
+	return nil
+}
+
+// renameCategorySlug renames the category with id and gives it the slug of
+// its new name, keeping the old slug in its history
+func renameCategorySlug(q execQueryer, id int, oldSlug, name string) error {
+	slug, err := uniqueSlug(q, slugify(name), id)
+	if err != nil {
+		return err
+	}
+	if slug != oldSlug {
+		if _, err := q.Exec(`DELETE FROM category_slug_history WHERE slug = ?`, slug); err != nil {
+			return fmt.Errorf("failed to take back category slug: %w", err)
+		}
+		if _, err := q.Exec(`INSERT INTO category_slug_history (slug, category_id) VALUES (?, ?)`, oldSlug, id); err != nil {
+			return fmt.Errorf("failed to keep category slug: %w", err)
+		}
+	}
+	if _, err := q.Exec(`UPDATE categories SET name = ?, slug = ? WHERE id = ?`, name, slug, id); err != nil {
+		return fmt.Errorf("failed to rename category: %w", err)
+	}
+	return nil
+}
+
+// ResolveCategorySlug returns the category a gallery URL names and whether
+// the URL used its current slug. A slug the category had before a rename or
+// merge, or the category's name as older URLs spelled it, resolves too, so
+// the caller can redirect to the current slug.
+func (db *DB) ResolveCategorySlug(slug string) (*models.Category, bool, error) {
+	defer db.timeRead("ResolveCategorySlug")()
+
+	var category models.Category
+	var priority int
+	err := db.reader.QueryRow(`
+		SELECT name, slug, 0 AS priority FROM categories WHERE slug = ?
+		UNION ALL
+		SELECT c.name, c.slug, 1 FROM category_slug_history h JOIN categories c ON c.id = h.category_id WHERE h.slug = ?
+		UNION ALL
+		SELECT name, slug, 2 FROM categories WHERE name = ?
+		ORDER BY priority
+		LIMIT 1`, slug, slug, slug).Scan(&category.Name, &category.Slug, &priority)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve category slug: %w", err)
+	}
+	return &category, priority == 0, nil
+}
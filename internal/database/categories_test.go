@@ -0,0 +1,246 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Birds", "birds"},
+		{"Abstract & Geometric", "abstract-geometric"},
+		{"  Sea   Birds  ", "sea-birds"},
+		{"Café", "cafe"},
+		{"Crème Brûlée", "creme-brulee"},
+		{"Ünïcödé 2025", "unicode-2025"},
+		{"Straße", "strasse"},
+		{"Æsop's Fables", "aesop-s-fables"},
+		{"Łódź", "lodz"},
+		{"Ελλάδα", "ελλάδα"},
+		{"Θάλασσα και Ήλιος", "θάλασσα-και-ήλιος"},
+		{"日本", "日本"},
+		{"日本 の 鳥", "日本-の-鳥"},
+		{"!!!", defaultCategorySlug},
+		{"&/?#", defaultCategorySlug},
+		{"", defaultCategorySlug},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.name); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// categorySlug returns the current slug of the category name
+func categorySlug(t *testing.T, db *DB, name string) string {
+	t.Helper()
+	var slug string
+	if err := db.writer.QueryRow(`SELECT slug FROM categories WHERE name = ?`, name).Scan(&slug); err != nil {
+		t.Fatalf("slug of %q: %v", name, err)
+	}
+	return slug
+}
+
+// checkResolve checks that slug resolves to the category name, and whether
+// it is that category's current slug
+func checkResolve(t *testing.T, db *DB, slug, wantName string, wantCurrent bool) {
+	t.Helper()
+	category, current, err := db.ResolveCategorySlug(slug)
+	if err != nil {
+		t.Errorf("ResolveCategorySlug(%q): %v", slug, err)
+		return
+	}
+	if category.Name != wantName || current != wantCurrent {
+		t.Errorf("ResolveCategorySlug(%q) = %q, current %v, want %q, current %v", slug, category.Name, current, wantName, wantCurrent)
+	}
+	if category.Slug != categorySlug(t, db, wantName) {
+		t.Errorf("ResolveCategorySlug(%q) has slug %q, not the current one", slug, category.Slug)
+	}
+}
+
+func TestCategorySlugCollisions(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"Cafe", "Café", "CAFÉ", "!!!", "???"} {
+		createTestGroup(t, db, models.ArtworkGroup{Title: name, Category: name})
+	}
+
+	want := map[string]string{
+		"Cafe": "cafe",
+		"Café": "cafe-2",
+		"CAFÉ": "cafe-3",
+		"!!!":  "category",
+		"???":  "category-2",
+	}
+	for name, slug := range want {
+		if got := categorySlug(t, db, name); got != slug {
+			t.Errorf("slug of %q = %q, want %q", name, got, slug)
+		}
+		checkResolve(t, db, slug, name, true)
+	}
+}
+
+func TestRenameCategoryKeepsOldSlugs(t *testing.T) {
+	db := newTestDB(t)
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican", Category: "Birds"})
+
+	if _, err := db.RenameCategory("Birds", "Sea Birds"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	checkResolve(t, db, "sea-birds", "Sea Birds", true)
+	checkResolve(t, db, "birds", "Sea Birds", false)
+
+	// A new category cannot take a slug that still redirects elsewhere
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Sparrow", Category: "Birds"})
+	if got := categorySlug(t, db, "Birds"); got != "birds-2" {
+		t.Errorf("slug of the new Birds = %q, want birds-2", got)
+	}
+	checkResolve(t, db, "birds", "Sea Birds", false)
+
+	// Renaming again keeps every earlier slug leading to the category
+	if _, err := db.RenameCategory("Sea Birds", "Seabirds"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	checkResolve(t, db, "seabirds", "Seabirds", true)
+	checkResolve(t, db, "sea-birds", "Seabirds", false)
+	checkResolve(t, db, "birds", "Seabirds", false)
+
+	// Renaming back takes the old slug back out of the history
+	if _, err := db.RenameCategory("Seabirds", "Sea Birds"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	checkResolve(t, db, "sea-birds", "Sea Birds", true)
+	checkResolve(t, db, "seabirds", "Sea Birds", false)
+	if n := countRows(t, db, "category_slug_history", "slug = ?", "sea-birds"); n != 0 {
+		t.Errorf("the slug taken back is still in the history %d time(s)", n)
+	}
+}
+
+func TestMergeCategoryKeepsSlugs(t *testing.T) {
+	db := newTestDB(t)
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Espresso", Category: "Cafe"})
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Latte", Category: "Café"})
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Mocha", Category: "Coffee"})
+
+	// Café had been renamed before, so it brings two slugs into the merge
+	if _, err := db.RenameCategory("Coffee", "Café Au Lait"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	if _, err := db.RenameCategory("Café Au Lait", "Cafe"); err != nil {
+		t.Fatalf("merge Café Au Lait: %v", err)
+	}
+	if _, err := db.RenameCategory("Café", "Cafe"); err != nil {
+		t.Fatalf("merge Café: %v", err)
+	}
+
+	checkResolve(t, db, "cafe", "Cafe", true)
+	for _, slug := range []string{"cafe-2", "cafe-au-lait", "coffee"} {
+		checkResolve(t, db, slug, "Cafe", false)
+	}
+	if n := countRows(t, db, "categories", "1"); n != 1 {
+		t.Errorf("%d categories left after merging, want 1", n)
+	}
+	if n := countRows(t, db, "artwork_groups", "category = ?", "Cafe"); n != 3 {
+		t.Errorf("Cafe has %d groups after merging, want 3", n)
+	}
+}
+
+func TestResolveCategorySlugPriorities(t *testing.T) {
+	db := newTestDB(t)
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican", Category: "Old"})
+	// A category named like the slug of another one
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Heron", Category: "old"})
+	createTestGroup(t, db, models.ArtworkGroup{Title: "Cube", Category: "Abstract & Geometric"})
+
+	// A current slug wins over a name
+	checkResolve(t, db, "old", "Old", true)
+	checkResolve(t, db, "old-2", "old", true)
+
+	// So does a slug from before a rename
+	if _, err := db.RenameCategory("Old", "New"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+	checkResolve(t, db, "old", "New", false)
+
+	// Names resolve as older URLs spelled them, to be redirected
+	checkResolve(t, db, "Abstract & Geometric", "Abstract & Geometric", false)
+
+	for _, slug := range []string{"", "missing", "abstract"} {
+		if _, _, err := db.ResolveCategorySlug(slug); !errors.Is(err, ErrCategoryNotFound) {
+			t.Errorf("ResolveCategorySlug(%q) error = %v, want ErrCategoryNotFound", slug, err)
+		}
+	}
+}
+
+func TestMigrateGivesCategoriesSlugs(t *testing.T) {
+	path := baselineDatabase(t)
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Categories get their slugs in the order they were first used
+	_, err = raw.Exec(`INSERT INTO artwork_groups (id, title, prompt, category)
+		VALUES (3, 'Espresso', 'Generate an SVG of an espresso', 'Café'),
+		       (4, 'Latte', 'Generate an SVG of a latte', 'Cafe'),
+		       (5, 'Crane', 'Generate an SVG of a crane', '日本'),
+		       (6, 'Mocha', 'Generate an SVG of a mocha', 'Café')`)
+	raw.Close()
+	if err != nil {
+		t.Fatalf("seed groups: %v", err)
+	}
+
+	db, err := New(path)
+	if err != nil {
+		t.Fatalf("New on a baseline database: %v", err)
+	}
+	defer db.Close()
+
+	want := map[string]string{"Birds": "birds", "Café": "cafe", "Cafe": "cafe-2", "日本": "日本"}
+	for name, slug := range want {
+		if got := categorySlug(t, db, name); got != slug {
+			t.Errorf("slug of %q = %q, want %q", name, got, slug)
+		}
+	}
+	if n := countRows(t, db, "categories", "1"); n != len(want) {
+		t.Errorf("%d categories after migrating, want %d", n, len(want))
+	}
+
+	categories, err := db.GetDistinctCategories()
+	if err != nil {
+		t.Fatalf("GetDistinctCategories: %v", err)
+	}
+	for _, c := range categories {
+		if c.Slug != want[c.Name] {
+			t.Errorf("GetDistinctCategories lists %q with slug %q, want %q", c.Name, c.Slug, want[c.Name])
+		}
+	}
+}
+
+func TestReslugDefaultCategories(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"!!!", "Ελλάδα", "日本", "Birds"} {
+		createTestGroup(t, db, models.ArtworkGroup{Title: name, Category: name})
+	}
+	// The slugs names without Latin letters got before migration 19
+	for name, slug := range map[string]string{"Ελλάδα": "category-2", "日本": "category-3"} {
+		if _, err := db.writer.Exec(`UPDATE categories SET slug = ? WHERE name = ?`, slug, name); err != nil {
+			t.Fatalf("set slug of %q: %v", name, err)
+		}
+	}
+
+	if err := db.WithTx(reslugDefaultCategories); err != nil {
+		t.Fatalf("reslugDefaultCategories: %v", err)
+	}
+
+	checkResolve(t, db, "category", "!!!", true)
+	checkResolve(t, db, "ελλάδα", "Ελλάδα", true)
+	checkResolve(t, db, "日本", "日本", true)
+	checkResolve(t, db, "birds", "Birds", true)
+	// Links to the default slugs keep working
+	checkResolve(t, db, "category-2", "Ελλάδα", false)
+	checkResolve(t, db, "category-3", "日本", false)
+}
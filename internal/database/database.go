@@ -1,94 +1,332 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"pelican-gallery/internal/models"
 
 	_ "modernc.org/sqlite"
 )
 
+// ErrReadOnly is returned by writes to a database opened read-only
+var ErrReadOnly = errors.New("database is read-only")
+
+// DB holds two handles on the same SQLite file: a single-connection writer
+// that serializes every write, and a pool of query-only connections for
+// reads, so page and list queries never queue behind a batch of writes.
 type DB struct {
-	conn *sql.DB
+	writer   pool
+	reader   pool
+	readOnly bool
+	slow     *slowQueryLog
+
+	observeRead func(query string, d time.Duration)
+	svgSaved    []func(artworkID int)
+
+	// revisionLimit is the number of replaced SVGs kept per artwork
+	revisionLimit int
 }
 
-// New creates a new database connection and initializes the schema
+// readerPoolSize bounds the concurrent read connections
+const readerPoolSize = 8
+
+// New creates a new database connection and migrates the schema to the
+// latest version
 func New(dbPath string) (*DB, error) {
-	conn, err := sql.Open("sqlite", dbPath)
+	readOnly := strings.Contains(dbPath, "mode=ro")
+
+	// In WAL mode a commit does not lock readers out, so page queries keep
+	// going while a batch generation saves SVGs. The mode is stored in the
+	// file, so the readers pick it up; a read-only open cannot change it.
+	writerDSN := withConnectionPragmas(dbPath)
+	if !readOnly {
+		writerDSN += "&_pragma=journal_mode(WAL)"
+	}
+	writer, err := sql.Open("sqlite", writerDSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	writer.SetMaxOpenConns(1)
+
+	slow := &slowQueryLog{}
+	db := &DB{
+		writer:   pool{DB: writer, name: "writer", slow: slow, readOnly: readOnly},
+		readOnly: readOnly,
+		slow:     slow,
+
+		revisionLimit: DefaultRevisionLimit,
+	}
 
-	db := &DB{conn: conn}
+	if err := db.Migrate(); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
 
-	if err := db.CreateTables(); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	// The reader is opened after the schema exists; query_only rejects any
+	// write that is accidentally routed to it
+	reader, err := sql.Open("sqlite", withConnectionPragmas(dbPath)+"&_pragma=query_only(1)")
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to open read connections: %w", err)
 	}
+	reader.SetMaxOpenConns(readerPoolSize)
+	db.reader = pool{DB: reader, name: "reader", slow: slow}
 
 	return db, nil
 }
 
+// IsReadOnly reports whether the database was opened read-only, in which
+// case every write fails with ErrReadOnly
+func (db *DB) IsReadOnly() bool {
+	return db.readOnly
+}
+
+// Ping checks that the database answers a trivial query on a read
+// connection. The writer is left alone, so a long write does not make the
+// database look unreachable.
+func (db *DB) Ping(ctx context.Context) error {
+	if err := db.reader.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach database: %w", err)
+	}
+	var one int
+	if err := db.reader.DB.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("failed to query database: %w", err)
+	}
+	return nil
+}
+
+// ObserveReads registers a callback receiving the duration of every read
+// query, labeled by the method that ran it
+func (db *DB) ObserveReads(observe func(query string, d time.Duration)) {
+	db.observeRead = observe
+}
+
+// OnSVGSaved registers a callback run after an artwork's SVG is replaced, so
+// anything derived from the old SVG can be dropped. Register callbacks
+// before serving; they run in the order registered.
+func (db *DB) OnSVGSaved(saved func(artworkID int)) {
+	db.svgSaved = append(db.svgSaved, saved)
+}
+
+// notifySVGSaved runs the OnSVGSaved callbacks for an artwork
+func (db *DB) notifySVGSaved(artworkID int) {
+	for _, saved := range db.svgSaved {
+		saved(artworkID)
+	}
+}
+
+// timeRead starts timing a read; call the returned function when it is done
+func (db *DB) timeRead(query string) func() {
+	started := time.Now()
+	return func() {
+		if db.observeRead != nil {
+			db.observeRead(query, time.Since(started))
+		}
+	}
+}
+
+// withConnectionPragmas appends the pragmas every pooled connection needs. A busy
+// timeout lets concurrent writers (e.g. batch generation) wait for the lock
+// instead of failing with SQLITE_BUSY, and foreign keys must be enabled per
+// connection for ON DELETE CASCADE to apply.
+func withConnectionPragmas(dsn string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
-	return db.conn.Close()
-}
-
-// CreateTables creates the necessary tables if they don't exist
-func (db *DB) CreateTables() error {
-	// Enable foreign key enforcement
-	_, err := db.conn.Exec("PRAGMA foreign_keys = ON;")
-	if err != nil {
-		return fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS artwork_groups (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		title TEXT NOT NULL,
-		prompt TEXT NOT NULL,
-		category TEXT NOT NULL DEFAULT '',
-        original_url TEXT NOT NULL DEFAULT '',
-        artist_name TEXT NOT NULL DEFAULT '',
-		original_artwork BLOB,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS artworks (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		group_id INTEGER NOT NULL,
-		model TEXT NOT NULL,
-		temperature REAL NOT NULL DEFAULT 0.0,
-		max_tokens INTEGER NOT NULL DEFAULT 0,
-		svg TEXT DEFAULT '',
-		featured BOOLEAN NOT NULL DEFAULT 0,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (group_id) REFERENCES artwork_groups(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_artworks_group_id ON artworks(group_id);
-	CREATE INDEX IF NOT EXISTS idx_artwork_groups_created_at ON artwork_groups(created_at);
-	CREATE INDEX IF NOT EXISTS idx_artworks_created_at ON artworks(created_at);
-	`
+	readErr := db.reader.Close()
+	if err := db.writer.Close(); err != nil {
+		return err
+	}
+	return readErr
+}
+
+// groupColumns is the column list shared by every query that returns artwork
+// groups; it must stay in sync with scanGroup. The original artwork blob lives
+// in its own table, so only its presence is selected here, and the category
+// slug is looked up in categories.
+const groupColumns = `id, title, prompt, category, COALESCE((SELECT slug FROM categories WHERE name = category), ''), original_url, artist_name, id IN (SELECT group_id FROM original_artworks), prompt_style, archived, sort_order, deleted_at, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// extraColumns scans the columns selected after a shared column list into
+// extra, so scanGroup and scanArtwork can be reused for wider rows
+type extraColumns struct {
+	rowScanner
+	extra []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.rowScanner.Scan(append(dest, e.extra...)...)
+}
+
+// scanGroup scans a row selected with groupColumns into an ArtworkGroup
+func scanGroup(row rowScanner) (models.ArtworkGroup, error) {
+	var group models.ArtworkGroup
+	err := row.Scan(
+		&group.ID,
+		&group.Title,
+		&group.Prompt,
+		&group.Category,
+		&group.CategorySlug,
+		&group.OriginalURL,
+		&group.ArtistName,
+		&group.HasOriginalArtwork,
+		&group.PromptStyle,
+		&group.Archived,
+		&group.SortOrder,
+		&group.DeletedAt,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	return group, err
+}
+
+// artworkColumns is the column list shared by every query that returns
+// artworks; it must stay in sync with scanArtwork.
+const artworkColumns = `id, group_id, model, temperature, max_tokens, ` + svgContent + `, featured, visibility, reasoning_effort, source, revision, sort_order, deleted_at, created_at, updated_at`
+
+// scanArtwork scans a row selected with artworkColumns into an Artwork,
+// decompressing its SVG
+func scanArtwork(row rowScanner) (models.Artwork, error) {
+	var artwork models.Artwork
+	var svg []byte
+	err := row.Scan(
+		&artwork.ID,
+		&artwork.GroupID,
+		&artwork.Model,
+		&artwork.Temperature,
+		&artwork.MaxTokens,
+		&svg,
+		&artwork.Featured,
+		&artwork.Visibility,
+		&artwork.ReasoningEffort,
+		&artwork.Source,
+		&artwork.Revision,
+		&artwork.SortOrder,
+		&artwork.DeletedAt,
+		&artwork.CreatedAt,
+		&artwork.UpdatedAt,
+	)
+	if err != nil {
+		return artwork, err
+	}
+	artwork.SVG, err = decompressSVG(svg)
+	return artwork, err
+}
 
-	_, err = db.conn.Exec(createTableSQL)
+// execer is satisfied by both the writer and a transaction on it
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// WithTx runs fn in a transaction on the writer, committing it when fn
+// returns nil and rolling it back otherwise, so a write of several steps is
+// applied whole or not at all. The writer has a single connection, which
+// the transaction holds: fn must use tx, never another method of db.
+func (db *DB) WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.writer.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
 // CreateGroup creates a new artwork group
 func (db *DB) CreateGroup(group models.ArtworkGroup) (int, error) {
+	var id int
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		id, err = insertGroup(tx, group)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// CreateGroupWithKey creates a group under an Idempotency-Key. If a group
+// was already created with the key, deleted or not, its ID is returned
+// instead and created is false, so a retried request creates one group.
+func (db *DB) CreateGroupWithKey(group models.ArtworkGroup, key string) (id int, created bool, err error) {
+	// Transactions on the writer run one at a time, so concurrent retries
+	// with the same key see each other's group
+	err = db.WithTx(func(tx *sql.Tx) error {
+		if id, err = groupIDByIdempotencyKey(tx, key); err != nil || id != 0 {
+			return err
+		}
+		if id, err = insertGroup(tx, group); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE artwork_groups SET idempotency_key = ? WHERE id = ?`, key, id); err != nil {
+			return fmt.Errorf("failed to store idempotency key: %w", err)
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return id, created, nil
+}
+
+// GroupIDByIdempotencyKey returns the ID of the group created with key,
+// deleted or not, or 0 if none was
+func (db *DB) GroupIDByIdempotencyKey(key string) (int, error) {
+	return groupIDByIdempotencyKey(db.writer, key)
+}
+
+// groupIDByIdempotencyKey is GroupIDByIdempotencyKey on the writer or a
+// transaction on it
+func groupIDByIdempotencyKey(q execQueryer, key string) (int, error) {
+	var id int
+	err := q.QueryRow(`SELECT id FROM artwork_groups WHERE idempotency_key = ?`, key).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	return id, nil
+}
+
+// insertGroup inserts a group, giving a new category a slug, and returns
+// its ID
+func insertGroup(ex execQueryer, group models.ArtworkGroup) (int, error) {
+	if _, err := ensureCategory(ex, group.Category); err != nil {
+		return 0, err
+	}
+
 	query := `
-		INSERT INTO artwork_groups (title, prompt, category, original_url, artist_name, original_artwork, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO artwork_groups (title, prompt, category, original_url, artist_name, prompt_style, archived, sort_order, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM artwork_groups), ?, ?)
 		`
 
-	result, err := db.conn.Exec(query, group.Title, group.Prompt, group.Category, group.OriginalURL, group.ArtistName, group.OriginalArtwork, group.CreatedAt, group.UpdatedAt)
+	result, err := ex.Exec(query, group.Title, group.Prompt, group.Category, group.OriginalURL, group.ArtistName, group.PromptStyle, group.Archived, group.CreatedAt, group.UpdatedAt)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create group: %w", err)
 	}
@@ -101,51 +339,95 @@ func (db *DB) CreateGroup(group models.ArtworkGroup) (int, error) {
 	return int(id), nil
 }
 
-// UpdateGroup updates an existing artwork group
+// UpdateGroup updates an existing artwork group, giving a new category a
+// slug
 func (db *DB) UpdateGroup(group models.ArtworkGroup) error {
-	query := `
+	return db.WithTx(func(tx *sql.Tx) error {
+		if _, err := ensureCategory(tx, group.Category); err != nil {
+			return err
+		}
+
+		query := `
 		UPDATE artwork_groups
-		SET title = ?, prompt = ?, category = ?, original_url = ?, artist_name = ?, original_artwork = ?, updated_at = ?
-		WHERE id = ?
+		SET title = ?, prompt = ?, category = ?, original_url = ?, artist_name = ?, prompt_style = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
 		`
 
-	result, err := db.conn.Exec(query, group.Title, group.Prompt, group.Category, group.OriginalURL, group.ArtistName, group.OriginalArtwork, group.UpdatedAt, group.ID)
-	if err != nil {
-		return fmt.Errorf("failed to update group: %w", err)
-	}
+		result, err := tx.Exec(query, group.Title, group.Prompt, group.Category, group.OriginalURL, group.ArtistName, group.PromptStyle, group.UpdatedAt, group.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update group: %w", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("group with ID %d not found", group.ID)
+		if rowsAffected == 0 {
+			return fmt.Errorf("group with ID %d not found", group.ID)
+		}
+
+		return nil
+	})
+}
+
+// SaveOriginalArtwork stores the reference image of a group, replacing any
+// previous upload
+func (db *DB) SaveOriginalArtwork(artwork models.OriginalArtwork) error {
+	return saveOriginalArtwork(db.writer, artwork)
+}
+
+// saveOriginalArtwork upserts the reference image of a group
+func saveOriginalArtwork(ex execer, artwork models.OriginalArtwork) error {
+	query := `
+		INSERT INTO original_artworks (group_id, content_type, data, thumbnail, uploaded_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(group_id) DO UPDATE SET
+			content_type = excluded.content_type,
+			data = excluded.data,
+			thumbnail = excluded.thumbnail,
+			uploaded_at = excluded.uploaded_at
+		`
+
+	if _, err := ex.Exec(query, artwork.GroupID, artwork.ContentType, artwork.Data, artwork.Thumbnail, artwork.UploadedAt); err != nil {
+		return fmt.Errorf("failed to save original artwork: %w", err)
 	}
 
 	return nil
 }
 
+// GetOriginalArtwork retrieves the reference image of a group
+func (db *DB) GetOriginalArtwork(groupID int) (*models.OriginalArtwork, error) {
+	defer db.timeRead("GetOriginalArtwork")()
+
+	query := `
+		SELECT group_id, content_type, data, thumbnail, uploaded_at
+		FROM original_artworks
+		WHERE group_id = ?
+		`
+
+	var artwork models.OriginalArtwork
+	err := db.reader.QueryRow(query, groupID).Scan(&artwork.GroupID, &artwork.ContentType, &artwork.Data, &artwork.Thumbnail, &artwork.UploadedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("original artwork not found")
+		}
+		return nil, fmt.Errorf("failed to get original artwork: %w", err)
+	}
+
+	return &artwork, nil
+}
+
 // GetGroup retrieves an artwork group by ID
 func (db *DB) GetGroup(id int) (*models.ArtworkGroup, error) {
-	query := `
-	   SELECT id, title, prompt, category, original_url, artist_name, original_artwork, created_at, updated_at
+	defer db.timeRead("GetGroup")()
+
+	query := `SELECT ` + groupColumns + `
 	   FROM artwork_groups
-	   WHERE id = ?
+	   WHERE id = ? AND deleted_at IS NULL
 	   `
 
-	var group models.ArtworkGroup
-	err := db.conn.QueryRow(query, id).Scan(
-		&group.ID,
-		&group.Title,
-		&group.Prompt,
-		&group.Category,
-		&group.OriginalURL,
-		&group.ArtistName,
-		&group.OriginalArtwork,
-		&group.CreatedAt,
-		&group.UpdatedAt,
-	)
+	group, err := scanGroup(db.reader.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -157,15 +439,99 @@ func (db *DB) GetGroup(id int) (*models.ArtworkGroup, error) {
 	return &group, nil
 }
 
+// FindGroupByPrompt returns a group of category whose prompt matches prompt
+// once both are trimmed, lowercased and have their runs of whitespace
+// collapsed, or nil when there is none. Archived groups count; the recycle
+// bin does not.
+func (db *DB) FindGroupByPrompt(prompt, category string) (*models.ArtworkGroup, error) {
+	defer db.timeRead("FindGroupByPrompt")()
+
+	// Whitespace cannot be collapsed in SQL, so the prompts of the category
+	// are compared here
+	rows, err := db.reader.Query(`SELECT id, prompt FROM artwork_groups
+		WHERE deleted_at IS NULL AND LOWER(TRIM(category)) = LOWER(TRIM(?))
+		ORDER BY id`, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups by prompt: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := normalizePrompt(prompt)
+	matchID := 0
+	for rows.Next() {
+		var id int
+		var candidate string
+		if err := rows.Scan(&id, &candidate); err != nil {
+			return nil, fmt.Errorf("failed to scan group prompt: %w", err)
+		}
+		if normalizePrompt(candidate) == wanted {
+			matchID = id
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group prompt rows: %w", err)
+	}
+
+	if matchID == 0 {
+		return nil, nil
+	}
+	return db.GetGroup(matchID)
+}
+
+// FindGroupByTitlePrompt returns a group, in any category, whose title and
+// prompt both match once case and whitespace are normalized, or nil if none
+func (db *DB) FindGroupByTitlePrompt(title, prompt string) (*models.ArtworkGroup, error) {
+	defer db.timeRead("FindGroupByTitlePrompt")()
+
+	// Whitespace cannot be collapsed in SQL, so every group is compared here
+	rows, err := db.reader.Query(`SELECT id, title, prompt FROM artwork_groups
+		WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups by title: %w", err)
+	}
+	defer rows.Close()
+
+	wantedTitle, wantedPrompt := normalizePrompt(title), normalizePrompt(prompt)
+	matchID := 0
+	for rows.Next() {
+		var id int
+		var candidateTitle, candidatePrompt string
+		if err := rows.Scan(&id, &candidateTitle, &candidatePrompt); err != nil {
+			return nil, fmt.Errorf("failed to scan group title: %w", err)
+		}
+		if normalizePrompt(candidateTitle) == wantedTitle && normalizePrompt(candidatePrompt) == wantedPrompt {
+			matchID = id
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group title rows: %w", err)
+	}
+
+	if matchID == 0 {
+		return nil, nil
+	}
+	return db.GetGroup(matchID)
+}
+
+// normalizePrompt lowercases a prompt and collapses its whitespace, so
+// prompts differing only in case or spacing compare equal
+func normalizePrompt(prompt string) string {
+	return strings.Join(strings.Fields(strings.ToLower(prompt)), " ")
+}
+
 // ListGroups retrieves all artwork groups
 func (db *DB) ListGroups() ([]models.ArtworkGroup, error) {
-	query := `
-	       SELECT id, title, prompt, category, original_url, artist_name, original_artwork, created_at, updated_at
+	defer db.timeRead("ListGroups")()
+
+	query := `SELECT ` + groupColumns + `
 	       FROM artwork_groups
-	       ORDER BY created_at ASC
+	       WHERE deleted_at IS NULL
+	       ORDER BY sort_order ASC, created_at ASC, id ASC
 	       `
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.reader.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query groups: %w", err)
 	}
@@ -173,18 +539,7 @@ func (db *DB) ListGroups() ([]models.ArtworkGroup, error) {
 
 	var groups []models.ArtworkGroup
 	for rows.Next() {
-		var group models.ArtworkGroup
-		err := rows.Scan(
-			&group.ID,
-			&group.Title,
-			&group.Prompt,
-			&group.Category,
-			&group.OriginalURL,
-			&group.ArtistName,
-			&group.OriginalArtwork,
-			&group.CreatedAt,
-			&group.UpdatedAt,
-		)
+		group, err := scanGroup(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan group: %w", err)
 		}
@@ -200,12 +555,37 @@ func (db *DB) ListGroups() ([]models.ArtworkGroup, error) {
 
 // CreateArtwork creates a new artwork
 func (db *DB) CreateArtwork(artwork models.Artwork) (int, error) {
+	return insertArtwork(db.writer, artwork)
+}
+
+// insertArtwork inserts an artwork and returns its ID
+func insertArtwork(ex execQueryer, artwork models.Artwork) (int, error) {
+	svgBlobID, err := storeSVG(ex, artwork.SVG)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-	INSERT INTO artworks (group_id, model, temperature, max_tokens, svg, featured, created_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO artworks (group_id, model, temperature, max_tokens, svg_blob_id, featured, visibility, reasoning_effort, source, revision, sort_order, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM artworks WHERE group_id = ?), ?, ?)
 	`
 
-	result, err := db.conn.Exec(query, artwork.GroupID, artwork.Model, artwork.Temperature, artwork.MaxTokens, artwork.SVG, artwork.Featured, artwork.CreatedAt, artwork.UpdatedAt)
+	visibility := artwork.Visibility
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+
+	source := artwork.Source
+	if source == "" {
+		source = models.SourceGenerated
+	}
+
+	revision := artwork.Revision
+	if revision < 1 {
+		revision = 1
+	}
+
+	result, err := ex.Exec(query, artwork.GroupID, artwork.Model, artwork.Temperature, artwork.MaxTokens, svgBlobID, artwork.Featured, string(visibility), artwork.ReasoningEffort, source, revision, artwork.GroupID, artwork.CreatedAt, artwork.UpdatedAt)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create artwork: %w", err)
 	}
@@ -218,26 +598,85 @@ func (db *DB) CreateArtwork(artwork models.Artwork) (int, error) {
 	return int(id), nil
 }
 
+// AddArtworkRevision creates a new artwork without an SVG that takes the
+// group, model and parameters of artwork, numbered with the next revision of
+// that model in the group, and returns it. The artwork itself is untouched.
+func (db *DB) AddArtworkRevision(artwork models.Artwork) (*models.Artwork, error) {
+	var created models.Artwork
+	err := db.WithTx(func(tx *sql.Tx) error {
+		// Artworks in the recycle bin keep their numbers, so none is reused
+		var revision int
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(revision), 0) + 1 FROM artworks WHERE group_id = ? AND model = ?`,
+			artwork.GroupID, artwork.Model).Scan(&revision); err != nil {
+			return fmt.Errorf("failed to number revision: %w", err)
+		}
+
+		now := time.Now()
+		created = models.Artwork{
+			GroupID:         artwork.GroupID,
+			Model:           artwork.Model,
+			Temperature:     artwork.Temperature,
+			MaxTokens:       artwork.MaxTokens,
+			Visibility:      artwork.Visibility,
+			ReasoningEffort: artwork.ReasoningEffort,
+			Source:          models.SourceGenerated,
+			Revision:        revision,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		id, err := insertArtwork(tx, created)
+		if err != nil {
+			return err
+		}
+		created.ID = id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListModelRevisions returns the active artworks of model in a group, by
+// revision
+func (db *DB) ListModelRevisions(groupID int, model string) ([]models.Artwork, error) {
+	defer db.timeRead("ListModelRevisions")()
+
+	rows, err := db.reader.Query(`SELECT `+artworkColumns+`
+		FROM artworks
+		WHERE group_id = ? AND model = ? AND deleted_at IS NULL
+		ORDER BY revision, id`, groupID, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	artworks := []models.Artwork{}
+	for rows.Next() {
+		artwork, err := scanArtwork(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artwork: %w", err)
+		}
+		artworks = append(artworks, artwork)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return artworks, nil
+}
+
 // GetArtwork retrieves an artwork by ID
 func (db *DB) GetArtwork(id int) (*models.Artwork, error) {
+	defer db.timeRead("GetArtwork")()
+
 	query := `
-	SELECT id, group_id, model, temperature, max_tokens, svg, featured, created_at, updated_at
+	SELECT ` + artworkColumns + `
 	FROM artworks
-	WHERE id = ?
+	WHERE id = ? AND deleted_at IS NULL
 	`
 
-	var artwork models.Artwork
-	err := db.conn.QueryRow(query, id).Scan(
-		&artwork.ID,
-		&artwork.GroupID,
-		&artwork.Model,
-		&artwork.Temperature,
-		&artwork.MaxTokens,
-		&artwork.SVG,
-		&artwork.Featured,
-		&artwork.CreatedAt,
-		&artwork.UpdatedAt,
-	)
+	artwork, err := scanArtwork(db.reader.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -251,14 +690,16 @@ func (db *DB) GetArtwork(id int) (*models.Artwork, error) {
 
 // ListArtworksByGroup retrieves all artworks for a group
 func (db *DB) ListArtworksByGroup(groupID int) ([]models.Artwork, error) {
+	defer db.timeRead("ListArtworksByGroup")()
+
 	query := `
-	SELECT id, group_id, model, temperature, max_tokens, svg, featured, created_at, updated_at
+	SELECT ` + artworkColumns + `
 	FROM artworks
-	WHERE group_id = ?
-	ORDER BY model ASC
+	WHERE group_id = ? AND deleted_at IS NULL
+	ORDER BY sort_order ASC, created_at ASC, id ASC
 	`
 
-	rows, err := db.conn.Query(query, groupID)
+	rows, err := db.reader.Query(query, groupID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query artworks: %w", err)
 	}
@@ -266,178 +707,910 @@ func (db *DB) ListArtworksByGroup(groupID int) ([]models.Artwork, error) {
 
 	var artworks []models.Artwork
 	for rows.Next() {
-		var artwork models.Artwork
-		err := rows.Scan(
-			&artwork.ID,
-			&artwork.GroupID,
-			&artwork.Model,
-			&artwork.Temperature,
-			&artwork.MaxTokens,
-			&artwork.SVG,
-			&artwork.Featured,
-			&artwork.CreatedAt,
-			&artwork.UpdatedAt,
-		)
+		artwork, err := scanArtwork(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artwork: %w", err)
+		}
+		artworks = append(artworks, artwork)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return artworks, nil
+}
+
+// ListArtworksAfter returns up to limit artworks with an ID greater than
+// afterID, ordered by ID, for walking the whole table in batches. Artworks
+// in the recycle bin are included since they can be restored.
+func (db *DB) ListArtworksAfter(afterID, limit int) ([]models.Artwork, error) {
+	defer db.timeRead("ListArtworksAfter")()
+
+	query := `
+	SELECT ` + artworkColumns + `
+	FROM artworks
+	WHERE id > ?
+	ORDER BY id ASC
+	LIMIT ?
+	`
+
+	rows, err := db.reader.Query(query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artworks: %w", err)
+	}
+	defer rows.Close()
+
+	var artworks []models.Artwork
+	for rows.Next() {
+		artwork, err := scanArtwork(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan artwork: %w", err)
 		}
-		artworks = append(artworks, artwork)
+		artworks = append(artworks, artwork)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return artworks, nil
+}
+
+// SaveArtworkSVG saves the SVG content for an artwork, keeping its source
+func (db *DB) SaveArtworkSVG(id int, svg string) error {
+	return db.saveSVG(id, svg, "")
+}
+
+// SaveGeneratedSVG saves a freshly generated SVG and marks the artwork as
+// generated, also when it was imported before
+func (db *DB) SaveGeneratedSVG(id int, svg string) error {
+	return db.saveSVG(id, svg, models.SourceGenerated)
+}
+
+// saveSVG stores the SVG of an artwork with a fresh thumbnail and, unless
+// source is empty, its source. The SVG it replaces is kept as a revision.
+func (db *DB) saveSVG(id int, svg, source string) error {
+	// Rasterizing is slow, so it happens before the write lock is taken
+	thumbnail, thumbnailWidth := renderThumbnail(id, svg)
+
+	err := db.WithTx(func(tx *sql.Tx) error {
+		released, err := db.keepRevision(tx, id, svg)
+		if err != nil {
+			return err
+		}
+
+		svgBlobID, err := storeSVG(tx, svg)
+		if err != nil {
+			return err
+		}
+
+		query := `
+		UPDATE artworks
+		SET svg_blob_id = ?, thumbnail = ?, thumbnail_width = ?, source = COALESCE(NULLIF(?, ''), source), updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+		`
+
+		result, err := tx.Exec(query, svgBlobID, thumbnail, thumbnailWidth, source, id)
+		if err != nil {
+			return fmt.Errorf("failed to save artwork SVG: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("artwork with ID %d not found", id)
+		}
+
+		// The replaced SVG usually lives on in the revision
+		if err := deleteSVGsIfUnused(tx, released); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	db.notifySVGSaved(id)
+
+	return nil
+}
+
+// DeleteArtwork moves an artwork to the recycle bin
+func (db *DB) DeleteArtwork(id int) error {
+	query := `UPDATE artworks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := db.writer.Exec(query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete artwork: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("artwork with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// RestoreArtwork takes an artwork out of the recycle bin. Artworks of a
+// deleted group come back with RestoreGroup instead.
+func (db *DB) RestoreArtwork(id int) error {
+	query := `
+	UPDATE artworks SET deleted_at = NULL
+	WHERE id = ? AND deleted_at IS NOT NULL
+	AND group_id IN (SELECT id FROM artwork_groups WHERE deleted_at IS NULL)
+	`
+
+	result, err := db.writer.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore artwork: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("deleted artwork with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// PurgeArtwork permanently deletes an artwork, whether or not it is in the
+// recycle bin. Its generation attempts go with it through ON DELETE CASCADE,
+// and its SVG unless another artwork has the same one.
+func (db *DB) PurgeArtwork(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		released, err := selectSVGBlobIDs(tx, `SELECT svg_blob_id FROM artworks WHERE id = ? AND svg_blob_id IS NOT NULL
+			UNION SELECT svg_blob_id FROM artwork_revisions WHERE artwork_id = ? AND svg_blob_id IS NOT NULL`, id, id)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`DELETE FROM artworks WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete artwork: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("artwork with ID %d not found", id)
+		}
+
+		if err := deleteSVGsIfUnused(tx, released); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// groupDependents lists every table holding rows that belong to a group, in
+// deletion order (children first). Each condition selects the rows of one
+// group; every "?" is bound to the group ID. Tables added later must be
+// listed here rather than relying on ON DELETE CASCADE alone.
+var groupDependents = []struct {
+	table, condition string
+}{
+	{"generation_attempts", "group_id = ? OR artwork_id IN (SELECT id FROM artworks WHERE group_id = ?)"},
+	{"votes", "group_id = ? OR winner_artwork_id IN (SELECT id FROM artworks WHERE group_id = ?) OR loser_artwork_id IN (SELECT id FROM artworks WHERE group_id = ?)"},
+	{"artwork_revisions", "artwork_id IN (SELECT id FROM artworks WHERE group_id = ?)"},
+	{"original_artworks", "group_id = ?"},
+	{"artworks", "group_id = ?"},
+}
+
+// DeleteGroup moves a group and its artworks to the recycle bin. The
+// artworks share the group's deletion time, so RestoreGroup brings back
+// exactly those and leaves artworks deleted earlier in the bin.
+func (db *DB) DeleteGroup(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		now := time.Now()
+		result, err := tx.Exec("UPDATE artwork_groups SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", now, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("group with ID %d not found", id)
+		}
+
+		if _, err := tx.Exec("UPDATE artworks SET deleted_at = ? WHERE group_id = ? AND deleted_at IS NULL", now, id); err != nil {
+			return fmt.Errorf("failed to delete group artworks: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RestoreGroup takes a group out of the recycle bin together with the
+// artworks that were deleted with it
+func (db *DB) RestoreGroup(id int) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		// Compare against the stored value so the timestamps match exactly
+		query := `
+		UPDATE artworks SET deleted_at = NULL
+		WHERE group_id = ? AND deleted_at = (SELECT deleted_at FROM artwork_groups WHERE id = ?)
+		`
+		if _, err := tx.Exec(query, id, id); err != nil {
+			return fmt.Errorf("failed to restore group artworks: %w", err)
+		}
+
+		result, err := tx.Exec("UPDATE artwork_groups SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+		if err != nil {
+			return fmt.Errorf("failed to restore group: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("deleted group with ID %d not found", id)
+		}
+
+		return nil
+	})
+}
+
+// ListRecycleBin returns the soft-deleted groups and the artworks deleted on
+// their own, most recently deleted first
+func (db *DB) ListRecycleBin() (*models.RecycleBin, error) {
+	defer db.timeRead("ListRecycleBin")()
+
+	bin := &models.RecycleBin{
+		Groups:   []models.ArtworkGroup{},
+		Artworks: []models.Artwork{},
+	}
+
+	rows, err := db.reader.Query(`SELECT ` + groupColumns + `
+	FROM artwork_groups
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted groups: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		bin.Groups = append(bin.Groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group rows: %w", err)
+	}
+
+	artworkRows, err := db.reader.Query(`
+	SELECT ` + artworkColumns + `
+	FROM artworks
+	WHERE deleted_at IS NOT NULL
+	AND group_id IN (SELECT id FROM artwork_groups WHERE deleted_at IS NULL)
+	ORDER BY deleted_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted artworks: %w", err)
+	}
+	defer artworkRows.Close()
+
+	for artworkRows.Next() {
+		artwork, err := scanArtwork(artworkRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artwork: %w", err)
+		}
+		bin.Artworks = append(bin.Artworks, artwork)
+	}
+
+	if err := artworkRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating artwork rows: %w", err)
+	}
+
+	return bin, nil
+}
+
+// DeleteGroupDeep permanently deletes a group and all of its dependent rows
+// in a single transaction, whether or not it is in the recycle bin. It
+// returns the number of rows removed per table, including artwork_groups
+// itself.
+func (db *DB) DeleteGroupDeep(id int) (map[string]int64, error) {
+	var deleted map[string]int64
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		if deleted, err = deleteGroupDependents(tx, id); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("DELETE FROM artwork_groups WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("group with ID %d not found", id)
+		}
+		deleted["artwork_groups"] = rowsAffected
+
+		if deleted["svg_blobs"], err = deleteUnusedSVGs(tx); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// groupSVGBlobs selects the SVG blobs of a group's artworks and their
+// revisions; both "?" are bound to the group ID
+const groupSVGBlobs = `SELECT svg_blob_id FROM artworks WHERE group_id = ? AND svg_blob_id IS NOT NULL
+	UNION SELECT svg_blob_id FROM artwork_revisions WHERE svg_blob_id IS NOT NULL
+	AND artwork_id IN (SELECT id FROM artworks WHERE group_id = ?)`
+
+// deleteGroupDependents removes every row belonging to a group, but not the
+// group itself, and returns the number of rows removed per table
+func deleteGroupDependents(ex execer, id int) (map[string]int64, error) {
+	deleted := make(map[string]int64, len(groupDependents)+1)
+	for _, dep := range groupDependents {
+		args := make([]interface{}, strings.Count(dep.condition, "?"))
+		for i := range args {
+			args[i] = id
+		}
+
+		result, err := ex.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", dep.table, dep.condition), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete from %s: %w", dep.table, err)
+		}
+		if deleted[dep.table], err = result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected for %s: %w", dep.table, err)
+		}
+	}
+	return deleted, nil
+}
+
+// ImportGroups creates the given groups with their artworks and reference
+// images in a single transaction; any error rolls back the whole import. A
+// group whose title matches an existing group is skipped, or with overwrite
+// replaces that group's fields, artworks and reference image while keeping
+// its ID. Titles are matched in order, so a title repeated within the import
+// also conflicts with its earlier occurrence.
+func (db *DB) ImportGroups(groups []models.ImportGroup, overwrite bool) (*models.ImportResult, error) {
+	result := &models.ImportResult{}
+	err := db.WithTx(func(tx *sql.Tx) error {
+		// SVGs of overwritten groups, unused afterwards unless shared
+		var released []int64
+		for _, entry := range groups {
+			group := entry.Group
+
+			var existingID int
+			err := tx.QueryRow("SELECT id FROM artwork_groups WHERE title = ? AND deleted_at IS NULL ORDER BY id LIMIT 1", group.Title).Scan(&existingID)
+			switch {
+			case err == sql.ErrNoRows:
+				if group.ID, err = insertGroup(tx, group); err != nil {
+					return fmt.Errorf("failed to import group %q: %w", group.Title, err)
+				}
+			case err != nil:
+				return fmt.Errorf("failed to look up group %q: %w", group.Title, err)
+			case !overwrite:
+				result.GroupsSkipped++
+				result.ArtworksSkipped += len(entry.Artworks)
+				continue
+			default:
+				group.ID = existingID
+				ids, err := selectSVGBlobIDs(tx, groupSVGBlobs, existingID, existingID)
+				if err != nil {
+					return err
+				}
+				released = append(released, ids...)
+				if err := overwriteGroup(tx, group); err != nil {
+					return err
+				}
+				result.GroupsOverwritten++
+			}
+			result.GroupsImported++
+
+			if entry.OriginalArtwork != nil {
+				original := *entry.OriginalArtwork
+				original.GroupID = group.ID
+				if err := saveOriginalArtwork(tx, original); err != nil {
+					return fmt.Errorf("failed to import original artwork of group %q: %w", group.Title, err)
+				}
+			}
+
+			for _, artwork := range entry.Artworks {
+				artwork.GroupID = group.ID
+				if _, err := insertArtwork(tx, artwork); err != nil {
+					return fmt.Errorf("failed to import %s artwork of group %q: %w", artwork.Model, group.Title, err)
+				}
+				result.ArtworksImported++
+			}
+		}
+
+		// Overwritten groups may have left SVGs behind
+		if err := deleteSVGsIfUnused(tx, released); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CreateGroups inserts groups in one transaction, skipping any whose title
+// matches an existing group or one inserted before it. It returns the ID of
+// each group in order, 0 for a skipped one.
+func (db *DB) CreateGroups(groups []models.ArtworkGroup) ([]int, error) {
+	ids := make([]int, len(groups))
+	err := db.WithTx(func(tx *sql.Tx) error {
+		for i, group := range groups {
+			var existingID int
+			err := tx.QueryRow("SELECT id FROM artwork_groups WHERE title = ? AND deleted_at IS NULL ORDER BY id LIMIT 1", group.Title).Scan(&existingID)
+			switch {
+			case err == sql.ErrNoRows:
+				if ids[i], err = insertGroup(tx, group); err != nil {
+					return fmt.Errorf("failed to create group %q: %w", group.Title, err)
+				}
+			case err != nil:
+				return fmt.Errorf("failed to look up group %q: %w", group.Title, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// overwriteGroup replaces the fields of an existing group and removes its
+// dependent rows, leaving it ready for the imported artworks
+func overwriteGroup(ex execQueryer, group models.ArtworkGroup) error {
+	if _, err := ensureCategory(ex, group.Category); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE artwork_groups
+		SET title = ?, prompt = ?, category = ?, original_url = ?, artist_name = ?, prompt_style = ?, archived = ?, created_at = ?, updated_at = ?
+		WHERE id = ?
+		`
+
+	if _, err := ex.Exec(query, group.Title, group.Prompt, group.Category, group.OriginalURL, group.ArtistName, group.PromptStyle, group.Archived, group.CreatedAt, group.UpdatedAt, group.ID); err != nil {
+		return fmt.Errorf("failed to overwrite group %q: %w", group.Title, err)
+	}
+
+	if _, err := deleteGroupDependents(ex, group.ID); err != nil {
+		return fmt.Errorf("failed to clear group %q: %w", group.Title, err)
+	}
+
+	return nil
+}
+
+// SetGroupArchived archives or unarchives a group. Archived groups keep all
+// their artworks but are hidden from the gallery.
+func (db *DB) SetGroupArchived(id int, archived bool) error {
+	result, err := db.writer.Exec("UPDATE artwork_groups SET archived = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", archived, id)
+	if err != nil {
+		return fmt.Errorf("failed to update group archive flag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("group with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// ArchiveGroup hides a group from the gallery without deleting it
+func (db *DB) ArchiveGroup(id int) error {
+	return db.SetGroupArchived(id, true)
+}
+
+// UnarchiveGroup makes an archived group visible in the gallery again
+func (db *DB) UnarchiveGroup(id int) error {
+	return db.SetGroupArchived(id, false)
+}
+
+// CountGroupsInCategory returns the number of groups, deleted ones included,
+// filed under category
+func (db *DB) CountGroupsInCategory(category string) (int, error) {
+	defer db.timeRead("CountGroupsInCategory")()
+
+	var count int
+	if err := db.reader.QueryRow("SELECT COUNT(*) FROM artwork_groups WHERE category = ?", category).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count groups in category: %w", err)
+	}
+	return count, nil
+}
+
+// RenameCategory moves every group, deleted ones included, from one category
+// to another and returns the number of groups moved. Moving into an existing
+// category merges the two. The slugs of the old category keep leading to
+// the moved groups.
+func (db *DB) RenameCategory(from, to string) (int64, error) {
+	if from == to {
+		return 0, nil
+	}
+
+	var rowsAffected int64
+	err := db.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE artwork_groups SET category = ?, updated_at = CURRENT_TIMESTAMP WHERE category = ?", to, from)
+		if err != nil {
+			return fmt.Errorf("failed to rename category: %w", err)
+		}
+
+		if rowsAffected, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		return moveCategorySlugs(tx, from, to)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// UpdateArtwork updates the generation parameters of an artwork
+func (db *DB) UpdateArtwork(id int, temperature float64, maxTokens int, reasoningEffort string) error {
+	query := `
+	UPDATE artworks
+	SET temperature = ?, max_tokens = ?, reasoning_effort = ?, updated_at = CURRENT_TIMESTAMP
+	WHERE id = ? AND deleted_at IS NULL
+	`
+
+	result, err := db.writer.Exec(query, temperature, maxTokens, reasoningEffort, id)
+	if err != nil {
+		return fmt.Errorf("failed to update artwork: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("artwork with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// SetArtworkVisibility changes where an artwork may be shown
+func (db *DB) SetArtworkVisibility(id int, visibility models.Visibility) error {
+	result, err := db.writer.Exec("UPDATE artworks SET visibility = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", string(visibility), id)
+	if err != nil {
+		return fmt.Errorf("failed to update artwork visibility: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("artwork with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// SetArtworkModel changes the model an artwork is attributed to. The SVG is
+// left as it is.
+func (db *DB) SetArtworkModel(id int, model string) error {
+	result, err := db.writer.Exec("UPDATE artworks SET model = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", model, id)
+	if err != nil {
+		return fmt.Errorf("failed to update artwork model: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("artwork with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// SetFeaturedArtwork sets an artwork as featured and unsets all others in
+// the same group, or with global in every group, making it the only
+// featured artwork
+func (db *DB) SetFeaturedArtwork(artworkID int, global bool) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		// First, get the group_id for this artwork
+		var groupID int
+		err := tx.QueryRow("SELECT group_id FROM artworks WHERE id = ? AND deleted_at IS NULL", artworkID).Scan(&groupID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("artwork with ID %d not found", artworkID)
+			}
+			return fmt.Errorf("failed to get artwork group: %w", err)
+		}
+
+		// Unset all featured artworks in this group, or everywhere
+		if global {
+			_, err = tx.Exec("UPDATE artworks SET featured = 0 WHERE featured = 1")
+		} else {
+			_, err = tx.Exec("UPDATE artworks SET featured = 0 WHERE group_id = ?", groupID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unset featured artworks: %w", err)
+		}
+
+		// Set this artwork as featured
+		result, err := tx.Exec("UPDATE artworks SET featured = 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", artworkID)
+		if err != nil {
+			return fmt.Errorf("failed to set artwork as featured: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("artwork with ID %d not found", artworkID)
+		}
+
+		return nil
+	})
+}
+
+// visibilitiesIn returns the placeholders of an IN list matching the
+// visibilities shown in scope, and their arguments
+func visibilitiesIn(scope models.ViewScope) (string, []interface{}) {
+	var placeholders []string
+	var args []interface{}
+	for _, visibility := range []models.Visibility{models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic} {
+		if visibility.VisibleIn(scope) {
+			placeholders = append(placeholders, "?")
+			args = append(args, visibility)
+		}
 	}
+	return strings.Join(placeholders, ", "), args
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+// ListGroupsWithCounts returns every group with the number of its artworks
+// that are visible in scope, and how many of those have an SVG. A non-empty
+// category limits the list to that category. Archived groups are included,
+// like ListGroups. Groups are listed in sortBy order.
+func (db *DB) ListGroupsWithCounts(category string, scope models.ViewScope, sortBy models.GroupSort) ([]models.GroupSummary, error) {
+	defer db.timeRead("ListGroupsWithCounts")()
+
+	orderBy, err := groupOrderBy(sortBy)
+	if err != nil {
+		return nil, err
 	}
 
-	return artworks, nil
-}
+	visible, args := visibilitiesIn(scope)
 
-// Artwork parameters are stored in `temperature` and `max_tokens` columns.
+	query := `SELECT ` + groupColumns + `, COALESCE(counts.total, 0), COALESCE(counts.generated, 0)
+		FROM artwork_groups
+		LEFT JOIN (
+			SELECT group_id, COUNT(*) AS total, SUM(svg_blob_id IS NOT NULL) AS generated
+			FROM artworks
+			WHERE deleted_at IS NULL AND visibility IN (` + visible + `)
+			GROUP BY group_id
+		) counts ON counts.group_id = artwork_groups.id`
 
-// SaveArtworkSVG saves the SVG content for an artwork
-func (db *DB) SaveArtworkSVG(id int, svg string) error {
-	query := `
-	UPDATE artworks
-	SET svg = ?, updated_at = CURRENT_TIMESTAMP
-	WHERE id = ?
-	`
+	conditions := []string{`deleted_at IS NULL`}
+	if category != "" {
+		conditions = append(conditions, `category = ?`)
+		args = append(args, category)
+	}
+	query += ` WHERE ` + strings.Join(conditions, " AND ")
+	query += orderBy
 
-	result, err := db.conn.Exec(query, svg, id)
+	rows, err := db.reader.Query(query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to save artwork SVG: %w", err)
+		return nil, fmt.Errorf("failed to query groups: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	var groups []models.GroupSummary
+	for rows.Next() {
+		var summary models.GroupSummary
+		summary.ArtworkGroup, err = scanGroup(extraColumns{rows, []interface{}{&summary.ArtworkCount, &summary.GeneratedCount}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, summary)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("artwork with ID %d not found", id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return nil
+	return groups, nil
 }
 
-// DeleteArtwork deletes an artwork by ID
-func (db *DB) DeleteArtwork(id int) error {
-	query := `DELETE FROM artworks WHERE id = ?`
+// ListGroupIDsWithUpdatedAt returns the ID, category and last change of
+// every active group, the ones shown in the gallery, by ID
+func (db *DB) ListGroupIDsWithUpdatedAt() ([]models.GroupStamp, error) {
+	defer db.timeRead("ListGroupIDsWithUpdatedAt")()
 
-	result, err := db.conn.Exec(query, id)
+	rows, err := db.reader.Query(`SELECT id, category, updated_at FROM artwork_groups WHERE archived = 0 AND deleted_at IS NULL ORDER BY id`)
 	if err != nil {
-		return fmt.Errorf("failed to delete artwork: %w", err)
+		return nil, fmt.Errorf("failed to query group IDs: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	var stamps []models.GroupStamp
+	for rows.Next() {
+		var stamp models.GroupStamp
+		if err := rows.Scan(&stamp.ID, &stamp.Category, &stamp.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group ID: %w", err)
+		}
+		stamps = append(stamps, stamp)
 	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("artwork with ID %d not found", id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group ID rows: %w", err)
 	}
 
-	return nil
+	return stamps, nil
 }
 
-// DeleteGroup deletes a group by ID (cascades to delete all associated artworks)
-func (db *DB) DeleteGroup(id int) error {
-	query := `DELETE FROM artwork_groups WHERE id = ?`
+// ListRecentGroups returns up to limit active groups with at least one
+// public generated artwork, most recently created first
+func (db *DB) ListRecentGroups(limit int) ([]models.ArtworkGroup, error) {
+	defer db.timeRead("ListRecentGroups")()
 
-	result, err := db.conn.Exec(query, id)
+	rows, err := db.reader.Query(`SELECT `+groupColumns+`
+		FROM artwork_groups g
+		WHERE archived = 0 AND deleted_at IS NULL
+		AND EXISTS (
+			SELECT 1 FROM artworks a
+			WHERE a.group_id = g.id AND a.deleted_at IS NULL AND a.visibility = 'public' AND a.svg_blob_id IS NOT NULL
+		)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, limit)
 	if err != nil {
-		return fmt.Errorf("failed to delete group: %w", err)
+		return nil, fmt.Errorf("failed to query recent groups: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	var groups []models.ArtworkGroup
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
 	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("group with ID %d not found", id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group rows: %w", err)
 	}
 
-	return nil
+	return groups, nil
 }
 
-// UpdateArtwork updates temperature and max_tokens for an artwork
-func (db *DB) UpdateArtwork(id int, temperature float64, maxTokens int) error {
-	query := `
-	UPDATE artworks
-	SET temperature = ?, max_tokens = ?, updated_at = CURRENT_TIMESTAMP
-	WHERE id = ?
-	`
+// GetFeaturedGroups returns up to limit active groups with a public featured
+// artwork, the group whose artwork was featured most recently first
+func (db *DB) GetFeaturedGroups(limit int) ([]models.ArtworkGroup, error) {
+	defer db.timeRead("GetFeaturedGroups")()
 
-	result, err := db.conn.Exec(query, temperature, maxTokens, id)
+	rows, err := db.reader.Query(`SELECT `+groupColumns+`
+		FROM artwork_groups g
+		JOIN (
+			SELECT group_id, MAX(updated_at) AS featured_at FROM artworks
+			WHERE featured = 1 AND deleted_at IS NULL AND visibility = 'public' AND svg_blob_id IS NOT NULL
+			GROUP BY group_id
+		) f ON f.group_id = g.id
+		WHERE archived = 0 AND deleted_at IS NULL
+		ORDER BY f.featured_at DESC, id DESC
+		LIMIT ?`, limit)
 	if err != nil {
-		return fmt.Errorf("failed to update artwork: %w", err)
+		return nil, fmt.Errorf("failed to query featured groups: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	var groups []models.ArtworkGroup
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
 	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("artwork with ID %d not found", id)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group rows: %w", err)
 	}
 
-	return nil
+	return groups, nil
 }
 
-// SetFeaturedArtwork sets an artwork as featured and unsets all others in the same group
-func (db *DB) SetFeaturedArtwork(artworkID int) error {
-	// First, get the group_id for this artwork
-	var groupID int
-	err := db.conn.QueryRow("SELECT group_id FROM artworks WHERE id = ?", artworkID).Scan(&groupID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("artwork with ID %d not found", artworkID)
-		}
-		return fmt.Errorf("failed to get artwork group: %w", err)
-	}
+// groupOrder is the ORDER BY clause of each group sort. Only these fixed
+// clauses are ever put into a query.
+var groupOrder = map[models.GroupSort]string{
+	models.GroupSortManual: `sort_order ASC, created_at ASC, id ASC`,
+	models.GroupSortNewest: `created_at DESC, id DESC`,
+	models.GroupSortOldest: `created_at ASC, id ASC`,
+	models.GroupSortTitle:  `title COLLATE NOCASE ASC, id ASC`,
+}
 
-	// Unset all featured artworks in this group
-	_, err = db.conn.Exec("UPDATE artworks SET featured = 0 WHERE group_id = ?", groupID)
-	if err != nil {
-		return fmt.Errorf("failed to unset featured artworks: %w", err)
+// groupOrderBy returns the ORDER BY clause for sortBy
+func groupOrderBy(sortBy models.GroupSort) (string, error) {
+	order, ok := groupOrder[sortBy]
+	if !ok {
+		return "", fmt.Errorf("unknown group sort %q", sortBy)
 	}
+	return ` ORDER BY ` + order, nil
+}
 
-	// Set this artwork as featured
-	result, err := db.conn.Exec("UPDATE artworks SET featured = 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", artworkID)
-	if err != nil {
-		return fmt.Errorf("failed to set artwork as featured: %w", err)
-	}
+// ListGroupsWithArtworks retrieves groups with their associated artworks
+// If category is not empty, filters groups by category. Archived groups are
+// left out unless includeArchived is set. Groups are listed in sortBy order.
+func (db *DB) ListGroupsWithArtworks(category string, includeArchived bool, sortBy models.GroupSort) ([]models.ArtworkGroup, map[int][]models.Artwork, error) {
+	defer db.timeRead("ListGroupsWithArtworks")()
 
-	rowsAffected, err := result.RowsAffected()
+	orderBy, err := groupOrderBy(sortBy)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("artwork with ID %d not found", artworkID)
+		return nil, nil, err
 	}
 
-	return nil
-}
-
-// ListGroupsWithArtworks retrieves groups with their associated artworks
-// If category is not empty, filters groups by category
-func (db *DB) ListGroupsWithArtworks(category string) ([]models.ArtworkGroup, map[int][]models.Artwork, error) {
-	// Build query with optional category filter
-	query := `
-		SELECT id, title, prompt, category, original_url, artist_name, original_artwork, created_at, updated_at
+	// Build query with optional category and archive filters
+	query := `SELECT ` + groupColumns + `
 		FROM artwork_groups`
 
+	conditions := []string{`deleted_at IS NULL`}
 	var args []interface{}
 	if category != "" {
-		query += ` WHERE category = ?`
+		conditions = append(conditions, `category = ?`)
 		args = append(args, category)
 	}
+	if !includeArchived {
+		conditions = append(conditions, `archived = 0`)
+	}
+	query += ` WHERE ` + strings.Join(conditions, " AND ")
+	query += orderBy
 
-	query += ` ORDER BY created_at ASC`
-
-	rows, err := db.conn.Query(query, args...)
+	rows, err := db.reader.Query(query, args...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query groups: %w", err)
 	}
@@ -446,18 +1619,7 @@ func (db *DB) ListGroupsWithArtworks(category string) ([]models.ArtworkGroup, ma
 	var groups []models.ArtworkGroup
 	var groupIDs []int
 	for rows.Next() {
-		var group models.ArtworkGroup
-		err := rows.Scan(
-			&group.ID,
-			&group.Title,
-			&group.Prompt,
-			&group.Category,
-			&group.OriginalURL,
-			&group.ArtistName,
-			&group.OriginalArtwork,
-			&group.CreatedAt,
-			&group.UpdatedAt,
-		)
+		group, err := scanGroup(rows)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to scan group: %w", err)
 		}
@@ -487,10 +1649,10 @@ func (db *DB) ListGroupsWithArtworks(category string) ([]models.ArtworkGroup, ma
 	}
 
 	artworkQuery := fmt.Sprintf(`
-	SELECT id, group_id, model, temperature, max_tokens, svg, featured, created_at, updated_at
+	SELECT `+artworkColumns+`
 	FROM artworks
-	WHERE group_id IN (%s)
-	ORDER BY group_id, model ASC
+	WHERE group_id IN (%s) AND deleted_at IS NULL
+	ORDER BY group_id, sort_order ASC, created_at ASC, id ASC
 	`, placeholders)
 
 	// Convert groupIDs to interface{} slice for query
@@ -499,25 +1661,14 @@ func (db *DB) ListGroupsWithArtworks(category string) ([]models.ArtworkGroup, ma
 		artworkArgs[i] = id
 	}
 
-	artworkRows, err := db.conn.Query(artworkQuery, artworkArgs...)
+	artworkRows, err := db.reader.Query(artworkQuery, artworkArgs...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query artworks: %w", err)
 	}
 	defer artworkRows.Close()
 
 	for artworkRows.Next() {
-		var artwork models.Artwork
-		err := artworkRows.Scan(
-			&artwork.ID,
-			&artwork.GroupID,
-			&artwork.Model,
-			&artwork.Temperature,
-			&artwork.MaxTokens,
-			&artwork.SVG,
-			&artwork.Featured,
-			&artwork.CreatedAt,
-			&artwork.UpdatedAt,
-		)
+		artwork, err := scanArtwork(artworkRows)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to scan artwork: %w", err)
 		}
@@ -531,25 +1682,194 @@ func (db *DB) ListGroupsWithArtworks(category string) ([]models.ArtworkGroup, ma
 	return groups, artworkMap, nil
 }
 
-// GetDistinctCategories returns all distinct categories from artwork groups
-func (db *DB) GetDistinctCategories() ([]string, error) {
+// ListModelUsage returns every model referenced by stored artworks with the
+// number of artworks using it and when the newest of them was created
+func (db *DB) ListModelUsage() ([]models.ModelUsage, error) {
+	defer db.timeRead("ListModelUsage")()
+
+	// created_at is selected as a column rather than through MAX() so the
+	// driver still scans it as a time
+	query := `
+	SELECT model, artwork_count, created_at
+	FROM (
+		SELECT model, created_at,
+			COUNT(*) OVER (PARTITION BY model) AS artwork_count,
+			ROW_NUMBER() OVER (PARTITION BY model ORDER BY created_at DESC, id DESC) AS recency
+		FROM artworks
+		WHERE deleted_at IS NULL
+	)
+	WHERE recency = 1
+	ORDER BY model
+	`
+
+	rows, err := db.reader.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query model usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []models.ModelUsage
+	for rows.Next() {
+		var u models.ModelUsage
+		if err := rows.Scan(&u.Model, &u.ArtworkCount, &u.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan model usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating model usage rows: %w", err)
+	}
+
+	return usage, nil
+}
+
+// ListUsedModels returns the distinct model IDs referenced by stored artworks
+func (db *DB) ListUsedModels() ([]string, error) {
+	usage, err := db.ListModelUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(usage))
+	for i, u := range usage {
+		ids[i] = u.Model
+	}
+	return ids, nil
+}
+
+// GetStats returns aggregate counts over all groups and artworks, archived
+// groups included and the recycle bin left out. A non-empty source limits
+// the artwork counts to generated or imported artworks.
+func (db *DB) GetStats(source string) (*models.Stats, error) {
+	defer db.timeRead("GetStats")()
+
+	stats := &models.Stats{
+		Source:          source,
+		ArtworksByModel: []models.ModelCount{},
+		Categories:      []models.CategoryCount{},
+	}
+
+	artworkFilter := "deleted_at IS NULL AND (? = '' OR source = ?)"
+
+	// The headline counts in one round trip
+	err := db.reader.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM artwork_groups WHERE deleted_at IS NULL),
+		(SELECT COUNT(DISTINCT category) FROM artwork_groups WHERE deleted_at IS NULL AND category != ''),
+		COUNT(*),
+		COUNT(svg_blob_id),
+		COUNT(DISTINCT model)
+		FROM artworks WHERE `+artworkFilter, source, source).Scan(
+		&stats.TotalGroups, &stats.TotalCategories, &stats.TotalArtworks, &stats.GeneratedSVGs, &stats.DistinctModels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count groups and artworks: %w", err)
+	}
+
+	rows, err := db.reader.Query(`SELECT model, COUNT(*) AS n,
+		COALESCE(SUM(CASE WHEN source = 'generated' THEN (SELECT b.size FROM svg_blobs b WHERE b.id = svg_blob_id) END), 0)
+		FROM artworks WHERE `+artworkFilter+` GROUP BY model ORDER BY n DESC, model`, source, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artworks per model: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.ModelCount
+		if err := rows.Scan(&c.Model, &c.Artworks, &c.GeneratedBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan model count: %w", err)
+		}
+		stats.ArtworksByModel = append(stats.ArtworksByModel, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating model count rows: %w", err)
+	}
+
+	catRows, err := db.reader.Query("SELECT category, COUNT(*) FROM artwork_groups WHERE deleted_at IS NULL GROUP BY category ORDER BY category")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups per category: %w", err)
+	}
+	defer catRows.Close()
+	for catRows.Next() {
+		var c models.CategoryCount
+		if err := catRows.Scan(&c.Category, &c.Groups); err != nil {
+			return nil, fmt.Errorf("failed to scan category count: %w", err)
+		}
+		stats.Categories = append(stats.Categories, c)
+	}
+	if err := catRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category count rows: %w", err)
+	}
+
+	// Saving an SVG bumps updated_at, so the newest artwork with an SVG marks
+	// the most recent generation
+	var last time.Time
+	err = db.reader.QueryRow("SELECT updated_at FROM artworks WHERE svg_blob_id IS NOT NULL AND "+artworkFilter+" ORDER BY updated_at DESC LIMIT 1", source, source).Scan(&last)
+	switch {
+	case err == nil:
+		stats.LastGeneratedAt = &last
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("failed to query last generation: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetCategoryCounts returns the number of non-archived groups per category
+func (db *DB) GetCategoryCounts() ([]models.CategoryCount, error) {
+	defer db.timeRead("GetCategoryCounts")()
+
 	query := `
-	SELECT DISTINCT category
+	SELECT category, COUNT(*)
 	FROM artwork_groups
-	WHERE category != ''
+	WHERE category != '' AND archived = 0 AND deleted_at IS NULL
+	GROUP BY category
 	ORDER BY category
 	`
 
-	rows, err := db.conn.Query(query)
+	rows, err := db.reader.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.CategoryCount
+	for rows.Next() {
+		var c models.CategoryCount
+		if err := rows.Scan(&c.Category, &c.Groups); err != nil {
+			return nil, fmt.Errorf("failed to scan category count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category count rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetDistinctCategories returns the categories of the groups shown in the
+// gallery, by name
+func (db *DB) GetDistinctCategories() ([]models.Category, error) {
+	defer db.timeRead("GetDistinctCategories")()
+
+	query := `
+	SELECT DISTINCT g.category, COALESCE(c.slug, '')
+	FROM artwork_groups g
+	LEFT JOIN categories c ON c.name = g.category
+	WHERE g.category != '' AND g.archived = 0 AND g.deleted_at IS NULL
+	ORDER BY g.category
+	`
+
+	rows, err := db.reader.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query categories: %w", err)
 	}
 	defer rows.Close()
 
-	var categories []string
+	var categories []models.Category
 	for rows.Next() {
-		var category string
-		err := rows.Scan(&category)
+		var category models.Category
+		err := rows.Scan(&category.Name, &category.Slug)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
 		}
@@ -563,34 +1883,86 @@ func (db *DB) GetDistinctCategories() ([]string, error) {
 	return categories, nil
 }
 
+// RecordModelSnapshot stores the model IDs of an OpenRouter model list fetch.
+// The list is refetched every few minutes but rarely changes, so a snapshot
+// is only written when it differs from the latest one. Nothing is written to
+// a read-only database.
+func (db *DB) RecordModelSnapshot(ids []string, fetchedAt time.Time) error {
+	if db.readOnly {
+		return nil
+	}
+
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	joined := strings.Join(sorted, "\n")
+
+	return db.WithTx(func(tx *sql.Tx) error {
+		var latest string
+		err := tx.QueryRow("SELECT model_ids FROM model_snapshots ORDER BY id DESC LIMIT 1").Scan(&latest)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get latest model snapshot: %w", err)
+		}
+		if err == nil && latest == joined {
+			return nil
+		}
+
+		if _, err := tx.Exec("INSERT INTO model_snapshots (fetched_at, model_ids) VALUES (?, ?)", fetchedAt, joined); err != nil {
+			return fmt.Errorf("failed to record model snapshot: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListModelSnapshots returns up to limit model snapshots, newest first
+func (db *DB) ListModelSnapshots(limit int) ([]models.ModelSnapshot, error) {
+	defer db.timeRead("ListModelSnapshots")()
+
+	rows, err := db.reader.Query("SELECT id, fetched_at, model_ids FROM model_snapshots ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query model snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []models.ModelSnapshot
+	for rows.Next() {
+		var snapshot models.ModelSnapshot
+		var ids string
+		if err := rows.Scan(&snapshot.ID, &snapshot.FetchedAt, &ids); err != nil {
+			return nil, fmt.Errorf("failed to scan model snapshot: %w", err)
+		}
+		if ids != "" {
+			snapshot.ModelIDs = strings.Split(ids, "\n")
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating model snapshot rows: %w", err)
+	}
+
+	return snapshots, nil
+}
+
 // GetRandomGroupWithModelArtworks returns a random group that has artworks from both specified models
 func (db *DB) GetRandomGroupWithModelArtworks(model1, model2 string) (*models.ArtworkGroup, []models.Artwork, error) {
+	defer db.timeRead("GetRandomGroupWithModelArtworks")()
+
 	// First, find groups that have artworks from both models
-	query := `
-		SELECT DISTINCT g.id, g.title, g.prompt, g.category, g.original_url, g.artist_name, g.original_artwork, g.created_at, g.updated_at
+	query := `SELECT ` + groupColumns + `
 		FROM artwork_groups g
-		WHERE EXISTS (
-			SELECT 1 FROM artworks a WHERE a.group_id = g.id AND a.model LIKE ?
+		WHERE archived = 0 AND deleted_at IS NULL
+		AND EXISTS (
+			SELECT 1 FROM artworks a WHERE a.group_id = g.id AND a.deleted_at IS NULL AND a.visibility = 'public' AND a.model LIKE ?
 		)
 		AND EXISTS (
-			SELECT 1 FROM artworks a WHERE a.group_id = g.id AND a.model LIKE ?
+			SELECT 1 FROM artworks a WHERE a.group_id = g.id AND a.deleted_at IS NULL AND a.visibility = 'public' AND a.model LIKE ?
 		)
 		ORDER BY RANDOM()
 		LIMIT 1
 	`
 
-	var group models.ArtworkGroup
-	err := db.conn.QueryRow(query, "%"+model1+"%", "%"+model2+"%").Scan(
-		&group.ID,
-		&group.Title,
-		&group.Prompt,
-		&group.Category,
-		&group.OriginalURL,
-		&group.ArtistName,
-		&group.OriginalArtwork,
-		&group.CreatedAt,
-		&group.UpdatedAt,
-	)
+	group, err := scanGroup(db.reader.QueryRow(query, "%"+model1+"%", "%"+model2+"%"))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -601,9 +1973,9 @@ func (db *DB) GetRandomGroupWithModelArtworks(model1, model2 string) (*models.Ar
 
 	// Get artworks for this group, filtered by the two models
 	artworkQuery := `
-		SELECT id, group_id, model, temperature, max_tokens, svg, featured, created_at, updated_at
+		SELECT ` + artworkColumns + `
 		FROM artworks
-		WHERE group_id = ? AND (model LIKE ? OR model LIKE ?)
+		WHERE group_id = ? AND deleted_at IS NULL AND visibility = 'public' AND (model LIKE ? OR model LIKE ?)
 		ORDER BY CASE
 			WHEN model LIKE ? THEN 1
 			WHEN model LIKE ? THEN 2
@@ -611,7 +1983,7 @@ func (db *DB) GetRandomGroupWithModelArtworks(model1, model2 string) (*models.Ar
 		END
 		`
 
-	rows, err := db.conn.Query(artworkQuery, group.ID, "%"+model1+"%", "%"+model2+"%", "%"+model1+"%", "%"+model2+"%")
+	rows, err := db.reader.Query(artworkQuery, group.ID, "%"+model1+"%", "%"+model2+"%", "%"+model1+"%", "%"+model2+"%")
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query artworks: %w", err)
 	}
@@ -619,18 +1991,7 @@ func (db *DB) GetRandomGroupWithModelArtworks(model1, model2 string) (*models.Ar
 
 	var artworks []models.Artwork
 	for rows.Next() {
-		var artwork models.Artwork
-		err := rows.Scan(
-			&artwork.ID,
-			&artwork.GroupID,
-			&artwork.Model,
-			&artwork.Temperature,
-			&artwork.MaxTokens,
-			&artwork.SVG,
-			&artwork.Featured,
-			&artwork.CreatedAt,
-			&artwork.UpdatedAt,
-		)
+		artwork, err := scanArtwork(rows)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to scan artwork: %w", err)
 		}
@@ -643,3 +2004,83 @@ func (db *DB) GetRandomGroupWithModelArtworks(model1, model2 string) (*models.Ar
 
 	return &group, artworks, nil
 }
+
+// ListGroupsWithBothModels returns the active groups that have a public
+// rendition by both models, newest first. Models are matched exactly. When a
+// model has several renditions in a group, the featured one wins, then the
+// most recently updated.
+func (db *DB) ListGroupsWithBothModels(modelA, modelB string) ([]models.ModelComparison, error) {
+	defer db.timeRead("ListGroupsWithBothModels")()
+
+	groupFilter := `archived = 0 AND deleted_at IS NULL
+		AND EXISTS (
+			SELECT 1 FROM artworks a WHERE a.group_id = g.id AND a.deleted_at IS NULL AND a.visibility = 'public' AND a.model = ?
+		)
+		AND EXISTS (
+			SELECT 1 FROM artworks a WHERE a.group_id = g.id AND a.deleted_at IS NULL AND a.visibility = 'public' AND a.model = ?
+		)`
+
+	rows, err := db.reader.Query(`SELECT `+groupColumns+` FROM artwork_groups g WHERE `+groupFilter+` ORDER BY created_at DESC`, modelA, modelB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups: %w", err)
+	}
+	defer rows.Close()
+
+	var comparisons []models.ModelComparison
+	index := make(map[int]int)
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		index[group.ID] = len(comparisons)
+		comparisons = append(comparisons, models.ModelComparison{Group: group})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group rows: %w", err)
+	}
+	if len(comparisons) == 0 {
+		return comparisons, nil
+	}
+
+	artworkRows, err := db.reader.Query(`
+		SELECT `+artworkColumns+`
+		FROM artworks
+		WHERE deleted_at IS NULL AND visibility = 'public' AND model IN (?, ?)
+		AND group_id IN (SELECT g.id FROM artwork_groups g WHERE `+groupFilter+`)
+		ORDER BY featured DESC, updated_at DESC, id DESC`,
+		modelA, modelB, modelA, modelB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artworks: %w", err)
+	}
+	defer artworkRows.Close()
+
+	for artworkRows.Next() {
+		artwork, err := scanArtwork(artworkRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artwork: %w", err)
+		}
+		i, ok := index[artwork.GroupID]
+		if !ok {
+			continue
+		}
+		if artwork.Model == modelA && comparisons[i].A.ID == 0 {
+			comparisons[i].A = artwork
+		}
+		if artwork.Model == modelB && comparisons[i].B.ID == 0 {
+			comparisons[i].B = artwork
+		}
+	}
+	if err := artworkRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating artwork rows: %w", err)
+	}
+
+	// A group can lose a rendition between the two queries
+	complete := comparisons[:0]
+	for _, comparison := range comparisons {
+		if comparison.A.ID != 0 && comparison.B.ID != 0 {
+			complete = append(complete, comparison)
+		}
+	}
+	return complete, nil
+}
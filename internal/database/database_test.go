@@ -0,0 +1,995 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// testSVG is a small valid SVG for artworks that need one
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4"/></svg>`
+
+// newTestDB opens a migrated database in a temporary file that is closed and
+// removed when the test ends
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// createTestGroup creates a group, filling in the title, prompt and
+// timestamps when they are empty, and returns its ID
+func createTestGroup(t *testing.T, db *DB, group models.ArtworkGroup) int {
+	t.Helper()
+	if group.Title == "" {
+		group.Title = "A pelican riding a bicycle"
+	}
+	if group.Prompt == "" {
+		group.Prompt = "Generate an SVG of " + group.Title
+	}
+	if group.CreatedAt.IsZero() {
+		group.CreatedAt = time.Now()
+		group.UpdatedAt = group.CreatedAt
+	}
+	id, err := db.CreateGroup(group)
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	return id
+}
+
+// createTestArtwork creates an artwork, filling in the model and timestamps
+// when they are empty, and returns its ID
+func createTestArtwork(t *testing.T, db *DB, artwork models.Artwork) int {
+	t.Helper()
+	if artwork.Model == "" {
+		artwork.Model = "openai/gpt-4o"
+	}
+	if artwork.CreatedAt.IsZero() {
+		artwork.CreatedAt = time.Now()
+		artwork.UpdatedAt = artwork.CreatedAt
+	}
+	id, err := db.CreateArtwork(artwork)
+	if err != nil {
+		t.Fatalf("CreateArtwork: %v", err)
+	}
+	return id
+}
+
+// countRows returns the number of rows of table matching where
+func countRows(t *testing.T, db *DB, table, where string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.writer.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+where, args...).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestNewMigratesToLatestVersion(t *testing.T) {
+	db := newTestDB(t)
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != LatestSchemaVersion {
+		t.Errorf("version = %d, want %d", version, LatestSchemaVersion)
+	}
+}
+
+func TestGroupQueries(t *testing.T) {
+	db := newTestDB(t)
+
+	id := createTestGroup(t, db, models.ArtworkGroup{
+		Title:       "Pelican",
+		Prompt:      "A pelican riding a bicycle",
+		Category:    "Animals",
+		OriginalURL: "https://example.com/pelican.png",
+		ArtistName:  "Jane",
+		PromptStyle: "line-art",
+	})
+
+	group, err := db.GetGroup(id)
+	if err != nil {
+		t.Fatalf("GetGroup: %v", err)
+	}
+	if group.Title != "Pelican" || group.Category != "Animals" || group.CategorySlug != "animals" ||
+		group.OriginalURL != "https://example.com/pelican.png" || group.ArtistName != "Jane" || group.PromptStyle != "line-art" {
+		t.Errorf("GetGroup = %+v", group)
+	}
+
+	group.Title = "Pelican on a bike"
+	group.Category = "Birds"
+	group.UpdatedAt = time.Now()
+	if err := db.UpdateGroup(*group); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+
+	groups, err := db.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Title != "Pelican on a bike" || groups[0].CategorySlug != "birds" {
+		t.Fatalf("ListGroups = %+v", groups)
+	}
+
+	found, err := db.FindGroupByPrompt("  a PELICAN riding   a bicycle ", "Birds")
+	if err != nil {
+		t.Fatalf("FindGroupByPrompt: %v", err)
+	}
+	if found == nil || found.ID != id {
+		t.Errorf("FindGroupByPrompt = %+v, want group %d", found, id)
+	}
+	found, err = db.FindGroupByPrompt("A pelican riding a bicycle", "Animals")
+	if err != nil {
+		t.Fatalf("FindGroupByPrompt: %v", err)
+	}
+	if found != nil {
+		t.Errorf("FindGroupByPrompt in another category = %+v, want nil", found)
+	}
+
+	found, err = db.FindGroupByTitlePrompt("pelican ON a bike", "a pelican riding a bicycle")
+	if err != nil {
+		t.Fatalf("FindGroupByTitlePrompt: %v", err)
+	}
+	if found == nil || found.ID != id {
+		t.Errorf("FindGroupByTitlePrompt = %+v, want group %d", found, id)
+	}
+
+	if err := db.UpdateGroup(models.ArtworkGroup{ID: id + 1, Title: "Missing"}); err == nil {
+		t.Error("UpdateGroup of a missing group succeeded")
+	}
+	if _, err := db.GetGroup(id + 1); err == nil {
+		t.Error("GetGroup of a missing group succeeded")
+	}
+}
+
+func TestArtworkQueries(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+
+	id := createTestArtwork(t, db, models.Artwork{
+		GroupID:         groupID,
+		Model:           "anthropic/claude-3.5-sonnet",
+		Temperature:     0.7,
+		MaxTokens:       2048,
+		ReasoningEffort: "low",
+	})
+	emptyID := createTestArtwork(t, db, models.Artwork{GroupID: groupID})
+
+	artwork, err := db.GetArtwork(id)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	if artwork.Model != "anthropic/claude-3.5-sonnet" || artwork.Temperature != 0.7 || artwork.MaxTokens != 2048 ||
+		artwork.ReasoningEffort != "low" || artwork.SVG != "" || artwork.Visibility != models.VisibilityPublic ||
+		artwork.Source != models.SourceGenerated || artwork.Revision != 1 {
+		t.Errorf("GetArtwork = %+v", artwork)
+	}
+
+	if err := db.SaveArtworkSVG(id, testSVG); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	if err := db.UpdateArtwork(id, 0.2, 1024, "high"); err != nil {
+		t.Fatalf("UpdateArtwork: %v", err)
+	}
+	if err := db.SetArtworkVisibility(id, models.VisibilityUnlisted); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	artworks, err := db.ListArtworksByGroup(groupID)
+	if err != nil {
+		t.Fatalf("ListArtworksByGroup: %v", err)
+	}
+	if len(artworks) != 2 || artworks[0].ID != id || artworks[1].ID != emptyID {
+		t.Fatalf("ListArtworksByGroup = %+v, want artworks %d and %d", artworks, id, emptyID)
+	}
+	got := artworks[0]
+	if got.SVG != testSVG || got.Temperature != 0.2 || got.MaxTokens != 1024 || got.ReasoningEffort != "high" || got.Visibility != models.VisibilityUnlisted {
+		t.Errorf("updated artwork = %+v", got)
+	}
+
+	groups, artworkMap, err := db.ListGroupsWithArtworks("", false, models.DefaultGroupSort)
+	if err != nil {
+		t.Fatalf("ListGroupsWithArtworks: %v", err)
+	}
+	if len(groups) != 1 || len(artworkMap[groupID]) != 2 {
+		t.Errorf("ListGroupsWithArtworks = %d groups, %d artworks", len(groups), len(artworkMap[groupID]))
+	}
+
+	summaries, err := db.ListGroupsWithCounts("", models.ScopeEditing, models.DefaultGroupSort)
+	if err != nil {
+		t.Fatalf("ListGroupsWithCounts: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].ArtworkCount != 2 || summaries[0].GeneratedCount != 1 {
+		t.Errorf("ListGroupsWithCounts = %+v", summaries)
+	}
+
+	if err := db.DeleteArtwork(emptyID); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	if _, err := db.GetArtwork(emptyID); err == nil {
+		t.Error("GetArtwork of a deleted artwork succeeded")
+	}
+	if err := db.RestoreArtwork(emptyID); err != nil {
+		t.Fatalf("RestoreArtwork: %v", err)
+	}
+	if err := db.PurgeArtwork(emptyID); err != nil {
+		t.Fatalf("PurgeArtwork: %v", err)
+	}
+	if n := countRows(t, db, "artworks", "id = ?", emptyID); n != 0 {
+		t.Errorf("purged artwork has %d rows", n)
+	}
+}
+
+func TestGetRandomGroupWithModelArtworks(t *testing.T) {
+	db := newTestDB(t)
+
+	both := createTestGroup(t, db, models.ArtworkGroup{Title: "Both"})
+	createTestArtwork(t, db, models.Artwork{GroupID: both, Model: "openai/gpt-4o"})
+	createTestArtwork(t, db, models.Artwork{GroupID: both, Model: "anthropic/claude-3.5-sonnet"})
+	createTestArtwork(t, db, models.Artwork{GroupID: both, Model: "google/gemini-pro"})
+
+	one := createTestGroup(t, db, models.ArtworkGroup{Title: "One"})
+	createTestArtwork(t, db, models.Artwork{GroupID: one, Model: "openai/gpt-4o"})
+
+	group, artworks, err := db.GetRandomGroupWithModelArtworks("claude", "gpt-4o")
+	if err != nil {
+		t.Fatalf("GetRandomGroupWithModelArtworks: %v", err)
+	}
+	if group.ID != both {
+		t.Errorf("group = %d, want %d", group.ID, both)
+	}
+	if len(artworks) != 2 || artworks[0].Model != "anthropic/claude-3.5-sonnet" || artworks[1].Model != "openai/gpt-4o" {
+		t.Errorf("artworks = %+v, want claude then gpt-4o", artworks)
+	}
+
+	if _, _, err := db.GetRandomGroupWithModelArtworks("claude", "llama"); err == nil {
+		t.Error("GetRandomGroupWithModelArtworks without a match succeeded")
+	}
+}
+
+func TestListGroupsWithBothModels(t *testing.T) {
+	db := newTestDB(t)
+
+	both := createTestGroup(t, db, models.ArtworkGroup{Title: "Both"})
+	a := createTestArtwork(t, db, models.Artwork{GroupID: both, Model: "a/model"})
+	b := createTestArtwork(t, db, models.Artwork{GroupID: both, Model: "b/model"})
+	private := createTestGroup(t, db, models.ArtworkGroup{Title: "Private"})
+	createTestArtwork(t, db, models.Artwork{GroupID: private, Model: "a/model"})
+	createTestArtwork(t, db, models.Artwork{GroupID: private, Model: "b/model", Visibility: models.VisibilityPrivate})
+
+	comparisons, err := db.ListGroupsWithBothModels("a/model", "b/model")
+	if err != nil {
+		t.Fatalf("ListGroupsWithBothModels: %v", err)
+	}
+	if len(comparisons) != 1 || comparisons[0].Group.ID != both || comparisons[0].A.ID != a || comparisons[0].B.ID != b {
+		t.Errorf("ListGroupsWithBothModels = %+v", comparisons)
+	}
+}
+
+func TestArchiveGroup(t *testing.T) {
+	db := newTestDB(t)
+	archived := createTestGroup(t, db, models.ArtworkGroup{Title: "Archived", Category: "Animals"})
+	kept := createTestGroup(t, db, models.ArtworkGroup{Title: "Kept", Category: "Animals"})
+	createTestArtwork(t, db, models.Artwork{GroupID: archived, SVG: testSVG})
+
+	if err := db.ArchiveGroup(archived); err != nil {
+		t.Fatalf("ArchiveGroup: %v", err)
+	}
+
+	group, err := db.GetGroup(archived)
+	if err != nil {
+		t.Fatalf("GetGroup: %v", err)
+	}
+	if !group.Archived {
+		t.Error("archived group is not marked archived")
+	}
+
+	groups, artworks, err := db.ListGroupsWithArtworks("Animals", false, models.DefaultGroupSort)
+	if err != nil {
+		t.Fatalf("ListGroupsWithArtworks: %v", err)
+	}
+	if len(groups) != 1 || groups[0].ID != kept || len(artworks[archived]) != 0 {
+		t.Errorf("gallery lists %+v, want only group %d", groups, kept)
+	}
+
+	groups, _, err = db.ListGroupsWithArtworks("Animals", true, models.DefaultGroupSort)
+	if err != nil {
+		t.Fatalf("ListGroupsWithArtworks: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Errorf("including archived lists %d groups, want 2", len(groups))
+	}
+
+	if err := db.UnarchiveGroup(archived); err != nil {
+		t.Fatalf("UnarchiveGroup: %v", err)
+	}
+	groups, artworks, err = db.ListGroupsWithArtworks("Animals", false, models.DefaultGroupSort)
+	if err != nil {
+		t.Fatalf("ListGroupsWithArtworks: %v", err)
+	}
+	if len(groups) != 2 || len(artworks[archived]) != 1 {
+		t.Errorf("after unarchiving the gallery lists %d groups and %d artworks of the group, want 2 and 1", len(groups), len(artworks[archived]))
+	}
+
+	if err := db.ArchiveGroup(kept + 1); err == nil {
+		t.Error("ArchiveGroup of a missing group succeeded")
+	}
+}
+
+// decorateGroup gives a group every kind of dependent row: artworks with
+// SVGs and revisions, a vote, generation attempts and a reference image. It
+// returns the IDs of the artworks.
+func decorateGroup(t *testing.T, db *DB, groupID int, svg string) []int {
+	t.Helper()
+	a := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "a/model", SVG: svg})
+	b := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "b/model", SVG: svg})
+	if err := db.SaveArtworkSVG(a, svg+"<!-- v2 -->"); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	if _, err := db.RecordVote(models.Vote{GroupID: groupID, WinnerArtworkID: a, LoserArtworkID: b, WinnerModel: "a/model", LoserModel: "b/model", Fingerprint: "voter"}); err != nil {
+		t.Fatalf("RecordVote: %v", err)
+	}
+	for _, artworkID := range []int{a, b} {
+		if err := db.RecordGenerationAttempt(models.GenerationAttempt{ArtworkID: artworkID, GroupID: groupID, Model: "a/model", Success: true, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("RecordGenerationAttempt: %v", err)
+		}
+	}
+	if err := db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: groupID, ContentType: "image/png", Data: []byte("png"), UploadedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+	return []int{a, b}
+}
+
+func TestDeleteGroupDeepLeavesNoOrphans(t *testing.T) {
+	db := newTestDB(t)
+	deleted := createTestGroup(t, db, models.ArtworkGroup{Title: "Deleted"})
+	kept := createTestGroup(t, db, models.ArtworkGroup{Title: "Kept"})
+	decorateGroup(t, db, deleted, `<svg xmlns="http://www.w3.org/2000/svg"><rect width="1" height="1"/></svg>`)
+	keptArtworks := decorateGroup(t, db, kept, testSVG)
+
+	counts, err := db.DeleteGroupDeep(deleted)
+	if err != nil {
+		t.Fatalf("DeleteGroupDeep: %v", err)
+	}
+	want := map[string]int64{
+		"generation_attempts": 2,
+		"votes":               1,
+		"artwork_revisions":   1,
+		"original_artworks":   1,
+		"artworks":            2,
+		"artwork_groups":      1,
+		"svg_blobs":           2,
+	}
+	for table, n := range want {
+		if counts[table] != n {
+			t.Errorf("deleted %d rows from %s, want %d", counts[table], table, n)
+		}
+	}
+
+	orphans := map[string]string{
+		"artworks":            "group_id NOT IN (SELECT id FROM artwork_groups)",
+		"original_artworks":   "group_id NOT IN (SELECT id FROM artwork_groups)",
+		"votes":               "group_id NOT IN (SELECT id FROM artwork_groups) OR winner_artwork_id NOT IN (SELECT id FROM artworks) OR loser_artwork_id NOT IN (SELECT id FROM artworks)",
+		"generation_attempts": "group_id NOT IN (SELECT id FROM artwork_groups) OR artwork_id NOT IN (SELECT id FROM artworks)",
+		"artwork_revisions":   "artwork_id NOT IN (SELECT id FROM artworks)",
+		"svg_blobs": `id NOT IN (SELECT svg_blob_id FROM artworks WHERE svg_blob_id IS NOT NULL)
+			AND id NOT IN (SELECT svg_blob_id FROM artwork_revisions WHERE svg_blob_id IS NOT NULL)`,
+		"categories": "name NOT IN (SELECT category FROM artwork_groups)",
+	}
+	for table, where := range orphans {
+		if n := countRows(t, db, table, where); n != 0 {
+			t.Errorf("%d orphaned rows left in %s", n, table)
+		}
+	}
+
+	// The other group keeps everything
+	if n := countRows(t, db, "artworks", "group_id = ?", kept); n != 2 {
+		t.Errorf("kept group has %d artworks, want 2", n)
+	}
+	if n := countRows(t, db, "votes", "group_id = ?", kept); n != 1 {
+		t.Errorf("kept group has %d votes, want 1", n)
+	}
+	if n := countRows(t, db, "artwork_revisions", "artwork_id = ?", keptArtworks[0]); n != 1 {
+		t.Errorf("kept artwork has %d revisions, want 1", n)
+	}
+	if _, err := db.GetOriginalArtwork(kept); err != nil {
+		t.Errorf("kept group lost its reference image: %v", err)
+	}
+
+	if _, err := db.DeleteGroupDeep(deleted); err == nil {
+		t.Error("DeleteGroupDeep of a deleted group succeeded")
+	}
+}
+
+func TestGroupDependentsListsEveryReferencingTable(t *testing.T) {
+	db := newTestDB(t)
+
+	listed := map[string]bool{"artwork_groups": true}
+	for _, dep := range groupDependents {
+		listed[dep.table] = true
+	}
+
+	var tables []string
+	err := eachRow(db.writer, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		tables = append(tables, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+
+	for _, table := range tables {
+		err := eachRow(db.writer, `SELECT "table" FROM pragma_foreign_key_list(?)`, func(rows *sql.Rows) error {
+			var parent string
+			if err := rows.Scan(&parent); err != nil {
+				return err
+			}
+			if (parent == "artwork_groups" || parent == "artworks") && !listed[table] {
+				t.Errorf("%s references %s but is not in groupDependents", table, parent)
+			}
+			return nil
+		}, table)
+		if err != nil {
+			t.Fatalf("foreign keys of %s: %v", table, err)
+		}
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db := newTestDB(t)
+
+	empty, err := db.GetStats("")
+	if err != nil {
+		t.Fatalf("GetStats on an empty database: %v", err)
+	}
+	if empty.TotalGroups != 0 || empty.TotalArtworks != 0 || empty.LastGeneratedAt != nil || len(empty.ArtworksByModel) != 0 {
+		t.Errorf("stats of an empty database = %+v", empty)
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+
+	pelican := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican", Category: "birds"})
+	heron := createTestGroup(t, db, models.ArtworkGroup{Title: "Heron", Category: "birds"})
+	bicycle := createTestGroup(t, db, models.ArtworkGroup{Title: "Bicycle"})
+	deleted := createTestGroup(t, db, models.ArtworkGroup{Title: "Deleted", Category: "gone"})
+
+	createTestArtwork(t, db, models.Artwork{GroupID: pelican, Model: "a/model", SVG: testSVG, CreatedAt: at(0), UpdatedAt: at(1)})
+	createTestArtwork(t, db, models.Artwork{GroupID: pelican, Model: "b/model", SVG: testSVG, CreatedAt: at(0), UpdatedAt: at(3)})
+	createTestArtwork(t, db, models.Artwork{GroupID: heron, Model: "a/model", CreatedAt: at(5), UpdatedAt: at(5)})
+	createTestArtwork(t, db, models.Artwork{GroupID: bicycle, Model: "a/model", SVG: testSVG, Source: models.SourceImported, CreatedAt: at(0), UpdatedAt: at(2)})
+	createTestArtwork(t, db, models.Artwork{GroupID: deleted, Model: "c/model", SVG: testSVG, CreatedAt: at(9), UpdatedAt: at(9)})
+	if err := db.DeleteGroup(deleted); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+
+	stats, err := db.GetStats("")
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalGroups != 3 || stats.TotalCategories != 1 || stats.TotalArtworks != 4 || stats.GeneratedSVGs != 3 || stats.DistinctModels != 2 {
+		t.Errorf("totals = %+v, want 3 groups, 1 category, 4 artworks, 3 SVGs, 2 models", stats)
+	}
+
+	// Imported SVGs count as artworks but not as generated bytes
+	size := int64(len(testSVG))
+	wantModels := []models.ModelCount{
+		{Model: "a/model", Artworks: 3, GeneratedBytes: size},
+		{Model: "b/model", Artworks: 1, GeneratedBytes: size},
+	}
+	if !reflect.DeepEqual(stats.ArtworksByModel, wantModels) {
+		t.Errorf("ArtworksByModel = %+v, want %+v", stats.ArtworksByModel, wantModels)
+	}
+
+	wantCategories := []models.CategoryCount{{Category: "", Groups: 1}, {Category: "birds", Groups: 2}}
+	if !reflect.DeepEqual(stats.Categories, wantCategories) {
+		t.Errorf("Categories = %+v, want %+v", stats.Categories, wantCategories)
+	}
+
+	// The artwork without an SVG and the deleted one are not generations
+	if stats.LastGeneratedAt == nil || !stats.LastGeneratedAt.Equal(at(3)) {
+		t.Errorf("LastGeneratedAt = %v, want %v", stats.LastGeneratedAt, at(3))
+	}
+}
+
+func TestOriginalArtworkRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican"})
+
+	if _, err := db.GetOriginalArtwork(groupID); err == nil {
+		t.Fatal("GetOriginalArtwork of a group without one succeeded")
+	}
+
+	uploadedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := models.OriginalArtwork{GroupID: groupID, ContentType: "image/png", Data: []byte("png bytes"), Thumbnail: []byte("thumb"), UploadedAt: uploadedAt}
+	if err := db.SaveOriginalArtwork(first); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+	got, err := db.GetOriginalArtwork(groupID)
+	if err != nil {
+		t.Fatalf("GetOriginalArtwork: %v", err)
+	}
+	if got.GroupID != groupID || got.ContentType != "image/png" || string(got.Data) != "png bytes" ||
+		string(got.Thumbnail) != "thumb" || !got.UploadedAt.Equal(uploadedAt) {
+		t.Errorf("GetOriginalArtwork = %+v, want %+v", got, first)
+	}
+
+	// The group row only flags the image, without loading its bytes
+	group, err := db.GetGroup(groupID)
+	if err != nil {
+		t.Fatalf("GetGroup: %v", err)
+	}
+	if !group.HasOriginalArtwork {
+		t.Error("HasOriginalArtwork = false after saving an original")
+	}
+
+	// Saving again replaces the image
+	second := models.OriginalArtwork{GroupID: groupID, ContentType: "image/jpeg", Data: []byte("jpeg bytes"), UploadedAt: uploadedAt.Add(time.Hour)}
+	if err := db.SaveOriginalArtwork(second); err != nil {
+		t.Fatalf("SaveOriginalArtwork again: %v", err)
+	}
+	got, err = db.GetOriginalArtwork(groupID)
+	if err != nil {
+		t.Fatalf("GetOriginalArtwork: %v", err)
+	}
+	if got.ContentType != "image/jpeg" || string(got.Data) != "jpeg bytes" || len(got.Thumbnail) != 0 || !got.UploadedAt.Equal(second.UploadedAt) {
+		t.Errorf("replaced original = %+v, want %+v", got, second)
+	}
+	if n := countRows(t, db, "original_artworks", "group_id = ?", groupID); n != 1 {
+		t.Errorf("%d original_artworks rows, want 1", n)
+	}
+}
+
+func TestRecycleBin(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican"})
+	alone := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "a/model", SVG: testSVG})
+	withGroup := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "b/model", SVG: testSVG})
+
+	listed := func() (groups, artworks int) {
+		t.Helper()
+		all, err := db.ListGroups()
+		if err != nil {
+			t.Fatalf("ListGroups: %v", err)
+		}
+		list, err := db.ListArtworksByGroup(groupID)
+		if err != nil {
+			t.Fatalf("ListArtworksByGroup: %v", err)
+		}
+		return len(all), len(list)
+	}
+	bin := func() *models.RecycleBin {
+		t.Helper()
+		bin, err := db.ListRecycleBin()
+		if err != nil {
+			t.Fatalf("ListRecycleBin: %v", err)
+		}
+		return bin
+	}
+
+	// An artwork deleted on its own disappears and waits in the bin
+	if err := db.DeleteArtwork(alone); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	if _, err := db.GetArtwork(alone); err == nil {
+		t.Error("GetArtwork of a deleted artwork succeeded")
+	}
+	if groups, artworks := listed(); groups != 1 || artworks != 1 {
+		t.Errorf("after deleting an artwork: %d groups, %d artworks listed, want 1, 1", groups, artworks)
+	}
+	if b := bin(); len(b.Groups) != 0 || len(b.Artworks) != 1 || b.Artworks[0].ID != alone {
+		t.Errorf("recycle bin = %+v, want the deleted artwork", b)
+	}
+	if err := db.DeleteArtwork(alone); err == nil {
+		t.Error("deleting an artwork twice succeeded")
+	}
+
+	// The group takes its remaining artwork into the bin
+	if err := db.DeleteGroup(groupID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if _, err := db.GetGroup(groupID); err == nil {
+		t.Error("GetGroup of a deleted group succeeded")
+	}
+	if groups, artworks := listed(); groups != 0 || artworks != 0 {
+		t.Errorf("after deleting the group: %d groups, %d artworks listed, want none", groups, artworks)
+	}
+	if b := bin(); len(b.Groups) != 1 || len(b.Artworks) != 0 {
+		t.Errorf("recycle bin = %+v, want only the group", b)
+	}
+	if err := db.RestoreArtwork(withGroup); err == nil {
+		t.Error("restoring an artwork of a deleted group succeeded")
+	}
+
+	// Restoring the group brings back what was deleted with it, not the
+	// artwork deleted before
+	if err := db.RestoreGroup(groupID); err != nil {
+		t.Fatalf("RestoreGroup: %v", err)
+	}
+	if groups, artworks := listed(); groups != 1 || artworks != 1 {
+		t.Errorf("after restoring the group: %d groups, %d artworks listed, want 1, 1", groups, artworks)
+	}
+	if err := db.RestoreGroup(groupID); err == nil {
+		t.Error("restoring a group that is not deleted succeeded")
+	}
+	if err := db.RestoreArtwork(alone); err != nil {
+		t.Fatalf("RestoreArtwork: %v", err)
+	}
+	if groups, artworks := listed(); groups != 1 || artworks != 2 {
+		t.Errorf("after restoring the artwork: %d groups, %d artworks listed, want 1, 2", groups, artworks)
+	}
+
+	// Permanent deletion removes the rows, from the bin or not
+	if err := db.DeleteArtwork(alone); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	if err := db.PurgeArtwork(alone); err != nil {
+		t.Fatalf("PurgeArtwork: %v", err)
+	}
+	if n := countRows(t, db, "artworks", "id = ?", alone); n != 0 {
+		t.Errorf("purged artwork left %d rows", n)
+	}
+	if err := db.DeleteGroup(groupID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if _, err := db.DeleteGroupDeep(groupID); err != nil {
+		t.Fatalf("DeleteGroupDeep of a group in the bin: %v", err)
+	}
+	if n := countRows(t, db, "artwork_groups", "id = ?", groupID) + countRows(t, db, "artworks", "group_id = ?", groupID); n != 0 {
+		t.Errorf("permanently deleted group left %d rows", n)
+	}
+	if b := bin(); len(b.Groups) != 0 || len(b.Artworks) != 0 {
+		t.Errorf("recycle bin = %+v, want it empty", b)
+	}
+}
+
+func TestReadsAreNotBlockedByWriter(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican"})
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, SVG: testSVG})
+
+	var mu sync.Mutex
+	observed := map[string]int{}
+	db.ObserveReads(func(query string, d time.Duration) {
+		mu.Lock()
+		observed[query]++
+		mu.Unlock()
+	})
+
+	// Hold the only writer connection in an open transaction, as a batch
+	// generation saving an SVG does
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	txDone := make(chan error, 1)
+	go func() {
+		txDone <- db.WithTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec("UPDATE artwork_groups SET title = 'Renamed' WHERE id = ?", groupID); err != nil {
+				return err
+			}
+			close(holding)
+			<-release
+			return nil
+		})
+	}()
+	<-holding
+
+	// A second write queues behind the transaction
+	writeDone := make(chan error, 1)
+	go func() {
+		_, err := db.CreateGroup(models.ArtworkGroup{Title: "Heron", Prompt: "a heron", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+		writeDone <- err
+	}()
+
+	// Page reads meanwhile go through the read pool
+	const readers = 32
+	readsDone := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			if _, err := db.ListGroups(); err != nil {
+				readsDone <- err
+				return
+			}
+			_, err := db.ListArtworksByGroup(groupID)
+			readsDone <- err
+		}()
+	}
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < readers; i++ {
+		select {
+		case err := <-readsDone:
+			if err != nil {
+				t.Fatalf("read during a write: %v", err)
+			}
+		case <-timeout:
+			close(release)
+			t.Fatalf("only %d of %d reads finished while the writer was busy", i, readers)
+		}
+	}
+
+	select {
+	case err := <-writeDone:
+		t.Errorf("a second write finished while the writer was busy: %v", err)
+	default:
+	}
+
+	close(release)
+	if err := <-txDone; err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if err := <-writeDone; err != nil {
+		t.Fatalf("queued write: %v", err)
+	}
+
+	// Reads see the write once it is committed
+	group, err := db.GetGroup(groupID)
+	if err != nil || group.Title != "Renamed" {
+		t.Errorf("GetGroup after the commit = %+v, %v", group, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if observed["ListGroups"] != readers || observed["ListArtworksByGroup"] != readers {
+		t.Errorf("observed reads = %v, want %d of each", observed, readers)
+	}
+}
+
+func TestWriterUsesWAL(t *testing.T) {
+	db := newTestDB(t)
+	for _, p := range []pool{db.writer, db.reader} {
+		var mode string
+		if err := p.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+			t.Fatalf("%s: read journal mode: %v", p.name, err)
+		}
+		if mode != "wal" {
+			t.Errorf("%s journal mode = %q, want wal", p.name, mode)
+		}
+	}
+}
+
+func TestReadOnlyWritesFail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	groupID := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican"})
+	if db.IsReadOnly() {
+		t.Error("IsReadOnly = true for a writable database")
+	}
+	db.Close()
+
+	readOnly, err := New("file:" + path + "?mode=ro")
+	if err != nil {
+		t.Fatalf("New read-only: %v", err)
+	}
+	defer readOnly.Close()
+	if !readOnly.IsReadOnly() {
+		t.Error("IsReadOnly = false with mode=ro")
+	}
+
+	if _, err := readOnly.CreateGroup(models.ArtworkGroup{Title: "Heron", Prompt: "A heron"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateGroup = %v, want ErrReadOnly", err)
+	}
+	if err := readOnly.SetGroupArchived(groupID, true); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SetGroupArchived = %v, want ErrReadOnly", err)
+	}
+	if err := readOnly.DeleteGroup(groupID); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("DeleteGroup = %v, want ErrReadOnly", err)
+	}
+	if group, err := readOnly.GetGroup(groupID); err != nil || group.Archived {
+		t.Errorf("GetGroup = %+v, %v, want the unchanged group", group, err)
+	}
+}
+
+func TestGroupSorts(t *testing.T) {
+	db := newTestDB(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"heron", "Bicycle", "pelican", "Albatross"} {
+		created := start.Add(time.Duration(i) * time.Hour)
+		createTestGroup(t, db, models.ArtworkGroup{Title: title, CreatedAt: created, UpdatedAt: created})
+	}
+
+	tests := []struct {
+		sort models.GroupSort
+		want []string
+	}{
+		{models.GroupSortNewest, []string{"Albatross", "pelican", "Bicycle", "heron"}},
+		{models.GroupSortOldest, []string{"heron", "Bicycle", "pelican", "Albatross"}},
+		{models.GroupSortTitle, []string{"Albatross", "Bicycle", "heron", "pelican"}},
+		{models.GroupSortManual, []string{"heron", "Bicycle", "pelican", "Albatross"}},
+	}
+	for _, tt := range tests {
+		groups, _, err := db.ListGroupsWithArtworks("", false, tt.sort)
+		if err != nil {
+			t.Fatalf("ListGroupsWithArtworks(%s): %v", tt.sort, err)
+		}
+		var titles []string
+		for _, g := range groups {
+			titles = append(titles, g.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("%s: groups = %v, want %v", tt.sort, titles, tt.want)
+		}
+	}
+
+	// Only the known sorts ever reach the query
+	if _, _, err := db.ListGroupsWithArtworks("", false, "title; DROP TABLE artwork_groups"); err == nil {
+		t.Error("ListGroupsWithArtworks accepted an unknown sort")
+	}
+	if _, err := db.ListGroupsWithCounts("", models.ScopeListing, "random"); err == nil {
+		t.Error("ListGroupsWithCounts accepted an unknown sort")
+	}
+}
+
+func TestCreateGroups(t *testing.T) {
+	db := newTestDB(t)
+	existing := createTestGroup(t, db, models.ArtworkGroup{Title: "Albatross"})
+	deleted := createTestGroup(t, db, models.ArtworkGroup{Title: "Stork"})
+	if err := db.DeleteGroup(deleted); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+
+	now := time.Now()
+	var groups []models.ArtworkGroup
+	for _, title := range []string{"Pelican", "Albatross", "Pelican", "Stork"} {
+		groups = append(groups, models.ArtworkGroup{Title: title, Prompt: "Generate an SVG of " + title, Category: "Sea Birds", CreatedAt: now, UpdatedAt: now})
+	}
+	ids, err := db.CreateGroups(groups)
+	if err != nil {
+		t.Fatalf("CreateGroups: %v", err)
+	}
+	if len(ids) != len(groups) {
+		t.Fatalf("CreateGroups returned %d IDs for %d groups", len(ids), len(groups))
+	}
+	// An existing title is skipped, as is one inserted earlier in the batch;
+	// a group in the recycle bin does not hold its title
+	if ids[0] == 0 || ids[1] != 0 || ids[2] != 0 || ids[3] == 0 || ids[3] == deleted {
+		t.Errorf("IDs = %v, want new groups for Pelican and Stork only", ids)
+	}
+	if n := countRows(t, db, "artwork_groups", "title = ? AND deleted_at IS NULL", "Albatross"); n != 1 {
+		t.Errorf("%d Albatross groups, want only the existing one %d", n, existing)
+	}
+	if slug := categorySlug(t, db, "Sea Birds"); slug != "sea-birds" {
+		t.Errorf("slug of the imported category = %q, want sea-birds", slug)
+	}
+}
+
+// featuredArtworks returns the IDs of the featured artworks, lowest first
+func featuredArtworks(t *testing.T, db *DB) []int {
+	t.Helper()
+	var ids []int
+	err := eachRow(db.writer, `SELECT id FROM artworks WHERE featured = 1 ORDER BY id`, func(rows *sql.Rows) error {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("list featured artworks: %v", err)
+	}
+	return ids
+}
+
+func TestSetFeaturedArtwork(t *testing.T) {
+	db := newTestDB(t)
+	pelican := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican"})
+	heron := createTestGroup(t, db, models.ArtworkGroup{Title: "Heron"})
+	pelicanGPT := createTestArtwork(t, db, models.Artwork{GroupID: pelican, SVG: testSVG})
+	pelicanGemini := createTestArtwork(t, db, models.Artwork{GroupID: pelican, Model: "google/gemini-2.5-pro", SVG: testSVG})
+	heronGPT := createTestArtwork(t, db, models.Artwork{GroupID: heron, SVG: testSVG})
+
+	steps := []struct {
+		artworkID int
+		global    bool
+		want      []int
+	}{
+		{pelicanGPT, false, []int{pelicanGPT}},
+		// Other groups keep their featured artwork
+		{heronGPT, false, []int{pelicanGPT, heronGPT}},
+		// The group's featured artwork is replaced
+		{pelicanGemini, false, []int{pelicanGemini, heronGPT}},
+		// Featuring it again changes nothing
+		{pelicanGemini, false, []int{pelicanGemini, heronGPT}},
+		// Globally, every other featured artwork is cleared
+		{heronGPT, true, []int{heronGPT}},
+		{pelicanGPT, true, []int{pelicanGPT}},
+	}
+	for i, step := range steps {
+		if err := db.SetFeaturedArtwork(step.artworkID, step.global); err != nil {
+			t.Fatalf("step %d: SetFeaturedArtwork(%d, %v): %v", i, step.artworkID, step.global, err)
+		}
+		if got := featuredArtworks(t, db); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: featured = %v, want %v", i, got, step.want)
+		}
+	}
+
+	// Unknown and deleted artworks cannot be featured, and nothing is cleared
+	if err := db.DeleteArtwork(heronGPT); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	for _, id := range []int{999, heronGPT} {
+		if err := db.SetFeaturedArtwork(id, true); err == nil {
+			t.Errorf("SetFeaturedArtwork(%d) succeeded", id)
+		}
+	}
+	if got := featuredArtworks(t, db); !reflect.DeepEqual(got, []int{pelicanGPT}) {
+		t.Errorf("featured after failed calls = %v, want [%d]", got, pelicanGPT)
+	}
+}
+
+func TestGetFeaturedGroups(t *testing.T) {
+	db := newTestDB(t)
+	if groups, err := db.GetFeaturedGroups(10); err != nil || len(groups) != 0 {
+		t.Fatalf("GetFeaturedGroups with nothing featured = %v, %v", groups, err)
+	}
+
+	// feature creates a group whose artwork is featured at featuredAt
+	feature := func(title string, featuredAt time.Time, artwork models.Artwork) (int, int) {
+		t.Helper()
+		groupID := createTestGroup(t, db, models.ArtworkGroup{Title: title})
+		artwork.GroupID = groupID
+		artworkID := createTestArtwork(t, db, artwork)
+		if err := db.SetFeaturedArtwork(artworkID, false); err != nil {
+			t.Fatalf("SetFeaturedArtwork: %v", err)
+		}
+		if _, err := db.writer.Exec(`UPDATE artworks SET updated_at = ? WHERE id = ?`, featuredAt, artworkID); err != nil {
+			t.Fatalf("set featured time: %v", err)
+		}
+		return groupID, artworkID
+	}
+	now := time.Now()
+	older, _ := feature("Older", now.Add(-2*time.Hour), models.Artwork{SVG: testSVG})
+	newer, _ := feature("Newer", now.Add(-time.Hour), models.Artwork{SVG: testSVG})
+	// Groups whose featured artwork cannot be shown are left out
+	feature("Private", now, models.Artwork{SVG: testSVG, Visibility: models.VisibilityPrivate})
+	feature("Empty", now, models.Artwork{})
+	_, deletedArtwork := feature("Deleted artwork", now, models.Artwork{SVG: testSVG})
+	if err := db.DeleteArtwork(deletedArtwork); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	archived, _ := feature("Archived", now, models.Artwork{SVG: testSVG})
+	if err := db.ArchiveGroup(archived); err != nil {
+		t.Fatalf("ArchiveGroup: %v", err)
+	}
+	deleted, _ := feature("Deleted group", now, models.Artwork{SVG: testSVG})
+	if err := db.DeleteGroup(deleted); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	// A group with artworks but none featured
+	createTestArtwork(t, db, models.Artwork{GroupID: createTestGroup(t, db, models.ArtworkGroup{Title: "Plain"}), SVG: testSVG})
+
+	groups, err := db.GetFeaturedGroups(10)
+	if err != nil {
+		t.Fatalf("GetFeaturedGroups: %v", err)
+	}
+	var ids []int
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+	if want := []int{newer, older}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("featured groups = %v, want %v, the latest featured first", ids, want)
+	}
+
+	if groups, err := db.GetFeaturedGroups(1); err != nil || len(groups) != 1 || groups[0].ID != newer {
+		t.Errorf("GetFeaturedGroups(1) = %v, %v; want only group %d", groups, err, newer)
+	}
+}
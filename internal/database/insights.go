@@ -0,0 +1,170 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sort"
+
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/render"
+)
+
+// rendition is the artwork of a model compared in a group, with the
+// perceptual hash of its SVG
+type rendition struct {
+	artworkID int
+	blobID    int64
+	hash      []byte // nil until computed, empty when the SVG cannot be rendered
+}
+
+// ListDivergentGroups returns the limit groups whose models drew the least
+// alike, most divergent first. Each model of a group is represented by its
+// latest public artwork with an SVG, and a group needs two models whose
+// SVGs can be rendered. Archived groups are left out. Perceptual hashes are
+// computed for SVGs that have none yet and kept in svg_blobs.
+func (db *DB) ListDivergentGroups(limit int) ([]models.DivergentGroup, error) {
+	defer db.timeRead("ListDivergentGroups")()
+
+	visible, args := visibilitiesIn(models.ScopeListing)
+	byGroup := make(map[int][]rendition)
+	var groupIDs []int
+	seen := make(map[int]map[string]bool)
+	err := eachRow(db.reader, `SELECT a.group_id, a.model, a.id, a.svg_blob_id, b.phash IS NOT NULL, b.phash
+		FROM artworks a
+		JOIN svg_blobs b ON b.id = a.svg_blob_id
+		JOIN artwork_groups g ON g.id = a.group_id
+		WHERE a.deleted_at IS NULL AND a.visibility IN (`+visible+`) AND g.deleted_at IS NULL AND g.archived = 0
+		ORDER BY a.group_id, a.model, a.revision DESC, a.id DESC`, func(rows *sql.Rows) error {
+		var (
+			groupID int
+			model   string
+			hashed  bool
+			r       rendition
+		)
+		if err := rows.Scan(&groupID, &model, &r.artworkID, &r.blobID, &hashed, &r.hash); err != nil {
+			return err
+		}
+		if hashed && r.hash == nil {
+			r.hash = []byte{}
+		}
+		if seen[groupID] == nil {
+			seen[groupID] = make(map[string]bool)
+			groupIDs = append(groupIDs, groupID)
+		}
+		// Rows come latest first within a model
+		if !seen[groupID][model] {
+			seen[groupID][model] = true
+			byGroup[groupID] = append(byGroup[groupID], r)
+		}
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list renditions: %w", err)
+	}
+
+	if err := db.hashRenditions(byGroup); err != nil {
+		return nil, err
+	}
+
+	type ranked struct {
+		groupID    int
+		agreement  float64
+		artworkIDs []int
+	}
+	var ranking []ranked
+	for _, groupID := range groupIDs {
+		var hashes []uint64
+		var artworkIDs []int
+		for _, r := range byGroup[groupID] {
+			if len(r.hash) == 8 {
+				hashes = append(hashes, binary.BigEndian.Uint64(r.hash))
+				artworkIDs = append(artworkIDs, r.artworkID)
+			}
+		}
+		if len(hashes) < 2 {
+			continue
+		}
+		var sum float64
+		var pairs int
+		for i := range hashes {
+			for j := i + 1; j < len(hashes); j++ {
+				sum += render.Similarity(hashes[i], hashes[j])
+				pairs++
+			}
+		}
+		ranking = append(ranking, ranked{groupID, sum / float64(pairs), artworkIDs})
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].agreement < ranking[j].agreement
+	})
+	if len(ranking) > limit {
+		ranking = ranking[:limit]
+	}
+
+	divergent := []models.DivergentGroup{}
+	for _, r := range ranking {
+		group, err := db.GetGroup(r.groupID)
+		if err != nil {
+			return nil, err
+		}
+		entry := models.DivergentGroup{Group: *group, Agreement: r.agreement}
+		for _, id := range r.artworkIDs {
+			artwork, err := db.GetArtwork(id)
+			if err != nil {
+				return nil, err
+			}
+			entry.Artworks = append(entry.Artworks, *artwork)
+		}
+		divergent = append(divergent, entry)
+	}
+	return divergent, nil
+}
+
+// hashRenditions fills in the hashes of the renditions that have none,
+// rendering each SVG once. The hashes are stored unless the database is
+// read-only, so the next ranking does not render them again.
+func (db *DB) hashRenditions(byGroup map[int][]rendition) error {
+	computed := make(map[int64][]byte)
+	for _, renditions := range byGroup {
+		for i := range renditions {
+			r := &renditions[i]
+			if r.hash != nil {
+				continue
+			}
+			if hash, ok := computed[r.blobID]; ok {
+				r.hash = hash
+				continue
+			}
+
+			var content []byte
+			if err := db.reader.QueryRow(`SELECT content FROM svg_blobs WHERE id = ?`, r.blobID).Scan(&content); err != nil {
+				return fmt.Errorf("failed to read SVG blob %d: %w", r.blobID, err)
+			}
+			svg, err := decompressSVG(content)
+			if err != nil {
+				return err
+			}
+			r.hash = []byte{}
+			if hash, err := render.Hash(svg); err != nil {
+				log.Printf("Not comparing artwork %d: %v", r.artworkID, err)
+			} else {
+				r.hash = binary.BigEndian.AppendUint64(nil, hash)
+			}
+			computed[r.blobID] = r.hash
+		}
+	}
+
+	if len(computed) == 0 || db.readOnly {
+		return nil
+	}
+	return db.WithTx(func(tx *sql.Tx) error {
+		for blobID, hash := range computed {
+			if _, err := tx.Exec(`UPDATE svg_blobs SET phash = ? WHERE id = ?`, hash, blobID); err != nil {
+				return fmt.Errorf("failed to store perceptual hash: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// Drawings for ranking groups by how alike their models drew
+const (
+	circleSVG    = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><circle cx="50" cy="50" r="40"/></svg>`
+	bigCircleSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 200"><circle cx="100" cy="100" r="82"/></svg>`
+	leftHalfSVG  = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect width="50" height="100"/></svg>`
+	rightHalfSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect x="50" width="50" height="100"/></svg>`
+	// Without a viewBox or size the SVG cannot be rendered
+	unrenderableSVG = `<svg xmlns="http://www.w3.org/2000/svg"><rect width="1" height="1"/></svg>`
+)
+
+func TestListDivergentGroups(t *testing.T) {
+	db := newTestDB(t)
+	const gpt, gemini, claude = "openai/gpt-4o", "google/gemini-2.5-pro", "anthropic/claude-sonnet-4"
+
+	// seed creates a group with an artwork per model and SVG, and returns
+	// the group ID and the artwork IDs
+	seed := func(title string, renditions ...[2]string) (int, []int) {
+		t.Helper()
+		groupID := createTestGroup(t, db, models.ArtworkGroup{Title: title})
+		var ids []int
+		for _, r := range renditions {
+			ids = append(ids, createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: r[0], SVG: r[1]}))
+		}
+		return groupID, ids
+	}
+
+	alike, _ := seed("Alike", [2]string{gpt, circleSVG}, [2]string{gemini, bigCircleSVG})
+	opposite, _ := seed("Opposite", [2]string{gpt, leftHalfSVG}, [2]string{gemini, rightHalfSVG})
+	mixed, _ := seed("Mixed", [2]string{gpt, leftHalfSVG}, [2]string{gemini, leftHalfSVG}, [2]string{claude, circleSVG})
+
+	// The latest generation of a model is the one compared
+	redrawn, redrawnIDs := seed("Redrawn", [2]string{gpt, leftHalfSVG}, [2]string{gemini, leftHalfSVG})
+	latest := createTestArtwork(t, db, models.Artwork{GroupID: redrawn, Model: gemini, Revision: 2, SVG: rightHalfSVG})
+
+	// None of these has two models to compare
+	seed("Single model", [2]string{gpt, leftHalfSVG})
+	seed("Unrenderable", [2]string{gpt, leftHalfSVG}, [2]string{gemini, unrenderableSVG})
+	_, hiddenIDs := seed("Private", [2]string{gpt, leftHalfSVG}, [2]string{gemini, rightHalfSVG})
+	if err := db.SetArtworkVisibility(hiddenIDs[1], models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+	archived, _ := seed("Archived", [2]string{gpt, leftHalfSVG}, [2]string{gemini, rightHalfSVG})
+	if err := db.ArchiveGroup(archived); err != nil {
+		t.Fatalf("ArchiveGroup: %v", err)
+	}
+	seed("Not drawn", [2]string{gpt, ""}, [2]string{gemini, ""})
+
+	divergent, err := db.ListDivergentGroups(10)
+	if err != nil {
+		t.Fatalf("ListDivergentGroups: %v", err)
+	}
+	var order []int
+	for _, group := range divergent {
+		order = append(order, group.Group.ID)
+	}
+	// Opposite and Redrawn compare the same two drawings, so they tie and
+	// keep the order of their IDs
+	want := []int{opposite, redrawn, mixed, alike}
+	if len(order) != len(want) {
+		t.Fatalf("ranked groups %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("ranked groups %v, want %v", order, want)
+		}
+	}
+
+	for i, group := range divergent {
+		if group.Agreement < 0 || group.Agreement > 1 || (i > 0 && group.Agreement < divergent[i-1].Agreement) {
+			t.Errorf("agreement of %q = %v after %v", group.Group.Title, group.Agreement, divergent[max(i-1, 0)].Agreement)
+		}
+	}
+	if divergent[3].Agreement < 0.9 {
+		t.Errorf("agreement of two circles = %v, want at least 0.9", divergent[3].Agreement)
+	}
+	if n := len(divergent[2].Artworks); n != 3 {
+		t.Errorf("Mixed compares %d artworks, want 3", n)
+	}
+	compared := map[string]models.Artwork{}
+	for _, artwork := range divergent[1].Artworks {
+		compared[artwork.Model] = artwork
+	}
+	if len(compared) != 2 || compared[gpt].ID != redrawnIDs[0] || compared[gemini].ID != latest {
+		t.Errorf("Redrawn compares %+v, want the latest gemini artwork %d", divergent[1].Artworks, latest)
+	}
+	if compared[gemini].SVG != rightHalfSVG {
+		t.Error("compared artworks come without their SVG")
+	}
+
+	// Every SVG compared is hashed once, including the one that cannot be
+	// rendered, and the hashes are kept
+	if n := countRows(t, db, "svg_blobs", "phash IS NULL AND id IN (SELECT svg_blob_id FROM artworks WHERE visibility = 'public')"); n != 0 {
+		t.Errorf("%d compared SVGs have no stored hash", n)
+	}
+	if n := countRows(t, db, "svg_blobs", "phash IS NOT NULL AND length(phash) = 0"); n != 1 {
+		t.Errorf("%d SVGs are marked as unrenderable, want 1", n)
+	}
+	again, err := db.ListDivergentGroups(2)
+	if err != nil {
+		t.Fatalf("ListDivergentGroups from stored hashes: %v", err)
+	}
+	if len(again) != 2 || again[0].Group.ID != opposite || again[1].Agreement != divergent[1].Agreement {
+		t.Errorf("ranking from stored hashes = %+v", again)
+	}
+}
@@ -0,0 +1,617 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"pelican-gallery/internal/images"
+)
+
+// migration is one step of the schema. Migrations are applied in order, each
+// in its own transaction, and recorded in schema_migrations so they run once.
+// Databases created before schema_migrations existed start at version 0 and
+// may already have part of a migration applied, so every step tolerates
+// tables and columns that already exist.
+type migration struct {
+	version int
+	name    string
+	apply   func(tx *sql.Tx) error
+}
+
+// migrations is the schema history. Append new steps to the end; never edit
+// or reorder a step that has shipped.
+var migrations = []migration{
+	{1, "initial schema", execSQL(`
+	CREATE TABLE IF NOT EXISTS artwork_groups (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		prompt TEXT NOT NULL,
+		category TEXT NOT NULL DEFAULT '',
+		original_url TEXT NOT NULL DEFAULT '',
+		artist_name TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS artworks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		group_id INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		temperature REAL NOT NULL DEFAULT 0.0,
+		max_tokens INTEGER NOT NULL DEFAULT 0,
+		svg TEXT DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (group_id) REFERENCES artwork_groups(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_artworks_group_id ON artworks(group_id);
+	CREATE INDEX IF NOT EXISTS idx_artwork_groups_created_at ON artwork_groups(created_at);
+	CREATE INDEX IF NOT EXISTS idx_artworks_created_at ON artworks(created_at);
+	`)},
+	{2, "featured artworks and reference images on groups", addColumns(
+		addedColumn{"artworks", "featured", "BOOLEAN NOT NULL DEFAULT 0"},
+		// Legacy, moved to original_artworks by migration 4
+		addedColumn{"artwork_groups", "original_artwork", "BLOB"},
+	)},
+	{3, "generation attempts", execSQL(`
+	CREATE TABLE IF NOT EXISTS generation_attempts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		artwork_id INTEGER NOT NULL,
+		group_id INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		success BOOLEAN NOT NULL DEFAULT 0,
+		error_class TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (artwork_id) REFERENCES artworks(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_generation_attempts_created_at ON generation_attempts(created_at);
+	`)},
+	{4, "reference images in their own table", moveOriginalArtworks},
+	{5, "archived groups and prompt styles", addColumns(
+		addedColumn{"artwork_groups", "archived", "BOOLEAN NOT NULL DEFAULT 0"},
+		addedColumn{"artwork_groups", "prompt_style", "TEXT NOT NULL DEFAULT ''"},
+	)},
+	{6, "artwork visibility, reasoning effort and source", addColumns(
+		// Existing artworks default to public so they stay where they were shown
+		addedColumn{"artworks", "visibility", "TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('private', 'unlisted', 'public'))"},
+		addedColumn{"artworks", "reasoning_effort", "TEXT NOT NULL DEFAULT '' CHECK (reasoning_effort IN ('', 'off', 'low', 'medium', 'high'))"},
+		addedColumn{"artworks", "source", "TEXT NOT NULL DEFAULT 'generated' CHECK (source IN ('generated', 'imported'))"},
+	)},
+	{7, "model list snapshots", execSQL(`
+	CREATE TABLE IF NOT EXISTS model_snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		model_ids TEXT NOT NULL -- sorted, newline separated
+	);
+	`)},
+	{8, "soft delete", addColumns(
+		// Soft-deleted rows have deleted_at set and are left out of every read
+		addedColumn{"artwork_groups", "deleted_at", "DATETIME"},
+		addedColumn{"artworks", "deleted_at", "DATETIME"},
+	)},
+	{9, "votes", execSQL(`
+	CREATE TABLE IF NOT EXISTS votes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		group_id INTEGER NOT NULL,
+		winner_artwork_id INTEGER NOT NULL,
+		loser_artwork_id INTEGER NOT NULL,
+		winner_model TEXT NOT NULL,
+		loser_model TEXT NOT NULL,
+		fingerprint TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (winner_artwork_id) REFERENCES artworks(id) ON DELETE CASCADE,
+		FOREIGN KEY (loser_artwork_id) REFERENCES artworks(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_votes_fingerprint ON votes(fingerprint);
+	`)},
+	{10, "artwork revisions", execSQL(`
+	CREATE TABLE IF NOT EXISTS artwork_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		artwork_id INTEGER NOT NULL,
+		svg TEXT NOT NULL,
+		temperature REAL NOT NULL DEFAULT 0.0,
+		max_tokens INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (artwork_id) REFERENCES artworks(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_artwork_revisions_artwork_id ON artwork_revisions(artwork_id);
+	`)},
+	{11, "reference image thumbnails", processOriginalArtworks},
+	{12, "deduplicated, compressed SVGs", moveSVGsToBlobs},
+	{13, "artwork revision numbers", addColumns(
+		// Existing artworks are the first of their model in their group
+		addedColumn{"artworks", "revision", "INTEGER NOT NULL DEFAULT 1"},
+	)},
+	{14, "artwork thumbnails", addColumns(
+		// Thumbnails are made when an SVG is saved, or on first request
+		addedColumn{"artworks", "thumbnail", "BLOB"},
+		addedColumn{"artworks", "thumbnail_width", "INTEGER NOT NULL DEFAULT 0"},
+	)},
+	{15, "group idempotency keys", addGroupIdempotencyKeys},
+	{16, "manual sort order", addSortOrder},
+	{17, "category slugs", addCategorySlugs},
+	{18, "revision SVGs in svg_blobs", moveRevisionSVGsToBlobs},
+	{19, "non-Latin category slugs", reslugDefaultCategories},
+	{20, "perceptual hashes of SVGs", addColumns(
+		// Hashes are computed on first use; an empty one marks an SVG that
+		// cannot be rendered
+		addedColumn{"svg_blobs", "phash", "BLOB"},
+	)},
+}
+
+// LatestSchemaVersion is the version of the newest migration
+var LatestSchemaVersion = migrations[len(migrations)-1].version
+
+// ErrSchemaOutdated is returned when a database opened read-only has
+// migrations pending
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// Migrate applies every migration the database has not had yet. A read-only
+// database is only checked: it fails when migrations are pending.
+func (db *DB) Migrate() error {
+	from, err := db.migrate()
+	if err != nil {
+		return err
+	}
+	if from < LatestSchemaVersion {
+		log.Printf("Migrated database schema from version %d to %d", from, LatestSchemaVersion)
+	}
+	return nil
+}
+
+// migrate applies the pending migrations and returns the version the
+// database was at
+func (db *DB) migrate() (int, error) {
+	version, err := schemaVersion(db.writer)
+	if err != nil {
+		return 0, err
+	}
+	if version >= LatestSchemaVersion {
+		return version, nil
+	}
+	if db.readOnly {
+		return version, fmt.Errorf("%w: at version %d, expected %d, and the database is read-only", ErrSchemaOutdated, version, LatestSchemaVersion)
+	}
+
+	if _, err := db.writer.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return version, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if err := db.applyMigration(m); err != nil {
+			return version, err
+		}
+	}
+
+	return version, nil
+}
+
+// applyMigration runs one migration and records it in the same transaction
+func (db *DB) applyMigration(m migration) error {
+	tx, err := db.writer.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.apply(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the last migration applied to the
+// database, 0 for a database that predates schema_migrations
+func (db *DB) SchemaVersion() (int, error) {
+	defer db.timeRead("SchemaVersion")()
+	return schemaVersion(db.reader)
+}
+
+// schemaVersion reads the applied schema version through q
+func schemaVersion(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}) (int, error) {
+	var tables int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version int
+	if err := q.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// execSQL returns a migration step running statements
+func execSQL(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// addedColumn is a column added to an existing table
+type addedColumn struct {
+	table, name, definition string
+}
+
+// addColumns returns a migration step adding columns with ALTER TABLE,
+// skipping those a database already has
+func addColumns(columns ...addedColumn) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, c := range columns {
+			if err := addColumnIfMissing(tx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func addColumnIfMissing(tx *sql.Tx, c addedColumn) error {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.name).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", c.table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.name, err)
+	}
+	return nil
+}
+
+// moveOriginalArtworks creates original_artworks and moves reference images
+// still stored on the group row into it. The content type is left empty and
+// detected when the image is served.
+func moveOriginalArtworks(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS original_artworks (
+		group_id INTEGER PRIMARY KEY,
+		content_type TEXT NOT NULL DEFAULT '',
+		data BLOB NOT NULL,
+		uploaded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (group_id) REFERENCES artwork_groups(id) ON DELETE CASCADE
+	);
+	`); err != nil {
+		return fmt.Errorf("failed to create original_artworks: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO original_artworks (group_id, data, uploaded_at)
+		SELECT id, original_artwork, updated_at FROM artwork_groups
+		WHERE original_artwork IS NOT NULL AND length(original_artwork) > 0
+	`); err != nil {
+		return fmt.Errorf("failed to copy original artworks: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE artwork_groups SET original_artwork = NULL WHERE original_artwork IS NOT NULL`); err != nil {
+		return fmt.Errorf("failed to clear legacy original artworks: %w", err)
+	}
+	return nil
+}
+
+// addGroupIdempotencyKeys adds the Idempotency-Key a group was created with.
+// Groups created without one keep NULL, which the unique index allows any
+// number of times.
+func addGroupIdempotencyKeys(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, addedColumn{"artwork_groups", "idempotency_key", "TEXT"}); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_artwork_groups_idempotency_key
+		ON artwork_groups(idempotency_key) WHERE idempotency_key IS NOT NULL`); err != nil {
+		return fmt.Errorf("failed to index idempotency keys: %w", err)
+	}
+	return nil
+}
+
+// addSortOrder adds the manual order of groups and of the artworks within
+// each group, numbered from 1 in the order they were listed until now:
+// groups by creation, artworks by model
+func addSortOrder(tx *sql.Tx) error {
+	err := addColumns(
+		addedColumn{"artwork_groups", "sort_order", "INTEGER NOT NULL DEFAULT 0"},
+		addedColumn{"artworks", "sort_order", "INTEGER NOT NULL DEFAULT 0"},
+	)(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+	UPDATE artwork_groups SET sort_order = (
+		SELECT position FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS position FROM artwork_groups
+		) numbered WHERE numbered.id = artwork_groups.id
+	);
+
+	UPDATE artworks SET sort_order = (
+		SELECT position FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY group_id ORDER BY model, id) AS position FROM artworks
+		) numbered WHERE numbered.id = artworks.id
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_artworks_group_sort_order ON artworks(group_id, sort_order);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to number sort order: %w", err)
+	}
+	return nil
+}
+
+// addCategorySlugs creates the categories with their slugs and the history
+// of slugs they had, and gives every category in use a slug. Categories are
+// numbered in the order they were first used, so an older category keeps
+// the unsuffixed slug when two fold to the same one.
+func addCategorySlugs(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS categories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		slug TEXT NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS category_slug_history (
+		slug TEXT PRIMARY KEY,
+		category_id INTEGER NOT NULL,
+		FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+	);
+	`); err != nil {
+		return fmt.Errorf("failed to create categories: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT category FROM artwork_groups WHERE category != '' GROUP BY category ORDER BY MIN(id)`)
+	if err != nil {
+		return fmt.Errorf("failed to list categories: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan category: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating category rows: %w", err)
+	}
+
+	for _, name := range names {
+		if _, err := ensureCategory(tx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processOriginalArtworks adds the thumbnail column and runs the stored
+// reference images through images.Process, which scales them down, strips
+// their metadata and makes their thumbnails. Images it cannot process are
+// kept as they are, without a thumbnail.
+func processOriginalArtworks(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, addedColumn{"original_artworks", "thumbnail", "BLOB"}); err != nil {
+		return err
+	}
+
+	var groupIDs []int
+	err := eachRow(tx, `SELECT group_id FROM original_artworks WHERE thumbnail IS NULL`, func(rows *sql.Rows) error {
+		var groupID int
+		if err := rows.Scan(&groupID); err != nil {
+			return err
+		}
+		groupIDs = append(groupIDs, groupID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list original artworks: %w", err)
+	}
+
+	// One image at a time, so only one is held in memory
+	for _, groupID := range groupIDs {
+		var data []byte
+		if err := tx.QueryRow(`SELECT data FROM original_artworks WHERE group_id = ?`, groupID).Scan(&data); err != nil {
+			return fmt.Errorf("failed to read original artwork of group %d: %w", groupID, err)
+		}
+		processed, err := images.Process(data)
+		if err != nil {
+			log.Printf("Keeping original artwork of group %d unprocessed: %v", groupID, err)
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE original_artworks SET content_type = ?, data = ?, thumbnail = ? WHERE group_id = ?`,
+			processed.ContentType, processed.Full, processed.Thumb, groupID); err != nil {
+			return fmt.Errorf("failed to store processed original artwork of group %d: %w", groupID, err)
+		}
+	}
+	return nil
+}
+
+// moveSVGsToBlobs moves the SVGs stored inline on artworks into svg_blobs,
+// one compressed copy per distinct SVG, and drops artworks.svg
+func moveSVGsToBlobs(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS svg_blobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sha256 TEXT NOT NULL UNIQUE,
+		content BLOB NOT NULL, -- gzip-compressed
+		size INTEGER NOT NULL -- uncompressed, in bytes
+	);
+	`); err != nil {
+		return fmt.Errorf("failed to create svg_blobs: %w", err)
+	}
+	if err := addColumnIfMissing(tx, addedColumn{"artworks", "svg_blob_id", "INTEGER REFERENCES svg_blobs(id)"}); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_artworks_svg_blob_id ON artworks(svg_blob_id)`); err != nil {
+		return fmt.Errorf("failed to index artworks.svg_blob_id: %w", err)
+	}
+
+	var hasInline int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('artworks') WHERE name = 'svg'`).Scan(&hasInline); err != nil {
+		return fmt.Errorf("failed to inspect table artworks: %w", err)
+	}
+	if hasInline == 0 {
+		return nil
+	}
+
+	var artworkIDs []int
+	err := eachRow(tx, `SELECT id FROM artworks WHERE COALESCE(svg, '') != ''`, func(rows *sql.Rows) error {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		artworkIDs = append(artworkIDs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list inline SVGs: %w", err)
+	}
+
+	var inlineBytes int64
+	for _, id := range artworkIDs {
+		var svg string
+		if err := tx.QueryRow(`SELECT svg FROM artworks WHERE id = ?`, id).Scan(&svg); err != nil {
+			return fmt.Errorf("failed to read SVG of artwork %d: %w", id, err)
+		}
+		blobID, err := storeSVG(tx, svg)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE artworks SET svg_blob_id = ? WHERE id = ?`, blobID, id); err != nil {
+			return fmt.Errorf("failed to link SVG of artwork %d: %w", id, err)
+		}
+		inlineBytes += int64(len(svg))
+	}
+
+	if _, err := tx.Exec(`ALTER TABLE artworks DROP COLUMN svg`); err != nil {
+		return fmt.Errorf("failed to drop artworks.svg: %w", err)
+	}
+
+	var blobs, blobBytes int64
+	if err := tx.QueryRow(`SELECT COUNT(*), COALESCE(SUM(length(content)), 0) FROM svg_blobs`).Scan(&blobs, &blobBytes); err != nil {
+		return fmt.Errorf("failed to measure svg_blobs: %w", err)
+	}
+	if len(artworkIDs) > 0 {
+		log.Printf("Moved %d SVGs (%d bytes) into %d compressed blobs (%d bytes), saving %d bytes (%.0f%%); VACUUM returns the space to the filesystem",
+			len(artworkIDs), inlineBytes, blobs, blobBytes, inlineBytes-blobBytes, 100*float64(inlineBytes-blobBytes)/float64(inlineBytes))
+	}
+	return nil
+}
+
+// moveRevisionSVGsToBlobs moves the SVGs stored inline on artwork revisions
+// into svg_blobs, so a revision shares its blob with the artwork or the other
+// revisions that have the same SVG
+func moveRevisionSVGsToBlobs(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, addedColumn{"artwork_revisions", "svg_blob_id", "INTEGER REFERENCES svg_blobs(id)"}); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_artwork_revisions_svg_blob_id ON artwork_revisions(svg_blob_id)`); err != nil {
+		return fmt.Errorf("failed to index artwork_revisions.svg_blob_id: %w", err)
+	}
+
+	var hasInline int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('artwork_revisions') WHERE name = 'svg'`).Scan(&hasInline); err != nil {
+		return fmt.Errorf("failed to inspect table artwork_revisions: %w", err)
+	}
+	if hasInline == 0 {
+		return nil
+	}
+
+	var revisionIDs []int
+	err := eachRow(tx, `SELECT id FROM artwork_revisions`, func(rows *sql.Rows) error {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		revisionIDs = append(revisionIDs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	for _, id := range revisionIDs {
+		var svg string
+		if err := tx.QueryRow(`SELECT svg FROM artwork_revisions WHERE id = ?`, id).Scan(&svg); err != nil {
+			return fmt.Errorf("failed to read SVG of revision %d: %w", id, err)
+		}
+		blobID, err := storeSVG(tx, svg)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE artwork_revisions SET svg_blob_id = ? WHERE id = ?`, blobID, id); err != nil {
+			return fmt.Errorf("failed to link SVG of revision %d: %w", id, err)
+		}
+	}
+
+	if _, err := tx.Exec(`ALTER TABLE artwork_revisions DROP COLUMN svg`); err != nil {
+		return fmt.Errorf("failed to drop artwork_revisions.svg: %w", err)
+	}
+	if len(revisionIDs) > 0 {
+		log.Printf("Moved the SVGs of %d revisions into svg_blobs; VACUUM returns the space to the filesystem", len(revisionIDs))
+	}
+	return nil
+}
+
+// reslugDefaultCategories gives the categories that got the default slug
+// because their names had no Latin letters a slug of their own. The default
+// slug they had stays in their history, so links to it keep working.
+func reslugDefaultCategories(tx *sql.Tx) error {
+	type category struct {
+		id         int
+		name, slug string
+	}
+	var categories []category
+	err := eachRow(tx, `SELECT id, name, slug FROM categories
+		WHERE slug = ? OR slug LIKE ? ORDER BY id`, func(rows *sql.Rows) error {
+		var c category
+		if err := rows.Scan(&c.id, &c.name, &c.slug); err != nil {
+			return err
+		}
+		categories = append(categories, c)
+		return nil
+	}, defaultCategorySlug, defaultCategorySlug+"-%")
+	if err != nil {
+		return fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	for _, c := range categories {
+		if slugify(c.name) == defaultCategorySlug {
+			continue
+		}
+		if err := renameCategorySlug(tx, c.id, c.slug, c.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
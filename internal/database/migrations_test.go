@@ -0,0 +1,203 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// baselineSchema is the schema CreateTables created before migrations
+// existed. Such databases have no schema_migrations table.
+const baselineSchema = `
+CREATE TABLE artwork_groups (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	prompt TEXT NOT NULL,
+	category TEXT NOT NULL DEFAULT '',
+	original_url TEXT NOT NULL DEFAULT '',
+	artist_name TEXT NOT NULL DEFAULT '',
+	original_artwork BLOB,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE artworks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	group_id INTEGER NOT NULL,
+	model TEXT NOT NULL,
+	temperature REAL NOT NULL DEFAULT 0.0,
+	max_tokens INTEGER NOT NULL DEFAULT 0,
+	svg TEXT DEFAULT '',
+	featured BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (group_id) REFERENCES artwork_groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_artworks_group_id ON artworks(group_id);
+CREATE INDEX idx_artwork_groups_created_at ON artwork_groups(created_at);
+CREATE INDEX idx_artworks_created_at ON artworks(created_at);
+`
+
+// baselineOriginal stands in for a reference image stored on the group row
+var baselineOriginal = []byte("\x89PNG\r\n\x1a\nnot really a picture")
+
+// baselineDatabase writes a database with the baseline schema and a few rows
+// and returns its path
+func baselineDatabase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	if _, err := raw.Exec(baselineSchema); err != nil {
+		t.Fatalf("create baseline schema: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO artwork_groups (id, title, prompt, category, original_url, artist_name, original_artwork)
+		VALUES (1, 'Pelican', 'Generate an SVG of a pelican', 'Birds', 'https://example.com/pelican.png', 'Jane', ?),
+		       (2, 'Bicycle', 'Generate an SVG of a bicycle', '', '', '', NULL)`, baselineOriginal); err != nil {
+		t.Fatalf("seed groups: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO artworks (id, group_id, model, temperature, max_tokens, svg, featured)
+		VALUES (1, 1, 'openai/gpt-4o', 0.7, 2000, ?, 1),
+		       (2, 1, 'google/gemini-2.5-pro', 1.0, 4000, '', 0),
+		       (3, 2, 'openai/gpt-4o', 0.5, 1000, ?, 0)`, testSVG, testSVG); err != nil {
+		t.Fatalf("seed artworks: %v", err)
+	}
+	return path
+}
+
+// schemaSnapshot describes a database well enough to tell whether a
+// migration run changed it: the definition of every table and index, the
+// applied migrations and the row count of every table
+type schemaSnapshot struct {
+	Definitions map[string]string
+	Migrations  []string
+	Rows        map[string]int
+}
+
+func snapshotSchema(t *testing.T, db *DB) schemaSnapshot {
+	t.Helper()
+	snapshot := schemaSnapshot{Definitions: map[string]string{}, Rows: map[string]int{}}
+
+	rows, err := db.writer.Query(`SELECT type, name, COALESCE(sql, '') FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		t.Fatalf("read sqlite_master: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var kind, name, definition string
+		if err := rows.Scan(&kind, &name, &definition); err != nil {
+			t.Fatal(err)
+		}
+		snapshot.Definitions[kind+" "+name] = definition
+		if kind == "table" {
+			tables = append(tables, name)
+		}
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		snapshot.Rows[table] = countRows(t, db, table, "1")
+	}
+
+	rows, err = db.writer.Query(`SELECT version || ' ' || name || ' ' || applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatalf("read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var applied string
+		if err := rows.Scan(&applied); err != nil {
+			t.Fatal(err)
+		}
+		snapshot.Migrations = append(snapshot.Migrations, applied)
+	}
+	return snapshot
+}
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	db, err := New(baselineDatabase(t))
+	if err != nil {
+		t.Fatalf("New on a baseline database: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != LatestSchemaVersion {
+		t.Errorf("version = %d, want %d", version, LatestSchemaVersion)
+	}
+
+	group, err := db.GetGroup(1)
+	if err != nil {
+		t.Fatalf("GetGroup: %v", err)
+	}
+	if group.Title != "Pelican" || group.Category != "Birds" || group.CategorySlug != "birds" ||
+		group.OriginalURL != "https://example.com/pelican.png" || group.ArtistName != "Jane" {
+		t.Errorf("migrated group = %+v", group)
+	}
+	original, err := db.GetOriginalArtwork(1)
+	if err != nil {
+		t.Fatalf("GetOriginalArtwork: %v", err)
+	}
+	if !bytes.Equal(original.Data, baselineOriginal) {
+		t.Errorf("reference image = %q, want the one stored on the group row", original.Data)
+	}
+	if _, err := db.GetOriginalArtwork(2); err == nil {
+		t.Error("a group without a reference image has one after migrating")
+	}
+
+	artworks, err := db.ListArtworksByGroup(1)
+	if err != nil {
+		t.Fatalf("ListArtworksByGroup: %v", err)
+	}
+	if len(artworks) != 2 {
+		t.Fatalf("group 1 has %d artworks after migrating, want 2", len(artworks))
+	}
+	for _, artwork := range artworks {
+		switch artwork.ID {
+		case 1:
+			if artwork.SVG != testSVG || !artwork.Featured || artwork.Temperature != 0.7 || artwork.MaxTokens != 2000 {
+				t.Errorf("migrated artwork 1 = %+v", artwork)
+			}
+		case 2:
+			if artwork.SVG != "" || artwork.Featured || artwork.Model != "google/gemini-2.5-pro" {
+				t.Errorf("migrated artwork 2 = %+v", artwork)
+			}
+		}
+		if artwork.Visibility != "public" {
+			t.Errorf("artwork %d has visibility %q, want public", artwork.ID, artwork.Visibility)
+		}
+	}
+	if artwork, err := db.GetArtwork(3); err != nil || artwork.SVG != testSVG || artwork.GroupID != 2 {
+		t.Errorf("GetArtwork(3) = %+v, %v", artwork, err)
+	}
+}
+
+func TestMigrateTwiceIsNoOp(t *testing.T) {
+	db, err := New(baselineDatabase(t))
+	if err != nil {
+		t.Fatalf("New on a baseline database: %v", err)
+	}
+	defer db.Close()
+
+	before := snapshotSchema(t, db)
+	if len(before.Migrations) != LatestSchemaVersion {
+		t.Fatalf("%d migrations recorded, want %d", len(before.Migrations), LatestSchemaVersion)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	if after := snapshotSchema(t, db); !reflect.DeepEqual(after, before) {
+		t.Errorf("second Migrate changed the database:\nbefore %+v\nafter  %+v", before, after)
+	}
+}
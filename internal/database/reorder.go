@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// ReorderError rejects a new order that does not list every item being
+// ordered exactly once
+type ReorderError struct {
+	Missing   []int `json:"missing,omitempty"`   // items left out of the order
+	Unknown   []int `json:"unknown,omitempty"`   // IDs that are not among the items
+	Duplicate []int `json:"duplicate,omitempty"` // IDs listed more than once
+}
+
+func (e *ReorderError) Error() string {
+	return fmt.Sprintf("order must list every item once: %d missing, %d unknown, %d duplicate", len(e.Missing), len(e.Unknown), len(e.Duplicate))
+}
+
+// ReorderGroups sets the manual order of the groups. ids must list every
+// group outside the recycle bin, archived ones included, exactly once;
+// otherwise a *ReorderError is returned and nothing changes.
+func (db *DB) ReorderGroups(ids []int) error {
+	return db.reorder(ids, `SELECT id FROM artwork_groups WHERE deleted_at IS NULL`,
+		`UPDATE artwork_groups SET sort_order = ? WHERE id = ?`)
+}
+
+// ReorderArtworks sets the order of the artworks within a group. ids must
+// list every artwork of the group outside the recycle bin exactly once;
+// otherwise a *ReorderError is returned and nothing changes.
+func (db *DB) ReorderArtworks(groupID int, ids []int) error {
+	return db.reorder(ids, `SELECT id FROM artworks WHERE group_id = ? AND deleted_at IS NULL`,
+		`UPDATE artworks SET sort_order = ? WHERE id = ?`, groupID)
+}
+
+// reorder numbers the rows listed by ids from 1, after checking in the same
+// transaction that they are exactly the rows selectQuery returns
+func (db *DB) reorder(ids []int, selectQuery, updateQuery string, args ...interface{}) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		current, err := queryIDs(tx, selectQuery, args...)
+		if err != nil {
+			return fmt.Errorf("failed to list items to reorder: %w", err)
+		}
+		if err := checkOrder(current, ids); err != nil {
+			return err
+		}
+
+		for i, id := range ids {
+			if _, err := tx.Exec(updateQuery, i+1, id); err != nil {
+				return fmt.Errorf("failed to reorder item %d: %w", id, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// queryIDs returns the IDs selected by query
+func queryIDs(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// checkOrder returns a *ReorderError unless order lists each of current
+// exactly once
+func checkOrder(current, order []int) error {
+	expected := make(map[int]bool, len(current))
+	for _, id := range current {
+		expected[id] = true
+	}
+
+	var e ReorderError
+	listed := make(map[int]bool, len(order))
+	for _, id := range order {
+		switch {
+		case listed[id]:
+			if !slices.Contains(e.Duplicate, id) {
+				e.Duplicate = append(e.Duplicate, id)
+			}
+		case !expected[id]:
+			e.Unknown = append(e.Unknown, id)
+		}
+		listed[id] = true
+	}
+	for _, id := range current {
+		if !listed[id] {
+			e.Missing = append(e.Missing, id)
+		}
+	}
+
+	if len(e.Missing) > 0 || len(e.Unknown) > 0 || len(e.Duplicate) > 0 {
+		return &e
+	}
+	return nil
+}
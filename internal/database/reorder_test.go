@@ -0,0 +1,138 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// listedGroupIDs returns the IDs of the groups as ListGroups orders them
+func listedGroupIDs(t *testing.T, db *DB) []int {
+	t.Helper()
+	groups, err := db.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+	var ids []int
+	for _, group := range groups {
+		ids = append(ids, group.ID)
+	}
+	return ids
+}
+
+// listedArtworkIDs returns the IDs of the artworks of a group in their order
+func listedArtworkIDs(t *testing.T, db *DB, groupID int) []int {
+	t.Helper()
+	artworks, err := db.ListArtworksByGroup(groupID)
+	if err != nil {
+		t.Fatalf("ListArtworksByGroup: %v", err)
+	}
+	var ids []int
+	for _, artwork := range artworks {
+		ids = append(ids, artwork.ID)
+	}
+	return ids
+}
+
+func TestReorderGroups(t *testing.T) {
+	db := newTestDB(t)
+	a := createTestGroup(t, db, models.ArtworkGroup{Title: "A"})
+	b := createTestGroup(t, db, models.ArtworkGroup{Title: "B"})
+	c := createTestGroup(t, db, models.ArtworkGroup{Title: "C"})
+	deleted := createTestGroup(t, db, models.ArtworkGroup{Title: "Deleted"})
+	if err := db.DeleteGroup(deleted); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	// Archived groups keep a place in the order
+	if err := db.SetGroupArchived(b, true); err != nil {
+		t.Fatalf("SetGroupArchived: %v", err)
+	}
+
+	if got := listedGroupIDs(t, db); !reflect.DeepEqual(got, []int{a, b, c}) {
+		t.Fatalf("initial order = %v, want creation order", got)
+	}
+	if err := db.ReorderGroups([]int{c, a, b}); err != nil {
+		t.Fatalf("ReorderGroups: %v", err)
+	}
+	if got := listedGroupIDs(t, db); !reflect.DeepEqual(got, []int{c, a, b}) {
+		t.Errorf("order after reordering = %v, want %v", got, []int{c, a, b})
+	}
+
+	tests := []struct {
+		name string
+		ids  []int
+		want ReorderError
+	}{
+		{"partial", []int{a, c}, ReorderError{Missing: []int{b}}},
+		{"foreign", []int{a, b, c, 999}, ReorderError{Unknown: []int{999}}},
+		{"deleted", []int{a, b, c, deleted}, ReorderError{Unknown: []int{deleted}}},
+		{"duplicate", []int{a, b, c, a, a}, ReorderError{Duplicate: []int{a}}},
+		{"empty", []int{}, ReorderError{Missing: []int{a, b, c}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.ReorderGroups(tt.ids)
+			var mismatch *ReorderError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("ReorderGroups(%v) = %v, want a *ReorderError", tt.ids, err)
+			}
+			// The listed IDs come in no set order
+			for _, list := range [][]int{mismatch.Missing, mismatch.Unknown, mismatch.Duplicate} {
+				slices.Sort(list)
+			}
+			if !reflect.DeepEqual(*mismatch, tt.want) {
+				t.Errorf("ReorderGroups(%v) = %+v, want %+v", tt.ids, *mismatch, tt.want)
+			}
+			if got := listedGroupIDs(t, db); !reflect.DeepEqual(got, []int{c, a, b}) {
+				t.Errorf("order after a rejected reorder = %v, want it unchanged", got)
+			}
+		})
+	}
+
+	// New groups go last
+	d := createTestGroup(t, db, models.ArtworkGroup{Title: "D"})
+	if got := listedGroupIDs(t, db); !reflect.DeepEqual(got, []int{c, a, b, d}) {
+		t.Errorf("order after creating a group = %v, want it last", got)
+	}
+}
+
+func TestReorderArtworks(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	x := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "x/model"})
+	y := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "y/model"})
+	z := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "z/model"})
+	other := createTestArtwork(t, db, models.Artwork{GroupID: createTestGroup(t, db, models.ArtworkGroup{Title: "Other"})})
+
+	if err := db.ReorderArtworks(groupID, []int{z, x, y}); err != nil {
+		t.Fatalf("ReorderArtworks: %v", err)
+	}
+	if got := listedArtworkIDs(t, db, groupID); !reflect.DeepEqual(got, []int{z, x, y}) {
+		t.Errorf("order after reordering = %v, want %v", got, []int{z, x, y})
+	}
+
+	// An artwork of another group cannot be ordered into this one
+	err := db.ReorderArtworks(groupID, []int{z, x, other})
+	var mismatch *ReorderError
+	if !errors.As(err, &mismatch) || !reflect.DeepEqual(mismatch.Unknown, []int{other}) || !reflect.DeepEqual(mismatch.Missing, []int{y}) {
+		t.Errorf("ReorderArtworks with a foreign artwork = %v, want %d unknown and %d missing", err, other, y)
+	}
+	if got := listedArtworkIDs(t, db, groupID); !reflect.DeepEqual(got, []int{z, x, y}) {
+		t.Errorf("order after a rejected reorder = %v, want it unchanged", got)
+	}
+
+	// New artworks go last, and ones in the recycle bin drop out of the set
+	w := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "w/model"})
+	if err := db.DeleteArtwork(x); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	if got := listedArtworkIDs(t, db, groupID); !reflect.DeepEqual(got, []int{z, y, w}) {
+		t.Errorf("order after adding and deleting = %v, want %v", got, []int{z, y, w})
+	}
+	if err := db.ReorderArtworks(groupID, []int{w, y, z}); err != nil {
+		t.Errorf("ReorderArtworks without the deleted artwork: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"pelican-gallery/internal/models"
+)
+
+// DefaultRevisionLimit is the number of replaced SVGs kept per artwork
+// unless KeepRevisions says otherwise
+const DefaultRevisionLimit = 20
+
+// ErrRevisionNotFound is returned for a revision that does not exist or
+// belongs to another artwork
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// KeepRevisions sets how many replaced SVGs are kept per artwork; older
+// ones are pruned when a new one is stored
+func (db *DB) KeepRevisions(limit int) {
+	db.revisionLimit = limit
+}
+
+// keepRevision stores the current SVG of an artwork as a revision before it
+// is replaced by svg, then prunes the oldest revisions beyond the limit. An
+// empty or unchanged SVG is not kept. It returns the SVG blobs the artwork
+// and the pruned revisions referred to, which may be unused once the new SVG
+// is saved.
+func (db *DB) keepRevision(tx *sql.Tx, artworkID int, svg string) ([]int64, error) {
+	var (
+		svgBlobID   sql.NullInt64
+		currentKey  sql.NullString
+		temperature float64
+		maxTokens   int
+	)
+	err := tx.QueryRow(`SELECT svg_blob_id, (SELECT b.sha256 FROM svg_blobs b WHERE b.id = svg_blob_id), temperature, max_tokens
+		FROM artworks WHERE id = ? AND deleted_at IS NULL`, artworkID).
+		Scan(&svgBlobID, &currentKey, &temperature, &maxTokens)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("artwork with ID %d not found", artworkID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current SVG: %w", err)
+	}
+	if !svgBlobID.Valid || currentKey.String == svgKey(svg) {
+		return nil, nil
+	}
+
+	// The revision shares the blob the artwork has now
+	if _, err := tx.Exec(`INSERT INTO artwork_revisions (artwork_id, svg_blob_id, temperature, max_tokens, created_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		artworkID, svgBlobID, temperature, maxTokens); err != nil {
+		return nil, fmt.Errorf("failed to store revision: %w", err)
+	}
+
+	const pruned = `artwork_id = ? AND id NOT IN (
+			SELECT id FROM artwork_revisions WHERE artwork_id = ? ORDER BY id DESC LIMIT ?
+		)`
+	limit := max(db.revisionLimit, 1)
+	released, err := selectSVGBlobIDs(tx, `SELECT DISTINCT svg_blob_id FROM artwork_revisions WHERE svg_blob_id IS NOT NULL AND `+pruned, artworkID, artworkID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM artwork_revisions WHERE `+pruned, artworkID, artworkID, limit); err != nil {
+		return nil, fmt.Errorf("failed to prune revisions: %w", err)
+	}
+
+	return append(released, svgBlobID.Int64), nil
+}
+
+// ListArtworkRevisions returns the revisions of an artwork, newest first,
+// with their size instead of their SVG
+func (db *DB) ListArtworkRevisions(artworkID int) ([]models.ArtworkRevision, error) {
+	defer db.timeRead("ListArtworkRevisions")()
+
+	rows, err := db.reader.Query(`SELECT id, artwork_id, COALESCE((SELECT b.size FROM svg_blobs b WHERE b.id = svg_blob_id), 0), temperature, max_tokens, created_at
+		FROM artwork_revisions WHERE artwork_id = ? ORDER BY id DESC`, artworkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.ArtworkRevision{}
+	for rows.Next() {
+		var revision models.ArtworkRevision
+		if err := rows.Scan(&revision.ID, &revision.ArtworkID, &revision.Size, &revision.Temperature, &revision.MaxTokens, &revision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revision rows: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// GetArtworkRevision returns a revision of an artwork with its SVG
+func (db *DB) GetArtworkRevision(artworkID, revisionID int) (*models.ArtworkRevision, error) {
+	defer db.timeRead("GetArtworkRevision")()
+
+	var revision models.ArtworkRevision
+	var content []byte
+	err := db.reader.QueryRow(`SELECT id, artwork_id, `+svgContent+`, temperature, max_tokens, created_at
+		FROM artwork_revisions WHERE id = ? AND artwork_id = ?`, revisionID, artworkID).
+		Scan(&revision.ID, &revision.ArtworkID, &content, &revision.Temperature, &revision.MaxTokens, &revision.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	if revision.SVG, err = decompressSVG(content); err != nil {
+		return nil, err
+	}
+	revision.Size = len(revision.SVG)
+
+	return &revision, nil
+}
+
+// CountArtworkRevisions returns the number of revisions of each artwork of
+// a group that has any
+func (db *DB) CountArtworkRevisions(groupID int) (map[int]int, error) {
+	defer db.timeRead("CountArtworkRevisions")()
+
+	rows, err := db.reader.Query(`SELECT r.artwork_id, COUNT(*)
+		FROM artwork_revisions r JOIN artworks a ON a.id = r.artwork_id
+		WHERE a.group_id = ?
+		GROUP BY r.artwork_id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count revisions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var artworkID, count int
+		if err := rows.Scan(&artworkID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan revision count: %w", err)
+		}
+		counts[artworkID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revision counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// ListModelHistory returns the latest versions drawn by model in a group,
+// oldest first and at most limit of them: the SVG each of its artworks
+// visible in scope has now, and the revisions those replaced. Versions are
+// ordered by artwork revision, then by when they were stored.
+func (db *DB) ListModelHistory(groupID int, model string, scope models.ViewScope, limit int) ([]models.ArtworkVersion, error) {
+	defer db.timeRead("ListModelHistory")()
+
+	visible, visibleArgs := visibilitiesIn(scope)
+	artworks := `SELECT id, revision, visibility FROM artworks
+		WHERE group_id = ? AND model = ? AND deleted_at IS NULL AND visibility IN (` + visible + `)`
+
+	// The current SVG sorts after the revisions it replaced
+	query := `WITH shown AS (` + artworks + `)
+		SELECT artwork_id, revision, revision_id, content, size, temperature, max_tokens, visibility, saved_at FROM (
+			SELECT s.id AS artwork_id, s.revision, r.id AS revision_id, b.content, b.size, r.temperature, r.max_tokens, s.visibility, r.created_at AS saved_at, 0 AS current
+			FROM artwork_revisions r
+			JOIN shown s ON s.id = r.artwork_id
+			JOIN svg_blobs b ON b.id = r.svg_blob_id
+			UNION ALL
+			SELECT s.id, s.revision, 0, b.content, b.size, a.temperature, a.max_tokens, s.visibility, a.updated_at, 1
+			FROM artworks a
+			JOIN shown s ON s.id = a.id
+			JOIN svg_blobs b ON b.id = a.svg_blob_id
+		)
+		ORDER BY revision DESC, artwork_id DESC, current DESC, revision_id DESC
+		LIMIT ?`
+
+	args := append([]interface{}{groupID, model}, visibleArgs...)
+	rows, err := db.reader.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []models.ArtworkVersion{}
+	for rows.Next() {
+		var version models.ArtworkVersion
+		var content []byte
+		if err := rows.Scan(&version.ArtworkID, &version.Revision, &version.RevisionID, &content, &version.Size,
+			&version.Temperature, &version.MaxTokens, &version.Visibility, &version.SavedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan version: %w", err)
+		}
+		if version.SVG, err = decompressSVG(content); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating version rows: %w", err)
+	}
+
+	// Selected newest first, so the limit keeps the latest versions
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// RestoreArtworkRevision puts the SVG and parameters of a revision back on
+// its artwork. The SVG it replaces is kept as a new revision, so a restore
+// can itself be undone.
+func (db *DB) RestoreArtworkRevision(artworkID, revisionID int) error {
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var (
+			content     []byte
+			temperature float64
+			maxTokens   int
+		)
+		err := tx.QueryRow(`SELECT `+svgContent+`, temperature, max_tokens FROM artwork_revisions WHERE id = ? AND artwork_id = ?`, revisionID, artworkID).
+			Scan(&content, &temperature, &maxTokens)
+		if err == sql.ErrNoRows {
+			return ErrRevisionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get revision: %w", err)
+		}
+		svg, err := decompressSVG(content)
+		if err != nil {
+			return err
+		}
+
+		released, err := db.keepRevision(tx, artworkID, svg)
+		if err != nil {
+			return err
+		}
+
+		svgBlobID, err := storeSVG(tx, svg)
+		if err != nil {
+			return err
+		}
+
+		thumbnail, thumbnailWidth := renderThumbnail(artworkID, svg)
+		if _, err := tx.Exec(`UPDATE artworks SET svg_blob_id = ?, thumbnail = ?, thumbnail_width = ?, temperature = ?, max_tokens = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`,
+			svgBlobID, thumbnail, thumbnailWidth, temperature, maxTokens, artworkID); err != nil {
+			return fmt.Errorf("failed to restore revision: %w", err)
+		}
+
+		if err := deleteSVGsIfUnused(tx, released); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	db.notifySVGSaved(artworkID)
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestListModelHistory(t *testing.T) {
+	db := newTestDB(t)
+	const model = "openai/gpt-4o"
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	first := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: model, MaxTokens: 1000, SVG: numberedSVG(1)})
+	for _, n := range []int{2, 3} {
+		if err := db.SaveArtworkSVG(first, numberedSVG(n)); err != nil {
+			t.Fatalf("SaveArtworkSVG: %v", err)
+		}
+	}
+	// A second generation of the model, kept next to the first
+	second := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: model, MaxTokens: 2000, Revision: 2, SVG: numberedSVG(10)})
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "google/gemini-2.5-pro", SVG: numberedSVG(20)})
+	// Neither an artwork without an SVG nor one in another group has a version
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: model})
+	createTestArtwork(t, db, models.Artwork{GroupID: createTestGroup(t, db, models.ArtworkGroup{Title: "Heron"}), Model: model, SVG: numberedSVG(30)})
+
+	if err := db.SetArtworkVisibility(second, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	// svgs returns the numbers of the SVGs of versions
+	svgs := func(versions []models.ArtworkVersion) []int {
+		numbers := []int{}
+		for _, version := range versions {
+			for n := 0; n <= 30; n++ {
+				if version.SVG == numberedSVG(n) {
+					numbers = append(numbers, n)
+				}
+			}
+		}
+		return numbers
+	}
+
+	tests := []struct {
+		name  string
+		scope models.ViewScope
+		limit int
+		want  []int
+	}{
+		{"all versions", models.ScopeEditing, 20, []int{1, 2, 3, 10}},
+		{"private artwork left out", models.ScopeDirectLink, 20, []int{1, 2, 3}},
+		{"latest versions kept", models.ScopeEditing, 2, []int{3, 10}},
+		{"limit counts visible versions", models.ScopeDirectLink, 2, []int{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := db.ListModelHistory(groupID, model, tt.scope, tt.limit)
+			if err != nil {
+				t.Fatalf("ListModelHistory: %v", err)
+			}
+			if got := svgs(versions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	versions, err := db.ListModelHistory(groupID, model, models.ScopeEditing, 20)
+	if err != nil {
+		t.Fatalf("ListModelHistory: %v", err)
+	}
+	for i, version := range versions {
+		current := i >= 2
+		if version.IsCurrent() != current {
+			t.Errorf("version %d: current = %v, want %v", i, version.IsCurrent(), current)
+		}
+		if version.Size != len(version.SVG) || version.SavedAt.IsZero() || version.MaxTokens == 0 {
+			t.Errorf("version %d = %+v", i, version)
+		}
+	}
+	if versions[2].ArtworkID != first || versions[2].Revision != 1 || versions[3].ArtworkID != second || versions[3].Revision != 2 {
+		t.Errorf("current versions = %+v and %+v", versions[2], versions[3])
+	}
+
+	// Each revision can be restored from the history
+	if err := db.RestoreArtworkRevision(first, versions[0].RevisionID); err != nil {
+		t.Fatalf("RestoreArtworkRevision: %v", err)
+	}
+	versions, err = db.ListModelHistory(groupID, model, models.ScopeDirectLink, 20)
+	if err != nil {
+		t.Fatalf("ListModelHistory: %v", err)
+	}
+	if got, want := svgs(versions), []int{1, 2, 3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("versions after restoring the first = %v, want %v", got, want)
+	}
+
+	if versions, err := db.ListModelHistory(groupID, "nobody/nothing", models.ScopeEditing, 20); err != nil || len(versions) != 0 {
+		t.Errorf("history of a model without artworks = %v, %v", versions, err)
+	}
+}
+
+func TestAddArtworkRevision(t *testing.T) {
+	db := newTestDB(t)
+	const model = "openai/gpt-4o"
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	first := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: model, Temperature: 0.4, MaxTokens: 3000, ReasoningEffort: "low", SVG: numberedSVG(1)})
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "google/gemini-2.5-pro", SVG: numberedSVG(2)})
+
+	original, err := db.GetArtwork(first)
+	if err != nil {
+		t.Fatalf("GetArtwork: %v", err)
+	}
+	second, err := db.AddArtworkRevision(*original)
+	if err != nil {
+		t.Fatalf("AddArtworkRevision: %v", err)
+	}
+	if second.ID == first || second.Revision != 2 || second.SVG != "" || second.GroupID != groupID || second.Model != model ||
+		second.Temperature != 0.4 || second.MaxTokens != 3000 || second.ReasoningEffort != "low" {
+		t.Errorf("new revision = %+v", second)
+	}
+	if after, err := db.GetArtwork(first); err != nil || after.SVG != numberedSVG(1) || after.Revision != 1 {
+		t.Errorf("original after AddArtworkRevision = %+v, %v", after, err)
+	}
+
+	// A revision in the recycle bin keeps its number
+	if err := db.DeleteArtwork(second.ID); err != nil {
+		t.Fatalf("DeleteArtwork: %v", err)
+	}
+	third, err := db.AddArtworkRevision(*original)
+	if err != nil {
+		t.Fatalf("AddArtworkRevision: %v", err)
+	}
+	if third.Revision != 3 {
+		t.Errorf("revision after one went to the recycle bin = %d, want 3", third.Revision)
+	}
+
+	list, err := db.ListModelRevisions(groupID, model)
+	if err != nil {
+		t.Fatalf("ListModelRevisions: %v", err)
+	}
+	var got [][2]int
+	for _, artwork := range list {
+		got = append(got, [2]int{artwork.ID, artwork.Revision})
+	}
+	if want := [][2]int{{first, 1}, {third.ID, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListModelRevisions = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"pelican-gallery/internal/models"
+)
+
+// maxViolationSamples bounds the row IDs listed per foreign-key violation
+const maxViolationSamples = 10
+
+// queryer is satisfied by both connection pools
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// schemaColumn is one row of PRAGMA table_info
+type schemaColumn struct {
+	Type    string
+	NotNull bool
+	Default sql.NullString
+	PK      int
+}
+
+// schemaTable is what the schema check compares for one table
+type schemaTable struct {
+	columns     map[string]schemaColumn
+	indexes     map[string]string // index name -> indexed columns
+	foreignKeys map[string]string // "from -> table(to)" -> ON DELETE action
+}
+
+// CheckSchema compares the live database with the schema the migration
+// runner produces. The expected schema is built by running Migrate on an
+// empty in-memory database, so it never drifts from the code. It reports
+// missing and extra tables, columns, indexes and foreign keys, and rows that
+// violate a foreign key. Nothing is modified; starting the app with editing
+// enabled applies any missing migrations.
+func (db *DB) CheckSchema() (*models.SchemaReport, error) {
+	defer db.timeRead("CheckSchema")()
+
+	reference, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference database: %w", err)
+	}
+	defer reference.Close()
+	// Every connection to :memory: is a separate database
+	reference.SetMaxOpenConns(1)
+
+	if _, err := (&DB{writer: pool{DB: reference}}).migrate(); err != nil {
+		return nil, fmt.Errorf("failed to build reference schema: %w", err)
+	}
+
+	expected, err := inspectSchema(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect reference schema: %w", err)
+	}
+	actual, err := inspectSchema(db.reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database schema: %w", err)
+	}
+
+	report := &models.SchemaReport{
+		Issues:               compareSchemas(expected, actual),
+		ForeignKeyViolations: []models.ForeignKeyViolation{},
+	}
+
+	violations, err := foreignKeyViolations(db.reader)
+	if err != nil {
+		return nil, err
+	}
+	report.ForeignKeyViolations = append(report.ForeignKeyViolations, violations...)
+	report.OK = len(report.Issues) == 0 && len(report.ForeignKeyViolations) == 0
+
+	return report, nil
+}
+
+// inspectSchema reads the columns, indexes and foreign keys of every table
+func inspectSchema(q queryer) (map[string]schemaTable, error) {
+	names, err := queryStrings(q, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]schemaTable, len(names))
+	for _, name := range names {
+		table := schemaTable{
+			columns:     make(map[string]schemaColumn),
+			indexes:     make(map[string]string),
+			foreignKeys: make(map[string]string),
+		}
+
+		err := eachRow(q, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?)`, func(rows *sql.Rows) error {
+			var column string
+			var info schemaColumn
+			if err := rows.Scan(&column, &info.Type, &info.NotNull, &info.Default, &info.PK); err != nil {
+				return err
+			}
+			table.columns[column] = info
+			return nil
+		}, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", name, err)
+		}
+
+		// Automatic indexes for UNIQUE and PRIMARY KEY constraints have no SQL
+		indexes, err := queryStrings(q, "SELECT name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL AND tbl_name = ?", name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read indexes of %s: %w", name, err)
+		}
+		for _, index := range indexes {
+			columns, err := queryStrings(q, "SELECT name FROM pragma_index_info(?) ORDER BY seqno", index)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read index %s: %w", index, err)
+			}
+			table.indexes[index] = strings.Join(columns, ", ")
+		}
+
+		err = eachRow(q, `SELECT "table", "from", "to", on_delete FROM pragma_foreign_key_list(?)`, func(rows *sql.Rows) error {
+			var parent, from, onDelete string
+			var to sql.NullString
+			if err := rows.Scan(&parent, &from, &to, &onDelete); err != nil {
+				return err
+			}
+			table.foreignKeys[fmt.Sprintf("%s -> %s(%s)", from, parent, to.String)] = onDelete
+			return nil
+		}, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read foreign keys of %s: %w", name, err)
+		}
+
+		tables[name] = table
+	}
+
+	return tables, nil
+}
+
+// compareSchemas lists every difference between the expected and the actual
+// schema, sorted by table
+func compareSchemas(expected, actual map[string]schemaTable) []models.SchemaIssue {
+	issues := []models.SchemaIssue{}
+	add := func(kind, table, name, detail string) {
+		issues = append(issues, models.SchemaIssue{Kind: kind, Table: table, Name: name, Detail: detail})
+	}
+
+	for _, name := range sortedKeys(expected) {
+		want := expected[name]
+		got, ok := actual[name]
+		if !ok {
+			add(models.SchemaIssueMissingTable, name, "", "")
+			continue
+		}
+
+		for _, column := range sortedKeys(want.columns) {
+			wantColumn := want.columns[column]
+			gotColumn, ok := got.columns[column]
+			switch {
+			case !ok:
+				add(models.SchemaIssueMissingColumn, name, column, describeColumn(wantColumn))
+			case !strings.EqualFold(wantColumn.Type, gotColumn.Type) || wantColumn.NotNull != gotColumn.NotNull ||
+				wantColumn.Default != gotColumn.Default || wantColumn.PK != gotColumn.PK:
+				add(models.SchemaIssueColumnMismatch, name, column,
+					fmt.Sprintf("expected %s, found %s", describeColumn(wantColumn), describeColumn(gotColumn)))
+			}
+		}
+		for _, column := range sortedKeys(got.columns) {
+			if _, ok := want.columns[column]; !ok {
+				add(models.SchemaIssueExtraColumn, name, column, describeColumn(got.columns[column]))
+			}
+		}
+
+		for _, index := range sortedKeys(want.indexes) {
+			gotColumns, ok := got.indexes[index]
+			switch {
+			case !ok:
+				add(models.SchemaIssueMissingIndex, name, index, "on "+want.indexes[index])
+			case gotColumns != want.indexes[index]:
+				add(models.SchemaIssueIndexMismatch, name, index,
+					fmt.Sprintf("expected on %s, found on %s", want.indexes[index], gotColumns))
+			}
+		}
+		for _, index := range sortedKeys(got.indexes) {
+			if _, ok := want.indexes[index]; !ok {
+				add(models.SchemaIssueExtraIndex, name, index, "on "+got.indexes[index])
+			}
+		}
+
+		for _, key := range sortedKeys(want.foreignKeys) {
+			gotAction, ok := got.foreignKeys[key]
+			switch {
+			case !ok:
+				add(models.SchemaIssueMissingForeignKey, name, key, "ON DELETE "+want.foreignKeys[key])
+			case gotAction != want.foreignKeys[key]:
+				add(models.SchemaIssueForeignKeyMismatch, name, key,
+					fmt.Sprintf("expected ON DELETE %s, found ON DELETE %s", want.foreignKeys[key], gotAction))
+			}
+		}
+		for _, key := range sortedKeys(got.foreignKeys) {
+			if _, ok := want.foreignKeys[key]; !ok {
+				add(models.SchemaIssueExtraForeignKey, name, key, "ON DELETE "+got.foreignKeys[key])
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(actual) {
+		if _, ok := expected[name]; !ok {
+			add(models.SchemaIssueExtraTable, name, "", "")
+		}
+	}
+
+	return issues
+}
+
+// foreignKeyViolations runs PRAGMA foreign_key_check and groups the offending
+// rows by table and parent
+func foreignKeyViolations(q queryer) ([]models.ForeignKeyViolation, error) {
+	var violations []models.ForeignKeyViolation
+	index := make(map[string]int)
+
+	err := eachRow(q, `SELECT "table", rowid, parent FROM pragma_foreign_key_check`, func(rows *sql.Rows) error {
+		var table, parent string
+		var rowID sql.NullInt64
+		if err := rows.Scan(&table, &rowID, &parent); err != nil {
+			return err
+		}
+
+		key := table + "\x00" + parent
+		i, ok := index[key]
+		if !ok {
+			i = len(violations)
+			index[key] = i
+			violations = append(violations, models.ForeignKeyViolation{Table: table, Parent: parent, RowIDs: []int64{}})
+		}
+		violations[i].Rows++
+		if rowID.Valid && len(violations[i].RowIDs) < maxViolationSamples {
+			violations[i].RowIDs = append(violations[i].RowIDs, rowID.Int64)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+
+	return violations, nil
+}
+
+// describeColumn renders a column definition for the report
+func describeColumn(column schemaColumn) string {
+	parts := []string{column.Type}
+	if column.PK > 0 {
+		parts = append(parts, "PRIMARY KEY")
+	}
+	if column.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if column.Default.Valid {
+		parts = append(parts, "DEFAULT "+column.Default.String)
+	}
+	return strings.Join(parts, " ")
+}
+
+// eachRow runs query and calls scan for every row
+func eachRow(q queryer, query string, scan func(*sql.Rows) error, args ...interface{}) error {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// queryStrings runs a query returning a single text column
+func queryStrings(q queryer, query string, args ...interface{}) ([]string, error) {
+	var values []string
+	err := eachRow(q, query, func(rows *sql.Rows) error {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	}, args...)
+	return values, err
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
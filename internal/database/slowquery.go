@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// sqliteReadOnly is the SQLITE_READONLY result code
+const sqliteReadOnly = 8
+
+// slowQueryLog decides which statements are slow and reports them. It is
+// shared by both pools of a DB; a zero threshold turns it off.
+type slowQueryLog struct {
+	threshold time.Duration
+	onSlow    func(pool, query string, d time.Duration)
+}
+
+// pool is a connection pool whose Query, QueryRow and Exec report slow
+// statements. Transactions and everything else go straight to the *sql.DB.
+// On a read-only pool, writes fail with ErrReadOnly.
+type pool struct {
+	*sql.DB
+	name     string
+	slow     *slowQueryLog
+	readOnly bool
+}
+
+// LogSlowQueries logs every statement on either pool that takes at least
+// threshold, with its SQL. onSlow, when set, is also called for each one,
+// e.g. to count them. A zero threshold, the default, disables the log.
+func (db *DB) LogSlowQueries(threshold time.Duration, onSlow func(pool, query string, d time.Duration)) {
+	db.slow.threshold = threshold
+	db.slow.onSlow = onSlow
+}
+
+// Query runs a query that returns rows
+func (p pool) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer p.time(query)()
+	return p.DB.Query(query, args...)
+}
+
+// QueryRow runs a query that returns at most one row
+func (p pool) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer p.time(query)()
+	return p.DB.QueryRow(query, args...)
+}
+
+// Exec runs a statement that returns no rows
+func (p pool) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer p.time(query)()
+	result, err := p.DB.Exec(query, args...)
+	if err != nil && p.readOnly && isReadOnlyError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrReadOnly, err)
+	}
+	return result, err
+}
+
+// Begin starts a transaction. Transactions are only used to write, so a
+// read-only pool refuses them up front.
+func (p pool) Begin() (*sql.Tx, error) {
+	if p.readOnly {
+		return nil, ErrReadOnly
+	}
+	return p.DB.Begin()
+}
+
+// isReadOnlyError reports whether err is SQLite refusing a write to a
+// database opened read-only (SQLITE_READONLY or one of its extended codes)
+func isReadOnlyError(err error) bool {
+	var sqliteErr interface{ Code() int }
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqliteReadOnly
+}
+
+// time starts timing a statement; call the returned function when it is done
+func (p pool) time(query string) func() {
+	if p.slow == nil || p.slow.threshold <= 0 {
+		return func() {}
+	}
+	started := time.Now()
+	return func() {
+		d := time.Since(started)
+		if d < p.slow.threshold {
+			return
+		}
+		log.Printf("Slow query on %s pool (%s): %s", p.name, d.Round(time.Microsecond), strings.Join(strings.Fields(query), " "))
+		if p.slow.onSlow != nil {
+			p.slow.onSlow(p.name, query, d)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowQuery keeps SQLite busy for far longer than the thresholds below
+const slowQuery = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 300000) SELECT count(*) FROM c`
+
+func TestLogSlowQueries(t *testing.T) {
+	db := newTestDB(t)
+
+	var buf bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	type report struct{ pool, query string }
+	var reports []report
+	onSlow := func(pool, query string, d time.Duration) {
+		reports = append(reports, report{pool, query})
+	}
+	run := func(p pool, query string) {
+		t.Helper()
+		var n int
+		if err := p.QueryRow(query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+
+	// Off by default
+	run(db.reader, slowQuery)
+	if len(reports) != 0 || buf.Len() != 0 {
+		t.Errorf("slow query reported with the log off: %v %q", reports, buf.String())
+	}
+
+	db.LogSlowQueries(20*time.Millisecond, onSlow)
+	run(db.reader, `SELECT 1`)
+	if len(reports) != 0 {
+		t.Errorf("fast query reported as slow: %v", reports)
+	}
+
+	run(db.reader, slowQuery)
+	if len(reports) != 1 || reports[0].pool != "reader" || reports[0].query != slowQuery {
+		t.Fatalf("reports = %v, want the slow query on the reader pool", reports)
+	}
+	if !strings.Contains(buf.String(), "Slow query on reader pool") || !strings.Contains(buf.String(), "WITH RECURSIVE c(x)") {
+		t.Errorf("log = %q, want the slow query with its SQL", buf.String())
+	}
+
+	db.LogSlowQueries(0, onSlow)
+	run(db.reader, slowQuery)
+	if len(reports) != 1 {
+		t.Errorf("slow query reported after turning the log off: %v", reports)
+	}
+}
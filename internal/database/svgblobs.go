@@ -0,0 +1,142 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// svgContent selects the compressed SVG of an artwork or revision row, NULL
+// when it has none; decompressSVG turns it back into the SVG. Rows with
+// identical SVGs share one row of svg_blobs, keyed by the SHA-256 of the SVG.
+const svgContent = `(SELECT b.content FROM svg_blobs b WHERE b.id = svg_blob_id)`
+
+// execQueryer is satisfied by both the writer and a transaction on it
+type execQueryer interface {
+	execer
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// storeSVG returns the ID of the svg_blobs row holding svg, adding the row
+// when no artwork has had this SVG before. An empty SVG is stored as NULL.
+func storeSVG(q execQueryer, svg string) (sql.NullInt64, error) {
+	if svg == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	key := svgKey(svg)
+
+	var id int64
+	err := q.QueryRow(`SELECT id FROM svg_blobs WHERE sha256 = ?`, key).Scan(&id)
+	if err == nil {
+		return sql.NullInt64{Int64: id, Valid: true}, nil
+	}
+	if err != sql.ErrNoRows {
+		return sql.NullInt64{}, fmt.Errorf("failed to look up SVG blob: %w", err)
+	}
+
+	content, err := compressSVG(svg)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	result, err := q.Exec(`INSERT INTO svg_blobs (sha256, content, size) VALUES (?, ?, ?)`, key, content, len(svg))
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("failed to store SVG blob: %w", err)
+	}
+	if id, err = result.LastInsertId(); err != nil {
+		return sql.NullInt64{}, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// svgKey returns the key of svg in svg_blobs, its hex SHA-256
+func svgKey(svg string) string {
+	sum := sha256.Sum256([]byte(svg))
+	return hex.EncodeToString(sum[:])
+}
+
+// selectSVGBlobIDs returns the SVG blob IDs selected by query, so that a
+// write can collect the blobs it is about to release and hand them to
+// deleteSVGsIfUnused afterwards
+func selectSVGBlobIDs(tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SVG blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan SVG blob: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// deleteSVGsIfUnused removes those of the blobs ids that no artwork or
+// revision refers to anymore. Unlike deleteUnusedSVGs it only checks the
+// given blobs through the svg_blob_id indexes, so saving an SVG does not
+// scan svg_blobs.
+func deleteSVGsIfUnused(ex execer, ids []int64) error {
+	for _, id := range ids {
+		if _, err := ex.Exec(`DELETE FROM svg_blobs WHERE id = ?
+			AND NOT EXISTS (SELECT 1 FROM artworks WHERE svg_blob_id = ?)
+			AND NOT EXISTS (SELECT 1 FROM artwork_revisions WHERE svg_blob_id = ?)`, id, id, id); err != nil {
+			return fmt.Errorf("failed to delete unused SVG blob: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteUnusedSVGs removes the SVG blobs no artwork or revision refers to
+// anymore, including artworks in the recycle bin, and returns how many went.
+// It scans every blob, so it is kept for purges; writes that release known
+// blobs use deleteSVGsIfUnused.
+func deleteUnusedSVGs(ex execer) (int64, error) {
+	result, err := ex.Exec(`DELETE FROM svg_blobs
+		WHERE NOT EXISTS (SELECT 1 FROM artworks WHERE svg_blob_id = svg_blobs.id)
+		AND NOT EXISTS (SELECT 1 FROM artwork_revisions WHERE svg_blob_id = svg_blobs.id)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unused SVG blobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// compressSVG gzips an SVG for svg_blobs
+func compressSVG(svg string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(zw, svg); err != nil {
+		return nil, fmt.Errorf("failed to compress SVG: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress SVG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressSVG reverses compressSVG; a NULL blob is an empty SVG
+func decompressSVG(content []byte) (string, error) {
+	if content == nil {
+		return "", nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress SVG: %w", err)
+	}
+	svg, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress SVG: %w", err)
+	}
+	return string(svg), nil
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// numberedSVG returns a distinct SVG for each n
+func numberedSVG(n int) string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><circle cx="50" cy="50" r="%d"/></svg>`, n)
+}
+
+// hasBlob reports whether svg is stored in svg_blobs
+func hasBlob(t *testing.T, db *DB, svg string) bool {
+	t.Helper()
+	return countRows(t, db, "svg_blobs", "sha256 = ?", svgKey(svg)) == 1
+}
+
+func TestSaveArtworkSVGReleasesReplacedBlobs(t *testing.T) {
+	db := newTestDB(t)
+	db.KeepRevisions(1)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	artworkID := createTestArtwork(t, db, models.Artwork{GroupID: groupID, SVG: numberedSVG(1)})
+	shared := numberedSVG(100)
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, SVG: shared, Model: "google/gemini-2.5-pro"})
+
+	// A blob nothing refers to, as a crash could leave behind. Saves only
+	// check the blobs they release, so it stays until a purge.
+	orphan := numberedSVG(999)
+	if _, err := storeSVG(db.writer, orphan); err != nil {
+		t.Fatalf("storeSVG: %v", err)
+	}
+
+	steps := []struct {
+		svg         string
+		kept, freed []string
+	}{
+		// The replaced SVG becomes the only revision
+		{numberedSVG(2), []string{numberedSVG(1), numberedSVG(2)}, nil},
+		// Pruning the revision of SVG 1 releases its blob
+		{numberedSVG(3), []string{numberedSVG(2), numberedSVG(3)}, []string{numberedSVG(1)}},
+		// A blob shared with the other artwork survives both artworks moving on
+		{shared, []string{shared, numberedSVG(3)}, []string{numberedSVG(2)}},
+		{numberedSVG(4), []string{shared, numberedSVG(4)}, []string{numberedSVG(3)}},
+	}
+	for i, step := range steps {
+		if err := db.SaveArtworkSVG(artworkID, step.svg); err != nil {
+			t.Fatalf("step %d: SaveArtworkSVG: %v", i, err)
+		}
+		for _, svg := range step.kept {
+			if !hasBlob(t, db, svg) {
+				t.Errorf("step %d: blob of %s was deleted while in use", i, svg)
+			}
+		}
+		for _, svg := range step.freed {
+			if hasBlob(t, db, svg) {
+				t.Errorf("step %d: blob of %s was kept after its last use went", i, svg)
+			}
+		}
+		if !hasBlob(t, db, orphan) {
+			t.Errorf("step %d: saving an SVG swept an unrelated blob", i)
+		}
+	}
+
+	if _, err := db.DeleteGroupDeep(groupID); err != nil {
+		t.Fatalf("DeleteGroupDeep: %v", err)
+	}
+	if n := countRows(t, db, "svg_blobs", "1"); n != 0 {
+		t.Errorf("%d blobs left after purging the only group, want 0", n)
+	}
+}
+
+func TestPurgeArtworkReleasesBlobs(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	purged := createTestArtwork(t, db, models.Artwork{GroupID: groupID, SVG: numberedSVG(1)})
+	if err := db.SaveArtworkSVG(purged, numberedSVG(2)); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, SVG: numberedSVG(2), Model: "google/gemini-2.5-pro"})
+
+	if err := db.PurgeArtwork(purged); err != nil {
+		t.Fatalf("PurgeArtwork: %v", err)
+	}
+	if hasBlob(t, db, numberedSVG(1)) {
+		t.Error("the blob of the purged artwork's revision was kept")
+	}
+	if !hasBlob(t, db, numberedSVG(2)) {
+		t.Error("a blob shared with another artwork was deleted")
+	}
+}
+
+func TestImportOverwriteReleasesBlobs(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{Title: "Pelican"})
+	createTestArtwork(t, db, models.Artwork{GroupID: groupID, SVG: numberedSVG(1)})
+
+	imported := models.ImportGroup{
+		Group:    models.ArtworkGroup{Title: "Pelican", Prompt: "A pelican"},
+		Artworks: []models.Artwork{{Model: "openai/gpt-4o", SVG: numberedSVG(2)}},
+	}
+	if _, err := db.ImportGroups([]models.ImportGroup{imported}, true); err != nil {
+		t.Fatalf("ImportGroups: %v", err)
+	}
+	if hasBlob(t, db, numberedSVG(1)) {
+		t.Error("the blob of the overwritten artwork was kept")
+	}
+	if !hasBlob(t, db, numberedSVG(2)) {
+		t.Error("the imported SVG has no blob")
+	}
+}
+
+// seedBlobs fills a database with n artworks of distinct SVGs over groups of
+// ten and returns the artwork IDs
+func seedBlobs(b *testing.B, db *DB, n int) []int {
+	b.Helper()
+	var ids []int
+	var groupID int
+	for i := 0; i < n; i++ {
+		if i%10 == 0 {
+			var err error
+			if groupID, err = db.CreateGroup(models.ArtworkGroup{Title: fmt.Sprintf("Group %d", i/10), Prompt: "A pelican"}); err != nil {
+				b.Fatalf("CreateGroup: %v", err)
+			}
+		}
+		id, err := db.CreateArtwork(models.Artwork{GroupID: groupID, Model: fmt.Sprintf("model-%d", i%10), SVG: numberedSVG(i)})
+		if err != nil {
+			b.Fatalf("CreateArtwork: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func newBenchmarkDB(b *testing.B) *DB {
+	b.Helper()
+	db, err := New(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("New: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+// BenchmarkSaveArtworkSVG measures a save against databases of growing
+// size; with the save path checking only the blobs it releases, the time per
+// save should not grow with the number of blobs
+func BenchmarkSaveArtworkSVG(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("blobs=%d", n), func(b *testing.B) {
+			db := newBenchmarkDB(b)
+			ids := seedBlobs(b, db, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := db.SaveArtworkSVG(ids[i%len(ids)], numberedSVG(n+i)); err != nil {
+					b.Fatalf("SaveArtworkSVG: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkListGroupsWithArtworks measures the gallery query, which
+// decompresses every SVG in Go
+func BenchmarkListGroupsWithArtworks(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("artworks=%d", n), func(b *testing.B) {
+			db := newBenchmarkDB(b)
+			seedBlobs(b, db, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := db.ListGroupsWithArtworks("", false, models.DefaultGroupSort); err != nil {
+					b.Fatalf("ListGroupsWithArtworks: %v", err)
+				}
+			}
+		})
+	}
+}
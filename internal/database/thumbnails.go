@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"pelican-gallery/internal/render"
+)
+
+// renderThumbnail rasterizes an artwork's SVG as the thumbnail stored with
+// it. An SVG that is empty or cannot be rendered gets none; the thumbnail
+// endpoint tries again on request.
+func renderThumbnail(artworkID int, svg string) (thumbnail []byte, width int) {
+	if svg == "" {
+		return nil, 0
+	}
+	thumbnail, err := render.PNG(svg, render.ThumbnailWidth)
+	if err != nil {
+		log.Printf("Not storing a thumbnail for artwork %d: %v", artworkID, err)
+		return nil, 0
+	}
+	return thumbnail, render.ThumbnailWidth
+}
+
+// GetArtworkThumbnail returns the stored PNG thumbnail of an artwork and its
+// width, or nil when it has none yet
+func (db *DB) GetArtworkThumbnail(artworkID int) (thumbnail []byte, width int, err error) {
+	defer db.timeRead("GetArtworkThumbnail")()
+
+	err = db.reader.QueryRow(`SELECT thumbnail, thumbnail_width FROM artworks WHERE id = ? AND deleted_at IS NULL`, artworkID).
+		Scan(&thumbnail, &width)
+	if err == sql.ErrNoRows {
+		return nil, 0, fmt.Errorf("artwork with ID %d not found", artworkID)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get thumbnail: %w", err)
+	}
+	return thumbnail, width, nil
+}
+
+// SaveArtworkThumbnail stores a PNG thumbnail of the given width rendered
+// from svg, replacing the one the artwork had. Nothing is stored when the
+// artwork's SVG has changed since, so an old rendering never outlives it.
+func (db *DB) SaveArtworkThumbnail(artworkID int, svg string, thumbnail []byte, width int) error {
+	if _, err := db.writer.Exec(`UPDATE artworks SET thumbnail = ?, thumbnail_width = ?
+		WHERE id = ? AND deleted_at IS NULL AND svg_blob_id = (SELECT id FROM svg_blobs WHERE sha256 = ?)`,
+		thumbnail, width, artworkID, svgKey(svg)); err != nil {
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	return nil
+}
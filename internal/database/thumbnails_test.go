@@ -0,0 +1,64 @@
+package database
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/render"
+)
+
+// wideSVG is a 2:1 drawing that can be rendered
+const wideSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100"><rect width="200" height="100" fill="red"/></svg>`
+
+func TestThumbnailStoredOnSave(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	artworkID := createTestArtwork(t, db, models.Artwork{GroupID: groupID})
+
+	if thumbnail, _, err := db.GetArtworkThumbnail(artworkID); err != nil || thumbnail != nil {
+		t.Fatalf("thumbnail before an SVG = %d bytes, %v", len(thumbnail), err)
+	}
+
+	if err := db.SaveArtworkSVG(artworkID, wideSVG); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	thumbnail, width, err := db.GetArtworkThumbnail(artworkID)
+	if err != nil {
+		t.Fatalf("GetArtworkThumbnail: %v", err)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatalf("stored thumbnail is not a PNG: %v", err)
+	}
+	if width != render.ThumbnailWidth || config.Width != render.ThumbnailWidth || config.Height != render.ThumbnailWidth/2 {
+		t.Errorf("thumbnail is %dx%d stored as %d wide, want %dx%d", config.Width, config.Height, width, render.ThumbnailWidth, render.ThumbnailWidth/2)
+	}
+
+	// A thumbnail rendered from an SVG the artwork no longer has is dropped
+	if err := db.SaveArtworkThumbnail(artworkID, numberedSVG(1), []byte("stale"), 100); err != nil {
+		t.Fatalf("SaveArtworkThumbnail: %v", err)
+	}
+	if stored, width, _ := db.GetArtworkThumbnail(artworkID); !bytes.Equal(stored, thumbnail) || width != render.ThumbnailWidth {
+		t.Errorf("thumbnail of an old SVG replaced the current one")
+	}
+	if err := db.SaveArtworkThumbnail(artworkID, wideSVG, []byte("resized"), 100); err != nil {
+		t.Fatalf("SaveArtworkThumbnail: %v", err)
+	}
+	if stored, width, _ := db.GetArtworkThumbnail(artworkID); string(stored) != "resized" || width != 100 {
+		t.Errorf("thumbnail of the current SVG = %q, %d wide, want it stored", stored, width)
+	}
+
+	// An SVG that cannot be rendered is saved without a thumbnail
+	if err := db.SaveArtworkSVG(artworkID, `<svg xmlns="http://www.w3.org/2000/svg"><rect/></svg>`); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	if thumbnail, _, err := db.GetArtworkThumbnail(artworkID); err != nil || thumbnail != nil {
+		t.Errorf("thumbnail of an SVG without a viewBox = %d bytes, %v, want none", len(thumbnail), err)
+	}
+
+	if _, _, err := db.GetArtworkThumbnail(999); err == nil {
+		t.Error("GetArtworkThumbnail of a missing artwork succeeded")
+	}
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// votableArtwork is the condition for artworks that can be put to a vote:
+// public, not deleted and with an SVG to show
+const votableArtwork = `deleted_at IS NULL AND visibility = 'public' AND svg_blob_id IS NOT NULL`
+
+// GetRandomArtworkPair picks a random active group with votable artworks by
+// at least two models, then two of those artworks by different models. It
+// returns a nil group when no group qualifies.
+func (db *DB) GetRandomArtworkPair() (*models.ArtworkGroup, [2]models.Artwork, error) {
+	defer db.timeRead("GetRandomArtworkPair")()
+
+	var pair [2]models.Artwork
+
+	group, err := scanGroup(db.reader.QueryRow(`SELECT ` + groupColumns + `
+		FROM artwork_groups g
+		WHERE archived = 0 AND deleted_at IS NULL
+		AND (SELECT COUNT(DISTINCT model) FROM artworks a WHERE a.group_id = g.id AND ` + votableArtwork + `) >= 2
+		ORDER BY RANDOM()
+		LIMIT 1`))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, pair, nil
+		}
+		return nil, pair, fmt.Errorf("failed to pick a group to vote on: %w", err)
+	}
+
+	// Two random models first, then a random rendition of each, so a model
+	// with many renditions is not picked more often
+	rows, err := db.reader.Query(`
+		SELECT `+artworkColumns+`
+		FROM artworks
+		WHERE group_id = ? AND `+votableArtwork+`
+		AND model IN (
+			SELECT model FROM artworks WHERE group_id = ? AND `+votableArtwork+`
+			GROUP BY model ORDER BY RANDOM() LIMIT 2
+		)
+		ORDER BY RANDOM()`, group.ID, group.ID)
+	if err != nil {
+		return nil, pair, fmt.Errorf("failed to query artworks: %w", err)
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() && found < len(pair) {
+		artwork, err := scanArtwork(rows)
+		if err != nil {
+			return nil, pair, fmt.Errorf("failed to scan artwork: %w", err)
+		}
+		if found == 1 && artwork.Model == pair[0].Model {
+			continue
+		}
+		pair[found] = artwork
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pair, fmt.Errorf("error iterating artwork rows: %w", err)
+	}
+	if found < len(pair) {
+		// An artwork changed between the two queries
+		return nil, pair, nil
+	}
+
+	return &group, pair, nil
+}
+
+// GetSeededArtworkPair picks a group and two of its artworks by different
+// models like GetRandomArtworkPair, but draws from a random source seeded
+// with seed. The same seed picks the same pair as long as the qualifying
+// groups and their artworks are unchanged.
+func (db *DB) GetSeededArtworkPair(seed int64) (*models.ArtworkGroup, [2]models.Artwork, error) {
+	defer db.timeRead("GetSeededArtworkPair")()
+
+	var pair [2]models.Artwork
+	rng := rand.New(rand.NewSource(seed))
+
+	rows, err := db.reader.Query(`SELECT id FROM artwork_groups g
+		WHERE archived = 0 AND deleted_at IS NULL
+		AND (SELECT COUNT(DISTINCT model) FROM artworks a WHERE a.group_id = g.id AND ` + votableArtwork + `) >= 2
+		ORDER BY id`)
+	if err != nil {
+		return nil, pair, fmt.Errorf("failed to query groups to compare: %w", err)
+	}
+	var groupIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, pair, fmt.Errorf("failed to scan group ID: %w", err)
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, pair, fmt.Errorf("error iterating group ID rows: %w", err)
+	}
+	if len(groupIDs) == 0 {
+		return nil, pair, nil
+	}
+
+	group, err := scanGroup(db.reader.QueryRow(`SELECT `+groupColumns+` FROM artwork_groups WHERE id = ?`, groupIDs[rng.Intn(len(groupIDs))]))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, pair, nil
+		}
+		return nil, pair, fmt.Errorf("failed to get group to compare: %w", err)
+	}
+
+	rows, err = db.reader.Query(`SELECT `+artworkColumns+`
+		FROM artworks
+		WHERE group_id = ? AND `+votableArtwork+`
+		ORDER BY model, id`, group.ID)
+	if err != nil {
+		return nil, pair, fmt.Errorf("failed to query artworks: %w", err)
+	}
+	defer rows.Close()
+
+	// As with GetRandomArtworkPair, two models first and then a rendition of
+	// each, so a model with many renditions is not picked more often
+	var modelNames []string
+	byModel := make(map[string][]models.Artwork)
+	for rows.Next() {
+		artwork, err := scanArtwork(rows)
+		if err != nil {
+			return nil, pair, fmt.Errorf("failed to scan artwork: %w", err)
+		}
+		if len(byModel[artwork.Model]) == 0 {
+			modelNames = append(modelNames, artwork.Model)
+		}
+		byModel[artwork.Model] = append(byModel[artwork.Model], artwork)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pair, fmt.Errorf("error iterating artwork rows: %w", err)
+	}
+	if len(modelNames) < len(pair) {
+		// An artwork changed between the two queries
+		return nil, pair, nil
+	}
+
+	for i, m := range rng.Perm(len(modelNames))[:len(pair)] {
+		renditions := byModel[modelNames[m]]
+		pair[i] = renditions[rng.Intn(len(renditions))]
+	}
+
+	return &group, pair, nil
+}
+
+// RecordVote stores a vote unless the same voter already voted on the same
+// two artworks, in either order; it reports whether the vote was stored
+func (db *DB) RecordVote(vote models.Vote) (bool, error) {
+	recorded := false
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRow(`
+		SELECT 1 FROM votes
+		WHERE fingerprint = ?
+		AND ((winner_artwork_id = ? AND loser_artwork_id = ?) OR (winner_artwork_id = ? AND loser_artwork_id = ?))`,
+			vote.Fingerprint, vote.WinnerArtworkID, vote.LoserArtworkID, vote.LoserArtworkID, vote.WinnerArtworkID).Scan(&exists)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to check for an earlier vote: %w", err)
+		}
+
+		if vote.CreatedAt.IsZero() {
+			vote.CreatedAt = time.Now()
+		}
+		_, err = tx.Exec(`
+		INSERT INTO votes (group_id, winner_artwork_id, loser_artwork_id, winner_model, loser_model, fingerprint, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			vote.GroupID, vote.WinnerArtworkID, vote.LoserArtworkID, vote.WinnerModel, vote.LoserModel, vote.Fingerprint, vote.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record vote: %w", err)
+		}
+
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded, nil
+}
+
+// ListVotes returns every vote in the order it was cast
+func (db *DB) ListVotes() ([]models.Vote, error) {
+	defer db.timeRead("ListVotes")()
+
+	return db.queryVotes(`
+		SELECT id, group_id, winner_artwork_id, loser_artwork_id, winner_model, loser_model, fingerprint, created_at
+		FROM votes
+		ORDER BY id`)
+}
+
+// ListCategoryVotes returns the votes on groups in category, in the order
+// they were cast
+func (db *DB) ListCategoryVotes(category string) ([]models.Vote, error) {
+	defer db.timeRead("ListCategoryVotes")()
+
+	return db.queryVotes(`
+		SELECT v.id, v.group_id, v.winner_artwork_id, v.loser_artwork_id, v.winner_model, v.loser_model, v.fingerprint, v.created_at
+		FROM votes v
+		JOIN artwork_groups g ON g.id = v.group_id
+		WHERE g.category = ?
+		ORDER BY v.id`, category)
+}
+
+// queryVotes runs a query selecting the columns of votes
+func (db *DB) queryVotes(query string, args ...interface{}) ([]models.Vote, error) {
+	rows, err := db.reader.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", err)
+	}
+	defer rows.Close()
+
+	var votes []models.Vote
+	for rows.Next() {
+		var vote models.Vote
+		if err := rows.Scan(&vote.ID, &vote.GroupID, &vote.WinnerArtworkID, &vote.LoserArtworkID,
+			&vote.WinnerModel, &vote.LoserModel, &vote.Fingerprint, &vote.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", err)
+		}
+		votes = append(votes, vote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vote rows: %w", err)
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestRecordVoteRefusesDoubleVotes(t *testing.T) {
+	db := newTestDB(t)
+	groupID := createTestGroup(t, db, models.ArtworkGroup{})
+	a := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "a/model", SVG: testSVG})
+	b := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "b/model", SVG: testSVG})
+	c := createTestArtwork(t, db, models.Artwork{GroupID: groupID, Model: "c/model", SVG: testSVG})
+
+	vote := func(winner, loser int, fingerprint string) models.Vote {
+		return models.Vote{GroupID: groupID, WinnerArtworkID: winner, LoserArtworkID: loser, Fingerprint: fingerprint}
+	}
+	tests := []struct {
+		name string
+		vote models.Vote
+		want bool
+	}{
+		{"first vote", vote(a, b, "alice"), true},
+		{"same vote again", vote(a, b, "alice"), false},
+		{"reversed vote", vote(b, a, "alice"), false},
+		{"another voter", vote(b, a, "bob"), true},
+		{"another pair", vote(a, c, "alice"), true},
+	}
+	for _, tt := range tests {
+		recorded, err := db.RecordVote(tt.vote)
+		if err != nil || recorded != tt.want {
+			t.Errorf("%s: RecordVote = %v, %v, want %v", tt.name, recorded, err, tt.want)
+		}
+	}
+
+	votes, err := db.ListVotes()
+	if err != nil || len(votes) != 3 {
+		t.Errorf("ListVotes = %d votes, %v, want 3", len(votes), err)
+	}
+}
+
+func TestArtworkPairs(t *testing.T) {
+	db := newTestDB(t)
+
+	// Only one group can be voted on: the others have a single model with
+	// an SVG, or are archived
+	votable := createTestGroup(t, db, models.ArtworkGroup{Title: "Votable"})
+	createTestArtwork(t, db, models.Artwork{GroupID: votable, Model: "a/model", SVG: testSVG})
+	createTestArtwork(t, db, models.Artwork{GroupID: votable, Model: "a/model", SVG: testSVG})
+	createTestArtwork(t, db, models.Artwork{GroupID: votable, Model: "b/model", SVG: testSVG})
+	createTestArtwork(t, db, models.Artwork{GroupID: votable, Model: "c/model"})
+	createTestArtwork(t, db, models.Artwork{GroupID: votable, Model: "d/model", SVG: testSVG, Visibility: models.VisibilityPrivate})
+
+	oneModel := createTestGroup(t, db, models.ArtworkGroup{Title: "One model"})
+	createTestArtwork(t, db, models.Artwork{GroupID: oneModel, Model: "a/model", SVG: testSVG})
+	createTestArtwork(t, db, models.Artwork{GroupID: oneModel, Model: "a/model", SVG: testSVG})
+	createTestArtwork(t, db, models.Artwork{GroupID: oneModel, Model: "b/model"})
+
+	archived := createTestGroup(t, db, models.ArtworkGroup{Title: "Archived"})
+	createTestArtwork(t, db, models.Artwork{GroupID: archived, Model: "a/model", SVG: testSVG})
+	createTestArtwork(t, db, models.Artwork{GroupID: archived, Model: "b/model", SVG: testSVG})
+	if err := db.SetGroupArchived(archived, true); err != nil {
+		t.Fatalf("SetGroupArchived: %v", err)
+	}
+
+	check := func(name string, group *models.ArtworkGroup, pair [2]models.Artwork, err error) {
+		t.Helper()
+		if err != nil || group == nil {
+			t.Fatalf("%s = %v, %v", name, group, err)
+		}
+		if group.ID != votable {
+			t.Errorf("%s picked group %q", name, group.Title)
+		}
+		if pair[0].Model == pair[1].Model {
+			t.Errorf("%s picked two artworks by %s", name, pair[0].Model)
+		}
+		for _, artwork := range pair {
+			if artwork.GroupID != votable || artwork.SVG == "" || (artwork.Model != "a/model" && artwork.Model != "b/model") {
+				t.Errorf("%s picked %+v", name, artwork)
+			}
+		}
+	}
+	for i := 0; i < 20; i++ {
+		group, pair, err := db.GetRandomArtworkPair()
+		check("GetRandomArtworkPair", group, pair, err)
+		group, pair, err = db.GetSeededArtworkPair(int64(i))
+		check("GetSeededArtworkPair", group, pair, err)
+	}
+
+	// The same seed picks the same pair
+	_, first, _ := db.GetSeededArtworkPair(42)
+	_, again, _ := db.GetSeededArtworkPair(42)
+	if first[0].ID != again[0].ID || first[1].ID != again[1].ID {
+		t.Errorf("seed 42 picked %d/%d, then %d/%d", first[0].ID, first[1].ID, again[0].ID, again[1].ID)
+	}
+
+	// Without votable groups there is no pair
+	empty := newTestDB(t)
+	if group, _, err := empty.GetRandomArtworkPair(); group != nil || err != nil {
+		t.Errorf("GetRandomArtworkPair on an empty database = %v, %v", group, err)
+	}
+	if group, _, err := empty.GetSeededArtworkPair(1); group != nil || err != nil {
+		t.Errorf("GetSeededArtworkPair on an empty database = %v, %v", group, err)
+	}
+}
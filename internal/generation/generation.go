@@ -0,0 +1,333 @@
+// Package generation generates the SVGs of artworks through a Generator and
+// stores them, for the HTTP API and the command line alike.
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/metrics"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+	"pelican-gallery/internal/sanitize"
+)
+
+// Workers bounds how many generations run concurrently in a batch
+const Workers = 4
+
+// maxContinuations bounds the follow-up requests made for output cut off at
+// max_tokens when auto-continue is on
+const maxContinuations = 2
+
+// Artwork states reported to the progress callback of GenerateBatch
+const (
+	StatusStarted   = "started"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Service generates artworks and stores the results
+type Service struct {
+	prompts   *config.PromptStore
+	db        *database.DB
+	generator openrouter.Generator
+	metrics   *metrics.AppMetrics
+
+	// slots bounds the generations running at once across every caller, to
+	// protect the OpenRouter rate limits and budget
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewService creates a generation service that runs at most
+// MAX_CONCURRENT_GENERATIONS generations at once
+func NewService(prompts *config.PromptStore, db *database.DB, generator openrouter.Generator, appMetrics *metrics.AppMetrics) *Service {
+	return &Service{
+		prompts:      prompts,
+		db:           db,
+		generator:    generator,
+		metrics:      appMetrics,
+		slots:        make(chan struct{}, config.MaxConcurrentGenerations()),
+		queueTimeout: config.GenerationQueueTimeout(),
+	}
+}
+
+// ModelDeprecatedError is returned when regenerating an artwork whose model
+// OpenRouter no longer offers
+type ModelDeprecatedError struct {
+	Model          string
+	SuggestedModel string
+}
+
+func (e *ModelDeprecatedError) Error() string {
+	if e.SuggestedModel == "" {
+		return fmt.Sprintf("model %s is no longer available on OpenRouter", e.Model)
+	}
+	return fmt.Sprintf("model %s is no longer available on OpenRouter; try %s", e.Model, e.SuggestedModel)
+}
+
+// SVGTruncatedError is returned when the model ran out of max_tokens before
+// finishing the SVG
+type SVGTruncatedError struct {
+	MaxTokens     int
+	Continuations int
+}
+
+func (e *SVGTruncatedError) Error() string {
+	if e.Continuations > 0 {
+		return fmt.Sprintf("SVG was still cut off at max_tokens=%d after %d continuation(s); raise max_tokens", e.MaxTokens, e.Continuations)
+	}
+	return fmt.Sprintf("SVG was cut off at max_tokens=%d; raise max_tokens or retry with auto_continue", e.MaxTokens)
+}
+
+func (e *SVGTruncatedError) Unwrap() error { return openrouter.ErrTruncated }
+
+// ErrSVGIncomplete marks output whose <svg> elements are not all closed
+var ErrSVGIncomplete = errors.New("generated SVG is incomplete: <svg> and </svg> tags are unbalanced")
+
+// ErrBusy is returned when no generation slot frees up within the queue
+// timeout
+var ErrBusy = errors.New("too many generations in progress, try again later")
+
+// ErrSaveSVG marks generation results that were produced but could not be persisted
+var ErrSaveSVG = errors.New("failed to save SVG")
+
+// svgOpenTag and svgCloseTag match the opening and closing tags of svg elements
+var (
+	svgOpenTag  = regexp.MustCompile(`(?i)<svg[\s>]`)
+	svgCloseTag = regexp.MustCompile(`(?i)</svg\s*>`)
+)
+
+// hasBalancedSVGTags is a cheap structural check that catches output cut off
+// mid-document: it needs at least one <svg> and a closing tag for each
+func hasBalancedSVGTags(svg string) bool {
+	opened := len(svgOpenTag.FindAllStringIndex(svg, -1))
+	return opened > 0 && opened == len(svgCloseTag.FindAllStringIndex(svg, -1))
+}
+
+// queueKey marks contexts whose generations wait for a slot for as long as
+// the context allows, instead of the queue timeout
+type queueKey struct{}
+
+// acquireSlot waits for a generation slot and returns the function that
+// frees it. It gives up with ErrBusy after the queue timeout, except for
+// batch generations, which wait for their turn until ctx is done.
+func (s *Service) acquireSlot(ctx context.Context) (release func(), err error) {
+	release = func() { <-s.slots }
+	select {
+	case s.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	wait := ctx
+	if ctx.Value(queueKey{}) == nil {
+		var cancel context.CancelFunc
+		wait, cancel = context.WithTimeout(ctx, s.queueTimeout)
+		defer cancel()
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return release, nil
+	case <-wait.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrBusy
+	}
+}
+
+// GenerateSVG calls the generator to produce an SVG, recording call metrics.
+// With autoContinue, output cut off at max_tokens is continued. It waits for
+// one of the MAX_CONCURRENT_GENERATIONS slots first and fails with ErrBusy
+// when none frees up in time.
+func (s *Service) GenerateSVG(ctx context.Context, req openrouter.GenerationRequest, autoContinue bool) (string, error) {
+	release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	var output strings.Builder
+	for continuation := 0; ; continuation++ {
+		started := time.Now()
+		result, err := s.generator.GenerateSVG(ctx, req)
+
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+			switch openrouter.ClassifyError(err) {
+			case "timeout":
+				outcome = "timeout"
+			case "canceled":
+				outcome = "canceled"
+			}
+		}
+		s.metrics.OpenRouterRequests.Inc(req.Model, outcome)
+		s.metrics.GenerationDuration.Observe(time.Since(started).Seconds(), req.Model)
+
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(result.Content)
+
+		if result.FinishReason != openrouter.FinishReasonLength {
+			break
+		}
+		if !autoContinue || continuation == maxContinuations {
+			return "", &SVGTruncatedError{MaxTokens: req.MaxTokens, Continuations: continuation}
+		}
+
+		slog.InfoContext(ctx, fmt.Sprintf("Output of %s was cut off at max_tokens=%d, requesting continuation %d", req.Model, req.MaxTokens, continuation+1),
+			"model", req.Model, "max_tokens", req.MaxTokens, "continuation", continuation+1)
+		req.Continue = output.String()
+	}
+
+	svg := strings.TrimSpace(output.String())
+	if !hasBalancedSVGTags(svg) {
+		return "", ErrSVGIncomplete
+	}
+	return svg, nil
+}
+
+// GenerateAndSave generates the SVG for an artwork, records the attempt and
+// stores the result. Persistence failures are wrapped in ErrSaveSVG.
+// Cancelling ctx aborts the OpenRouter call; an SVG that has already been
+// generated is still saved.
+func (s *Service) GenerateAndSave(ctx context.Context, artwork *models.Artwork, group *models.ArtworkGroup, autoContinue bool) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if suggestion, deprecated := config.DeprecatedModel(artwork.Model); deprecated {
+		return "", &ModelDeprecatedError{Model: artwork.Model, SuggestedModel: suggestion}
+	}
+
+	started := time.Now()
+	svg, err := s.GenerateSVG(ctx, openrouter.GenerationRequest{
+		PromptConfig:    s.prompts.Load().Get(group.PromptStyle),
+		Prompt:          group.Prompt,
+		Title:           group.Title,
+		Category:        group.Category,
+		Model:           artwork.Model,
+		Temperature:     artwork.Temperature,
+		MaxTokens:       artwork.MaxTokens,
+		ReasoningEffort: artwork.ReasoningEffort,
+	}, autoContinue)
+	s.recordAttempt(artwork, started, err)
+	if err != nil {
+		return "", err
+	}
+
+	slog.InfoContext(ctx, fmt.Sprintf("Generated SVG for artwork %d: length=%d characters", artwork.ID, len(svg)),
+		"artwork_id", artwork.ID, "model", artwork.Model, "svg_length", len(svg))
+
+	svg = sanitize.SVG(svg)
+
+	if err := s.db.SaveGeneratedSVG(artwork.ID, svg); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSaveSVG, err)
+	}
+
+	return svg, nil
+}
+
+// recordAttempt stores the outcome of a generation call for the error dashboard.
+// Failures to record are logged but never fail the generation. Cancelled
+// calls and calls turned away by the queue say nothing about the model and
+// are not recorded.
+func (s *Service) recordAttempt(artwork *models.Artwork, started time.Time, genErr error) {
+	if errors.Is(genErr, context.Canceled) || errors.Is(genErr, ErrBusy) {
+		return
+	}
+
+	attempt := models.GenerationAttempt{
+		ArtworkID:  artwork.ID,
+		GroupID:    artwork.GroupID,
+		Model:      artwork.Model,
+		Success:    genErr == nil,
+		DurationMS: time.Since(started).Milliseconds(),
+		CreatedAt:  time.Now(),
+	}
+	if genErr != nil {
+		attempt.ErrorClass = openrouter.ClassifyError(genErr)
+		attempt.Error = genErr.Error()
+	}
+
+	if err := s.db.RecordGenerationAttempt(attempt); err != nil {
+		log.Printf("Error recording generation attempt (artwork=%d): %v", artwork.ID, err)
+	}
+}
+
+// BatchResult reports the outcome of generating a single artwork in a batch
+type BatchResult struct {
+	ArtworkID int    `json:"artwork_id"`
+	Model     string `json:"model"`
+	Success   bool   `json:"success"`
+	SVGLength int    `json:"svg_length,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// GenerateBatch generates every artwork of group using a bounded worker pool.
+// Results are returned in the same order as the input; a failure never stops
+// the others. If progress is set it is called when an artwork starts and
+// when it ends. Once ctx is cancelled the calls in flight are aborted and
+// the remaining artworks fail without being generated. Artworks wait for a
+// generation slot for as long as ctx allows rather than failing with ErrBusy.
+func (s *Service) GenerateBatch(ctx context.Context, group *models.ArtworkGroup, artworks []models.Artwork, autoContinue bool, progress func(idx int, status string, result BatchResult)) []BatchResult {
+	ctx = context.WithValue(ctx, queueKey{}, true)
+	results := make([]BatchResult, len(artworks))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < Workers && i < len(artworks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				artwork := &artworks[idx]
+				result := BatchResult{ArtworkID: artwork.ID, Model: artwork.Model}
+				if progress != nil {
+					progress(idx, StatusStarted, result)
+				}
+
+				svg, err := s.GenerateAndSave(ctx, artwork, group, autoContinue)
+				if err != nil {
+					slog.ErrorContext(ctx, fmt.Sprintf("Batch: error generating artwork %d (%s): %v", artwork.ID, artwork.Model, err),
+						"artwork_id", artwork.ID, "model", artwork.Model, "error", err)
+					result.Error = err.Error()
+					if errors.Is(err, ErrSaveSVG) {
+						result.Error = "Failed to save SVG"
+					}
+				} else {
+					result.Success = true
+					result.SVGLength = len(svg)
+				}
+
+				results[idx] = result
+				if progress != nil {
+					status := StatusCompleted
+					if !result.Success {
+						status = StatusFailed
+					}
+					progress(idx, status, result)
+				}
+			}
+		}()
+	}
+
+	for i := range artworks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,392 @@
+package generation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/metrics"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+)
+
+// testSVG is a small valid SVG for the fake generator to return
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4"/></svg>`
+
+// fakeGenerator stands in for OpenRouter. Each call takes the next of
+// results, repeating the last one, and every request is recorded.
+type fakeGenerator struct {
+	results []fakeResult
+
+	mu       sync.Mutex
+	requests []openrouter.GenerationRequest
+}
+
+// fakeResult is one answer of fakeGenerator
+type fakeResult struct {
+	result openrouter.GenerationResult
+	err    error
+}
+
+func (g *fakeGenerator) GenerateSVG(ctx context.Context, req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests = append(g.requests, req)
+
+	if len(g.results) == 0 {
+		return openrouter.GenerationResult{SVG: testSVG, Content: testSVG, FinishReason: "stop"}, nil
+	}
+	next := g.results[min(len(g.requests), len(g.results))-1]
+	return next.result, next.err
+}
+
+// calls returns the requests the generator got so far
+func (g *fakeGenerator) calls() []openrouter.GenerationRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]openrouter.GenerationRequest(nil), g.requests...)
+}
+
+// newTestService returns a service on a fresh database, with the prompt
+// styles of config/prompts, generating through generator
+func newTestService(t *testing.T, generator openrouter.Generator) (*Service, *database.DB) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	prompts, err := config.NewPromptStore("../../config/prompts")
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+	return NewService(prompts, db, generator, metrics.NewAppMetrics()), db
+}
+
+// seedArtwork creates a group with style and an artwork of it without an
+// SVG
+func seedArtwork(t *testing.T, db *database.DB, style string) (*models.ArtworkGroup, *models.Artwork) {
+	t.Helper()
+	now := time.Now()
+	group := models.ArtworkGroup{Title: "Pelican", Prompt: "a pelican riding a bicycle", PromptStyle: style, CreatedAt: now, UpdatedAt: now}
+	var err error
+	if group.ID, err = db.CreateGroup(group); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	artwork := models.Artwork{GroupID: group.ID, Model: "openai/gpt-4o", MaxTokens: 1000, CreatedAt: now, UpdatedAt: now}
+	if artwork.ID, err = db.CreateArtwork(artwork); err != nil {
+		t.Fatalf("CreateArtwork: %v", err)
+	}
+	return &group, &artwork
+}
+
+func TestGenerateAndSaveUsesGroupPromptStyle(t *testing.T) {
+	generator := &fakeGenerator{}
+	service, db := newTestService(t, generator)
+
+	library := service.prompts.Load()
+	var style string
+	for name := range library.Configs {
+		if name != library.Default {
+			style = name
+		}
+	}
+	if style == "" {
+		t.Fatal("config/prompts has no style besides the default")
+	}
+
+	for _, tt := range []struct{ groupStyle, want string }{
+		{style, style},
+		{"", library.Default},
+	} {
+		group, artwork := seedArtwork(t, db, tt.groupStyle)
+		if _, err := service.GenerateAndSave(context.Background(), artwork, group, false); err != nil {
+			t.Fatalf("GenerateAndSave: %v", err)
+		}
+
+		calls := generator.calls()
+		if got := calls[len(calls)-1].PromptConfig; got.Name != library.Get(tt.want).Name {
+			t.Errorf("group style %q generated with %q, want %q", tt.groupStyle, got.Name, tt.want)
+		}
+
+		saved, err := db.GetArtwork(artwork.ID)
+		if err != nil {
+			t.Fatalf("GetArtwork: %v", err)
+		}
+		if saved.SVG != testSVG {
+			t.Errorf("saved SVG = %q", saved.SVG)
+		}
+	}
+}
+
+// chatMessage is a message of a chat completions request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// fakeUpstream is an OpenRouter chat completions endpoint answering with
+// parts in turn, repeating the last one. Each part ending in "…" is cut off
+// at max_tokens. It records the messages of every request.
+type fakeUpstream struct {
+	*httptest.Server
+	parts []string
+
+	mu       sync.Mutex
+	requests [][]chatMessage
+}
+
+func newFakeUpstream(t *testing.T, parts ...string) *fakeUpstream {
+	t.Helper()
+	u := &fakeUpstream{parts: parts}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []chatMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+
+		u.mu.Lock()
+		u.requests = append(u.requests, body.Messages)
+		part := u.parts[min(len(u.requests), len(u.parts))-1]
+		u.mu.Unlock()
+
+		finishReason := "stop"
+		if content, cut := strings.CutSuffix(part, "…"); cut {
+			part, finishReason = content, openrouter.FinishReasonLength
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   "openai/gpt-4o",
+			"choices": []map[string]interface{}{{"message": chatMessage{Role: "assistant", Content: part}, "finish_reason": finishReason}},
+		})
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// calls returns the messages of the requests the upstream got so far
+func (u *fakeUpstream) calls() [][]chatMessage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([][]chatMessage(nil), u.requests...)
+}
+
+func TestGenerateSVGTruncation(t *testing.T) {
+	const (
+		head = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect`
+		body = ` width="5" height="5"/>`
+		tail = `</svg>`
+	)
+	tests := []struct {
+		name              string
+		parts             []string
+		autoContinue      bool
+		wantSVG           string
+		wantContinuations int // of the SVGTruncatedError, when no SVG is wanted
+		wantErr           error
+		wantCalls         int
+	}{
+		{"complete", []string{head + body + tail}, false, head + body + tail, 0, nil, 1},
+		{"cut off without auto-continue", []string{head + "…"}, false, "", 0, openrouter.ErrTruncated, 1},
+		{"continued once", []string{head + "…", body + tail}, true, head + body + tail, 0, nil, 2},
+		{"continued twice", []string{head + "…", body + "…", tail}, true, head + body + tail, 0, nil, 3},
+		{"still cut off after the continuations", []string{head + "…"}, true, "", maxContinuations, openrouter.ErrTruncated, maxContinuations + 1},
+		{"unbalanced", []string{head + body}, false, "", 0, ErrSVGIncomplete, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newFakeUpstream(t, tt.parts...)
+			service, db := newTestService(t, &openrouter.Client{BaseURL: upstream.URL, APIKey: "key"})
+			group, artwork := seedArtwork(t, db, "")
+
+			svg, err := service.GenerateAndSave(context.Background(), artwork, group, tt.autoContinue)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateAndSave error = %v, want %v", err, tt.wantErr)
+			}
+			var truncated *SVGTruncatedError
+			if errors.As(err, &truncated) && truncated.Continuations != tt.wantContinuations {
+				t.Errorf("truncated after %d continuations, want %d", truncated.Continuations, tt.wantContinuations)
+			}
+			if svg != tt.wantSVG {
+				t.Errorf("SVG = %q, want %q", svg, tt.wantSVG)
+			}
+			if got := len(upstream.calls()); got != tt.wantCalls {
+				t.Errorf("%d requests to OpenRouter, want %d", got, tt.wantCalls)
+			}
+
+			// Only a complete SVG is stored
+			saved, err := db.GetArtwork(artwork.ID)
+			if err != nil {
+				t.Fatalf("GetArtwork: %v", err)
+			}
+			if saved.SVG != tt.wantSVG {
+				t.Errorf("stored SVG = %q, want %q", saved.SVG, tt.wantSVG)
+			}
+		})
+	}
+}
+
+func TestGenerateSVGContinuationMessages(t *testing.T) {
+	upstream := newFakeUpstream(t, "<svg>…", "<rect/>…", "</svg>")
+	service, _ := newTestService(t, &openrouter.Client{BaseURL: upstream.URL, APIKey: "key"})
+
+	svg, err := service.GenerateSVG(context.Background(), openrouter.GenerationRequest{
+		PromptConfig: service.prompts.Load().Get(""),
+		Prompt:       "a pelican",
+		Model:        "openai/gpt-4o",
+		MaxTokens:    100,
+	}, true)
+	if err != nil {
+		t.Fatalf("GenerateSVG: %v", err)
+	}
+	if svg != "<svg><rect/></svg>" {
+		t.Errorf("SVG = %q", svg)
+	}
+
+	calls := upstream.calls()
+	if len(calls) != 3 {
+		t.Fatalf("%d requests, want 3", len(calls))
+	}
+	prompt := calls[0]
+	for i, soFar := range []string{"<svg>", "<svg><rect/>"} {
+		messages := calls[i+1]
+		if len(messages) != len(prompt)+2 {
+			t.Fatalf("continuation %d has %d messages, want the %d of the prompt and 2 more", i+1, len(messages), len(prompt))
+		}
+		assistant, user := messages[len(prompt)], messages[len(prompt)+1]
+		if assistant.Role != "assistant" || assistant.Content != soFar {
+			t.Errorf("continuation %d replays %+v, want the output so far %q", i+1, assistant, soFar)
+		}
+		if user.Role != "user" || user.Content == "" {
+			t.Errorf("continuation %d asks %+v, want a user message", i+1, user)
+		}
+	}
+}
+
+// blockingGenerator holds every call until release is closed, counting the
+// calls in progress and the most it has seen at once
+type blockingGenerator struct {
+	release chan struct{}
+	started chan struct{}
+
+	mu            sync.Mutex
+	running, peak int
+}
+
+func newBlockingGenerator() *blockingGenerator {
+	return &blockingGenerator{release: make(chan struct{}), started: make(chan struct{}, 100)}
+}
+
+func (g *blockingGenerator) GenerateSVG(ctx context.Context, req openrouter.GenerationRequest) (openrouter.GenerationResult, error) {
+	g.mu.Lock()
+	g.running++
+	g.peak = max(g.peak, g.running)
+	g.mu.Unlock()
+	g.started <- struct{}{}
+
+	<-g.release
+	g.mu.Lock()
+	g.running--
+	g.mu.Unlock()
+	return openrouter.GenerationResult{SVG: testSVG, Content: testSVG, FinishReason: "stop"}, nil
+}
+
+// waitStarted waits until n calls have reached the generator
+func (g *blockingGenerator) waitStarted(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-g.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d generations started", i, n)
+		}
+	}
+}
+
+func TestGenerateSVGCapsConcurrency(t *testing.T) {
+	t.Setenv("MAX_CONCURRENT_GENERATIONS", "2")
+	t.Setenv("GENERATION_QUEUE_TIMEOUT", "10s")
+	generator := newBlockingGenerator()
+	s, _ := newTestService(t, generator)
+
+	const calls = 5
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			_, err := s.GenerateSVG(context.Background(), openrouter.GenerationRequest{Model: "openai/gpt-4o"}, false)
+			errs <- err
+		}()
+	}
+
+	generator.waitStarted(t, 2)
+	// The other calls queue rather than reach the generator
+	select {
+	case <-generator.started:
+		t.Fatal("a third generation started while two were running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Once slots free up, every queued call gets its turn
+	close(generator.release)
+	for i := 0; i < calls; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("GenerateSVG: %v", err)
+		}
+	}
+	if generator.peak != 2 {
+		t.Errorf("at most %d generations ran at once, want 2", generator.peak)
+	}
+}
+
+func TestGenerateSVGQueueTimeout(t *testing.T) {
+	t.Setenv("MAX_CONCURRENT_GENERATIONS", "1")
+	t.Setenv("GENERATION_QUEUE_TIMEOUT", "50ms")
+	generator := newBlockingGenerator()
+	s, _ := newTestService(t, generator)
+	req := openrouter.GenerationRequest{Model: "openai/gpt-4o"}
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := s.GenerateSVG(context.Background(), req, false)
+		first <- err
+	}()
+	generator.waitStarted(t, 1)
+
+	// Past the queue timeout a call is turned away
+	if _, err := s.GenerateSVG(context.Background(), req, false); !errors.Is(err, ErrBusy) {
+		t.Errorf("GenerateSVG with every slot taken = %v, want ErrBusy", err)
+	}
+
+	// A cancelled call reports its own error, not ErrBusy
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.GenerateSVG(ctx, req, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled GenerateSVG = %v, want context.Canceled", err)
+	}
+
+	// Batch generations wait past the timeout for as long as ctx allows
+	batch := make(chan error, 1)
+	go func() {
+		_, err := s.GenerateSVG(context.WithValue(context.Background(), queueKey{}, true), req, false)
+		batch <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	close(generator.release)
+	if err := <-first; err != nil {
+		t.Errorf("first GenerateSVG: %v", err)
+	}
+	if err := <-batch; err != nil {
+		t.Errorf("queued batch GenerateSVG: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package images prepares uploaded reference images for storage: it checks
+// their size, drops their metadata and produces a full and a thumbnail
+// version.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	// Decoders for the accepted upload formats
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	// FullSize is the longest side of the full version of an image
+	FullSize = 2000
+	// ThumbSize is the longest side of the thumbnail
+	ThumbSize = 400
+	// MaxPixels bounds the decoded size of an upload, so a small file that
+	// decompresses to a huge bitmap is rejected before it is decoded
+	MaxPixels = 40_000_000
+
+	jpegQuality = 85
+)
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image is too large")
+
+// ErrUnsupported is returned for data that is not a JPEG, PNG, GIF or WebP
+// image
+var ErrUnsupported = errors.New("unsupported image format")
+
+// Processed is an image ready to be stored. Both versions have the same
+// content type: JPEG for opaque images, PNG for images with transparency.
+type Processed struct {
+	ContentType string
+	Full        []byte
+	Thumb       []byte
+}
+
+// Process decodes a JPEG, PNG, GIF or WebP image and re-encodes it at most
+// FullSize and ThumbSize pixels on its longest side. Re-encoding leaves out
+// EXIF and other metadata; the EXIF orientation of a JPEG is applied to the
+// pixels first so photos stay upright. Only the first frame of an animated
+// GIF is kept.
+func Process(data []byte) (*Processed, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("%w: image has no pixels", ErrUnsupported)
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d is more than %d pixels", ErrTooLarge, config.Width, config.Height, MaxPixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %w", format, err)
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	full := orient(fit(src, FullSize), orientation)
+	thumb := orient(fit(full, ThumbSize), 1)
+
+	processed := &Processed{ContentType: "image/jpeg"}
+	if !full.Opaque() {
+		processed.ContentType = "image/png"
+	}
+	if processed.Full, err = encode(full, processed.ContentType); err != nil {
+		return nil, err
+	}
+	if processed.Thumb, err = encode(thumb, processed.ContentType); err != nil {
+		return nil, err
+	}
+	return processed, nil
+}
+
+// fit scales src down to at most size pixels on its longest side. Smaller
+// images keep their size. The result is always a fresh *image.RGBA.
+func fit(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(height*size/width, 1)
+		} else {
+			width, height = max(width*size/height, 1), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == bounds.Dx() && height == bounds.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	} else {
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	}
+	return dst
+}
+
+// orient transforms img as described by an EXIF orientation value, 1 to 8,
+// so that it displays upright
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-dx, dy
+			case 3: // rotated 180°
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // mirrored vertically
+				sx, sy = dx, h-1-dy
+			case 5: // transposed
+				sx, sy = dy, dx
+			case 6: // rotated 90° clockwise to display
+				sx, sy = dy, h-1-dx
+			case 7: // transversed
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // rotated 90° counter-clockwise to display
+				sx, sy = w-1-dy, dx
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// encode encodes img as contentType, image/jpeg or image/png
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
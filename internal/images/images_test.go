@@ -0,0 +1,236 @@
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// webpFixture is a 1x1 lossless WebP image with a transparent pixel
+const webpFixture = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+// opaqueImage is a width x height image in one opaque colour
+func opaqueImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:i+4], []byte{200, 120, 40, 255})
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 segment holding an EXIF orientation and a
+// camera make right after the start of a JPEG
+func withExif(jpegData []byte, orientation uint16) []byte {
+	// Little-endian TIFF header and an IFD with two entries
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x010F) // Make
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)      // ASCII
+	tiff = binary.LittleEndian.AppendUint32(tiff, 4)
+	tiff = append(tiff, "Cam\x00"...)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, app1...)
+	return append(out, jpegData[2:]...)
+}
+
+// withSize rewrites the dimensions in the IHDR chunk of a PNG, so it claims
+// a size its pixel data does not have
+func withSize(pngData []byte, width, height uint32) []byte {
+	out := append([]byte{}, pngData...)
+	// 8 byte signature, then the IHDR length, type and data
+	ihdr := out[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+// decoded decodes an image produced by Process and checks its format
+func decoded(t *testing.T, data []byte, contentType string) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding processed image: %v", err)
+	}
+	if "image/"+format != contentType {
+		t.Errorf("processed image is %s, want %s", format, contentType)
+	}
+	return img
+}
+
+func TestProcess(t *testing.T) {
+	transparent := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	transparent.Set(10, 10, color.RGBA{R: 255, A: 255})
+	webp, err := base64.StdEncoding.DecodeString(webpFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		full, thumb image.Point
+	}{
+		{"large JPEG", encodeJPEG(t, opaqueImage(3000, 1500)), "image/jpeg", image.Pt(2000, 1000), image.Pt(400, 200)},
+		{"tall JPEG", encodeJPEG(t, opaqueImage(500, 1000)), "image/jpeg", image.Pt(500, 1000), image.Pt(200, 400)},
+		{"small JPEG", encodeJPEG(t, opaqueImage(120, 80)), "image/jpeg", image.Pt(120, 80), image.Pt(120, 80)},
+		{"opaque PNG", encodePNG(t, opaqueImage(800, 800)), "image/jpeg", image.Pt(800, 800), image.Pt(400, 400)},
+		{"transparent PNG", encodePNG(t, transparent), "image/png", image.Pt(600, 300), image.Pt(400, 200)},
+		{"GIF", encodeGIF(t, opaqueImage(64, 32)), "image/jpeg", image.Pt(64, 32), image.Pt(64, 32)},
+		{"WebP", webp, "image/png", image.Pt(1, 1), image.Pt(1, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := Process(tt.data)
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			if processed.ContentType != tt.contentType {
+				t.Errorf("content type = %s, want %s", processed.ContentType, tt.contentType)
+			}
+			if size := decoded(t, processed.Full, tt.contentType).Bounds().Size(); size != tt.full {
+				t.Errorf("full size = %v, want %v", size, tt.full)
+			}
+			if size := decoded(t, processed.Thumb, tt.contentType).Bounds().Size(); size != tt.thumb {
+				t.Errorf("thumb size = %v, want %v", size, tt.thumb)
+			}
+		})
+	}
+}
+
+func TestProcessAppliesAndStripsExif(t *testing.T) {
+	// A landscape photo taken with the camera turned: upright, it is portrait
+	data := withExif(encodeJPEG(t, opaqueImage(300, 200)), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("fixture orientation = %d, want 6", got)
+	}
+
+	processed, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if size := decoded(t, processed.Full, "image/jpeg").Bounds().Size(); size != image.Pt(200, 300) {
+		t.Errorf("full size = %v, want the upright 200x300", size)
+	}
+	for name, out := range map[string][]byte{"full": processed.Full, "thumb": processed.Thumb} {
+		if bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte("Cam\x00")) {
+			t.Errorf("%s image still has EXIF metadata", name)
+		}
+		if got := jpegOrientation(out); got != 1 {
+			t.Errorf("%s image orientation = %d, want none", name, got)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image, red then blue
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		want        [][]color.RGBA // rows of the result
+	}{
+		{1, [][]color.RGBA{{red, blue}}},
+		{2, [][]color.RGBA{{blue, red}}},
+		{3, [][]color.RGBA{{blue, red}}},
+		{4, [][]color.RGBA{{red, blue}}},
+		{5, [][]color.RGBA{{red}, {blue}}},
+		{6, [][]color.RGBA{{red}, {blue}}},
+		{7, [][]color.RGBA{{blue}, {red}}},
+		{8, [][]color.RGBA{{blue}, {red}}},
+		{9, [][]color.RGBA{{red, blue}}},
+	}
+	for _, tt := range tests {
+		got := orient(img, tt.orientation)
+		if got.Bounds().Dx() != len(tt.want[0]) || got.Bounds().Dy() != len(tt.want) {
+			t.Errorf("orientation %d: size = %v", tt.orientation, got.Bounds().Size())
+			continue
+		}
+		for y, row := range tt.want {
+			for x, want := range row {
+				if c := got.RGBAAt(x, y); c != want {
+					t.Errorf("orientation %d: pixel %d,%d = %v, want %v", tt.orientation, x, y, c, want)
+				}
+			}
+		}
+	}
+}
+
+func TestProcessRejectsImages(t *testing.T) {
+	small := encodePNG(t, opaqueImage(1, 1))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"too many pixels", withSize(small, 10000, 5000), ErrTooLarge},
+		{"too wide", withSize(small, 1_000_000, 41), ErrTooLarge},
+		{"not an image", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), ErrUnsupported},
+		{"empty", nil, ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Process error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// At the limit the header passes, and only the missing pixel data fails
+	if _, err := Process(withSize(small, 8000, 5000)); err == nil || errors.Is(err, ErrTooLarge) {
+		t.Errorf("Process of exactly MaxPixels error = %v, want a decode error", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Package logging configures the application log. Messages go through
+// log/slog, so the key/value attributes of a message, such as the method,
+// status and duration of a request, are kept when logging JSON.
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+)
+
+// Setup makes the default slog and log loggers write to w. With format
+// "json" every message is one JSON object with its attributes; otherwise
+// messages are written as plain log lines, as they always were. Either way a
+// message logged with a request's context carries its request ID.
+func Setup(format string, w io.Writer) {
+	var handler slog.Handler
+	if format == "json" {
+		handler = requestIDHandler{slog.NewJSONHandler(w, nil)}
+	} else {
+		handler = &textHandler{out: log.New(w, "", log.LstdFlags)}
+	}
+	// SetDefault also routes log.Printf through the handler
+	slog.SetDefault(slog.New(handler))
+}
+
+// textHandler writes the message of each record as a classic log line,
+// prefixed with the request ID when there is one. The attributes are left
+// out: the messages already describe what happened.
+type textHandler struct {
+	out *log.Logger
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *textHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		return h.out.Output(0, "["+id+"] "+r.Message)
+	}
+	return h.out.Output(0, r.Message)
+}
+
+func (h *textHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *textHandler) WithGroup(string) slog.Handler { return h }
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// captureLog sets up logging in format into a buffer, restoring plain
+// logging to stderr when the test ends
+func captureLog(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	Setup(format, &buf)
+	t.Cleanup(func() { Setup("", os.Stderr) })
+	return &buf
+}
+
+func TestSetupJSON(t *testing.T) {
+	buf := captureLog(t, "json")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	slog.InfoContext(ctx, "Completed GET /gallery/", "method", "GET", "status", 200, "duration_ms", 1.5)
+	slog.Warn("Model unknown", "model", "openai/gpt-4o")
+	log.Printf("Plain message %d", 42)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%d log lines, want 3:\n%s", len(lines), buf)
+	}
+	want := []map[string]interface{}{
+		{"level": "INFO", "msg": "Completed GET /gallery/", "method": "GET", "status": 200.0, "duration_ms": 1.5, "request_id": "req-1"},
+		{"level": "WARN", "msg": "Model unknown", "model": "openai/gpt-4o"},
+		{"level": "INFO", "msg": "Plain message 42"},
+	}
+	for i, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Errorf("line %d is not JSON: %v\n%s", i+1, err, line)
+			continue
+		}
+		if _, ok := record["time"].(string); !ok {
+			t.Errorf("line %d has no time: %s", i+1, line)
+		}
+		for key, value := range want[i] {
+			if record[key] != value {
+				t.Errorf("line %d: %s = %v, want %v", i+1, key, record[key], value)
+			}
+		}
+		if _, ok := want[i]["request_id"]; !ok && record["request_id"] != nil {
+			t.Errorf("line %d has request_id %v without a request", i+1, record["request_id"])
+		}
+	}
+}
+
+func TestSetupText(t *testing.T) {
+	buf := captureLog(t, "")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	slog.InfoContext(ctx, "Completed GET /gallery/", "method", "GET", "status", 200)
+	log.Printf("Plain message %d", 42)
+	slog.Debug("Not shown")
+
+	// Classic log lines: a timestamp and the message, without attributes
+	stamp := `\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `
+	want := regexp.MustCompile(`^` + stamp + `\[req-1\] Completed GET /gallery/\n` + stamp + `Plain message 42\n$`)
+	if !want.MatchString(buf.String()) {
+		t.Errorf("text log =\n%s", buf)
+	}
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDContext(t *testing.T) {
+	if id := RequestID(context.Background()); id != "" {
+		t.Errorf("RequestID of a bare context = %q, want none", id)
+	}
+	if id := RequestID(WithRequestID(context.Background(), "req-1")); id != "req-1" {
+		t.Errorf("RequestID = %q, want req-1", id)
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewRequestID()
+		if !uuid.MatchString(id) {
+			t.Fatalf("NewRequestID() = %q, want a version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewRequestID() repeated %q", id)
+		}
+		seen[id] = true
+		if !ValidRequestID(id) {
+			t.Errorf("ValidRequestID(%q) = false for a generated ID", id)
+		}
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"req-1", true},
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"trace:abc/123", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{"", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"two words", false},
+		{"line\nbreak", false},
+		{"tab\there", false},
+		{"naïve", false},
+	}
+	for _, tt := range tests {
+		if got := ValidRequestID(tt.id); got != tt.want {
+			t.Errorf("ValidRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"strings"
+	"unicode"
+)
+
+// AppMetrics groups the metric families exported by the gallery
+type AppMetrics struct {
+	*Registry
+	HTTPRequests       *CounterVec
+	HTTPDuration       *HistogramVec
+	OpenRouterRequests *CounterVec
+	GenerationDuration *HistogramVec
+	DBReadDuration     *HistogramVec
+	DBSlowQueries      *CounterVec
+}
+
+// NewAppMetrics creates the gallery's metric families on a fresh registry
+func NewAppMetrics() *AppMetrics {
+	reg := NewRegistry()
+	return &AppMetrics{
+		Registry: reg,
+		HTTPRequests: reg.NewCounterVec(
+			"pelican_http_requests_total",
+			"Total HTTP requests by route, method and status code.",
+			"path", "method", "status",
+		),
+		HTTPDuration: reg.NewHistogramVec(
+			"pelican_http_request_duration_seconds",
+			"HTTP request latency by route and method.",
+			DefaultBuckets,
+			"path", "method",
+		),
+		OpenRouterRequests: reg.NewCounterVec(
+			"pelican_openrouter_requests_total",
+			"OpenRouter generation calls by model and result: success, error, timeout or canceled.",
+			"model", "result",
+		),
+		GenerationDuration: reg.NewHistogramVec(
+			"pelican_generation_duration_seconds",
+			"Duration of SVG generation calls by model.",
+			GenerationBuckets,
+			"model",
+		),
+		DBReadDuration: reg.NewHistogramVec(
+			"pelican_db_read_duration_seconds",
+			"Latency of read queries on the database read pool by query.",
+			DefaultBuckets,
+			"query",
+		),
+		DBSlowQueries: reg.NewCounterVec(
+			"pelican_db_slow_queries_total",
+			"Statements that reached SLOW_QUERY_THRESHOLD by connection pool.",
+			"pool",
+		),
+	}
+}
+
+// RouteLabel collapses numeric path segments so per-resource URLs share a
+// label, e.g. "/api/groups/12/generate-all" becomes "/api/groups/:id/generate-all".
+// Static assets are collapsed into a single label to keep cardinality bounded.
+func RouteLabel(path string) string {
+	if strings.HasPrefix(path, "/static/") {
+		return "/static/*"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && isNumeric(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesPrometheusText(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.NewCounterVec("test_requests_total", "Requests.", "path")
+	duration := reg.NewHistogramVec("test_duration_seconds", "Duration.", []float64{1, 0.1}, "path")
+
+	requests.Inc("/a")
+	requests.Add(2, `/b"`)
+	duration.Observe(0.05, "/a")
+	duration.Observe(0.5, "/a")
+
+	var out strings.Builder
+	reg.Write(&out)
+	want := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{path="/a",le="0.1"} 1
+test_duration_seconds_bucket{path="/a",le="1"} 2
+test_duration_seconds_bucket{path="/a",le="+Inf"} 2
+test_duration_seconds_sum{path="/a"} 0.55
+test_duration_seconds_count{path="/a"} 2
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{path="/a"} 1
+test_requests_total{path="/b\""} 2
+`
+	if out.String() != want {
+		t.Errorf("Write =\n%s\nwant\n%s", out.String(), want)
+	}
+	if got := requests.Value("/a"); got != 1 {
+		t.Errorf("Value = %v, want 1", got)
+	}
+	if got := duration.Count("/a"); got != 2 {
+		t.Errorf("Count = %d, want 2", got)
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/", "/"},
+		{"/api/groups", "/api/groups"},
+		{"/api/groups/12/generate-all", "/api/groups/:id/generate-all"},
+		{"/group/7", "/group/:id"},
+		{"/static/css/styles.css", "/static/*"},
+	}
+	for _, tt := range tests {
+		if got := RouteLabel(tt.path); got != tt.want {
+			t.Errorf("RouteLabel(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// ELO parameters of the leaderboard. Every model starts at EloInitialRating;
+// EloK is the most a rating moves in one vote.
+const (
+	EloInitialRating = 1500
+	EloK             = 32
+)
+
+// Vote is one head-to-head vote between renditions of the same group. The
+// models are stored with the vote so ratings survive later edits.
+type Vote struct {
+	ID              int       `json:"id"`
+	GroupID         int       `json:"group_id"`
+	WinnerArtworkID int       `json:"winner_artwork_id"`
+	LoserArtworkID  int       `json:"loser_artwork_id"`
+	WinnerModel     string    `json:"winner_model"`
+	LoserModel      string    `json:"loser_model"`
+	Fingerprint     string    `json:"-"` // hash of the voter's IP and user agent
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ModelRating is a model's place on the leaderboard
+type ModelRating struct {
+	Model  string  `json:"model"`
+	Rating float64 `json:"rating"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+}
+
+// EloRatings replays votes in the given order and returns the resulting
+// rating of every model that took part, best first. The same votes in the
+// same order always give the same ratings; ties are broken by model ID.
+func EloRatings(votes []Vote) []ModelRating {
+	ratings := make(map[string]*ModelRating)
+	rating := func(model string) *ModelRating {
+		r, ok := ratings[model]
+		if !ok {
+			r = &ModelRating{Model: model, Rating: EloInitialRating}
+			ratings[model] = r
+		}
+		return r
+	}
+
+	for _, vote := range votes {
+		if vote.WinnerModel == vote.LoserModel {
+			continue
+		}
+		winner, loser := rating(vote.WinnerModel), rating(vote.LoserModel)
+
+		expected := 1 / (1 + math.Pow(10, (loser.Rating-winner.Rating)/400))
+		delta := EloK * (1 - expected)
+		winner.Rating += delta
+		loser.Rating -= delta
+		winner.Wins++
+		loser.Losses++
+	}
+
+	leaderboard := make([]ModelRating, 0, len(ratings))
+	for _, r := range ratings {
+		leaderboard = append(leaderboard, *r)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Rating != leaderboard[j].Rating {
+			return leaderboard[i].Rating > leaderboard[j].Rating
+		}
+		return leaderboard[i].Model < leaderboard[j].Model
+	})
+	return leaderboard
+}
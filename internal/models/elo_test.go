@@ -0,0 +1,82 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func vote(winner, loser string) Vote {
+	return Vote{WinnerModel: winner, LoserModel: loser}
+}
+
+func TestEloRatings(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []Vote
+		want  []ModelRating
+	}{
+		{"no votes", nil, []ModelRating{}},
+		{
+			"one vote between new models",
+			[]Vote{vote("a", "b")},
+			[]ModelRating{{"a", 1516, 1, 0}, {"b", 1484, 0, 1}},
+		},
+		{
+			"favourite wins again",
+			[]Vote{vote("a", "b"), vote("a", "b")},
+			[]ModelRating{{"a", 1530.5304984710244, 2, 0}, {"b", 1469.4695015289756, 0, 2}},
+		},
+		{
+			"upset",
+			[]Vote{vote("a", "b"), vote("b", "a")},
+			[]ModelRating{{"b", 1501.4695015289756, 1, 1}, {"a", 1498.5304984710244, 1, 1}},
+		},
+		{
+			"same model votes are skipped",
+			[]Vote{vote("a", "a"), vote("a", "b"), vote("b", "b")},
+			[]ModelRating{{"a", 1516, 1, 0}, {"b", 1484, 0, 1}},
+		},
+		{
+			"equal ratings are ordered by model",
+			[]Vote{vote("d", "c"), vote("b", "a")},
+			[]ModelRating{{"b", 1516, 1, 0}, {"d", 1516, 1, 0}, {"a", 1484, 0, 1}, {"c", 1484, 0, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EloRatings(tt.votes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("EloRatings = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Model != w.Model || g.Wins != w.Wins || g.Losses != w.Losses || math.Abs(g.Rating-w.Rating) > 1e-9 {
+					t.Errorf("rating %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestEloRatingsDeterministic(t *testing.T) {
+	votes := []Vote{
+		vote("a", "b"), vote("c", "a"), vote("b", "c"), vote("a", "c"),
+		vote("b", "a"), vote("c", "b"), vote("a", "b"), vote("a", "c"),
+	}
+	first := EloRatings(votes)
+	for i := 0; i < 20; i++ {
+		if got := EloRatings(votes); !reflect.DeepEqual(got, first) {
+			t.Fatalf("run %d = %+v, want %+v", i, got, first)
+		}
+	}
+
+	// Ratings depend on the order of the votes, not just their count
+	reversed := make([]Vote, len(votes))
+	for i, v := range votes {
+		reversed[len(votes)-1-i] = v
+	}
+	if got := EloRatings(reversed); reflect.DeepEqual(got, first) {
+		t.Errorf("reversed votes rated the same: %+v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import "strings"
+
+// ModelOverride holds the generation parameters of the models matching a
+// pattern. Temperature, MaxTokens and ReasoningEffort are defaults for
+// artworks that do not set them; MaxTokensLimit, OmitTemperature and
+// SystemPrompt are applied to every generation with the model.
+type ModelOverride struct {
+	// Match is a model ID ("openai/gpt-4o"), a prefix ending in "*"
+	// ("openai/o*") or a pattern with "*" anywhere ("*/*-thinking")
+	Match           string   `yaml:"match" json:"match"`
+	Temperature     *float64 `yaml:"temperature" json:"temperature,omitempty"`
+	MaxTokens       int      `yaml:"max_tokens" json:"max_tokens,omitempty"`
+	ReasoningEffort string   `yaml:"reasoning_effort" json:"reasoning_effort,omitempty"`
+
+	// MaxTokensLimit caps max_tokens for models that reject larger values
+	MaxTokensLimit int `yaml:"max_tokens_limit" json:"max_tokens_limit,omitempty"`
+	// OmitTemperature leaves temperature out of the request, for reasoning
+	// models that refuse it
+	OmitTemperature bool `yaml:"omit_temperature" json:"omit_temperature,omitempty"`
+	// SystemPrompt is sent as an extra system message after the prompt
+	// style's own
+	SystemPrompt string `yaml:"system_prompt" json:"system_prompt,omitempty"`
+}
+
+// ModelOverrides is the configuration of per-model generation parameters
+type ModelOverrides struct {
+	Overrides []ModelOverride `yaml:"model_overrides"`
+}
+
+// Pattern kinds in order of precedence
+const (
+	patternExact = iota
+	patternPrefix
+	patternWildcard
+)
+
+// patternKind classifies an override pattern: without "*" it is exact, with
+// a single trailing "*" after some text a prefix, and otherwise, "*" alone
+// included, a wildcard
+func patternKind(pattern string) int {
+	switch strings.Count(pattern, "*") {
+	case 0:
+		return patternExact
+	case 1:
+		if len(pattern) > 1 && strings.HasSuffix(pattern, "*") {
+			return patternPrefix
+		}
+	}
+	return patternWildcard
+}
+
+// matchPattern reports whether model matches pattern, where "*" stands for
+// any run of characters, slashes included
+func matchPattern(pattern, model string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == model
+	}
+
+	first, last := parts[0], parts[len(parts)-1]
+	if len(model) < len(first)+len(last) || !strings.HasPrefix(model, first) || !strings.HasSuffix(model, last) {
+		return false
+	}
+	rest := model[len(first) : len(model)-len(last)]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return true
+}
+
+// Match returns the override for model, or nil when none matches. An exact
+// ID wins over a prefix and a prefix over a wildcard; among patterns of the
+// same kind the longest wins, then the first in the file.
+func (o *ModelOverrides) Match(model string) *ModelOverride {
+	if o == nil {
+		return nil
+	}
+
+	var best *ModelOverride
+	bestKind := 0
+	for i := range o.Overrides {
+		override := &o.Overrides[i]
+		if !matchPattern(override.Match, model) {
+			continue
+		}
+		kind := patternKind(override.Match)
+		if best == nil || kind < bestKind || (kind == bestKind && len(override.Match) > len(best.Match)) {
+			best, bestKind = override, kind
+		}
+	}
+	return best
+}
@@ -0,0 +1,89 @@
+package models
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, model string
+		want           bool
+	}{
+		{"openai/gpt-4o", "openai/gpt-4o", true},
+		{"openai/gpt-4o", "openai/gpt-4o-mini", false},
+		{"openai/o*", "openai/o3", true},
+		{"openai/o*", "openai/o", true},
+		{"openai/o*", "anthropic/openai-o3", false},
+		{"*", "any/model", true},
+		{"*-thinking*", "anthropic/claude-3.7-thinking:beta", true},
+		{"*-thinking*", "anthropic/claude-3.7", false},
+		{"*/o*-mini", "openai/o4-mini", true},
+		{"*/o*-mini", "openai/o4-mini-high", false},
+		{"a*b*b", "ab", false},
+		{"a*b*b", "abb", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.model); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestModelOverridesMatch(t *testing.T) {
+	overrides := &ModelOverrides{Overrides: []ModelOverride{
+		{Match: "*"},
+		{Match: "*/o*-mini"},
+		{Match: "*-mini"},
+		{Match: "openai/*"},
+		{Match: "openai/o*"},
+		{Match: "openai/o3"},
+		{Match: "openai/o4*"},
+		{Match: "google/*"},
+		{Match: "google/gemini*"},
+		{Match: "*gemini*"},
+		{Match: "*flash*"},
+	}}
+
+	tests := []struct {
+		model, want string
+	}{
+		// An exact ID beats every pattern
+		{"openai/o3", "openai/o3"},
+		// A prefix beats a wildcard, and the longest prefix wins
+		{"openai/o4-mini", "openai/o4*"},
+		{"openai/o1", "openai/o*"},
+		{"openai/gpt-4o-mini", "openai/*"},
+		// Among wildcards the longest wins
+		{"mistral/o1-mini", "*/o*-mini"},
+		{"mistral/small-mini", "*-mini"},
+		// Between wildcards of the same length the first listed wins
+		{"acme/gemini-flash", "*gemini*"},
+		{"acme/model", "*"},
+	}
+	for _, tt := range tests {
+		got := overrides.Match(tt.model)
+		if got == nil || got.Match != tt.want {
+			t.Errorf("Match(%q) = %+v, want %q", tt.model, got, tt.want)
+		}
+	}
+
+	// The result does not depend on the order of the overrides
+	reversed := &ModelOverrides{}
+	for i := len(overrides.Overrides) - 1; i >= 0; i-- {
+		reversed.Overrides = append(reversed.Overrides, overrides.Overrides[i])
+	}
+	for _, tt := range tests {
+		if tt.model == "acme/gemini-flash" {
+			continue
+		}
+		if got := reversed.Match(tt.model); got == nil || got.Match != tt.want {
+			t.Errorf("reversed Match(%q) = %+v, want %q", tt.model, got, tt.want)
+		}
+	}
+
+	if got := (&ModelOverrides{Overrides: []ModelOverride{{Match: "openai/*"}}}).Match("anthropic/claude"); got != nil {
+		t.Errorf("Match without a matching pattern = %+v, want nil", got)
+	}
+	var none *ModelOverrides
+	if got := none.Match("openai/o3"); got != nil {
+		t.Errorf("Match on nil overrides = %+v, want nil", got)
+	}
+}
@@ -1,13 +1,73 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
 
 // PromptConfig represents the YAML configuration for the LLM prompts
 type PromptConfig struct {
 	Name               string         `yaml:"name"`
 	Description        string         `yaml:"description"`
+	Default            bool           `yaml:"default"`
 	SystemPrompts      []SystemPrompt `yaml:"system_prompts"`
 	UserPromptTemplate string         `yaml:"user_prompt_template"`
+	// UserPrompt is UserPromptTemplate parsed, set when the library is loaded
+	UserPrompt *template.Template `yaml:"-" json:"-"`
+}
+
+// PromptLibrary holds the prompt configurations groups can choose from,
+// keyed by name
+type PromptLibrary struct {
+	Configs map[string]*PromptConfig
+	Default string
+}
+
+// PromptStyle describes a prompt configuration for style pickers
+type PromptStyle struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// Has reports whether style names a loaded prompt configuration. The empty
+// style is always valid and selects the default.
+func (l *PromptLibrary) Has(style string) bool {
+	if style == "" {
+		return true
+	}
+	_, ok := l.Configs[style]
+	return ok
+}
+
+// Get returns the prompt configuration for style, falling back to the default
+// for the empty style or one that is no longer loaded
+func (l *PromptLibrary) Get(style string) *PromptConfig {
+	if config, ok := l.Configs[style]; ok {
+		return config
+	}
+	return l.Configs[l.Default]
+}
+
+// Styles lists the available prompt styles sorted by name
+func (l *PromptLibrary) Styles() []PromptStyle {
+	styles := make([]PromptStyle, 0, len(l.Configs))
+	for name, config := range l.Configs {
+		styles = append(styles, PromptStyle{
+			Name:        name,
+			Description: config.Description,
+			Default:     name == l.Default,
+		})
+	}
+	sort.Slice(styles, func(i, j int) bool {
+		return styles[i].Name < styles[j].Name
+	})
+	return styles
 }
 
 // SystemPrompt represents a system prompt with role and content
@@ -18,34 +78,283 @@ type SystemPrompt struct {
 
 // ArtworkGroup represents a group of artworks with the same prompt
 type ArtworkGroup struct {
-	ID              int       `db:"id" json:"id"`
-	Title           string    `db:"title" json:"title"`
-	Prompt          string    `db:"prompt" json:"prompt"`
-	Category        string    `db:"category" json:"category"`
-	OriginalURL     string    `db:"original_url" json:"original_url"`
-	ArtistName      string    `db:"artist_name" json:"artist_name"`
-	OriginalArtwork []byte    `db:"original_artwork" json:"-"`
-	CreatedAt       time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+	ID           int        `db:"id" json:"id"`
+	Title        string     `db:"title" json:"title"`
+	Prompt       string     `db:"prompt" json:"prompt"`
+	Category     string     `db:"category" json:"category"`
+	CategorySlug string     `db:"-" json:"category_slug,omitempty"` // slug of the category's gallery URL
+	OriginalURL  string     `db:"original_url" json:"original_url"`
+	ArtistName   string     `db:"artist_name" json:"artist_name"`
+	PromptStyle  string     `db:"prompt_style" json:"prompt_style"`
+	Archived     bool       `db:"archived" json:"archived"`
+	SortOrder    int        `db:"sort_order" json:"sort_order"`           // position in the manual gallery order
+	DeletedAt    *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // set while in the recycle bin
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+
+	// HasOriginalArtwork reports whether a reference image is stored in
+	// original_artworks; the image itself is loaded separately
+	HasOriginalArtwork bool `db:"-" json:"-"`
+}
+
+// GroupSummary is a group with the number of its artworks, as listed by
+// GET /api/groups. GeneratedCount counts the artworks that have an SVG.
+type GroupSummary struct {
+	ArtworkGroup
+	ArtworkCount   int `json:"artwork_count"`
+	GeneratedCount int `json:"generated_count"`
+}
+
+// ModelComparison pairs the renditions of one group by two models
+type ModelComparison struct {
+	Group ArtworkGroup `json:"group"`
+	A     Artwork      `json:"a"`
+	B     Artwork      `json:"b"`
+}
+
+// ModelRenditions are the artworks of one group by one model, with what is
+// known about the model. Name and Cost are empty while OpenRouter's model
+// list has not been loaded or no longer has the model.
+type ModelRenditions struct {
+	Model          string    `json:"model"`
+	Name           string    `json:"name,omitempty"`
+	Provider       string    `json:"provider"`
+	Cost           float64   `json:"cost,omitempty"` // Cost per 1M output tokens in dollars
+	Deprecated     bool      `json:"deprecated"`
+	SuggestedModel string    `json:"suggested_model,omitempty"`
+	Artworks       []Artwork `json:"artworks"`
+}
+
+// GroupComparison lays the renditions of a group side by side, one entry
+// per model
+type GroupComparison struct {
+	Group  ArtworkGroup      `json:"group"`
+	Models []ModelRenditions `json:"models"`
+}
+
+// OriginalArtwork is the uploaded reference image of a group. Data is the
+// full version and Thumbnail a small one of the same content type; images
+// that could not be processed have no thumbnail.
+type OriginalArtwork struct {
+	GroupID     int       `db:"group_id" json:"group_id"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	Data        []byte    `db:"data" json:"-"`
+	Thumbnail   []byte    `db:"thumbnail" json:"-"`
+	UploadedAt  time.Time `db:"uploaded_at" json:"uploaded_at"`
 }
 
 // Artwork represents an individual artwork within a group
 type Artwork struct {
-	ID          int       `db:"id" json:"id"`
-	GroupID     int       `db:"group_id" json:"group_id"`
-	Model       string    `db:"model" json:"model"`
-	Temperature float64   `db:"temperature" json:"temperature"`
-	MaxTokens   int       `db:"max_tokens" json:"max_tokens"`
-	SVG         string    `db:"svg" json:"svg"`
-	Featured    bool      `db:"featured" json:"featured"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID              int        `db:"id" json:"id"`
+	GroupID         int        `db:"group_id" json:"group_id"`
+	Model           string     `db:"model" json:"model"`
+	Temperature     float64    `db:"temperature" json:"temperature"`
+	MaxTokens       int        `db:"max_tokens" json:"max_tokens"`
+	SVG             string     `db:"svg" json:"svg"`
+	Featured        bool       `db:"featured" json:"featured"`
+	Visibility      Visibility `db:"visibility" json:"visibility"`
+	ReasoningEffort string     `db:"reasoning_effort" json:"reasoning_effort"` // empty uses the default effort
+	Source          string     `db:"source" json:"source"`
+	Revision        int        `db:"revision" json:"revision"`               // numbers the artworks of Model in the group, from 1
+	SortOrder       int        `db:"sort_order" json:"sort_order"`           // position within the group
+	DeletedAt       *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // set while in the recycle bin
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+
+	// Deprecated is set when OpenRouter no longer offers Model
+	Deprecated     bool   `db:"-" json:"deprecated"`
+	SuggestedModel string `db:"-" json:"suggested_model,omitempty"`
 }
 
-// Params represents the parameters for an artwork
-type Params struct {
-	Temperature float64 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+// ArtworkRevision is an SVG an artwork had before it was replaced, with the
+// parameters it was generated with. Lists leave SVG empty and give its Size.
+type ArtworkRevision struct {
+	ID          int       `json:"id"`
+	ArtworkID   int       `json:"artwork_id"`
+	SVG         string    `json:"svg,omitempty"`
+	Size        int       `json:"size"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ArtworkVersion is one SVG drawn for an artwork: the one it has now or a
+// revision it replaced. RevisionID is 0 for the current SVG.
+type ArtworkVersion struct {
+	ArtworkID   int        `json:"artwork_id"`
+	Revision    int        `json:"revision"` // of the artwork, see Artwork.Revision
+	RevisionID  int        `json:"revision_id,omitempty"`
+	SVG         string     `json:"svg"`
+	Size        int        `json:"size"`
+	Temperature float64    `json:"temperature"`
+	MaxTokens   int        `json:"max_tokens"`
+	Visibility  Visibility `json:"visibility"`
+	SavedAt     time.Time  `json:"saved_at"` // when the version was stored or replaced
+}
+
+// IsCurrent reports whether the version is the SVG its artwork has now
+func (v ArtworkVersion) IsCurrent() bool {
+	return v.RevisionID == 0
+}
+
+// DivergentGroup is a group ranked by how little the renditions of its
+// models look alike
+type DivergentGroup struct {
+	Group ArtworkGroup `json:"group"`
+	// Agreement is the mean perceptual similarity of each pair of models'
+	// renditions, from 0 (nothing alike) to 1 (identical)
+	Agreement float64   `json:"agreement"`
+	Artworks  []Artwork `json:"artworks"` // the rendition compared for each model
+}
+
+// Artwork sources: generated by this app, or imported with a finished SVG
+const (
+	SourceGenerated = "generated"
+	SourceImported  = "imported"
+)
+
+// ValidateSource rejects unknown artwork sources; empty means any source
+// where a filter is expected
+func ValidateSource(source string) error {
+	switch source {
+	case "", SourceGenerated, SourceImported:
+		return nil
+	}
+	return fmt.Errorf("invalid source %q: must be generated or imported", source)
+}
+
+// RecycleBin lists soft-deleted groups and artworks. Artworks deleted along
+// with their group are listed under the group only.
+type RecycleBin struct {
+	Groups   []ArtworkGroup `json:"groups"`
+	Artworks []Artwork      `json:"artworks"`
+}
+
+// ImportGroup is a group to import with its artworks and reference image
+type ImportGroup struct {
+	Group           ArtworkGroup
+	OriginalArtwork *OriginalArtwork
+	Artworks        []Artwork
+}
+
+// ImportResult counts what an import wrote and what it left alone.
+// GroupsImported includes the overwritten groups.
+type ImportResult struct {
+	GroupsImported    int `json:"groups_imported"`
+	GroupsOverwritten int `json:"groups_overwritten"`
+	GroupsSkipped     int `json:"groups_skipped"`
+	ArtworksImported  int `json:"artworks_imported"`
+	ArtworksSkipped   int `json:"artworks_skipped"`
+}
+
+// Outcomes of a row in a bulk group import
+const (
+	GroupRowCreated = "created"
+	GroupRowSkipped = "skipped"
+	GroupRowError   = "error"
+)
+
+// GroupRowResult is the outcome of one row of a bulk group import. Row
+// counts data rows from 1; the header of a CSV file is not a row.
+type GroupRowResult struct {
+	Row    int    `json:"row"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Visibility controls where an artwork may be shown
+type Visibility string
+
+const (
+	// VisibilityPrivate artworks only appear in editing views
+	VisibilityPrivate Visibility = "private"
+	// VisibilityUnlisted artworks are reachable by direct link but never listed
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPublic artworks are listed in the gallery and on the homepage
+	VisibilityPublic Visibility = "public"
+)
+
+// ParseVisibility validates a visibility value
+func ParseVisibility(s string) (Visibility, error) {
+	switch v := Visibility(strings.ToLower(strings.TrimSpace(s))); v {
+	case VisibilityPrivate, VisibilityUnlisted, VisibilityPublic:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid visibility %q: must be private, unlisted or public", s)
+}
+
+// GroupStamp is the little of a group needed to link to it: its ID,
+// category and when it last changed
+type GroupStamp struct {
+	ID        int       `json:"id"`
+	Category  string    `json:"category"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GroupSort is the order in which groups are listed
+type GroupSort string
+
+const (
+	// GroupSortManual lists groups in the order set with PUT
+	// /api/groups/reorder, new groups last
+	GroupSortManual GroupSort = "manual"
+	// GroupSortNewest lists the most recently created groups first
+	GroupSortNewest GroupSort = "newest"
+	// GroupSortOldest lists groups in the order they were created
+	GroupSortOldest GroupSort = "oldest"
+	// GroupSortTitle lists groups alphabetically by title
+	GroupSortTitle GroupSort = "title"
+)
+
+// DefaultGroupSort is used when no sort is given
+const DefaultGroupSort = GroupSortManual
+
+// ParseGroupSort validates a sort value; an empty value is DefaultGroupSort
+func ParseGroupSort(s string) (GroupSort, error) {
+	switch v := GroupSort(strings.ToLower(strings.TrimSpace(s))); v {
+	case "":
+		return DefaultGroupSort, nil
+	case GroupSortManual, GroupSortNewest, GroupSortOldest, GroupSortTitle:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid sort %q: must be manual, newest, oldest or title", s)
+}
+
+// ViewScope describes how an artwork is being reached
+type ViewScope int
+
+const (
+	// ScopeListing covers discovery pages such as the gallery and homepage
+	ScopeListing ViewScope = iota
+	// ScopeDirectLink covers pages and API calls addressed by ID
+	ScopeDirectLink
+	// ScopeEditing covers views available while editing is enabled
+	ScopeEditing
+)
+
+// VisibleIn reports whether an artwork with this visibility may be shown in scope
+func (v Visibility) VisibleIn(scope ViewScope) bool {
+	switch v {
+	case VisibilityPublic:
+		return true
+	case VisibilityUnlisted:
+		return scope >= ScopeDirectLink
+	default:
+		return scope == ScopeEditing
+	}
+}
+
+// FilterVisible returns the artworks that may be shown in scope
+func FilterVisible(artworks []Artwork, scope ViewScope) []Artwork {
+	var visible []Artwork
+	for _, artwork := range artworks {
+		if artwork.Visibility.VisibleIn(scope) {
+			visible = append(visible, artwork)
+		}
+	}
+	return visible
 }
 
 // GenerateRequest represents the request for generating SVG
@@ -56,6 +365,16 @@ type GenerateRequest struct {
 	Category    string  `json:"category,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
 	MaxTokens   int     `json:"max_tokens,omitempty"`
+	// ReasoningEffort is one of low, medium, high or off; empty uses the default
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	// PromptStyle names a prompt configuration; empty uses the default
+	PromptStyle string `json:"prompt_style,omitempty"`
+	// AutoContinue asks the model to continue output cut off at max_tokens
+	AutoContinue bool `json:"auto_continue,omitempty"`
+	// DryRun returns a placeholder SVG without calling OpenRouter
+	DryRun bool `json:"dry_run,omitempty"`
+	// AllowUnknownModel accepts a model OpenRouter does not list
+	AllowUnknownModel bool `json:"allow_unknown_model,omitempty"`
 }
 
 // GenerateResponse represents the response with generated SVG
@@ -84,10 +403,126 @@ type SaveArtworkResponse struct {
 
 // ModelInfo represents information about an available model
 type ModelInfo struct {
-	ID      string  `json:"id"`
-	Name    string  `json:"name"`
-	Checked bool    `json:"checked"`
-	Cost    float64 `json:"cost"` // Cost per 1M output tokens in dollars
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Checked        bool    `json:"checked"`
+	Cost           float64 `json:"cost"`                     // Cost per 1M output tokens in dollars
+	PromptCost     float64 `json:"prompt_cost"`              // Cost per 1M input tokens in dollars
+	ContextLength  int     `json:"context_length,omitempty"` // Tokens; 0 when unknown
+	SupportsVision bool    `json:"supports_vision"`          // Accepts image input
+
+	// ArtworkCount and LastUsedAt describe the stored artworks made with the
+	// model; GET /api/models fills them in
+	ArtworkCount int        `json:"artwork_count"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+}
+
+// ModelGroup holds the available models of a single provider
+type ModelGroup struct {
+	Provider string      `json:"provider"`
+	Models   []ModelInfo `json:"models"`
+}
+
+// Stats summarizes the whole collection
+type Stats struct {
+	Source          string          `json:"source,omitempty"` // artwork source the counts are limited to
+	TotalGroups     int             `json:"total_groups"`
+	TotalArtworks   int             `json:"total_artworks"`
+	GeneratedSVGs   int             `json:"generated_svgs"`   // artworks that have an SVG
+	DistinctModels  int             `json:"distinct_models"`  // models with at least one artwork
+	TotalCategories int             `json:"total_categories"` // named categories with at least one group
+	EstimatedCost   float64         `json:"estimated_cost"`   // dollars; see ModelCount.GeneratedBytes
+	ArtworksByModel []ModelCount    `json:"artworks_by_model"`
+	Categories      []CategoryCount `json:"categories"`
+	LastGeneratedAt *time.Time      `json:"last_generated_at"`
+}
+
+// ModelCount reports how many artworks were made with a model.
+// GeneratedBytes is the size of the SVGs the model generated, which the
+// estimated cost is derived from; imported SVGs cost nothing.
+type ModelCount struct {
+	Model          string `json:"model"`
+	Artworks       int    `json:"artworks"`
+	GeneratedBytes int64  `json:"generated_bytes"`
+}
+
+// Category is a gallery category: the name groups are filed under and the
+// slug its gallery URL uses
+type Category struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// CategoryCount reports how many visible groups belong to a category
+type CategoryCount struct {
+	Category string `json:"category"`
+	Groups   int    `json:"groups"`
+}
+
+// ModelUsage reports how many stored artworks reference a model
+type ModelUsage struct {
+	Model          string    `json:"model"`
+	ArtworkCount   int       `json:"artwork_count"`
+	LastUsedAt     time.Time `json:"last_used_at"` // creation time of its newest artwork
+	Deprecated     bool      `json:"deprecated"`
+	SuggestedModel string    `json:"suggested_model,omitempty"`
+}
+
+// ModelSnapshot is the set of model IDs OpenRouter offered at one fetch
+type ModelSnapshot struct {
+	ID        int       `json:"id"`
+	FetchedAt time.Time `json:"fetched_at"`
+	ModelIDs  []string  `json:"model_ids"` // sorted
+}
+
+// ModelListChange reports the models that appeared on or disappeared from
+// OpenRouter between a snapshot and the one before it. The oldest recorded
+// snapshot is marked Initial and has nothing to compare against.
+type ModelListChange struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Total     int       `json:"total"`
+	Added     []string  `json:"added"`
+	Removed   []string  `json:"removed"`
+	Initial   bool      `json:"initial,omitempty"`
+}
+
+// SchemaReport is the result of comparing the database with the schema the
+// code expects
+type SchemaReport struct {
+	OK                   bool                  `json:"ok"`
+	Issues               []SchemaIssue         `json:"issues"`
+	ForeignKeyViolations []ForeignKeyViolation `json:"foreign_key_violations"`
+}
+
+// Kinds of schema differences
+const (
+	SchemaIssueMissingTable       = "missing_table"
+	SchemaIssueExtraTable         = "extra_table"
+	SchemaIssueMissingColumn      = "missing_column"
+	SchemaIssueExtraColumn        = "extra_column"
+	SchemaIssueColumnMismatch     = "column_mismatch"
+	SchemaIssueMissingIndex       = "missing_index"
+	SchemaIssueExtraIndex         = "extra_index"
+	SchemaIssueIndexMismatch      = "index_mismatch"
+	SchemaIssueMissingForeignKey  = "missing_foreign_key"
+	SchemaIssueExtraForeignKey    = "extra_foreign_key"
+	SchemaIssueForeignKeyMismatch = "foreign_key_mismatch"
+)
+
+// SchemaIssue is one difference between the database and the expected schema
+type SchemaIssue struct {
+	Kind   string `json:"kind"`
+	Table  string `json:"table"`
+	Name   string `json:"name,omitempty"` // column, index or foreign key
+	Detail string `json:"detail,omitempty"`
+}
+
+// ForeignKeyViolation counts the rows of a table whose parent row is missing
+type ForeignKeyViolation struct {
+	Table  string  `json:"table"`
+	Parent string  `json:"parent"`
+	Rows   int     `json:"rows"`
+	RowIDs []int64 `json:"row_ids"` // a sample of the offending rows
 }
 
 // PromptExample represents an example prompt for users
@@ -99,47 +534,147 @@ type PromptExample struct {
 
 // TemplateData represents all the data needed to render template
 type TemplateData struct {
-	Models         []ModelInfo `json:"models"`
-	EditingEnabled bool        `json:"editing_enabled"`
+	Models         []ModelInfo   `json:"models"`
+	PromptStyles   []PromptStyle `json:"prompt_styles"`
+	EditingEnabled bool          `json:"editing_enabled"`
 }
 
-// OpenRouterRequest represents the request to OpenRouter API
-type OpenRouterRequest struct {
-	Model       string     `json:"model"`
-	Messages    []Message  `json:"messages"`
-	Temperature float64    `json:"temperature"`
-	MaxTokens   int        `json:"max_tokens"`
-	Reasoning   *Reasoning `json:"reasoning,omitempty"`
+// Reasoning effort levels accepted per artwork. "off" omits the reasoning
+// block from the OpenRouter request entirely.
+const (
+	ReasoningEffortOff    = "off"
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+
+	// DefaultReasoningEffort is used when no effort is set
+	DefaultReasoningEffort = ReasoningEffortMedium
+)
+
+// ValidateReasoningEffort rejects unknown effort values; empty means the default
+func ValidateReasoningEffort(effort string) error {
+	switch effort {
+	case "", ReasoningEffortOff, ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh:
+		return nil
+	}
+	return fmt.Errorf("invalid reasoning effort %q: must be low, medium, high or off", effort)
+}
+
+// Bounds of the generation parameters accepted by OpenRouter
+const (
+	MinTemperature = 0.0
+	MaxTemperature = 2.0
+	MinMaxTokens   = 1
+	MaxMaxTokens   = 100000
+)
+
+// ValidateTemperature checks a sampling temperature against the bounds
+// OpenRouter accepts
+func ValidateTemperature(temperature float64) error {
+	if temperature < MinTemperature || temperature > MaxTemperature {
+		return fmt.Errorf("temperature must be between %g and %g, got %g", MinTemperature, MaxTemperature, temperature)
+	}
+	return nil
 }
 
-// Reasoning represents the reasoning token controls for OpenRouter
-type Reasoning struct {
-	Effort    string `json:"effort,omitempty"` // "high", "medium", "low"
-	MaxTokens int    `json:"max_tokens,omitempty"`
-	Exclude   bool   `json:"exclude,omitempty"`
-	Enabled   bool   `json:"enabled,omitempty"`
+// ValidateMaxTokens checks a max_tokens value against the bounds OpenRouter
+// accepts
+func ValidateMaxTokens(maxTokens int) error {
+	if maxTokens < MinMaxTokens || maxTokens > MaxMaxTokens {
+		return fmt.Errorf("max_tokens must be between %d and %d, got %d", MinMaxTokens, MaxMaxTokens, maxTokens)
+	}
+	return nil
 }
 
-// Message represents a message in the OpenRouter request
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// ValidateGenerationParams checks temperature and max_tokens, naming the
+// first bad field
+func ValidateGenerationParams(temperature float64, maxTokens int) error {
+	if err := ValidateTemperature(temperature); err != nil {
+		return err
+	}
+	return ValidateMaxTokens(maxTokens)
 }
 
-// OpenRouterResponse represents the response from OpenRouter API
-type OpenRouterResponse struct {
-	Choices []Choice         `json:"choices"`
-	Error   *OpenRouterError `json:"error,omitempty"`
+// GenerationAttempt records the outcome of a single SVG generation call
+type GenerationAttempt struct {
+	ID         int       `db:"id" json:"id"`
+	ArtworkID  int       `db:"artwork_id" json:"artwork_id"`
+	GroupID    int       `db:"group_id" json:"group_id"`
+	Model      string    `db:"model" json:"model"`
+	Success    bool      `db:"success" json:"success"`
+	ErrorClass string    `db:"error_class" json:"error_class,omitempty"`
+	Error      string    `db:"error" json:"error,omitempty"`
+	DurationMS int64     `db:"duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
 }
 
-// Choice represents a choice in the OpenRouter response
-type Choice struct {
-	Message Message `json:"message"`
+// GenerationErrorFilter narrows down the failures aggregated by the error dashboard
+type GenerationErrorFilter struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	GroupID int       `json:"group_id,omitempty"`
+	Query   string    `json:"q,omitempty"`
+}
+
+// DefaultGenerationErrorWindow is how far back the error dashboard looks when no range is given
+const DefaultGenerationErrorWindow = 7 * 24 * time.Hour
+
+// ParseGenerationErrorFilter builds a filter from query parameters. It accepts
+// `from`/`to` as RFC 3339 timestamps or `since` as a duration (e.g. "24h"),
+// plus optional `group_id` and `q` (substring match on the error message).
+func ParseGenerationErrorFilter(values url.Values, now time.Time) (GenerationErrorFilter, error) {
+	filter := GenerationErrorFilter{
+		From:  now.Add(-DefaultGenerationErrorWindow),
+		To:    now,
+		Query: strings.TrimSpace(values.Get("q")),
+	}
+
+	if since := values.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return filter, fmt.Errorf("invalid since duration: %q", since)
+		}
+		filter.From = now.Add(-d)
+	}
+
+	if from := values.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from timestamp: %q", from)
+		}
+		filter.From = t
+	}
+
+	if to := values.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to timestamp: %q", to)
+		}
+		filter.To = t
+	}
+
+	if filter.From.After(filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+
+	if groupID := values.Get("group_id"); groupID != "" {
+		id, err := strconv.Atoi(groupID)
+		if err != nil || id <= 0 {
+			return filter, fmt.Errorf("invalid group_id: %q", groupID)
+		}
+		filter.GroupID = id
+	}
+
+	return filter, nil
 }
 
-// OpenRouterError represents an error from OpenRouter API
-type OpenRouterError struct {
-	Message string      `json:"message"`
-	Type    string      `json:"type"`
-	Code    interface{} `json:"code"` // Can be string or number
+// GenerationErrorSummary aggregates failures for one model and error classification
+type GenerationErrorSummary struct {
+	Model         string    `json:"model"`
+	ErrorClass    string    `json:"error_class"`
+	Count         int       `json:"count"`
+	LastError     string    `json:"last_error"`
+	LastArtworkID int       `json:"last_artwork_id"`
+	LastGroupID   int       `json:"last_group_id"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
 }
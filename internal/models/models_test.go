@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGroupSort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    GroupSort
+		wantErr bool
+	}{
+		{"", DefaultGroupSort, false},
+		{"newest", GroupSortNewest, false},
+		{"oldest", GroupSortOldest, false},
+		{"title", GroupSortTitle, false},
+		{"manual", GroupSortManual, false},
+		{" Title ", GroupSortTitle, false},
+		{"random", "", true},
+		{"created_at; DROP TABLE artwork_groups", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGroupSort(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseGroupSort(%q) = %q, %v, want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateGenerationParams(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		maxTokens   int
+		wantField   string // named in the error, empty when valid
+	}{
+		{0, 1, ""},
+		{0.7, 4096, ""},
+		{2, 100000, ""},
+		{1.5, 1000, ""},
+		{-0.1, 1000, "temperature"},
+		{2.5, 1000, "temperature"},
+		{0.7, 0, "max_tokens"},
+		{0.7, -5, "max_tokens"},
+		{0.7, 100001, "max_tokens"},
+		{3, 0, "temperature"},
+	}
+	for _, tt := range tests {
+		err := ValidateGenerationParams(tt.temperature, tt.maxTokens)
+		switch {
+		case tt.wantField == "" && err != nil:
+			t.Errorf("ValidateGenerationParams(%g, %d) = %v, want nil", tt.temperature, tt.maxTokens, err)
+		case tt.wantField != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantField)):
+			t.Errorf("ValidateGenerationParams(%g, %d) = %v, want an error naming %s", tt.temperature, tt.maxTokens, err, tt.wantField)
+		}
+	}
+}
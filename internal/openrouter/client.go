@@ -0,0 +1,324 @@
+// Package openrouter generates SVG artwork through the OpenRouter chat
+// completions API.
+package openrouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+const (
+	// DefaultBaseURL is the OpenRouter API root
+	DefaultBaseURL = config.DefaultOpenRouterBaseURL
+
+	// DefaultTimeout bounds a single generation; reasoning models can be slow
+	DefaultTimeout = 300 * time.Second
+
+	// FinishReasonLength is the finish reason of output cut off at max_tokens
+	FinishReasonLength = "length"
+)
+
+// continuePrompt asks the model to resume output that was cut off
+const continuePrompt = "Your previous answer was cut off. Continue exactly where you left off, without repeating anything and without any commentary."
+
+// GenerationRequest describes one SVG generation
+type GenerationRequest struct {
+	PromptConfig    *models.PromptConfig
+	Prompt          string // artwork description inserted into the user prompt template
+	Title           string // group title, available to the user prompt template
+	Category        string // group category, available to the user prompt template
+	Model           string
+	Temperature     float64
+	MaxTokens       int
+	ReasoningEffort string // empty uses the default effort
+	// Continue holds the output so far of a generation that was cut off; when
+	// set the model is asked to carry on from where it stopped
+	Continue string
+}
+
+// Usage reports the tokens consumed by a generation
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// GenerationResult is the outcome of a successful generation
+type GenerationResult struct {
+	SVG          string
+	Content      string // untrimmed output, for stitching continuations
+	Usage        Usage
+	FinishReason string
+	Model        string // model name as reported by OpenRouter
+}
+
+// Generator produces SVG artwork from a prompt. Client implements it against
+// the OpenRouter API; tests can substitute a fake.
+type Generator interface {
+	GenerateSVG(ctx context.Context, req GenerationRequest) (GenerationResult, error)
+}
+
+// Client calls the OpenRouter chat completions API
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the OpenRouter API using the
+// OPENROUTER_API_KEY environment variable. OPENROUTER_BASE_URL, when set,
+// replaces the public API root, e.g. to go through a proxy or a stub.
+func NewClient() *Client {
+	return &Client{
+		BaseURL: config.OpenRouterBaseURL(),
+		APIKey:  os.Getenv("OPENROUTER_API_KEY"),
+		Timeout: DefaultTimeout,
+	}
+}
+
+// ErrMissingAPIKey is returned when the client has no API key
+var ErrMissingAPIKey = errors.New("OPENROUTER_API_KEY environment variable is not set")
+
+// ErrEmptyResponse is returned when OpenRouter answers without any choices
+var ErrEmptyResponse = errors.New("no response from OpenRouter API")
+
+// ErrTruncated marks output that was cut off because max_tokens ran out
+var ErrTruncated = errors.New("output was cut off at max_tokens")
+
+// StatusError is returned when OpenRouter answers with a non-200 status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("OpenRouter API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// APIError is returned when OpenRouter reports an error in a 200 response
+type APIError struct {
+	Message string
+	Type    string
+	Code    interface{}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("OpenRouter API error: %s", e.Message)
+}
+
+// GenerateSVG sends the generation request to OpenRouter and returns the
+// trimmed content of the first choice. In a dry run it returns a placeholder
+// without contacting OpenRouter.
+func (c *Client) GenerateSVG(ctx context.Context, genReq GenerationRequest) (GenerationResult, error) {
+	if IsDryRun(ctx) {
+		log.Printf("Dry run: returning a placeholder SVG instead of calling OpenRouter (model %s)", genReq.Model)
+		return placeholderResult(genReq), nil
+	}
+
+	if c.APIKey == "" {
+		return GenerationResult{}, ErrMissingAPIKey
+	}
+
+	log.Printf("Calling OpenRouter API with model: %s, prompt style: %s", genReq.Model, genReq.PromptConfig.Name)
+
+	chatReq, err := newChatRequest(genReq)
+	if err != nil {
+		return GenerationResult{}, err
+	}
+	log.Printf("Sending %d messages to OpenRouter", len(chatReq.Messages))
+
+	// Note: reasoning is enabled for supported models unless the effort is "off".
+	// We exclude reasoning from the response (exclude=true) and do not log reasoning content.
+	if chatReq.Reasoning != nil {
+		log.Printf("Request will use reasoning: effort=%s, exclude=%t", chatReq.Reasoning.Effort, chatReq.Reasoning.Exclude)
+	} else {
+		log.Printf("Request will not use reasoning")
+	}
+
+	jsonData, err := json.Marshal(chatReq)
+	if err != nil {
+		return GenerationResult{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return GenerationResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("X-Title", "Pelican Art Gallery")
+
+	log.Printf("Making request to OpenRouter API...")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return GenerationResult{}, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	log.Printf("OpenRouter API responded with status: %d", resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return GenerationResult{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("OpenRouter API error (status %d): %s", resp.StatusCode, string(body))
+		return GenerationResult{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	result, err := parseResponse(body)
+	if err != nil {
+		log.Printf("OpenRouter response rejected: %v", err)
+		return GenerationResult{}, err
+	}
+
+	log.Printf("Raw OpenRouter response content length: %d", len(result.SVG))
+	return result, nil
+}
+
+// httpClient returns the configured HTTP client, or one using Timeout
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// newChatRequest builds the chat completion request: the prompt config's
+// system prompts followed by the user prompt with the description filled in.
+// The configured override of the model, if any, caps max_tokens, drops the
+// temperature, fills in an unset reasoning effort and adds a system prompt.
+func newChatRequest(req GenerationRequest) (chatRequest, error) {
+	userPrompt, err := config.FormatUserPrompt(req.PromptConfig, config.UserPromptData{
+		Description: req.Prompt,
+		Title:       req.Title,
+		Category:    req.Category,
+	})
+	if err != nil {
+		return chatRequest{}, fmt.Errorf("prompt style %q: %w", req.PromptConfig.Name, err)
+	}
+
+	override, hasOverride := config.ModelOverride(req.Model)
+
+	var messages []message
+	for _, sysPrompt := range req.PromptConfig.SystemPrompts {
+		messages = append(messages, message(sysPrompt))
+	}
+	if hasOverride && override.SystemPrompt != "" {
+		messages = append(messages, message{Role: "system", Content: override.SystemPrompt})
+	}
+	messages = append(messages, message{
+		Role:    "user",
+		Content: userPrompt,
+	})
+	if req.Continue != "" {
+		messages = append(messages,
+			message{Role: "assistant", Content: req.Continue},
+			message{Role: "user", Content: continuePrompt},
+		)
+	}
+
+	chatReq := chatRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		Temperature: &req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Reasoning:   newReasoning(req.ReasoningEffort),
+	}
+	if hasOverride {
+		log.Printf("Applying model override %q to %s", override.Match, req.Model)
+		if override.OmitTemperature {
+			chatReq.Temperature = nil
+		}
+		if override.MaxTokensLimit > 0 && chatReq.MaxTokens > override.MaxTokensLimit {
+			chatReq.MaxTokens = override.MaxTokensLimit
+		}
+		if req.ReasoningEffort == "" && override.ReasoningEffort != "" {
+			chatReq.Reasoning = newReasoning(override.ReasoningEffort)
+		}
+	}
+	return chatReq, nil
+}
+
+// parseResponse extracts the result from a 200 response body
+func parseResponse(body []byte) (GenerationResult, error) {
+	var resp chatResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return GenerationResult{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if resp.Error != nil {
+		return GenerationResult{}, &APIError{Message: resp.Error.Message, Type: resp.Error.Type, Code: resp.Error.Code}
+	}
+
+	if len(resp.Choices) == 0 {
+		return GenerationResult{}, ErrEmptyResponse
+	}
+
+	choice := resp.Choices[0]
+	return GenerationResult{
+		SVG:          strings.TrimSpace(choice.Message.Content),
+		Content:      choice.Message.Content,
+		Usage:        resp.Usage,
+		FinishReason: choice.FinishReason,
+		Model:        resp.Model,
+	}, nil
+}
+
+// ClassifyError buckets a generation error into a coarse category for the
+// error dashboard
+func ClassifyError(err error) string {
+	var statusErr *StatusError
+	var apiErr *APIError
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case errors.Is(err, ErrMissingAPIKey):
+		return "configuration"
+	case errors.Is(err, ErrTruncated):
+		return "truncated"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded"):
+		return "timeout"
+	case errors.As(err, &statusErr):
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return "rate_limited"
+		case statusErr.StatusCode >= 400 && statusErr.StatusCode < 500:
+			return "upstream_client_error"
+		case statusErr.StatusCode >= 500:
+			return "upstream_server_error"
+		}
+		return "upstream_error"
+	case errors.As(err, &apiErr):
+		return "upstream_error"
+	case errors.Is(err, ErrEmptyResponse):
+		return "empty_response"
+	case strings.Contains(msg, "failed to parse") || strings.Contains(msg, "failed to read"):
+		return "invalid_response"
+	case strings.Contains(msg, "failed to make request"):
+		return "network"
+	default:
+		return "other"
+	}
+}
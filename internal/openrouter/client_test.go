@@ -0,0 +1,242 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// testPromptConfig is a prompt style with one system prompt
+var testPromptConfig = &models.PromptConfig{
+	Name:               "test",
+	SystemPrompts:      []models.SystemPrompt{{Role: "system", Content: "You draw SVGs."}},
+	UserPromptTemplate: "Draw {{.Description}}",
+}
+
+func TestChatRequestJSON(t *testing.T) {
+	const messages = `"messages":[{"role":"system","content":"You draw SVGs."},{"role":"user","content":"Draw a pelican"}]`
+	tests := []struct {
+		effort string
+		want   string
+	}{
+		{"", `{"model":"test/model",` + messages + `,"temperature":0.5,"max_tokens":1000,"reasoning":{"effort":"medium","exclude":true,"enabled":true}}`},
+		{"low", `{"model":"test/model",` + messages + `,"temperature":0.5,"max_tokens":1000,"reasoning":{"effort":"low","exclude":true,"enabled":true}}`},
+		{"medium", `{"model":"test/model",` + messages + `,"temperature":0.5,"max_tokens":1000,"reasoning":{"effort":"medium","exclude":true,"enabled":true}}`},
+		{"high", `{"model":"test/model",` + messages + `,"temperature":0.5,"max_tokens":1000,"reasoning":{"effort":"high","exclude":true,"enabled":true}}`},
+		{"off", `{"model":"test/model",` + messages + `,"temperature":0.5,"max_tokens":1000}`},
+	}
+	for _, tt := range tests {
+		t.Run("effort="+tt.effort, func(t *testing.T) {
+			chatReq, err := newChatRequest(GenerationRequest{
+				PromptConfig:    testPromptConfig,
+				Prompt:          "a pelican",
+				Model:           "test/model",
+				Temperature:     0.5,
+				MaxTokens:       1000,
+				ReasoningEffort: tt.effort,
+			})
+			if err != nil {
+				t.Fatalf("newChatRequest: %v", err)
+			}
+			got, err := json.Marshal(chatReq)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("JSON =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatRequestAppliesModelOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte(`model_overrides:
+  - match: "openai/o*"
+    omit_temperature: true
+    max_tokens_limit: 800
+    reasoning_effort: high
+    system_prompt: Think first.
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.InitModelOverrides(path); err != nil {
+		t.Fatalf("InitModelOverrides: %v", err)
+	}
+	t.Cleanup(func() { config.InitModelOverrides(filepath.Join(t.TempDir(), "none.yaml")) })
+
+	const messages = `"messages":[{"role":"system","content":"You draw SVGs."},{"role":"user","content":"Draw a pelican"}]`
+	const overridden = `"messages":[{"role":"system","content":"You draw SVGs."},{"role":"system","content":"Think first."},{"role":"user","content":"Draw a pelican"}]`
+	tests := []struct {
+		model, effort string
+		want          string
+	}{
+		{"openai/o3", "", `{"model":"openai/o3",` + overridden + `,"max_tokens":800,"reasoning":{"effort":"high","exclude":true,"enabled":true}}`},
+		// An effort set on the artwork is kept
+		{"openai/o3", "low", `{"model":"openai/o3",` + overridden + `,"max_tokens":800,"reasoning":{"effort":"low","exclude":true,"enabled":true}}`},
+		{"openai/gpt-4o", "", `{"model":"openai/gpt-4o",` + messages + `,"temperature":0.5,"max_tokens":1000,"reasoning":{"effort":"medium","exclude":true,"enabled":true}}`},
+	}
+	for _, tt := range tests {
+		chatReq, err := newChatRequest(GenerationRequest{
+			PromptConfig:    testPromptConfig,
+			Prompt:          "a pelican",
+			Model:           tt.model,
+			Temperature:     0.5,
+			MaxTokens:       1000,
+			ReasoningEffort: tt.effort,
+		})
+		if err != nil {
+			t.Fatalf("newChatRequest: %v", err)
+		}
+		got, err := json.Marshal(chatReq)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s, effort %q: JSON =\n%s\nwant\n%s", tt.model, tt.effort, got, tt.want)
+		}
+	}
+}
+
+func TestClientSendsReasoning(t *testing.T) {
+	var sent map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &sent); err != nil {
+			t.Errorf("request body %q: %v", body, err)
+		}
+		io.WriteString(w, `{"model":"test/model","choices":[{"message":{"role":"assistant","content":"<svg></svg>"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL, APIKey: "key"}
+	result, err := client.GenerateSVG(context.Background(), GenerationRequest{
+		PromptConfig:    testPromptConfig,
+		Prompt:          "a pelican",
+		Model:           "test/model",
+		MaxTokens:       1000,
+		ReasoningEffort: "high",
+	})
+	if err != nil {
+		t.Fatalf("GenerateSVG: %v", err)
+	}
+	if result.SVG != "<svg></svg>" || result.FinishReason != "stop" {
+		t.Errorf("result = %+v", result)
+	}
+
+	reasoning, _ := sent["reasoning"].(map[string]interface{})
+	if reasoning["effort"] != "high" || reasoning["exclude"] != true || reasoning["enabled"] != true {
+		t.Errorf("sent reasoning = %v", sent["reasoning"])
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    GenerationResult
+		wantErr string
+	}{
+		{
+			name: "choice",
+			body: `{"model":"openai/gpt-4o-2024","choices":[{"message":{"role":"assistant","content":"  <svg></svg>\n"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":20,"total_tokens":30}}`,
+			want: GenerationResult{SVG: "<svg></svg>", Content: "  <svg></svg>\n", Usage: Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30}, FinishReason: "stop", Model: "openai/gpt-4o-2024"},
+		},
+		{
+			name: "truncated",
+			body: `{"model":"m","choices":[{"message":{"content":"<svg><rect"},"finish_reason":"length"}]}`,
+			want: GenerationResult{SVG: "<svg><rect", Content: "<svg><rect", FinishReason: "length", Model: "m"},
+		},
+		{
+			name: "first of several choices",
+			body: `{"choices":[{"message":{"content":"first"}},{"message":{"content":"second"}}]}`,
+			want: GenerationResult{SVG: "first", Content: "first"},
+		},
+		{
+			name:    "error payload",
+			body:    `{"error":{"message":"Rate limit exceeded","type":"rate_limit","code":429}}`,
+			wantErr: "OpenRouter API error: Rate limit exceeded",
+		},
+		{
+			name:    "empty choices",
+			body:    `{"model":"m","choices":[]}`,
+			wantErr: ErrEmptyResponse.Error(),
+		},
+		{
+			name:    "no choices",
+			body:    `{}`,
+			wantErr: ErrEmptyResponse.Error(),
+		},
+		{
+			name:    "not JSON",
+			body:    `<html>Bad gateway</html>`,
+			wantErr: "failed to parse response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponse([]byte(tt.body))
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("parseResponse error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseResponse: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseResponse =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResponseErrorPayload(t *testing.T) {
+	_, err := parseResponse([]byte(`{"error":{"message":"No such model","type":"invalid_request","code":"model_not_found"}}`))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want an *APIError", err)
+	}
+	if apiErr.Type != "invalid_request" || apiErr.Code != "model_not_found" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if got := ClassifyError(err); got != "upstream_error" {
+		t.Errorf("ClassifyError = %q, want upstream_error", got)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{ErrMissingAPIKey, "configuration"},
+		{fmt.Errorf("generate: %w", ErrTruncated), "truncated"},
+		{context.Canceled, "canceled"},
+		{context.DeadlineExceeded, "timeout"},
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, "rate_limited"},
+		{&StatusError{StatusCode: http.StatusUnauthorized}, "upstream_client_error"},
+		{&StatusError{StatusCode: http.StatusBadGateway}, "upstream_server_error"},
+		{ErrEmptyResponse, "empty_response"},
+		{errors.New("failed to parse response: unexpected EOF"), "invalid_response"},
+		{errors.New("failed to make request: connection refused"), "network"},
+		{errors.New("something else"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"strconv"
+	"unicode/utf8"
+
+	"pelican-gallery/internal/config"
+)
+
+// dryRunKey marks a context whose generations are dry runs
+type dryRunKey struct{}
+
+// WithDryRun returns a context under which GenerateSVG returns a placeholder
+// SVG instead of calling OpenRouter, for a single request
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether generations under ctx are dry runs, either
+// because DRY_RUN is set or because the context was marked with WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun || config.DryRun()
+}
+
+// placeholderResult is the result of a dry run: an SVG naming the model,
+// temperature and prompt length. The same request always gives the same SVG,
+// tinted by model so placeholders of a group are told apart.
+func placeholderResult(req GenerationRequest) GenerationResult {
+	hash := fnv.New32a()
+	hash.Write([]byte(req.Model))
+	hue := hash.Sum32() % 360
+
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 400 400" width="400" height="400">
+  <rect width="400" height="400" fill="hsl(%d, 60%%, 90%%)"/>
+  <rect x="20" y="20" width="360" height="360" fill="none" stroke="hsl(%d, 40%%, 45%%)" stroke-width="4" stroke-dasharray="12 8"/>
+  <g font-family="monospace" text-anchor="middle" fill="#222">
+    <text x="200" y="160" font-size="28" font-weight="bold">DRY RUN</text>
+    <text x="200" y="210" font-size="16">%s</text>
+    <text x="200" y="240" font-size="16">temperature %s</text>
+    <text x="200" y="270" font-size="16">prompt %d characters</text>
+  </g>
+</svg>`, hue, hue, html.EscapeString(req.Model), strconv.FormatFloat(req.Temperature, 'f', -1, 64), utf8.RuneCountInString(req.Prompt))
+
+	return GenerationResult{
+		SVG:          svg,
+		Content:      svg,
+		FinishReason: "stop",
+		Model:        req.Model,
+	}
+}
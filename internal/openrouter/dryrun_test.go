@@ -0,0 +1,114 @@
+package openrouter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// unreachable is an OpenRouter that fails the test when it is contacted
+func unreachable(t *testing.T) *httptest.Server {
+	t.Helper()
+	var contacted atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contacted.Add(1)
+		http.Error(w, "not in a dry run", http.StatusInternalServerError)
+	}))
+	t.Cleanup(func() {
+		server.Close()
+		if n := contacted.Load(); n != 0 {
+			t.Errorf("OpenRouter was contacted %d times", n)
+		}
+	})
+	return server
+}
+
+func TestGenerateSVGDryRun(t *testing.T) {
+	t.Setenv("DRY_RUN", "")
+	// Without an API key a real call fails, so only a dry run succeeds
+	client := &Client{BaseURL: unreachable(t).URL}
+	req := GenerationRequest{
+		PromptConfig: testPromptConfig,
+		Prompt:       "a pelican riding a bicycle",
+		Model:        "openai/gpt-4o",
+		Temperature:  0.7,
+		MaxTokens:    1000,
+	}
+
+	if _, err := client.GenerateSVG(context.Background(), req); !errors.Is(err, ErrMissingAPIKey) {
+		t.Fatalf("GenerateSVG without a dry run error = %v, want %v", err, ErrMissingAPIKey)
+	}
+
+	ctx := WithDryRun(context.Background())
+	result, err := client.GenerateSVG(ctx, req)
+	if err != nil {
+		t.Fatalf("GenerateSVG in a dry run: %v", err)
+	}
+	for _, want := range []string{"<svg", "DRY RUN", ">openai/gpt-4o<", ">temperature 0.7<", ">prompt 26 characters<"} {
+		if !strings.Contains(result.SVG, want) {
+			t.Errorf("placeholder does not contain %q:\n%s", want, result.SVG)
+		}
+	}
+	if result.Content != result.SVG || result.FinishReason != "stop" || result.Model != req.Model {
+		t.Errorf("result = %+v, want a complete answer by %s", result, req.Model)
+	}
+
+	// The same request gives the same placeholder, another model another one
+	if again, _ := client.GenerateSVG(ctx, req); again.SVG != result.SVG {
+		t.Error("the same request gave two placeholders")
+	}
+	other := req
+	other.Model = "anthropic/claude-sonnet-4"
+	if placeholder, _ := client.GenerateSVG(ctx, other); placeholder.SVG == result.SVG {
+		t.Error("two models gave the same placeholder")
+	}
+
+	// Model names are escaped, and prompt lengths are counted in characters
+	odd := req
+	odd.Model = "<script>"
+	odd.Prompt = "pélican"
+	placeholder, _ := client.GenerateSVG(ctx, odd)
+	if strings.Contains(placeholder.SVG, "<script>") || !strings.Contains(placeholder.SVG, "&lt;script&gt;") {
+		t.Errorf("placeholder does not escape the model:\n%s", placeholder.SVG)
+	}
+	if !strings.Contains(placeholder.SVG, ">prompt 7 characters<") {
+		t.Errorf("placeholder does not count characters:\n%s", placeholder.SVG)
+	}
+}
+
+func TestIsDryRun(t *testing.T) {
+	tests := []struct {
+		env    string
+		marked bool
+		want   bool
+	}{
+		{"", false, false},
+		{"", true, true},
+		{"true", false, true},
+		{"1", false, true},
+		{"false", false, false},
+		{"false", true, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("DRY_RUN", tt.env)
+		ctx := context.Background()
+		if tt.marked {
+			ctx = WithDryRun(ctx)
+		}
+		if got := IsDryRun(ctx); got != tt.want {
+			t.Errorf("DRY_RUN=%q, marked %v: IsDryRun = %v, want %v", tt.env, tt.marked, got, tt.want)
+		}
+	}
+
+	// DRY_RUN alone makes every generation a dry run
+	t.Setenv("DRY_RUN", "true")
+	client := &Client{BaseURL: unreachable(t).URL, APIKey: "key"}
+	result, err := client.GenerateSVG(context.Background(), GenerationRequest{PromptConfig: testPromptConfig, Prompt: "a pelican", Model: "test/model"})
+	if err != nil || !strings.Contains(result.SVG, "DRY RUN") {
+		t.Errorf("GenerateSVG with DRY_RUN = %v, %q; want the placeholder", err, result.SVG)
+	}
+}
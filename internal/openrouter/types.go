@@ -0,0 +1,63 @@
+package openrouter
+
+import "pelican-gallery/internal/models"
+
+// chatRequest is the body of a chat completions request
+type chatRequest struct {
+	Model       string     `json:"model"`
+	Messages    []message  `json:"messages"`
+	Temperature *float64   `json:"temperature,omitempty"` // nil for models that refuse it
+	MaxTokens   int        `json:"max_tokens"`
+	Reasoning   *reasoning `json:"reasoning,omitempty"`
+}
+
+// message is a single chat message
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// reasoning holds the reasoning token controls
+type reasoning struct {
+	Effort    string `json:"effort,omitempty"` // "high", "medium", "low"
+	MaxTokens int    `json:"max_tokens,omitempty"`
+	Exclude   bool   `json:"exclude,omitempty"`
+	Enabled   bool   `json:"enabled,omitempty"`
+}
+
+// newReasoning returns the reasoning controls for an effort level, or nil when
+// reasoning is off. Reasoning output is always excluded from the response.
+func newReasoning(effort string) *reasoning {
+	if effort == "" {
+		effort = models.DefaultReasoningEffort
+	}
+	if effort == models.ReasoningEffortOff {
+		return nil
+	}
+	return &reasoning{
+		Effort:  effort,
+		Enabled: true,
+		Exclude: true,
+	}
+}
+
+// chatResponse is the body of a chat completions response
+type chatResponse struct {
+	Model   string     `json:"model"`
+	Choices []choice   `json:"choices"`
+	Usage   Usage      `json:"usage"`
+	Error   *errorBody `json:"error,omitempty"`
+}
+
+// choice is one completion in the response
+type choice struct {
+	Message      message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// errorBody is the error object OpenRouter may return with a 200 status
+type errorBody struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Code    interface{} `json:"code"` // Can be string or number
+}
@@ -0,0 +1,112 @@
+package pages
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// The models compared when none are picked, as on the homepage
+const (
+	defaultCompareModelA = "openai/gpt-3.5-turbo"
+	defaultCompareModelB = "openai/gpt-5"
+)
+
+// compareModelOptions lists the models offered in the compare picker: the
+// available models that have artworks, in the usual order, followed by used
+// models that are no longer offered so older renditions stay comparable
+func compareModelOptions(used []string) []models.ModelInfo {
+	remaining := make(map[string]bool, len(used))
+	for _, id := range used {
+		remaining[id] = true
+	}
+
+	var options []models.ModelInfo
+	for _, model := range config.GetAvailableModels() {
+		if remaining[model.ID] {
+			options = append(options, model)
+			delete(remaining, model.ID)
+		}
+	}
+	for _, id := range used {
+		if remaining[id] {
+			options = append(options, models.ModelInfo{ID: id, Name: id})
+		}
+	}
+	return options
+}
+
+// CompareHandler serves GET /compare, the renditions of two models side by
+// side for every group both have drawn. ?model_a= and ?model_b= pick the
+// models and default to the pair shown on the homepage.
+func (h *PageHandler) CompareHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	modelA := strings.TrimSpace(r.URL.Query().Get("model_a"))
+	if modelA == "" {
+		modelA = defaultCompareModelA
+	}
+	modelB := strings.TrimSpace(r.URL.Query().Get("model_b"))
+	if modelB == "" {
+		modelB = defaultCompareModelB
+	}
+
+	used, err := h.db.ListUsedModels()
+	if err != nil {
+		log.Printf("Error fetching used models: %v", err)
+		http.Error(w, "Failed to fetch models", http.StatusInternalServerError)
+		return
+	}
+
+	type CompareRow struct {
+		models.ModelComparison
+		SVGA template.HTML `json:"svg_a"`
+		SVGB template.HTML `json:"svg_b"`
+	}
+
+	var rows []CompareRow
+	if modelA != modelB {
+		comparisons, err := h.db.ListGroupsWithBothModels(modelA, modelB)
+		if err != nil {
+			log.Printf("Error fetching groups for %s vs %s: %v", modelA, modelB, err)
+			http.Error(w, "Failed to fetch artworks", http.StatusInternalServerError)
+			return
+		}
+		for _, comparison := range comparisons {
+			rows = append(rows, CompareRow{
+				ModelComparison: comparison,
+				SVGA:            template.HTML(comparison.A.SVG),
+				SVGB:            template.HTML(comparison.B.SVG),
+			})
+		}
+	}
+
+	data := struct {
+		Title          string             `json:"title"`
+		ModelA         string             `json:"model_a"`
+		ModelB         string             `json:"model_b"`
+		SameModel      bool               `json:"same_model"`
+		Models         []models.ModelInfo `json:"models"`
+		Rows           []CompareRow       `json:"rows"`
+		EditingEnabled bool               `json:"editing_enabled"`
+		CSSHash        string             `json:"css_hash"`
+	}{
+		Title:          "Compare models - Pelican Art Gallery",
+		ModelA:         modelA,
+		ModelB:         modelB,
+		SameModel:      modelA == modelB,
+		Models:         compareModelOptions(used),
+		Rows:           rows,
+		EditingEnabled: isEditingEnabled(),
+		CSSHash:        h.getCSSHash(),
+	}
+
+	h.render(w, "compare.html", data)
+}
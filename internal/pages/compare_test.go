@@ -0,0 +1,134 @@
+package pages
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// compareData is the data CompareHandler passes to compare.html
+type compareData struct {
+	ModelA    string             `json:"model_a"`
+	ModelB    string             `json:"model_b"`
+	SameModel bool               `json:"same_model"`
+	Models    []models.ModelInfo `json:"models"`
+	Rows      []struct {
+		Group models.ArtworkGroup `json:"group"`
+		A     models.Artwork      `json:"a"`
+		B     models.Artwork      `json:"b"`
+		SVGA  string              `json:"svg_a"`
+	} `json:"rows"`
+}
+
+// withoutOpenRouter points the model list at a server that has no models, so
+// the compare picker never reaches the network
+func withoutOpenRouter(t *testing.T) {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(upstream.Close)
+	t.Setenv("OPENROUTER_BASE_URL", upstream.URL)
+}
+
+func TestCompareHandlerEmpty(t *testing.T) {
+	withoutOpenRouter(t)
+	db := newTestDB(t)
+
+	h := NewPageHandler(db, dataTemplates(t, "compare.html"), models.TemplateData{}, nil)
+	var data compareData
+	decodeData(t, serve(h.CompareHandler, "/compare"), &data)
+	if data.ModelA != defaultCompareModelA || data.ModelB != defaultCompareModelB {
+		t.Errorf("models = %q, %q, want the defaults %q, %q", data.ModelA, data.ModelB, defaultCompareModelA, defaultCompareModelB)
+	}
+	if data.SameModel || len(data.Rows) != 0 || len(data.Models) != 0 {
+		t.Errorf("empty database compared as %+v", data)
+	}
+
+	h = NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+	rec := serve(h.CompareHandler, "/compare")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "Nothing to compare yet") {
+		t.Error("empty compare page does not show the empty state")
+	}
+
+	rec = httptest.NewRecorder()
+	h.CompareHandler(rec, httptest.NewRequest(http.MethodPost, "/compare", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCompareHandlerSeeded(t *testing.T) {
+	withoutOpenRouter(t)
+	db := newTestDB(t)
+	const modelA, modelB = "openai/gpt-4o", "google/gemini-2.5-pro"
+
+	pelican := seedGroup(t, db, "Pelican", "")
+	seedArtwork(t, db, pelican, modelA, testSVG)
+	seedArtwork(t, db, pelican, modelB, testSVG)
+	bicycle := seedGroup(t, db, "Bicycle", "")
+	seedArtwork(t, db, bicycle, modelA, testSVG)
+	seedArtwork(t, db, bicycle, modelB, testSVG)
+	heron := seedGroup(t, db, "Heron", "")
+	seedArtwork(t, db, heron, modelA, testSVG)
+	archived := seedGroup(t, db, "Archived", "")
+	seedArtwork(t, db, archived, modelA, testSVG)
+	seedArtwork(t, db, archived, modelB, testSVG)
+	if err := db.SetGroupArchived(archived, true); err != nil {
+		t.Fatalf("SetGroupArchived: %v", err)
+	}
+
+	h := NewPageHandler(db, dataTemplates(t, "compare.html"), models.TemplateData{}, nil)
+	target := "/compare?model_a=" + modelA + "&model_b=" + modelB
+	var data compareData
+	decodeData(t, serve(h.CompareHandler, target), &data)
+	if data.ModelA != modelA || data.ModelB != modelB || data.SameModel {
+		t.Errorf("compared %q with %q (same %v), want %q with %q", data.ModelA, data.ModelB, data.SameModel, modelA, modelB)
+	}
+
+	// Only groups both models have drawn are compared, newest first
+	var titles []string
+	for _, row := range data.Rows {
+		titles = append(titles, row.Group.Title)
+		if row.A.Model != modelA || row.B.Model != modelB || row.SVGA != testSVG {
+			t.Errorf("%s row = %s vs %s, want %s vs %s with the SVG", row.Group.Title, row.A.Model, row.B.Model, modelA, modelB)
+		}
+	}
+	if want := []string{"Bicycle", "Pelican"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("rows = %v, want %v", titles, want)
+	}
+
+	// Without the OpenRouter list the picker still offers every used model
+	var offered []string
+	for _, model := range data.Models {
+		offered = append(offered, model.ID)
+	}
+	sort.Strings(offered)
+	if want := []string{modelB, modelA}; !reflect.DeepEqual(offered, want) {
+		t.Errorf("models = %v, want %v", offered, want)
+	}
+
+	data = compareData{}
+	decodeData(t, serve(h.CompareHandler, "/compare?model_a="+modelA+"&model_b="+modelA), &data)
+	if !data.SameModel || len(data.Rows) != 0 {
+		t.Errorf("same model compared as %+v, want no rows", data)
+	}
+
+	h = NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+	rec := serve(h.CompareHandler, target)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Pelican") || !strings.Contains(body, "Bicycle") || strings.Contains(body, "Heron") {
+		t.Error("compare page does not show exactly the groups both models drew")
+	}
+}
@@ -0,0 +1,127 @@
+package pages
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pelican-gallery/internal/config"
+)
+
+// atomFeed is an Atom (RFC 4287) feed
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Category  *atomTerm  `xml:"category,omitempty"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+}
+
+type atomTerm struct {
+	Term   string `xml:"term,attr"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+	Label  string `xml:"label,attr,omitempty"`
+}
+
+// FeedHandler serves GET /feed.xml, an Atom feed of the most recently added
+// groups. Each entry links to the group page and to its preview card.
+// ?limit= overrides the number of groups, up to config.MaxFeedSize.
+func (h *PageHandler) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := config.FeedSize()
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, config.MaxFeedSize)
+	}
+
+	groups, err := h.db.ListRecentGroups(limit)
+	if err != nil {
+		log.Printf("Error listing recent groups for the feed: %v", err)
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	base := config.RequestBaseURL(r)
+	feed := atomFeed{
+		ID:     base + "/",
+		Title:  siteName,
+		Author: atomAuthor{Name: siteName},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: base + "/feed.xml"},
+			{Rel: "alternate", Type: "text/html", Href: base + "/"},
+		},
+	}
+
+	// The feed is as recent as its newest change; an empty feed is as old as now
+	updated := time.Time{}
+	for _, group := range groups {
+		groupURL := fmt.Sprintf("%s/group/%d", base, group.ID)
+		entry := atomEntry{
+			ID:        groupURL,
+			Title:     group.Title,
+			Published: group.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   group.UpdatedAt.UTC().Format(time.RFC3339),
+			Links: []atomLink{
+				{Rel: "alternate", Type: "text/html", Href: groupURL},
+				{Rel: "enclosure", Type: "image/png", Href: groupCardURL(base, group.ID)},
+			},
+			Summary: group.Prompt,
+		}
+		if group.CategorySlug != "" {
+			entry.Category = &atomTerm{Term: group.CategorySlug, Scheme: base + "/gallery/category/", Label: group.Category}
+		}
+		feed.Entries = append(feed.Entries, entry)
+
+		if group.UpdatedAt.After(updated) {
+			updated = group.UpdatedAt
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding feed: %v", err)
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
@@ -0,0 +1,116 @@
+package pages
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/models"
+)
+
+// seedDatedGroup creates a group in category created at created, with a
+// generated artwork so it is published, and returns its ID
+func seedDatedGroup(t *testing.T, db *database.DB, title, category string, created time.Time) int {
+	t.Helper()
+	id, err := db.CreateGroup(models.ArtworkGroup{Title: title, Prompt: "Generate an SVG of " + title, Category: category, CreatedAt: created, UpdatedAt: created})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	seedArtwork(t, db, id, "openai/gpt-4o", testSVG)
+	return id
+}
+
+// readFeed serves the feed at target and decodes it
+func readFeed(t *testing.T, h *PageHandler, target string) atomFeed {
+	t.Helper()
+	rec := serve(h.FeedHandler, target)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, body %s", target, rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q, want application/atom+xml", ct)
+	}
+	if !strings.HasPrefix(rec.Body.String(), xml.Header) {
+		t.Error("feed does not start with an XML declaration")
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not well-formed Atom: %v", err)
+	}
+	return feed
+}
+
+func TestFeedHandler(t *testing.T) {
+	t.Setenv("BASE_URL", "https://pelican.example")
+	db := newTestDB(t)
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldest := seedDatedGroup(t, db, "Heron", "", start)
+	newest := seedDatedGroup(t, db, "Pelican", "Birds", start.Add(2*time.Hour))
+	middle := seedDatedGroup(t, db, "Bicycle", "", start.Add(time.Hour))
+	archived := seedDatedGroup(t, db, "Archived", "", start.Add(3*time.Hour))
+	if err := db.SetGroupArchived(archived, true); err != nil {
+		t.Fatalf("SetGroupArchived: %v", err)
+	}
+	// Groups without a generated artwork are not published yet
+	pending := seedGroup(t, db, "Pending", "")
+	seedArtwork(t, db, pending, "openai/gpt-4o", "")
+
+	h := NewPageHandler(db, dataTemplates(t), models.TemplateData{}, nil)
+	feed := readFeed(t, h, "/feed.xml")
+	if feed.XMLName.Space != "http://www.w3.org/2005/Atom" || feed.XMLName.Local != "feed" {
+		t.Errorf("root element = %v, want an Atom feed", feed.XMLName)
+	}
+	if feed.ID != "https://pelican.example/" || feed.Title == "" || feed.Author.Name == "" {
+		t.Errorf("feed = id %q, title %q, author %q", feed.ID, feed.Title, feed.Author.Name)
+	}
+	if feed.Updated != start.Add(2*time.Hour).Format(time.RFC3339) {
+		t.Errorf("feed updated = %q, want the newest entry's", feed.Updated)
+	}
+
+	// Newest first, without archived or unpublished groups
+	var ids []int
+	for _, entry := range feed.Entries {
+		id, err := strconv.Atoi(strings.TrimPrefix(entry.ID, "https://pelican.example/group/"))
+		if err != nil {
+			t.Fatalf("entry ID %q is not a group URL", entry.ID)
+		}
+		ids = append(ids, id)
+	}
+	if want := []int{newest, middle, oldest}; len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Fatalf("entries = %v, want %v", ids, want)
+	}
+
+	entry := feed.Entries[0]
+	groupURL := "https://pelican.example/group/" + strconv.Itoa(newest)
+	if entry.Title != "Pelican" || entry.Summary != "Generate an SVG of Pelican" || entry.Published != start.Add(2*time.Hour).Format(time.RFC3339) {
+		t.Errorf("entry = %+v", entry)
+	}
+	if len(entry.Links) != 2 || entry.Links[0].Href != groupURL || entry.Links[1].Rel != "enclosure" || entry.Links[1].Href != groupURL+"/og-image.png" {
+		t.Errorf("entry links = %+v", entry.Links)
+	}
+	if entry.Category == nil || entry.Category.Label != "Birds" || entry.Category.Term != "birds" {
+		t.Errorf("entry category = %+v, want Birds", entry.Category)
+	}
+	if feed.Entries[1].Category != nil {
+		t.Errorf("uncategorized entry has category %+v", feed.Entries[1].Category)
+	}
+
+	rec := httptest.NewRecorder()
+	h.FeedHandler(rec, httptest.NewRequest(http.MethodPost, "/feed.xml", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestFeedHandlerEmpty(t *testing.T) {
+	h := NewPageHandler(newTestDB(t), dataTemplates(t), models.TemplateData{}, nil)
+	feed := readFeed(t, h, "/feed.xml")
+	if len(feed.Entries) != 0 || feed.Updated == "" {
+		t.Errorf("empty feed = %d entries, updated %q", len(feed.Entries), feed.Updated)
+	}
+}
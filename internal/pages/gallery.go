@@ -0,0 +1,104 @@
+package pages
+
+import (
+	"html/template"
+	"strings"
+	"sync"
+
+	"pelican-gallery/internal/models"
+)
+
+// galleryArtwork is the artwork shown for a group on the gallery page
+type galleryArtwork struct {
+	models.Artwork
+	Title      string        `json:"title"`
+	Category   string        `json:"category"`
+	Prompt     string        `json:"prompt"`
+	ArtistName string        `json:"artist_name"`
+	SVGContent template.HTML `json:"svg_content"`
+}
+
+// galleryGroup is a group on the gallery page with the artwork it shows
+type galleryGroup struct {
+	models.ArtworkGroup
+	Artworks           []galleryArtwork `json:"artworks"`
+	HasOriginalArtwork bool             `json:"has_original_artwork"`
+}
+
+// assembleGallery builds the gallery groups in the order of groups. Batches
+// of batchSize groups are spread over at most workers goroutines; with one
+// worker, or no more than one batch, the groups are assembled serially.
+func assembleGallery(groups []models.ArtworkGroup, artworkMap map[int][]models.Artwork, workers, batchSize int) []galleryGroup {
+	galleryGroups := make([]galleryGroup, len(groups))
+	if workers <= 1 || len(groups) <= batchSize {
+		for i, group := range groups {
+			galleryGroups[i] = buildGalleryGroup(group, artworkMap[group.ID])
+		}
+		return galleryGroups
+	}
+
+	// Each batch writes its own range of galleryGroups, so the order is kept
+	// without further synchronization
+	batches := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range batches {
+				end := min(start+batchSize, len(groups))
+				for i := start; i < end; i++ {
+					galleryGroups[i] = buildGalleryGroup(groups[i], artworkMap[groups[i].ID])
+				}
+			}
+		}()
+	}
+	for start := 0; start < len(groups); start += batchSize {
+		batches <- start
+	}
+	close(batches)
+	wg.Wait()
+
+	return galleryGroups
+}
+
+// buildGalleryGroup picks the artwork shown for a group: its featured
+// artwork, or else its GPT-5 artwork, alongside the original
+func buildGalleryGroup(group models.ArtworkGroup, artworks []models.Artwork) galleryGroup {
+	artworks = models.FilterVisible(artworks, models.ScopeListing)
+
+	var featuredArtwork *models.Artwork
+	var gpt5Artwork *models.Artwork
+	for i, artwork := range artworks {
+		if artwork.Featured {
+			featuredArtwork = &artworks[i]
+			break
+		}
+		if strings.ToLower(artwork.Model) == "openai/gpt-5" {
+			gpt5Artwork = &artworks[i]
+		}
+	}
+
+	selectedArtwork := featuredArtwork
+	if selectedArtwork == nil {
+		selectedArtwork = gpt5Artwork
+	}
+
+	var filteredArtworks []galleryArtwork
+	if selectedArtwork != nil {
+		filteredArtworks = append(filteredArtworks, galleryArtwork{
+			Artwork:    *selectedArtwork,
+			Title:      group.Title,
+			Category:   group.Category,
+			Prompt:     group.Prompt,
+			ArtistName: group.ArtistName,
+			SVGContent: template.HTML(selectedArtwork.SVG),
+		})
+	}
+
+	return galleryGroup{
+		ArtworkGroup:       group,
+		Artworks:           filteredArtworks,
+		HasOriginalArtwork: group.HasOriginalArtwork,
+	}
+}
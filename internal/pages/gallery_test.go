@@ -0,0 +1,96 @@
+package pages
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// syntheticGallery returns n groups with artworks by three models, some of
+// them featured, private or without an SVG, as the gallery query returns them
+func syntheticGallery(n int) ([]models.ArtworkGroup, map[int][]models.Artwork) {
+	groups := make([]models.ArtworkGroup, n)
+	artworkMap := make(map[int][]models.Artwork, n)
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">` + strings.Repeat(`<path d="M10 10 L90 90"/>`, 50) + `</svg>`
+
+	for i := range groups {
+		id := i + 1
+		groups[i] = models.ArtworkGroup{ID: id, Title: fmt.Sprintf("Group %d", id), Prompt: "A pelican", Category: "Birds", HasOriginalArtwork: i%5 == 0}
+		for j, model := range []string{"openai/gpt-5", "google/gemini-2.5-pro", "anthropic/claude-sonnet-4"} {
+			artwork := models.Artwork{ID: id*10 + j, GroupID: id, Model: model, SVG: svg, Visibility: models.VisibilityPublic}
+			switch {
+			case i%7 == 0 && j == 0:
+				artwork.SVG = ""
+			case i%4 == 0 && j == 1:
+				artwork.Featured = true
+			case i%6 == 0 && j == 2:
+				artwork.Featured = true
+				artwork.Visibility = models.VisibilityPrivate
+			}
+			artworkMap[id] = append(artworkMap[id], artwork)
+		}
+	}
+	return groups, artworkMap
+}
+
+func TestAssembleGalleryMatchesSerial(t *testing.T) {
+	groups, artworkMap := syntheticGallery(1037)
+	serial := assembleGallery(groups, artworkMap, 1, len(groups))
+	if len(serial) != len(groups) {
+		t.Fatalf("serial assembly returned %d groups, want %d", len(serial), len(groups))
+	}
+
+	tests := []struct {
+		workers, batchSize int
+	}{
+		{2, 1},
+		{4, 7},
+		{8, 100},
+		{3, 1036},
+		{16, 2000}, // a single batch stays serial
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d workers, batch %d", tt.workers, tt.batchSize), func(t *testing.T) {
+			if got := assembleGallery(groups, artworkMap, tt.workers, tt.batchSize); !reflect.DeepEqual(got, serial) {
+				t.Error("parallel assembly differs from the serial one")
+			}
+		})
+	}
+}
+
+func TestAssembleGalleryPicksShownArtwork(t *testing.T) {
+	groups, artworkMap := syntheticGallery(13)
+	gallery := assembleGallery(groups, artworkMap, 4, 2)
+
+	for i, group := range gallery {
+		if group.ID != groups[i].ID {
+			t.Fatalf("gallery[%d] is group %d, want %d", i, group.ID, groups[i].ID)
+		}
+		// Private artworks are skipped even when featured
+		want := "openai/gpt-5"
+		if i%4 == 0 {
+			want = "google/gemini-2.5-pro"
+		}
+		var shown []string
+		for _, artwork := range group.Artworks {
+			shown = append(shown, artwork.Model)
+		}
+		if len(shown) != 1 || shown[0] != want {
+			t.Errorf("group %d shows %v, want the artwork of %s", group.ID, shown, want)
+		}
+	}
+}
+
+func BenchmarkAssembleGallery(b *testing.B) {
+	groups, artworkMap := syntheticGallery(5000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				assembleGallery(groups, artworkMap, workers, 100)
+			}
+		})
+	}
+}
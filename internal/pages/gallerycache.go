@@ -0,0 +1,122 @@
+package pages
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// maxCachedGalleryPages bounds the rendered gallery pages kept in memory
+const maxCachedGalleryPages = 256
+
+// galleryPage is a rendered gallery page and when it stops being served
+type galleryPage struct {
+	html    []byte
+	expires time.Time
+}
+
+// GalleryCache holds rendered gallery pages for a TTL. Invalidate drops them
+// all; it is called after every write through the API and every saved SVG,
+// so the TTL only bounds how long writes from other processes, such as the
+// CLI, take to show.
+type GalleryCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	pages      map[string]galleryPage
+	generation uint64 // bumped by Invalidate, so renders it overtook are not stored
+}
+
+// NewGalleryCache creates a cache serving each page for ttl
+func NewGalleryCache(ttl time.Duration) *GalleryCache {
+	return &GalleryCache{ttl: ttl, pages: make(map[string]galleryPage)}
+}
+
+// galleryCacheKey identifies a gallery page: its category, sort and archive
+// filter, and the base URL its canonical links were rendered with
+func galleryCacheKey(r *http.Request, category string, sortBy models.GroupSort, includeArchived bool) string {
+	return strings.Join([]string{category, string(sortBy), strconv.FormatBool(includeArchived), config.RequestBaseURL(r)}, "\x00")
+}
+
+// get returns the page stored under key while it is fresh, and the
+// generation to store a newly rendered page with
+func (c *GalleryCache) get(key string) ([]byte, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	page, ok := c.pages[key]
+	if ok && time.Now().After(page.expires) {
+		delete(c.pages, key)
+		ok = false
+	}
+	return page.html, c.generation, ok
+}
+
+// put stores a page rendered from data read at generation, unless the cache
+// has been invalidated since. An arbitrary page is dropped when full.
+func (c *GalleryCache) put(key string, generation uint64, html []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if _, exists := c.pages[key]; !exists && len(c.pages) >= maxCachedGalleryPages {
+		for k := range c.pages {
+			delete(c.pages, k)
+			break
+		}
+	}
+	c.pages[key] = galleryPage{html: html, expires: time.Now().Add(c.ttl)}
+}
+
+// Invalidate drops every cached page
+func (c *GalleryCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pages = make(map[string]galleryPage)
+	c.generation++
+}
+
+// InvalidateOnWrite calls Invalidate after every successful request to next
+// that may change data: any method but GET, HEAD and OPTIONS answered with a
+// 2xx status. The status is only known once the handler returns, so a
+// response streamed over a long time, like batch job events, invalidates
+// when it ends.
+func (c *GalleryCache) InvalidateOnWrite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status >= 200 && recorder.status < 300 {
+			c.Invalidate()
+		}
+	})
+}
+
+// statusRecorder remembers the status written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rw *statusRecorder) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush server-sent events
+func (rw *statusRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,151 @@
+package pages
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+// countReads counts the read queries run on db by the method that ran them
+func countReads(t *testing.T, h *PageHandler) func(query string) int {
+	t.Helper()
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	h.db.ObserveReads(func(query string, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[query]++
+	})
+	return func(query string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[query]
+	}
+}
+
+func TestGalleryHandlerCache(t *testing.T) {
+	db := newTestDB(t)
+	seedGroup(t, db, "Pelican", "Birds")
+
+	h := NewPageHandler(db, dataTemplates(t, "gallery.html"), models.TemplateData{}, nil)
+	cache := NewGalleryCache(time.Minute)
+	h.SetGalleryCache(cache)
+	reads := countReads(t, h)
+
+	titles := func(target string) string {
+		t.Helper()
+		var data struct {
+			Groups []models.ArtworkGroup `json:"groups"`
+		}
+		decodeData(t, serve(h.GalleryHandler, target), &data)
+		var titles []string
+		for _, g := range data.Groups {
+			titles = append(titles, g.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	const target = "/gallery/?category=birds&sort=title"
+	if got := titles(target); got != "Pelican" {
+		t.Fatalf("first render: groups = %q, want Pelican", got)
+	}
+	if n := reads("ListGroupsWithArtworks"); n != 1 {
+		t.Fatalf("first render listed groups %d times, want 1", n)
+	}
+
+	// Writes that bypass the cache are not seen until it is invalidated
+	seedGroup(t, db, "Heron", "Birds")
+	if got := titles(target); got != "Pelican" {
+		t.Errorf("cache hit: groups = %q, want the cached Pelican", got)
+	}
+	if n := reads("ListGroupsWithArtworks"); n != 1 {
+		t.Errorf("cache hit listed groups again: %d queries, want 1", n)
+	}
+
+	// Another sort is another page
+	if got := titles("/gallery/?category=birds&sort=newest"); got != "Heron,Pelican" {
+		t.Errorf("other sort: groups = %q, want Heron,Pelican", got)
+	}
+	if n := reads("ListGroupsWithArtworks"); n != 2 {
+		t.Errorf("other sort: %d queries, want 2", n)
+	}
+
+	cache.Invalidate()
+	if got := titles(target); got != "Heron,Pelican" {
+		t.Errorf("after Invalidate: groups = %q, want Heron,Pelican", got)
+	}
+	if n := reads("ListGroupsWithArtworks"); n != 3 {
+		t.Errorf("after Invalidate: %d queries, want 3", n)
+	}
+}
+
+func TestGalleryCacheDropsStaleRenders(t *testing.T) {
+	cache := NewGalleryCache(time.Minute)
+
+	_, generation, ok := cache.get("page")
+	if ok {
+		t.Fatal("empty cache has a page")
+	}
+	// A write while the page was rendered makes the render stale
+	cache.Invalidate()
+	cache.put("page", generation, []byte("stale"))
+	if _, _, ok := cache.get("page"); ok {
+		t.Error("a page rendered before Invalidate was stored")
+	}
+
+	_, generation, _ = cache.get("page")
+	cache.put("page", generation, []byte("fresh"))
+	if html, _, ok := cache.get("page"); !ok || string(html) != "fresh" {
+		t.Errorf("get = %q, %v; want fresh", html, ok)
+	}
+}
+
+func TestGalleryCacheExpires(t *testing.T) {
+	cache := NewGalleryCache(time.Millisecond)
+	_, generation, _ := cache.get("page")
+	cache.put("page", generation, []byte("page"))
+	time.Sleep(5 * time.Millisecond)
+	if _, _, ok := cache.get("page"); ok {
+		t.Error("page served past its TTL")
+	}
+}
+
+func TestGalleryCacheInvalidateOnWrite(t *testing.T) {
+	tests := []struct {
+		method          string
+		status          int
+		wantInvalidated bool
+	}{
+		{http.MethodPost, http.StatusCreated, true},
+		{http.MethodPut, http.StatusOK, true},
+		{http.MethodDelete, http.StatusNoContent, true},
+		{http.MethodPost, http.StatusBadRequest, false},
+		{http.MethodDelete, http.StatusNotFound, false},
+		{http.MethodGet, http.StatusOK, false},
+		{http.MethodHead, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+http.StatusText(tt.status), func(t *testing.T) {
+			cache := NewGalleryCache(time.Minute)
+			_, generation, _ := cache.get("page")
+			cache.put("page", generation, []byte("page"))
+
+			handler := cache.InvalidateOnWrite(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/api/groups", nil))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if _, _, ok := cache.get("page"); ok == tt.wantInvalidated {
+				t.Errorf("page cached = %v, want %v", ok, !tt.wantInvalidated)
+			}
+		})
+	}
+}
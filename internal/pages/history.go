@@ -0,0 +1,97 @@
+package pages
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"pelican-gallery/internal/models"
+)
+
+// historyLimit is the number of versions the history page shows, the latest
+const historyLimit = 20
+
+// GroupHistoryHandler serves GET /group/{id}/history?model=..., the versions
+// a model drew for a group in the order they were stored, with their
+// parameters and size. Without ?model= the first model of the group is shown.
+// While editing is enabled, earlier versions can be restored.
+func (h *PageHandler) GroupHistoryHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	group, err := h.db.GetGroup(groupID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	artworks, err := h.db.ListArtworksByGroup(groupID)
+	if err != nil {
+		log.Printf("Error fetching artworks for group %d history: %v", groupID, err)
+		http.Error(w, "Failed to load artworks", http.StatusInternalServerError)
+		return
+	}
+	scope := viewScope(r)
+	var modelOptions []string
+	seen := make(map[string]bool)
+	for _, artwork := range models.FilterVisible(artworks, scope) {
+		if !seen[artwork.Model] {
+			seen[artwork.Model] = true
+			modelOptions = append(modelOptions, artwork.Model)
+		}
+	}
+
+	model := r.URL.Query().Get("model")
+	if model == "" && len(modelOptions) > 0 {
+		model = modelOptions[0]
+	}
+
+	type HistoryVersion struct {
+		models.ArtworkVersion
+		SVGContent template.HTML `json:"-"`
+	}
+
+	var versions []HistoryVersion
+	if model != "" {
+		stored, err := h.db.ListModelHistory(groupID, model, scope, historyLimit)
+		if err != nil {
+			log.Printf("Error fetching history of %s in group %d: %v", model, groupID, err)
+			http.Error(w, "Failed to load history", http.StatusInternalServerError)
+			return
+		}
+		for _, version := range stored {
+			versions = append(versions, HistoryVersion{ArtworkVersion: version, SVGContent: template.HTML(version.SVG)})
+		}
+	}
+
+	data := struct {
+		Title          string               `json:"title"`
+		Group          *models.ArtworkGroup `json:"group"`
+		Model          string               `json:"model"`
+		Models         []string             `json:"models"`
+		Versions       []HistoryVersion     `json:"versions"`
+		Limit          int                  `json:"limit"`
+		EditingEnabled bool                 `json:"editing_enabled"`
+		CSSHash        string               `json:"css_hash"`
+	}{
+		Title:          "History - " + group.Title + " - Pelican Art Gallery",
+		Group:          group,
+		Model:          model,
+		Models:         modelOptions,
+		Versions:       versions,
+		Limit:          historyLimit,
+		EditingEnabled: isEditingEnabled(),
+		CSSHash:        h.getCSSHash(),
+	}
+
+	h.render(w, "group-history.html", data)
+}
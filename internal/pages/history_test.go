@@ -0,0 +1,137 @@
+package pages
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+)
+
+// historyData is the data GroupHistoryHandler passes to group-history.html
+type historyData struct {
+	Group          models.ArtworkGroup     `json:"group"`
+	Model          string                  `json:"model"`
+	Models         []string                `json:"models"`
+	Versions       []models.ArtworkVersion `json:"versions"`
+	Limit          int                     `json:"limit"`
+	EditingEnabled bool                    `json:"editing_enabled"`
+}
+
+// serveHistory runs GroupHistoryHandler on a GET of target, as the router
+// does for /group/{id}/history, optionally with the admin flag set
+func serveHistory(h *PageHandler, groupID, target string, admin bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if admin {
+		req = req.WithContext(config.WithAdmin(req.Context()))
+	}
+	rec := httptest.NewRecorder()
+	h.GroupHistoryHandler(rec, req, groupID)
+	return rec
+}
+
+func TestGroupHistoryHandler(t *testing.T) {
+	t.Setenv("ENABLE_EDITING", "true")
+	db := newTestDB(t)
+	const gpt, gemini = "openai/gpt-4o", "google/gemini-2.5-pro"
+	groupID := seedGroup(t, db, "Pelican", "")
+	id := strconv.Itoa(groupID)
+	artworkID := seedArtwork(t, db, groupID, gpt, testSVG)
+	for _, r := range []string{"1", "2"} {
+		svg := strings.Replace(testSVG, `r="4"`, `r="`+r+`"`, 1)
+		if err := db.SaveArtworkSVG(artworkID, svg); err != nil {
+			t.Fatalf("SaveArtworkSVG: %v", err)
+		}
+	}
+	private := seedArtwork(t, db, groupID, gemini, testSVG)
+	if err := db.SetArtworkVisibility(private, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	h := NewPageHandler(db, dataTemplates(t, "group-history.html"), models.TemplateData{}, nil)
+	tests := []struct {
+		name, target string
+		admin        bool
+		model        string
+		models       []string
+		versions     int
+	}{
+		{"first model by default", "/group/" + id + "/history", false, gpt, []string{gpt}, 3},
+		{"picked model", "/group/" + id + "/history?model=" + gpt, false, gpt, []string{gpt}, 3},
+		{"private artwork without the key", "/group/" + id + "/history?model=" + gemini, false, gemini, []string{gpt}, 0},
+		{"private artwork with the key", "/group/" + id + "/history?model=" + gemini, true, gemini, []string{gpt, gemini}, 1},
+		{"model without artworks", "/group/" + id + "/history?model=nobody/nothing", false, "nobody/nothing", []string{gpt}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data historyData
+			decodeData(t, serveHistory(h, id, tt.target, tt.admin), &data)
+			if data.Group.ID != groupID || data.Model != tt.model || data.Limit != historyLimit || !data.EditingEnabled {
+				t.Errorf("data = %+v", data)
+			}
+			if strings.Join(data.Models, ",") != strings.Join(tt.models, ",") {
+				t.Errorf("models = %v, want %v", data.Models, tt.models)
+			}
+			if len(data.Versions) != tt.versions {
+				t.Fatalf("%d versions, want %d", len(data.Versions), tt.versions)
+			}
+			for i, version := range data.Versions {
+				if version.IsCurrent() != (i == len(data.Versions)-1) {
+					t.Errorf("version %d current = %v", i, version.IsCurrent())
+				}
+			}
+		})
+	}
+
+	for _, target := range []string{"abc", strconv.Itoa(groupID + 100)} {
+		if rec := serveHistory(h, target, "/group/"+target+"/history", false); rec.Code != http.StatusNotFound {
+			t.Errorf("history of group %q = %d, want %d", target, rec.Code, http.StatusNotFound)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.GroupHistoryHandler(rec, httptest.NewRequest(http.MethodPost, "/group/"+id+"/history", nil), id)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestGroupHistoryPage(t *testing.T) {
+	db := newTestDB(t)
+	groupID := seedGroup(t, db, "Pelican", "")
+	id := strconv.Itoa(groupID)
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	if err := db.SaveArtworkSVG(artworkID, strings.Replace(testSVG, `r="4"`, `r="2"`, 1)); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	h := NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+
+	tests := []struct {
+		editing, target string
+		want, unwanted  []string
+	}{
+		{"true", "/group/" + id + "/history", []string{"Version 1", "Version 2", "current", "Restore this version", `data-revision-id="`}, nil},
+		{"false", "/group/" + id + "/history", []string{"Version 1", "Version 2"}, []string{"Restore this version", "modules/api.js"}},
+		{"false", "/group/" + id + "/history?model=nobody/nothing", []string{"No history yet"}, []string{"Version 1"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("ENABLE_EDITING", tt.editing)
+		rec := serveHistory(h, id, tt.target, false)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s = %d: %s", tt.target, rec.Code, rec.Body)
+		}
+		body := rec.Body.String()
+		for _, want := range tt.want {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s with editing %s does not contain %q", tt.target, tt.editing, want)
+			}
+		}
+		for _, unwanted := range tt.unwanted {
+			if strings.Contains(body, unwanted) {
+				t.Errorf("%s with editing %s contains %q", tt.target, tt.editing, unwanted)
+			}
+		}
+	}
+}
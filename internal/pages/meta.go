@@ -0,0 +1,183 @@
+package pages
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/render"
+)
+
+// siteName is appended to page titles and shown as og:site_name
+const siteName = "Pelican Art Gallery"
+
+// metaDescriptionLength bounds descriptions taken from prompts
+const metaDescriptionLength = 200
+
+// ogArtworkWidth is the width of the artwork PNGs listed as extra og:image
+// tags after the preview card
+const ogArtworkWidth = 800
+
+// pageMeta holds the description, canonical URL and OpenGraph tags of a
+// page, rendered by the "meta" partial
+type pageMeta struct {
+	Title        string
+	Description  string
+	CanonicalURL string
+	ImageURL     string
+	ImageWidth   int
+	ImageHeight  int
+	ImageAlt     string
+
+	// ArtworkImages are PNG renders of the artworks on the card, for
+	// consumers that prefer a single artwork over the card
+	ArtworkImages []string
+
+	// StructuredData, when set, is emitted as a JSON-LD script. The template
+	// encodes it as JSON and escapes it for the script element.
+	StructuredData interface{}
+}
+
+// schemaContext is the JSON-LD context of schema.org markup
+const schemaContext = "https://schema.org"
+
+// visualArtwork is the schema.org VisualArtwork markup of a group page
+type visualArtwork struct {
+	Context     string        `json:"@context"`
+	Type        string        `json:"@type"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	URL         string        `json:"url"`
+	Image       string        `json:"image,omitempty"`
+	DateCreated string        `json:"dateCreated,omitempty"`
+	Creator     *schemaPerson `json:"creator,omitempty"`
+}
+
+// schemaPerson is a schema.org Person
+type schemaPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// groupStructuredData describes a group as a schema.org VisualArtwork: its
+// title, its prompt, the artist it is after and when it was created
+func groupStructuredData(group *models.ArtworkGroup, canonicalURL, imageURL string) visualArtwork {
+	data := visualArtwork{
+		Context:     schemaContext,
+		Type:        "VisualArtwork",
+		Name:        group.Title,
+		Description: strings.Join(strings.Fields(group.Prompt), " "),
+		URL:         canonicalURL,
+		Image:       imageURL,
+	}
+	if !group.CreatedAt.IsZero() {
+		data.DateCreated = group.CreatedAt.UTC().Format("2006-01-02")
+	}
+	if group.ArtistName != "" {
+		data.Creator = &schemaPerson{Type: "Person", Name: group.ArtistName}
+	}
+	return data
+}
+
+// truncateDescription collapses whitespace in s and shortens it to at most
+// max runes, cutting at the last word boundary and adding an ellipsis
+func truncateDescription(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+
+	cut := string(runes[:max-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
+
+// groupCardURL is the preview card of a group
+func groupCardURL(base string, groupID int) string {
+	return fmt.Sprintf("%s/group/%d/og-image.png", base, groupID)
+}
+
+// groupMeta returns the metadata of a group page: its title, artist and an
+// excerpt of its prompt, with the group's preview card as image and
+// schema.org markup for rich results
+func groupMeta(r *http.Request, group *models.ArtworkGroup, artworks []models.Artwork) pageMeta {
+	base := config.RequestBaseURL(r)
+
+	description := group.Prompt
+	if group.ArtistName != "" {
+		description = "After " + group.ArtistName + ". " + description
+	}
+
+	// The SVG version in the URL lets the PNG be cached for good
+	var artworkImages []string
+	for _, artwork := range cardArtworks(artworks) {
+		artworkImages = append(artworkImages, fmt.Sprintf("%s/artworks/%d.png?width=%d&v=%s",
+			base, artwork.ID, ogArtworkWidth, render.Version(artwork.SVG)))
+	}
+
+	canonicalURL := fmt.Sprintf("%s/group/%d", base, group.ID)
+	imageURL := groupCardURL(base, group.ID)
+	return pageMeta{
+		Title:          group.Title + " - " + siteName,
+		Description:    truncateDescription(description, metaDescriptionLength),
+		CanonicalURL:   canonicalURL,
+		ImageURL:       imageURL,
+		ImageWidth:     render.CardWidth,
+		ImageHeight:    render.CardHeight,
+		ImageAlt:       "Artwork drawn by AI models for " + group.Title,
+		ArtworkImages:  artworkImages,
+		StructuredData: groupStructuredData(group, canonicalURL, imageURL),
+	}
+}
+
+// galleryMeta returns the metadata of a gallery category page. The card of
+// the first group shown is used as image.
+func galleryMeta(r *http.Request, category models.Category, artworkCount int, firstGroupID int) pageMeta {
+	base := config.RequestBaseURL(r)
+
+	meta := pageMeta{
+		Title:        "Gallery - " + siteName,
+		Description:  "SVG artwork drawn by AI models from the same prompts.",
+		CanonicalURL: base + "/gallery/",
+	}
+	if category.Name != "" {
+		plural := "s"
+		if artworkCount == 1 {
+			plural = ""
+		}
+		meta.Title = category.Name + " - " + meta.Title
+		meta.Description = fmt.Sprintf("%d artwork%s in the %s category, drawn by AI models from the same prompts.", artworkCount, plural, category.Name)
+		meta.CanonicalURL = base + "/gallery/category/" + url.PathEscape(category.Slug)
+	}
+	if firstGroupID > 0 {
+		meta.ImageURL = groupCardURL(base, firstGroupID)
+		meta.ImageWidth = render.CardWidth
+		meta.ImageHeight = render.CardHeight
+	}
+	return meta
+}
+
+// homepageMeta returns the metadata of the homepage, illustrated by the
+// featured group when there is one
+func homepageMeta(r *http.Request, featured *models.ArtworkGroup) pageMeta {
+	base := config.RequestBaseURL(r)
+
+	meta := pageMeta{
+		Title:        siteName,
+		Description:  "The same prompts drawn as SVG by different AI models, side by side.",
+		CanonicalURL: base + "/",
+	}
+	if featured != nil {
+		meta.Description = truncateDescription(meta.Description+" Featured: "+featured.Title+".", metaDescriptionLength)
+		meta.ImageURL = groupCardURL(base, featured.ID)
+		meta.ImageWidth = render.CardWidth
+		meta.ImageHeight = render.CardHeight
+	}
+	return meta
+}
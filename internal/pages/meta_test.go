@@ -0,0 +1,105 @@
+package pages
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestTruncateDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"short", "A pelican riding a bicycle", 200, "A pelican riding a bicycle"},
+		{"exact", "twelve chars", 12, "twelve chars"},
+		{"whitespace collapsed", "  A pelican\n\triding   a bicycle ", 200, "A pelican riding a bicycle"},
+		{"cut at a word", "A pelican riding a bicycle", 18, "A pelican riding…"},
+		{"partial word dropped", "A pelican riding a bicycle", 16, "A pelican…"},
+		{"punctuation dropped", "A pelican, riding a bicycle", 12, "A pelican…"},
+		{"no space to cut at", "Supercalifragilistic", 10, "Supercali…"},
+		{"runes not bytes", "Ein Storch fährt Fahrrad über Brücken", 20, "Ein Storch fährt…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateDescription(tt.in, tt.max)
+			if got != tt.want {
+				t.Errorf("truncateDescription(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.max {
+				t.Errorf("truncateDescription(%q, %d) has %d runes", tt.in, tt.max, n)
+			}
+		})
+	}
+
+	long := strings.Repeat("pelican ", 60)
+	if got := truncateDescription(long, metaDescriptionLength); utf8.RuneCountInString(got) > metaDescriptionLength || !strings.HasSuffix(got, "pelican…") {
+		t.Errorf("long description truncated to %q", got)
+	}
+}
+
+// hasTags reports every tag that is missing from page
+func hasTags(t *testing.T, page string, tags ...string) {
+	t.Helper()
+	for _, tag := range tags {
+		if !strings.Contains(page, tag) {
+			t.Errorf("page is missing %s", tag)
+		}
+	}
+}
+
+func TestMetaTagsRender(t *testing.T) {
+	t.Setenv("BASE_URL", "https://pelican.example/")
+	db := newTestDB(t)
+	groupID := seedGroup(t, db, "Pelican", "Birds of the sea")
+	// The gallery shows the GPT-5 rendition of a group
+	seedArtwork(t, db, groupID, "openai/gpt-5", testSVG)
+	group, err := db.GetGroup(groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group.ArtistName = "Audubon"
+	group.Prompt = strings.Repeat("A pelican riding a bicycle along the beach. ", 10)
+	if err := db.UpdateGroup(*group); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	categories, err := db.GetDistinctCategories()
+	if err != nil || len(categories) != 1 {
+		t.Fatalf("GetDistinctCategories = %v, %v", categories, err)
+	}
+	slug := categories[0].Slug
+
+	h := NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+	card := `https://pelican.example/group/` + strconv.Itoa(groupID) + `/og-image.png`
+
+	rec := serve(h.ArtworkGroupHandler, "/group/"+strconv.Itoa(groupID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("group page = %d, body %s", rec.Code, rec.Body)
+	}
+	hasTags(t, rec.Body.String(),
+		`<link rel="canonical" href="https://pelican.example/group/`+strconv.Itoa(groupID)+`" />`,
+		`<meta property="og:title" content="Pelican - Pelican Art Gallery" />`,
+		`<meta property="og:description" content="After Audubon. A pelican riding a bicycle`,
+		`…" />`,
+		`<meta name="twitter:card" content="summary_large_image" />`,
+		`<meta name="twitter:image" content="`+card+`" />`,
+		`<script type="application/ld+json">`,
+	)
+
+	rec = serve(h.GalleryHandler, "/gallery/?category="+slug)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("gallery page = %d, body %s", rec.Code, rec.Body)
+	}
+	hasTags(t, rec.Body.String(),
+		`<link rel="canonical" href="https://pelican.example/gallery/category/`+slug+`" />`,
+		`<meta property="og:title" content="Birds of the sea - Gallery - Pelican Art Gallery" />`,
+		`<meta property="og:description" content="1 artwork in the Birds of the sea category`,
+		`<meta property="og:image" content="`+card+`" />`,
+	)
+}
@@ -0,0 +1,134 @@
+package pages
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/render"
+)
+
+// maxCachedOGImages bounds the number of preview cards kept in memory
+const maxCachedOGImages = 128
+
+// ogImage is a rendered preview card. The fingerprint covers everything the
+// card is drawn from, so a changed title or artwork renders a new one.
+type ogImage struct {
+	fingerprint string
+	data        []byte
+	renderedAt  time.Time
+}
+
+// ogImageCache holds the preview card of each group
+type ogImageCache struct {
+	mu      sync.Mutex
+	entries map[int]ogImage
+}
+
+// get returns the card of a group if it was rendered from the same inputs
+func (c *ogImageCache) get(groupID int, fingerprint string) (ogImage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[groupID]
+	return entry, ok && entry.fingerprint == fingerprint
+}
+
+// put stores the card of a group, dropping an arbitrary entry when full
+func (c *ogImageCache) put(groupID int, entry ogImage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[int]ogImage)
+	}
+	if _, exists := c.entries[groupID]; !exists && len(c.entries) >= maxCachedOGImages {
+		for id := range c.entries {
+			delete(c.entries, id)
+			break
+		}
+	}
+	c.entries[groupID] = entry
+}
+
+// cardArtworks picks the artworks shown on a group's preview card: the
+// featured artwork on its own, otherwise the first public artworks
+func cardArtworks(artworks []models.Artwork) []models.Artwork {
+	var picked []models.Artwork
+	for _, artwork := range models.FilterVisible(artworks, models.ScopeListing) {
+		if artwork.SVG == "" {
+			continue
+		}
+		if artwork.Featured {
+			return []models.Artwork{artwork}
+		}
+		if len(picked) < render.MaxCardArtworks {
+			picked = append(picked, artwork)
+		}
+	}
+	return picked
+}
+
+// GroupOGImageHandler serves GET /group/{id}/og-image.png, the 1200x630
+// preview card shown when a group link is shared
+func (h *PageHandler) GroupOGImageHandler(w http.ResponseWriter, r *http.Request, groupIDStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDStr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	group, err := h.db.GetGroup(groupID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	artworks, err := h.db.ListArtworksByGroup(groupID)
+	if err != nil {
+		log.Printf("Error fetching artworks for group %d preview: %v", groupID, err)
+		http.Error(w, "Failed to load artworks", http.StatusInternalServerError)
+		return
+	}
+	picked := cardArtworks(artworks)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00", group.Title)
+	for _, artwork := range picked {
+		fmt.Fprintf(hash, "%d:%d\x00", artwork.ID, artwork.UpdatedAt.UnixNano())
+	}
+	fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+	card, ok := h.ogImages.get(groupID, fingerprint)
+	if !ok {
+		svgs := make([]string, len(picked))
+		for i, artwork := range picked {
+			svgs[i] = artwork.SVG
+		}
+
+		data, err := render.Card(group.Title, "Pelican Art Gallery", svgs)
+		if err != nil {
+			log.Printf("Error rendering preview card for group %d: %v", groupID, err)
+			http.Error(w, "Failed to render preview", http.StatusInternalServerError)
+			return
+		}
+		card = ogImage{fingerprint: fingerprint, data: data, renderedAt: time.Now()}
+		h.ogImages.put(groupID, card)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("ETag", `"`+card.fingerprint+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", card.renderedAt, bytes.NewReader(card.data))
+}
@@ -0,0 +1,84 @@
+package pages
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/models"
+)
+
+func TestGroupOGImageHandler(t *testing.T) {
+	db := newTestDB(t)
+	groupID := seedGroup(t, db, "Pelican riding a bicycle", "")
+	artworkID := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	seedArtwork(t, db, groupID, "google/gemini-2.5-pro", testSVG)
+	h := NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+	id := strconv.Itoa(groupID)
+
+	card := func() []byte {
+		t.Helper()
+		rec := serve(func(w http.ResponseWriter, r *http.Request) { h.GroupOGImageHandler(w, r, id) }, "/group/"+id+"/og-image.png")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("status = %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("card is not a PNG: %v", err)
+		}
+		if config.Width != 1200 || config.Height != 630 {
+			t.Errorf("card is %dx%d, want 1200x630", config.Width, config.Height)
+		}
+		return rec.Body.Bytes()
+	}
+
+	first := card()
+	if again := card(); !bytes.Equal(again, first) {
+		t.Error("second request rendered a different card")
+	}
+
+	// Changing an artwork renders a new card
+	time.Sleep(time.Millisecond)
+	if err := db.SaveArtworkSVG(artworkID, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10" fill="red"/></svg>`); err != nil {
+		t.Fatalf("SaveArtworkSVG: %v", err)
+	}
+	if changed := card(); bytes.Equal(changed, first) {
+		t.Error("card was not rendered again after an artwork changed")
+	}
+
+	for _, target := range []string{"abc", "99999"} {
+		rec := serve(func(w http.ResponseWriter, r *http.Request) { h.GroupOGImageHandler(w, r, target) }, "/group/"+target+"/og-image.png")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("card of group %s = %d, want 404", target, rec.Code)
+		}
+	}
+}
+
+func TestArtworkGroupHandlerOpenGraphTags(t *testing.T) {
+	t.Setenv("BASE_URL", "https://pelican.example")
+	db := newTestDB(t)
+	groupID := seedGroup(t, db, "Pelican riding a bicycle", "")
+	seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	h := NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+
+	rec := serve(h.ArtworkGroupHandler, "/group/"+strconv.Itoa(groupID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	page := rec.Body.String()
+	for _, tag := range []string{
+		`<meta property="og:image" content="https://pelican.example/group/` + strconv.Itoa(groupID) + `/og-image.png" />`,
+		`<meta property="og:image:width" content="1200" />`,
+		`<meta property="og:image:height" content="630" />`,
+		`<meta property="og:title" content="Pelican riding a bicycle`,
+		`<meta property="og:description" content="`,
+	} {
+		if !strings.Contains(page, tag) {
+			t.Errorf("page is missing %s", tag)
+		}
+	}
+}
@@ -1,28 +1,24 @@
 package pages
 
 import (
+	"bytes"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"pelican-gallery/internal/config"
 	"pelican-gallery/internal/database"
 	"pelican-gallery/internal/models"
 )
 
-// Filter constants for model providers
-const (
-	FilterOpenAI    = "openai"
-	FilterAnthropic = "anthropic"
-	FilterGoogle    = "google"
-	FilterOther     = "other"
-)
-
 // TemplateParser is a function type for parsing templates
 type TemplateParser func(*template.Template) (*template.Template, error)
 
@@ -32,6 +28,9 @@ type PageHandler struct {
 	tmpl           *template.Template
 	templateData   models.TemplateData
 	templateParser TemplateParser
+
+	ogImages ogImageCache
+	gallery  *GalleryCache // nil when gallery pages are rendered every time
 }
 
 // NewPageHandler creates a new page handler
@@ -44,6 +43,11 @@ func NewPageHandler(db *database.DB, tmpl *template.Template, templateData model
 	}
 }
 
+// SetGalleryCache makes the gallery page be served from cache
+func (h *PageHandler) SetGalleryCache(cache *GalleryCache) {
+	h.gallery = cache
+}
+
 // getTemplate returns the appropriate template (cached or re-parsed)
 func (h *PageHandler) getTemplate() (*template.Template, error) {
 	if h.templateParser != nil {
@@ -64,6 +68,20 @@ func (h *PageHandler) getCSSHash() string {
 	return fmt.Sprintf("%x", hash)
 }
 
+// sortOption is a gallery sort offered as a link
+type sortOption struct {
+	Value models.GroupSort `json:"value"`
+	Label string           `json:"label"`
+}
+
+// gallerySorts are the sorts offered on the gallery page
+var gallerySorts = []sortOption{
+	{Value: models.GroupSortManual, Label: "curated"},
+	{Value: models.GroupSortNewest, Label: "newest"},
+	{Value: models.GroupSortOldest, Label: "oldest"},
+	{Value: models.GroupSortTitle, Label: "a–z"},
+}
+
 // GalleryHandler handles requests to display the gallery of saved artworks
 func (h *PageHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -71,12 +89,18 @@ func (h *PageHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	category := r.URL.Query().Get("category")
+	slug := r.URL.Query().Get("category")
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	sortBy, err := models.ParseGroupSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// No model filtering on gallery page — show all artworks for the selected category
 
 	// If no category specified, redirect to first available category
-	if category == "" {
+	if slug == "" {
 		categories, err := h.db.GetDistinctCategories()
 		if err != nil {
 			log.Printf("Error fetching categories: %v", err)
@@ -84,12 +108,47 @@ func (h *PageHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if len(categories) > 0 {
-			http.Redirect(w, r, "/gallery/category/"+categories[0], http.StatusFound)
+			http.Redirect(w, r, categoryURL(r, categories[0].Slug), http.StatusFound)
 			return
 		}
 	}
 
-	groups, artworkMap, err := h.db.ListGroupsWithArtworks(category)
+	// Only pages of current slugs are rendered, so a cached page needs no
+	// lookup of its slug
+	var cacheKey string
+	var generation uint64
+	if h.gallery != nil {
+		cacheKey = galleryCacheKey(r, slug, sortBy, includeArchived)
+		html, gen, ok := h.gallery.get(cacheKey)
+		if ok {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(html)
+			return
+		}
+		generation = gen
+	}
+
+	var category models.Category
+	if slug != "" {
+		resolved, current, err := h.db.ResolveCategorySlug(slug)
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Error resolving category %q: %v", slug, err)
+			http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+			return
+		}
+		// Slugs from before a rename and the names older URLs used have moved
+		if !current {
+			http.Redirect(w, r, categoryURL(r, resolved.Slug), http.StatusMovedPermanently)
+			return
+		}
+		category = *resolved
+	}
+
+	groups, artworkMap, err := h.db.ListGroupsWithArtworks(category.Name, includeArchived, sortBy)
 	if err != nil {
 		log.Printf("Error fetching groups with artworks: %v", err)
 		http.Error(w, "Failed to fetch artworks", http.StatusInternalServerError)
@@ -103,92 +162,46 @@ func (h *PageHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only show GPT-5 artwork alongside original
-	type GalleryArtwork struct {
-		models.Artwork
-		Title      string        `json:"title"`
-		Category   string        `json:"category"`
-		Prompt     string        `json:"prompt"`
-		ArtistName string        `json:"artist_name"`
-		SVGContent template.HTML `json:"svg_content"`
-	}
+	galleryGroups := assembleGallery(groups, artworkMap, config.GalleryWorkers(), config.GalleryBatchSize())
 
-	type GalleryGroup struct {
-		models.ArtworkGroup
-		Artworks           []GalleryArtwork `json:"artworks"`
-		HasOriginalArtwork bool             `json:"has_original_artwork"`
+	var flatArtworks []galleryArtwork
+	for _, group := range galleryGroups {
+		flatArtworks = append(flatArtworks, group.Artworks...)
 	}
 
-	var galleryGroups []GalleryGroup
-	var flatArtworks []GalleryArtwork
-	for _, group := range groups {
-		artworks := artworkMap[group.ID]
-		var filteredArtworks []GalleryArtwork
-
-		// Find featured artwork (or fallback to GPT-5)
-		var featuredArtwork *models.Artwork
-		var gpt5Artwork *models.Artwork
-
-		for i, artwork := range artworks {
-			if artwork.Featured {
-				featuredArtwork = &artworks[i]
-				break
-			}
-			if strings.ToLower(artwork.Model) == "openai/gpt-5" {
-				gpt5Artwork = &artworks[i]
-			}
-		}
-
-		// Use featured if available, otherwise fallback to GPT-5
-		selectedArtwork := featuredArtwork
-		if selectedArtwork == nil {
-			selectedArtwork = gpt5Artwork
-		}
-
-		if selectedArtwork != nil {
-			ga := GalleryArtwork{
-				Artwork:    *selectedArtwork,
-				Title:      group.Title,
-				Category:   group.Category,
-				Prompt:     group.Prompt,
-				ArtistName: group.ArtistName,
-				SVGContent: template.HTML(selectedArtwork.SVG),
-			}
-			filteredArtworks = append(filteredArtworks, ga)
-			flatArtworks = append(flatArtworks, ga)
-		}
-
-		hasOriginalArtwork := len(group.OriginalArtwork) > 0
+	log.Printf("Fetched %d groups with artworks and %d categories for gallery", len(galleryGroups), len(categories))
 
-		galleryGroups = append(galleryGroups, GalleryGroup{
-			ArtworkGroup:       group,
-			Artworks:           filteredArtworks,
-			HasOriginalArtwork: hasOriginalArtwork,
-		})
+	firstGroupID := 0
+	if len(flatArtworks) > 0 {
+		firstGroupID = flatArtworks[0].GroupID
 	}
 
-	log.Printf("Fetched %d groups with artworks and %d categories for gallery", len(galleryGroups), len(categories))
-
 	data := struct {
-		Title          string           `json:"title"`
-		Groups         []GalleryGroup   `json:"groups"`
-		Artworks       []GalleryArtwork `json:"artworks"`
-		Categories     []string         `json:"categories"`
-		Category       string           `json:"category"`
-		EditingEnabled bool             `json:"editing_enabled"`
-		CSSHash        string           `json:"css_hash"`
+		Title          string            `json:"title"`
+		Groups         []galleryGroup    `json:"groups"`
+		Artworks       []galleryArtwork  `json:"artworks"`
+		Categories     []models.Category `json:"categories"`
+		Category       string            `json:"category"`
+		CategorySlug   string            `json:"category_slug"`
+		Sort           models.GroupSort  `json:"sort"`
+		Sorts          []sortOption      `json:"sorts"`
+		EditingEnabled bool              `json:"editing_enabled"`
+		CSSHash        string            `json:"css_hash"`
+		Meta           pageMeta          `json:"-"`
 	}{
 		Title:          "Gallery - Pelican Art Gallery",
 		Groups:         galleryGroups,
 		Artworks:       flatArtworks,
 		Categories:     categories,
-		Category:       category,
+		Category:       category.Name,
+		CategorySlug:   category.Slug,
+		Sort:           sortBy,
+		Sorts:          gallerySorts,
 		EditingEnabled: isEditingEnabled(),
 		CSSHash:        h.getCSSHash(),
+		Meta:           galleryMeta(r, category, len(flatArtworks), firstGroupID),
 	}
 
-	w.Header().Set("Content-Type", "text/html")
-
 	tmpl, err := h.getTemplate()
 	if err != nil {
 		log.Printf("Error getting template: %v", err)
@@ -196,11 +209,31 @@ func (h *PageHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "gallery.html", data); err != nil {
+	// Rendered to a buffer so a failed page is never cached or half sent
+	var page bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&page, "gallery.html", data); err != nil {
 		log.Printf("Error executing gallery template: %v", err)
 		http.Error(w, "Template error", http.StatusInternalServerError)
 		return
 	}
+	if h.gallery != nil {
+		h.gallery.put(cacheKey, generation, page.Bytes())
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(page.Bytes())
+}
+
+// categoryURL returns the gallery URL of the category with slug, keeping
+// the query of r but for the category the router put there
+func categoryURL(r *http.Request, slug string) string {
+	query := r.URL.Query()
+	query.Del("category")
+	target := "/gallery/category/" + url.PathEscape(slug)
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return target
 }
 
 // isEditingEnabled checks if artwork editing/creating is enabled
@@ -208,6 +241,15 @@ func isEditingEnabled() bool {
 	return config.IsEditingEnabled()
 }
 
+// viewScope returns the scope for pages addressed by direct link; private artworks
+// are only included while editing is enabled and r carries the admin API key
+func viewScope(r *http.Request) models.ViewScope {
+	if isEditingEnabled() && config.IsAdmin(r.Context()) {
+		return models.ScopeEditing
+	}
+	return models.ScopeDirectLink
+}
+
 // HomepageHandler handles requests to the homepage
 func (h *PageHandler) HomepageHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
@@ -215,38 +257,9 @@ func (h *PageHandler) HomepageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the Starry Night group (group_id = 86) with specific artworks
-	featuredGroup, err := h.db.GetGroup(86)
-	var featuredArtworks []models.Artwork
-
-	if err != nil {
-		log.Printf("Error fetching Starry Night group: %v", err)
-		// If group not found, just continue without featured content
-	} else {
-		// Get all artworks for the Starry Night group
-		allArtworks, err := h.db.ListArtworksByGroup(86)
-		if err != nil {
-			log.Printf("Error fetching artworks for Starry Night: %v", err)
-		} else {
-			// Find the specific artworks we want to feature
-			var gpt35Artwork, gpt5Artwork *models.Artwork
-			for i, artwork := range allArtworks {
-				if artwork.Model == "openai/gpt-3.5-turbo" {
-					gpt35Artwork = &allArtworks[i]
-				}
-				if artwork.Model == "openai/gpt-5" {
-					gpt5Artwork = &allArtworks[i]
-				}
-			}
-
-			// Add them in order: GPT-3.5 first, then GPT-5
-			if gpt35Artwork != nil {
-				featuredArtworks = append(featuredArtworks, *gpt35Artwork)
-			}
-			if gpt5Artwork != nil {
-				featuredArtworks = append(featuredArtworks, *gpt5Artwork)
-			}
-		}
+	featuredGroup, featuredArtworks := h.curatedFeature()
+	if featuredGroup == nil {
+		featuredGroup, featuredArtworks = h.latestFeature()
 	}
 
 	type HomepageArtwork struct {
@@ -268,11 +281,13 @@ func (h *PageHandler) HomepageHandler(w http.ResponseWriter, r *http.Request) {
 		FeaturedGroup    *models.ArtworkGroup `json:"featured_group,omitempty"`
 		FeaturedArtworks []HomepageArtwork    `json:"featured_artworks,omitempty"`
 		CSSHash          string               `json:"css_hash"`
+		Meta             pageMeta             `json:"-"`
 	}{
 		EditingEnabled:   config.IsEditingEnabled(),
 		FeaturedGroup:    featuredGroup,
 		FeaturedArtworks: homepageArtworks,
 		CSSHash:          h.getCSSHash(),
+		Meta:             homepageMeta(r, featuredGroup),
 	}
 
 	tmpl, err := h.getTemplate()
@@ -289,6 +304,79 @@ func (h *PageHandler) HomepageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// curatedGroupID is the group shown on the homepage, the Starry Night
+const curatedGroupID = 86
+
+// curatedFeature returns the curated homepage group with its GPT-3.5 and
+// GPT-5 artworks, or nil when the group does not exist
+func (h *PageHandler) curatedFeature() (*models.ArtworkGroup, []models.Artwork) {
+	group, err := h.db.GetGroup(curatedGroupID)
+	if err != nil {
+		log.Printf("Error fetching Starry Night group: %v", err)
+		return nil, nil
+	}
+
+	allArtworks, err := h.db.ListArtworksByGroup(curatedGroupID)
+	if err != nil {
+		log.Printf("Error fetching artworks for Starry Night: %v", err)
+		return group, nil
+	}
+	allArtworks = models.FilterVisible(allArtworks, models.ScopeListing)
+
+	// Find the specific artworks we want to feature
+	var featuredArtworks []models.Artwork
+	var gpt35Artwork, gpt5Artwork *models.Artwork
+	for i, artwork := range allArtworks {
+		if artwork.Model == "openai/gpt-3.5-turbo" {
+			gpt35Artwork = &allArtworks[i]
+		}
+		if artwork.Model == "openai/gpt-5" {
+			gpt5Artwork = &allArtworks[i]
+		}
+	}
+
+	if gpt35Artwork != nil {
+		featuredArtworks = append(featuredArtworks, *gpt35Artwork)
+	}
+	if gpt5Artwork != nil {
+		featuredArtworks = append(featuredArtworks, *gpt5Artwork)
+	}
+	config.SortFeaturedArtworks(featuredArtworks)
+	return group, featuredArtworks
+}
+
+// latestFeature returns the group whose artwork was featured most recently,
+// with that artwork followed by one other, or nil when nothing is featured
+func (h *PageHandler) latestFeature() (*models.ArtworkGroup, []models.Artwork) {
+	groups, err := h.db.GetFeaturedGroups(1)
+	if err != nil {
+		log.Printf("Error fetching featured groups: %v", err)
+		return nil, nil
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	group := groups[0]
+
+	allArtworks, err := h.db.ListArtworksByGroup(group.ID)
+	if err != nil {
+		log.Printf("Error fetching artworks for featured group %d: %v", group.ID, err)
+		return &group, nil
+	}
+
+	var featured, other []models.Artwork
+	for _, artwork := range models.FilterVisible(allArtworks, models.ScopeListing) {
+		switch {
+		case artwork.SVG == "":
+		case artwork.Featured:
+			featured = append(featured, artwork)
+		case len(other) == 0:
+			other = append(other, artwork)
+		}
+	}
+	return &group, append(featured, other...)
+}
+
 // WorkshopHandler handles requests to the workshop page
 func (h *PageHandler) WorkshopHandler(w http.ResponseWriter, r *http.Request) {
 	// Check if editing is enabled
@@ -318,6 +406,7 @@ func (h *PageHandler) WorkshopHandler(w http.ResponseWriter, r *http.Request) {
 				if err != nil {
 					log.Printf("Error fetching artworks for group %d: %v", editID, err)
 				}
+				config.AnnotateDeprecated(editArtworks)
 				log.Printf("Found group %d with %d artwork(s) for editing: %s", editID, len(editArtworks), group.Title)
 			}
 		}
@@ -328,18 +417,20 @@ func (h *PageHandler) WorkshopHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create template data with edit information
 	hasOriginalArtwork := false
-	if editGroup != nil && editGroup.OriginalArtwork != nil && len(editGroup.OriginalArtwork) > 0 {
+	if editGroup != nil && editGroup.HasOriginalArtwork {
 		hasOriginalArtwork = true
 	}
 
 	currentTemplateData := struct {
 		Models             []models.ModelInfo   `json:"models"`
+		PromptStyles       []models.PromptStyle `json:"prompt_styles"`
 		EditGroup          *models.ArtworkGroup `json:"edit_group,omitempty"`
 		EditArtworks       []models.Artwork     `json:"edit_artworks,omitempty"`
 		HasOriginalArtwork bool                 `json:"has_original_artwork"`
 		CSSHash            string               `json:"css_hash"`
 	}{
 		Models:             templateData.Models,
+		PromptStyles:       templateData.PromptStyles,
 		EditGroup:          editGroup,
 		EditArtworks:       editArtworks,
 		HasOriginalArtwork: hasOriginalArtwork,
@@ -401,33 +492,22 @@ func (h *PageHandler) ArtworkGroupHandler(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Failed to load artworks", http.StatusInternalServerError)
 		return
 	}
+	artworks = models.FilterVisible(artworks, viewScope(r))
 
 	// If model filters are present, filter the artworks accordingly
-	// Supported filters: "openai", "anthropic", "google", "other"
+	// Supported filters are the provider families of config.ProviderOf
 	var filtered []models.Artwork
 	if len(modelFilters) == 0 {
 		filtered = artworks
 	} else {
 		for _, a := range artworks {
-			show := false
-			lowModel := strings.ToLower(a.Model)
+			provider := config.ProviderOf(a.Model)
 			for _, f := range modelFilters {
-				ff := strings.ToLower(f)
-				if ff == "other" {
-					if !(strings.Contains(lowModel, "openai") || strings.Contains(lowModel, "anthropic") || strings.Contains(lowModel, "google")) {
-						show = true
-						break
-					}
-				} else {
-					if strings.Contains(lowModel, ff) {
-						show = true
-						break
-					}
+				if strings.ToLower(f) == provider {
+					filtered = append(filtered, a)
+					break
 				}
 			}
-			if show {
-				filtered = append(filtered, a)
-			}
 		}
 	}
 
@@ -435,14 +515,29 @@ func (h *PageHandler) ArtworkGroupHandler(w http.ResponseWriter, r *http.Request
 	type ArtworkWithHTML struct {
 		models.Artwork
 		SVGContent template.HTML
+		// Revisions is the number of earlier SVGs kept for rollback
+		Revisions int
+	}
+
+	revisions, err := h.db.CountArtworkRevisions(id)
+	if err != nil {
+		// The badge is optional; the page still renders without it
+		log.Printf("Error counting revisions for group %d: %v", id, err)
 	}
 
 	var artList []ArtworkWithHTML
 	for _, a := range filtered {
-		artList = append(artList, ArtworkWithHTML{Artwork: a, SVGContent: template.HTML(a.SVG)})
+		artList = append(artList, ArtworkWithHTML{Artwork: a, SVGContent: template.HTML(a.SVG), Revisions: revisions[a.ID]})
 	}
 
-	hasOriginalArtwork := len(group.OriginalArtwork) > 0
+	hasOriginalArtwork := group.HasOriginalArtwork
+
+	// The reference image is shown first unless ?with_original=0 turns it off
+	showOriginal := hasOriginalArtwork && r.URL.Query().Get("with_original") != "0"
+	originalArtworkURL := ""
+	if showOriginal {
+		originalArtworkURL = fmt.Sprintf("/api/groups/%d/original-artwork", group.ID)
+	}
 
 	data := struct {
 		Title              string
@@ -451,7 +546,10 @@ func (h *PageHandler) ArtworkGroupHandler(w http.ResponseWriter, r *http.Request
 		EditingEnabled     bool
 		ModelFilters       []string
 		HasOriginalArtwork bool
+		ShowOriginal       bool
+		OriginalArtworkURL string
 		CSSHash            string
+		Meta               pageMeta
 	}{
 		Title:              "Artwork Group - Pelican Art Gallery",
 		Group:              group,
@@ -459,7 +557,10 @@ func (h *PageHandler) ArtworkGroupHandler(w http.ResponseWriter, r *http.Request
 		EditingEnabled:     isEditingEnabled(),
 		ModelFilters:       modelFilters,
 		HasOriginalArtwork: hasOriginalArtwork,
+		ShowOriginal:       showOriginal,
+		OriginalArtworkURL: originalArtworkURL,
 		CSSHash:            h.getCSSHash(),
+		Meta:               groupMeta(r, group, artworks),
 	}
 
 	tmpl, err := h.getTemplate()
@@ -476,3 +577,71 @@ func (h *PageHandler) ArtworkGroupHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 }
+
+// AdminErrorsHandler renders the generation error dashboard
+func (h *PageHandler) AdminErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isEditingEnabled() {
+		log.Printf("Admin errors access denied: editing is disabled")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := models.ParseGenerationErrorFilter(query, time.Now())
+	filterError := ""
+	if err != nil {
+		filterError = err.Error()
+		filter, _ = models.ParseGenerationErrorFilter(nil, time.Now())
+	}
+
+	summaries, err := h.db.ListGenerationErrorSummaries(filter)
+	if err != nil {
+		log.Printf("Error listing generation errors: %v", err)
+		http.Error(w, "Failed to load generation errors", http.StatusInternalServerError)
+		return
+	}
+
+	total := 0
+	for _, summary := range summaries {
+		total += summary.Count
+	}
+
+	data := struct {
+		Title       string
+		Errors      []models.GenerationErrorSummary
+		Total       int
+		Filter      models.GenerationErrorFilter
+		Since       string
+		GroupID     string
+		FilterError string
+		CSSHash     string
+	}{
+		Title:       "Generation Errors - Pelican Art Gallery",
+		Errors:      summaries,
+		Total:       total,
+		Filter:      filter,
+		Since:       query.Get("since"),
+		GroupID:     query.Get("group_id"),
+		FilterError: filterError,
+		CSSHash:     h.getCSSHash(),
+	}
+
+	tmpl, err := h.getTemplate()
+	if err != nil {
+		log.Printf("Error getting template: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "admin-errors.html", data); err != nil {
+		log.Printf("Failed to execute admin-errors template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
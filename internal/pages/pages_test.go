@@ -0,0 +1,281 @@
+package pages
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/models"
+)
+
+// testSVG is a small valid SVG for artworks that need one
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4"/></svg>`
+
+// testFuncs are the template functions main registers, with model names
+// shown as they are
+var testFuncs = template.FuncMap{
+	"modelName": func(model string) string { return model },
+	"inc":       func(i int) int { return i + 1 },
+	"contains": func(slice []string, item string) bool {
+		for _, s := range slice {
+			if s == item {
+				return true
+			}
+		}
+		return false
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// newTestDB returns a fresh database that is closed when the test ends
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// siteTemplates parses the templates of the site
+func siteTemplates(t *testing.T) *template.Template {
+	t.Helper()
+	tmpl, err := template.New("").Funcs(testFuncs).ParseGlob("../../templates/*.html")
+	if err != nil {
+		t.Fatalf("parse templates: %v", err)
+	}
+	return tmpl
+}
+
+// dataTemplates defines each of names as the JSON of the data it is
+// executed with, so tests can check what a handler passes to its template
+func dataTemplates(t *testing.T, names ...string) *template.Template {
+	t.Helper()
+	tmpl := template.New("").Funcs(testFuncs)
+	for _, name := range names {
+		if _, err := tmpl.New(name).Parse(`{{json .}}`); err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+	}
+	return tmpl
+}
+
+// serve runs handler on a GET of target and returns the recorded response
+func serve(handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+// decodeData decodes the data a dataTemplates template rendered
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	// html/template escapes the JSON as text; unescape it before decoding
+	if err := json.Unmarshal([]byte(html.UnescapeString(rec.Body.String())), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// seedGroup creates a group with title in category and returns its ID
+func seedGroup(t *testing.T, db *database.DB, title, category string) int {
+	t.Helper()
+	now := time.Now()
+	id, err := db.CreateGroup(models.ArtworkGroup{Title: title, Prompt: "Generate an SVG of " + title, Category: category, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	return id
+}
+
+// seedArtwork creates an artwork of model in a group, with svg unless it is
+// empty, and returns its ID
+func seedArtwork(t *testing.T, db *database.DB, groupID int, model, svg string) int {
+	t.Helper()
+	now := time.Now()
+	id, err := db.CreateArtwork(models.Artwork{GroupID: groupID, Model: model, MaxTokens: 1000, SVG: svg, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("CreateArtwork: %v", err)
+	}
+	return id
+}
+
+func TestArtworkGroupHandlerWithOriginal(t *testing.T) {
+	db := newTestDB(t)
+	withImage := seedGroup(t, db, "With original", "")
+	without := seedGroup(t, db, "Without original", "")
+	seedArtwork(t, db, withImage, "openai/gpt-4o", testSVG)
+	if err := db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: withImage, ContentType: "image/png", Data: []byte("png"), UploadedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+	originalURL := "/api/groups/" + strconv.Itoa(withImage) + "/original-artwork"
+
+	h := NewPageHandler(db, dataTemplates(t, "artwork-group.html"), models.TemplateData{}, nil)
+	tests := []struct {
+		target   string
+		wantShow bool
+		wantURL  string
+	}{
+		{"/group/" + strconv.Itoa(withImage) + "?with_original=1", true, originalURL},
+		{"/group/" + strconv.Itoa(withImage), true, originalURL},
+		{"/group/" + strconv.Itoa(withImage) + "?with_original=0", false, ""},
+		{"/group/" + strconv.Itoa(without) + "?with_original=1", false, ""},
+	}
+	for _, tt := range tests {
+		var data struct {
+			HasOriginalArtwork bool
+			ShowOriginal       bool
+			OriginalArtworkURL string
+		}
+		decodeData(t, serve(h.ArtworkGroupHandler, tt.target), &data)
+		if data.ShowOriginal != tt.wantShow || data.OriginalArtworkURL != tt.wantURL {
+			t.Errorf("%s: ShowOriginal = %v, OriginalArtworkURL = %q, want %v, %q", tt.target, data.ShowOriginal, data.OriginalArtworkURL, tt.wantShow, tt.wantURL)
+		}
+	}
+
+	// The page renders the reference image from that URL
+	h = NewPageHandler(db, siteTemplates(t), models.TemplateData{}, nil)
+	rec := serve(h.ArtworkGroupHandler, "/group/"+strconv.Itoa(withImage)+"?with_original=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `src="`+originalURL+`"`) {
+		t.Errorf("page does not show the original from %s", originalURL)
+	}
+}
+
+func TestGalleryHandlerSort(t *testing.T) {
+	db := newTestDB(t)
+	seedGroup(t, db, "Heron", "")
+	seedGroup(t, db, "Albatross", "")
+	seedGroup(t, db, "Pelican", "")
+
+	h := NewPageHandler(db, dataTemplates(t, "gallery.html"), models.TemplateData{}, nil)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?sort=title", []string{"Albatross", "Heron", "Pelican"}},
+		{"?sort=newest", []string{"Pelican", "Albatross", "Heron"}},
+		{"?sort=oldest", []string{"Heron", "Albatross", "Pelican"}},
+	}
+	for _, tt := range tests {
+		var data struct {
+			Groups []models.ArtworkGroup `json:"groups"`
+			Sort   models.GroupSort      `json:"sort"`
+		}
+		decodeData(t, serve(h.GalleryHandler, "/gallery/"+tt.query), &data)
+		var titles []string
+		for _, g := range data.Groups {
+			titles = append(titles, g.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: groups = %v, want %v", tt.query, titles, tt.want)
+		}
+		if "?sort="+string(data.Sort) != tt.query {
+			t.Errorf("%s: sort = %q", tt.query, data.Sort)
+		}
+	}
+
+	if rec := serve(h.GalleryHandler, "/gallery/?sort=random"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown sort = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGalleryHandlerRedirectsOldCategoryURLs(t *testing.T) {
+	db := newTestDB(t)
+	seedGroup(t, db, "Cube", "Abstract & Geometric")
+	seedGroup(t, db, "Pelican", "Birds")
+	if _, err := db.RenameCategory("Birds", "Sea Birds"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+
+	h := NewPageHandler(db, dataTemplates(t, "gallery.html"), models.TemplateData{}, nil)
+	tests := []struct {
+		target   string
+		want     int
+		location string
+	}{
+		{"/gallery/?category=sea-birds", http.StatusOK, ""},
+		{"/gallery/?category=abstract-geometric", http.StatusOK, ""},
+		// A slug from before the rename
+		{"/gallery/?category=birds", http.StatusMovedPermanently, "/gallery/category/sea-birds"},
+		{"/gallery/?category=birds&sort=title", http.StatusMovedPermanently, "/gallery/category/sea-birds?sort=title"},
+		// The name, as URLs spelled it before slugs
+		{"/gallery/?category=" + url.QueryEscape("Abstract & Geometric"), http.StatusMovedPermanently, "/gallery/category/abstract-geometric"},
+		{"/gallery/?category=missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(h.GalleryHandler, tt.target)
+		if rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d", tt.target, rec.Code, tt.want)
+			continue
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Errorf("%s redirects to %q, want %q", tt.target, got, tt.location)
+		}
+	}
+}
+
+func TestHomepageHandlerFallsBackToFeaturedGroups(t *testing.T) {
+	db := newTestDB(t)
+	h := NewPageHandler(db, dataTemplates(t, "homepage.html"), models.TemplateData{}, nil)
+
+	type homepage struct {
+		FeaturedGroup    *models.ArtworkGroup `json:"featured_group"`
+		FeaturedArtworks []models.Artwork     `json:"featured_artworks"`
+	}
+	var data homepage
+	decodeData(t, serve(h.HomepageHandler, "/"), &data)
+	if data.FeaturedGroup != nil || len(data.FeaturedArtworks) != 0 {
+		t.Errorf("homepage with nothing featured = %+v", data)
+	}
+
+	older := seedGroup(t, db, "Heron", "")
+	if err := db.SetFeaturedArtwork(seedArtwork(t, db, older, "openai/gpt-4o", testSVG), false); err != nil {
+		t.Fatalf("SetFeaturedArtwork: %v", err)
+	}
+	groupID := seedGroup(t, db, "Pelican", "")
+	other := seedArtwork(t, db, groupID, "anthropic/claude-sonnet-4", testSVG)
+	seedArtwork(t, db, groupID, "google/gemini-2.5-pro", "")
+	seedArtwork(t, db, groupID, "x-ai/grok-4", testSVG)
+	featured := seedArtwork(t, db, groupID, "openai/gpt-4o", testSVG)
+	// Featured globally, which clears the featured artwork of Heron
+	if err := db.SetFeaturedArtwork(featured, true); err != nil {
+		t.Fatalf("SetFeaturedArtwork: %v", err)
+	}
+
+	data = homepage{}
+	decodeData(t, serve(h.HomepageHandler, "/"), &data)
+	if data.FeaturedGroup == nil || data.FeaturedGroup.ID != groupID {
+		t.Fatalf("featured group = %+v, want Pelican", data.FeaturedGroup)
+	}
+	var ids []int
+	for _, a := range data.FeaturedArtworks {
+		ids = append(ids, a.ID)
+	}
+	// The featured artwork, then the first other one with an SVG
+	if want := []int{featured, other}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("featured artworks = %v, want %v", ids, want)
+	}
+
+	if rec := serve(h.HomepageHandler, "/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /missing = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
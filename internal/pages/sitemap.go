@@ -0,0 +1,166 @@
+package pages
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pelican-gallery/internal/config"
+)
+
+// sitemapNamespace is the XML namespace of sitemaps and sitemap indexes
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// maxSitemapURLs is the most URLs the sitemaps protocol allows in one file.
+// Larger sites are split into pages, /sitemap.xml?page=N, listed by a
+// sitemap index at /sitemap.xml.
+const maxSitemapURLs = 50000
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapLastMod formats t as a sitemap date, or "" when it is unknown
+func sitemapLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// SitemapHandler serves GET /sitemap.xml: the homepage, every gallery
+// category and every active group page, each with the date it last changed.
+// The ETag follows the most recent change, so crawlers revalidate cheaply.
+func (h *PageHandler) SitemapHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	categories, err := h.db.GetDistinctCategories()
+	if err != nil {
+		log.Printf("Error fetching categories for the sitemap: %v", err)
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+	groups, err := h.db.ListGroupIDsWithUpdatedAt()
+	if err != nil {
+		log.Printf("Error listing groups for the sitemap: %v", err)
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	// A category changes whenever one of its groups does
+	var siteUpdated time.Time
+	categoryUpdated := make(map[string]time.Time)
+	for _, group := range groups {
+		if group.UpdatedAt.After(categoryUpdated[group.Category]) {
+			categoryUpdated[group.Category] = group.UpdatedAt
+		}
+		if group.UpdatedAt.After(siteUpdated) {
+			siteUpdated = group.UpdatedAt
+		}
+	}
+
+	// The counts catch groups and categories that disappear without
+	// anything else changing
+	etag := fmt.Sprintf(`"%x-%d-%d"`, siteUpdated.UnixNano(), len(groups), len(categories))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	base := config.RequestBaseURL(r)
+	urls := make([]sitemapURL, 0, 1+len(categories)+len(groups))
+	urls = append(urls, sitemapURL{Loc: base + "/", LastMod: sitemapLastMod(siteUpdated)})
+	for _, category := range categories {
+		urls = append(urls, sitemapURL{
+			Loc:     base + "/gallery/category/" + url.PathEscape(category.Slug),
+			LastMod: sitemapLastMod(categoryUpdated[category.Name]),
+		})
+	}
+	for _, group := range groups {
+		urls = append(urls, sitemapURL{
+			Loc:     fmt.Sprintf("%s/group/%d", base, group.ID),
+			LastMod: sitemapLastMod(group.UpdatedAt),
+		})
+	}
+
+	pages := (len(urls) + maxSitemapURLs - 1) / maxSitemapURLs
+	pageParam := r.URL.Query().Get("page")
+
+	// A site that fits in one file gets a plain sitemap; a larger one gets
+	// an index of its pages
+	if pageParam == "" && pages > 1 {
+		index := make([]sitemapURL, pages)
+		for i := range index {
+			index[i] = sitemapURL{
+				Loc:     fmt.Sprintf("%s/sitemap.xml?page=%d", base, i+1),
+				LastMod: sitemapLastMod(siteUpdated),
+			}
+		}
+		writeSitemap(w, "sitemapindex", "sitemap", index)
+		return
+	}
+
+	page := 1
+	if pageParam != "" {
+		page, err = strconv.Atoi(pageParam)
+		if err != nil || page < 1 || page > pages {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	end := min(page*maxSitemapURLs, len(urls))
+	writeSitemap(w, "urlset", "url", urls[(page-1)*maxSitemapURLs:end])
+}
+
+// writeSitemap streams entries as the elements of a sitemap document with
+// the given root, a urlset or a sitemap index
+func writeSitemap(w http.ResponseWriter, root, element string, entries []sitemapURL) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	start := xml.StartElement{
+		Name: xml.Name{Local: root},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace}},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		log.Printf("Error writing sitemap: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		if err := enc.EncodeElement(entry, xml.StartElement{Name: xml.Name{Local: element}}); err != nil {
+			log.Printf("Error writing sitemap: %v", err)
+			return
+		}
+	}
+	if err := enc.EncodeToken(start.End()); err != nil {
+		log.Printf("Error writing sitemap: %v", err)
+		return
+	}
+	if err := enc.Flush(); err != nil {
+		log.Printf("Error writing sitemap: %v", err)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
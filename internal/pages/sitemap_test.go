@@ -0,0 +1,83 @@
+package pages
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/models"
+)
+
+// sitemapDocument is a decoded urlset or sitemap index
+type sitemapDocument struct {
+	XMLName xml.Name
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// readSitemap serves the sitemap at target and decodes it
+func readSitemap(t *testing.T, h *PageHandler, target string) sitemapDocument {
+	t.Helper()
+	rec := serve(h.SitemapHandler, target)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, body %s", target, rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("Content-Type = %q, want application/xml", ct)
+	}
+	var doc sitemapDocument
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("sitemap is not well-formed XML: %v", err)
+	}
+	if doc.XMLName.Space != sitemapNamespace || doc.XMLName.Local != "urlset" {
+		t.Errorf("root element = %v, want a urlset", doc.XMLName)
+	}
+	return doc
+}
+
+func TestSitemapHandlerListsEveryPageOnce(t *testing.T) {
+	t.Setenv("BASE_URL", "https://pelican.example")
+	db := newTestDB(t)
+	seedGroup(t, db, "Pelican", "Birds")
+	seedGroup(t, db, "Heron", "Birds")
+	seedGroup(t, db, "Bicycle", "Vehicles")
+	seedGroup(t, db, "Uncategorized", "")
+	categories, err := db.GetDistinctCategories()
+	if err != nil {
+		t.Fatalf("GetDistinctCategories: %v", err)
+	}
+	groups, err := db.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+
+	h := NewPageHandler(db, dataTemplates(t), models.TemplateData{}, nil)
+	doc := readSitemap(t, h, "/sitemap.xml")
+
+	seen := make(map[string]int)
+	for _, u := range doc.URLs {
+		parsed, err := url.Parse(u.Loc)
+		if err != nil || parsed.Scheme != "https" || parsed.Host != "pelican.example" {
+			t.Errorf("loc %q is not an absolute URL on the base URL", u.Loc)
+		}
+		seen[u.Loc]++
+	}
+
+	want := []string{"https://pelican.example/"}
+	for _, c := range categories {
+		want = append(want, "https://pelican.example/gallery/category/"+c.Slug)
+	}
+	for _, g := range groups {
+		want = append(want, "https://pelican.example/group/"+strconv.Itoa(g.ID))
+	}
+	for _, loc := range want {
+		if seen[loc] != 1 {
+			t.Errorf("%s is listed %d times, want once", loc, seen[loc])
+		}
+	}
+	if len(doc.URLs) != len(want) {
+		t.Errorf("sitemap lists %d URLs, want %d", len(doc.URLs), len(want))
+	}
+}
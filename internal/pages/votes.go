@@ -0,0 +1,103 @@
+package pages
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"pelican-gallery/internal/models"
+)
+
+// VoteHandler serves GET /vote: two renditions of a random group by
+// different models, shown without their model names until a vote is cast
+func (h *PageHandler) VoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group, pair, err := h.db.GetRandomArtworkPair()
+	if err != nil {
+		log.Printf("Error picking artworks to vote on: %v", err)
+		http.Error(w, "Failed to fetch artworks", http.StatusInternalServerError)
+		return
+	}
+
+	type Contender struct {
+		ID         int           `json:"id"`
+		SVGContent template.HTML `json:"svg_content"`
+	}
+
+	var contenders []Contender
+	if group != nil {
+		for _, artwork := range pair {
+			contenders = append(contenders, Contender{ID: artwork.ID, SVGContent: template.HTML(artwork.SVG)})
+		}
+	}
+
+	data := struct {
+		Title      string               `json:"title"`
+		Group      *models.ArtworkGroup `json:"group,omitempty"`
+		Contenders []Contender          `json:"contenders"`
+		VotingOpen bool                 `json:"voting_open"`
+		CSSHash    string               `json:"css_hash"`
+	}{
+		Title:      "Vote - Pelican Art Gallery",
+		Group:      group,
+		Contenders: contenders,
+		VotingOpen: !h.db.IsReadOnly(),
+		CSSHash:    h.getCSSHash(),
+	}
+
+	// Every visit shows a new pair
+	w.Header().Set("Cache-Control", "no-store")
+	h.render(w, "vote.html", data)
+}
+
+// LeaderboardHandler serves GET /leaderboard, the models ranked by the ELO
+// rating earned in votes
+func (h *PageHandler) LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	votes, err := h.db.ListVotes()
+	if err != nil {
+		log.Printf("Error listing votes: %v", err)
+		http.Error(w, "Failed to load votes", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Title   string               `json:"title"`
+		Ratings []models.ModelRating `json:"ratings"`
+		Votes   int                  `json:"votes"`
+		CSSHash string               `json:"css_hash"`
+	}{
+		Title:   "Leaderboard - Pelican Art Gallery",
+		Ratings: models.EloRatings(votes),
+		Votes:   len(votes),
+		CSSHash: h.getCSSHash(),
+	}
+
+	h.render(w, "leaderboard.html", data)
+}
+
+// render executes the named page template with data
+func (h *PageHandler) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html")
+
+	tmpl, err := h.getTemplate()
+	if err != nil {
+		log.Printf("Error getting template: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing %s: %v", name, err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,185 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Size of an OpenGraph preview card, as recommended by the major networks
+const (
+	CardWidth  = 1200
+	CardHeight = 630
+)
+
+// MaxCardArtworks bounds the artworks shown side by side on a card
+const MaxCardArtworks = 4
+
+// Card layout, in pixels
+const (
+	cardPadding     = 48
+	cardGap         = 24
+	cardTileInset   = 16
+	cardTitleSize   = 56
+	cardTitleLine   = 66
+	cardTitleLines  = 2
+	cardCaptionSize = 28
+	cardCaptionLine = 40
+)
+
+// Card colors follow the site's black and white palette
+var (
+	cardBackground = color.White
+	cardTile       = color.RGBA{0xf8, 0xf9, 0xfa, 0xff}
+	cardBorder     = color.RGBA{0xe5, 0xe7, 0xeb, 0xff}
+	cardTitleColor = color.Black
+	cardCaption    = color.RGBA{0x6b, 0x72, 0x80, 0xff}
+)
+
+var (
+	cardFontsOnce sync.Once
+	cardTitleFace font.Face
+	cardCaptFace  font.Face
+	cardFontsErr  error
+)
+
+// Card composes a PNG preview card: the artworks side by side on tiles, with
+// the title and caption underneath. Artworks that cannot be rasterized are
+// left out; a card without artworks shows only the text.
+func Card(title, caption string, svgs []string) ([]byte, error) {
+	cardFontsOnce.Do(loadCardFonts)
+	if cardFontsErr != nil {
+		return nil, cardFontsErr
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, CardWidth, CardHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(cardBackground), image.Point{}, draw.Src)
+
+	textWidth := CardWidth - 2*cardPadding
+	lines := wrapText(cardTitleFace, title, textWidth, cardTitleLines)
+	textHeight := len(lines)*cardTitleLine + cardCaptionLine
+
+	var artworks []*image.RGBA
+	if len(svgs) > MaxCardArtworks {
+		svgs = svgs[:MaxCardArtworks]
+	}
+	tileArea := CardHeight - 2*cardPadding - textHeight - cardGap
+	tileSize := tileArea
+	if n := len(svgs); n > 0 {
+		tileSize = min(tileArea, (textWidth-cardGap*(n-1))/n)
+	}
+	for _, svg := range svgs {
+		artwork, err := Image(svg, tileSize-2*cardTileInset, tileSize-2*cardTileInset)
+		if err != nil {
+			continue
+		}
+		artworks = append(artworks, artwork)
+	}
+
+	if n := len(artworks); n > 0 {
+		rowWidth := n*tileSize + (n-1)*cardGap
+		x := (CardWidth - rowWidth) / 2
+		y := cardPadding + (tileArea-tileSize)/2
+		for _, artwork := range artworks {
+			tile := image.Rect(x, y, x+tileSize, y+tileSize)
+			draw.Draw(img, tile, image.NewUniform(cardBorder), image.Point{}, draw.Src)
+			draw.Draw(img, tile.Inset(1), image.NewUniform(cardTile), image.Point{}, draw.Src)
+
+			size := artwork.Bounds().Size()
+			at := image.Pt(x+(tileSize-size.X)/2, y+(tileSize-size.Y)/2)
+			draw.Draw(img, image.Rectangle{Min: at, Max: at.Add(size)}, artwork, image.Point{}, draw.Over)
+			x += tileSize + cardGap
+		}
+	}
+
+	// Text is anchored to the bottom so short titles line up with the padding
+	y := CardHeight - cardPadding - textHeight
+	for _, line := range lines {
+		y += cardTitleLine
+		drawText(img, cardTitleFace, cardTitleColor, line, cardPadding, y-(cardTitleLine-cardTitleSize))
+	}
+	drawText(img, cardCaptFace, cardCaption, caption, cardPadding, y+cardCaptionLine-(cardCaptionLine-cardCaptionSize)/2)
+
+	return encodePNG(img)
+}
+
+// loadCardFonts parses the embedded Go fonts used on cards
+func loadCardFonts() {
+	load := func(ttf []byte, size float64) font.Face {
+		parsed, err := opentype.Parse(ttf)
+		if err != nil {
+			cardFontsErr = fmt.Errorf("failed to parse card font: %w", err)
+			return nil
+		}
+		face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			cardFontsErr = fmt.Errorf("failed to load card font: %w", err)
+			return nil
+		}
+		return face
+	}
+	cardTitleFace = load(gobold.TTF, cardTitleSize)
+	cardCaptFace = load(goregular.TTF, cardCaptionSize)
+}
+
+// wrapText breaks text into at most maxLines lines no wider than width,
+// ending the last line with an ellipsis when text does not fit
+func wrapText(face font.Face, text string, width, maxLines int) []string {
+	fits := func(s string) bool { return font.MeasureString(face, s).Ceil() <= width }
+
+	var lines []string
+	line := ""
+	words := strings.Fields(text)
+	for i, word := range words {
+		candidate := strings.TrimSpace(line + " " + word)
+		if fits(candidate) || line == "" {
+			line = candidate
+			continue
+		}
+		if len(lines) == maxLines-1 {
+			return append(lines, ellipsize(face, strings.Join(append([]string{line}, words[i:]...), " "), width))
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, ellipsize(face, line, width))
+	}
+	return lines
+}
+
+// ellipsize shortens s with a trailing ellipsis until it fits width
+func ellipsize(face font.Face, s string, width int) string {
+	if font.MeasureString(face, s).Ceil() <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		shortened := strings.TrimSpace(string(runes)) + "…"
+		if font.MeasureString(face, shortened).Ceil() <= width {
+			return shortened
+		}
+	}
+	return "…"
+}
+
+// drawText draws s with its baseline at y
+func drawText(img draw.Image, face font.Face, c color.Color, s string, x, y int) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(s)
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/bits"
+)
+
+// A hash is taken of a hashWidth x hashHeight grayscale version of the
+// drawing, each pixel averaged over hashSample x hashSample rendered pixels
+const (
+	hashWidth  = 9
+	hashHeight = 8
+	hashSample = 8
+)
+
+// HashBits is the number of bits of a perceptual hash
+const HashBits = (hashWidth - 1) * hashHeight
+
+// Hash returns a perceptual difference hash of svg. The drawing is rendered
+// on white and reduced to a small grayscale image whose aspect ratio is
+// ignored; each bit tells whether a pixel is brighter than its right
+// neighbour. Drawings that look alike have hashes that differ in few bits.
+func Hash(svg string) (uint64, error) {
+	icon, err := parse(svg)
+	if err != nil {
+		return 0, err
+	}
+	drawing, err := rasterize(icon, hashWidth*hashSample, hashHeight*hashSample)
+	if err != nil {
+		return 0, err
+	}
+
+	// Transparent areas count as the white the gallery shows them on
+	img := image.NewGray(drawing.Bounds())
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, img.Bounds(), drawing, image.Point{}, draw.Over)
+
+	var gray [hashHeight][hashWidth]int
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth; x++ {
+			sum := 0
+			for dy := 0; dy < hashSample; dy++ {
+				for dx := 0; dx < hashSample; dx++ {
+					sum += int(img.GrayAt(x*hashSample+dx, y*hashSample+dy).Y)
+				}
+			}
+			gray[y][x] = sum
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// Similarity returns how alike two perceptual hashes are, from 0 when every
+// bit differs to 1 when they are equal
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/HashBits
+}
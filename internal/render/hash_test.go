@@ -0,0 +1,74 @@
+package render
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	const (
+		circle      = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><circle cx="50" cy="50" r="40"/></svg>`
+		bigCircle   = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 200"><circle cx="100" cy="100" r="82" fill="#111"/></svg>`
+		leftHalf    = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect width="50" height="100"/></svg>`
+		rightHalf   = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect x="50" width="50" height="100"/></svg>`
+		transparent = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect width="100" height="100" fill="none"/></svg>`
+		white       = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><rect width="100" height="100" fill="white"/></svg>`
+	)
+	hash := func(svg string) uint64 {
+		t.Helper()
+		h, err := Hash(svg)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		return h
+	}
+
+	if hash(circle) != hash(circle) {
+		t.Error("an SVG hashes differently twice")
+	}
+	if got := Similarity(hash(transparent), hash(white)); got != 1 {
+		t.Errorf("transparent and white drawings have similarity %v, want 1", got)
+	}
+
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max float64
+	}{
+		{"same drawing at another scale", circle, bigCircle, 0.9, 1},
+		{"mirrored halves", leftHalf, rightHalf, 0, 0.9},
+		{"circle and half", circle, leftHalf, 0, 0.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(hash(tt.a), hash(tt.b))
+			if got < tt.min || got > tt.max {
+				t.Errorf("similarity = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+
+	if _, err := Hash(`<svg xmlns="http://www.w3.org/2000/svg"><rect width="1" height="1"/></svg>`); !errors.Is(err, ErrNoViewport) {
+		t.Errorf("Hash without a viewport = %v, want ErrNoViewport", err)
+	}
+	if _, err := Hash("not an svg"); err == nil {
+		t.Error("Hash of text succeeded")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want float64
+	}{
+		{0, 0, 1},
+		{0, ^uint64(0), 0},
+		{0xff, 0, 1 - 8.0/HashBits},
+		{0x0f0f, 0xf0f0, 1 - 16.0/HashBits},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("Similarity(%#x, %#x) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package render rasterizes stored SVG artwork into bitmap images.
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// ThumbnailWidth is the width of the PNG thumbnails stored with artworks
+const ThumbnailWidth = 320
+
+// ErrNoViewport is returned when an SVG has neither a viewBox nor a size to
+// scale from
+var ErrNoViewport = errors.New("SVG has no viewBox or width and height")
+
+// PNG rasterizes svg at the given pixel width and encodes it as PNG. The
+// height follows the aspect ratio of the SVG's viewBox.
+func PNG(svg string, width int) ([]byte, error) {
+	if width <= 0 {
+		return nil, fmt.Errorf("invalid width %d", width)
+	}
+
+	icon, err := parse(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	box := icon.ViewBox
+	height := int(math.Round(float64(width) * box.H / box.W))
+	img, err := rasterize(icon, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePNG(img)
+}
+
+// Version returns a short fingerprint of svg, used to tell renders of
+// different SVGs of the same artwork apart
+func Version(svg string) string {
+	sum := sha256.Sum256([]byte(svg))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Image rasterizes svg as large as fits within maxWidth x maxHeight while
+// keeping the aspect ratio of its viewBox
+func Image(svg string, maxWidth, maxHeight int) (*image.RGBA, error) {
+	if maxWidth <= 0 || maxHeight <= 0 {
+		return nil, fmt.Errorf("invalid size %dx%d", maxWidth, maxHeight)
+	}
+
+	icon, err := parse(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	box := icon.ViewBox
+	scale := math.Min(float64(maxWidth)/box.W, float64(maxHeight)/box.H)
+	return rasterize(icon, int(math.Round(box.W*scale)), int(math.Round(box.H*scale)))
+}
+
+// parse reads svg, skipping elements the rasterizer does not support
+func parse(svg string) (*oksvg.SvgIcon, error) {
+	icon, err := oksvg.ReadIconStream(strings.NewReader(svg), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SVG: %w", err)
+	}
+	if icon.ViewBox.W <= 0 || icon.ViewBox.H <= 0 {
+		return nil, ErrNoViewport
+	}
+	return icon, nil
+}
+
+// rasterize renders icon scaled to width x height on a transparent image
+func rasterize(icon *oksvg.SvgIcon, width, height int) (img *image.RGBA, err error) {
+	width, height = max(width, 1), max(height, 1)
+
+	// The rasterizer panics on some malformed path data instead of reporting it
+	defer func() {
+		if p := recover(); p != nil {
+			img, err = nil, fmt.Errorf("failed to rasterize SVG: %v", p)
+		}
+	}()
+
+	img = image.NewRGBA(image.Rect(0, 0, width, height))
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+	return img, nil
+}
+
+// encodePNG encodes img as PNG
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	tests := []struct {
+		name       string
+		svg        string
+		width      int
+		wantHeight int
+	}{
+		{"square", `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4" fill="red"/></svg>`, 64, 64},
+		{"wide", `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 400 200"><rect width="400" height="200" fill="blue"/></svg>`, 300, 150},
+		{"tall", `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 300"><rect width="100" height="300"/></svg>`, 100, 300},
+		{"size without viewBox", `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="30"><rect width="40" height="30"/></svg>`, 80, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := PNG(tt.svg, tt.width)
+			if err != nil {
+				t.Fatalf("PNG: %v", err)
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("output is not a PNG: %v", err)
+			}
+			if config.Width != tt.width || config.Height != tt.wantHeight {
+				t.Errorf("PNG is %dx%d, want %dx%d", config.Width, config.Height, tt.width, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestPNGErrors(t *testing.T) {
+	const svg = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"/>`
+	if _, err := PNG(svg, 0); err == nil {
+		t.Error("PNG with width 0 succeeded")
+	}
+	if _, err := PNG(`<svg xmlns="http://www.w3.org/2000/svg"><rect width="1" height="1"/></svg>`, 64); !errors.Is(err, ErrNoViewport) {
+		t.Errorf("PNG without a viewport = %v, want ErrNoViewport", err)
+	}
+	if _, err := PNG("not an svg", 64); err == nil {
+		t.Error("PNG of text succeeded")
+	}
+}
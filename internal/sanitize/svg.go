@@ -0,0 +1,34 @@
+// Package sanitize cleans model-generated SVG markup before it is stored and
+// rendered inline in the gallery.
+package sanitize
+
+import "regexp"
+
+var (
+	// Elements that can execute script or embed foreign documents, with content
+	dangerousElements = regexp.MustCompile(`(?is)<\s*(script|foreignobject|iframe|object|embed)\b[^>]*>.*?<\s*/\s*(script|foreignobject|iframe|object|embed)\s*>`)
+	// Self-closing or unterminated opening tags of the same elements
+	dangerousTags = regexp.MustCompile(`(?is)<\s*/?\s*(script|foreignobject|iframe|object|embed)\b[^>]*>`)
+	// Inline event handlers such as onload="..."
+	eventHandlers = regexp.MustCompile(`(?i)\s+on[a-z]+\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	// Links pointing at script or HTML payloads
+	scriptLinks = regexp.MustCompile(`(?i)\s+(xlink:href|href)\s*=\s*("\s*(javascript|vbscript|data:text/html)[^"]*"|'\s*(javascript|vbscript|data:text/html)[^']*')`)
+)
+
+// SVG removes scripting from SVG markup: script, foreignObject and embedding
+// elements, inline event handlers, and javascript: links. Clean input is
+// returned unchanged.
+func SVG(markup string) string {
+	// Repeat until stable so nested constructs like "<scr<script></script>ipt>"
+	// cannot reassemble into a live element after one pass
+	for {
+		cleaned := dangerousElements.ReplaceAllString(markup, "")
+		cleaned = dangerousTags.ReplaceAllString(cleaned, "")
+		cleaned = eventHandlers.ReplaceAllString(cleaned, "")
+		cleaned = scriptLinks.ReplaceAllString(cleaned, "")
+		if cleaned == markup {
+			return cleaned
+		}
+		markup = cleaned
+	}
+}
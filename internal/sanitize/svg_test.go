@@ -0,0 +1,52 @@
+package sanitize
+
+import "testing"
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name, markup, want string
+	}{
+		{
+			name:   "clean markup is unchanged",
+			markup: `<svg viewBox="0 0 10 10"><circle cx="5" cy="5" r="4" fill="red"/><a href="#top"><text>Hi</text></a></svg>`,
+			want:   `<svg viewBox="0 0 10 10"><circle cx="5" cy="5" r="4" fill="red"/><a href="#top"><text>Hi</text></a></svg>`,
+		},
+		{
+			name:   "script element",
+			markup: `<svg><script type="text/javascript">alert(1)</script><rect/></svg>`,
+			want:   `<svg><rect/></svg>`,
+		},
+		{
+			name:   "foreignObject and iframe",
+			markup: `<svg><foreignObject><div>x</div></foreignObject><iframe src="https://example.com"></iframe></svg>`,
+			want:   `<svg></svg>`,
+		},
+		{
+			name:   "unterminated script tag",
+			markup: `<svg><script src="https://example.com/x.js"/><rect/></svg>`,
+			want:   `<svg><rect/></svg>`,
+		},
+		{
+			name:   "nested script reassembly",
+			markup: `<svg><scr<script></script>ipt>alert(1)</script></svg>`,
+			want:   `<svg>alert(1)</svg>`,
+		},
+		{
+			name:   "event handlers",
+			markup: `<svg onload="alert(1)"><rect ONCLICK='alert(2)' onmouseover=alert(3) width="5"/></svg>`,
+			want:   `<svg><rect width="5"/></svg>`,
+		},
+		{
+			name:   "script links",
+			markup: `<svg><a href="javascript:alert(1)"><text>x</text></a><use xlink:href=" data:text/html,<b>"/></svg>`,
+			want:   `<svg><a><text>x</text></a><use/></svg>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SVG(tt.markup); got != tt.want {
+				t.Errorf("SVG(%q) = %q, want %q", tt.markup, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,28 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
-	"net"
+	"log/slog"
 	"net/http"
-	"net/url"
+	"net/netip"
 	"os"
-	"strings"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
-	"pelican-gallery/internal/api"
 	"pelican-gallery/internal/config"
 	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/logging"
+	"pelican-gallery/internal/metrics"
 	"pelican-gallery/internal/models"
-	"pelican-gallery/internal/pages"
-
-	"github.com/joho/godotenv"
+	"pelican-gallery/internal/openrouter"
 )
 
 // RateLimiter implements a simple in-memory rate limiter
@@ -30,6 +31,12 @@ type RateLimiter struct {
 	requests map[string][]time.Time
 	window   time.Duration
 	limit    int
+	warnAt   float64
+
+	// exempt clients bypass the limiter; their address is taken from
+	// X-Forwarded-For only when the request comes through a trusted proxy
+	exempt         []netip.Prefix
+	trustedProxies []netip.Prefix
 }
 
 func NewRateLimiter(window time.Duration, limit int) *RateLimiter {
@@ -65,35 +72,119 @@ func (rl *RateLimiter) Allow(key string) bool {
 	return false
 }
 
+// WarnAt sets the fraction of the allowance after which responses carry a
+// rate limit warning; 0 disables the warning
+func (rl *RateLimiter) WarnAt(fraction float64) {
+	rl.warnAt = fraction
+}
+
+// Usage returns how many requests key made in the current window, without
+// recording a request or dropping expired ones
+func (rl *RateLimiter) Usage(key string) int {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	windowStart := time.Now().Add(-rl.window)
+	used := 0
+	for _, req := range rl.requests[key] {
+		if req.After(windowStart) {
+			used++
+		}
+	}
+	return used
+}
+
+// Exempt lets the clients in exempt bypass the limiter. X-Forwarded-For is
+// only consulted for requests whose peer is in trustedProxies, so the
+// exemption cannot be claimed with a spoofed header.
+func (rl *RateLimiter) Exempt(exempt, trustedProxies []netip.Prefix) {
+	rl.exempt = exempt
+	rl.trustedProxies = trustedProxies
+}
+
+// isExempt reports whether the request comes from an exempt client
+func (rl *RateLimiter) isExempt(r *http.Request) bool {
+	if len(rl.exempt) == 0 {
+		return false
+	}
+	addr, ok := config.TrustedClientIP(r, rl.trustedProxies)
+	return ok && config.PrefixesContain(rl.exempt, addr)
+}
+
 func (rl *RateLimiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		clientIP := getClientIP(r)
+		if rl.isExempt(r) {
+			next(w, r)
+			return
+		}
+
+		clientIP := config.ClientIP(r)
 		if !rl.Allow(clientIP) {
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
+
+		// Warn clients close to the limit so they can slow down before a 429
+		if used := rl.Usage(clientIP); rl.warnAt > 0 && float64(used) > rl.warnAt*float64(rl.limit) {
+			w.Header().Set(config.RateLimitWarningHeader,
+				fmt.Sprintf("%d of %d requests used in the last %s; %d remaining", used, rl.limit, rl.window, max(rl.limit-used, 0)))
+		}
 		next(w, r)
 	}
 }
 
-func getClientIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in case of multiple
-		if idx := strings.Index(xff, ","); idx > 0 {
-			return strings.TrimSpace(xff[:idx])
+// requireAdminKey rejects write requests (anything other than GET, HEAD and
+// OPTIONS) that lack a matching "Authorization: Bearer" header when
+// ADMIN_API_KEY is set. Reads always pass through.
+func requireAdminKey(next http.HandlerFunc) http.HandlerFunc {
+	guarded := requireAdminKeyForAll(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next(w, withAdminFlag(r))
+			return
 		}
-		return strings.TrimSpace(xff)
+		guarded(w, r)
 	}
+}
 
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
-	}
+// requireAdminKeyForAll is requireAdminKey for endpoints whose reads expose
+// deployment internals, so reads need the key as well
+func requireAdminKeyForAll(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if config.AdminAPIKey() == "" {
+			next(w, r)
+			return
+		}
 
-	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
-		return host
+		if !config.HasAdminKey(r) {
+			log.Printf("Rejected unauthenticated %s %s from %s", r.Method, r.URL.Path, config.ClientIP(r))
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "Missing or invalid admin API key"})
+			return
+		}
+
+		next(w, withAdminFlag(r))
 	}
+}
+
+// adminContextMiddleware marks requests that present a valid admin API key,
+// so handlers outside the key middleware, such as group pages, can show
+// private artworks to the admin only
+func adminContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, withAdminFlag(r))
+	})
+}
 
-	return r.RemoteAddr
+// withAdminFlag returns r with its context marked by config.WithAdmin when
+// it carries a valid admin API key, and r unchanged otherwise
+func withAdminFlag(r *http.Request) *http.Request {
+	if !config.HasAdminKey(r) {
+		return r
+	}
+	return r.WithContext(config.WithAdmin(r.Context()))
 }
 
 //go:embed static/*
@@ -124,6 +215,7 @@ func parseTemplates() (*template.Template, error) {
 	// Create template with custom functions
 	funcMap := template.FuncMap{
 		"modelName": getModelDisplayName,
+		"inc":       func(i int) int { return i + 1 },
 		"contains": func(slice []string, item string) bool {
 			for _, s := range slice {
 				if s == item {
@@ -171,13 +263,28 @@ func getModelDisplayName(modelID string) string {
 	return modelID
 }
 
-// loggingMiddleware logs all HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
+// requestIDMiddleware gives every request an ID, reusing a valid X-Request-ID
+// from the client. The ID is echoed in the response header, repeated in JSON
+// error bodies and carried by the request context into the log.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(config.RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(config.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// loggingMiddleware logs all HTTP requests and records request metrics
+func loggingMiddleware(next http.Handler, appMetrics *metrics.AppMetrics) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
 		// Log the request
-		log.Printf("Started %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		slog.InfoContext(r.Context(), fmt.Sprintf("Started %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr),
+			"method", r.Method, "path", r.URL.Path, "client_ip", config.ClientIP(r))
 
 		// Create a response writer wrapper to capture status code
 		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -187,7 +294,13 @@ func loggingMiddleware(next http.Handler) http.Handler {
 
 		// Log the response
 		duration := time.Since(start)
-		log.Printf("Completed %s %s with status %d in %v", r.Method, r.URL.Path, wrapper.statusCode, duration)
+		slog.InfoContext(r.Context(), fmt.Sprintf("Completed %s %s with status %d in %v", r.Method, r.URL.Path, wrapper.statusCode, duration),
+			"method", r.Method, "path", r.URL.Path, "status", wrapper.statusCode,
+			"duration_ms", float64(duration.Microseconds())/1000, "client_ip", config.ClientIP(r))
+
+		route := metrics.RouteLabel(r.URL.Path)
+		appMetrics.HTTPRequests.Inc(route, r.Method, strconv.Itoa(wrapper.statusCode))
+		appMetrics.HTTPDuration.Observe(duration.Seconds(), route, r.Method)
 	})
 }
 
@@ -202,23 +315,34 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush server-sent events
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// serve runs the web server until it fails. envErr is the outcome of
+// loading .env.
+func serve(envErr error) {
 	log.Println("🚀 Starting Pelican Art Gallery application...")
 
-	if err := godotenv.Load(); err != nil {
+	if envErr != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey == "" {
+	if config.DryRun() {
+		log.Println("INFO: DRY_RUN is set - generation returns placeholder SVGs without calling OpenRouter")
+	} else if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey == "" {
 		log.Println("WARNING: OPENROUTER_API_KEY environment variable not found - artwork generation will be disabled")
 	} else {
 		log.Println("INFO: OPENROUTER_API_KEY found - artwork generation is enabled")
 	}
 
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "artworks.db"
-	}
+	dbPath := databasePath()
 	log.Printf("Database path: %s", dbPath)
 
 	var db *database.DB
@@ -229,14 +353,14 @@ func main() {
 	if !config.IsEditingEnabled() {
 		// Open database in read-only mode
 		log.Printf("Opening database in read-only mode: %s", "file:"+dbPath+"?mode=ro")
-		db, err = database.New("file:" + dbPath + "?mode=ro")
+		db, err = openDatabase(dbPath, false)
 		if err != nil {
 			log.Fatalf("Failed to open database in read-only mode: %v", err)
 		}
 		log.Printf("Database opened in read-only mode at: %s", dbPath)
 	} else {
 		log.Printf("Opening database in write mode: %s", dbPath)
-		db, err = database.New(dbPath)
+		db, err = openDatabase(dbPath, true)
 		if err != nil {
 			log.Fatalf("Failed to initialize database: %v", err)
 		}
@@ -244,9 +368,19 @@ func main() {
 	}
 	defer db.Close()
 
-	promptConfig, err := config.LoadPromptConfig("config/prompt.yaml")
+	// Flag stored models that OpenRouter retires whenever the model cache refreshes
+	config.SetUsedModelsSource(db.ListUsedModels)
+	config.SetModelListRecorder(db.RecordModelSnapshot)
+
+	prompts, err := loadPrompts()
 	if err != nil {
-		log.Fatalf("Failed to load prompt config: %v", err)
+		log.Fatalf("Failed to load prompt configs: %v", err)
+	}
+	log.Printf("Loaded %d prompt style(s), default: %s", len(prompts.Load().Configs), prompts.Load().Default)
+
+	if os.Getenv("WATCH_CONFIG") == "true" {
+		log.Printf("Watching config/prompts for changes")
+		go prompts.Watch(2*time.Second, nil)
 	}
 
 	tmpl, err := parseTemplates()
@@ -256,152 +390,55 @@ func main() {
 
 	templateData := models.TemplateData{
 		Models:         config.GetAvailableModels(),
+		PromptStyles:   prompts.Load().Styles(),
 		EditingEnabled: config.IsEditingEnabled(),
 	}
 
-	apiHandler := api.NewHandler(promptConfig, db, tmpl)
-
-	pageHandler := pages.NewPageHandler(db, tmpl, templateData, getTemplates)
-
-	rateLimiter := NewRateLimiter(time.Minute, 100)
-
-	mux := http.NewServeMux()
-
-	// Static file handler
-	staticHandler := http.StripPrefix("/static/", http.FileServer(getStaticFS()))
-	mux.Handle("/static/", staticHandler)
-
-	mux.HandleFunc("/", pageHandler.HomepageHandler)
-	mux.HandleFunc("/workshop", pageHandler.WorkshopHandler)
-	mux.HandleFunc("/gallery", func(w http.ResponseWriter, r *http.Request) {
-		// Redirect /gallery to /gallery/ for consistency
-		http.Redirect(w, r, "/gallery/", http.StatusMovedPermanently)
-	})
-	mux.HandleFunc("/gallery/", func(w http.ResponseWriter, r *http.Request) {
-		// Extract category from path: /gallery/category/nature -> "nature"
-		path := r.URL.Path
-		category := ""
-
-		if path != "/gallery/" && path != "/gallery" {
-			// Check if it's a category path
-			if strings.HasPrefix(path, "/gallery/category/") {
-				category = strings.TrimPrefix(path, "/gallery/category/")
-				// URL decode the category
-				if decoded, err := url.QueryUnescape(category); err == nil {
-					category = decoded
-				}
-			} else {
-				// Invalid path
-				http.NotFound(w, r)
-				return
-			}
-		}
-
-		// Add category to query parameters
-		if category != "" {
-			q := r.URL.Query()
-			q.Set("category", category)
-			r.URL.RawQuery = q.Encode()
-		}
-
-		pageHandler.GalleryHandler(w, r)
-	})
+	db.KeepRevisions(config.ArtworkRevisions())
 
-	mux.HandleFunc("/group/", func(w http.ResponseWriter, r *http.Request) {
-		pageHandler.ArtworkGroupHandler(w, r)
+	appMetrics := metrics.NewAppMetrics()
+	db.ObserveReads(func(query string, d time.Duration) {
+		appMetrics.DBReadDuration.Observe(d.Seconds(), query)
 	})
+	if threshold := config.SlowQueryThreshold(); threshold > 0 {
+		log.Printf("Logging database statements slower than %s", threshold)
+		db.LogSlowQueries(threshold, func(pool, query string, d time.Duration) {
+			appMetrics.DBSlowQueries.Inc(pool)
+		})
+	}
 
-	mux.HandleFunc("/api/generate", rateLimiter.Middleware(apiHandler.GenerateArtworkHandler))
-	mux.HandleFunc("/api/delete-artwork/", rateLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
-		// Extract ID from path
-		path := strings.TrimPrefix(r.URL.Path, "/api/delete-artwork/")
-		apiHandler.DeleteArtworkHandler(w, r, path)
-	}))
-	mux.HandleFunc("/api/models", rateLimiter.Middleware(apiHandler.ListModelsHandler))
-
-	// Group endpoints
-	mux.HandleFunc("/api/groups", rateLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			apiHandler.ListGroupsHandler(w, r)
-		case http.MethodPost:
-			apiHandler.CreateGroupHandler(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-	mux.HandleFunc("/api/groups/", rateLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimPrefix(r.URL.Path, "/api/groups/")
-
-		// Handle original-artwork endpoint
-		if strings.Contains(path, "/original-artwork") {
-			parts := strings.Split(path, "/")
-			if len(parts) >= 2 {
-				idStr := parts[0]
-				switch r.Method {
-				case http.MethodPost:
-					apiHandler.UploadOriginalArtworkHandler(w, r, idStr)
-				case http.MethodGet:
-					apiHandler.GetOriginalArtworkHandler(w, r, idStr)
-				default:
-					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-				}
-				return
-			}
-		}
-
-		idStr := strings.TrimSuffix(path, "/")
-
-		switch r.Method {
-		case http.MethodGet:
-			apiHandler.GetGroupHandler(w, r)
-		case http.MethodPut:
-			apiHandler.UpdateGroupHandler(w, r, idStr)
-		case http.MethodDelete:
-			apiHandler.DeleteGroupHandler(w, r, idStr)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-
-	// Artwork endpoints
-	mux.HandleFunc("/api/artworks", rateLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			apiHandler.CreateArtworkHandler(w, r)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
-	mux.HandleFunc("/api/artworks/", rateLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimPrefix(r.URL.Path, "/api/artworks/")
-
-		// Handle featured endpoint
-		if strings.Contains(path, "/featured") {
-			parts := strings.Split(path, "/")
-			if len(parts) >= 2 {
-				idStr := parts[0]
-				if r.Method == http.MethodPost {
-					apiHandler.SetFeaturedArtworkHandler(w, r, idStr)
-				} else {
-					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-				}
-				return
-			}
+	if interval := config.BackupInterval(); interval > 0 {
+		backupDir := config.BackupDir()
+		if backupDir == "" {
+			backupDir = filepath.Join(filepath.Dir(dbPath), "backups")
 		}
+		log.Printf("Backing up the database to %s every %s, keeping %d snapshot(s)", backupDir, interval, config.BackupKeep())
+		go db.ScheduleBackups(context.Background(), interval, backupDir, config.BackupKeep(), log.Printf)
+	}
 
-		if r.Method == http.MethodPatch {
-			// Extract ID from path
-			idStr := strings.TrimSuffix(path, "/")
-			apiHandler.UpdateArtworkHandler(w, r, idStr)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	}))
+	rateLimiter := NewRateLimiter(config.RateLimitWindow, config.RateLimitRequests)
+	rateLimiter.WarnAt(config.RateLimitWarnFraction())
+	exempt, err := config.RateLimitExempt()
+	if err != nil {
+		log.Fatalf("Failed to parse rate limit exemptions: %v", err)
+	}
+	trustedProxies, err := config.TrustedProxies()
+	if err != nil {
+		log.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+	if len(exempt) > 0 {
+		log.Printf("Rate limit exempt: %v (trusted proxies: %v)", exempt, trustedProxies)
+	}
+	rateLimiter.Exempt(exempt, trustedProxies)
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	handler := NewServer(ServerConfig{
+		Prompts:        prompts,
+		Templates:      tmpl,
+		TemplateData:   templateData,
+		Metrics:        appMetrics,
+		RateLimiter:    rateLimiter,
+		TrustedProxies: trustedProxies,
+	}, db, openrouter.NewClient())
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -412,10 +449,8 @@ func main() {
 	fmt.Printf("Pelican Art Gallery starting on http://localhost:%s\n", port)
 	fmt.Println("Press Ctrl+C to stop the server")
 
-	loggedMux := loggingMiddleware(mux)
-
 	log.Printf("Server configured, attempting to listen on port %s", port)
-	if err := http.ListenAndServe(":"+port, loggedMux); err != nil {
+	if err := http.ListenAndServe(":"+port, handler); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
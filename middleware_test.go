@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"pelican-gallery/internal/logging"
+	"pelican-gallery/internal/metrics"
+)
+
+func TestMetricsEndpointReportsRequests(t *testing.T) {
+	appMetrics := metrics.NewAppMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/groups/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/metrics", appMetrics.Handler())
+	handler := loggingMiddleware(mux, appMetrics)
+
+	for _, path := range []string{"/api/groups/1", "/api/groups/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	appMetrics.OpenRouterRequests.Inc("openai/gpt-4o", "success")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE pelican_http_requests_total counter",
+		"# TYPE pelican_http_request_duration_seconds histogram",
+		"# TYPE pelican_openrouter_requests_total counter",
+		"# TYPE pelican_generation_duration_seconds histogram",
+		`pelican_http_requests_total{path="/api/groups/:id",method="GET",status="404"} 2`,
+		`pelican_http_request_duration_seconds_count{path="/api/groups/:id",method="GET"} 2`,
+		`pelican_openrouter_requests_total{model="openai/gpt-4o",result="success"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestRequireAdminKey(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "secret")
+	handler := requireAdminKey(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name, method, authorization string
+		want                        int
+	}{
+		{"missing token", http.MethodPost, "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "Bearer wrong", http.StatusUnauthorized},
+		{"wrong scheme", http.MethodDelete, "Basic secret", http.StatusUnauthorized},
+		{"correct token", http.MethodPost, "Bearer secret", http.StatusNoContent},
+		{"read without token", http.MethodGet, "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/groups", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRequireAdminKeyWithoutKeyConfigured(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "")
+	handler := requireAdminKey(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/groups", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestRequireAdminKeyForAllGuardsReads(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "secret")
+	handler := requireAdminKeyForAll(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoggingMiddlewareLogsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logging.Setup("json", &buf)
+	t.Cleanup(func() { logging.Setup("", os.Stderr) })
+
+	handler := requestIDMiddleware(loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), metrics.NewAppMetrics()))
+	req := httptest.NewRequest(http.MethodPost, "/api/groups", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %v\n%s", err, line)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("%d log records, want the start and end of the request:\n%s", len(records), buf.String())
+	}
+
+	for i, record := range records {
+		for key, want := range map[string]interface{}{"method": "POST", "path": "/api/groups", "client_ip": "192.0.2.1", "request_id": "req-1"} {
+			if record[key] != want {
+				t.Errorf("record %d: %s = %v, want %v", i, key, record[key], want)
+			}
+		}
+	}
+	completed := records[1]
+	if completed["status"] != float64(http.StatusTeapot) {
+		t.Errorf("status = %v, want %d", completed["status"], http.StatusTeapot)
+	}
+	if duration, ok := completed["duration_ms"].(float64); !ok || duration < 0 {
+		t.Errorf("duration_ms = %v", completed["duration_ms"])
+	}
+	if msg, _ := completed["msg"].(string); !strings.HasPrefix(msg, "Completed POST /api/groups with status 418") {
+		t.Errorf("msg = %q", msg)
+	}
+}
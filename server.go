@@ -0,0 +1,333 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"pelican-gallery/internal/api"
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/metrics"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+	"pelican-gallery/internal/pages"
+)
+
+// ServerConfig holds what NewServer needs besides the database and the
+// generator
+type ServerConfig struct {
+	Prompts        *config.PromptStore
+	Templates      *template.Template
+	TemplateData   models.TemplateData
+	Metrics        *metrics.AppMetrics
+	RateLimiter    *RateLimiter
+	TrustedProxies []netip.Prefix
+}
+
+// NewServer creates the handlers and assembles every route, wrapped in the
+// rate limiter and request logging, into a single http.Handler
+func NewServer(cfg ServerConfig, db *database.DB, generator openrouter.Generator) http.Handler {
+	apiHandler := api.NewHandler(cfg.Prompts, db, cfg.Templates, cfg.Metrics, generator)
+	apiHandler.SetTrustedProxies(cfg.TrustedProxies)
+
+	pageHandler := pages.NewPageHandler(db, cfg.Templates, cfg.TemplateData, getTemplates)
+
+	rateLimiter := cfg.RateLimiter
+
+	// Rendered gallery pages are reused in production until a write
+	var galleryCache *pages.GalleryCache
+	if ttl := config.GalleryCacheTTL(); !isDevelopment() && ttl > 0 {
+		galleryCache = pages.NewGalleryCache(ttl)
+		pageHandler.SetGalleryCache(galleryCache)
+		// Batch generation saves SVGs after its request has returned
+		db.OnSVGSaved(func(int) { galleryCache.Invalidate() })
+	}
+
+	// adminWrite guards the endpoints that change artworks with the admin
+	// key and clears the gallery cache after each successful write
+	adminWrite := func(next http.HandlerFunc) http.HandlerFunc {
+		next = requireAdminKey(next)
+		if galleryCache == nil {
+			return next
+		}
+		return galleryCache.InvalidateOnWrite(next).ServeHTTP
+	}
+
+	mux := http.NewServeMux()
+
+	// Static file handler
+	staticHandler := http.StripPrefix("/static/", http.FileServer(getStaticFS()))
+	mux.Handle("/static/", staticHandler)
+
+	mux.HandleFunc("/", pageHandler.HomepageHandler)
+	mux.HandleFunc("/workshop", pageHandler.WorkshopHandler)
+	mux.HandleFunc("/compare", pageHandler.CompareHandler)
+	mux.HandleFunc("/vote", pageHandler.VoteHandler)
+	mux.HandleFunc("/leaderboard", pageHandler.LeaderboardHandler)
+	mux.HandleFunc("/feed.xml", pageHandler.FeedHandler)
+	mux.HandleFunc("/sitemap.xml", pageHandler.SitemapHandler)
+	mux.HandleFunc("/gallery", func(w http.ResponseWriter, r *http.Request) {
+		// Redirect /gallery to /gallery/ for consistency
+		http.Redirect(w, r, "/gallery/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gallery/", func(w http.ResponseWriter, r *http.Request) {
+		// Extract the category slug from path: /gallery/category/nature -> "nature".
+		// Older URLs spelled out the category name; the gallery redirects
+		// those to the slug.
+		path := r.URL.Path
+		category := ""
+
+		if path != "/gallery/" && path != "/gallery" {
+			// Check if it's a category path
+			if strings.HasPrefix(path, "/gallery/category/") {
+				category = strings.TrimPrefix(path, "/gallery/category/")
+				// URL decode the category
+				if decoded, err := url.QueryUnescape(category); err == nil {
+					category = decoded
+				}
+			} else {
+				// Invalid path
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		// Add category to query parameters
+		if category != "" {
+			q := r.URL.Query()
+			q.Set("category", category)
+			r.URL.RawQuery = q.Encode()
+		}
+
+		pageHandler.GalleryHandler(w, r)
+	})
+
+	mux.HandleFunc("/group/", func(w http.ResponseWriter, r *http.Request) {
+		if idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/group/"), "/og-image.png"); ok {
+			pageHandler.GroupOGImageHandler(w, r, idStr)
+			return
+		}
+		if idStr, ok := strings.CutSuffix(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/group/"), "/"), "/history"); ok {
+			pageHandler.GroupHistoryHandler(w, r, idStr)
+			return
+		}
+		pageHandler.ArtworkGroupHandler(w, r)
+	})
+
+	// Rasterized artworks for social previews and feed readers
+	mux.HandleFunc("/artworks/", func(w http.ResponseWriter, r *http.Request) {
+		idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/artworks/"), ".png")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		apiHandler.ArtworkPNGHandler(w, r, idStr)
+	})
+
+	mux.HandleFunc("/api/generate", rateLimiter.Middleware(adminWrite(apiHandler.GenerateArtworkHandler)))
+	mux.HandleFunc("/api/delete-artwork/", rateLimiter.Middleware(adminWrite(func(w http.ResponseWriter, r *http.Request) {
+		// Extract ID from path
+		path := strings.TrimPrefix(r.URL.Path, "/api/delete-artwork/")
+		apiHandler.DeleteArtworkHandler(w, r, path)
+	})))
+	mux.HandleFunc("/api/models", rateLimiter.Middleware(apiHandler.ListModelsHandler))
+	mux.HandleFunc("/api/stats", rateLimiter.Middleware(apiHandler.StatsHandler))
+	// The full export holds archived groups and reference images, so it needs
+	// the key; without one configured, private artworks are still left out
+	mux.HandleFunc("/api/export", rateLimiter.Middleware(requireAdminKeyForAll(apiHandler.ExportHandler)))
+	mux.HandleFunc("/api/import", rateLimiter.Middleware(adminWrite(apiHandler.ImportHandler)))
+	mux.HandleFunc("/api/manifest", rateLimiter.Middleware(apiHandler.ManifestHandler))
+	mux.HandleFunc("/api/prompt-styles", rateLimiter.Middleware(apiHandler.ListPromptStylesHandler))
+	mux.HandleFunc("/api/models/used", rateLimiter.Middleware(apiHandler.ListUsedModelsHandler))
+	mux.HandleFunc("/api/models/history", rateLimiter.Middleware(apiHandler.ModelHistoryHandler))
+	mux.HandleFunc("/api/insights/divergent", rateLimiter.Middleware(apiHandler.DivergentGroupsHandler))
+	// Visitors vote without an admin key
+	mux.HandleFunc("/api/votes", rateLimiter.Middleware(apiHandler.VoteHandler))
+	mux.HandleFunc("/api/leaderboard", rateLimiter.Middleware(apiHandler.LeaderboardHandler))
+	mux.HandleFunc("/api/daily", rateLimiter.Middleware(apiHandler.DailyMatchupHandler))
+
+	// Admin endpoints. The error log holds prompts and upstream error bodies
+	// and the recycle bin lists deleted groups, so their reads need the key.
+	mux.HandleFunc("/admin/errors", requireAdminKeyForAll(pageHandler.AdminErrorsHandler))
+	mux.HandleFunc("/api/admin/errors", rateLimiter.Middleware(requireAdminKeyForAll(apiHandler.ListGenerationErrorsHandler)))
+	mux.HandleFunc("/api/admin/reload-config", rateLimiter.Middleware(adminWrite(apiHandler.ReloadConfigHandler)))
+	mux.HandleFunc("/api/admin/schema-check", rateLimiter.Middleware(requireAdminKeyForAll(apiHandler.SchemaCheckHandler)))
+	mux.HandleFunc("/api/admin/backup", rateLimiter.Middleware(requireAdminKeyForAll(apiHandler.BackupHandler)))
+	mux.HandleFunc("/api/admin/restore", rateLimiter.Middleware(adminWrite(apiHandler.RestoreHandler)))
+	mux.HandleFunc("/api/recycle-bin", rateLimiter.Middleware(requireAdminKeyForAll(apiHandler.RecycleBinHandler)))
+	mux.HandleFunc("/api/svg/resanitize-all", rateLimiter.Middleware(adminWrite(apiHandler.ResanitizeAllHandler)))
+
+	// Group endpoints
+	mux.HandleFunc("/api/groups", rateLimiter.Middleware(adminWrite(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiHandler.ListGroupsHandler(w, r)
+		case http.MethodPost:
+			apiHandler.CreateGroupHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.HandleFunc("/api/groups/", rateLimiter.Middleware(adminWrite(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/groups/"), "/")
+
+		if path == "import" {
+			apiHandler.ImportGroupsHandler(w, r)
+			return
+		}
+		if path == "reorder" {
+			apiHandler.ReorderGroupsHandler(w, r)
+			return
+		}
+
+		// Split "{id}/{action}" so sub-resources can be dispatched by name
+		idStr, action, _ := strings.Cut(path, "/")
+
+		if action != "" {
+			switch action {
+			case "original-artwork":
+				switch r.Method {
+				case http.MethodPost:
+					apiHandler.UploadOriginalArtworkHandler(w, r, idStr)
+				case http.MethodGet:
+					apiHandler.GetOriginalArtworkHandler(w, r, idStr)
+				default:
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+			case "generate-all":
+				apiHandler.GenerateGroupHandler(w, r, idStr)
+			case "archive":
+				apiHandler.ArchiveGroupHandler(w, r, idStr, true)
+			case "unarchive":
+				apiHandler.ArchiveGroupHandler(w, r, idStr, false)
+			case "restore":
+				apiHandler.RestoreGroupHandler(w, r, idStr)
+			case "export":
+				apiHandler.GroupExportHandler(w, r, idStr)
+			case "export.zip":
+				apiHandler.GroupZipExportHandler(w, r, idStr)
+			case "revisions":
+				apiHandler.ModelRevisionsHandler(w, r, idStr)
+			case "comparison":
+				apiHandler.GroupComparisonHandler(w, r, idStr)
+			case "artworks/reorder":
+				apiHandler.ReorderArtworksHandler(w, r, idStr)
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			apiHandler.GetGroupHandler(w, r)
+		case http.MethodPut:
+			apiHandler.UpdateGroupHandler(w, r, idStr)
+		case http.MethodDelete:
+			apiHandler.DeleteGroupHandler(w, r, idStr)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Category endpoints
+	mux.HandleFunc("/api/categories/", rateLimiter.Middleware(adminWrite(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/categories/")
+		if name == "merge" && r.Method == http.MethodPost {
+			apiHandler.MergeCategoriesHandler(w, r)
+			return
+		}
+		apiHandler.RenameCategoryHandler(w, r, name)
+	})))
+
+	// Batch job progress
+	mux.HandleFunc("/api/jobs/", rateLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/")
+		idStr, ok := strings.CutSuffix(path, "/events")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		apiHandler.JobEventsHandler(w, r, idStr)
+	}))
+
+	// Artwork endpoints
+	mux.HandleFunc("/api/artworks", rateLimiter.Middleware(adminWrite(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			apiHandler.CreateArtworkHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.HandleFunc("/api/artworks/", rateLimiter.Middleware(adminWrite(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/api/artworks/")
+
+		if idStr, rest, ok := strings.Cut(strings.TrimSuffix(path, "/"), "/revisions"); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+			revisionIDStr, action, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+			apiHandler.ArtworkRevisionsHandler(w, r, idStr, revisionIDStr, action)
+			return
+		}
+
+		if idStr, ok := strings.CutSuffix(strings.TrimSuffix(path, "/"), "/visibility"); ok {
+			apiHandler.SetArtworkVisibilityHandler(w, r, idStr)
+			return
+		}
+
+		if idStr, ok := strings.CutSuffix(strings.TrimSuffix(path, "/"), "/restore"); ok {
+			apiHandler.RestoreArtworkHandler(w, r, idStr)
+			return
+		}
+
+		if idStr, ok := strings.CutSuffix(strings.TrimSuffix(path, "/"), "/model"); ok {
+			apiHandler.SetArtworkModelHandler(w, r, idStr)
+			return
+		}
+
+		if idStr, ok := strings.CutSuffix(strings.TrimSuffix(path, "/"), "/png"); ok {
+			apiHandler.ArtworkPNGHandler(w, r, idStr)
+			return
+		}
+
+		if idStr, ok := strings.CutSuffix(strings.TrimSuffix(path, "/"), "/thumbnail"); ok {
+			apiHandler.ArtworkThumbnailHandler(w, r, idStr)
+			return
+		}
+
+		// Handle featured endpoint
+		if strings.Contains(path, "/featured") {
+			parts := strings.Split(path, "/")
+			if len(parts) >= 2 {
+				idStr := parts[0]
+				if r.Method == http.MethodPost {
+					apiHandler.SetFeaturedArtworkHandler(w, r, idStr)
+				} else {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				}
+				return
+			}
+		}
+
+		if r.Method == http.MethodPatch {
+			// Extract ID from path
+			idStr := strings.TrimSuffix(path, "/")
+			apiHandler.UpdateArtworkHandler(w, r, idStr)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.HandleFunc("/metrics", cfg.Metrics.Handler())
+
+	// One liveness and one readiness probe. /health stays an alias of
+	// liveness for existing monitors, and Kubernetes-style probes are often
+	// configured with /readyz.
+	mux.HandleFunc("/health/live", apiHandler.LiveHandler)
+	mux.HandleFunc("/health", apiHandler.LiveHandler)
+	mux.HandleFunc("/health/ready", apiHandler.ReadyHandler)
+	mux.HandleFunc("/readyz", apiHandler.ReadyHandler)
+
+	return requestIDMiddleware(loggingMiddleware(adminContextMiddleware(mux), cfg.Metrics))
+}
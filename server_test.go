@@ -0,0 +1,897 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pelican-gallery/internal/config"
+	"pelican-gallery/internal/database"
+	"pelican-gallery/internal/metrics"
+	"pelican-gallery/internal/models"
+	"pelican-gallery/internal/openrouter"
+)
+
+// testSVG is a small valid SVG for artworks that need one
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4"/></svg>`
+
+// fakeModels are the models the fake OpenRouter offers
+var fakeModels = []string{"openai/gpt-4o", "google/gemini-2.5-pro", "anthropic/claude-sonnet-4"}
+
+// fakeOpenRouter serves the OpenRouter model list and chat completions,
+// drawing an SVG titled with the requested model
+type fakeOpenRouter struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []string        // models of the chat completion requests
+	failing  map[string]bool // models answered with an error
+}
+
+func newFakeOpenRouter(t *testing.T) *fakeOpenRouter {
+	t.Helper()
+	u := &fakeOpenRouter{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/models", func(w http.ResponseWriter, r *http.Request) {
+		var data []map[string]interface{}
+		for _, id := range fakeModels {
+			data = append(data, map[string]interface{}{"id": id, "name": id, "pricing": map[string]string{"completion": "0.00001"}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	})
+	mux.HandleFunc("/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode chat request: %v", err)
+		}
+		u.mu.Lock()
+		u.requests = append(u.requests, body.Model)
+		failing := u.failing[body.Model]
+		u.mu.Unlock()
+		if failing {
+			http.Error(w, `{"error": {"message": "model refused"}}`, http.StatusBadRequest)
+			return
+		}
+
+		svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><title>` + body.Model + `</title></svg>`
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   body.Model,
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": svg}, "finish_reason": "stop"}},
+		})
+	})
+	u.Server = httptest.NewServer(mux)
+	t.Cleanup(u.Close)
+	return u
+}
+
+// fail makes the chat completions of model fail from now on
+func (u *fakeOpenRouter) fail(model string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.failing == nil {
+		u.failing = make(map[string]bool)
+	}
+	u.failing[model] = true
+}
+
+// calls returns the models of the chat completion requests so far
+func (u *fakeOpenRouter) calls() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.requests...)
+}
+
+// testServer is the composed server of NewServer listening on a local port,
+// with its database and the fake OpenRouter it generates with
+type testServer struct {
+	*httptest.Server
+	db         *database.DB
+	openRouter *fakeOpenRouter
+}
+
+// newTestServer starts NewServer on an in-memory database and a fake
+// OpenRouter, with editing enabled
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("ENABLE_EDITING", "true")
+	openRouter := newFakeOpenRouter(t)
+	t.Setenv("OPENROUTER_BASE_URL", openRouter.URL)
+	if err := config.RefreshModels(context.Background()); err != nil {
+		t.Fatalf("RefreshModels: %v", err)
+	}
+
+	// Every connection of the pools shares the one in-memory database
+	dsn := "file:" + url.PathEscape(t.Name()) + "?mode=memory&cache=shared"
+	db, err := database.New(dsn)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	prompts, err := config.NewPromptStore("config/prompts")
+	if err != nil {
+		t.Fatalf("NewPromptStore: %v", err)
+	}
+	tmpl, err := parseTemplates()
+	if err != nil {
+		t.Fatalf("parseTemplates: %v", err)
+	}
+
+	generator := &openrouter.Client{BaseURL: openRouter.URL, APIKey: "test-key", Timeout: 10 * time.Second}
+	handler := NewServer(ServerConfig{
+		Prompts:   prompts,
+		Templates: tmpl,
+		TemplateData: models.TemplateData{
+			PromptStyles:   prompts.Load().Styles(),
+			EditingEnabled: true,
+		},
+		Metrics:     metrics.NewAppMetrics(),
+		RateLimiter: NewRateLimiter(time.Minute, 1000),
+	}, db, generator)
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &testServer{Server: server, db: db, openRouter: openRouter}
+}
+
+// do sends a request to the server and returns the response with its body
+// read
+func (s *testServer) do(t *testing.T, method, path, contentType, body string) (*http.Response, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s %s: %v", method, path, err)
+	}
+	return resp, string(data)
+}
+
+// seedGroup creates a group with title in category and returns its ID
+func (s *testServer) seedGroup(t *testing.T, title, category string) int {
+	t.Helper()
+	now := time.Now()
+	id, err := s.db.CreateGroup(models.ArtworkGroup{Title: title, Prompt: "Generate an SVG of " + title, Category: category, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	return id
+}
+
+// seedArtwork creates an artwork of model in a group, with svg unless it is
+// empty, and returns its ID
+func (s *testServer) seedArtwork(t *testing.T, groupID int, model, svg string) int {
+	t.Helper()
+	now := time.Now()
+	id, err := s.db.CreateArtwork(models.Artwork{GroupID: groupID, Model: model, MaxTokens: 1000, SVG: svg, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("CreateArtwork: %v", err)
+	}
+	return id
+}
+
+// testPNG encodes a blank PNG of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOriginalArtworkRoutes(t *testing.T) {
+	s := newTestServer(t)
+	withImage := s.seedGroup(t, "With original", "")
+	without := s.seedGroup(t, "Without original", "")
+
+	original := testPNG(t, 4, 4)
+	if err := s.db.SaveOriginalArtwork(models.OriginalArtwork{GroupID: withImage, ContentType: "image/png", Data: original, UploadedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveOriginalArtwork: %v", err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/groups/" + strconv.Itoa(withImage) + "/original-artwork", http.StatusOK},
+		{http.MethodGet, "/api/groups/" + strconv.Itoa(withImage) + "/original-artwork/", http.StatusOK},
+		{http.MethodGet, "/api/groups/" + strconv.Itoa(without) + "/original-artwork", http.StatusNotFound},
+		{http.MethodGet, "/api/groups/abc/original-artwork", http.StatusBadRequest},
+		{http.MethodPost, "/api/groups/" + strconv.Itoa(withImage) + "/original-artwork", http.StatusBadRequest},
+		{http.MethodPut, "/api/groups/" + strconv.Itoa(withImage) + "/original-artwork", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/groups/" + strconv.Itoa(withImage) + "/original-artwork", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		resp, body := s.do(t, tt.method, tt.path, "", "")
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.want, body)
+		}
+		if resp.StatusCode == http.StatusOK && body != string(original) {
+			t.Errorf("%s %s returned %d bytes, not the stored original", tt.method, tt.path, len(body))
+		}
+	}
+}
+
+// decode decodes the JSON body of a response
+func decode(t *testing.T, body string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+}
+
+func TestGroupJourney(t *testing.T) {
+	s := newTestServer(t)
+
+	// Create a group
+	resp, body := s.do(t, http.MethodPost, "/api/groups", "application/json", `{"title": "Pelican on a bicycle", "prompt": "A pelican riding a bicycle"}`)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create group = %d: %s", resp.StatusCode, body)
+	}
+	var group models.ArtworkGroup
+	decode(t, body, &group)
+	groupPath := "/api/groups/" + strconv.Itoa(group.ID)
+
+	// Add an artwork per model
+	var artworkIDs []int
+	for _, model := range fakeModels[:2] {
+		resp, body = s.do(t, http.MethodPost, "/api/artworks", "application/json", `{"group_id": `+strconv.Itoa(group.ID)+`, "model": "`+model+`", "max_tokens": 4000}`)
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			t.Fatalf("add %s = %d: %s", model, resp.StatusCode, body)
+		}
+		var artwork models.Artwork
+		decode(t, body, &artwork)
+		artworkIDs = append(artworkIDs, artwork.ID)
+	}
+
+	// An unknown model is refused with the list from OpenRouter
+	resp, body = s.do(t, http.MethodPost, "/api/artworks", "application/json", `{"group_id": `+strconv.Itoa(group.ID)+`, "model": "nobody/nothing", "max_tokens": 4000}`)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("add unknown model = %d, want %d: %s", resp.StatusCode, http.StatusUnprocessableEntity, body)
+	}
+
+	// Generate each artwork through OpenRouter
+	for _, id := range artworkIDs {
+		resp, body = s.do(t, http.MethodPost, "/api/generate", "application/json", `{"artwork_id": `+strconv.Itoa(id)+`}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("generate %d = %d: %s", id, resp.StatusCode, body)
+		}
+	}
+	if calls := s.openRouter.calls(); len(calls) != 2 || calls[0] != fakeModels[0] || calls[1] != fakeModels[1] {
+		t.Errorf("OpenRouter got requests for %v, want %v", calls, fakeModels[:2])
+	}
+
+	// Featuring a drawing puts the group in the gallery
+	resp, body = s.do(t, http.MethodPost, "/api/artworks/"+strconv.Itoa(artworkIDs[1])+"/featured", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("feature = %d: %s", resp.StatusCode, body)
+	}
+	resp, body = s.do(t, http.MethodGet, "/gallery/", "", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "Pelican on a bicycle") || !strings.Contains(body, "<title>"+fakeModels[1]+"</title>") {
+		t.Errorf("gallery = %d without the featured drawing of the new group", resp.StatusCode)
+	}
+	resp, body = s.do(t, http.MethodGet, "/group/"+strconv.Itoa(group.ID), "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("group page = %d", resp.StatusCode)
+	}
+	for _, model := range fakeModels[:2] {
+		if !strings.Contains(body, "<title>"+model+"</title>") {
+			t.Errorf("group page does not show the drawing by %s", model)
+		}
+	}
+
+	// Deleting the group takes its artworks with it
+	resp, body = s.do(t, http.MethodDelete, groupPath+"?permanent=true", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete group = %d: %s", resp.StatusCode, body)
+	}
+	resp, body = s.do(t, http.MethodGet, "/api/groups", "", "")
+	if resp.StatusCode != http.StatusOK || strings.Contains(body, "Pelican on a bicycle") {
+		t.Errorf("group list = %d, still listing the deleted group: %s", resp.StatusCode, body)
+	}
+	if resp, _ = s.do(t, http.MethodGet, "/group/"+strconv.Itoa(group.ID), "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("page of the deleted group = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	for _, id := range artworkIDs {
+		if artwork, err := s.db.GetArtwork(id); err == nil {
+			t.Errorf("artwork %d of the deleted group is still stored: %+v", id, artwork)
+		}
+	}
+	resp, body = s.do(t, http.MethodGet, "/gallery/", "", "")
+	if resp.StatusCode != http.StatusOK || strings.Contains(body, "Pelican on a bicycle") {
+		t.Errorf("gallery = %d, still showing the deleted group", resp.StatusCode)
+	}
+}
+
+func TestPrivateArtworksNeedTheAdminKey(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("ADMIN_API_KEY", "secret")
+	groupID := s.seedGroup(t, "Pelican", "Birds")
+	s.seedArtwork(t, groupID, fakeModels[0], testSVG)
+	private := s.seedArtwork(t, groupID, fakeModels[1], testSVG)
+	if err := s.db.SetArtworkVisibility(private, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	paths := []string{"/api/groups/" + strconv.Itoa(groupID), "/group/" + strconv.Itoa(groupID)}
+	tests := []struct {
+		name, authorization string
+		wantPrivate         bool
+	}{
+		{"anonymous", "", false},
+		{"wrong key", "Bearer wrong", false},
+		{"admin key", "Bearer secret", true},
+	}
+	for _, tt := range tests {
+		for _, path := range paths {
+			req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s: GET %s = %d", tt.name, path, resp.StatusCode)
+			}
+			if !strings.Contains(string(body), fakeModels[0]) {
+				t.Errorf("%s: GET %s does not show the public artwork", tt.name, path)
+			}
+			if got := strings.Contains(string(body), fakeModels[1]); got != tt.wantPrivate {
+				t.Errorf("%s: GET %s shows the private artwork = %v, want %v", tt.name, path, got, tt.wantPrivate)
+			}
+		}
+	}
+}
+
+func TestCreateGroupRejectsDuplicatePrompts(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("ADMIN_API_KEY", "secret")
+
+	post := func(authorization, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, s.URL+"/api/groups", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /api/groups: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := post("Bearer secret", `{"title": "Pelican", "prompt": "A pelican riding a bicycle", "category": "Birds"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("first group = %d: %s", code, body)
+	}
+	var first models.ArtworkGroup
+	decode(t, body, &first)
+
+	tests := []struct {
+		name, authorization, body string
+		want                      int
+	}{
+		{"no key", "", `{"title": "Pelican 2", "prompt": "A pelican riding a bicycle", "category": "Birds"}`, http.StatusUnauthorized},
+		{"wrong key", "Bearer wrong", `{"title": "Pelican 2", "prompt": "A pelican riding a bicycle", "category": "Birds"}`, http.StatusUnauthorized},
+		{"same prompt", "Bearer secret", `{"title": "Pelican 2", "prompt": "  a PELICAN riding   a bicycle ", "category": " birds"}`, http.StatusConflict},
+		{"other category", "Bearer secret", `{"title": "Pelican 3", "prompt": "A pelican riding a bicycle", "category": "Vehicles"}`, http.StatusCreated},
+		{"allow_duplicate", "Bearer secret", `{"title": "Pelican 4", "prompt": "A pelican riding a bicycle", "category": "Birds", "allow_duplicate": true}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := post(tt.authorization, tt.body)
+			if code != tt.want {
+				t.Fatalf("POST /api/groups = %d, want %d: %s", code, tt.want, body)
+			}
+			if code != http.StatusConflict {
+				return
+			}
+			var conflict struct {
+				Details struct {
+					Code    string `json:"code"`
+					GroupID int    `json:"group_id"`
+				} `json:"details"`
+			}
+			decode(t, body, &conflict)
+			if conflict.Details.Code != "duplicate_prompt" || conflict.Details.GroupID != first.ID {
+				t.Errorf("conflict details = %+v, want duplicate_prompt of group %d", conflict.Details, first.ID)
+			}
+		})
+	}
+
+	// A group in the recycle bin no longer holds its prompt
+	if err := s.db.DeleteGroup(s.seedGroup(t, "Stork", "Storks")); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if code, body := post("Bearer secret", `{"title": "Stork 2", "prompt": "Generate an SVG of Stork", "category": "Storks"}`); code != http.StatusCreated {
+		t.Errorf("prompt of a deleted group: POST /api/groups = %d, want %d: %s", code, http.StatusCreated, body)
+	}
+
+	t.Setenv("ENABLE_EDITING", "false")
+	if code, body := post("Bearer secret", `{"title": "Heron", "prompt": "A heron", "category": "Birds"}`); code != http.StatusForbidden {
+		t.Errorf("with editing disabled, POST /api/groups = %d, want %d: %s", code, http.StatusForbidden, body)
+	}
+}
+
+func TestAdminReadsNeedTheAdminKey(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("ADMIN_API_KEY", "secret")
+
+	for _, path := range []string{"/admin/errors", "/api/admin/errors", "/api/recycle-bin", "/api/admin/schema-check", "/api/export"} {
+		for _, authorization := range []string{"", "Bearer wrong", "Bearer secret"} {
+			req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			resp.Body.Close()
+
+			want := http.StatusUnauthorized
+			if authorization == "Bearer secret" {
+				want = http.StatusOK
+			}
+			if resp.StatusCode != want {
+				t.Errorf("GET %s with %q = %d, want %d", path, authorization, resp.StatusCode, want)
+			}
+		}
+	}
+}
+
+func TestExportLeavesOutPrivateArtworks(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("ADMIN_API_KEY", "")
+	groupID := s.seedGroup(t, "Pelican", "Birds")
+	s.seedArtwork(t, groupID, fakeModels[0], testSVG)
+	private := s.seedArtwork(t, groupID, fakeModels[1], testSVG)
+	if err := s.db.SetArtworkVisibility(private, models.VisibilityPrivate); err != nil {
+		t.Fatalf("SetArtworkVisibility: %v", err)
+	}
+
+	// Without a key configured anyone may export, but only what they may see
+	resp, body := s.do(t, http.MethodGet, "/api/export", "", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, fakeModels[0]) {
+		t.Fatalf("anonymous export = %d without the public artwork: %s", resp.StatusCode, body)
+	}
+	if strings.Contains(body, fakeModels[1]) {
+		t.Errorf("anonymous export holds the private artwork: %s", body)
+	}
+
+	t.Setenv("ADMIN_API_KEY", "secret")
+	req, err := http.NewRequest(http.MethodGet, s.URL+"/api/export", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	adminResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/export: %v", err)
+	}
+	data, _ := io.ReadAll(adminResp.Body)
+	adminResp.Body.Close()
+	if adminResp.StatusCode != http.StatusOK || !strings.Contains(string(data), fakeModels[1]) {
+		t.Errorf("admin export = %d without the private artwork", adminResp.StatusCode)
+	}
+}
+
+func TestBackupRestoresIntoAFreshServer(t *testing.T) {
+	source := newTestServer(t)
+	for _, title := range []string{"Pelican", "Heron", "Bicycle"} {
+		groupID := source.seedGroup(t, title, "Birds")
+		source.seedArtwork(t, groupID, fakeModels[0], testSVG)
+		source.seedArtwork(t, groupID, fakeModels[1], "")
+	}
+	want, err := source.db.GetStats("")
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+
+	resp, snapshot := source.do(t, http.MethodGet, "/api/admin/backup", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("backup = %d: %s", resp.StatusCode, snapshot)
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); !strings.Contains(disposition, database.BackupFilePrefix) {
+		t.Errorf("backup is sent as %q, not a timestamped file", disposition)
+	}
+
+	// A subtest has a name of its own, so its server gets a database of its own
+	t.Run("restore", func(t *testing.T) {
+		target := newTestServer(t)
+		target.seedGroup(t, "Overwritten", "")
+
+		tests := []struct {
+			name, query, body string
+			want              int
+		}{
+			{"unconfirmed", "", snapshot, http.StatusBadRequest},
+			{"wrong confirmation", "?confirm=yes", snapshot, http.StatusBadRequest},
+			{"not a database", "?confirm=" + config.RestoreConfirmation, "not a database", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			resp, body := target.do(t, http.MethodPost, "/api/admin/restore"+tt.query, "application/vnd.sqlite3", tt.body)
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s restore = %d, want %d: %s", tt.name, resp.StatusCode, tt.want, body)
+			}
+		}
+		if stats, _ := target.db.GetStats(""); stats.TotalGroups != 1 {
+			t.Fatalf("a refused restore changed the database: %d group(s)", stats.TotalGroups)
+		}
+
+		resp, body := target.do(t, http.MethodPost, "/api/admin/restore?confirm="+config.RestoreConfirmation, "application/vnd.sqlite3", snapshot)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("restore = %d: %s", resp.StatusCode, body)
+		}
+		var restored struct {
+			TotalGroups   int `json:"total_groups"`
+			TotalArtworks int `json:"total_artworks"`
+		}
+		decode(t, body, &restored)
+		if restored.TotalGroups != want.TotalGroups || restored.TotalArtworks != want.TotalArtworks {
+			t.Errorf("restore reported %d group(s) and %d artwork(s), want %d and %d",
+				restored.TotalGroups, restored.TotalArtworks, want.TotalGroups, want.TotalArtworks)
+		}
+
+		got, err := target.db.GetStats("")
+		if err != nil {
+			t.Fatalf("GetStats: %v", err)
+		}
+		if got.TotalGroups != want.TotalGroups || got.TotalArtworks != want.TotalArtworks ||
+			got.GeneratedSVGs != want.GeneratedSVGs || got.TotalCategories != want.TotalCategories {
+			t.Errorf("restored counts = %+v, want %+v", got, want)
+		}
+		resp, body = target.do(t, http.MethodGet, "/api/groups", "", "")
+		if resp.StatusCode != http.StatusOK || strings.Contains(body, "Overwritten") || !strings.Contains(body, "Pelican") {
+			t.Errorf("group list after restoring = %d: %s", resp.StatusCode, body)
+		}
+	})
+}
+
+func TestGroupHistoryRoute(t *testing.T) {
+	s := newTestServer(t)
+	groupID := s.seedGroup(t, "Pelican", "Birds")
+	s.seedArtwork(t, groupID, fakeModels[0], testSVG)
+	id := strconv.Itoa(groupID)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/group/" + id + "/history", http.StatusOK},
+		{"/group/" + id + "/history/", http.StatusOK},
+		{"/group/" + id + "/history?model=" + url.QueryEscape(fakeModels[0]), http.StatusOK},
+		{"/group/abc/history", http.StatusNotFound},
+		{"/group/" + id, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, body := s.do(t, http.MethodGet, tt.path, "", "")
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusOK && strings.HasPrefix(tt.path, "/group/"+id+"/history") && !strings.Contains(body, "Version 1") {
+			t.Errorf("GET %s is not the history page", tt.path)
+		}
+	}
+}
+
+func TestGalleryCacheClearedByWrites(t *testing.T) {
+	// The gallery cache is only used in production
+	t.Setenv("GO_ENV", "production")
+	s := newTestServer(t)
+	t.Setenv("ADMIN_API_KEY", "secret")
+	pelican := s.seedGroup(t, "Pelican", "Birds")
+	// The gallery shows the GPT-5 artwork of a group
+	s.seedArtwork(t, pelican, "openai/gpt-5", testSVG)
+
+	gallery := func() string {
+		t.Helper()
+		resp, body := s.do(t, http.MethodGet, "/gallery/category/birds", "", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /gallery/category/birds = %d", resp.StatusCode)
+		}
+		return body
+	}
+	if body := gallery(); !strings.Contains(body, `href="/group/`+strconv.Itoa(pelican)+`"`) {
+		t.Fatal("gallery does not show Pelican")
+	}
+
+	// Written straight to the database, so the cached page is still served
+	heron := s.seedGroup(t, "Heron", "Birds")
+	s.seedArtwork(t, heron, "openai/gpt-5", testSVG)
+	heronLink := `href="/group/` + strconv.Itoa(heron) + `"`
+	if body := gallery(); strings.Contains(body, heronLink) {
+		t.Fatal("gallery was rendered again, want the cached page")
+	}
+
+	// A rejected write leaves the cache alone
+	body := `{"title": "Stork", "prompt": "A stork", "category": "Birds"}`
+	if resp, _ := s.do(t, http.MethodPost, "/api/groups", "application/json", body); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("POST /api/groups without the key = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if body := gallery(); strings.Contains(body, heronLink) {
+		t.Error("a rejected write cleared the gallery cache")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/api/groups", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/groups: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/groups = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	if body := gallery(); !strings.Contains(body, heronLink) {
+		t.Error("gallery still cached after a group was created")
+	}
+}
+
+func TestGalleryCategoryRoutes(t *testing.T) {
+	s := newTestServer(t)
+	s.seedGroup(t, "Cube", "Abstract & Geometric")
+	s.seedGroup(t, "Crane", "日本")
+	s.seedGroup(t, "Pelican", "Birds")
+	if _, err := s.db.RenameCategory("Birds", "Sea Birds"); err != nil {
+		t.Fatalf("RenameCategory: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		want     int
+		location string
+	}{
+		{"/gallery/category/abstract-geometric", http.StatusOK, ""},
+		{"/gallery/category/sea-birds", http.StatusOK, ""},
+		{"/gallery/category/" + url.PathEscape("日本"), http.StatusOK, ""},
+		// Percent-encoded names, as the gallery linked them before slugs
+		{"/gallery/category/Abstract%20%26%20Geometric", http.StatusMovedPermanently, "/gallery/category/abstract-geometric"},
+		{"/gallery/category/Abstract+%26+Geometric", http.StatusMovedPermanently, "/gallery/category/abstract-geometric"},
+		// The slug from before the rename
+		{"/gallery/category/birds", http.StatusMovedPermanently, "/gallery/category/sea-birds"},
+		{"/gallery/category/birds?sort=title", http.StatusMovedPermanently, "/gallery/category/sea-birds?sort=title"},
+		{"/gallery/category/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		resp, _ := s.do(t, http.MethodGet, tt.path, "", "")
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.want)
+			continue
+		}
+		if got := resp.Header.Get("Location"); got != tt.location {
+			t.Errorf("GET %s redirects to %q, want %q", tt.path, got, tt.location)
+		}
+	}
+}
+
+func TestRequestIDInResponsesAndErrors(t *testing.T) {
+	s := newTestServer(t)
+
+	get := func(path, requestID string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	tests := []struct {
+		name, sent string
+		reused     bool
+	}{
+		{"client ID", "client-req-1", true},
+		{"no ID", "", false},
+		{"invalid ID", "not a valid id", false},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := get("/api/groups/abc", tt.sent)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, http.StatusBadRequest, body)
+			}
+			id := resp.Header.Get("X-Request-ID")
+			if tt.reused && id != tt.sent {
+				t.Errorf("X-Request-ID = %q, want the client's %q", id, tt.sent)
+			}
+			if !tt.reused && (id == "" || id == tt.sent) {
+				t.Errorf("X-Request-ID = %q, want a generated ID", id)
+			}
+			if seen[id] {
+				t.Errorf("X-Request-ID %q was given to an earlier request", id)
+			}
+			seen[id] = true
+
+			var apiErr struct {
+				Message   string `json:"message"`
+				RequestID string `json:"request_id"`
+			}
+			decode(t, body, &apiErr)
+			if apiErr.RequestID != id {
+				t.Errorf("error body request_id = %q, want the header's %q", apiErr.RequestID, id)
+			}
+		})
+	}
+
+	// Successful responses carry the ID in the header only
+	resp, body := get("/api/groups", "client-req-2")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Request-ID") != "client-req-2" {
+		t.Errorf("GET /api/groups = %d with X-Request-ID %q", resp.StatusCode, resp.Header.Get("X-Request-ID"))
+	}
+	if strings.Contains(body, "client-req-2") {
+		t.Errorf("successful body mentions the request ID: %s", body)
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+
+	type report struct {
+		Status     string `json:"status"`
+		Components map[string]struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"components"`
+	}
+	probe := func(path string) (int, report) {
+		t.Helper()
+		resp, body := s.do(t, http.MethodGet, path, "", "")
+		var r report
+		decode(t, body, &r)
+		return resp.StatusCode, r
+	}
+
+	for _, path := range []string{"/health/ready", "/readyz"} {
+		code, r := probe(path)
+		if code != http.StatusOK || r.Status != "ok" || len(r.Components) != 3 {
+			t.Errorf("GET %s = %d %+v, want 200 with three ok components", path, code, r)
+		}
+	}
+	// /health is an alias of the liveness probe
+	for _, path := range []string{"/health/live", "/health"} {
+		if code, r := probe(path); code != http.StatusOK || r.Status != "ok" || r.Components != nil {
+			t.Errorf("GET %s = %d %+v, want 200 without components", path, code, r)
+		}
+	}
+
+	// Without a database the process is alive but not ready
+	s.db.Close()
+	for _, path := range []string{"/health/live", "/health"} {
+		if code, r := probe(path); code != http.StatusOK || r.Status != "ok" {
+			t.Errorf("GET %s with a closed database = %d %+v, want 200", path, code, r)
+		}
+	}
+	code, r := probe("/health/ready")
+	if code != http.StatusServiceUnavailable || r.Status != "unhealthy" {
+		t.Errorf("GET /health/ready with a closed database = %d %+v, want 503", code, r)
+	}
+	if db := r.Components["database"]; db.Status != "unhealthy" || db.Error == "" {
+		t.Errorf("database component = %+v, want unhealthy with the error", db)
+	}
+	if templates := r.Components["templates"]; templates.Status != "ok" {
+		t.Errorf("templates component = %+v, want ok", templates)
+	}
+}
+
+func TestDryRunGeneration(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("DRY_RUN", "")
+	group := s.seedGroup(t, "Pelican", "")
+	var artworkIDs []int
+	for _, model := range fakeModels[:2] {
+		artworkIDs = append(artworkIDs, s.seedArtwork(t, group, model, ""))
+	}
+
+	// placeholder checks that artwork id holds the dry-run SVG of its model
+	placeholder := func(id int) {
+		t.Helper()
+		artwork, err := s.db.GetArtwork(id)
+		if err != nil {
+			t.Fatalf("GetArtwork: %v", err)
+		}
+		if !strings.Contains(artwork.SVG, "DRY RUN") || !strings.Contains(artwork.SVG, ">"+artwork.Model+"<") {
+			t.Errorf("artwork %d by %s holds %q, want the placeholder", id, artwork.Model, artwork.SVG)
+		}
+	}
+
+	resp, body := s.do(t, http.MethodPost, "/api/generate", "application/json", `{"artwork_id": `+strconv.Itoa(artworkIDs[0])+`, "dry_run": true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("dry-run generate = %d: %s", resp.StatusCode, body)
+	}
+	var artwork models.Artwork
+	decode(t, body, &artwork)
+	if artwork.ID != artworkIDs[0] || !strings.Contains(artwork.SVG, "DRY RUN") {
+		t.Errorf("dry-run generate answered %+v, want the artwork with the placeholder", artwork)
+	}
+	placeholder(artworkIDs[0])
+
+	resp, body = s.do(t, http.MethodPost, "/api/groups/"+strconv.Itoa(group)+"/generate-all?dry_run=true", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("dry-run generate-all = %d: %s", resp.StatusCode, body)
+	}
+	for _, id := range artworkIDs {
+		placeholder(id)
+	}
+
+	// DRY_RUN covers requests that do not ask for a dry run
+	t.Setenv("DRY_RUN", "true")
+	if resp, body = s.do(t, http.MethodPost, "/api/generate", "application/json", `{"artwork_id": `+strconv.Itoa(artworkIDs[1])+`}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("generate with DRY_RUN = %d: %s", resp.StatusCode, body)
+	}
+	placeholder(artworkIDs[1])
+
+	if calls := s.openRouter.calls(); len(calls) != 0 {
+		t.Errorf("dry runs sent OpenRouter requests for %v", calls)
+	}
+
+	// Without a dry run OpenRouter draws again
+	t.Setenv("DRY_RUN", "")
+	if resp, body = s.do(t, http.MethodPost, "/api/generate", "application/json", `{"artwork_id": `+strconv.Itoa(artworkIDs[0])+`}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("generate = %d: %s", resp.StatusCode, body)
+	}
+	if calls := s.openRouter.calls(); len(calls) != 1 || calls[0] != fakeModels[0] {
+		t.Errorf("OpenRouter got requests for %v, want %s", calls, fakeModels[0])
+	}
+}
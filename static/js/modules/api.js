@@ -25,9 +25,26 @@ const parseError = async (response) => {
   }
 };
 
-const request = async (url, options = {}) => {
+// Write endpoints require the admin API key when the server sets ADMIN_API_KEY.
+// The key is asked for once and kept in localStorage.
+const ADMIN_KEY_STORAGE = "adminApiKey";
+
+const withAdminKey = (options) => {
+  const key = localStorage.getItem(ADMIN_KEY_STORAGE);
+  if (!key) return options;
+  return { ...options, headers: { ...options.headers, Authorization: `Bearer ${key}` } };
+};
+
+const request = async (url, options = {}, retried = false) => {
   try {
-    const res = await withTimeout(fetch(url, options), options.timeout, options.signal);
+    const res = await withTimeout(fetch(url, withAdminKey(options)), options.timeout, options.signal);
+    if (res.status === 401 && !retried) {
+      const key = prompt("Admin API key");
+      if (key) {
+        localStorage.setItem(ADMIN_KEY_STORAGE, key.trim());
+        return request(url, options, true);
+      }
+    }
     if (!res.ok) {
       throw new Error(await parseError(res));
     }
@@ -43,7 +60,7 @@ const request = async (url, options = {}) => {
 };
 
 // Endpoints
-const getModels = () => request("/api/models");
+const getModels = ({ grouped = false } = {}) => request(grouped ? "/api/models?grouped=true" : "/api/models");
 
 const getGroup = (groupId) => request(`/api/groups/${groupId}`);
 
@@ -102,6 +119,11 @@ const setFeaturedArtwork = (artworkId) =>
     method: "POST",
   });
 
+const restoreRevision = (artworkId, revisionId) =>
+  request(`/api/artworks/${artworkId}/revisions/${revisionId}/restore`, {
+    method: "POST",
+  });
+
 // Default aggregated API object for convenient imports
 const api = {
   getModels,
@@ -116,6 +138,7 @@ const api = {
   uploadOriginalArtwork,
   getOriginalArtworkUrl,
   setFeaturedArtwork,
+  restoreRevision,
 };
 
 export default api;
@@ -105,6 +105,11 @@ export const ArtworkCard = ({ artwork, onRegenerate, onConfigure, onRemove, onTo
       <div class="flex items-center justify-between p-4 border-b border-border">
         <div class="flex-1 min-w-0">
           <h3 class="font-semibold text-sm truncate">${artwork.model}</h3>
+          ${artwork.deprecated
+            ? html`<p class="text-xs text-red-600 truncate" title="This model is no longer available on OpenRouter">
+                Deprecated${artwork.suggested_model ? ` · try ${artwork.suggested_model}` : ""}
+              </p>`
+            : ""}
         </div>
         <div class="flex items-center gap-1 ml-4">
           <button
@@ -1,10 +1,16 @@
 import { html, useState, useEffect } from "https://esm.sh/htm/preact/standalone";
 import { Modal } from "/static/js/modules/components.js";
 
-export const ModelModal = ({ isOpen, onClose, onSelect, models, loading, error }) => {
+export const ModelModal = ({ isOpen, onClose, onSelect, groups, loading, error }) => {
   const [filterText, setFilterText] = useState("");
 
-  const filteredModels = models.filter((model) => model.id.toLowerCase().includes(filterText.toLowerCase()));
+  // Groups arrive partitioned by provider; filtering drops groups left empty
+  const filteredGroups = groups
+    .map((group) => ({
+      ...group,
+      models: group.models.filter((model) => model.id.toLowerCase().includes(filterText.toLowerCase())),
+    }))
+    .filter((group) => group.models.length > 0);
 
   return html`
     <${Modal}
@@ -37,31 +43,40 @@ export const ModelModal = ({ isOpen, onClose, onSelect, models, loading, error }
         ${
           loading
             ? html`<div class="text-center py-8 text-sm" role="status" aria-live="polite">Loading models...</div>`
-            : filteredModels.length === 0
+            : filteredGroups.length === 0
             ? html`<div class="text-center py-8 text-sm text-fg/60">No models match your filter.</div>`
-            : filteredModels.map(
-                (model) => html`
-                  <div
-                    key=${model.id}
-                    class="model-card border border-border p-4 hover:bg-fg hover:text-bg transition-colors duration-200 cursor-pointer focus:outline-none focus:bg-fg focus:text-bg flex items-center justify-between"
-                    role="option"
-                    tabindex="0"
-                    aria-label="Select ${model.name}"
-                    onClick=${(e) => {
-                      e.preventDefault();
-                      e.stopPropagation();
-                      onSelect(model);
-                    }}
-                    onKeyDown=${(e) => {
-                      if (e.key === "Enter" || e.key === " ") {
-                        e.preventDefault();
-                        e.stopPropagation();
-                        onSelect(model);
-                      }
-                    }}
-                  >
-                    <div class="font-semibold">${model.name}</div>
-                    <div class="text-sm opacity-75">$${model.cost.toFixed(2)}/1M tokens</div>
+            : filteredGroups.map(
+                (group) => html`
+                  <div key=${group.provider} role="group" aria-label=${group.provider}>
+                    <h4 class="text-xs font-semibold uppercase tracking-wide text-fg/60 mb-2">${group.provider}</h4>
+                    <div class="space-y-3">
+                      ${group.models.map(
+                        (model) => html`
+                          <div
+                            key=${model.id}
+                            class="model-card border border-border p-4 hover:bg-fg hover:text-bg transition-colors duration-200 cursor-pointer focus:outline-none focus:bg-fg focus:text-bg flex items-center justify-between"
+                            role="option"
+                            tabindex="0"
+                            aria-label="Select ${model.name}"
+                            onClick=${(e) => {
+                              e.preventDefault();
+                              e.stopPropagation();
+                              onSelect(model);
+                            }}
+                            onKeyDown=${(e) => {
+                              if (e.key === "Enter" || e.key === " ") {
+                                e.preventDefault();
+                                e.stopPropagation();
+                                onSelect(model);
+                              }
+                            }}
+                          >
+                            <div class="font-semibold">${model.name}</div>
+                            <div class="text-sm opacity-75">$${model.cost.toFixed(2)}/1M tokens</div>
+                          </div>
+                        `
+                      )}
+                    </div>
                   </div>
                 `
               )
@@ -71,7 +86,7 @@ export const ModelModal = ({ isOpen, onClose, onSelect, models, loading, error }
   `;
 };
 
-const DEFAULT_CONFIG = { temperature: 0.7, max_tokens: 50000 };
+const DEFAULT_CONFIG = { temperature: 0.7, max_tokens: 50000, reasoning_effort: "" };
 
 export const ConfigModal = ({ isOpen, onClose, onSave, artwork }) => {
   const [config, setConfig] = useState(DEFAULT_CONFIG);
@@ -82,6 +97,7 @@ export const ConfigModal = ({ isOpen, onClose, onSave, artwork }) => {
         setConfig({
           temperature: artwork.temperature,
           max_tokens: artwork.max_tokens,
+          reasoning_effort: artwork.reasoning_effort || "",
         });
       } catch (e) {
         console.error("Failed to read artwork params:", e);
@@ -139,7 +155,7 @@ export const ConfigModal = ({ isOpen, onClose, onSave, artwork }) => {
                 id="max-tokens-input"
                 class="flex-1 h-2 bg-border appearance-none cursor-pointer accent-fg"
                 min="100"
-                max="1000000"
+                max="100000"
                 step="100"
                 value=${config.max_tokens}
                 onInput=${(e) => setConfig({ ...config, max_tokens: parseInt(e.target.value) })}
@@ -147,6 +163,25 @@ export const ConfigModal = ({ isOpen, onClose, onSave, artwork }) => {
               <span class="text-sm font-mono min-w-16 text-right">${config.max_tokens}</span>
             </div>
           </div>
+
+          <div class="space-y-2">
+            <label for="reasoning-effort-input" class="block text-sm font-medium">
+              Reasoning Effort
+              <span class="block text-xs text-fg/70 font-normal">How much the model reasons before drawing</span>
+            </label>
+            <select
+              id="reasoning-effort-input"
+              class="w-full px-3 py-2 border border-border bg-bg text-sm"
+              value=${config.reasoning_effort}
+              onChange=${(e) => setConfig({ ...config, reasoning_effort: e.target.value })}
+            >
+              <option value="">Default (medium)</option>
+              <option value="low">Low</option>
+              <option value="medium">Medium</option>
+              <option value="high">High</option>
+              <option value="off">Off</option>
+            </select>
+          </div>
         </div>
       </div>
 
@@ -25,10 +25,25 @@ export function createInitialState(windowObj = window) {
     }
   })();
 
+  const promptStyles = (() => {
+    const raw = windowObj.promptStyles;
+    if (Array.isArray(raw)) return raw;
+    if (typeof raw === "string" && raw.trim()) {
+      try {
+        const parsed = JSON.parse(raw);
+        if (Array.isArray(parsed)) return parsed;
+      } catch (e) {
+        console.warn("Failed to parse window.promptStyles string:", e);
+      }
+    }
+    return [];
+  })();
+
   return {
     currentGroup: null,
     artworks: initialArtworks,
     models: [],
+    promptStyles,
     toasts: [],
     loading: { visible: false, message: "" },
     modals: { model: false, config: false },
@@ -40,6 +55,7 @@ export function createInitialState(windowObj = window) {
       title: "Sunflowers",
       prompt: "Sunflowers by Vincent van Gogh.",
       category: "Art",
+      prompt_style: "",
     },
     originalArtworkUploaded: 0,
     selectedFile: null,
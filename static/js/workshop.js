@@ -113,6 +113,7 @@ const WorkshopApp = () => {
         category: state.currentGroup.category,
         original_url: state.currentGroup.original_url || "",
         artist_name: state.currentGroup.artist_name,
+        prompt_style: state.currentGroup.prompt_style || "",
       };
       dispatch({ type: "SET_FORM_DATA", payload: groupFormData });
     }
@@ -123,8 +124,8 @@ const WorkshopApp = () => {
     dispatch({ type: "SET_MODELS_LOADING", payload: true });
     dispatch({ type: "SET_MODELS_ERROR", payload: "" });
     try {
-      const data = await api.getModels();
-      dispatch({ type: "SET_MODELS", payload: data.models || [] });
+      const data = await api.getModels({ grouped: true });
+      dispatch({ type: "SET_MODELS", payload: data.groups || [] });
     } catch (error) {
       console.error("Failed to load models:", error);
       dispatch({ type: "SET_MODELS_ERROR", payload: `Failed to load models: ${error.message}` });
@@ -135,7 +136,7 @@ const WorkshopApp = () => {
 
   // API functions
   const saveGroup = async () => {
-    const { title, prompt, category, original_url, artist_name } = state.formData;
+    const { title, prompt, category, original_url, artist_name, prompt_style } = state.formData;
 
     if (!title?.trim() || !prompt?.trim() || !category?.trim()) {
       showToast("Title, prompt and category are required", "error");
@@ -150,6 +151,7 @@ const WorkshopApp = () => {
         category: category.trim(),
         original_url: original_url?.trim(),
         artist_name: artist_name?.trim(),
+        prompt_style: prompt_style || "",
       };
       const groupId = state.currentGroup?.id;
       const group = await (groupId ? api.updateGroup(groupId, payload) : api.createGroup(payload));
@@ -187,14 +189,14 @@ const WorkshopApp = () => {
     }
 
     const groupTitle = state.currentGroup?.title;
-    if (!confirm('Delete group "' + groupTitle + '" and all artworks?')) {
+    if (!confirm('Move group "' + groupTitle + '" and all artworks to the recycle bin?')) {
       return;
     }
 
     try {
       showLoading("Deleting group...");
       await api.deleteGroup(groupId);
-      showToast("Group deleted", "success");
+      showToast("Group moved to the recycle bin", "success");
       setTimeout(() => {
         window.location.href = "/gallery";
       }, 500);
@@ -210,7 +212,7 @@ const WorkshopApp = () => {
 
     // If no group exists, save the group first
     if (!groupId) {
-      const { title, prompt, category, original_url, artist_name } = state.formData;
+      const { title, prompt, category, original_url, artist_name, prompt_style } = state.formData;
 
       if (!title?.trim() || !prompt?.trim()) {
         showToast("Please enter a title and prompt before adding models", "error");
@@ -225,6 +227,7 @@ const WorkshopApp = () => {
           category: category.trim(),
           original_url: original_url?.trim() || "",
           artist_name: artist_name?.trim() || "",
+          prompt_style: prompt_style || "",
         };
 
         const newGroup = await api.createGroup(groupPayload);
@@ -293,6 +296,7 @@ const WorkshopApp = () => {
       const updated = await api.updateArtwork(artworkId, {
         temperature: params.temperature,
         max_tokens: params.max_tokens,
+        reasoning_effort: params.reasoning_effort,
       });
       dispatch({ type: "UPDATE_ARTWORK", payload: updated });
       showToast("Parameters updated", "success");
@@ -422,6 +426,28 @@ const WorkshopApp = () => {
               </div>
             </div>
 
+            ${state.promptStyles.length > 1 &&
+            html`
+              <div class="space-y-2">
+                <label for="prompt-style-input" class="block text-sm font-medium">Prompt Style</label>
+                <select
+                  id="prompt-style-input"
+                  class="w-full p-3 border border-border bg-bg text-fg text-sm focus:outline-none focus:border-fg"
+                  value=${state.formData.prompt_style || ""}
+                  onChange=${(e) =>
+                    dispatch({ type: "SET_FORM_DATA", payload: { ...state.formData, prompt_style: e.target.value } })}
+                >
+                  ${state.promptStyles.map(
+                    (style) => html`
+                      <option value=${style.default ? "" : style.name} title=${style.description}>
+                        ${style.name}${style.default ? " (default)" : ""}
+                      </option>
+                    `
+                  )}
+                </select>
+              </div>
+            `}
+
             <div class="space-y-2">
               <label for="original-url-input" class="block text-sm font-medium">Original Artwork URL</label>
               <input
@@ -542,7 +568,7 @@ const WorkshopApp = () => {
         isOpen=${state.modals.model}
         onClose=${() => dispatch({ type: "SET_MODAL", payload: { modal: "model", value: false } })}
         onSelect=${handleModelSelect}
-        models=${state.models}
+        groups=${state.models}
         loading=${state.modelsLoading}
         error=${state.modelsError}
       />